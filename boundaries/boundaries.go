@@ -0,0 +1,205 @@
+// Package boundaries answers which admin areas contain a point from polygons kept in memory.
+// The importer saves assembled admin boundaries with Save when boundaries_file is configured,
+// so other Go programs can Open the file and look up points without elasticsearch or the geocoder
+package boundaries
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/maddevsio/ariadna/geodesic"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// cellSize is size in degrees of grid cells areas are indexed by
+const cellSize = 1.0
+
+type (
+	// Area is admin boundary: country, city, district and other roles of admin levels.
+	// Geometry is polygon or multipolygon with holes
+	Area struct {
+		ID       string            `json:"id"`
+		Name     string            `json:"name"`
+		Names    map[string]string `json:"names,omitempty"`
+		Role     string            `json:"role"`
+		Level    int               `json:"level,omitempty"`
+		Geometry *geojson.Geometry `json:"geometry"`
+	}
+	// Index finds areas containing point by grid of bounding boxes, it is safe for concurrent use
+	Index struct {
+		areas []area
+		// cells lists areas which bounding box overlaps grid cell, the outermost first
+		cells map[[2]int][]int
+	}
+	area struct {
+		Area
+		// polygons are outer rings followed by holes
+		polygons [][]*geodesic.Polygon
+		// bbox is min lat, min lon, max lat, max lon, longitudes of area crossing the
+		// antimeridian are out of -180..180 range
+		bbox [4]float64
+	}
+)
+
+// New indexes areas, ones without polygon geometry are skipped
+func New(areas []Area) *Index {
+	idx := &Index{cells: make(map[[2]int][]int)}
+	for _, a := range areas {
+		polygons, bbox, ok := prepare(a.Geometry)
+		if !ok {
+			continue
+		}
+		idx.areas = append(idx.areas, area{Area: a, polygons: polygons, bbox: bbox})
+	}
+	sort.SliceStable(idx.areas, func(a, b int) bool {
+		return size(idx.areas[a].bbox) > size(idx.areas[b].bbox)
+	})
+	for n, a := range idx.areas {
+		from, to := cell(a.bbox[0], a.bbox[1]), cell(a.bbox[2], a.bbox[3])
+		for y := from[0]; y <= to[0]; y++ {
+			for x := from[1]; x <= to[1]; x++ {
+				key := cell(float64(y)*cellSize, float64(x)*cellSize)
+				idx.cells[key] = append(idx.cells[key], n)
+			}
+		}
+	}
+	return idx
+}
+
+// Lookup returns areas containing point, the outermost first
+func (idx *Index) Lookup(lat, lon float64) []Area {
+	var result []Area
+	point := geodesic.Point{Lat: lat, Lon: lon}
+	for _, n := range idx.cells[cell(lat, lon)] {
+		if a := idx.areas[n]; contains(a.polygons, point) {
+			result = append(result, a.Area)
+		}
+	}
+	return result
+}
+
+// Areas returns all indexed areas, the outermost first
+func (idx *Index) Areas() []Area {
+	areas := make([]Area, 0, len(idx.areas))
+	for _, a := range idx.areas {
+		areas = append(areas, a.Area)
+	}
+	return areas
+}
+
+// Len returns number of indexed areas
+func (idx *Index) Len() int {
+	return len(idx.areas)
+}
+
+// Write writes areas as gzipped JSON array
+func (idx *Index) Write(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	if err := json.NewEncoder(gz).Encode(idx.Areas()); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Read indexes areas written by Write
+func Read(r io.Reader) (*Index, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	var areas []Area
+	if err := json.NewDecoder(gz).Decode(&areas); err != nil {
+		return nil, err
+	}
+	return New(areas), nil
+}
+
+// Save writes areas to file at path, existing file is replaced
+func (idx *Index) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := idx.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Open indexes areas of file saved by Save
+func Open(path string) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// prepare builds polygons of geometry and their bounding box
+func prepare(g *geojson.Geometry) ([][]*geodesic.Polygon, [4]float64, bool) {
+	var polygons [][][][]float64
+	switch {
+	case g == nil:
+	case g.IsPolygon():
+		polygons = [][][][]float64{g.Polygon}
+	case g.IsMultiPolygon():
+		polygons = g.MultiPolygon
+	}
+	bbox := [4]float64{90, math.Inf(1), -90, math.Inf(-1)}
+	var prepared [][]*geodesic.Polygon
+	for _, polygon := range polygons {
+		if len(polygon) == 0 {
+			continue
+		}
+		rings := make([]*geodesic.Polygon, 0, len(polygon))
+		for _, ring := range polygon {
+			points := make([]geodesic.Point, 0, len(ring))
+			for _, c := range ring {
+				points = append(points, geodesic.Point{Lat: c[1], Lon: c[0]})
+			}
+			rings = append(rings, geodesic.NewPolygon(points))
+		}
+		b := rings[0].Bounds()
+		bbox[0], bbox[1] = math.Min(bbox[0], b[0]), math.Min(bbox[1], b[1])
+		bbox[2], bbox[3] = math.Max(bbox[2], b[2]), math.Max(bbox[3], b[3])
+		prepared = append(prepared, rings)
+	}
+	return prepared, bbox, bbox[1] <= bbox[3]
+}
+
+func size(bbox [4]float64) float64 {
+	return (bbox[2] - bbox[0]) * (bbox[3] - bbox[1])
+}
+
+// cell returns grid cell of point, longitudes out of -180..180 range are wrapped
+func cell(lat, lon float64) [2]int {
+	lon = math.Mod(math.Mod(lon+180, 360)+360, 360) - 180
+	return [2]int{int(math.Floor(lat / cellSize)), int(math.Floor(lon / cellSize))}
+}
+
+// contains reports whether point is inside of outer ring of any polygon and out of its holes
+func contains(polygons [][]*geodesic.Polygon, point geodesic.Point) bool {
+	for _, rings := range polygons {
+		if !rings[0].Contains(point) {
+			continue
+		}
+		inHole := false
+		for _, hole := range rings[1:] {
+			if hole.Contains(point) {
+				inHole = true
+				break
+			}
+		}
+		if !inHole {
+			return true
+		}
+	}
+	return false
+}
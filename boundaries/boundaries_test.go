@@ -0,0 +1,51 @@
+package boundaries
+
+import (
+	"bytes"
+	"testing"
+
+	geojson "github.com/paulmach/go.geojson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func square(minLon, minLat, maxLon, maxLat float64) [][]float64 {
+	return [][]float64{{minLon, minLat}, {maxLon, minLat}, {maxLon, maxLat}, {minLon, maxLat}, {minLon, minLat}}
+}
+
+func testIndex() *Index {
+	return New([]Area{
+		{ID: "relation/2", Name: "Бишкек", Role: "city", Level: 4, Geometry: geojson.NewPolygonGeometry([][][]float64{square(74.4, 42.7, 74.8, 43)})},
+		{ID: "relation/1", Name: "Кыргызстан", Role: "country", Level: 2, Geometry: geojson.NewPolygonGeometry([][][]float64{square(69, 39, 80, 43), square(70, 39.5, 71, 40)})},
+		{ID: "node/3", Name: "point", Geometry: geojson.NewPointGeometry([]float64{74, 42})},
+	})
+}
+
+func names(areas []Area) []string {
+	var result []string
+	for _, a := range areas {
+		result = append(result, a.Name)
+	}
+	return result
+}
+
+func TestLookup(t *testing.T) {
+	idx := testIndex()
+	assert.Equal(t, 2, idx.Len(), "point geometry is not an area")
+	assert.Equal(t, []string{"Кыргызстан", "Бишкек"}, names(idx.Lookup(42.87, 74.59)), "the outermost area goes first")
+	assert.Equal(t, []string{"Кыргызстан"}, names(idx.Lookup(41, 75)))
+	assert.Empty(t, idx.Lookup(39.7, 70.5), "point in hole")
+	assert.Empty(t, idx.Lookup(50, 100))
+}
+
+func TestWriteRead(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, testIndex().Write(&buf))
+	idx, err := Read(&buf)
+	require.NoError(t, err)
+	areas := idx.Lookup(42.87, 74.59)
+	require.Len(t, areas, 2)
+	assert.Equal(t, "relation/2", areas[1].ID)
+	assert.Equal(t, "city", areas[1].Role)
+	assert.Equal(t, 4, areas[1].Level)
+}
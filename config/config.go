@@ -1,17 +1,410 @@
 package config
 
-import "github.com/spf13/viper"
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
 
 type Ariadna struct {
-	ElasticIndex  string   `json:"elastic_index" mapstructure:"elastic_index"`
-	ElasticURLs   []string `json:"elastic_urls" mapstructure:"elastic_urls"`
-	OSMFilename   string   `json:"osm_filename" mapstructure:"osm_filename"`
-	IndexSettings string   `json:"index_settings" mapstructure:"index_settings"`
-	OSMURL        string   `json:"osm_url" mapstructure:"osm_url"`
-	ImportCountry string   `json:"import_country" mapstructure:"import_country"`
+	ElasticIndex  string    `json:"elastic_index" mapstructure:"elastic_index"`
+	ElasticURLs   []string  `json:"elastic_urls" mapstructure:"elastic_urls"`
+	OSMFilename   string    `json:"osm_filename" mapstructure:"osm_filename"`
+	IndexSettings string    `json:"index_settings" mapstructure:"index_settings"`
+	OSMURL        string    `json:"osm_url" mapstructure:"osm_url"`
+	ImportCountry string    `json:"import_country" mapstructure:"import_country"`
+	AdminLevels   Levels    `json:"admin_levels" mapstructure:"admin_levels"`
+	Synonyms      []string  `json:"synonyms" mapstructure:"synonyms"`
+	Analyzer      string    `json:"analyzer" mapstructure:"analyzer"`
+	ClipPolygon   string    `json:"clip_polygon" mapstructure:"clip_polygon"`
+	KeepTags      []string  `json:"keep_tags" mapstructure:"keep_tags"`
+	Plugins       []string  `json:"plugins" mapstructure:"plugins"`
+	Fields        []Field   `json:"fields" mapstructure:"fields"`
+	Wikidata      Wikidata  `json:"wikidata" mapstructure:"wikidata"`
+	ElevationDir  string    `json:"elevation_dir" mapstructure:"elevation_dir"`
+	Timezones     string    `json:"timezones" mapstructure:"timezones"`
+	API           API       `json:"api" mapstructure:"api"`
+	Analytics     Analytics `json:"analytics" mapstructure:"analytics"`
+	Ranking       Ranking   `json:"ranking" mapstructure:"ranking"`
+	Sharding      Sharding  `json:"sharding" mapstructure:"sharding"`
+	Fallback      Fallback  `json:"fallback" mapstructure:"fallback"`
+	Simplify      Simplify  `json:"simplify" mapstructure:"simplify"`
+	Source        Source    `json:"source" mapstructure:"source"`
+	Snapshot      Snapshot  `json:"snapshot" mapstructure:"snapshot"`
+	// TagMappings extend and override built-in mappings of deprecated tags
+	TagMappings []TagMapping `json:"tag_mappings" mapstructure:"tag_mappings"`
+	// BoundariesFile is path admin boundaries of import are saved to for package boundaries
+	BoundariesFile string `json:"boundaries_file" mapstructure:"boundaries_file"`
+	// FieldNames renames top level document fields in the index, e.g. housenumber: house_number
+	FieldNames map[string]string `json:"field_names" mapstructure:"field_names"`
+	// Pipeline is elasticsearch ingest pipeline documents are indexed through
+	Pipeline string `json:"pipeline" mapstructure:"pipeline"`
+	// Followers are clusters replicating the index, searches are spread over them
+	Followers Followers `json:"followers" mapstructure:"followers"`
+	// Connections separate connections of imports and searches
+	Connections Connections `json:"connections" mapstructure:"connections"`
+	// Backpressure adapts concurrency of bulk requests to write queues of the cluster
+	Backpressure Backpressure `json:"backpressure" mapstructure:"backpressure"`
+	// Resume makes import continue in index of the latest unfinished import instead of new one
+	Resume bool `json:"resume" mapstructure:"resume"`
+	// ImportLayers rebuilds only documents of these layers in the current index, e.g. poi, address
+	ImportLayers []string `json:"import_layers" mapstructure:"import_layers"`
+	// Geofences configures named areas registered by clients
+	Geofences Geofences `json:"geofences" mapstructure:"geofences"`
+	// History keeps indices of previous imports for queries as of past dates
+	History History `json:"history" mapstructure:"history"`
+	// Quotas limits requests of API keys per day and month
+	Quotas Quotas `json:"quotas" mapstructure:"quotas"`
+	// Audit records every import run with timings of its stages
+	Audit Audit `json:"audit" mapstructure:"audit"`
+	// Memory bounds memory of import instead of letting it be killed when memory runs out
+	Memory Memory `json:"memory" mapstructure:"memory"`
+}
+
+// TagMapping replaces deprecated tag by current tagging at import, tags are key=value
+type TagMapping struct {
+	From string   `json:"from" mapstructure:"from"`
+	To   []string `json:"to" mapstructure:"to"`
+}
+
+// Snapshot configures elasticsearch snapshot repository built indices are copied through,
+// Type and Settings are passed to repository API as is, e.g. fs with location
+type Snapshot struct {
+	Repository string            `json:"repository" mapstructure:"repository"`
+	Type       string            `json:"type" mapstructure:"type"`
+	Settings   map[string]string `json:"settings" mapstructure:"settings"`
+}
+
+// Geofences configures named areas clients register for point checks. Index keeps fences,
+// <elastic_index>-geofences by default, Keys are API keys allowed to register and delete them,
+// fences can't be changed without keys. Fences are reloaded into memory every RefreshInterval
+type Geofences struct {
+	Index           string        `json:"index" mapstructure:"index"`
+	Keys            []string      `json:"keys" mapstructure:"keys"`
+	RefreshInterval time.Duration `json:"refresh_interval" mapstructure:"refresh_interval"`
+}
+
+// History keeps indices of previous imports behind Alias, <elastic_index>-history by default,
+// instead of deleting them, so searches with ?as_of= read data of their date. Imports replaced
+// longer than Retention ago are deleted, zero retention keeps them all
+type History struct {
+	Enabled   bool          `json:"enabled" mapstructure:"enabled"`
+	Alias     string        `json:"alias" mapstructure:"alias"`
+	Retention time.Duration `json:"retention" mapstructure:"retention"`
+}
+
+// Quotas limits requests of API keys per UTC day and month, requests without key are counted per
+// client address. Counts are kept in Index, <elastic_index>-usage by default, and written every
+// FlushInterval, so instances see requests of each other with that delay. Keys get default limits,
+// Clients override them for their keys, zero limit is unlimited. Other API keys of configuration
+// get default limits too, unknown keys are rejected. AdminKeys are API keys allowed to read usage
+type Quotas struct {
+	Enabled       bool          `json:"enabled" mapstructure:"enabled"`
+	Index         string        `json:"index" mapstructure:"index"`
+	Daily         int64         `json:"daily" mapstructure:"daily"`
+	Monthly       int64         `json:"monthly" mapstructure:"monthly"`
+	Clients       []ClientQuota `json:"clients" mapstructure:"clients"`
+	AdminKeys     []string      `json:"admin_keys" mapstructure:"admin_keys"`
+	FlushInterval time.Duration `json:"flush_interval" mapstructure:"flush_interval"`
+	// Keys are API keys counted under default limits
+	Keys []string `json:"keys" mapstructure:"keys"`
+}
+
+// Audit keeps entry per import run in Index, <elastic_index>-audit by default. AdminKeys are API
+// keys allowed to read the entries
+type Audit struct {
+	Enabled   bool     `json:"enabled" mapstructure:"enabled"`
+	Index     string   `json:"index" mapstructure:"index"`
+	AdminKeys []string `json:"admin_keys" mapstructure:"admin_keys"`
+}
+
+// Memory is budget of import in bytes. Near Max node coordinates, not ways, are spilled to temporary files
+// under SpillDir, system temporary directory by default, and bulk requests get smaller and fewer.
+// Zero Max is unlimited
+type Memory struct {
+	Max      int64  `json:"max" mapstructure:"max"`
+	SpillDir string `json:"spill_dir" mapstructure:"spill_dir"`
+}
+
+// ClientQuota is daily and monthly limit of API key
+type ClientQuota struct {
+	Key     string `json:"key" mapstructure:"key"`
+	Daily   int64  `json:"daily" mapstructure:"daily"`
+	Monthly int64  `json:"monthly" mapstructure:"monthly"`
+}
+
+// Followers configures clusters following the primary one by cross-cluster replication.
+// Index is name of followed index or alias there, elastic_index by default. Followers
+// failing health checks every CheckInterval don't get searches until they recover
+type Followers struct {
+	URLs          []string      `json:"urls" mapstructure:"urls"`
+	Index         string        `json:"index" mapstructure:"index"`
+	CheckInterval time.Duration `json:"check_interval" mapstructure:"check_interval"`
 }
 
+// Connections configure pools of connections to elasticsearch: Index one is used by imports
+// and other writes, Search one by searches, so heavy import doesn't starve live traffic
+type Connections struct {
+	Index  Pool `json:"index" mapstructure:"index"`
+	Search Pool `json:"search" mapstructure:"search"`
+}
+
+// Pool is connection pool of elasticsearch client. URLs are elastic_urls by default, MaxConns
+// caps connections per node, zero means no limit, MaxIdle is kept idle connections per node.
+// With Sniff client discovers HTTP addresses of all nodes of the cluster on start
+type Pool struct {
+	URLs             []string      `json:"urls" mapstructure:"urls"`
+	MaxConns         int           `json:"max_conns" mapstructure:"max_conns"`
+	MaxIdle          int           `json:"max_idle" mapstructure:"max_idle"`
+	KeepAlive        time.Duration `json:"keep_alive" mapstructure:"keep_alive"`
+	DisableKeepAlive bool          `json:"disable_keep_alive" mapstructure:"disable_keep_alive"`
+	Sniff            bool          `json:"sniff" mapstructure:"sniff"`
+}
+
+// Backpressure bounds bulk requests of import. Bulk bodies are split into chunks of ChunkSize
+// bytes sent by up to MaxConcurrent requests at once. Concurrency is halved when documents are
+// rejected by full write queues or the queues hold over QueueThreshold requests, checked every
+// CheckInterval, rejected documents are retried up to MaxRetries times
+type Backpressure struct {
+	MaxConcurrent  int           `json:"max_concurrent" mapstructure:"max_concurrent"`
+	ChunkSize      int           `json:"chunk_size" mapstructure:"chunk_size"`
+	MaxRetries     int           `json:"max_retries" mapstructure:"max_retries"`
+	QueueThreshold int           `json:"queue_threshold" mapstructure:"queue_threshold"`
+	CheckInterval  time.Duration `json:"check_interval" mapstructure:"check_interval"`
+}
+
+// Source configures access to osm_url mirrors: headers and bearer token sent with HTTP(S)
+// and gs:// requests to host of osm_url, credentials of s3:// buckets. Mirrors are tried in
+// order when osm_url fails, Bandwidth caps download in bytes per second and Connections splits
+// it into parallel range requests
+type Source struct {
+	Headers     map[string]string `json:"headers" mapstructure:"headers"`
+	Token       string            `json:"token" mapstructure:"token"`
+	S3          S3                `json:"s3" mapstructure:"s3"`
+	Mirrors     []string          `json:"mirrors" mapstructure:"mirrors"`
+	Bandwidth   int64             `json:"bandwidth" mapstructure:"bandwidth"`
+	Connections int               `json:"connections" mapstructure:"connections"`
+	// Auth is headers and bearer token of other hosts, e.g. mirrors, by host or gs:// bucket
+	Auth map[string]SourceAuth `json:"auth" mapstructure:"auth"`
+}
+
+// SourceAuth is headers and bearer token sent to one host of extracts
+type SourceAuth struct {
+	Headers map[string]string `json:"headers" mapstructure:"headers"`
+	Token   string            `json:"token" mapstructure:"token"`
+}
+
+// S3 configures s3:// sources. Endpoint replaces AWS one for compatible storages, buckets
+// are addressed by path then. Empty credentials are taken from AWS_* environment variables,
+// requests are sent unsigned without them
+type S3 struct {
+	Region       string `json:"region" mapstructure:"region"`
+	Endpoint     string `json:"endpoint" mapstructure:"endpoint"`
+	AccessKey    string `json:"access_key" mapstructure:"access_key"`
+	SecretKey    string `json:"secret_key" mapstructure:"secret_key"`
+	SessionToken string `json:"session_token" mapstructure:"session_token"`
+}
+
+// Simplify configures simplification of indexed admin polygons. Tolerance is max deviation
+// of simplified outline in meters, zero keeps polygons as mapped
+type Simplify struct {
+	Tolerance float64 `json:"tolerance" mapstructure:"tolerance"`
+}
+
+// Fallback configures external geocoder asked when index has no results: nominatim or google
+type Fallback struct {
+	Provider  string        `json:"provider" mapstructure:"provider"`
+	URL       string        `json:"url" mapstructure:"url"`
+	Key       string        `json:"key" mapstructure:"key"`
+	UserAgent string        `json:"user_agent" mapstructure:"user_agent"`
+	Rate      float64       `json:"rate" mapstructure:"rate"`
+	Burst     int           `json:"burst" mapstructure:"burst"`
+	CacheSize int           `json:"cache_size" mapstructure:"cache_size"`
+	CacheTTL  time.Duration `json:"cache_ttl" mapstructure:"cache_ttl"`
+	Timeout   time.Duration `json:"timeout" mapstructure:"timeout"`
+	// StaleTTL is how long answers older than cache_ttl are still served while refreshed
+	StaleTTL time.Duration `json:"stale_ttl" mapstructure:"stale_ttl"`
+}
+
+// Sharding splits imported documents into index per country or per grid cell.
+// All shard indices share the elastic_index alias, so search stays global
+type Sharding struct {
+	Mode     string  `json:"mode" mapstructure:"mode"`
+	CellSize float64 `json:"cell_size" mapstructure:"cell_size"`
+}
+
+// Field projects OSM tag into document field of given type: string, number, bool or list
+type Field struct {
+	Name string `json:"name" mapstructure:"name"`
+	Tag  string `json:"tag" mapstructure:"tag"`
+	Type string `json:"type" mapstructure:"type"`
+}
+
+// Types of extracted fields
+const (
+	FieldString = "string"
+	FieldNumber = "number"
+	FieldBool   = "bool"
+	FieldList   = "list"
+)
+
+// Levels maps admin_level of boundaries to their role: country, city, town or village
+type Levels map[string]string
+
+type Ranking struct {
+	Profiles   map[string]RankingProfile `json:"profiles" mapstructure:"profiles"`
+	Experiment Experiment                `json:"experiment" mapstructure:"experiment"`
+}
+
+type RankingProfile struct {
+	Fields     []string `json:"fields" mapstructure:"fields"`
+	Operator   string   `json:"operator" mapstructure:"operator"`
+	Popularity bool     `json:"popularity" mapstructure:"popularity"`
+	// LayerBoosts and CategoryBoosts multiply scores of documents of layers and categories,
+	// layers and categories query parameters override them per request
+	LayerBoosts    map[string]float64 `json:"layer_boosts" mapstructure:"layer_boosts"`
+	CategoryBoosts map[string]float64 `json:"category_boosts" mapstructure:"category_boosts"`
+	// Layers and Categories restrict results of profile used as query preset, Size overrides
+	// number of results
+	Layers     []string `json:"layers" mapstructure:"layers"`
+	Categories []string `json:"categories" mapstructure:"categories"`
+	Size       int      `json:"size" mapstructure:"size"`
+	// Filters restrict results to documents having any of values of extracted fields, filter
+	// query parameters like filter=cuisine:georgian replace them per field
+	Filters map[string][]string `json:"filters" mapstructure:"filters"`
+	// Facets lists fields which values are counted among all found documents
+	Facets []string `json:"facets" mapstructure:"facets"`
+}
+
+// Experiment splits search traffic between control and candidate ranking profiles
+type Experiment struct {
+	Control    string   `json:"control" mapstructure:"control"`
+	Candidate  string   `json:"candidate" mapstructure:"candidate"`
+	Percentage int      `json:"percentage" mapstructure:"percentage"`
+	Keys       []string `json:"keys" mapstructure:"keys"`
+}
+
+type Analytics struct {
+	Enabled       bool          `json:"enabled" mapstructure:"enabled"`
+	Index         string        `json:"index" mapstructure:"index"`
+	Retention     time.Duration `json:"retention" mapstructure:"retention"`
+	FlushInterval time.Duration `json:"flush_interval" mapstructure:"flush_interval"`
+	// Lifecycle lets ILM roll analytics indices over instead of writing daily ones
+	Lifecycle Lifecycle `json:"lifecycle" mapstructure:"lifecycle"`
+}
+
+// Lifecycle is ILM policy of indices written through alias. Index rolls over when it is older
+// than MaxAge or bigger than MaxSize, e.g. "50gb"
+type Lifecycle struct {
+	Enabled bool          `json:"enabled" mapstructure:"enabled"`
+	MaxAge  time.Duration `json:"max_age" mapstructure:"max_age"`
+	MaxSize string        `json:"max_size" mapstructure:"max_size"`
+}
+
+type API struct {
+	Listen         string        `json:"listen" mapstructure:"listen"`
+	Listeners      []string      `json:"listeners" mapstructure:"listeners"`
+	RequestTimeout time.Duration `json:"request_timeout" mapstructure:"request_timeout"`
+	TerminateAfter int           `json:"terminate_after" mapstructure:"terminate_after"`
+	MaxConcurrent  int           `json:"max_concurrent" mapstructure:"max_concurrent"`
+	MaxQueue       int           `json:"max_queue" mapstructure:"max_queue"`
+	QueueTimeout   time.Duration `json:"queue_timeout" mapstructure:"queue_timeout"`
+	RetryAfter     time.Duration `json:"retry_after" mapstructure:"retry_after"`
+	QueryLogSize   int           `json:"query_log_size" mapstructure:"query_log_size"`
+	DisableLog     bool          `json:"disable_query_log" mapstructure:"disable_query_log"`
+	ChangesLogSize int           `json:"changes_log_size" mapstructure:"changes_log_size"`
+	MaxBatch       int           `json:"max_batch" mapstructure:"max_batch"`
+	MaxQueryLength int           `json:"max_query_length" mapstructure:"max_query_length"`
+	// MaxBodySize is limit of request bodies of batch endpoints in bytes
+	MaxBodySize int64 `json:"max_body_size" mapstructure:"max_body_size"`
+	// CacheControl is Cache-Control header value per endpoint: search, reverse
+	CacheControl map[string]string `json:"cache_control" mapstructure:"cache_control"`
+	Compression  Compression       `json:"compression" mapstructure:"compression"`
+	// Autocomplete configures WebSocket autocomplete sessions
+	Autocomplete Autocomplete `json:"autocomplete" mapstructure:"autocomplete"`
+	// PlaceRadius is distance in kilometers by place type, e.g. city or village, reverse geocoding
+	// falls back to the nearest place node within when no address is near the point
+	PlaceRadius map[string]float64 `json:"place_radius" mapstructure:"place_radius"`
+	// ExportKeys are API keys allowed to export full result sets, export is disabled without them
+	ExportKeys []string `json:"export_keys" mapstructure:"export_keys"`
+	// DebugKeys are API keys allowed to explain ranking of search by debug=true
+	DebugKeys []string `json:"debug_keys" mapstructure:"debug_keys"`
+	// ReverseCache caches reverse geocoding results by geohash of points
+	ReverseCache ReverseCache `json:"reverse_cache" mapstructure:"reverse_cache"`
+	// SignedURLs lets requests authenticate by URL signed with API key instead of the key itself
+	SignedURLs SignedURLs `json:"signed_urls" mapstructure:"signed_urls"`
+	// Access restricts client addresses allowed to use the service
+	Access Access `json:"access" mapstructure:"access"`
+	// Messages is directory of <lang>.json catalogs of error messages extending built in ones
+	Messages string `json:"messages" mapstructure:"messages"`
+	// ChangesIndex keeps changes feed, <elastic_index>-changes by default. ChangesLogSize is
+	// number of the latest changes kept in it
+	ChangesIndex string `json:"changes_index" mapstructure:"changes_index"`
+}
+
+// Access lists networks of clients as CIDRs, single addresses or "private" and "loopback" for
+// private and loopback ranges. Clients of Deny are rejected, with Allow only its clients are
+// served. Requests of TrustedProxies are attributed to the client in RealIPHeader, which is
+// X-Forwarded-For by default
+type Access struct {
+	Allow          []string `json:"allow" mapstructure:"allow"`
+	Deny           []string `json:"deny" mapstructure:"deny"`
+	TrustedProxies []string `json:"trusted_proxies" mapstructure:"trusted_proxies"`
+	RealIPHeader   string   `json:"real_ip_header" mapstructure:"real_ip_header"`
+}
+
+// SignedURLs configures HMAC signed request URLs. URLs are signed by any API key of the
+// configuration or by Keys, which are not allowed any feature by themselves. Expiry of URL
+// can't be later than MaxTTL from now, zero means one day
+type SignedURLs struct {
+	Enabled bool          `json:"enabled" mapstructure:"enabled"`
+	Keys    []string      `json:"keys" mapstructure:"keys"`
+	MaxTTL  time.Duration `json:"max_ttl" mapstructure:"max_ttl"`
+}
+
+// ReverseCache configures cache of reverse geocoding results. Points in the same geohash cell
+// of Precision characters share result, zero precision disables the cache. Size caps cached
+// cells, results expire after TTL
+type ReverseCache struct {
+	Precision int           `json:"precision" mapstructure:"precision"`
+	Size      int           `json:"size" mapstructure:"size"`
+	TTL       time.Duration `json:"ttl" mapstructure:"ttl"`
+}
+
+// Autocomplete configures WebSocket autocomplete sessions: queries are geocoded once client
+// pauses typing for Debounce, sessions without messages for IdleTimeout are closed
+type Autocomplete struct {
+	Debounce    time.Duration `json:"debounce" mapstructure:"debounce"`
+	IdleTimeout time.Duration `json:"idle_timeout" mapstructure:"idle_timeout"`
+}
+
+// Compression configures gzip and brotli compression of responses
+type Compression struct {
+	Disabled    bool `json:"disabled" mapstructure:"disabled"`
+	MinSize     int  `json:"min_size" mapstructure:"min_size"`
+	GzipLevel   int  `json:"gzip_level" mapstructure:"gzip_level"`
+	BrotliLevel int  `json:"brotli_level" mapstructure:"brotli_level"`
+}
+
+type Wikidata struct {
+	Fetch     bool     `json:"fetch" mapstructure:"fetch"`
+	URL       string   `json:"url" mapstructure:"url"`
+	Languages []string `json:"languages" mapstructure:"languages"`
+	// Timeout limits every request to wikidata API
+	Timeout time.Duration `json:"timeout" mapstructure:"timeout"`
+}
+
+// Get reads configuration file, see GetProfile
 func Get() (*Ariadna, error) {
+	return GetProfile("")
+}
+
+// GetProfile reads configuration file with settings of named profile from its profiles
+// section merged over top-level ones. Empty name selects top-level settings only
+func GetProfile(name string) (*Ariadna, error) {
 	var a Ariadna
 	viper.SetConfigName("ariadna")
 	viper.AddConfigPath(".")
@@ -26,9 +419,31 @@ func Get() (*Ariadna, error) {
 	if err != nil {
 		return nil, err
 	}
+	if name != "" {
+		profile := viper.Sub(profilesKey + "." + name)
+		if profile == nil {
+			return nil, fmt.Errorf("unknown profile %q, available: %s", name, strings.Join(Profiles(), ", "))
+		}
+		if err := viper.MergeConfigMap(profile.AllSettings()); err != nil {
+			return nil, err
+		}
+	}
 	err = viper.Unmarshal(&a)
 	if err != nil {
 		return nil, err
 	}
 	return &a, nil
 }
+
+// profilesKey is section of configuration file holding named profiles
+const profilesKey = "profiles"
+
+// Profiles lists names of profiles of read configuration file
+func Profiles() []string {
+	names := make([]string, 0)
+	for name := range viper.GetStringMap(profilesKey) {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -1,9 +1,12 @@
 package config
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -19,3 +22,35 @@ func TestConfig(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "override", c.ElasticIndex)
 }
+
+func TestGetProfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ariadna")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "ariadna.yml"), []byte(`
+elastic_index: addresses
+osm_url: http://download.geofabrik.de/asia/kyrgyzstan-latest.osm.pbf
+import_country: Кыргызстан
+profiles:
+  bishkek:
+    elastic_index: bishkek
+    osm_url: https://example.com/bishkek.osm.pbf
+`), 0644))
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+	os.Unsetenv("ELASTIC_INDEX")
+	viper.Reset()
+	defer viper.Reset()
+
+	c, err := GetProfile("bishkek")
+	require.NoError(t, err)
+	assert.Equal(t, "bishkek", c.ElasticIndex)
+	assert.Equal(t, "https://example.com/bishkek.osm.pbf", c.OSMURL)
+	assert.Equal(t, "Кыргызстан", c.ImportCountry)
+	assert.Equal(t, []string{"bishkek"}, Profiles())
+
+	_, err = GetProfile("osh")
+	assert.Error(t, err)
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// Preset is ready import configuration of a country
+type Preset struct {
+	Code string
+	// Name is name tag of country boundary
+	Name        string
+	OSMURL      string
+	AdminLevels Levels
+	Languages   []string
+	Synonyms    []string
+}
+
+// cyrillicSynonyms are abbreviations of street types common in post-soviet addresses
+var cyrillicSynonyms = []string{
+	"ул, улица",
+	"пр, пр-т, проспект",
+	"пер, переулок",
+	"б-р, бульвар",
+	"мкр, микрорайон",
+	"ж/м, жилмассив",
+}
+
+var presets = map[string]Preset{
+	"KG": {
+		Code:        "KG",
+		Name:        "Кыргызстан",
+		OSMURL:      "http://download.geofabrik.de/asia/kyrgyzstan-latest.osm.pbf",
+		AdminLevels: Levels{"2": "country"},
+		Languages:   []string{"ky", "ru", "en"},
+		Synonyms:    append([]string{"көч, көчөсү"}, cyrillicSynonyms...),
+	},
+	"KZ": {
+		Code:        "KZ",
+		Name:        "Қазақстан",
+		OSMURL:      "http://download.geofabrik.de/asia/kazakhstan-latest.osm.pbf",
+		AdminLevels: Levels{"2": "country"},
+		Languages:   []string{"kk", "ru", "en"},
+		Synonyms:    append([]string{"көш, көшесі", "даң, даңғылы"}, cyrillicSynonyms...),
+	},
+	"TJ": {
+		Code:        "TJ",
+		Name:        "Тоҷикистон",
+		OSMURL:      "http://download.geofabrik.de/asia/tajikistan-latest.osm.pbf",
+		AdminLevels: Levels{"2": "country"},
+		Languages:   []string{"tg", "ru", "en"},
+		Synonyms:    append([]string{"кӯч, кӯчаи"}, cyrillicSynonyms...),
+	},
+	"UZ": {
+		Code:        "UZ",
+		Name:        "Oʻzbekiston",
+		OSMURL:      "http://download.geofabrik.de/asia/uzbekistan-latest.osm.pbf",
+		AdminLevels: Levels{"2": "country"},
+		Languages:   []string{"uz", "ru", "en"},
+		Synonyms:    append([]string{"ko'ch, ko'chasi"}, cyrillicSynonyms...),
+	},
+}
+
+// PresetByCode returns preset of country by ISO 3166-1 alpha-2 code
+func PresetByCode(code string) (Preset, bool) {
+	p, ok := presets[strings.ToUpper(code)]
+	return p, ok
+}
+
+// PresetCodes lists codes of built-in presets
+func PresetCodes() []string {
+	codes := make([]string, 0, len(presets))
+	for code := range presets {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// ApplyPreset configures import of preset country, explicitly configured synonyms and languages are kept
+func (a *Ariadna) ApplyPreset(p Preset) {
+	a.OSMURL = p.OSMURL
+	a.OSMFilename = path.Base(p.OSMURL)
+	a.ImportCountry = p.Name
+	a.AdminLevels = p.AdminLevels
+	if len(a.Synonyms) == 0 {
+		a.Synonyms = p.Synonyms
+	}
+	if len(a.Wikidata.Languages) == 0 {
+		a.Wikidata.Languages = p.Languages
+	}
+}
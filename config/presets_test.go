@@ -0,0 +1,22 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyPreset(t *testing.T) {
+	p, ok := PresetByCode("kg")
+	require.True(t, ok)
+	a := Ariadna{Wikidata: Wikidata{Languages: []string{"en"}}}
+	a.ApplyPreset(p)
+	assert.Equal(t, "kyrgyzstan-latest.osm.pbf", a.OSMFilename)
+	assert.Equal(t, "Кыргызстан", a.ImportCountry)
+	assert.Equal(t, []string{"en"}, a.Wikidata.Languages)
+	assert.NotEmpty(t, a.Synonyms)
+
+	_, ok = PresetByCode("XX")
+	assert.False(t, ok)
+}
@@ -0,0 +1,153 @@
+// Package coordinates recognizes coordinates typed as search query:
+// decimal degrees, degrees with minutes and seconds, and UTM
+package coordinates
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	decimalRe = regexp.MustCompile(`^([-+]?\d{1,3}(?:[.,]\d+)?)\s*([NSns])?\s*([,;]|\s)\s*([-+]?\d{1,3}(?:[.,]\d+)?)\s*([EWew])?$`)
+	dmsPart   = `(\d{1,3}(?:\.\d+)?)\s*°\s*(?:(\d{1,2}(?:\.\d+)?)\s*['′]\s*)?(?:(\d{1,2}(?:\.\d+)?)\s*(?:["″]|'')\s*)?([NSEWnsew])`
+	dmsRe     = regexp.MustCompile(`^` + dmsPart + `[,;\s]*` + dmsPart + `$`)
+	utmRe     = regexp.MustCompile(`^(\d{1,2})\s*([C-HJ-NP-Xc-hj-np-x])\s+(\d{5,7}(?:\.\d+)?)\s*(?:m\s*)?E?\s+(\d{1,8}(?:\.\d+)?)\s*(?:m\s*)?N?$`)
+)
+
+// Parse returns point described by s. It reports false when s does not look like coordinates
+func Parse(s string) (float64, float64, bool) {
+	s = strings.TrimSpace(s)
+	for _, parse := range []func(string) (float64, float64, bool){parseDecimal, parseDMS, parseUTM} {
+		if lat, lon, ok := parse(s); ok && valid(lat, lon) {
+			return lat, lon, true
+		}
+	}
+	return 0, 0, false
+}
+
+func valid(lat, lon float64) bool {
+	return lat >= -90 && lat <= 90 && lon >= -180 && lon <= 180
+}
+
+func parseDecimal(s string) (float64, float64, bool) {
+	m := decimalRe.FindStringSubmatch(s)
+	if m == nil {
+		return 0, 0, false
+	}
+	// two integers separated by space are rather house number and flat, like "7 12"
+	fractional := strings.ContainsAny(m[1]+m[4], ".,")
+	if !fractional && m[3] != "," && m[3] != ";" && m[2] == "" && m[5] == "" {
+		return 0, 0, false
+	}
+	// comma is decimal separator only when numbers are separated by space or semicolon
+	lat, err := strconv.ParseFloat(strings.Replace(m[1], ",", ".", 1), 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	lon, err := strconv.ParseFloat(strings.Replace(m[4], ",", ".", 1), 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	if strings.EqualFold(m[2], "S") {
+		lat = -lat
+	}
+	if strings.EqualFold(m[5], "W") {
+		lon = -lon
+	}
+	return lat, lon, true
+}
+
+func parseDMS(s string) (float64, float64, bool) {
+	m := dmsRe.FindStringSubmatch(s)
+	if m == nil {
+		return 0, 0, false
+	}
+	first, firstHemisphere := dmsValue(m[1:5])
+	second, secondHemisphere := dmsValue(m[5:9])
+	if firstHemisphere == "E" || firstHemisphere == "W" {
+		first, second = second, first
+		firstHemisphere, secondHemisphere = secondHemisphere, firstHemisphere
+	}
+	if (firstHemisphere != "N" && firstHemisphere != "S") || (secondHemisphere != "E" && secondHemisphere != "W") {
+		return 0, 0, false
+	}
+	if firstHemisphere == "S" {
+		first = -first
+	}
+	if secondHemisphere == "W" {
+		second = -second
+	}
+	return first, second, true
+}
+
+func dmsValue(parts []string) (float64, string) {
+	var value float64
+	for idx, divisor := range []float64{1, 60, 3600} {
+		if parts[idx] == "" {
+			continue
+		}
+		v, _ := strconv.ParseFloat(parts[idx], 64)
+		value += v / divisor
+	}
+	return value, strings.ToUpper(parts[3])
+}
+
+// WGS84 ellipsoid and UTM projection constants
+const (
+	utmScale        = 0.9996
+	wgs84Radius     = 6378137.0
+	wgs84Eccentric2 = 0.00669438
+	falseEasting    = 500000.0
+	falseNorthing   = 10000000.0
+)
+
+func parseUTM(s string) (float64, float64, bool) {
+	m := utmRe.FindStringSubmatch(s)
+	if m == nil {
+		return 0, 0, false
+	}
+	zone, _ := strconv.Atoi(m[1])
+	easting, _ := strconv.ParseFloat(m[3], 64)
+	northing, _ := strconv.ParseFloat(m[4], 64)
+	if zone < 1 || zone > 60 {
+		return 0, 0, false
+	}
+	northern := strings.ToUpper(m[2]) >= "N"
+	lat, lon := utmToLatLon(zone, northern, easting, northing)
+	return lat, lon, true
+}
+
+// utmToLatLon converts UTM coordinates to WGS84 degrees
+func utmToLatLon(zone int, northern bool, easting, northing float64) (float64, float64) {
+	e2 := wgs84Eccentric2
+	ep2 := e2 / (1 - e2)
+	x := easting - falseEasting
+	y := northing
+	if !northern {
+		y -= falseNorthing
+	}
+	lonOrigin := float64((zone-1)*6-180+3) * math.Pi / 180
+
+	mu := y / utmScale / (wgs84Radius * (1 - e2/4 - 3*e2*e2/64 - 5*e2*e2*e2/256))
+	e1 := (1 - math.Sqrt(1-e2)) / (1 + math.Sqrt(1-e2))
+	phi1 := mu + (3*e1/2-27*math.Pow(e1, 3)/32)*math.Sin(2*mu) +
+		(21*e1*e1/16-55*math.Pow(e1, 4)/32)*math.Sin(4*mu) +
+		(151*math.Pow(e1, 3)/96)*math.Sin(6*mu)
+
+	sin, cos, tan := math.Sin(phi1), math.Cos(phi1), math.Tan(phi1)
+	n1 := wgs84Radius / math.Sqrt(1-e2*sin*sin)
+	t1 := tan * tan
+	c1 := ep2 * cos * cos
+	r1 := wgs84Radius * (1 - e2) / math.Pow(1-e2*sin*sin, 1.5)
+	d := x / (n1 * utmScale)
+
+	lat := phi1 - (n1*tan/r1)*(d*d/2-
+		(5+3*t1+10*c1-4*c1*c1-9*ep2)*math.Pow(d, 4)/24+
+		(61+90*t1+298*c1+45*t1*t1-252*ep2-3*c1*c1)*math.Pow(d, 6)/720)
+	lon := lonOrigin + (d-
+		(1+2*t1+c1)*math.Pow(d, 3)/6+
+		(5-2*c1+28*t1-3*c1*c1+8*ep2+24*t1*t1)*math.Pow(d, 5)/120)/cos
+	return lat * 180 / math.Pi, lon * 180 / math.Pi
+}
@@ -0,0 +1,39 @@
+package coordinates
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		query    string
+		lat, lon float64
+	}{
+		{"42.87, 74.59", 42.87, 74.59},
+		{"42.87 74.59", 42.87, 74.59},
+		{"-33.86;151.21", -33.86, 151.21},
+		{"42,87 74,59", 42.87, 74.59},
+		{"42.87N 74.59E", 42.87, 74.59},
+		{"42°52'N 74°36'E", 42.866667, 74.6},
+		{"42°52'30\"N, 74°36'0\"E", 42.875, 74.6},
+		{"74°36'E 42°52'N", 42.866667, 74.6},
+		{"18T 580741 4504692", 40.6892, -74.0445},
+		{"7, 12", 7, 12},
+		{"7;12", 7, 12},
+		{"7N 12E", 7, 12},
+		{"42.8 74", 42.8, 74},
+	}
+	for _, c := range cases {
+		lat, lon, ok := Parse(c.query)
+		if assert.True(t, ok, c.query) {
+			assert.InDelta(t, c.lat, lat, 0.001, c.query)
+			assert.InDelta(t, c.lon, lon, 0.001, c.query)
+		}
+	}
+	for _, query := range []string{"Toktogula 125", "95.1, 74.5", "125 12", "Chui 42", "7 12", "-7 12"} {
+		_, _, ok := Parse(query)
+		assert.False(t, ok, query)
+	}
+}
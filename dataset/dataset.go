@@ -0,0 +1,317 @@
+// Package dataset saves processed documents of import into gzipped file of elasticsearch bulk
+// lines and loads them into index later, so parsing and geometry processing of extract is
+// decoupled from writing to elasticsearch
+package dataset
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/model"
+)
+
+// loadBatch is number of documents written to index by single bulk request
+const loadBatch = 5000
+
+// ErrNotSearchable is returned by queries of dataset file, documents are searchable once loaded
+var ErrNotSearchable = errors.New("dataset is not searchable")
+
+type (
+	// Writer is storage of importer saving bulk lines of documents into dataset file
+	Writer struct {
+		mu   sync.Mutex
+		f    *os.File
+		gz   *gzip.Writer
+		path string
+	}
+	// Index receives loaded documents, elastic.Client implements it
+	Index interface {
+		UpdateIndex(ctx context.Context) error
+		BulkWrite(ctx context.Context, buf bytes.Buffer) error
+		WriteImportInfo(ctx context.Context, info elastic.ImportInfo) error
+		DeleteIndices(ctx context.Context) error
+	}
+	// line is bulk action line of dataset or import info saved after documents
+	line struct {
+		Import *elastic.ImportInfo `json:"import"`
+	}
+)
+
+// Create creates dataset file at path, existing file is replaced
+func Create(path string) (*Writer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{f: f, gz: gzip.NewWriter(f), path: path}, nil
+}
+
+// Close flushes compressed stream and closes file, it is safe to call more than once
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.gz == nil {
+		return nil
+	}
+	err := w.gz.Close()
+	if cerr := w.f.Close(); err == nil {
+		err = cerr
+	}
+	w.gz = nil
+	return err
+}
+
+// UpdateIndex does nothing, dataset is created empty
+func (w *Writer) UpdateIndex(ctx context.Context) error {
+	return nil
+}
+
+// ReindexLayers fails, dataset is always saved by full import
+func (w *Writer) ReindexLayers(ctx context.Context, layers []string) error {
+	return errors.New("partial import into dataset is not supported")
+}
+
+// DeleteIndices does nothing, there are no previous imports in dataset
+func (w *Writer) DeleteIndices(ctx context.Context) error {
+	return nil
+}
+
+// IndexVersion returns path of dataset
+func (w *Writer) IndexVersion(ctx context.Context) (string, error) {
+	return w.path, nil
+}
+
+// BulkWrite appends bulk request body to dataset
+func (w *Writer) BulkWrite(ctx context.Context, buf bytes.Buffer) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.gz == nil {
+		return os.ErrClosed
+	}
+	_, err := w.gz.Write(buf.Bytes())
+	return err
+}
+
+// WriteImportInfo saves import after documents, it is recorded in index by Load
+func (w *Writer) WriteImportInfo(ctx context.Context, info elastic.ImportInfo) error {
+	data, err := json.Marshal(line{Import: &info})
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.gz == nil {
+		return os.ErrClosed
+	}
+	_, err = w.gz.Write(append(data, '\n'))
+	return err
+}
+
+// Search is not supported
+func (w *Writer) Search(ctx context.Context, query string) (*elastic.Result, error) {
+	return nil, ErrNotSearchable
+}
+
+// SearchRanked is not supported
+func (w *Writer) SearchRanked(ctx context.Context, query string, profile config.RankingProfile) (*elastic.Result, error) {
+	return nil, ErrNotSearchable
+}
+
+// ExplainSearch is not supported
+func (w *Writer) ExplainSearch(ctx context.Context, query string, profile config.RankingProfile) (*elastic.Explanation, error) {
+	return nil, ErrNotSearchable
+}
+
+// Export is not supported
+func (w *Writer) Export(ctx context.Context, q elastic.ExportQuery, fn func(model.Address) error) error {
+	return ErrNotSearchable
+}
+
+// Slices is not supported
+func (w *Writer) Slices(ctx context.Context) ([]elastic.Slice, error) {
+	return nil, ErrNotSearchable
+}
+
+// PutGeofence is not supported
+func (w *Writer) PutGeofence(ctx context.Context, fence elastic.Geofence) (bool, error) {
+	return false, ErrNotSearchable
+}
+
+// DeleteGeofence is not supported
+func (w *Writer) DeleteGeofence(ctx context.Context, name string) (bool, error) {
+	return false, ErrNotSearchable
+}
+
+// Geofences is not supported
+func (w *Writer) Geofences(ctx context.Context) ([]elastic.Geofence, error) {
+	return nil, ErrNotSearchable
+}
+
+// AddUsage is not supported
+func (w *Writer) AddUsage(ctx context.Context, usage []elastic.Usage) error {
+	return ErrNotSearchable
+}
+
+// Usage is not supported
+func (w *Writer) Usage(ctx context.Context, periods []string) ([]elastic.Usage, error) {
+	return nil, ErrNotSearchable
+}
+
+// WriteAudit does nothing, audit of imports is not kept in dataset
+func (w *Writer) WriteAudit(ctx context.Context, audit elastic.ImportAudit) error {
+	return nil
+}
+
+// Audits is not supported
+func (w *Writer) Audits(ctx context.Context, limit int) ([]elastic.ImportAudit, error) {
+	return nil, ErrNotSearchable
+}
+
+// WriteChanges does nothing, changes feed is not kept in dataset
+func (w *Writer) WriteChanges(ctx context.Context, changes []elastic.Change) error {
+	return nil
+}
+
+// Changes is not supported
+func (w *Writer) Changes(ctx context.Context, since int64, limit int) (*elastic.ChangesPage, error) {
+	return nil, ErrNotSearchable
+}
+
+// TrimChanges does nothing, changes feed is not kept in dataset
+func (w *Writer) TrimChanges(ctx context.Context) error {
+	return nil
+}
+
+// Reverse is not supported
+func (w *Writer) Reverse(ctx context.Context, lat, lon float64) (*elastic.Result, error) {
+	return nil, ErrNotSearchable
+}
+
+// ReverseBatch is not supported
+func (w *Writer) ReverseBatch(ctx context.Context, points []model.Location) ([]*elastic.Result, error) {
+	return nil, ErrNotSearchable
+}
+
+// Nearby is not supported
+func (w *Writer) Nearby(ctx context.Context, layer string, lat, lon float64, distance string) (*elastic.Result, error) {
+	return nil, ErrNotSearchable
+}
+
+// Containing is not supported
+func (w *Writer) Containing(ctx context.Context, layer string, lat, lon float64) (*elastic.Result, error) {
+	return nil, ErrNotSearchable
+}
+
+// Intersecting is not supported
+func (w *Writer) Intersecting(ctx context.Context, layer string, lat, lon, radius float64) (*elastic.Result, error) {
+	return nil, ErrNotSearchable
+}
+
+// Lookup is not supported
+func (w *Writer) Lookup(ctx context.Context, ids []string) (*elastic.Result, error) {
+	return nil, ErrNotSearchable
+}
+
+// Category is not supported
+func (w *Writer) Category(ctx context.Context, layer, category string) (*elastic.Result, error) {
+	return nil, ErrNotSearchable
+}
+
+// Stats is not supported
+func (w *Writer) Stats(ctx context.Context) (*elastic.Stats, error) {
+	return nil, ErrNotSearchable
+}
+
+// WriteDocuments does nothing, analytics are not kept in dataset
+func (w *Writer) WriteDocuments(index string, docs []interface{}) error {
+	return nil
+}
+
+// DeleteDailyIndices does nothing, analytics are not kept in dataset
+func (w *Writer) DeleteDailyIndices(prefix string, retention time.Duration) error {
+	return nil
+}
+
+// SetupLifecycle does nothing, analytics are not kept in dataset
+func (w *Writer) SetupLifecycle(ctx context.Context, alias string, lifecycle config.Lifecycle, retention time.Duration) error {
+	return nil
+}
+
+// Load writes documents of dataset at path into new index in batches, records import saved
+// with them and deletes indices of previous imports. Number of loaded documents is returned
+func Load(ctx context.Context, path string, index Index) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return 0, err
+	}
+	defer gz.Close()
+	if err := index.UpdateIndex(ctx); err != nil {
+		return 0, err
+	}
+	var (
+		r     = bufio.NewReader(gz)
+		buf   bytes.Buffer
+		info  *elastic.ImportInfo
+		count int
+	)
+	for {
+		action, err := r.ReadBytes('\n')
+		if err == io.EOF && len(bytes.TrimSpace(action)) == 0 {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return count, err
+		}
+		var l line
+		if err := json.Unmarshal(action, &l); err != nil {
+			return count, fmt.Errorf("dataset line: %w", err)
+		}
+		if l.Import != nil {
+			info = l.Import
+			continue
+		}
+		doc, err := r.ReadBytes('\n')
+		if err != nil && (err != io.EOF || len(doc) == 0) {
+			return count, fmt.Errorf("dataset: document expected after %s", bytes.TrimSpace(action))
+		}
+		buf.Write(action)
+		buf.Write(doc)
+		if doc[len(doc)-1] != '\n' {
+			buf.WriteByte('\n')
+		}
+		if count++; count%loadBatch == 0 {
+			if err := index.BulkWrite(ctx, buf); err != nil {
+				return count, err
+			}
+			buf = bytes.Buffer{}
+		}
+	}
+	if buf.Len() > 0 {
+		if err := index.BulkWrite(ctx, buf); err != nil {
+			return count, err
+		}
+	}
+	if info != nil {
+		info.ImportedAt = time.Now().UTC()
+		if err := index.WriteImportInfo(ctx, *info); err != nil {
+			return count, err
+		}
+	}
+	return count, index.DeleteIndices(ctx)
+}
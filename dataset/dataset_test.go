@@ -0,0 +1,76 @@
+package dataset
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeIndex struct {
+	updated, deleted bool
+	bulks            []string
+	info             *elastic.ImportInfo
+}
+
+func (f *fakeIndex) UpdateIndex(ctx context.Context) error {
+	f.updated = true
+	return nil
+}
+
+func (f *fakeIndex) BulkWrite(ctx context.Context, buf bytes.Buffer) error {
+	f.bulks = append(f.bulks, buf.String())
+	return nil
+}
+
+func (f *fakeIndex) WriteImportInfo(ctx context.Context, info elastic.ImportInfo) error {
+	f.info = &info
+	return nil
+}
+
+func (f *fakeIndex) DeleteIndices(ctx context.Context) error {
+	f.deleted = true
+	return nil
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dataset")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "kg.ndjson.gz")
+
+	ctx := context.Background()
+	w, err := Create(path)
+	require.NoError(t, err)
+	body := `{"index":{"_index":"addresses","_id":"node/1"}}
+{"name":"Ала-Тоо"}
+{"index":{"_index":"addresses","_id":"way/2"}}
+{"name":"Чуй"}
+`
+	require.NoError(t, w.BulkWrite(ctx, *bytes.NewBufferString(body)))
+	extract := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, w.WriteImportInfo(ctx, elastic.ImportInfo{ExtractTime: &extract, Source: "kyrgyzstan-latest.osm.pbf"}))
+	require.NoError(t, w.Close())
+	require.NoError(t, w.Close())
+	_, err = w.Search(ctx, "Чуй")
+	assert.Equal(t, ErrNotSearchable, err)
+
+	index := &fakeIndex{}
+	count, err := Load(ctx, path, index)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.True(t, index.updated)
+	assert.True(t, index.deleted)
+	assert.Equal(t, []string{body}, index.bulks)
+	require.NotNil(t, index.info)
+	assert.Equal(t, "kyrgyzstan-latest.osm.pbf", index.info.Source)
+	assert.Equal(t, extract, index.info.ExtractTime.UTC())
+	assert.False(t, index.info.ImportedAt.IsZero())
+}
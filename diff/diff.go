@@ -0,0 +1,214 @@
+// Package diff compares documents of two imports, so QA and consumers of map data see which
+// streets, POIs and admin areas were added, removed or renamed between them
+package diff
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/model"
+)
+
+// roadLayer holds named roads indexed by importer, one document per way
+const roadLayer = "road"
+
+// fields are document fields read for comparison, geometries are left out
+var fields = []string{"name", "layer", "category", "admin_level", "intersection"}
+
+type (
+	// Scanner streams documents of the index set by elastic.WithIndex
+	Scanner interface {
+		Export(ctx context.Context, q elastic.ExportQuery, fn func(model.Address) error) error
+	}
+	// Feature is POI or admin area which was added or removed
+	Feature struct {
+		ID       string `json:"id"`
+		Name     string `json:"name"`
+		Category string `json:"category,omitempty"`
+		Level    int    `json:"admin_level,omitempty"`
+	}
+	// Rename is name change of document, or of street ways when ID is empty. Count is number
+	// of ways renamed so for streets
+	Rename struct {
+		ID    string `json:"id,omitempty"`
+		Old   string `json:"old"`
+		New   string `json:"new"`
+		Count int    `json:"count,omitempty"`
+	}
+	// Changes lists added, removed and renamed documents
+	Changes struct {
+		Added   []Feature `json:"added"`
+		Removed []Feature `json:"removed"`
+		Renamed []Rename  `json:"renamed"`
+	}
+	// StreetChanges lists street names which appeared or disappeared and renames of their ways
+	StreetChanges struct {
+		Added   []string `json:"added"`
+		Removed []string `json:"removed"`
+		Renamed []Rename `json:"renamed"`
+	}
+	// Report is difference between old and new index
+	Report struct {
+		Old        string        `json:"old"`
+		New        string        `json:"new"`
+		Streets    StreetChanges `json:"streets"`
+		POIs       Changes       `json:"pois"`
+		AdminAreas Changes       `json:"admin_areas"`
+	}
+	// snapshot holds compared documents of one index by id
+	snapshot struct {
+		roads      map[string]string
+		pois       map[string]Feature
+		adminAreas map[string]Feature
+	}
+)
+
+// Run reads streets, POIs and admin areas of both indices and compares them
+func Run(ctx context.Context, s Scanner, oldIndex, newIndex string) (*Report, error) {
+	before, err := read(ctx, s, oldIndex)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", oldIndex, err)
+	}
+	after, err := read(ctx, s, newIndex)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", newIndex, err)
+	}
+	return &Report{
+		Old:        oldIndex,
+		New:        newIndex,
+		Streets:    compareStreets(before.roads, after.roads),
+		POIs:       compare(before.pois, after.pois),
+		AdminAreas: compare(before.adminAreas, after.adminAreas),
+	}, nil
+}
+
+// read collects compared documents of index
+func read(ctx context.Context, s Scanner, index string) (*snapshot, error) {
+	snap := &snapshot{roads: make(map[string]string), pois: make(map[string]Feature), adminAreas: make(map[string]Feature)}
+	q := elastic.ExportQuery{
+		Layers:     []string{roadLayer, elastic.PoiLayer, elastic.BoundaryLayer},
+		Boundaries: true,
+		Fields:     fields,
+	}
+	err := s.Export(elastic.WithIndex(ctx, index), q, func(a model.Address) error {
+		switch a.Layer {
+		case roadLayer:
+			if a.Name != "" {
+				snap.roads[a.ID] = a.Name
+			}
+		case elastic.BoundaryLayer:
+			snap.adminAreas[a.ID] = Feature{ID: a.ID, Name: a.Name, Category: a.Category, Level: a.AdminLevel}
+		case "":
+			if a.Name != "" && !a.Intersection {
+				snap.pois[a.ID] = Feature{ID: a.ID, Name: a.Name, Category: a.Category}
+			}
+		}
+		return nil
+	})
+	return snap, err
+}
+
+// compare finds documents added, removed and renamed by id
+func compare(before, after map[string]Feature) Changes {
+	c := Changes{Added: []Feature{}, Removed: []Feature{}, Renamed: []Rename{}}
+	for id, f := range after {
+		old, ok := before[id]
+		switch {
+		case !ok:
+			c.Added = append(c.Added, f)
+		case old.Name != f.Name:
+			c.Renamed = append(c.Renamed, Rename{ID: id, Old: old.Name, New: f.Name})
+		}
+	}
+	for id, f := range before {
+		if _, ok := after[id]; !ok {
+			c.Removed = append(c.Removed, f)
+		}
+	}
+	sortFeatures(c.Added)
+	sortFeatures(c.Removed)
+	sort.Slice(c.Renamed, func(a, b int) bool { return c.Renamed[a].ID < c.Renamed[b].ID })
+	return c
+}
+
+// compareStreets finds street names missing in either import. Ways keeping id and changing name
+// are renames, names of renames are not reported as added or removed
+func compareStreets(before, after map[string]string) StreetChanges {
+	renames := make(map[[2]string]int)
+	for id, name := range after {
+		if old, ok := before[id]; ok && old != name {
+			renames[[2]string{old, name}]++
+		}
+	}
+	oldNames, newNames := names(before), names(after)
+	renamed := make(map[string]bool, 2*len(renames))
+	c := StreetChanges{Added: []string{}, Removed: []string{}, Renamed: make([]Rename, 0, len(renames))}
+	for pair, count := range renames {
+		c.Renamed = append(c.Renamed, Rename{Old: pair[0], New: pair[1], Count: count})
+		renamed[pair[0]], renamed[pair[1]] = true, true
+	}
+	for name := range newNames {
+		if !oldNames[name] && !renamed[name] {
+			c.Added = append(c.Added, name)
+		}
+	}
+	for name := range oldNames {
+		if !newNames[name] && !renamed[name] {
+			c.Removed = append(c.Removed, name)
+		}
+	}
+	sort.Strings(c.Added)
+	sort.Strings(c.Removed)
+	sort.Slice(c.Renamed, func(a, b int) bool {
+		if c.Renamed[a].Old != c.Renamed[b].Old {
+			return c.Renamed[a].Old < c.Renamed[b].Old
+		}
+		return c.Renamed[a].New < c.Renamed[b].New
+	})
+	return c
+}
+
+func names(roads map[string]string) map[string]bool {
+	set := make(map[string]bool, len(roads))
+	for _, name := range roads {
+		set[name] = true
+	}
+	return set
+}
+
+func sortFeatures(features []Feature) {
+	sort.Slice(features, func(a, b int) bool { return features[a].ID < features[b].ID })
+}
+
+// Print writes summary of report with every change on its own line
+func (r *Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "%s -> %s\n", r.Old, r.New)
+	fmt.Fprintf(w, "streets:      +%d -%d ~%d\n", len(r.Streets.Added), len(r.Streets.Removed), len(r.Streets.Renamed))
+	for _, name := range r.Streets.Added {
+		fmt.Fprintf(w, "  + %s\n", name)
+	}
+	for _, name := range r.Streets.Removed {
+		fmt.Fprintf(w, "  - %s\n", name)
+	}
+	for _, rename := range r.Streets.Renamed {
+		fmt.Fprintf(w, "  ~ %s -> %s (%d ways)\n", rename.Old, rename.New, rename.Count)
+	}
+	printChanges(w, "pois:         ", r.POIs)
+	printChanges(w, "admin areas:  ", r.AdminAreas)
+}
+
+func printChanges(w io.Writer, title string, c Changes) {
+	fmt.Fprintf(w, "%s+%d -%d ~%d\n", title, len(c.Added), len(c.Removed), len(c.Renamed))
+	for _, f := range c.Added {
+		fmt.Fprintf(w, "  + %s %s\n", f.ID, f.Name)
+	}
+	for _, f := range c.Removed {
+		fmt.Fprintf(w, "  - %s %s\n", f.ID, f.Name)
+	}
+	for _, rename := range c.Renamed {
+		fmt.Fprintf(w, "  ~ %s %s -> %s\n", rename.ID, rename.Old, rename.New)
+	}
+}
@@ -0,0 +1,71 @@
+package diff
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// indexScanner exports documents of index set in context
+type indexScanner map[string][]model.Address
+
+func (s indexScanner) Export(ctx context.Context, q elastic.ExportQuery, fn func(model.Address) error) error {
+	index, _ := elastic.ContextIndex(ctx)
+	for _, a := range s[index] {
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	s := indexScanner{
+		"addresses-1": {
+			{ID: "way/1", Layer: roadLayer, Name: "Киевская"},
+			{ID: "way/2", Layer: roadLayer, Name: "Киевская"},
+			{ID: "way/3", Layer: roadLayer, Name: "Советская"},
+			{ID: "way/4", Layer: roadLayer, Name: "Токтогула"},
+			{ID: "node/1", Name: "Бишкек Парк", Category: "mall"},
+			{ID: "node/2", Name: "Кофейня", Category: "cafe"},
+			{ID: "node/3", HouseNumber: "1", Street: "Киевская"},
+			{ID: "relation/1", Layer: elastic.BoundaryLayer, Name: "Первомайский район", AdminLevel: 9},
+		},
+		"addresses-2": {
+			{ID: "way/1", Layer: roadLayer, Name: "Абдрахманова"},
+			{ID: "way/2", Layer: roadLayer, Name: "Абдрахманова"},
+			{ID: "way/4", Layer: roadLayer, Name: "Токтогула"},
+			{ID: "way/5", Layer: roadLayer, Name: "Чуй"},
+			{ID: "node/1", Name: "Бишкек Парк", Category: "mall"},
+			{ID: "node/2", Name: "Кофе", Category: "cafe"},
+			{ID: "node/4", Name: "Аптека", Category: "pharmacy"},
+			{ID: "node/5", Name: "Киевская & Чуй", Intersection: true},
+			{ID: "relation/1", Layer: elastic.BoundaryLayer, Name: "Первомайский район", AdminLevel: 9},
+			{ID: "relation/2", Layer: elastic.BoundaryLayer, Name: "Ленинский район", AdminLevel: 9},
+		},
+	}
+	r, err := Run(context.Background(), s, "addresses-1", "addresses-2")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"Чуй"}, r.Streets.Added)
+	assert.Equal(t, []string{"Советская"}, r.Streets.Removed)
+	assert.Equal(t, []Rename{{Old: "Киевская", New: "Абдрахманова", Count: 2}}, r.Streets.Renamed)
+
+	assert.Equal(t, []Feature{{ID: "node/4", Name: "Аптека", Category: "pharmacy"}}, r.POIs.Added)
+	assert.Empty(t, r.POIs.Removed)
+	assert.Equal(t, []Rename{{ID: "node/2", Old: "Кофейня", New: "Кофе"}}, r.POIs.Renamed)
+
+	require.Len(t, r.AdminAreas.Added, 1)
+	assert.Equal(t, "relation/2", r.AdminAreas.Added[0].ID)
+	assert.Empty(t, r.AdminAreas.Removed)
+
+	var out bytes.Buffer
+	r.Print(&out)
+	assert.Contains(t, out.String(), "~ Киевская -> Абдрахманова (2 ways)")
+	assert.Contains(t, out.String(), "+ relation/2 Ленинский район")
+}
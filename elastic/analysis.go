@@ -0,0 +1,77 @@
+package elastic
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// analyzerPreset is tokenizer and token filters of text fields suited to scripts of a region.
+// Plugin names elasticsearch plugin providing them, empty for built-in ones
+type analyzerPreset struct {
+	Tokenizer string
+	Filters   []string
+	Plugin    string
+}
+
+var analyzerPresets = map[string]analyzerPreset{
+	"standard": {Tokenizer: "standard", Filters: []string{"lowercase"}},
+	// ICU segments scripts without spaces between words, like Thai, Chinese or Khmer,
+	// and folds case, width and diacritics of any script
+	"icu": {Tokenizer: "icu_tokenizer", Filters: []string{"icu_folding"}, Plugin: "analysis-icu"},
+	// Arabic and Persian letter variants, diacritics and digits are normalized, words
+	// are split by spaces as usual in right-to-left scripts
+	"arabic": {Tokenizer: "standard", Filters: []string{"lowercase", "decimal_digit", "arabic_normalization", "persian_normalization"}},
+	// Japanese morphological analysis, inflected forms are reduced to base ones
+	"kuromoji": {Tokenizer: "kuromoji_tokenizer", Filters: []string{"kuromoji_baseform", "cjk_width", "lowercase"}, Plugin: "analysis-kuromoji"},
+	// Korean morphological analysis, hanja are read as hangul
+	"nori": {Tokenizer: "nori_tokenizer", Filters: []string{"nori_readingform", "lowercase"}, Plugin: "analysis-nori"},
+}
+
+// AnalyzerPresets lists names of analyzer presets
+func AnalyzerPresets() []string {
+	names := make([]string, 0, len(analyzerPresets))
+	for name := range analyzerPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func analyzerPresetByName(name string) (analyzerPreset, error) {
+	if name == "" {
+		name = "standard"
+	}
+	p, ok := analyzerPresets[name]
+	if !ok {
+		return p, fmt.Errorf("unknown analyzer %q, supported: %s", name, strings.Join(AnalyzerPresets(), ", "))
+	}
+	return p, nil
+}
+
+// analysis returns analysis settings with address analyzer of text fields and address_search
+// analyzer which adds synonyms to it at search time
+func analysis(preset analyzerPreset, synonyms []string) map[string]interface{} {
+	search := append([]string(nil), preset.Filters...)
+	settings := map[string]interface{}{}
+	if len(synonyms) > 0 {
+		settings["filter"] = map[string]interface{}{
+			"address_synonyms": map[string]interface{}{
+				"type":     "synonym_graph",
+				"synonyms": synonyms,
+			},
+		}
+		search = append(search, "address_synonyms")
+	}
+	settings["analyzer"] = map[string]interface{}{
+		"address": map[string]interface{}{
+			"tokenizer": preset.Tokenizer,
+			"filter":    preset.Filters,
+		},
+		"address_search": map[string]interface{}{
+			"tokenizer": preset.Tokenizer,
+			"filter":    search,
+		},
+	}
+	return settings
+}
@@ -0,0 +1,79 @@
+package elastic
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// dailySuffix is layout of date suffix of daily indices like analytics-2020.01.31
+const dailySuffix = "2006.01.02"
+
+// DailyIndex returns name of daily index for time t
+func DailyIndex(prefix string, t time.Time) string {
+	return prefix + "-" + t.UTC().Format(dailySuffix)
+}
+
+// WriteDocuments indexes documents into given index with generated ids
+func (c *Client) WriteDocuments(index string, docs []interface{}) error {
+	var buf bytes.Buffer
+	for _, doc := range docs {
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(`{ "index": {} }` + "\n")
+		buf.Write(data)
+		buf.WriteString("\n")
+	}
+	res, err := c.conn.Bulk(bytes.NewReader(buf.Bytes()), c.conn.Bulk.WithIndex(index))
+	if err != nil {
+		return unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return responseError("write documents to "+index, res)
+	}
+	return nil
+}
+
+// DeleteDailyIndices removes daily indices with prefix older than retention
+func (c *Client) DeleteDailyIndices(prefix string, retention time.Duration) error {
+	res, err := c.conn.Indices.Get([]string{prefix + "-*"})
+	if err != nil {
+		return unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return responseError("list indices", res)
+	}
+	var indices map[string]json.RawMessage
+	if err := json.NewDecoder(res.Body).Decode(&indices); err != nil {
+		return err
+	}
+	threshold := time.Now().Add(-retention)
+	var expired []string
+	for name := range indices {
+		day, err := time.Parse(dailySuffix, strings.TrimPrefix(name, prefix+"-"))
+		if err != nil {
+			continue
+		}
+		if day.Before(threshold) {
+			expired = append(expired, name)
+		}
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+	res, err = c.conn.Indices.Delete(expired)
+	if err != nil {
+		return unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return responseError("delete indices", res)
+	}
+	c.logger.Infof("deleted expired indices: %v", expired)
+	return nil
+}
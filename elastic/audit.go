@@ -0,0 +1,133 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// maxAudits caps audit entries read by Audits
+const maxAudits = 1000
+
+// statuses of import runs
+const (
+	ImportSucceeded = "succeeded"
+	ImportPartial   = "partial"
+	ImportFailed    = "failed"
+)
+
+type (
+	// ImportAudit is record of import run: where data came from, how long stages took, how many
+	// documents they wrote, what failed and which index received them
+	ImportAudit struct {
+		ID          string       `json:"id"`
+		Source      string       `json:"source,omitempty"`
+		ExtractTime *time.Time   `json:"extract_time,omitempty"`
+		Index       string       `json:"index,omitempty"`
+		Layers      []string     `json:"layers,omitempty"`
+		StartedAt   time.Time    `json:"started_at"`
+		FinishedAt  time.Time    `json:"finished_at"`
+		Status      string       `json:"status"`
+		Documents   int64        `json:"documents"`
+		Stages      []StageAudit `json:"stages"`
+		Errors      []string     `json:"errors,omitempty"`
+	}
+	// StageAudit is timing of import stage and number of documents it wrote
+	StageAudit struct {
+		Name       string    `json:"name"`
+		StartedAt  time.Time `json:"started_at"`
+		DurationMS int64     `json:"duration_ms"`
+		Documents  int64     `json:"documents"`
+		Error      string    `json:"error,omitempty"`
+	}
+)
+
+// AuditIndex returns index audit of imports is kept in, it is not behind the alias
+func (c *Client) AuditIndex() string {
+	if c.config.Audit.Index != "" {
+		return c.config.Audit.Index
+	}
+	return c.config.ElasticIndex + "-audit"
+}
+
+// WriteAudit records audit of import run, index of audit defaults to the one created by import
+func (c *Client) WriteAudit(ctx context.Context, audit ImportAudit) error {
+	if audit.Index == "" {
+		audit.Index = c.createdIndex
+	}
+	err := c.ensureIndex(ctx, c.AuditIndex(), map[string]interface{}{
+		"id":          map[string]interface{}{"type": "keyword"},
+		"source":      map[string]interface{}{"type": "keyword"},
+		"index":       map[string]interface{}{"type": "keyword"},
+		"status":      map[string]interface{}{"type": "keyword"},
+		"started_at":  map[string]interface{}{"type": "date"},
+		"finished_at": map[string]interface{}{"type": "date"},
+		// stages and errors are only read back
+		"stages": map[string]interface{}{"type": "object", "enabled": false},
+		"errors": map[string]interface{}{"type": "text", "index": false},
+	})
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(audit)
+	if err != nil {
+		return err
+	}
+	res, err := c.conn.Index(c.AuditIndex(), bytes.NewReader(data),
+		c.conn.Index.WithDocumentID(audit.ID),
+		c.conn.Index.WithRefresh("wait_for"),
+		c.conn.Index.WithContext(ctx),
+	)
+	if err != nil {
+		return unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return responseError("write audit of import "+audit.ID, res)
+	}
+	return nil
+}
+
+// Audits returns up to limit audit entries of imports, the latest first. They are read from the
+// primary cluster as followers replicate only the alias
+func (c *Client) Audits(ctx context.Context, limit int) ([]ImportAudit, error) {
+	if limit <= 0 || limit > maxAudits {
+		limit = maxAudits
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"size": limit,
+		"sort": []interface{}{map[string]interface{}{"started_at": "desc"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.conn.Search(
+		c.conn.Search.WithContext(ctx),
+		c.conn.Search.WithIndex(c.AuditIndex()),
+		c.conn.Search.WithBody(bytes.NewReader(data)),
+		c.conn.Search.WithIgnoreUnavailable(true),
+	)
+	if err != nil {
+		return nil, unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, responseError("get audit of imports", res)
+	}
+	var r struct {
+		Hits struct {
+			Hits []struct {
+				Source ImportAudit `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return nil, err
+	}
+	audits := make([]ImportAudit, 0, len(r.Hits.Hits))
+	for _, hit := range r.Hits.Hits {
+		audits = append(audits, hit.Source)
+	}
+	return audits, nil
+}
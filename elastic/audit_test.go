@@ -0,0 +1,62 @@
+package elastic
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maddevsio/ariadna/config"
+)
+
+func TestAudit(t *testing.T) {
+	var written, query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		data, _ := ioutil.ReadAll(r.Body)
+		switch {
+		case r.Method == http.MethodHead && r.URL.Path == "/addresses-audit":
+		case r.Method == http.MethodPut && r.URL.Path == "/addresses-audit/_doc/20220115T100000.000Z":
+			written = string(data)
+			w.Write([]byte(`{"result": "created"}`))
+		case r.URL.Path == "/addresses-audit/_search":
+			query = string(data)
+			w.Write([]byte(`{"hits": {"hits": [{"_source": {"id": "20220115T100000.000Z", "status": "succeeded", "documents": 42,
+				"stages": [{"name": "parse", "duration_ms": 1500}]}}]}}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	c, err := New(&config.Ariadna{ElasticURLs: []string{server.URL}, ElasticIndex: "addresses"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.createdIndex = "addresses-1642240800"
+	ctx := context.Background()
+	audit := ImportAudit{
+		ID:        "20220115T100000.000Z",
+		StartedAt: time.Date(2022, 1, 15, 10, 0, 0, 0, time.UTC),
+		Status:    ImportSucceeded,
+		Stages:    []StageAudit{{Name: "parse", DurationMS: 1500}},
+	}
+	if err := c.WriteAudit(ctx, audit); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(written, `"index":"addresses-1642240800"`) || !strings.Contains(written, `"duration_ms":1500`) {
+		t.Errorf("audit = %s", written)
+	}
+	audits, err := c.Audits(ctx, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(audits) != 1 || audits[0].Documents != 42 || audits[0].Stages[0].DurationMS != 1500 {
+		t.Errorf("audits %+v", audits)
+	}
+	if !strings.Contains(query, `"size":5`) || !strings.Contains(query, `{"started_at":"desc"}`) {
+		t.Errorf("query = %s", query)
+	}
+}
@@ -0,0 +1,309 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v7/esapi"
+	"github.com/maddevsio/ariadna/memory"
+)
+
+const (
+	defaultBulkConcurrency = 4
+	defaultBulkChunk       = 5 << 20
+	defaultBulkRetries     = 5
+	defaultQueueThreshold  = 200
+	defaultQueueCheck      = 5 * time.Second
+	// memoryChunkDivisor shrinks bulk chunks when memory is near budget
+	memoryChunkDivisor = 4
+
+	bulkBackoff    = 100 * time.Millisecond
+	maxBulkBackoff = 10 * time.Second
+)
+
+// bulkLimiter adapts number of concurrent bulk requests to health of the cluster. Limit is
+// halved when documents are rejected by full write queues or the queues grow, and grows
+// by one with every accepted request up to configured maximum
+type bulkLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	max    int
+	active int
+	// wake is closed when request finishes or limit grows
+	wake chan struct{}
+
+	// rejected is total of write rejections of cluster nodes at the last check
+	rejected int64
+	checked  time.Time
+}
+
+func newBulkLimiter(max int) *bulkLimiter {
+	if max <= 0 {
+		max = defaultBulkConcurrency
+	}
+	return &bulkLimiter{limit: max, max: max, wake: make(chan struct{})}
+}
+
+// acquire waits until another bulk request may be sent
+func (l *bulkLimiter) acquire(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		if l.active < l.limit {
+			l.active++
+			l.mu.Unlock()
+			return nil
+		}
+		wake := l.wake
+		l.mu.Unlock()
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (l *bulkLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active--
+	l.notify()
+}
+
+// slowDown halves concurrency, it is at least one request
+func (l *bulkLimiter) slowDown() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.limit > 1 {
+		l.limit /= 2
+	}
+	return l.limit
+}
+
+// speedUp lets one more request run concurrently
+func (l *bulkLimiter) speedUp() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.limit < l.max {
+		l.limit++
+		l.notify()
+	}
+}
+
+func (l *bulkLimiter) notify() {
+	close(l.wake)
+	l.wake = make(chan struct{})
+}
+
+// due reports whether queues of the cluster should be checked, only one caller gets true per interval
+func (l *bulkLimiter) due(interval time.Duration) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if time.Since(l.checked) < interval {
+		return false
+	}
+	l.checked = time.Now()
+	return true
+}
+
+// bulk sends documents of bulk request body in chunks, as many at once as the limiter allows.
+// Documents rejected because write queues are full are retried with backoff
+func (c *Client) bulk(ctx context.Context, body []byte, options []func(*esapi.BulkRequest)) error {
+	b := c.config.Backpressure
+	size := b.ChunkSize
+	if size <= 0 {
+		size = defaultBulkChunk
+	}
+	if memory.Pressure(c.config.Memory.Max) {
+		// chunks are copied for requests, smaller and fewer of them hold less memory
+		size /= memoryChunkDivisor
+		limit := c.bulkLimiter.slowDown()
+		c.logger.Warnf("memory near budget, bulk insert in chunks of %d KiB with %d concurrent requests", size>>10, limit)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg    sync.WaitGroup
+		once  sync.Once
+		first error
+	)
+	fail := func(err error) {
+		once.Do(func() {
+			first = err
+			cancel()
+		})
+	}
+	for _, chunk := range bulkChunks(body, size) {
+		c.checkQueues(ctx)
+		if err := c.bulkLimiter.acquire(ctx); err != nil {
+			fail(err)
+			break
+		}
+		wg.Add(1)
+		go func(chunk [][]byte) {
+			defer wg.Done()
+			defer c.bulkLimiter.release()
+			if err := c.bulkChunk(ctx, chunk, options); err != nil {
+				fail(err)
+			}
+		}(chunk)
+	}
+	wg.Wait()
+	return first
+}
+
+// bulkChunk sends lines of bulk request, retrying documents rejected by busy cluster
+func (c *Client) bulkChunk(ctx context.Context, lines [][]byte, options []func(*esapi.BulkRequest)) error {
+	retries := c.config.Backpressure.MaxRetries
+	if retries <= 0 {
+		retries = defaultBulkRetries
+	}
+	backoff := bulkBackoff
+	for attempt := 0; ; attempt++ {
+		res, err := c.conn.Bulk(bytes.NewReader(bytes.Join(append(lines, nil), []byte("\n"))), options...)
+		if err != nil {
+			return unavailable(err)
+		}
+		var retry [][]byte
+		switch {
+		case res.StatusCode == http.StatusTooManyRequests:
+			retry = lines
+		case res.IsError():
+			res.Body.Close()
+			return responseError("perform bulk insert", res)
+		default:
+			var body bulkResponse
+			err := json.NewDecoder(res.Body).Decode(&body)
+			if err != nil {
+				res.Body.Close()
+				return err
+			}
+			retry = body.busy(lines)
+			c.reportRejected(body.rejected())
+		}
+		res.Body.Close()
+		if len(retry) == 0 {
+			c.bulkLimiter.speedUp()
+			return nil
+		}
+		if attempt >= retries {
+			return fmt.Errorf("%w: bulk insert: %d documents rejected by busy cluster after %d retries", ErrIndexUnavailable, len(retry)/2, retries)
+		}
+		limit := c.bulkLimiter.slowDown()
+		c.logger.Warnf("bulk insert: %d documents rejected by busy cluster, retrying in %v with %d concurrent requests", len(retry)/2, backoff, limit)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if backoff *= 2; backoff > maxBulkBackoff {
+			backoff = maxBulkBackoff
+		}
+		lines = retry
+	}
+}
+
+// reportRejected logs documents rejected one by one, they don't fail the request and would
+// be missing silently
+func (c *Client) reportRejected(rejected []string) {
+	if len(rejected) == 0 {
+		return
+	}
+	n := len(rejected)
+	if n > maxReportedRejects {
+		rejected = rejected[:maxReportedRejects]
+	}
+	c.logger.Warnf("bulk insert: %d documents rejected, %s", n, strings.Join(rejected, "; "))
+}
+
+// checkQueues slows bulk requests down when write queues of the cluster grow over threshold
+// or nodes rejected writes since the previous check
+func (c *Client) checkQueues(ctx context.Context) {
+	b := c.config.Backpressure
+	interval, threshold := b.CheckInterval, b.QueueThreshold
+	if interval <= 0 {
+		interval = defaultQueueCheck
+	}
+	if threshold <= 0 {
+		threshold = defaultQueueThreshold
+	}
+	if !c.bulkLimiter.due(interval) {
+		return
+	}
+	queue, rejected, err := c.writeQueues(ctx)
+	if err != nil {
+		c.logger.Warnf("could not check write queues: %v", err)
+		return
+	}
+	l := c.bulkLimiter
+	l.mu.Lock()
+	previous := l.rejected
+	l.rejected = rejected
+	l.mu.Unlock()
+	if queue > threshold || (previous > 0 && rejected > previous) {
+		limit := l.slowDown()
+		c.logger.Warnf("write queues hold %d requests, %d rejected since last check, %d concurrent bulk requests", queue, rejected-previous, limit)
+	}
+}
+
+// writeQueues returns queued requests and total rejections of write thread pools of all nodes
+func (c *Client) writeQueues(ctx context.Context) (int, int64, error) {
+	res, err := c.conn.Nodes.Stats(c.conn.Nodes.Stats.WithMetric("thread_pool"), c.conn.Nodes.Stats.WithContext(ctx))
+	if err != nil {
+		return 0, 0, unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return 0, 0, responseError("get thread pool stats", res)
+	}
+	var stats struct {
+		Nodes map[string]struct {
+			ThreadPool map[string]struct {
+				Queue    int   `json:"queue"`
+				Rejected int64 `json:"rejected"`
+			} `json:"thread_pool"`
+		} `json:"nodes"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&stats); err != nil {
+		return 0, 0, err
+	}
+	var queue int
+	var rejected int64
+	for _, node := range stats.Nodes {
+		// write pool was named bulk before elasticsearch 6.3
+		for _, name := range []string{"write", "bulk"} {
+			if pool, ok := node.ThreadPool[name]; ok {
+				queue += pool.Queue
+				rejected += pool.Rejected
+				break
+			}
+		}
+	}
+	return queue, rejected, nil
+}
+
+// bulkChunks splits bulk request body into chunks of action and document line pairs of about size bytes
+func bulkChunks(body []byte, size int) [][][]byte {
+	lines := bytes.Split(bytes.TrimRight(body, "\n"), []byte("\n"))
+	var chunks [][][]byte
+	var chunk [][]byte
+	bytesInChunk := 0
+	for n := 0; n+1 < len(lines); n += 2 {
+		if len(chunk) > 0 && bytesInChunk+len(lines[n])+len(lines[n+1]) > size {
+			chunks = append(chunks, chunk)
+			chunk, bytesInChunk = nil, 0
+		}
+		chunk = append(chunk, lines[n], lines[n+1])
+		bytesInChunk += len(lines[n]) + len(lines[n+1]) + 2
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
@@ -0,0 +1,135 @@
+package elastic
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/maddevsio/ariadna/config"
+)
+
+func TestBulkChunks(t *testing.T) {
+	body := []byte("{\"index\":{}}\n{\"n\":1}\n{\"index\":{}}\n{\"n\":2}\n{\"index\":{}}\n{\"n\":3}\n")
+	chunks := bulkChunks(body, 40)
+	if len(chunks) != 2 || len(chunks[0]) != 4 || len(chunks[1]) != 2 || string(chunks[1][1]) != `{"n":3}` {
+		t.Errorf("chunks = %q", chunks)
+	}
+}
+
+func TestBulkBackpressure(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		attempts = map[string]int{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/_nodes/stats/thread_pool" {
+			w.Write([]byte(`{"nodes": {"a": {"thread_pool": {"write": {"queue": 500, "rejected": 0}}}}}`))
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		var items []string
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var action struct {
+				Index struct {
+					ID string `json:"_id"`
+				} `json:"index"`
+			}
+			json.Unmarshal(scanner.Bytes(), &action)
+			scanner.Scan()
+			id := action.Index.ID
+			attempts[id]++
+			// every document is rejected by full queue once
+			status := 201
+			if attempts[id] == 1 {
+				status = 429
+			}
+			items = append(items, fmt.Sprintf(`{"index": {"_id": %q, "status": %d, "error": %s}}`, id, status, map[bool]string{
+				true: `{"type": "es_rejected_execution_exception", "reason": "queue is full"}`, false: "null",
+			}[status == 429]))
+		}
+		fmt.Fprintf(w, `{"errors": true, "items": [%s]}`, strings.Join(items, ","))
+	}))
+	defer server.Close()
+	c, err := New(&config.Ariadna{
+		ElasticURLs:  []string{server.URL},
+		ElasticIndex: "addresses",
+		Backpressure: config.Backpressure{MaxConcurrent: 4, ChunkSize: 64},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	for n := 0; n < 10; n++ {
+		fmt.Fprintf(&buf, "{\"index\":{\"_id\":\"node/%d\"}}\n{\"n\":%d}\n", n, n)
+	}
+	if err := c.BulkWrite(context.Background(), buf); err != nil {
+		t.Fatal(err)
+	}
+	if len(attempts) != 10 {
+		t.Errorf("attempts = %v", attempts)
+	}
+	for id, n := range attempts {
+		if n != 2 {
+			t.Errorf("%s is sent %d times, expected retry after rejection", id, n)
+		}
+	}
+
+	l := newBulkLimiter(4)
+	if l.slowDown() != 2 || l.slowDown() != 1 || l.slowDown() != 1 {
+		t.Error("limit is halved down to one request")
+	}
+	l.speedUp()
+	if l.limit != 2 {
+		t.Errorf("limit = %d after speed up", l.limit)
+	}
+}
+
+func TestBulkMemoryBudget(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/_nodes/stats/thread_pool" {
+			w.Write([]byte(`{"nodes": {}}`))
+			return
+		}
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		w.Write([]byte(`{"errors": false, "items": []}`))
+	}))
+	defer server.Close()
+	c, err := New(&config.Ariadna{
+		ElasticURLs:  []string{server.URL},
+		ElasticIndex: "addresses",
+		Backpressure: config.Backpressure{MaxConcurrent: 4, ChunkSize: 4 << 10},
+		// any process is over budget of one byte
+		Memory: config.Memory{Max: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	// body fits one chunk of configured size
+	for n := 0; n < 60; n++ {
+		fmt.Fprintf(&buf, "{\"index\":{\"_id\":\"node/%d\"}}\n{\"n\":%d}\n", n, n)
+	}
+	if err := c.BulkWrite(context.Background(), buf); err != nil {
+		t.Fatal(err)
+	}
+	if requests < 3 {
+		t.Errorf("%d requests, expected chunks of quarter size near memory budget", requests)
+	}
+}
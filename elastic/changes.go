@@ -0,0 +1,196 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/maddevsio/ariadna/model"
+)
+
+// defaultChangesKept is number of changes kept in changes index by default
+const defaultChangesKept = 100000
+
+type (
+	// Change is document upsert or delete applied by import, Seq numbers changes of all imports
+	Change struct {
+		Seq      int64          `json:"seq"`
+		Op       string         `json:"op"`
+		ID       string         `json:"id"`
+		Document *model.Address `json:"document,omitempty"`
+	}
+	// ChangesPage is changes after requested seq along with the first and the last seq kept in
+	// changes index, both zero without changes
+	ChangesPage struct {
+		Changes []Change
+		First   int64
+		Last    int64
+	}
+)
+
+// ChangesIndex returns index changes feed is kept in, it is not behind the alias
+func (c *Client) ChangesIndex() string {
+	if c.config.API.ChangesIndex != "" {
+		return c.config.API.ChangesIndex
+	}
+	return c.config.ElasticIndex + "-changes"
+}
+
+// WriteChanges appends changes to changes feed. They are visible to readers once written, so
+// changes of the next call don't overtake them
+func (c *Client) WriteChanges(ctx context.Context, changes []Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	err := c.ensureIndex(ctx, c.ChangesIndex(), map[string]interface{}{
+		"seq": map[string]interface{}{"type": "long"},
+		"op":  map[string]interface{}{"type": "keyword"},
+		"id":  map[string]interface{}{"type": "keyword"},
+		// documents are only read back
+		"document": map[string]interface{}{"type": "object", "enabled": false},
+	})
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, change := range changes {
+		if err := enc.Encode(map[string]interface{}{"index": map[string]interface{}{"_id": strconv.FormatInt(change.Seq, 10)}}); err != nil {
+			return err
+		}
+		if err := enc.Encode(change); err != nil {
+			return err
+		}
+	}
+	res, err := c.conn.Bulk(bytes.NewReader(buf.Bytes()),
+		c.conn.Bulk.WithIndex(c.ChangesIndex()),
+		c.conn.Bulk.WithRefresh("true"),
+		c.conn.Bulk.WithContext(ctx),
+	)
+	if err != nil {
+		return unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return responseError("write changes", res)
+	}
+	var r bulkResponse
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return err
+	}
+	if !r.Errors {
+		return nil
+	}
+	var failed []string
+	for _, item := range r.Items {
+		for _, result := range item {
+			if result.Error != nil {
+				failed = append(failed, fmt.Sprintf("%s: %s", result.ID, result.Error.Type))
+			}
+		}
+	}
+	return fmt.Errorf("write changes: %s", strings.Join(failed, ", "))
+}
+
+// Changes returns up to limit changes after since ordered by seq. They are read from the primary
+// cluster as followers replicate only the alias
+func (c *Client) Changes(ctx context.Context, since int64, limit int) (*ChangesPage, error) {
+	data, err := json.Marshal(map[string]interface{}{
+		"size":  limit,
+		"sort":  []interface{}{map[string]interface{}{"seq": "asc"}},
+		"query": map[string]interface{}{"range": map[string]interface{}{"seq": map[string]interface{}{"gt": since}}},
+		"aggs": map[string]interface{}{
+			"kept": map[string]interface{}{
+				"global": map[string]interface{}{},
+				"aggs": map[string]interface{}{
+					"first": map[string]interface{}{"min": map[string]interface{}{"field": "seq"}},
+					"last":  map[string]interface{}{"max": map[string]interface{}{"field": "seq"}},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.conn.Search(
+		c.conn.Search.WithContext(ctx),
+		c.conn.Search.WithIndex(c.ChangesIndex()),
+		c.conn.Search.WithBody(bytes.NewReader(data)),
+		c.conn.Search.WithIgnoreUnavailable(true),
+	)
+	if err != nil {
+		return nil, unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, responseError("get changes", res)
+	}
+	var r struct {
+		Hits struct {
+			Hits []struct {
+				Source Change `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+		Aggregations struct {
+			Kept struct {
+				First struct {
+					Value *float64 `json:"value"`
+				} `json:"first"`
+				Last struct {
+					Value *float64 `json:"value"`
+				} `json:"last"`
+			} `json:"kept"`
+		} `json:"aggregations"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return nil, err
+	}
+	page := &ChangesPage{Changes: make([]Change, 0, len(r.Hits.Hits))}
+	for _, hit := range r.Hits.Hits {
+		page.Changes = append(page.Changes, hit.Source)
+	}
+	if v := r.Aggregations.Kept.First.Value; v != nil {
+		page.First = int64(*v)
+	}
+	if v := r.Aggregations.Kept.Last.Value; v != nil {
+		page.Last = int64(*v)
+	}
+	return page, nil
+}
+
+// TrimChanges deletes all but the latest api.changes_log_size changes
+func (c *Client) TrimChanges(ctx context.Context) error {
+	kept := int64(c.config.API.ChangesLogSize)
+	if kept <= 0 {
+		kept = defaultChangesKept
+	}
+	page, err := c.Changes(ctx, 0, 0)
+	if err != nil {
+		return err
+	}
+	if page.Last-page.First < kept {
+		return nil
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"query": map[string]interface{}{"range": map[string]interface{}{"seq": map[string]interface{}{"lte": page.Last - kept}}},
+	})
+	if err != nil {
+		return err
+	}
+	res, err := c.conn.DeleteByQuery([]string{c.ChangesIndex()}, bytes.NewReader(body),
+		c.conn.DeleteByQuery.WithConflicts("proceed"),
+		c.conn.DeleteByQuery.WithRefresh(true),
+		c.conn.DeleteByQuery.WithContext(ctx),
+	)
+	if err != nil {
+		return unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return responseError("trim changes", res)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package elastic
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/model"
+)
+
+func TestChanges(t *testing.T) {
+	var bulk, query, deleted string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		data, _ := ioutil.ReadAll(r.Body)
+		switch {
+		case r.Method == http.MethodHead && r.URL.Path == "/addresses-changes":
+		case r.URL.Path == "/addresses-changes/_bulk":
+			bulk = string(data)
+			w.Write([]byte(`{"errors": false, "items": []}`))
+		case r.URL.Path == "/addresses-changes/_search":
+			query = string(data)
+			w.Write([]byte(`{"hits": {"hits": [{"_source": {"seq": 8, "op": "delete", "id": "1"}}]},
+				"aggregations": {"kept": {"first": {"value": 3}, "last": {"value": 12}}}}`))
+		case r.URL.Path == "/addresses-changes/_delete_by_query":
+			deleted = string(data)
+			w.Write([]byte(`{"deleted": 5}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	c, err := New(&config.Ariadna{ElasticURLs: []string{server.URL}, ElasticIndex: "addresses", API: config.API{ChangesLogSize: 5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	err = c.WriteChanges(ctx, []Change{{Seq: 7, Op: "upsert", ID: "2", Document: &model.Address{Name: "a"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(bulk, `{"index":{"_id":"7"}}`) || !strings.Contains(bulk, `"document":{`) {
+		t.Errorf("bulk = %s", bulk)
+	}
+	page, err := c.Changes(ctx, 7, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Changes) != 1 || page.Changes[0].Seq != 8 || page.First != 3 || page.Last != 12 {
+		t.Errorf("page = %+v", page)
+	}
+	if !strings.Contains(query, `"gt":7`) || !strings.Contains(query, `"global":{}`) {
+		t.Errorf("query = %s", query)
+	}
+	if err := c.TrimChanges(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(deleted, `"lte":7`) {
+		t.Errorf("trim = %s", deleted)
+	}
+}
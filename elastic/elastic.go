@@ -6,7 +6,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	es "github.com/elastic/go-elasticsearch/v7"
@@ -17,59 +20,203 @@ import (
 
 type Client struct {
 	conn         *es.Client
+	searchConn   *es.Client
 	config       *config.Ariadna
 	createdIndex string
+	created      int64
+	mu           sync.Mutex
+	shards       map[string]string
 	logger       *logrus.Logger
+	names        fieldNames
+	followers    *followers
+	bulkLimiter  *bulkLimiter
 }
 
 func New(conf *config.Ariadna) (*Client, error) {
-	c, err := es.NewClient(es.Config{
-		Addresses: conf.ElasticURLs,
-	})
+	if _, err := analyzerPresetByName(conf.Analyzer); err != nil {
+		return nil, err
+	}
+	names, err := newFieldNames(conf.FieldNames)
 	if err != nil {
 		return nil, err
 	}
-	return &Client{conn: c, config: conf, logger: logrus.New()}, nil
-}
-func (c *Client) UpdateIndex() error {
-	c.createdIndex = fmt.Sprintf("%s-%d", c.config.ElasticIndex, time.Now().Unix())
-	r := &esapi.IndicesCreateRequest{Index: c.createdIndex}
-	data := `
-{
-    "mappings": {
-			"properties": {
-				"location": {"type":"geo_point"}
-			}
-    }
-}`
-	r.Body = bytes.NewReader([]byte(data))
-	res, err := r.Do(context.TODO(), c.conn.Transport)
+	index := conf.Followers.Index
+	if index == "" {
+		index = conf.ElasticIndex
+	}
+	followers, err := newFollowers(conf.Followers.URLs, index, conf.Followers.CheckInterval, transport(conf.Connections.Search))
 	if err != nil {
+		return nil, err
+	}
+	c, err := connect(conf.Connections.Index, conf.ElasticURLs)
+	if err != nil {
+		return nil, err
+	}
+	search, err := connect(conf.Connections.Search, conf.ElasticURLs)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		conn:       c,
+		searchConn: search,
+		config:     conf,
+		shards:     make(map[string]string),
+		logger:     logrus.New(),
+		names:      names,
+		followers:  followers,
+		// concurrent bulk requests of all import stages are limited together
+		bulkLimiter: newBulkLimiter(conf.Backpressure.MaxConcurrent),
+	}, nil
+}
+func (c *Client) UpdateIndex(ctx context.Context) error {
+	if err := c.checkPipeline(ctx); err != nil {
 		return err
 	}
+	if c.config.Resume {
+		if resumed, err := c.resume(ctx); err != nil || resumed {
+			return err
+		}
+	}
+	c.created = time.Now().Unix()
+	c.createdIndex = fmt.Sprintf("%s-%d", c.config.ElasticIndex, c.created)
+	return c.createIndex(ctx, c.createdIndex, c.config.ElasticIndex)
+}
+
+// checkPipeline fails import early when configured ingest pipeline doesn't exist, otherwise
+// every bulk request of the import would be rejected
+func (c *Client) checkPipeline(ctx context.Context) error {
+	if c.config.Pipeline == "" {
+		return nil
+	}
+	res, err := c.conn.Ingest.GetPipeline(c.conn.Ingest.GetPipeline.WithDocumentID(c.config.Pipeline), c.conn.Ingest.GetPipeline.WithContext(ctx))
+	if err != nil {
+		return unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("ingest pipeline %s is not found", c.config.Pipeline)
+	}
 	if res.IsError() {
-		return fmt.Errorf("could not update settings: %v", res)
+		return responseError("get ingest pipeline", res)
 	}
-	c.logger.Infof("created index %s", c.createdIndex)
-	res, err = c.conn.Indices.PutAlias([]string{c.createdIndex}, c.config.ElasticIndex)
+	return nil
+}
+
+// createIndex creates index with mappings and puts it behind given aliases
+func (c *Client) createIndex(ctx context.Context, index string, aliases ...string) error {
+	r := &esapi.IndicesCreateRequest{Index: index}
+	data, err := json.Marshal(c.indexBody())
 	if err != nil {
 		return err
 	}
+	r.Body = bytes.NewReader(data)
+	res, err := r.Do(ctx, c.conn.Transport)
+	if err != nil {
+		return unavailable(err)
+	}
 	if res.IsError() {
-		return fmt.Errorf("could not create alias: %v", res)
+		return responseError("update settings", res)
+	}
+	c.logger.Infof("created index %s", index)
+	for _, alias := range aliases {
+		res, err = c.conn.Indices.PutAlias([]string{index}, alias, c.conn.Indices.PutAlias.WithContext(ctx))
+		if err != nil {
+			return unavailable(err)
+		}
+		if res.IsError() {
+			return responseError("create alias", res)
+		}
+		c.logger.Infof("alias %s was created", alias)
 	}
-	c.logger.Info("alias was created")
 	return nil
 }
-func (c *Client) DeleteIndices() error {
+
+// indexBody returns settings and mappings of created index. Text fields are analyzed by
+// configured analyzer preset, configured synonyms are applied to them at search time
+func (c *Client) indexBody() map[string]interface{} {
+	mappings := map[string]interface{}{
+		"properties": map[string]interface{}{
+			"location": map[string]string{"type": "geo_point"},
+			"geometry": map[string]string{"type": "geo_shape"},
+			"layer":    map[string]string{"type": "keyword"},
+			"category": map[string]string{"type": "keyword"},
+			"routes":   map[string]string{"type": "keyword"},
+			"fields":   map[string]interface{}{"properties": fieldMappings(c.config.Fields)},
+
+			// area in square meters, centroid and label point of polygons
+			"area":        map[string]string{"type": "double"},
+			"centroid":    map[string]string{"type": "geo_point"},
+			"label_point": map[string]string{"type": "geo_point"},
+
+			// OSM layer, bridge, tunnel and levels of stacked features
+			"vertical": map[string]interface{}{
+				"properties": map[string]interface{}{
+					"layer":  map[string]string{"type": "integer"},
+					"bridge": map[string]string{"type": "boolean"},
+					"tunnel": map[string]string{"type": "boolean"},
+					"levels": map[string]string{"type": "float"},
+				},
+			},
+		},
+	}
+	mappings["properties"] = c.names.keys(mappings["properties"].(map[string]interface{}))
+	body := map[string]interface{}{"mappings": mappings}
+	preset, _ := analyzerPresetByName(c.config.Analyzer)
+	if c.config.Analyzer == "" && len(c.config.Synonyms) == 0 {
+		return body
+	}
+	body["settings"] = map[string]interface{}{"analysis": analysis(preset, c.config.Synonyms)}
+	mappings["dynamic_templates"] = []interface{}{
+		map[string]interface{}{
+			"strings": map[string]interface{}{
+				"match_mapping_type": "string",
+				"mapping": map[string]interface{}{
+					"type":            "text",
+					"analyzer":        "address",
+					"search_analyzer": "address_search",
+					"fields": map[string]interface{}{
+						"keyword": map[string]interface{}{"type": "keyword", "ignore_above": 256},
+					},
+				},
+			},
+		},
+	}
+	return body
+}
+
+// fieldMappings maps fields extracted from tags by their type, strings are searchable as text
+// and filterable by keyword subfield
+func fieldMappings(fields []config.Field) map[string]interface{} {
+	properties := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		switch f.Type {
+		case config.FieldNumber:
+			properties[f.Name] = map[string]string{"type": "double"}
+		case config.FieldBool:
+			properties[f.Name] = map[string]string{"type": "boolean"}
+		case config.FieldList:
+			properties[f.Name] = map[string]string{"type": "keyword"}
+		default:
+			properties[f.Name] = map[string]interface{}{
+				"type": "text",
+				"fields": map[string]interface{}{
+					"keyword": map[string]interface{}{"type": "keyword", "ignore_above": 256},
+				},
+			}
+		}
+	}
+	return properties
+}
+
+func (c *Client) DeleteIndices(ctx context.Context) error {
 	var indicesToDelete []string
 	r := esapi.IndicesGetAliasRequest{Name: []string{c.config.ElasticIndex}}
-	res, err := r.Do(context.TODO(), c.conn.Transport)
+	res, err := r.Do(ctx, c.conn.Transport)
 	if err != nil {
-		return err
+		return unavailable(err)
 	}
 	if res.IsError() {
-		return fmt.Errorf("could not create alias: %v", res)
+		return responseError("get alias", res)
 	}
 	data, err := ioutil.ReadAll(res.Body)
 	if err != nil {
@@ -81,29 +228,119 @@ func (c *Client) DeleteIndices() error {
 		return err
 	}
 	for key := range schema {
-		if key != c.createdIndex && strings.Contains(key, c.config.ElasticIndex) {
+		if key != c.createdIndex && !c.isShard(key) && strings.Contains(key, c.config.ElasticIndex) {
 			indicesToDelete = append(indicesToDelete, key)
 		}
 	}
-	res, err = c.conn.Indices.Delete(indicesToDelete)
+	if len(indicesToDelete) == 0 {
+		// partial import writes into the index behind the alias
+		return nil
+	}
+	if c.config.History.Enabled {
+		return c.retire(ctx, indicesToDelete)
+	}
+	res, err = c.conn.Indices.Delete(indicesToDelete, c.conn.Indices.Delete.WithContext(ctx))
 	if err != nil {
-		return err
+		return unavailable(err)
 	}
 	if res.IsError() {
-		return fmt.Errorf("could not delete indices: %v", res)
+		return responseError("delete indices", res)
 	}
 	c.logger.Infof("deleted indices: %v", indicesToDelete)
 	return nil
 }
 
-func (c *Client) BulkWrite(buf bytes.Buffer) error {
-	res, err := c.conn.Bulk(bytes.NewReader(buf.Bytes()), c.conn.Bulk.WithIndex(c.createdIndex))
+func (c *Client) BulkWrite(ctx context.Context, buf bytes.Buffer) error {
+	if c.config.Sharding.Mode != "" {
+		sharded, err := c.route(ctx, buf)
+		if err != nil {
+			return err
+		}
+		buf = sharded
+	}
+	buf, err := c.names.encodeBulk(buf)
 	if err != nil {
 		return err
 	}
-	if res.IsError() {
-		return fmt.Errorf("could not perform bulk insert: %v", res)
+	options := []func(*esapi.BulkRequest){c.conn.Bulk.WithIndex(c.createdIndex), c.conn.Bulk.WithContext(ctx)}
+	if c.config.Pipeline != "" {
+		// documents are enriched by processors of the pipeline, e.g. geoip or script
+		options = append(options, c.conn.Bulk.WithPipeline(c.config.Pipeline))
+	}
+	if err := c.bulk(ctx, buf.Bytes(), options); err != nil {
+		return err
 	}
 	c.logger.Info("bulk insert is finished")
 	return nil
 }
+
+// maxReportedRejects limits rejected documents listed in log
+const maxReportedRejects = 10
+
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		ID     string `json:"_id"`
+		Status int    `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// rejected describes documents bulk request failed to write
+func (r bulkResponse) rejected() []string {
+	if !r.Errors {
+		return nil
+	}
+	var rejected []string
+	for _, item := range r.Items {
+		for _, result := range item {
+			if result.Error != nil && result.Status != http.StatusTooManyRequests {
+				rejected = append(rejected, fmt.Sprintf("%s: %s: %s", result.ID, result.Error.Type, result.Error.Reason))
+			}
+		}
+	}
+	return rejected
+}
+
+// busy returns action and document lines of documents rejected because write queues were full,
+// they are worth retrying. Items follow order of actions of request lines
+func (r bulkResponse) busy(lines [][]byte) [][]byte {
+	if !r.Errors {
+		return nil
+	}
+	var retry [][]byte
+	for n, item := range r.Items {
+		for _, result := range item {
+			if result.Status == http.StatusTooManyRequests && 2*n+1 < len(lines) {
+				retry = append(retry, lines[2*n], lines[2*n+1])
+			}
+		}
+	}
+	return retry
+}
+
+// IndexVersion returns name of index behind the alias, it changes with every import
+func (c *Client) IndexVersion(ctx context.Context) (string, error) {
+	r := esapi.IndicesGetAliasRequest{Name: []string{c.config.ElasticIndex}}
+	res, err := r.Do(ctx, c.conn.Transport)
+	if err != nil {
+		return "", unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return "", responseError("get alias", res)
+	}
+	var indices map[string]json.RawMessage
+	if err := json.NewDecoder(res.Body).Decode(&indices); err != nil {
+		return "", err
+	}
+	names := make([]string, 0, len(indices))
+	for name := range indices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ","), nil
+}
@@ -0,0 +1,119 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maddevsio/ariadna/config"
+)
+
+func TestBulkRejected(t *testing.T) {
+	data := `{"errors": true, "items": [
+		{"index": {"_id": "1", "status": 201}},
+		{"index": {"_id": "way/2", "status": 400, "error": {"type": "mapper_parsing_exception", "reason": "invalid polygon"}}}
+	]}`
+	var res bulkResponse
+	if err := json.Unmarshal([]byte(data), &res); err != nil {
+		t.Fatal(err)
+	}
+	rejected := res.rejected()
+	if len(rejected) != 1 || rejected[0] != "way/2: mapper_parsing_exception: invalid polygon" {
+		t.Errorf("rejected = %q", rejected)
+	}
+}
+
+func TestNormalizedFields(t *testing.T) {
+	fields, ok := normalizedFields([]string{"name^3", "street^2", "housenumber", "city"})
+	if !ok || strings.Join(fields, ",") != "name_normalized^3,street_normalized^2,housenumber,city" {
+		t.Errorf("fields = %v, %v", fields, ok)
+	}
+	if _, ok := normalizedFields([]string{"housenumber", "city"}); ok {
+		t.Error("fields without names are not replaced")
+	}
+}
+
+func TestIndexBodyAnalyzer(t *testing.T) {
+	if _, err := New(&config.Ariadna{Analyzer: "klingon"}); err == nil {
+		t.Error("unknown analyzer is rejected")
+	}
+	c, err := New(&config.Ariadna{Analyzer: "kuromoji", Synonyms: []string{"丁目, ちょうめ"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(c.indexBody())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body struct {
+		Settings struct {
+			Analysis struct {
+				Analyzer map[string]struct {
+					Tokenizer string   `json:"tokenizer"`
+					Filter    []string `json:"filter"`
+				} `json:"analyzer"`
+			} `json:"analysis"`
+		} `json:"settings"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatal(err)
+	}
+	index, search := body.Settings.Analysis.Analyzer["address"], body.Settings.Analysis.Analyzer["address_search"]
+	if index.Tokenizer != "kuromoji_tokenizer" || search.Tokenizer != "kuromoji_tokenizer" {
+		t.Errorf("tokenizers = %q, %q", index.Tokenizer, search.Tokenizer)
+	}
+	if len(search.Filter) != len(index.Filter)+1 || search.Filter[len(search.Filter)-1] != "address_synonyms" {
+		t.Errorf("search filters = %v, index filters = %v", search.Filter, index.Filter)
+	}
+
+	c, _ = New(&config.Ariadna{})
+	if _, ok := c.indexBody()["settings"]; ok {
+		t.Error("default index has no analysis settings")
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	var bulk string
+	exists := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/_ingest/pipeline/enrich":
+			if !exists {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{}`))
+				return
+			}
+			w.Write([]byte(`{"enrich": {"processors": []}}`))
+		case strings.HasSuffix(r.URL.Path, "/_bulk"):
+			bulk = r.URL.Query().Get("pipeline")
+			w.Write([]byte(`{"errors": false, "items": []}`))
+		default:
+			w.Write([]byte(`{"acknowledged": true}`))
+		}
+	}))
+	defer server.Close()
+	c, err := New(&config.Ariadna{ElasticURLs: []string{server.URL}, ElasticIndex: "addresses", Pipeline: "enrich"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.UpdateIndex(context.Background()); err == nil || !strings.Contains(err.Error(), "enrich") {
+		t.Errorf("missing pipeline fails import, err = %v", err)
+	}
+	exists = true
+	if err := c.UpdateIndex(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	buf.WriteString(`{"index":{"_id":"node/1"}}` + "\n" + `{"name":"Ala-Too"}` + "\n")
+	if err := c.BulkWrite(context.Background(), buf); err != nil {
+		t.Fatal(err)
+	}
+	if bulk != "enrich" {
+		t.Errorf("bulk pipeline = %q", bulk)
+	}
+}
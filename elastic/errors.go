@@ -0,0 +1,30 @@
+package elastic
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/elastic/go-elasticsearch/v7/esapi"
+)
+
+var (
+	// ErrIndexUnavailable is reported when elasticsearch can't be reached, fails or index is missing
+	ErrIndexUnavailable = errors.New("index unavailable")
+	// ErrNotSupported is reported by storages which can't serve a request, e.g. export offline
+	ErrNotSupported = errors.New("not supported")
+)
+
+// unavailable wraps transport error of request to elasticsearch
+func unavailable(err error) error {
+	return fmt.Errorf("%w: %v", ErrIndexUnavailable, err)
+}
+
+// responseError describes failed response. Missing index and server side failures
+// are reported as ErrIndexUnavailable
+func responseError(action string, res *esapi.Response) error {
+	if res.StatusCode == http.StatusNotFound || res.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("%w: could not %s: %v", ErrIndexUnavailable, action, res)
+	}
+	return fmt.Errorf("could not %s: %v", action, res)
+}
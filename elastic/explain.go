@@ -0,0 +1,118 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/model"
+)
+
+type (
+	// Explanation tells why search ranked documents the way it did: request sent to index,
+	// tokens query is analyzed into by every ranking field and score explanation of every hit
+	Explanation struct {
+		Request  json.RawMessage    `json:"request"`
+		Analysis map[string][]Token `json:"analysis"`
+		Hits     []ExplainedHit     `json:"hits"`
+		TimedOut bool               `json:"timed_out"`
+	}
+	// Token is term of analyzed query text
+	Token struct {
+		Token    string `json:"token"`
+		Type     string `json:"type"`
+		Position int    `json:"position"`
+	}
+	// ExplainedHit is found address with its score and explanation of the score by index
+	ExplainedHit struct {
+		Address     model.Address   `json:"address"`
+		Score       float64         `json:"score"`
+		Explanation json.RawMessage `json:"explanation"`
+	}
+)
+
+// ExplainSearch performs search of query ranked by profile like SearchRanked and explains it
+func (c *Client) ExplainSearch(ctx context.Context, query string, profile config.RankingProfile) (*Explanation, error) {
+	body := c.rankedBody(query, profile)
+	body["explain"] = true
+	r, err := c.runSearch(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	request, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	explanation := &Explanation{Request: request, Hits: make([]ExplainedHit, 0, len(r.Hits.Hits)), TimedOut: r.TimedOut}
+	result, err := r.result(c.names)
+	if err != nil {
+		return nil, err
+	}
+	for n, address := range result.Addresses {
+		hit := r.Hits.Hits[n]
+		explanation.Hits = append(explanation.Hits, ExplainedHit{Address: address, Score: hit.Score, Explanation: hit.Explanation})
+	}
+	fields := profile.Fields
+	if len(fields) == 0 {
+		fields = DefaultRanking.Fields
+	}
+	if explanation.Analysis, err = c.analyze(ctx, query, fields); err != nil {
+		return nil, err
+	}
+	return explanation, nil
+}
+
+// analyze returns tokens text is analyzed into by analyzers of fields, boosts of fields are ignored
+func (c *Client) analyze(ctx context.Context, text string, fields []string) (map[string][]Token, error) {
+	index, ok := ContextIndex(ctx)
+	if !ok {
+		// analysis is run on single index, shards of the alias share mapping
+		version, err := c.IndexVersion(ctx)
+		if err != nil {
+			return nil, err
+		}
+		index = strings.Split(version, ",")[0]
+	}
+	analysis := make(map[string][]Token, len(fields))
+	for _, field := range fields {
+		field = strings.SplitN(field, "^", 2)[0]
+		if _, ok := analysis[field]; ok {
+			continue
+		}
+		tokens, err := c.analyzeField(ctx, index, field, text)
+		if err != nil {
+			return nil, err
+		}
+		analysis[field] = tokens
+	}
+	return analysis, nil
+}
+
+// analyzeField returns tokens text is analyzed into by analyzer of field in index
+func (c *Client) analyzeField(ctx context.Context, index, field, text string) ([]Token, error) {
+	data, err := json.Marshal(map[string]string{"field": c.names.name(field), "text": text})
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.conn.Indices.Analyze(
+		c.conn.Indices.Analyze.WithContext(ctx),
+		c.conn.Indices.Analyze.WithIndex(index),
+		c.conn.Indices.Analyze.WithBody(bytes.NewReader(data)),
+	)
+	if err != nil {
+		return nil, unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, responseError("analyze query", res)
+	}
+	var r struct {
+		Tokens []Token `json:"tokens"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return nil, err
+	}
+	return r.Tokens, nil
+}
@@ -0,0 +1,58 @@
+package elastic
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maddevsio/ariadna/config"
+)
+
+func TestExplainSearch(t *testing.T) {
+	var search string
+	analyzed := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		data, _ := ioutil.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/addresses/_search":
+			search = string(data)
+			w.Write([]byte(`{"hits": {"hits": [{"_id": "1", "_score": 4.2, "_source": {"street": "Киевская"},
+				"_explanation": {"value": 4.2, "description": "sum of:", "details": []}}]}}`))
+		case "/_alias/addresses":
+			w.Write([]byte(`{"addresses-2": {"aliases": {"addresses": {}}}, "addresses-1": {"aliases": {"addresses": {}}}}`))
+		case "/addresses-1/_analyze":
+			analyzed[string(data)] = true
+			w.Write([]byte(`{"tokens": [{"token": "киевская", "type": "<ALPHANUM>", "position": 0}]}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	c, err := New(&config.Ariadna{ElasticURLs: []string{server.URL}, ElasticIndex: "addresses"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile := DefaultRanking
+	profile.Fields = []string{"street^2", "name", "street"}
+	explanation, err := c.ExplainSearch(context.Background(), "Киевская", profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(search, `"explain":true`) || !strings.Contains(string(explanation.Request), `"explain":true`) {
+		t.Errorf("search %s, request %s", search, explanation.Request)
+	}
+	if len(explanation.Hits) != 1 || explanation.Hits[0].Score != 4.2 || explanation.Hits[0].Address.Street != "Киевская" ||
+		!strings.Contains(string(explanation.Hits[0].Explanation), "sum of:") {
+		t.Errorf("hits = %+v", explanation.Hits)
+	}
+	if len(analyzed) != 2 || !analyzed[`{"field":"street","text":"Киевская"}`] {
+		t.Errorf("analyzed %v", analyzed)
+	}
+	if tokens := explanation.Analysis["street"]; len(tokens) != 1 || tokens[0].Token != "киевская" {
+		t.Errorf("analysis = %+v", explanation.Analysis)
+	}
+}
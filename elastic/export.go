@@ -0,0 +1,162 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	es "github.com/elastic/go-elasticsearch/v7"
+	"github.com/elastic/go-elasticsearch/v7/esapi"
+	"github.com/maddevsio/ariadna/model"
+)
+
+const (
+	exportPageSize  = 1000
+	exportKeepAlive = time.Minute
+)
+
+// ExportQuery selects documents of export. Text is matched like search query, empty one matches
+// every document except boundaries, other fields restrict documents like ranking profiles do.
+// Boundaries includes admin boundaries, Fields limits exported fields of documents
+type ExportQuery struct {
+	Text       string
+	Layers     []string
+	Categories []string
+	Filters    map[string][]string
+	BBox       *model.BBox
+	Boundaries bool
+	Fields     []string
+}
+
+// Export passes every document matching query to fn in index order. Documents are read by scroll
+// which keeps consistent view of the index while it is exported, the whole export goes to one
+// cluster. Error of fn stops export
+func (c *Client) Export(ctx context.Context, q ExportQuery, fn func(model.Address) error) error {
+	body := map[string]interface{}{
+		"size":  exportPageSize,
+		"sort":  []string{"_doc"},
+		"query": c.exportQuery(q),
+	}
+	if len(q.Fields) > 0 {
+		body["_source"] = c.names.names(q.Fields)
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	var (
+		conn     *es.Client
+		scrollID string
+	)
+	defer func() {
+		if scrollID != "" {
+			c.clearScroll(conn, scrollID)
+		}
+	}()
+	res, err := c.read(ctx, func(reader *es.Client, index string) (*esapi.Response, error) {
+		conn = reader
+		return reader.Search(
+			reader.Search.WithContext(ctx),
+			reader.Search.WithIndex(index),
+			reader.Search.WithBody(bytes.NewReader(data)),
+			reader.Search.WithScroll(exportKeepAlive),
+		)
+	})
+	for {
+		if err != nil {
+			return unavailable(err)
+		}
+		page, err := c.exportPage(res)
+		if err != nil {
+			return err
+		}
+		scrollID = page.ScrollID
+		result, err := page.result(c.names)
+		if err != nil {
+			return err
+		}
+		for _, address := range result.Addresses {
+			if err := fn(address); err != nil {
+				return err
+			}
+		}
+		if len(result.Addresses) < exportPageSize || scrollID == "" {
+			return nil
+		}
+		if res, err = c.scroll(ctx, conn, scrollID); err != nil {
+			return unavailable(err)
+		}
+	}
+}
+
+// scroll requests next page of scrolled documents
+func (c *Client) scroll(ctx context.Context, conn *es.Client, id string) (*esapi.Response, error) {
+	return conn.Scroll(
+		conn.Scroll.WithContext(ctx),
+		conn.Scroll.WithScrollID(id),
+		conn.Scroll.WithScroll(exportKeepAlive),
+	)
+}
+
+// scrollResponse is page of scrolled documents
+type scrollResponse struct {
+	searchResponse
+	ScrollID string `json:"_scroll_id"`
+}
+
+func (c *Client) exportPage(res *esapi.Response) (*scrollResponse, error) {
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, responseError("export documents", res)
+	}
+	var page scrollResponse
+	if err := json.NewDecoder(res.Body).Decode(&page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// clearScroll releases scroll context, it expires by itself when this fails
+func (c *Client) clearScroll(conn *es.Client, id string) {
+	res, err := conn.ClearScroll(conn.ClearScroll.WithScrollID(id))
+	if err != nil {
+		c.logger.Warnf("could not clear scroll: %v", err)
+		return
+	}
+	res.Body.Close()
+}
+
+// exportQuery matches documents of export query
+func (c *Client) exportQuery(q ExportQuery) map[string]interface{} {
+	var match interface{} = map[string]interface{}{"match_all": map[string]interface{}{}}
+	if q.Text != "" {
+		match = map[string]interface{}{
+			"multi_match": map[string]interface{}{
+				"query":    q.Text,
+				"type":     "cross_fields",
+				"operator": DefaultRanking.Operator,
+				"fields":   c.names.names(DefaultRanking.Fields),
+			},
+		}
+	}
+	filters := c.filters(q.Layers, q.Categories, q.Filters)
+	if b := q.BBox; b != nil {
+		filters = append(filters, map[string]interface{}{
+			"geo_bounding_box": map[string]interface{}{
+				c.names.name("location"): map[string]interface{}{
+					"top_left":     map[string]float64{"lat": b.MaxLat, "lon": b.MinLon},
+					"bottom_right": map[string]float64{"lat": b.MinLat, "lon": b.MaxLon},
+				},
+			},
+		})
+	}
+	query := map[string]interface{}{"must": match}
+	if !q.Boundaries {
+		query["must_not"] = c.notBoundary()
+	}
+	if len(filters) > 0 {
+		query["filter"] = filters
+	}
+	return map[string]interface{}{"bool": query}
+}
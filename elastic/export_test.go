@@ -0,0 +1,66 @@
+package elastic
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/model"
+)
+
+func TestExport(t *testing.T) {
+	var (
+		query   string
+		cleared bool
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/addresses/_search":
+			data, _ := ioutil.ReadAll(r.Body)
+			query = string(data)
+			hits := make([]string, exportPageSize)
+			for n := range hits {
+				hits[n] = fmt.Sprintf(`{"_id": "node/%d", "_source": {"name": "Неман"}}`, n)
+			}
+			fmt.Fprintf(w, `{"_scroll_id": "s1", "hits": {"hits": [%s]}}`, strings.Join(hits, ","))
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/_search/scroll"):
+			cleared = true
+			w.Write([]byte(`{"succeeded": true}`))
+		case r.URL.Path == "/_search/scroll":
+			w.Write([]byte(`{"_scroll_id": "s1", "hits": {"hits": [{"_id": "way/1", "_source": {"name": "Неман"}}]}}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	c, err := New(&config.Ariadna{ElasticURLs: []string{server.URL}, ElasticIndex: "addresses"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	q := ExportQuery{Text: "аптека", Categories: []string{"pharmacy"}, BBox: &model.BBox{MinLat: 42, MinLon: 74, MaxLat: 43, MaxLon: 75}}
+	err = c.Export(context.Background(), q, func(a model.Address) error {
+		ids = append(ids, a.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != exportPageSize+1 || ids[exportPageSize] != "way/1" {
+		t.Errorf("exported %d documents", len(ids))
+	}
+	if !cleared {
+		t.Error("scroll is not cleared")
+	}
+	for _, part := range []string{`"sort":["_doc"]`, `"terms":{"category":["pharmacy"]}`, `"top_left":{"lat":43,"lon":74}`} {
+		if !strings.Contains(query, part) {
+			t.Errorf("query has no %s: %s", part, query)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	es "github.com/elastic/go-elasticsearch/v7"
+	"github.com/elastic/go-elasticsearch/v7/esapi"
+)
+
+const defaultFollowerCheck = 10 * time.Second
+
+type (
+	// follower is cluster replicating the index from the primary one
+	follower struct {
+		url  string
+		conn *es.Client
+		// down is unix nano time follower failed at, zero when it is healthy
+		down int64
+	}
+	// followers spread searches over healthy followers round-robin. Follower failing a search
+	// or a health check is skipped until a later health check passes
+	followers struct {
+		index    string
+		interval time.Duration
+		list     []*follower
+		next     uint32
+		once     sync.Once
+	}
+)
+
+// newFollowers connects to follower clusters, nil is returned when none is configured
+func newFollowers(urls []string, index string, interval time.Duration, t http.RoundTripper) (*followers, error) {
+	if len(urls) == 0 {
+		return nil, nil
+	}
+	if interval <= 0 {
+		interval = defaultFollowerCheck
+	}
+	f := &followers{index: index, interval: interval}
+	for _, url := range urls {
+		conn, err := es.NewClient(es.Config{Addresses: []string{url}, Transport: t})
+		if err != nil {
+			return nil, err
+		}
+		f.list = append(f.list, &follower{url: url, conn: conn})
+	}
+	return f, nil
+}
+
+// pick returns the next healthy follower, nil when all of them are down
+func (f *followers) pick() *follower {
+	if f == nil {
+		return nil
+	}
+	for range f.list {
+		next := f.list[int(atomic.AddUint32(&f.next, 1))%len(f.list)]
+		if atomic.LoadInt64(&next.down) == 0 {
+			return next
+		}
+	}
+	return nil
+}
+
+// fail takes follower out of rotation until it passes health check
+func (f *follower) fail() {
+	atomic.CompareAndSwapInt64(&f.down, 0, time.Now().UnixNano())
+}
+
+// check reports whether cluster of follower responds and is not red
+func (f *follower) check(ctx context.Context) bool {
+	res, err := f.conn.Cluster.Health(f.conn.Cluster.Health.WithContext(ctx))
+	if err != nil {
+		return false
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return false
+	}
+	var health struct {
+		Status string `json:"status"`
+	}
+	return json.NewDecoder(res.Body).Decode(&health) == nil && health.Status != "red"
+}
+
+// checkFollowers updates health of followers
+func (c *Client) checkFollowers(ctx context.Context) {
+	for _, f := range c.followers.list {
+		ctx, cancel := context.WithTimeout(ctx, c.followers.interval)
+		healthy := f.check(ctx)
+		cancel()
+		was := atomic.LoadInt64(&f.down) == 0
+		switch {
+		case healthy && !was:
+			atomic.StoreInt64(&f.down, 0)
+			c.logger.Infof("follower %s is back", f.url)
+		case !healthy && was:
+			f.fail()
+			c.logger.Warnf("follower %s is down", f.url)
+		}
+	}
+}
+
+// watchFollowers checks health of followers periodically, it is started by the first search
+func (c *Client) watchFollowers() {
+	c.followers.once.Do(func() {
+		go func() {
+			ticker := time.NewTicker(c.followers.interval)
+			defer ticker.Stop()
+			for range ticker.C {
+				c.checkFollowers(context.Background())
+			}
+		}()
+	})
+}
+
+// reader returns cluster and index searches go to: healthy follower or search connections
+// of the primary cluster
+func (c *Client) reader() (*es.Client, string, *follower) {
+	if c.followers == nil {
+		return c.searchConn, c.config.ElasticIndex, nil
+	}
+	c.watchFollowers()
+	if f := c.followers.pick(); f != nil {
+		return f.conn, c.followers.index, f
+	}
+	return c.searchConn, c.config.ElasticIndex, nil
+}
+
+// read performs search request on reader cluster. Request failed by follower is retried on the
+// primary cluster and the follower is taken out of rotation. Index set by WithIndex is read
+// on the primary cluster
+func (c *Client) read(ctx context.Context, do func(conn *es.Client, index string) (*esapi.Response, error)) (*esapi.Response, error) {
+	if index, ok := ContextIndex(ctx); ok {
+		// followers replicate only the alias
+		return do(c.searchConn, index)
+	}
+	conn, index, f := c.reader()
+	res, err := do(conn, index)
+	if f == nil || ctx.Err() != nil || (err == nil && res.StatusCode < http.StatusInternalServerError) {
+		return res, err
+	}
+	if err == nil {
+		res.Body.Close()
+		err = fmt.Errorf("status %d", res.StatusCode)
+	}
+	f.fail()
+	c.logger.Warnf("follower %s failed, searching primary cluster: %v", f.url, err)
+	return do(c.searchConn, c.config.ElasticIndex)
+}
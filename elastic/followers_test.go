@@ -0,0 +1,74 @@
+package elastic
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maddevsio/ariadna/config"
+)
+
+func TestFollowers(t *testing.T) {
+	var primary, replica []string
+	failing := false
+	cluster := func(requests *[]string, name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if r.URL.Path == "/_cluster/health" {
+				if failing {
+					w.Write([]byte(`{"status": "red"}`))
+					return
+				}
+				w.Write([]byte(`{"status": "green"}`))
+				return
+			}
+			*requests = append(*requests, r.URL.Path)
+			if name == "replica" && failing {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				w.Write([]byte(`{}`))
+				return
+			}
+			w.Write([]byte(`{"hits": {"hits": [{"_id": "node/1", "_source": {"name": "` + name + `"}}]}}`))
+		}))
+	}
+	p, f := cluster(&primary, "primary"), cluster(&replica, "replica")
+	defer p.Close()
+	defer f.Close()
+	c, err := New(&config.Ariadna{
+		ElasticURLs:  []string{p.URL},
+		ElasticIndex: "addresses",
+		Followers:    config.Followers{URLs: []string{f.URL}, Index: "addresses-follower"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := func() string {
+		result, err := c.Lookup(context.Background(), []string{"node/1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.Addresses[0].Name
+	}
+	if got := name(); got != "replica" || replica[0] != "/addresses-follower/_search" {
+		t.Errorf("search went to %s, replica requests = %v", got, replica)
+	}
+
+	failing = true
+	if got := name(); got != "primary" {
+		t.Errorf("failed search is retried on primary, got %s", got)
+	}
+	if got := name(); got != "primary" || len(replica) != 2 {
+		t.Errorf("failed follower is skipped, got %s, replica requests = %v", got, replica)
+	}
+	c.checkFollowers(context.Background())
+	if got := name(); got != "primary" {
+		t.Errorf("red follower stays skipped, got %s", got)
+	}
+
+	failing = false
+	c.checkFollowers(context.Background())
+	if got := name(); got != "replica" || len(primary) != 3 {
+		t.Errorf("recovered follower is back, got %s, primary requests = %v", got, primary)
+	}
+}
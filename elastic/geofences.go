@@ -0,0 +1,157 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// maxGeofences caps fences read by Geofences, they are kept in memory of the geocoder
+const maxGeofences = 10000
+
+// Geofence is named area registered by client. Properties are returned as is by point checks
+type Geofence struct {
+	Name       string                 `json:"name"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+	Geometry   *geojson.Geometry      `json:"geometry"`
+	UpdatedAt  time.Time              `json:"updated_at"`
+}
+
+// GeofenceIndex returns index fences are kept in. It is not behind the alias, so fences
+// outlive imports replacing indices of the alias
+func (c *Client) GeofenceIndex() string {
+	if c.config.Geofences.Index != "" {
+		return c.config.Geofences.Index
+	}
+	return c.config.ElasticIndex + "-geofences"
+}
+
+// PutGeofence creates or replaces fence by its name and reports whether it was created.
+// Index of fences is created on first write
+func (c *Client) PutGeofence(ctx context.Context, fence Geofence) (bool, error) {
+	if err := c.createGeofenceIndex(ctx); err != nil {
+		return false, err
+	}
+	data, err := json.Marshal(fence)
+	if err != nil {
+		return false, err
+	}
+	res, err := c.conn.Index(c.GeofenceIndex(), bytes.NewReader(data),
+		c.conn.Index.WithDocumentID(fence.Name),
+		c.conn.Index.WithRefresh("wait_for"),
+		c.conn.Index.WithContext(ctx),
+	)
+	if err != nil {
+		return false, unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return false, responseError("put geofence "+fence.Name, res)
+	}
+	var r struct {
+		Result string `json:"result"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return false, err
+	}
+	return r.Result == "created", nil
+}
+
+// DeleteGeofence removes fence by name and reports whether it existed
+func (c *Client) DeleteGeofence(ctx context.Context, name string) (bool, error) {
+	res, err := c.conn.Delete(c.GeofenceIndex(), name,
+		c.conn.Delete.WithRefresh("wait_for"),
+		c.conn.Delete.WithContext(ctx),
+	)
+	if err != nil {
+		return false, unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		// missing fence or index without fences
+		return false, nil
+	}
+	if res.IsError() {
+		return false, responseError("delete geofence "+name, res)
+	}
+	return true, nil
+}
+
+// Geofences returns all registered fences. They are read from the primary cluster as
+// followers replicate only the alias
+func (c *Client) Geofences(ctx context.Context) ([]Geofence, error) {
+	data, err := json.Marshal(map[string]interface{}{
+		"size":  maxGeofences,
+		"query": map[string]interface{}{"match_all": map[string]interface{}{}},
+		"sort":  []string{"name"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.conn.Search(
+		c.conn.Search.WithContext(ctx),
+		c.conn.Search.WithIndex(c.GeofenceIndex()),
+		c.conn.Search.WithBody(bytes.NewReader(data)),
+		c.conn.Search.WithIgnoreUnavailable(true),
+	)
+	if err != nil {
+		return nil, unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, responseError("get geofences", res)
+	}
+	var r struct {
+		Hits struct {
+			Hits []struct {
+				Source Geofence `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return nil, err
+	}
+	fences := make([]Geofence, 0, len(r.Hits.Hits))
+	for _, hit := range r.Hits.Hits {
+		fences = append(fences, hit.Source)
+	}
+	return fences, nil
+}
+
+// createGeofenceIndex creates index of fences with geo_shape mapping of geometry unless it exists
+func (c *Client) createGeofenceIndex(ctx context.Context) error {
+	return c.ensureIndex(ctx, c.GeofenceIndex(), map[string]interface{}{
+		"name":       map[string]interface{}{"type": "keyword"},
+		"properties": map[string]interface{}{"type": "object", "enabled": false},
+		"geometry":   map[string]interface{}{"type": "geo_shape"},
+		"updated_at": map[string]interface{}{"type": "date"},
+	})
+}
+
+// ensureIndex creates index outside of the alias with mapping of properties unless it exists
+func (c *Client) ensureIndex(ctx context.Context, index string, properties map[string]interface{}) error {
+	res, err := c.conn.Indices.Exists([]string{index}, c.conn.Indices.Exists.WithContext(ctx))
+	if err != nil {
+		return unavailable(err)
+	}
+	res.Body.Close()
+	if res.StatusCode == http.StatusOK {
+		return nil
+	}
+	err = c.put(ctx, "/"+index, map[string]interface{}{
+		"mappings": map[string]interface{}{"properties": properties},
+	}, "create index "+index)
+	if err != nil && strings.Contains(err.Error(), "resource_already_exists_exception") {
+		// created by concurrent write
+		return nil
+	}
+	if err == nil {
+		c.logger.Infof("created index %s", index)
+	}
+	return err
+}
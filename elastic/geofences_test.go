@@ -0,0 +1,77 @@
+package elastic
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maddevsio/ariadna/config"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+func TestGeofences(t *testing.T) {
+	var (
+		created bool
+		mapping string
+		indexed string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodHead && r.URL.Path == "/addresses-geofences":
+			if !created {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case r.Method == http.MethodPut && r.URL.Path == "/addresses-geofences":
+			data, _ := ioutil.ReadAll(r.Body)
+			created, mapping = true, string(data)
+			w.Write([]byte(`{"acknowledged": true}`))
+		case r.Method == http.MethodPut && r.URL.Path == "/addresses-geofences/_doc/depot":
+			data, _ := ioutil.ReadAll(r.Body)
+			indexed = string(data)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"result": "created"}`))
+		case r.Method == http.MethodDelete && r.URL.Path == "/addresses-geofences/_doc/depot":
+			w.Write([]byte(`{"result": "deleted"}`))
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"result": "not_found"}`))
+		case r.URL.Path == "/addresses-geofences/_search":
+			w.Write([]byte(`{"hits": {"hits": [{"_source": {"name": "depot", "properties": {"zone": "A"}, "geometry": {"type": "Polygon", "coordinates": [[[0,0],[1,0],[1,1],[0,0]]]}}}]}}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	c, err := New(&config.Ariadna{ElasticURLs: []string{server.URL}, ElasticIndex: "addresses"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	fence := Geofence{Name: "depot", Geometry: geojson.NewPolygonGeometry([][][]float64{{{0, 0}, {1, 0}, {1, 1}, {0, 0}}})}
+	isNew, err := c.PutGeofence(ctx, fence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isNew || !strings.Contains(mapping, `"geo_shape"`) || !strings.Contains(indexed, `"name":"depot"`) {
+		t.Errorf("created %v, mapping %s, document %s", isNew, mapping, indexed)
+	}
+
+	fences, err := c.Geofences(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fences) != 1 || fences[0].Name != "depot" || fences[0].Properties["zone"] != "A" || !fences[0].Geometry.IsPolygon() {
+		t.Errorf("fences = %+v", fences)
+	}
+
+	if deleted, err := c.DeleteGeofence(ctx, "depot"); err != nil || !deleted {
+		t.Errorf("deleted %v: %v", deleted, err)
+	}
+	if deleted, err := c.DeleteGeofence(ctx, "missing"); err != nil || deleted {
+		t.Errorf("deleted missing %v: %v", deleted, err)
+	}
+}
@@ -0,0 +1,198 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Slice is data of one import. It is valid from extract time of the import, or import time
+// when extract time is unknown, until the next import. ValidTo of the current import is nil
+type Slice struct {
+	Indices   []string   `json:"indices"`
+	ValidFrom time.Time  `json:"valid_from"`
+	ValidTo   *time.Time `json:"valid_to,omitempty"`
+	Import    ImportInfo `json:"import"`
+}
+
+type indexKey struct{}
+
+// WithIndex returns context which makes searches read given index instead of the alias,
+// e.g. slice of past import. Such searches go to the primary cluster
+func WithIndex(ctx context.Context, index string) context.Context {
+	return context.WithValue(ctx, indexKey{}, index)
+}
+
+// ContextIndex returns index set by WithIndex
+func ContextIndex(ctx context.Context) (string, bool) {
+	index, ok := ctx.Value(indexKey{}).(string)
+	return index, ok
+}
+
+// HistoryAlias returns alias of indices of previous imports kept for queries of past dates
+func (c *Client) HistoryAlias() string {
+	if c.config.History.Alias != "" {
+		return c.config.History.Alias
+	}
+	return c.config.ElasticIndex + "-history"
+}
+
+// Slices returns imports of the alias and of history ordered by validity
+func (c *Client) Slices(ctx context.Context) ([]Slice, error) {
+	current, err := c.importInfos(ctx, c.config.ElasticIndex)
+	if err != nil {
+		return nil, err
+	}
+	history, err := c.importInfos(ctx, c.HistoryAlias())
+	if err != nil {
+		return nil, err
+	}
+	return slices(current, history), nil
+}
+
+// SliceAt returns slice valid at time t, false when t precedes all slices
+func SliceAt(slices []Slice, t time.Time) (Slice, bool) {
+	for n := len(slices) - 1; n >= 0; n-- {
+		if !slices[n].ValidFrom.After(t) {
+			return slices[n], true
+		}
+	}
+	return Slice{}, false
+}
+
+// slices groups indices of the same import into slices ordered by validity, indices
+// without recorded import can't be dated and are skipped
+func slices(indices ...map[string]*ImportInfo) []Slice {
+	byImport := make(map[time.Time]*Slice)
+	for _, infos := range indices {
+		for index, info := range infos {
+			if info == nil {
+				continue
+			}
+			s, ok := byImport[info.ImportedAt]
+			if !ok {
+				s = &Slice{ValidFrom: info.ImportedAt, Import: *info}
+				if info.ExtractTime != nil {
+					s.ValidFrom = *info.ExtractTime
+				}
+				byImport[info.ImportedAt] = s
+			}
+			s.Indices = append(s.Indices, index)
+		}
+	}
+	list := make([]Slice, 0, len(byImport))
+	for _, s := range byImport {
+		sort.Strings(s.Indices)
+		list = append(list, *s)
+	}
+	sort.Slice(list, func(a, b int) bool { return list[a].ValidFrom.Before(list[b].ValidFrom) })
+	for n := 0; n+1 < len(list); n++ {
+		validTo := list[n+1].ValidFrom
+		list[n].ValidTo = &validTo
+	}
+	return list
+}
+
+// importInfos returns imports recorded in metadata of indices behind alias, missing alias has none
+func (c *Client) importInfos(ctx context.Context, alias string) (map[string]*ImportInfo, error) {
+	res, err := c.conn.Indices.GetMapping(
+		c.conn.Indices.GetMapping.WithIndex(alias),
+		c.conn.Indices.GetMapping.WithContext(ctx),
+	)
+	if err != nil {
+		return nil, unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound && alias != c.config.ElasticIndex {
+		return nil, nil
+	}
+	if res.IsError() {
+		return nil, responseError("get import info", res)
+	}
+	var indices map[string]struct {
+		Mappings struct {
+			Meta struct {
+				Import *ImportInfo `json:"import"`
+			} `json:"_meta"`
+		} `json:"mappings"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&indices); err != nil {
+		return nil, err
+	}
+	infos := make(map[string]*ImportInfo, len(indices))
+	for name, index := range indices {
+		infos[name] = index.Mappings.Meta.Import
+	}
+	return infos, nil
+}
+
+// retire moves indices of previous imports from the alias behind history alias and deletes
+// slices which were replaced longer than retention ago
+func (c *Client) retire(ctx context.Context, indices []string) error {
+	history := c.HistoryAlias()
+	actions := make([]interface{}, 0, 2*len(indices))
+	for _, index := range indices {
+		actions = append(actions,
+			map[string]interface{}{"remove": map[string]string{"index": index, "alias": c.config.ElasticIndex}},
+			map[string]interface{}{"add": map[string]string{"index": index, "alias": history}},
+		)
+	}
+	data, err := json.Marshal(map[string]interface{}{"actions": actions})
+	if err != nil {
+		return err
+	}
+	res, err := c.conn.Indices.UpdateAliases(bytes.NewReader(data), c.conn.Indices.UpdateAliases.WithContext(ctx))
+	if err != nil {
+		return unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return responseError("move indices to history", res)
+	}
+	c.logger.Infof("indices %v moved to history alias %s", indices, history)
+	return c.expireHistory(ctx)
+}
+
+// expireHistory deletes history slices replaced before retention
+func (c *Client) expireHistory(ctx context.Context) error {
+	retention := c.config.History.Retention
+	if retention <= 0 {
+		return nil
+	}
+	current, err := c.importInfos(ctx, c.config.ElasticIndex)
+	if err != nil {
+		return err
+	}
+	history, err := c.importInfos(ctx, c.HistoryAlias())
+	if err != nil {
+		return err
+	}
+	threshold := time.Now().Add(-retention)
+	var expired []string
+	for _, s := range slices(current, history) {
+		if s.ValidTo == nil || !s.ValidTo.Before(threshold) {
+			continue
+		}
+		for _, index := range s.Indices {
+			if _, ok := history[index]; ok {
+				expired = append(expired, index)
+			}
+		}
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+	res, err := c.conn.Indices.Delete(expired, c.conn.Indices.Delete.WithContext(ctx))
+	if err != nil {
+		return unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return responseError("delete history indices", res)
+	}
+	c.logger.Infof("deleted expired history indices: %v", expired)
+	return nil
+}
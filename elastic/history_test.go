@@ -0,0 +1,97 @@
+package elastic
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maddevsio/ariadna/config"
+)
+
+func TestSlices(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2022, 1, d, 0, 0, 0, 0, time.UTC) }
+	extract := day(1)
+	list := slices(
+		map[string]*ImportInfo{"addresses-3": {ImportedAt: day(20)}, "addresses-kg-3": {ImportedAt: day(20)}},
+		map[string]*ImportInfo{"addresses-1": {ImportedAt: day(2), ExtractTime: &extract}, "addresses-2": {ImportedAt: day(10)}, "addresses-0": nil},
+	)
+	if len(list) != 3 {
+		t.Fatalf("slices = %+v", list)
+	}
+	if !list[0].ValidFrom.Equal(day(1)) || !list[0].ValidTo.Equal(day(10)) || list[2].ValidTo != nil {
+		t.Errorf("validity = %+v", list)
+	}
+	if strings.Join(list[2].Indices, ",") != "addresses-3,addresses-kg-3" {
+		t.Errorf("indices of current import = %v", list[2].Indices)
+	}
+	if _, ok := SliceAt(list, day(1).Add(-time.Second)); ok {
+		t.Error("date before the first import has slice")
+	}
+	if s, ok := SliceAt(list, day(15)); !ok || s.Indices[0] != "addresses-2" {
+		t.Errorf("slice at 15th = %+v", s)
+	}
+	if s, ok := SliceAt(list, day(25)); !ok || s.ValidTo != nil {
+		t.Errorf("slice at 25th = %+v", s)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	var (
+		actions  string
+		deleted  string
+		searched string
+	)
+	daysAgo := func(d int) string { return time.Now().AddDate(0, 0, -d).UTC().Format(time.RFC3339) }
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/_alias/addresses":
+			w.Write([]byte(`{"addresses-1": {}, "addresses-2": {}}`))
+		case r.URL.Path == "/_aliases":
+			data, _ := ioutil.ReadAll(r.Body)
+			actions = string(data)
+			w.Write([]byte(`{"acknowledged": true}`))
+		case r.URL.Path == "/addresses/_mapping":
+			fmt.Fprintf(w, `{"addresses-2": {"mappings": {"_meta": {"import": {"imported_at": %q}}}}}`, daysAgo(1))
+		case r.URL.Path == "/addresses-history/_mapping":
+			fmt.Fprintf(w, `{"addresses-0": {"mappings": {"_meta": {"import": {"imported_at": %q}}}}, "addresses-1": {"mappings": {"_meta": {"import": {"imported_at": %q}}}}}`, daysAgo(800), daysAgo(400))
+		case r.Method == http.MethodDelete:
+			deleted = r.URL.Path
+			w.Write([]byte(`{"acknowledged": true}`))
+		case strings.HasSuffix(r.URL.Path, "/_search"):
+			searched = r.URL.Path
+			w.Write([]byte(`{"hits": {"hits": []}}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	conf := &config.Ariadna{ElasticURLs: []string{server.URL}, ElasticIndex: "addresses", History: config.History{Enabled: true, Retention: 365 * 24 * time.Hour}}
+	c, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.createdIndex = "addresses-2"
+	ctx := context.Background()
+	if err := c.DeleteIndices(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(actions, `"remove":{"alias":"addresses","index":"addresses-1"}`) || !strings.Contains(actions, `"add":{"alias":"addresses-history","index":"addresses-1"}`) {
+		t.Errorf("alias actions = %s", actions)
+	}
+	if deleted != "/addresses-0" {
+		t.Errorf("deleted %q, the import replaced over retention ago is expected", deleted)
+	}
+
+	if _, err := c.Search(WithIndex(ctx, "addresses-1"), "Киевская"); err != nil {
+		t.Fatal(err)
+	}
+	if searched != "/addresses-1/_search" {
+		t.Errorf("searched %s", searched)
+	}
+}
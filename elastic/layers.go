@@ -0,0 +1,103 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	// PoiLayer names documents of address layer with name, e.g. shops and cafes
+	PoiLayer = "poi"
+	// CrossroadLayer names intersections of streets
+	CrossroadLayer = "crossroad"
+)
+
+// ReindexLayers prepares partial import of layers: documents are written into the index behind
+// the alias instead of a new one, and documents of the layers are deleted from it, so documents
+// excluded by changed rules don't stay. Address and POI documents have no layer field, they are
+// told apart by name, and crossroads by intersection flag
+func (c *Client) ReindexLayers(ctx context.Context, layers []string) error {
+	if c.config.Sharding.Mode != "" {
+		return errors.New("partial import of sharded index is not supported")
+	}
+	indices, err := c.aliasIndices(ctx)
+	if err != nil {
+		return err
+	}
+	if len(indices) != 1 {
+		return fmt.Errorf("partial import needs single index behind %s, found %v: finish or resume import first", c.config.ElasticIndex, indices)
+	}
+	if err := c.checkPipeline(ctx); err != nil {
+		return err
+	}
+	c.createdIndex = indices[0]
+	body, err := json.Marshal(map[string]interface{}{"query": c.layersQuery(layers)})
+	if err != nil {
+		return err
+	}
+	res, err := c.conn.DeleteByQuery([]string{c.createdIndex}, bytes.NewReader(body),
+		c.conn.DeleteByQuery.WithConflicts("proceed"),
+		c.conn.DeleteByQuery.WithRefresh(true),
+		c.conn.DeleteByQuery.WithContext(ctx),
+	)
+	if err != nil {
+		return unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return responseError("delete documents of layers", res)
+	}
+	var deleted struct {
+		Deleted int64 `json:"deleted"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&deleted); err != nil {
+		return err
+	}
+	c.logger.Infof("deleted %d documents of layers %v from %s", deleted.Deleted, layers, c.createdIndex)
+	return nil
+}
+
+// layersQuery matches documents of any of layers
+func (c *Client) layersQuery(layers []string) map[string]interface{} {
+	should := make([]interface{}, 0, len(layers))
+	for _, layer := range layers {
+		should = append(should, c.layerFilter(layer))
+	}
+	return map[string]interface{}{
+		"bool": map[string]interface{}{"should": should, "minimum_should_match": 1},
+	}
+}
+
+// layerFilter matches documents of layer, including address, POI and crossroad documents
+// which have no layer field
+func (c *Client) layerFilter(layer string) map[string]interface{} {
+	exists := func(field string) map[string]interface{} {
+		return map[string]interface{}{"exists": map[string]interface{}{"field": c.names.name(field)}}
+	}
+	term := func(field string, value interface{}) map[string]interface{} {
+		return map[string]interface{}{"term": map[string]interface{}{c.names.name(field): value}}
+	}
+	var q map[string]interface{}
+	switch layer {
+	case AddressLayer:
+		q = map[string]interface{}{
+			"must_not": []interface{}{exists("layer"), term("intersection", true)},
+			"filter":   []interface{}{term("name.keyword", "")},
+		}
+	case PoiLayer:
+		q = map[string]interface{}{
+			"must_not": []interface{}{exists("layer"), term("intersection", true), term("name.keyword", "")},
+		}
+	case CrossroadLayer:
+		q = map[string]interface{}{
+			"must_not": []interface{}{exists("layer")},
+			"filter":   []interface{}{term("intersection", true)},
+		}
+	default:
+		return c.layer(layer)
+	}
+	return map[string]interface{}{"bool": q}
+}
@@ -0,0 +1,44 @@
+package elastic
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maddevsio/ariadna/config"
+)
+
+func TestReindexLayers(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/_alias/addresses":
+			w.Write([]byte(`{"addresses-100": {"aliases": {"addresses": {}}}}`))
+		case r.URL.Path == "/addresses-100/_delete_by_query":
+			data, _ := ioutil.ReadAll(r.Body)
+			query = string(data)
+			w.Write([]byte(`{"deleted": 3}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+	c, err := New(&config.Ariadna{ElasticURLs: []string{server.URL}, ElasticIndex: "addresses"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ReindexLayers(context.Background(), []string{PoiLayer, "transit"}); err != nil {
+		t.Fatal(err)
+	}
+	if c.createdIndex != "addresses-100" {
+		t.Errorf("index = %s", c.createdIndex)
+	}
+	if !strings.Contains(query, `"layer":"transit"`) || !strings.Contains(query, `"name.keyword":""`) {
+		t.Errorf("query = %s", query)
+	}
+}
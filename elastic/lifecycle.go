@@ -0,0 +1,107 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v7/esapi"
+	"github.com/maddevsio/ariadna/config"
+)
+
+// defaultRolloverAge rolls indices over daily like daily indices when no condition is configured
+const defaultRolloverAge = 24 * time.Hour
+
+// SetupLifecycle puts ILM policy and index template of indices written through alias and
+// creates the first index behind alias. Indices roll over by age or size and are deleted
+// retention after rollover, zero retention keeps them. It is idempotent
+func (c *Client) SetupLifecycle(ctx context.Context, alias string, lifecycle config.Lifecycle, retention time.Duration) error {
+	policy := alias + "-policy"
+	rollover := map[string]interface{}{}
+	if lifecycle.MaxAge > 0 {
+		rollover["max_age"] = timeUnits(lifecycle.MaxAge)
+	}
+	if lifecycle.MaxSize != "" {
+		rollover["max_size"] = lifecycle.MaxSize
+	}
+	if len(rollover) == 0 {
+		rollover["max_age"] = timeUnits(defaultRolloverAge)
+	}
+	phases := map[string]interface{}{
+		"hot": map[string]interface{}{"actions": map[string]interface{}{"rollover": rollover}},
+	}
+	if retention > 0 {
+		phases["delete"] = map[string]interface{}{
+			"min_age": timeUnits(retention),
+			"actions": map[string]interface{}{"delete": map[string]interface{}{}},
+		}
+	}
+	if err := c.put(ctx, "/_ilm/policy/"+policy, map[string]interface{}{
+		"policy": map[string]interface{}{"phases": phases},
+	}, "put lifecycle policy"); err != nil {
+		return err
+	}
+	if err := c.put(ctx, "/_template/"+alias, map[string]interface{}{
+		"index_patterns": []string{alias + "-*"},
+		"settings": map[string]interface{}{
+			"index.lifecycle.name":           policy,
+			"index.lifecycle.rollover_alias": alias,
+		},
+	}, "put index template"); err != nil {
+		return err
+	}
+	res, err := c.conn.Indices.ExistsAlias([]string{alias}, c.conn.Indices.ExistsAlias.WithContext(ctx))
+	if err != nil {
+		return unavailable(err)
+	}
+	res.Body.Close()
+	if res.StatusCode == http.StatusOK {
+		return nil
+	}
+	if err := c.put(ctx, "/"+alias+"-000001", map[string]interface{}{
+		"aliases": map[string]interface{}{alias: map[string]interface{}{"is_write_index": true}},
+	}, "create index "+alias); err != nil {
+		return err
+	}
+	c.logger.Infof("index %s is managed by lifecycle policy %s", alias, policy)
+	return nil
+}
+
+// put sends body to path, it is used for APIs missing in esapi of the client
+func (c *Client) put(ctx context.Context, path string, body interface{}, action string) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPut, path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	r, err := c.conn.Perform(req.WithContext(ctx))
+	if err != nil {
+		return unavailable(err)
+	}
+	defer r.Body.Close()
+	res := &esapi.Response{StatusCode: r.StatusCode, Body: r.Body, Header: r.Header}
+	if res.IsError() {
+		return responseError(action, res)
+	}
+	return nil
+}
+
+// timeUnits formats duration in the largest time unit of elasticsearch dividing it
+func timeUnits(d time.Duration) string {
+	for _, unit := range []struct {
+		suffix string
+		size   time.Duration
+	}{{"d", 24 * time.Hour}, {"h", time.Hour}, {"m", time.Minute}} {
+		if d%unit.size == 0 {
+			return fmt.Sprintf("%d%s", d/unit.size, unit.suffix)
+		}
+	}
+	return fmt.Sprintf("%ds", int64(d/time.Second))
+}
@@ -0,0 +1,80 @@
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maddevsio/ariadna/config"
+)
+
+func TestSetupLifecycle(t *testing.T) {
+	var requests []string
+	var policy struct {
+		Policy struct {
+			Phases struct {
+				Hot struct {
+					Actions struct {
+						Rollover map[string]string `json:"rollover"`
+					} `json:"actions"`
+				} `json:"hot"`
+				Delete struct {
+					MinAge string `json:"min_age"`
+				} `json:"delete"`
+			} `json:"phases"`
+		} `json:"policy"`
+	}
+	aliased := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/_ilm/policy/analytics-policy":
+			json.NewDecoder(r.Body).Decode(&policy)
+		case "/_alias/analytics":
+			if !aliased {
+				w.WriteHeader(http.StatusNotFound)
+			}
+			return
+		case "/analytics-000001":
+			aliased = true
+		}
+		w.Write([]byte(`{"acknowledged": true}`))
+	}))
+	defer server.Close()
+	c, err := New(&config.Ariadna{ElasticURLs: []string{server.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lifecycle := config.Lifecycle{Enabled: true, MaxAge: 7 * 24 * time.Hour, MaxSize: "50gb"}
+	if err := c.SetupLifecycle(context.Background(), "analytics", lifecycle, 90*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	rollover := policy.Policy.Phases.Hot.Actions.Rollover
+	if rollover["max_age"] != "7d" || rollover["max_size"] != "50gb" || policy.Policy.Phases.Delete.MinAge != "90m" {
+		t.Errorf("policy = %+v", policy)
+	}
+	if err := c.SetupLifecycle(context.Background(), "analytics", lifecycle, 0); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"PUT /_ilm/policy/analytics-policy",
+		"PUT /_template/analytics",
+		"HEAD /_alias/analytics",
+		"PUT /analytics-000001",
+		"PUT /_ilm/policy/analytics-policy",
+		"PUT /_template/analytics",
+		"HEAD /_alias/analytics",
+	}
+	if len(requests) != len(expected) {
+		t.Fatalf("requests = %v", requests)
+	}
+	for n := range expected {
+		if requests[n] != expected[n] {
+			t.Errorf("request %d = %q, expected %q", n, requests[n], expected[n])
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package elastic
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/maddevsio/ariadna/model"
+)
+
+// fieldNames renames top level fields of documents in the index, so index matches schema of
+// its downstream consumer. Documents are renamed on bulk write and renamed back on search,
+// queries refer to renamed fields
+type fieldNames struct {
+	out map[string]string
+	in  map[string]string
+}
+
+// newFieldNames validates renaming of document fields to names of the index. Renamed fields
+// must be fields of model.Address and names must not clash with other fields of it
+func newFieldNames(names map[string]string) (fieldNames, error) {
+	f := fieldNames{out: make(map[string]string, len(names)), in: make(map[string]string, len(names))}
+	fields := documentFields()
+	for field, name := range names {
+		if !fields[field] {
+			return f, fmt.Errorf("field names: unknown document field %q", field)
+		}
+		if name == "" || strings.ContainsAny(name, ".^") {
+			return f, fmt.Errorf("field names: %q is not valid name of %s", name, field)
+		}
+		if other, ok := f.in[name]; ok {
+			return f, fmt.Errorf("field names: %s and %s are both named %s", other, field, name)
+		}
+		f.out[field], f.in[name] = name, field
+	}
+	for name, field := range f.in {
+		if _, renamed := f.out[name]; fields[name] && !renamed && name != field {
+			return f, fmt.Errorf("field names: %s is named %s like another field", field, name)
+		}
+	}
+	return f, nil
+}
+
+// documentFields returns top level fields of indexed documents
+func documentFields() map[string]bool {
+	t := reflect.TypeOf(model.Address{})
+	fields := make(map[string]bool, t.NumField())
+	for n := 0; n < t.NumField(); n++ {
+		if name := strings.Split(t.Field(n).Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
+}
+
+// name returns name of field in the index. Dotted paths are renamed by top level field
+// and boosts like "name^3" are kept
+func (f fieldNames) name(field string) string {
+	if len(f.out) == 0 {
+		return field
+	}
+	end := strings.IndexAny(field, ".^")
+	if end < 0 {
+		end = len(field)
+	}
+	if name, ok := f.out[field[:end]]; ok {
+		return name + field[end:]
+	}
+	return field
+}
+
+// names returns names of fields in the index
+func (f fieldNames) names(fields []string) []string {
+	result := make([]string, len(fields))
+	for n, field := range fields {
+		result[n] = f.name(field)
+	}
+	return result
+}
+
+// keys returns copy of mapping properties keyed by names of the index
+func (f fieldNames) keys(properties map[string]interface{}) map[string]interface{} {
+	renamed := make(map[string]interface{}, len(properties))
+	for field, mapping := range properties {
+		renamed[f.name(field)] = mapping
+	}
+	return renamed
+}
+
+// encode renames fields of document for the index
+func (f fieldNames) encode(data []byte) ([]byte, error) {
+	return rename(data, f.out)
+}
+
+// decode renames fields of document found in the index back
+func (f fieldNames) decode(data []byte) ([]byte, error) {
+	return rename(data, f.in)
+}
+
+// encodeBulk renames fields of documents of bulk request body, action lines are kept as is
+func (f fieldNames) encodeBulk(buf bytes.Buffer) (bytes.Buffer, error) {
+	if len(f.out) == 0 {
+		return buf, nil
+	}
+	var renamed bytes.Buffer
+	lines := bytes.Split(bytes.TrimRight(buf.Bytes(), "\n"), []byte("\n"))
+	for n := 0; n+1 < len(lines); n += 2 {
+		data, err := f.encode(lines[n+1])
+		if err != nil {
+			return renamed, err
+		}
+		renamed.Grow(len(lines[n]) + len(data) + 2)
+		renamed.Write(lines[n])
+		renamed.WriteByte('\n')
+		renamed.Write(data)
+		renamed.WriteByte('\n')
+	}
+	return renamed, nil
+}
+
+func rename(data []byte, names map[string]string) ([]byte, error) {
+	if len(names) == 0 {
+		return data, nil
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	renamed := make(map[string]json.RawMessage, len(doc))
+	for field, value := range doc {
+		if name, ok := names[field]; ok {
+			field = name
+		}
+		renamed[field] = value
+	}
+	return json.Marshal(renamed)
+}
@@ -0,0 +1,64 @@
+package elastic
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestFieldNamesValidation(t *testing.T) {
+	for _, names := range []map[string]string{
+		{"house_number": "number"},
+		{"housenumber": ""},
+		{"housenumber": "address.number"},
+		{"housenumber": "number", "street": "number"},
+		{"housenumber": "street"},
+	} {
+		if _, err := newFieldNames(names); err == nil {
+			t.Errorf("names %v are rejected", names)
+		}
+	}
+	if _, err := newFieldNames(map[string]string{"name": "street", "street": "name"}); err != nil {
+		t.Errorf("swapped names are valid: %v", err)
+	}
+}
+
+func TestFieldNames(t *testing.T) {
+	names, err := newFieldNames(map[string]string{"housenumber": "house_number", "location": "coordinates", "wikidata": "wiki"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(names.names([]string{"housenumber^2", "wikidata.sitelinks", "street"}), ","); got != "house_number^2,wiki.sitelinks,street" {
+		t.Errorf("names = %s", got)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"index":{"_id":"node/1"}}` + "\n" + `{"housenumber":"7","location":{"lat":1,"lon":2},"street":"Main"}` + "\n")
+	renamed, err := names.encodeBulk(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(renamed.String()), "\n")
+	if len(lines) != 2 || lines[0] != `{"index":{"_id":"node/1"}}` {
+		t.Fatalf("bulk = %q", renamed.String())
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(lines[1]), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := doc["house_number"]; !ok || string(doc["coordinates"]) != `{"lat":1,"lon":2}` || string(doc["street"]) != `"Main"` {
+		t.Errorf("document = %s", lines[1])
+	}
+
+	var r searchResponse
+	r.Hits.Hits = append(r.Hits.Hits, searchHit{ID: "node/1", Source: json.RawMessage(lines[1])})
+	result, err := r.result(names)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := result.Addresses[0]
+	if a.ID != "node/1" || a.HouseNumber != "7" || a.Location.Lon != 2 || a.Street != "Main" {
+		t.Errorf("address = %+v", a)
+	}
+}
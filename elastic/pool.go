@@ -0,0 +1,106 @@
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	es "github.com/elastic/go-elasticsearch/v7"
+	"github.com/maddevsio/ariadna/config"
+)
+
+const (
+	defaultKeepAlive = 90 * time.Second
+	sniffTimeout     = 5 * time.Second
+)
+
+// transport returns HTTP transport with own connection pool tuned by pool settings, so
+// bulk requests of import and searches don't wait for connections of each other
+func transport(pool config.Pool) *http.Transport {
+	keepAlive := pool.KeepAlive
+	if keepAlive <= 0 {
+		keepAlive = defaultKeepAlive
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: keepAlive}).DialContext
+	t.IdleConnTimeout = keepAlive
+	t.MaxConnsPerHost = pool.MaxConns
+	if pool.MaxIdle > 0 {
+		t.MaxIdleConnsPerHost = pool.MaxIdle
+		t.MaxIdleConns = 0
+	}
+	t.DisableKeepAlives = pool.DisableKeepAlive
+	return t
+}
+
+// connect creates client of pool, urls are used when pool has none. With sniffing enabled
+// client talks to HTTP addresses of all nodes of the cluster found through urls
+func connect(pool config.Pool, urls []string) (*es.Client, error) {
+	if len(pool.URLs) > 0 {
+		urls = pool.URLs
+	}
+	t := transport(pool)
+	if pool.Sniff {
+		ctx, cancel := context.WithTimeout(context.Background(), sniffTimeout)
+		defer cancel()
+		nodes, err := sniff(ctx, urls, t)
+		if err != nil {
+			return nil, fmt.Errorf("could not sniff nodes of %v: %w", urls, err)
+		}
+		urls = nodes
+	}
+	return es.NewClient(es.Config{Addresses: urls, Transport: t})
+}
+
+// sniff returns HTTP addresses of nodes of the cluster, scheme of the first url is kept
+func sniff(ctx context.Context, urls []string, t http.RoundTripper) ([]string, error) {
+	conn, err := es.NewClient(es.Config{Addresses: urls, Transport: t})
+	if err != nil {
+		return nil, err
+	}
+	res, err := conn.Nodes.Info(conn.Nodes.Info.WithMetric("http"), conn.Nodes.Info.WithContext(ctx))
+	if err != nil {
+		return nil, unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, responseError("get nodes", res)
+	}
+	var info struct {
+		Nodes map[string]struct {
+			HTTP struct {
+				PublishAddress string `json:"publish_address"`
+			} `json:"http"`
+		} `json:"nodes"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&info); err != nil {
+		return nil, err
+	}
+	scheme := "http"
+	if u, err := url.Parse(urls[0]); err == nil && u.Scheme != "" {
+		scheme = u.Scheme
+	}
+	nodes := make([]string, 0, len(info.Nodes))
+	for _, node := range info.Nodes {
+		address := node.HTTP.PublishAddress
+		if address == "" {
+			continue
+		}
+		// address is "ip:port" or "hostname/ip:port"
+		if n := strings.LastIndex(address, "/"); n >= 0 {
+			address = address[n+1:]
+		}
+		nodes = append(nodes, scheme+"://"+address)
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no nodes with http address")
+	}
+	sort.Strings(nodes)
+	return nodes, nil
+}
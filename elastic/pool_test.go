@@ -0,0 +1,78 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maddevsio/ariadna/config"
+)
+
+func TestTransport(t *testing.T) {
+	tr := transport(config.Pool{MaxConns: 4, MaxIdle: 2, KeepAlive: time.Minute})
+	if tr.MaxConnsPerHost != 4 || tr.MaxIdleConnsPerHost != 2 || tr.IdleConnTimeout != time.Minute {
+		t.Errorf("transport = %+v", tr)
+	}
+	if tr := transport(config.Pool{}); tr.IdleConnTimeout != defaultKeepAlive || tr.MaxConnsPerHost != 0 {
+		t.Errorf("default transport = %+v", tr)
+	}
+}
+
+func TestSniff(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_nodes/http" {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"nodes": {
+			"a": {"http": {"publish_address": "es-1/10.0.0.1:9200"}},
+			"b": {"http": {"publish_address": "10.0.0.2:9200"}},
+			"c": {}
+		}}`))
+	}))
+	defer server.Close()
+	nodes, err := sniff(context.Background(), []string{server.URL}, http.DefaultTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(nodes, ",") != "http://10.0.0.1:9200,http://10.0.0.2:9200" {
+		t.Errorf("nodes = %v", nodes)
+	}
+}
+
+func TestConnections(t *testing.T) {
+	var index, search []string
+	cluster := func(requests *[]string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*requests = append(*requests, r.URL.Path)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"hits": {"hits": []}, "items": []}`))
+		}))
+	}
+	i, s := cluster(&index), cluster(&search)
+	defer i.Close()
+	defer s.Close()
+	c, err := New(&config.Ariadna{
+		ElasticURLs:  []string{i.URL},
+		ElasticIndex: "addresses",
+		Connections:  config.Connections{Search: config.Pool{URLs: []string{s.URL}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Search(context.Background(), "Ала-Тоо"); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	buf.WriteString(`{"index":{"_id":"node/1"}}` + "\n" + `{"name":"Ала-Тоо"}` + "\n")
+	if err := c.BulkWrite(context.Background(), buf); err != nil {
+		t.Fatal(err)
+	}
+	if len(search) != 1 || search[0] != "/addresses/_search" || len(index) == 0 || index[len(index)-1] != "/_bulk" {
+		t.Errorf("search requests = %v, index requests = %v", search, index)
+	}
+}
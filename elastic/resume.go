@@ -0,0 +1,102 @@
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// unfinished is index of import which failed or was interrupted, with its shard indices
+type unfinished struct {
+	index   string
+	created int64
+	shards  map[string]string
+}
+
+// unfinishedImport finds the latest import which didn't finish after the latest finished one.
+// Indices are put behind the alias when import starts and get import info in metadata when it
+// is done, so indices without import info are left by failed import. Unfinished imports older
+// than the finished one serving the alias are stale and ignored. Nil is returned when there is none
+func (c *Client) unfinishedImport(ctx context.Context) (*unfinished, error) {
+	res, err := c.conn.Indices.GetMapping(
+		c.conn.Indices.GetMapping.WithIndex(c.config.ElasticIndex),
+		c.conn.Indices.GetMapping.WithContext(ctx),
+	)
+	if err != nil {
+		return nil, unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if res.IsError() {
+		return nil, responseError("get import info", res)
+	}
+	var indices map[string]struct {
+		Mappings struct {
+			Meta struct {
+				Import *ImportInfo `json:"import"`
+			} `json:"_meta"`
+		} `json:"mappings"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&indices); err != nil {
+		return nil, err
+	}
+	var (
+		latest   *unfinished
+		finished int64
+	)
+	prefix := c.config.ElasticIndex + "-"
+	for index, mapping := range indices {
+		if !strings.HasPrefix(index, prefix) {
+			continue
+		}
+		// main index is <alias>-<created>, shards are <alias>-<shard>-<created>
+		created, err := strconv.ParseInt(strings.TrimPrefix(index, prefix), 10, 64)
+		if err != nil {
+			continue
+		}
+		if mapping.Mappings.Meta.Import != nil {
+			if created > finished {
+				finished = created
+			}
+			continue
+		}
+		if latest == nil || created > latest.created {
+			latest = &unfinished{index: index, created: created, shards: make(map[string]string)}
+		}
+	}
+	if latest == nil || latest.created < finished {
+		return nil, nil
+	}
+	suffix := "-" + strconv.FormatInt(latest.created, 10)
+	for index, mapping := range indices {
+		if mapping.Mappings.Meta.Import != nil || index == latest.index {
+			continue
+		}
+		if strings.HasPrefix(index, prefix) && strings.HasSuffix(index, suffix) {
+			latest.shards[strings.TrimSuffix(strings.TrimPrefix(index, prefix), suffix)] = index
+		}
+	}
+	return latest, nil
+}
+
+// resume makes client write into index of unfinished import, false is returned when there is none.
+// Documents are indexed by ids of their OSM elements, so documents indexed before the failure are
+// replaced rather than duplicated
+func (c *Client) resume(ctx context.Context) (bool, error) {
+	u, err := c.unfinishedImport(ctx)
+	if err != nil || u == nil {
+		return false, err
+	}
+	c.mu.Lock()
+	c.created, c.createdIndex = u.created, u.index
+	for key, index := range u.shards {
+		c.shards[key] = index
+	}
+	c.mu.Unlock()
+	c.logger.Infof("resuming unfinished import into %s", u.index)
+	return true, nil
+}
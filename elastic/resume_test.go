@@ -0,0 +1,69 @@
+package elastic
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maddevsio/ariadna/config"
+)
+
+func TestResume(t *testing.T) {
+	var created []string
+	unfinished, stale := true, false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/addresses/_mapping":
+			mappings := `{
+				"addresses-100": {"mappings": {"_meta": {"import": {"imported_at": "2020-01-01T00:00:00Z"}}}}`
+			if stale {
+				mappings += `,
+				"addresses-50": {"mappings": {}}`
+			}
+			if unfinished {
+				mappings += `,
+				"addresses-200": {"mappings": {}},
+				"addresses-kg-200": {"mappings": {}},
+				"addresses-kg-100": {"mappings": {"_meta": {"import": {"imported_at": "2020-01-01T00:00:00Z"}}}}`
+			}
+			w.Write([]byte(mappings + "}"))
+		case r.Method == http.MethodPut && !strings.HasPrefix(r.URL.Path, "/_") && !strings.Contains(r.URL.Path, "/_alias"):
+			created = append(created, r.URL.Path)
+			w.Write([]byte(`{"acknowledged": true}`))
+		default:
+			w.Write([]byte(`{"acknowledged": true}`))
+		}
+	}))
+	defer server.Close()
+	c, err := New(&config.Ariadna{ElasticURLs: []string{server.URL}, ElasticIndex: "addresses", Resume: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.UpdateIndex(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if c.createdIndex != "addresses-200" || c.created != 200 || c.shards["kg"] != "addresses-kg-200" || len(created) != 0 {
+		t.Errorf("index = %s, shards = %v, created indices = %v", c.createdIndex, c.shards, created)
+	}
+
+	unfinished = false
+	c, _ = New(&config.Ariadna{ElasticURLs: []string{server.URL}, ElasticIndex: "addresses", Resume: true})
+	if err := c.UpdateIndex(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if c.createdIndex == "addresses-100" || len(created) != 1 || created[0] != "/"+c.createdIndex {
+		t.Errorf("new index is created after finished imports, index = %s, created = %v", c.createdIndex, created)
+	}
+
+	stale = true
+	c, _ = New(&config.Ariadna{ElasticURLs: []string{server.URL}, ElasticIndex: "addresses", Resume: true})
+	if err := c.UpdateIndex(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if c.createdIndex == "addresses-50" || len(created) != 2 {
+		t.Errorf("import older than finished one isn't resumed, index = %s, created = %v", c.createdIndex, created)
+	}
+}
@@ -0,0 +1,587 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	es "github.com/elastic/go-elasticsearch/v7"
+	"github.com/elastic/go-elasticsearch/v7/esapi"
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/maddevsio/ariadna/normalize"
+)
+
+const (
+	searchSize      = 10
+	maxSearchSize   = 100
+	facetSize       = 10
+	categorySize    = 1000
+	reverseDistance = "200m"
+	metersPerDegree = 111320.0
+	// BoundaryLayer holds admin polygons, they are found only by Containing and
+	// Nearby of this layer, not by search and reverse geocoding
+	BoundaryLayer = "boundary"
+)
+
+// Result holds found addresses. TimedOut is set when search was cut by deadline
+// and addresses are partial. Facets count values of requested fields among all found
+// documents, the most frequent go first
+type Result struct {
+	Addresses []model.Address
+	TimedOut  bool
+	Facets    map[string][]model.Facet
+}
+
+type searchResponse struct {
+	TimedOut bool `json:"timed_out"`
+	Hits     struct {
+		Hits []searchHit `json:"hits"`
+	} `json:"hits"`
+	Aggregations map[string]struct {
+		Buckets []struct {
+			Key         json.RawMessage `json:"key"`
+			KeyAsString string          `json:"key_as_string"`
+			DocCount    int64           `json:"doc_count"`
+		} `json:"buckets"`
+	} `json:"aggregations"`
+}
+
+// searchHit is found document, score is explained when search request asks for explanation
+type searchHit struct {
+	ID          string          `json:"_id"`
+	Score       float64         `json:"_score"`
+	Source      json.RawMessage `json:"_source"`
+	Explanation json.RawMessage `json:"_explanation"`
+}
+
+// DefaultRanking is ranking profile used when none is configured
+var DefaultRanking = config.RankingProfile{
+	Fields: []string{
+		"name^3", "street^2", "housenumber", "prefix", "unit",
+		"city", "town", "village", "district", "country",
+	},
+	Operator:   "and",
+	Popularity: true,
+}
+
+// Search performs full text search of addresses
+func (c *Client) Search(ctx context.Context, query string) (*Result, error) {
+	return c.SearchRanked(ctx, query, DefaultRanking)
+}
+
+// SearchRanked performs full text search of addresses ranked by given profile
+func (c *Client) SearchRanked(ctx context.Context, query string, profile config.RankingProfile) (*Result, error) {
+	return c.search(ctx, c.rankedBody(query, profile))
+}
+
+// rankedBody returns search request of query ranked by profile
+func (c *Client) rankedBody(query string, profile config.RankingProfile) map[string]interface{} {
+	if len(profile.Fields) == 0 {
+		profile.Fields = DefaultRanking.Fields
+	}
+	if profile.Operator == "" {
+		profile.Operator = DefaultRanking.Operator
+	}
+	q := map[string]interface{}{
+		"multi_match": map[string]interface{}{
+			"query":    query,
+			"type":     "cross_fields",
+			"operator": profile.Operator,
+			"fields":   c.names.names(profile.Fields),
+		},
+	}
+	if fields, ok := normalizedFields(profile.Fields); ok {
+		// spellings like "First of May" and "1-May" meet in normalized names
+		q = map[string]interface{}{
+			"bool": map[string]interface{}{
+				"should": []interface{}{q, map[string]interface{}{
+					"multi_match": map[string]interface{}{
+						"query":    normalize.Name(query),
+						"type":     "cross_fields",
+						"operator": profile.Operator,
+						"fields":   c.names.names(fields),
+					},
+				}},
+				"minimum_should_match": 1,
+			},
+		}
+	}
+	if profile.Popularity {
+		q = map[string]interface{}{
+			"function_score": map[string]interface{}{
+				"query": q,
+				// well known places referenced by many wikipedia articles rank higher
+				"field_value_factor": map[string]interface{}{
+					"field":    c.names.name("wikidata.sitelinks"),
+					"modifier": "log2p",
+					"missing":  0,
+				},
+				"boost_mode": "multiply",
+			},
+		}
+	}
+	if functions := c.boostFunctions(profile); len(functions) > 0 {
+		q = map[string]interface{}{
+			"function_score": map[string]interface{}{
+				"query":      q,
+				"functions":  functions,
+				"score_mode": "multiply",
+				"boost_mode": "multiply",
+			},
+		}
+	}
+	match := map[string]interface{}{
+		"must":     q,
+		"must_not": c.notBoundary(),
+	}
+	if filters := c.filters(profile.Layers, profile.Categories, profile.Filters); len(filters) > 0 {
+		match["filter"] = filters
+	}
+	size := searchSize
+	if profile.Size > 0 {
+		size = profile.Size
+	}
+	if size > maxSearchSize {
+		size = maxSearchSize
+	}
+	body := map[string]interface{}{
+		"size":  size,
+		"query": map[string]interface{}{"bool": match},
+	}
+	if len(profile.Facets) > 0 {
+		body["aggs"] = c.facetAggs(profile.Facets)
+	}
+	return body
+}
+
+// facetAggs counts values of fields of facets. Addresses, POIs and crossroads have no layer
+// and are counted as addresses like in stats
+func (c *Client) facetAggs(facets []string) map[string]interface{} {
+	aggs := make(map[string]interface{}, len(facets))
+	for _, facet := range facets {
+		terms := map[string]interface{}{"field": c.names.name(c.facetField(facet)), "size": facetSize}
+		if facet == "layer" {
+			terms["missing"] = AddressLayer
+		}
+		aggs[facet] = map[string]interface{}{"terms": terms}
+	}
+	return aggs
+}
+
+// facetField returns aggregatable field of facet, text fields are counted by keyword subfield
+// and facets named after extracted fields count their values
+func (c *Client) facetField(facet string) string {
+	switch facet {
+	case "layer", "category":
+		return facet
+	case "country", "city", "town", "village", "district", "street":
+		return facet + ".keyword"
+	}
+	field := "fields." + facet
+	for _, f := range c.config.Fields {
+		if f.Name == facet && (f.Type == "" || f.Type == config.FieldString) {
+			field += ".keyword"
+		}
+	}
+	return field
+}
+
+// boostFunctions weights scores of documents by boosts of their layer and category, functions
+// are sorted to keep queries of the same profile equal
+func (c *Client) boostFunctions(profile config.RankingProfile) []interface{} {
+	functions := make([]interface{}, 0, len(profile.LayerBoosts)+len(profile.CategoryBoosts))
+	for _, layer := range sortedKeys(profile.LayerBoosts) {
+		functions = append(functions, map[string]interface{}{
+			"filter": c.layerFilter(layer),
+			"weight": profile.LayerBoosts[layer],
+		})
+	}
+	for _, category := range sortedKeys(profile.CategoryBoosts) {
+		functions = append(functions, map[string]interface{}{
+			"filter": map[string]interface{}{"term": map[string]interface{}{c.names.name("category"): category}},
+			"weight": profile.CategoryBoosts[category],
+		})
+	}
+	return functions
+}
+
+// filters restrict documents to layers, categories and values of extracted fields
+func (c *Client) filters(layers, categories []string, fields map[string][]string) []interface{} {
+	var filters []interface{}
+	if len(layers) > 0 {
+		filters = append(filters, c.layersQuery(layers))
+	}
+	if len(categories) > 0 {
+		filters = append(filters, map[string]interface{}{
+			"terms": map[string]interface{}{c.names.name("category"): categories},
+		})
+	}
+	return append(filters, c.fieldFilters(fields)...)
+}
+
+// fieldFilters match documents having any of values of extracted fields, strings are
+// matched exactly by keyword subfield
+func (c *Client) fieldFilters(filters map[string][]string) []interface{} {
+	names := make([]string, 0, len(filters))
+	for name := range filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		field := "fields." + name
+		for _, f := range c.config.Fields {
+			if f.Name == name && (f.Type == "" || f.Type == config.FieldString) {
+				field += ".keyword"
+			}
+		}
+		result = append(result, map[string]interface{}{
+			"terms": map[string]interface{}{c.names.name(field): filters[name]},
+		})
+	}
+	return result
+}
+
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// normalizedFields replaces name and street of ranking fields by their normalized versions,
+// false is returned when fields have none of them
+func normalizedFields(fields []string) ([]string, bool) {
+	result := make([]string, 0, len(fields))
+	replaced := false
+	for _, field := range fields {
+		name, boost := field, ""
+		if n := strings.Index(field, "^"); n >= 0 {
+			name, boost = field[:n], field[n:]
+		}
+		if name == "name" || name == "street" {
+			field = name + "_normalized" + boost
+			replaced = true
+		}
+		result = append(result, field)
+	}
+	return result, replaced
+}
+
+// Reverse returns addresses nearest to given point
+func (c *Client) Reverse(ctx context.Context, lat, lon float64) (*Result, error) {
+	return c.Nearby(ctx, "", lat, lon, reverseDistance)
+}
+
+// notBoundary excludes admin polygons from queries of all layers
+func (c *Client) notBoundary() map[string]interface{} {
+	return c.layer(BoundaryLayer)
+}
+
+// layer returns term filter of documents of layer
+func (c *Client) layer(layer string) map[string]interface{} {
+	return map[string]interface{}{
+		"term": map[string]interface{}{c.names.name("layer"): layer},
+	}
+}
+
+// Nearby returns documents of layer within distance from point sorted by distance.
+// Empty layer matches all documents except boundaries
+func (c *Client) Nearby(ctx context.Context, layer string, lat, lon float64, distance string) (*Result, error) {
+	return c.search(ctx, c.nearbyBody(layer, lat, lon, distance))
+}
+
+// ReverseBatch reverse geocodes points by single multi search request, results follow order of points
+func (c *Client) ReverseBatch(ctx context.Context, points []model.Location) ([]*Result, error) {
+	bodies := make([][]byte, 0, len(points))
+	for _, p := range points {
+		body := c.nearbyBody("", p.Lat, p.Lon, reverseDistance)
+		if !c.limit(ctx, body) {
+			return timedOut(len(points)), nil
+		}
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		bodies = append(bodies, data)
+	}
+	res, err := c.read(ctx, func(conn *es.Client, index string) (*esapi.Response, error) {
+		header, err := json.Marshal(map[string]string{"index": index})
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		for _, data := range bodies {
+			buf.Write(header)
+			buf.WriteByte('\n')
+			buf.Write(data)
+			buf.WriteByte('\n')
+		}
+		return conn.Msearch(&buf, conn.Msearch.WithContext(ctx))
+	})
+	if err == context.DeadlineExceeded || ctx.Err() == context.DeadlineExceeded {
+		return timedOut(len(points)), nil
+	}
+	if err != nil {
+		return nil, unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, responseError("perform multi search", res)
+	}
+	var r struct {
+		Responses []struct {
+			searchResponse
+			Error json.RawMessage `json:"error"`
+		} `json:"responses"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return nil, err
+	}
+	if len(r.Responses) != len(points) {
+		return nil, fmt.Errorf("multi search returned %d responses for %d points", len(r.Responses), len(points))
+	}
+	results := make([]*Result, 0, len(points))
+	for _, response := range r.Responses {
+		if response.Error != nil {
+			return nil, fmt.Errorf("%w: could not perform multi search: %s", ErrIndexUnavailable, response.Error)
+		}
+		result, err := response.result(c.names)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func timedOut(n int) []*Result {
+	results := make([]*Result, n)
+	for k := range results {
+		results[k] = &Result{Addresses: []model.Address{}, TimedOut: true}
+	}
+	return results
+}
+
+// nearbyBody returns query of Nearby
+func (c *Client) nearbyBody(layer string, lat, lon float64, distance string) map[string]interface{} {
+	location := model.Location{Lat: lat, Lon: lon}
+	filter := []interface{}{
+		map[string]interface{}{
+			"geo_distance": map[string]interface{}{
+				"distance":               distance,
+				c.names.name("location"): location,
+			},
+		},
+	}
+	query := map[string]interface{}{"filter": filter}
+	if layer != "" {
+		query["filter"] = append(filter, c.layer(layer))
+	} else {
+		query["must_not"] = c.notBoundary()
+	}
+	body := map[string]interface{}{
+		"size": searchSize,
+		"query": map[string]interface{}{
+			"bool": query,
+		},
+		"sort": []interface{}{
+			map[string]interface{}{
+				"_geo_distance": map[string]interface{}{
+					c.names.name("location"): location,
+					"order":                  "asc",
+					"unit":                   "m",
+				},
+			},
+		},
+	}
+	return body
+}
+
+// Containing returns features of layer which geometry contains given point, e.g. lakes
+// and islands. Empty layer matches all documents
+func (c *Client) Containing(ctx context.Context, layer string, lat, lon float64) (*Result, error) {
+	filter := []interface{}{
+		map[string]interface{}{
+			"geo_shape": map[string]interface{}{
+				c.names.name("geometry"): map[string]interface{}{
+					"shape": map[string]interface{}{
+						"type":        "point",
+						"coordinates": []float64{lon, lat},
+					},
+					"relation": "intersects",
+				},
+			},
+		},
+	}
+	if layer != "" {
+		filter = append(filter, c.layer(layer))
+	}
+	body := map[string]interface{}{
+		"size": searchSize,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": filter,
+			},
+		},
+	}
+	return c.search(ctx, body)
+}
+
+// Intersecting returns documents of layer which geometry crosses square of radius meters around point
+func (c *Client) Intersecting(ctx context.Context, layer string, lat, lon, radius float64) (*Result, error) {
+	dLat := radius / metersPerDegree
+	dLon := dLat / math.Max(math.Cos(lat*math.Pi/180), 0.01)
+	body := map[string]interface{}{
+		"size": searchSize,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": []interface{}{
+					c.layer(layer),
+					map[string]interface{}{
+						"geo_shape": map[string]interface{}{
+							c.names.name("geometry"): map[string]interface{}{
+								"shape": map[string]interface{}{
+									"type":        "envelope",
+									"coordinates": [][]float64{{lon - dLon, lat + dLat}, {lon + dLon, lat - dLat}},
+								},
+								"relation": "intersects",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	return c.search(ctx, body)
+}
+
+// Lookup returns documents with given ids
+func (c *Client) Lookup(ctx context.Context, ids []string) (*Result, error) {
+	body := map[string]interface{}{
+		"size": len(ids),
+		"query": map[string]interface{}{
+			"ids": map[string]interface{}{"values": ids},
+		},
+	}
+	return c.search(ctx, body)
+}
+
+// Category returns all documents of layer in category, like country boundaries, up to categorySize
+func (c *Client) Category(ctx context.Context, layer, category string) (*Result, error) {
+	body := map[string]interface{}{
+		"size": categorySize,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": []interface{}{
+					c.layer(layer),
+					map[string]interface{}{"term": map[string]interface{}{c.names.name("category"): category}},
+				},
+			},
+		},
+	}
+	return c.search(ctx, body)
+}
+
+// limit bounds search by deadline of ctx and configured terminate_after, false is returned
+// when no time is left
+func (c *Client) limit(ctx context.Context, body map[string]interface{}) bool {
+	if deadline, ok := ctx.Deadline(); ok {
+		// leave part of the budget for transport and response encoding
+		budget := time.Until(deadline) * 8 / 10
+		if budget <= 0 {
+			return false
+		}
+		body["timeout"] = fmt.Sprintf("%dms", int64(budget/time.Millisecond))
+	}
+	if c.config.API.TerminateAfter > 0 {
+		body["terminate_after"] = c.config.API.TerminateAfter
+	}
+	return true
+}
+
+func (c *Client) search(ctx context.Context, body map[string]interface{}) (*Result, error) {
+	r, err := c.runSearch(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	return r.result(c.names)
+}
+
+// runSearch sends search request, response of search cut by deadline has only timed_out set
+func (c *Client) runSearch(ctx context.Context, body map[string]interface{}) (*searchResponse, error) {
+	if !c.limit(ctx, body) {
+		return &searchResponse{TimedOut: true}, nil
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.read(ctx, func(conn *es.Client, index string) (*esapi.Response, error) {
+		return conn.Search(
+			conn.Search.WithContext(ctx),
+			conn.Search.WithIndex(index),
+			conn.Search.WithBody(bytes.NewReader(data)),
+		)
+	})
+	if err == context.DeadlineExceeded || ctx.Err() == context.DeadlineExceeded {
+		return &searchResponse{TimedOut: true}, nil
+	}
+	if err != nil {
+		return nil, unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, responseError("perform search", res)
+	}
+	var r searchResponse
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// result decodes found documents, their fields are renamed back from names of the index
+func (r searchResponse) result(names fieldNames) (*Result, error) {
+	result := &Result{Addresses: make([]model.Address, 0, len(r.Hits.Hits)), TimedOut: r.TimedOut}
+	for _, hit := range r.Hits.Hits {
+		data, err := names.decode(hit.Source)
+		if err != nil {
+			return nil, err
+		}
+		var address model.Address
+		if err := json.Unmarshal(data, &address); err != nil {
+			return nil, err
+		}
+		address.ID = hit.ID
+		result.Addresses = append(result.Addresses, address)
+	}
+	for facet, agg := range r.Aggregations {
+		if result.Facets == nil {
+			result.Facets = make(map[string][]model.Facet, len(r.Aggregations))
+		}
+		buckets := make([]model.Facet, 0, len(agg.Buckets))
+		for _, b := range agg.Buckets {
+			value := b.KeyAsString
+			if value == "" {
+				// string keys are quoted, numbers are kept as they are
+				var s string
+				if json.Unmarshal(b.Key, &s) != nil {
+					s = string(b.Key)
+				}
+				value = s
+			}
+			buckets = append(buckets, model.Facet{Value: value, Count: b.DocCount})
+		}
+		result.Facets[facet] = buckets
+	}
+	return result, nil
+}
@@ -0,0 +1,144 @@
+package elastic
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/model"
+)
+
+func TestReverseBatch(t *testing.T) {
+	var lines int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_msearch" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			lines++
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"responses": [
+			{"hits": {"hits": [{"_id": "1", "_source": {"street": "Киевская", "housenumber": "1"}}]}},
+			{"hits": {"hits": []}}
+		]}`))
+	}))
+	defer server.Close()
+	c, err := New(&config.Ariadna{ElasticURLs: []string{server.URL}, ElasticIndex: "addresses"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := c.ReverseBatch(context.Background(), []model.Location{{Lat: 42.87, Lon: 74.59}, {Lat: 42, Lon: 75}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines != 4 {
+		t.Errorf("request has %d lines, expected header and body per point", lines)
+	}
+	if len(results) != 2 || len(results[0].Addresses) != 1 || len(results[1].Addresses) != 0 {
+		data, _ := json.Marshal(results)
+		t.Fatalf("results = %s", data)
+	}
+	if results[0].Addresses[0].ID != "1" || results[0].Addresses[0].Street != "Киевская" {
+		t.Errorf("address = %+v", results[0].Addresses[0])
+	}
+}
+
+func TestSearchBoosts(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		body = string(data)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"hits": {"hits": []}}`))
+	}))
+	defer server.Close()
+	c, err := New(&config.Ariadna{ElasticURLs: []string{server.URL}, ElasticIndex: "addresses"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile := DefaultRanking
+	profile.LayerBoosts = map[string]float64{"transit": 0.5, PoiLayer: 2}
+	profile.CategoryBoosts = map[string]float64{"cafe": 3}
+	if _, err := c.SearchRanked(context.Background(), "Чуй", profile); err != nil {
+		t.Fatal(err)
+	}
+	for _, part := range []string{`"weight":2`, `{"term":{"layer":"transit"}},"weight":0.5`, `{"term":{"category":"cafe"}},"weight":3`, `"score_mode":"multiply"`} {
+		if !strings.Contains(body, part) {
+			t.Errorf("query has no %s: %s", part, body)
+		}
+	}
+}
+
+func TestSearchPreset(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		body = string(data)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"hits": {"hits": []}}`))
+	}))
+	defer server.Close()
+	c, err := New(&config.Ariadna{ElasticURLs: []string{server.URL}, ElasticIndex: "addresses"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.config.Fields = []config.Field{{Name: "cuisine", Tag: "cuisine"}, {Name: "wheelchair", Tag: "wheelchair", Type: config.FieldBool}}
+	profile := config.RankingProfile{
+		Layers:     []string{"transit"},
+		Categories: []string{"cafe"},
+		Filters:    map[string][]string{"cuisine": {"georgian"}, "wheelchair": {"true"}},
+		Size:       500,
+	}
+	if _, err := c.SearchRanked(context.Background(), "Чуй", profile); err != nil {
+		t.Fatal(err)
+	}
+	for _, part := range []string{`"size":100`, `{"term":{"layer":"transit"}}`, `{"terms":{"category":["cafe"]}}`,
+		`{"terms":{"fields.cuisine.keyword":["georgian"]}}`, `{"terms":{"fields.wheelchair":["true"]}}`} {
+		if !strings.Contains(body, part) {
+			t.Errorf("query has no %s: %s", part, body)
+		}
+	}
+}
+
+func TestSearchFacets(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		body = string(data)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"hits": {"hits": []}, "aggregations": {
+			"category": {"buckets": [{"key": "restaurant", "doc_count": 124}, {"key": "cafe", "doc_count": 87}]},
+			"wheelchair": {"buckets": [{"key": 1, "key_as_string": "true", "doc_count": 5}]}
+		}}`))
+	}))
+	defer server.Close()
+	c, err := New(&config.Ariadna{ElasticURLs: []string{server.URL}, ElasticIndex: "addresses",
+		Fields: []config.Field{{Name: "wheelchair", Tag: "wheelchair", Type: config.FieldBool}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := c.SearchRanked(context.Background(), "Чуй", config.RankingProfile{Facets: []string{"category", "city", "layer", "wheelchair"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, part := range []string{`"city":{"terms":{"field":"city.keyword","size":10}}`, `"missing":"address"`, `"field":"fields.wheelchair"`} {
+		if !strings.Contains(body, part) {
+			t.Errorf("query has no %s: %s", part, body)
+		}
+	}
+	category := result.Facets["category"]
+	if len(category) != 2 || category[0].Value != "restaurant" || category[0].Count != 124 {
+		t.Errorf("category facet = %+v", category)
+	}
+	if wheelchair := result.Facets["wheelchair"]; len(wheelchair) != 1 || wheelchair[0].Value != "true" {
+		t.Errorf("wheelchair facet = %+v", wheelchair)
+	}
+}
@@ -0,0 +1,127 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/maddevsio/ariadna/model"
+)
+
+const (
+	// ShardByCountry puts documents of every country into own index
+	ShardByCountry = "country"
+	// ShardByCell puts documents into index of grid cell containing their location
+	ShardByCell = "cell"
+
+	defaultCellSize = 10.0
+)
+
+// shardDocument holds fields of document used to pick its shard
+type shardDocument struct {
+	Country  string         `json:"country"`
+	Location model.Location `json:"location"`
+}
+
+// route rewrites bulk request body so every document goes into index of its shard,
+// routed by shard key. Shard indices are created on first use
+func (c *Client) route(ctx context.Context, buf bytes.Buffer) (bytes.Buffer, error) {
+	var routed bytes.Buffer
+	lines := bytes.Split(bytes.TrimRight(buf.Bytes(), "\n"), []byte("\n"))
+	for n := 0; n+1 < len(lines); n += 2 {
+		meta, data := lines[n], lines[n+1]
+		var doc shardDocument
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return routed, err
+		}
+		key, err := shardKey(c.config.Sharding.Mode, c.config.Sharding.CellSize, doc)
+		if err != nil {
+			return routed, err
+		}
+		if key != "" {
+			index, err := c.shardIndex(ctx, key)
+			if err != nil {
+				return routed, err
+			}
+			if meta, err = routeMeta(meta, index, key); err != nil {
+				return routed, err
+			}
+		}
+		routed.Grow(len(meta) + len(data) + 2)
+		routed.Write(meta)
+		routed.WriteByte('\n')
+		routed.Write(data)
+		routed.WriteByte('\n')
+	}
+	return routed, nil
+}
+
+// shardKey returns shard of document. Documents without country stay in the main index
+func shardKey(mode string, cellSize float64, doc shardDocument) (string, error) {
+	switch mode {
+	case ShardByCountry:
+		return strings.Trim(strings.Map(indexRune, strings.ToLower(strings.TrimSpace(doc.Country))), "_"), nil
+	case ShardByCell:
+		if cellSize <= 0 {
+			cellSize = defaultCellSize
+		}
+		row := int(math.Floor((doc.Location.Lat + 90) / cellSize))
+		col := int(math.Floor((doc.Location.Lon + 180) / cellSize))
+		return fmt.Sprintf("r%dc%d", row, col), nil
+	}
+	return "", fmt.Errorf("unknown sharding mode %q", mode)
+}
+
+// indexRune replaces characters which are not allowed or awkward in index names
+func indexRune(r rune) rune {
+	if unicode.IsLetter(r) || unicode.IsDigit(r) {
+		return r
+	}
+	return '_'
+}
+
+// routeMeta sets index and routing of bulk action
+func routeMeta(meta []byte, index, routing string) ([]byte, error) {
+	var actions map[string]map[string]interface{}
+	if err := json.Unmarshal(meta, &actions); err != nil {
+		return nil, err
+	}
+	for _, action := range actions {
+		action["_index"] = index
+		action["routing"] = routing
+	}
+	return json.Marshal(actions)
+}
+
+// shardIndex returns index of shard created by current import. Every shard index is
+// put behind the global alias and own alias named after the shard
+func (c *Client) shardIndex(ctx context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if index, ok := c.shards[key]; ok {
+		return index, nil
+	}
+	alias := fmt.Sprintf("%s-%s", c.config.ElasticIndex, key)
+	index := fmt.Sprintf("%s-%d", alias, c.created)
+	if err := c.createIndex(ctx, index, c.config.ElasticIndex, alias); err != nil {
+		return "", err
+	}
+	c.shards[key] = index
+	return index, nil
+}
+
+// isShard reports whether index is a shard created by current import
+func (c *Client) isShard(index string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, shard := range c.shards {
+		if shard == index {
+			return true
+		}
+	}
+	return false
+}
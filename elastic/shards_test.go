@@ -0,0 +1,54 @@
+package elastic
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/maddevsio/ariadna/model"
+)
+
+func TestShardKey(t *testing.T) {
+	bishkek := shardDocument{Country: "Кыргызстан", Location: model.Location{Lat: 42.87, Lon: 74.59}}
+	cases := []struct {
+		mode     string
+		cellSize float64
+		doc      shardDocument
+		want     string
+	}{
+		{ShardByCountry, 0, bishkek, "кыргызстан"},
+		{ShardByCountry, 0, shardDocument{Country: "Côte d'Ivoire"}, "côte_d_ivoire"},
+		{ShardByCountry, 0, shardDocument{}, ""},
+		{ShardByCell, 0, bishkek, "r13c25"},
+		{ShardByCell, 1, bishkek, "r132c254"},
+		{ShardByCell, 10, shardDocument{Location: model.Location{Lat: -33.9, Lon: -70.6}}, "r5c10"},
+	}
+	for _, c := range cases {
+		got, err := shardKey(c.mode, c.cellSize, c.doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.want {
+			t.Errorf("shardKey(%q, %v, %+v) = %q, want %q", c.mode, c.cellSize, c.doc, got, c.want)
+		}
+	}
+	if _, err := shardKey("continent", 0, bishkek); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
+
+func TestRouteMeta(t *testing.T) {
+	meta, err := routeMeta([]byte(`{ "index": { "_id": "node/1" } }`), "addresses-kg-1", "kg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions map[string]map[string]string
+	if err := json.Unmarshal(meta, &actions); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"_id": "node/1", "_index": "addresses-kg-1", "routing": "kg"}
+	for k, v := range want {
+		if actions["index"][k] != v {
+			t.Errorf("%s = %q, want %q", k, actions["index"][k], v)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v7/esapi"
+)
+
+// ErrNoRepository is reported by snapshot commands when repository is not configured
+var ErrNoRepository = errors.New("snapshot repository is not configured")
+
+// SnapshotInfo describes snapshot of indices in repository
+type SnapshotInfo struct {
+	Name      string    `json:"snapshot"`
+	State     string    `json:"state"`
+	Indices   []string  `json:"indices"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+}
+
+// CreateSnapshot copies indices behind the alias into configured repository and waits
+// for completion. Empty name is replaced by alias and current time
+func (c *Client) CreateSnapshot(ctx context.Context, name string) (*SnapshotInfo, error) {
+	if err := c.putRepository(ctx); err != nil {
+		return nil, err
+	}
+	indices, err := c.aliasIndices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(indices) == 0 {
+		return nil, fmt.Errorf("%w: no indices behind %s", ErrIndexUnavailable, c.config.ElasticIndex)
+	}
+	if name == "" {
+		name = fmt.Sprintf("%s-%s", c.config.ElasticIndex, time.Now().UTC().Format("20060102150405"))
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"indices":              indices,
+		"include_global_state": false,
+	})
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.conn.Snapshot.Create(c.config.Snapshot.Repository, name,
+		c.conn.Snapshot.Create.WithBody(bytes.NewReader(body)),
+		c.conn.Snapshot.Create.WithWaitForCompletion(true),
+		c.conn.Snapshot.Create.WithContext(ctx),
+	)
+	if err != nil {
+		return nil, unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, responseError("create snapshot", res)
+	}
+	var r struct {
+		Snapshot SnapshotInfo `json:"snapshot"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return nil, err
+	}
+	c.logger.Infof("snapshot %s of %v created", name, indices)
+	return &r.Snapshot, nil
+}
+
+// Snapshots lists snapshots of configured repository
+func (c *Client) Snapshots(ctx context.Context) ([]SnapshotInfo, error) {
+	if err := c.putRepository(ctx); err != nil {
+		return nil, err
+	}
+	res, err := c.conn.Snapshot.Get(c.config.Snapshot.Repository, []string{"_all"},
+		c.conn.Snapshot.Get.WithContext(ctx),
+	)
+	if err != nil {
+		return nil, unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, responseError("list snapshots", res)
+	}
+	var r struct {
+		Snapshots []SnapshotInfo `json:"snapshots"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return nil, err
+	}
+	return r.Snapshots, nil
+}
+
+// RestoreSnapshot restores indices of snapshot with their aliases and waits for completion.
+// Indices which were behind the alias before are deleted, so search switches to restored ones
+func (c *Client) RestoreSnapshot(ctx context.Context, name string) (*SnapshotInfo, error) {
+	if err := c.putRepository(ctx); err != nil {
+		return nil, err
+	}
+	previous, err := c.aliasIndices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"include_aliases":      true,
+		"include_global_state": false,
+	})
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.conn.Snapshot.Restore(c.config.Snapshot.Repository, name,
+		c.conn.Snapshot.Restore.WithBody(bytes.NewReader(body)),
+		c.conn.Snapshot.Restore.WithWaitForCompletion(true),
+		c.conn.Snapshot.Restore.WithContext(ctx),
+	)
+	if err != nil {
+		return nil, unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, responseError("restore snapshot", res)
+	}
+	var r struct {
+		Snapshot SnapshotInfo `json:"snapshot"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return nil, err
+	}
+	restored := make(map[string]bool, len(r.Snapshot.Indices))
+	for _, index := range r.Snapshot.Indices {
+		restored[index] = true
+	}
+	var stale []string
+	for _, index := range previous {
+		if !restored[index] {
+			stale = append(stale, index)
+		}
+	}
+	c.logger.Infof("snapshot %s restored: %v", name, r.Snapshot.Indices)
+	if len(stale) == 0 {
+		return &r.Snapshot, nil
+	}
+	del, err := c.conn.Indices.Delete(stale, c.conn.Indices.Delete.WithContext(ctx))
+	if err != nil {
+		return nil, unavailable(err)
+	}
+	defer del.Body.Close()
+	if del.IsError() {
+		return nil, responseError("delete indices", del)
+	}
+	c.logger.Infof("deleted indices: %v", stale)
+	return &r.Snapshot, nil
+}
+
+// putRepository registers configured repository, it is idempotent
+func (c *Client) putRepository(ctx context.Context) error {
+	s := c.config.Snapshot
+	if s.Repository == "" {
+		return ErrNoRepository
+	}
+	body, err := json.Marshal(map[string]interface{}{"type": s.Type, "settings": s.Settings})
+	if err != nil {
+		return err
+	}
+	res, err := c.conn.Snapshot.CreateRepository(s.Repository, bytes.NewReader(body),
+		c.conn.Snapshot.CreateRepository.WithContext(ctx),
+	)
+	if err != nil {
+		return unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return responseError("create snapshot repository", res)
+	}
+	return nil
+}
+
+// aliasIndices lists indices behind the alias, none when alias doesn't exist yet
+func (c *Client) aliasIndices(ctx context.Context) ([]string, error) {
+	r := esapi.IndicesGetAliasRequest{Name: []string{c.config.ElasticIndex}}
+	res, err := r.Do(ctx, c.conn.Transport)
+	if err != nil {
+		return nil, unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if res.IsError() {
+		return nil, responseError("get alias", res)
+	}
+	var indices map[string]json.RawMessage
+	if err := json.NewDecoder(res.Body).Decode(&indices); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(indices))
+	for name := range indices {
+		names = append(names, name)
+	}
+	return names, nil
+}
@@ -0,0 +1,68 @@
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/maddevsio/ariadna/config"
+)
+
+func TestRestoreSnapshot(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/_alias/addresses":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"addresses-1": map[string]interface{}{}, "addresses-kg-1": map[string]interface{}{},
+			})
+		case "/_snapshot/backups/nightly/_restore":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"snapshot": map[string]interface{}{"snapshot": "nightly", "indices": []string{"addresses-2", "addresses-kg-1"}},
+			})
+		default:
+			w.Write([]byte(`{"acknowledged": true}`))
+		}
+	}))
+	defer server.Close()
+	c, err := New(&config.Ariadna{
+		ElasticURLs:  []string{server.URL},
+		ElasticIndex: "addresses",
+		Snapshot:     config.Snapshot{Repository: "backups", Type: "fs", Settings: map[string]string{"location": "/backups"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := c.RestoreSnapshot(context.Background(), "nightly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(info.Indices)
+	if len(info.Indices) != 2 || info.Indices[0] != "addresses-2" {
+		t.Errorf("indices = %v", info.Indices)
+	}
+	expected := []string{
+		"PUT /_snapshot/backups",
+		"GET /_alias/addresses",
+		"POST /_snapshot/backups/nightly/_restore",
+		"DELETE /addresses-1",
+	}
+	if len(requests) != len(expected) {
+		t.Fatalf("requests = %v", requests)
+	}
+	for n := range expected {
+		if requests[n] != expected[n] {
+			t.Errorf("request %d = %q, expected %q", n, requests[n], expected[n])
+		}
+	}
+
+	c.config.Snapshot.Repository = ""
+	if _, err := c.CreateSnapshot(context.Background(), ""); err != ErrNoRepository {
+		t.Errorf("err = %v", err)
+	}
+}
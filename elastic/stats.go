@@ -0,0 +1,186 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+)
+
+const (
+	// AddressLayer names documents without layer in statistics
+	AddressLayer = "address"
+	// statsTerms limits layers and categories counted by statistics
+	statsTerms = 100
+)
+
+// ImportInfo describes import which filled the index. ExtractTime is modification time
+// of the source extract, nil when unknown
+type ImportInfo struct {
+	ImportedAt  time.Time  `json:"imported_at"`
+	ExtractTime *time.Time `json:"extract_time,omitempty"`
+	Source      string     `json:"source,omitempty"`
+}
+
+// Stats describes content of index
+type Stats struct {
+	Documents int64                 `json:"documents"`
+	SizeBytes int64                 `json:"size_bytes"`
+	Layers    map[string]LayerStats `json:"layers"`
+	Import    *ImportInfo           `json:"import,omitempty"`
+}
+
+// LayerStats counts documents of layer per category
+type LayerStats struct {
+	Documents  int64            `json:"documents"`
+	Categories map[string]int64 `json:"categories,omitempty"`
+}
+
+// WriteImportInfo records import in metadata of created indices
+func (c *Client) WriteImportInfo(ctx context.Context, info ImportInfo) error {
+	data, err := json.Marshal(map[string]interface{}{
+		"_meta": map[string]interface{}{"import": info},
+	})
+	if err != nil {
+		return err
+	}
+	indices := []string{c.createdIndex}
+	c.mu.Lock()
+	for _, index := range c.shards {
+		indices = append(indices, index)
+	}
+	c.mu.Unlock()
+	res, err := c.conn.Indices.PutMapping(bytes.NewReader(data),
+		c.conn.Indices.PutMapping.WithIndex(indices...),
+		c.conn.Indices.PutMapping.WithContext(ctx),
+	)
+	if err != nil {
+		return unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return responseError("write import info", res)
+	}
+	return nil
+}
+
+type statsResponse struct {
+	Hits struct {
+		Total struct {
+			Value int64 `json:"value"`
+		} `json:"total"`
+	} `json:"hits"`
+	Aggregations struct {
+		Layers struct {
+			Buckets []struct {
+				Key        string `json:"key"`
+				DocCount   int64  `json:"doc_count"`
+				Categories struct {
+					Buckets []struct {
+						Key      string `json:"key"`
+						DocCount int64  `json:"doc_count"`
+					} `json:"buckets"`
+				} `json:"categories"`
+			} `json:"buckets"`
+		} `json:"layers"`
+	} `json:"aggregations"`
+}
+
+// Stats counts documents per layer and category, reports size of index and its latest import
+func (c *Client) Stats(ctx context.Context) (*Stats, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"size":             0,
+		"track_total_hits": true,
+		"aggs": map[string]interface{}{
+			"layers": map[string]interface{}{
+				"terms": map[string]interface{}{"field": c.names.name("layer"), "size": statsTerms, "missing": AddressLayer},
+				"aggs": map[string]interface{}{
+					"categories": map[string]interface{}{
+						"terms": map[string]interface{}{"field": c.names.name("category"), "size": statsTerms},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.conn.Search(
+		c.conn.Search.WithContext(ctx),
+		c.conn.Search.WithIndex(c.config.ElasticIndex),
+		c.conn.Search.WithBody(bytes.NewReader(body)),
+	)
+	if err != nil {
+		return nil, unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, responseError("count documents", res)
+	}
+	var r statsResponse
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return nil, err
+	}
+	stats := &Stats{Documents: r.Hits.Total.Value, Layers: make(map[string]LayerStats)}
+	for _, layer := range r.Aggregations.Layers.Buckets {
+		l := LayerStats{Documents: layer.DocCount}
+		for _, category := range layer.Categories.Buckets {
+			if l.Categories == nil {
+				l.Categories = make(map[string]int64)
+			}
+			l.Categories[category.Key] = category.DocCount
+		}
+		stats.Layers[layer.Key] = l
+	}
+	if stats.SizeBytes, err = c.indexSize(ctx); err != nil {
+		return nil, err
+	}
+	if stats.Import, err = c.importInfo(ctx); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// indexSize returns size of primary shards behind the alias in bytes
+func (c *Client) indexSize(ctx context.Context) (int64, error) {
+	res, err := c.conn.Indices.Stats(
+		c.conn.Indices.Stats.WithIndex(c.config.ElasticIndex),
+		c.conn.Indices.Stats.WithMetric("store"),
+		c.conn.Indices.Stats.WithContext(ctx),
+	)
+	if err != nil {
+		return 0, unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return 0, responseError("get index size", res)
+	}
+	var r struct {
+		All struct {
+			Primaries struct {
+				Store struct {
+					SizeInBytes int64 `json:"size_in_bytes"`
+				} `json:"store"`
+			} `json:"primaries"`
+		} `json:"_all"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return 0, err
+	}
+	return r.All.Primaries.Store.SizeInBytes, nil
+}
+
+// importInfo returns the latest import recorded in metadata of indices behind the alias
+func (c *Client) importInfo(ctx context.Context) (*ImportInfo, error) {
+	infos, err := c.importInfos(ctx, c.config.ElasticIndex)
+	if err != nil {
+		return nil, err
+	}
+	var latest *ImportInfo
+	for _, info := range infos {
+		if info != nil && (latest == nil || info.ImportedAt.After(latest.ImportedAt)) {
+			latest = info
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,159 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// maxUsage caps counters read by Usage
+const maxUsage = 10000
+
+// maxUsageKey caps length of keys in ids of counters, longer keys are hashed
+const maxUsageKey = 128
+
+type (
+	// Usage is count of requests of API key in period, day like 2022-01-15 or month like 2022-01
+	Usage struct {
+		Key    string `json:"key"`
+		Period string `json:"period"`
+		Count  int64  `json:"count"`
+	}
+	// UsageError lists counts which failed to be added, others are added
+	UsageError struct {
+		Failed []Usage
+		Errors []string
+	}
+)
+
+func (e *UsageError) Error() string {
+	return "add usage: " + strings.Join(e.Errors, ", ")
+}
+
+// usageID returns id of counter of key in period, it fits limit of elasticsearch ids
+func usageID(u Usage) string {
+	key := u.Key
+	if len(key) > maxUsageKey {
+		sum := sha256.Sum256([]byte(key))
+		key = hex.EncodeToString(sum[:])
+	}
+	return key + "/" + u.Period
+}
+
+// UsageIndex returns index request counters are kept in, it is not behind the alias
+func (c *Client) UsageIndex() string {
+	if c.config.Quotas.Index != "" {
+		return c.config.Quotas.Index
+	}
+	return c.config.ElasticIndex + "-usage"
+}
+
+// AddUsage adds counts to counters of their keys and periods, counters missing yet are created.
+// Counters of failed request may be partially added, *UsageError lists counts which weren't
+func (c *Client) AddUsage(ctx context.Context, usage []Usage) error {
+	if len(usage) == 0 {
+		return nil
+	}
+	err := c.ensureIndex(ctx, c.UsageIndex(), map[string]interface{}{
+		"key":    map[string]interface{}{"type": "keyword"},
+		"period": map[string]interface{}{"type": "keyword"},
+		"count":  map[string]interface{}{"type": "long"},
+	})
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, u := range usage {
+		action := map[string]interface{}{
+			"update": map[string]interface{}{"_id": usageID(u), "retry_on_conflict": 3},
+		}
+		update := map[string]interface{}{
+			"script": map[string]interface{}{
+				"source": "ctx._source.count += params.count",
+				"params": map[string]int64{"count": u.Count},
+			},
+			"upsert": u,
+		}
+		if err := enc.Encode(action); err != nil {
+			return err
+		}
+		if err := enc.Encode(update); err != nil {
+			return err
+		}
+	}
+	res, err := c.conn.Bulk(bytes.NewReader(buf.Bytes()),
+		c.conn.Bulk.WithIndex(c.UsageIndex()),
+		c.conn.Bulk.WithContext(ctx),
+	)
+	if err != nil {
+		return unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return responseError("add usage", res)
+	}
+	var r bulkResponse
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return err
+	}
+	if !r.Errors {
+		return nil
+	}
+	failed := &UsageError{}
+	// items are in order of actions
+	for n, item := range r.Items {
+		for _, result := range item {
+			if result.Error != nil && n < len(usage) {
+				failed.Failed = append(failed.Failed, usage[n])
+				failed.Errors = append(failed.Errors, fmt.Sprintf("%s: %s", result.ID, result.Error.Type))
+			}
+		}
+	}
+	return failed
+}
+
+// Usage returns counters of periods. They are read from the primary cluster as followers
+// replicate only the alias
+func (c *Client) Usage(ctx context.Context, periods []string) ([]Usage, error) {
+	data, err := json.Marshal(map[string]interface{}{
+		"size":  maxUsage,
+		"query": map[string]interface{}{"terms": map[string]interface{}{"period": periods}},
+		"sort":  []string{"key", "period"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.conn.Search(
+		c.conn.Search.WithContext(ctx),
+		c.conn.Search.WithIndex(c.UsageIndex()),
+		c.conn.Search.WithBody(bytes.NewReader(data)),
+		c.conn.Search.WithIgnoreUnavailable(true),
+	)
+	if err != nil {
+		return nil, unavailable(err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, responseError("get usage", res)
+	}
+	var r struct {
+		Hits struct {
+			Hits []struct {
+				Source Usage `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return nil, err
+	}
+	usage := make([]Usage, 0, len(r.Hits.Hits))
+	for _, hit := range r.Hits.Hits {
+		usage = append(usage, hit.Source)
+	}
+	return usage, nil
+}
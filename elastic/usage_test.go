@@ -0,0 +1,82 @@
+package elastic
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maddevsio/ariadna/config"
+)
+
+func TestUsage(t *testing.T) {
+	var bulk, query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodHead && r.URL.Path == "/addresses-usage":
+		case r.URL.Path == "/addresses-usage/_bulk":
+			data, _ := ioutil.ReadAll(r.Body)
+			bulk = string(data)
+			w.Write([]byte(`{"errors": false, "items": []}`))
+		case r.URL.Path == "/addresses-usage/_search":
+			data, _ := ioutil.ReadAll(r.Body)
+			query = string(data)
+			w.Write([]byte(`{"hits": {"hits": [{"_source": {"key": "client", "period": "2022-01-15", "count": 42}}]}}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	c, err := New(&config.Ariadna{ElasticURLs: []string{server.URL}, ElasticIndex: "addresses"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := c.AddUsage(ctx, []Usage{{Key: "client", Period: "2022-01-15", Count: 3}}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(bulk, `"_id":"client/2022-01-15"`) || !strings.Contains(bulk, `ctx._source.count += params.count`) || !strings.Contains(bulk, `"upsert":{"key":"client"`) {
+		t.Errorf("bulk = %s", bulk)
+	}
+	usage, err := c.Usage(ctx, []string{"2022-01-15", "2022-01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usage) != 1 || usage[0].Count != 42 || !strings.Contains(query, `"period":["2022-01-15","2022-01"]`) {
+		t.Errorf("usage %+v, query %s", usage, query)
+	}
+}
+
+func TestAddUsagePartialFailure(t *testing.T) {
+	var bulk string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodHead {
+			return
+		}
+		data, _ := ioutil.ReadAll(r.Body)
+		bulk = string(data)
+		w.Write([]byte(`{"errors": true, "items": [
+			{"update": {"_id": "a/2022-01-15", "status": 200}},
+			{"update": {"_id": "b/2022-01-15", "status": 400, "error": {"type": "mapper_parsing_exception", "reason": "bad"}}}
+		]}`))
+	}))
+	defer server.Close()
+	c, err := New(&config.Ariadna{ElasticURLs: []string{server.URL}, ElasticIndex: "addresses"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	long := strings.Repeat("k", 600)
+	err = c.AddUsage(context.Background(), []Usage{{Key: "a", Period: "2022-01-15", Count: 1}, {Key: long, Period: "2022-01-15", Count: 2}})
+	var failed *UsageError
+	if !errors.As(err, &failed) || len(failed.Failed) != 1 || failed.Failed[0].Count != 2 {
+		t.Fatalf("err = %v, expected the second count to fail", err)
+	}
+	if strings.Contains(bulk, `"_id":"`+long) {
+		t.Error("long key is used in id as is")
+	}
+}
@@ -0,0 +1,113 @@
+// Package elevation samples terrain height from SRTM .hgt tiles stored in local directory
+package elevation
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// void marks missing data in SRTM tiles
+const void = -32768
+
+type tile struct {
+	size int
+	data []int16
+}
+
+// Tiles lazily loads and caches tiles on first access
+type Tiles struct {
+	mu    sync.Mutex
+	dir   string
+	tiles map[string]*tile
+}
+
+// New creates Tiles reading .hgt files from dir
+func New(dir string) *Tiles {
+	return &Tiles{dir: dir, tiles: make(map[string]*tile)}
+}
+
+// Name returns SRTM tile name covering point, e.g. N42E074
+func Name(lat, lon float64) string {
+	latBase := int(math.Floor(lat))
+	lonBase := int(math.Floor(lon))
+	ns, ew := 'N', 'E'
+	if latBase < 0 {
+		ns = 'S'
+		latBase = -latBase
+	}
+	if lonBase < 0 {
+		ew = 'W'
+		lonBase = -lonBase
+	}
+	return fmt.Sprintf("%c%02d%c%03d", ns, latBase, ew, lonBase)
+}
+
+// Elevation returns height in meters at point using bilinear interpolation.
+// It reports false when tile is missing or has no data at point
+func (t *Tiles) Elevation(lat, lon float64) (float64, bool) {
+	tl, err := t.tile(Name(lat, lon))
+	if err != nil || tl == nil {
+		return 0, false
+	}
+	// rows go from north to south, columns from west to east
+	y := (1 - (lat - math.Floor(lat))) * float64(tl.size-1)
+	x := (lon - math.Floor(lon)) * float64(tl.size-1)
+	x0, y0 := int(x), int(y)
+	x1, y1 := min(x0+1, tl.size-1), min(y0+1, tl.size-1)
+	h00, h10 := tl.at(x0, y0), tl.at(x1, y0)
+	h01, h11 := tl.at(x0, y1), tl.at(x1, y1)
+	if h00 == void || h10 == void || h01 == void || h11 == void {
+		return 0, false
+	}
+	dx, dy := x-float64(x0), y-float64(y0)
+	top := float64(h00)*(1-dx) + float64(h10)*dx
+	bottom := float64(h01)*(1-dx) + float64(h11)*dx
+	return top*(1-dy) + bottom*dy, true
+}
+
+func (tl *tile) at(x, y int) int16 {
+	return tl.data[y*tl.size+x]
+}
+
+func (t *Tiles) tile(name string) (*tile, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if tl, ok := t.tiles[name]; ok {
+		return tl, nil
+	}
+	tl, err := load(filepath.Join(t.dir, name+".hgt"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	// missing tiles are cached as nil to avoid hitting disk again
+	t.tiles[name] = tl
+	return tl, nil
+}
+
+func load(path string) (*tile, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	size := int(math.Sqrt(float64(len(raw) / 2)))
+	if size < 2 || size*size*2 != len(raw) {
+		return nil, fmt.Errorf("%s is not a valid hgt tile", path)
+	}
+	tl := &tile{size: size, data: make([]int16, size*size)}
+	for idx := range tl.data {
+		tl.data[idx] = int16(binary.BigEndian.Uint16(raw[idx*2:]))
+	}
+	return tl, nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,43 @@
+package elevation
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestElevation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hgt")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	// 3x3 tile, north row first
+	heights := []int16{
+		800, 900, 1000,
+		700, 800, 900,
+		600, 700, void,
+	}
+	raw := make([]byte, len(heights)*2)
+	for idx, h := range heights {
+		binary.BigEndian.PutUint16(raw[idx*2:], uint16(h))
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "N42E074.hgt"), raw, 0644))
+
+	assert.Equal(t, "N42E074", Name(42.87, 74.59))
+	assert.Equal(t, "S01W001", Name(-0.5, -0.5))
+	tiles := New(dir)
+	h, ok := tiles.Elevation(42.5, 74)
+	assert.True(t, ok)
+	assert.Equal(t, 700.0, h)
+	h, ok = tiles.Elevation(42.5, 74.25)
+	assert.True(t, ok)
+	assert.Equal(t, 750.0, h)
+	_, ok = tiles.Elevation(42.1, 74.9)
+	assert.False(t, ok)
+	_, ok = tiles.Elevation(10, 10)
+	assert.False(t, ok)
+}
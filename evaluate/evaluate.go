@@ -0,0 +1,169 @@
+// Package evaluate measures search quality against ground-truth test set
+package evaluate
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/geodesic"
+)
+
+// topN is number of results inspected for every query
+const topN = 5
+
+type (
+	// Searcher runs text search against the index
+	Searcher interface {
+		Search(ctx context.Context, query string) (*elastic.Result, error)
+	}
+	// Case is a single ground-truth entry. Either expected point or expected document id is set
+	Case struct {
+		Query    string
+		Lat, Lon float64
+		HasPoint bool
+		ID       string
+	}
+	// Outcome is result of a single case. Rank is 1-based position of expected result, 0 when not found
+	Outcome struct {
+		Query     string  `json:"query"`
+		Rank      int     `json:"rank"`
+		DistanceM float64 `json:"distance_m,omitempty"`
+	}
+	// Report aggregates outcomes of test set run
+	Report struct {
+		Total         int       `json:"total"`
+		PrecisionAt1  float64   `json:"precision_at_1"`
+		PrecisionAt5  float64   `json:"precision_at_5"`
+		MeanDistanceM float64   `json:"mean_distance_m"`
+		Outcomes      []Outcome `json:"outcomes"`
+		Regressions   []string  `json:"regressions,omitempty"`
+	}
+)
+
+// ReadCases parses CSV with query,lat,lon,id columns. Header row is optional
+func ReadCases(r io.Reader) ([]Case, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	var cases []Case
+	for line, record := range records {
+		if line == 0 && strings.EqualFold(strings.TrimSpace(record[0]), "query") {
+			continue
+		}
+		c := Case{Query: strings.TrimSpace(record[0])}
+		if len(record) >= 3 && record[1] != "" && record[2] != "" {
+			if c.Lat, err = strconv.ParseFloat(strings.TrimSpace(record[1]), 64); err != nil {
+				return nil, fmt.Errorf("line %d: %v", line+1, err)
+			}
+			if c.Lon, err = strconv.ParseFloat(strings.TrimSpace(record[2]), 64); err != nil {
+				return nil, fmt.Errorf("line %d: %v", line+1, err)
+			}
+			c.HasPoint = true
+		}
+		if len(record) >= 4 {
+			c.ID = strings.TrimSpace(record[3])
+		}
+		if c.Query == "" || (!c.HasPoint && c.ID == "") {
+			return nil, fmt.Errorf("line %d: query and expected point or id are required", line+1)
+		}
+		cases = append(cases, c)
+	}
+	return cases, nil
+}
+
+// Run searches every case and reports precision and distance error.
+// Result matches when it has expected id or lies within radius meters from expected point
+func Run(ctx context.Context, s Searcher, cases []Case, radius float64) (*Report, error) {
+	report := &Report{Total: len(cases)}
+	var (
+		at1, at5  int
+		distances float64
+		measured  int
+	)
+	for _, c := range cases {
+		result, err := s.Search(ctx, c.Query)
+		if err != nil {
+			return nil, err
+		}
+		outcome := Outcome{Query: c.Query}
+		for idx, address := range result.Addresses {
+			if idx >= topN {
+				break
+			}
+			distance := 0.0
+			if c.HasPoint {
+				expected := geodesic.Point{Lat: c.Lat, Lon: c.Lon}
+				distance = geodesic.Distance(expected, geodesic.Point{Lat: address.Location.Lat, Lon: address.Location.Lon})
+			}
+			if idx == 0 && c.HasPoint {
+				outcome.DistanceM = distance
+				distances += distance
+				measured++
+			}
+			if (c.ID != "" && address.ID == c.ID) || (c.ID == "" && distance <= radius) {
+				outcome.Rank = idx + 1
+				break
+			}
+		}
+		if outcome.Rank == 1 {
+			at1++
+		}
+		if outcome.Rank >= 1 {
+			at5++
+		}
+		report.Outcomes = append(report.Outcomes, outcome)
+	}
+	if report.Total > 0 {
+		report.PrecisionAt1 = float64(at1) / float64(report.Total)
+		report.PrecisionAt5 = float64(at5) / float64(report.Total)
+	}
+	if measured > 0 {
+		report.MeanDistanceM = distances / float64(measured)
+	}
+	return report, nil
+}
+
+// Compare records queries which were found at the first position by previous run and are not anymore
+func (r *Report) Compare(previous *Report) {
+	ranks := make(map[string]int, len(previous.Outcomes))
+	for _, o := range previous.Outcomes {
+		ranks[o.Query] = o.Rank
+	}
+	for _, o := range r.Outcomes {
+		if prev, ok := ranks[o.Query]; ok && prev == 1 && o.Rank != 1 {
+			r.Regressions = append(r.Regressions, o.Query)
+		}
+	}
+}
+
+// Load reads report saved by previous run
+func Load(r io.Reader) (*Report, error) {
+	var report Report
+	if err := json.NewDecoder(r).Decode(&report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Print writes human readable summary
+func (r *Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "queries:        %d\n", r.Total)
+	fmt.Fprintf(w, "precision@1:    %.3f\n", r.PrecisionAt1)
+	fmt.Fprintf(w, "precision@5:    %.3f\n", r.PrecisionAt5)
+	fmt.Fprintf(w, "mean distance:  %.1fm\n", r.MeanDistanceM)
+	if len(r.Regressions) > 0 {
+		fmt.Fprintf(w, "regressions:    %d\n", len(r.Regressions))
+		for _, q := range r.Regressions {
+			fmt.Fprintf(w, "  %s\n", q)
+		}
+	}
+}
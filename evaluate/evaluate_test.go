@@ -0,0 +1,41 @@
+package evaluate
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSearcher map[string][]model.Address
+
+func (f fakeSearcher) Search(ctx context.Context, query string) (*elastic.Result, error) {
+	return &elastic.Result{Addresses: f[query]}, nil
+}
+
+func TestRun(t *testing.T) {
+	cases, err := ReadCases(strings.NewReader(`query,lat,lon,id
+Toktogula 125,42.8746,74.5698,
+Ala-Too,,,way/1
+Unknown,42.0,74.0,
+`))
+	require.NoError(t, err)
+	require.Len(t, cases, 3)
+	s := fakeSearcher{
+		"Toktogula 125": {{Location: model.Location{Lat: 42.8746, Lon: 74.5698}}},
+		"Ala-Too":       {{ID: "way/2"}, {ID: "way/1"}},
+	}
+	report, err := Run(context.Background(), s, cases, 100)
+	require.NoError(t, err)
+	assert.InDelta(t, 1.0/3, report.PrecisionAt1, 1e-9)
+	assert.InDelta(t, 2.0/3, report.PrecisionAt5, 1e-9)
+	assert.Equal(t, 2, report.Outcomes[1].Rank)
+
+	previous := &Report{Outcomes: []Outcome{{Query: "Ala-Too", Rank: 1}, {Query: "Toktogula 125", Rank: 1}}}
+	report.Compare(previous)
+	assert.Equal(t, []string{"Ala-Too"}, report.Regressions)
+}
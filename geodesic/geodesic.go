@@ -0,0 +1,109 @@
+// Package geodesic implements geometry on the Earth: distance on WGS84 ellipsoid, area on sphere
+// and point in polygon test for rings crossing the antimeridian or enclosing a pole. Coordinates
+// are degrees, rings of GeoJSON geometries are lon, lat pairs
+package geodesic
+
+import "math"
+
+const (
+	// EarthRadius is mean radius of the Earth in meters
+	EarthRadius = 6371008.8
+
+	// WGS84 ellipsoid
+	semiMajor  = 6378137.0
+	flattening = 1 / 298.257223563
+	semiMinor  = semiMajor * (1 - flattening)
+
+	maxIterations = 200
+)
+
+// Point is location in degrees
+type Point struct {
+	Lat, Lon float64
+}
+
+// Distance returns length in meters of geodesic between points on WGS84 ellipsoid by Vincenty
+// formulae. Nearly antipodal points where iteration doesn't converge get great circle distance
+func Distance(a, b Point) float64 {
+	l := radians(b.Lon - a.Lon)
+	u1 := math.Atan((1 - flattening) * math.Tan(radians(a.Lat)))
+	u2 := math.Atan((1 - flattening) * math.Tan(radians(b.Lat)))
+	sinU1, cosU1 := math.Sincos(u1)
+	sinU2, cosU2 := math.Sincos(u2)
+	lambda := l
+	for n := 0; n < maxIterations; n++ {
+		sinLambda, cosLambda := math.Sincos(lambda)
+		sinSigma := math.Hypot(cosU2*sinLambda, cosU1*sinU2-sinU1*cosU2*cosLambda)
+		if sinSigma == 0 {
+			// coincident points
+			return 0
+		}
+		cosSigma := sinU1*sinU2 + cosU1*cosU2*cosLambda
+		sigma := math.Atan2(sinSigma, cosSigma)
+		sinAlpha := cosU1 * cosU2 * sinLambda / sinSigma
+		cos2Alpha := 1 - sinAlpha*sinAlpha
+		cos2SigmaM := 0.0
+		if cos2Alpha != 0 {
+			// points on equator have no mid point latitude
+			cos2SigmaM = cosSigma - 2*sinU1*sinU2/cos2Alpha
+		}
+		c := flattening / 16 * cos2Alpha * (4 + flattening*(4-3*cos2Alpha))
+		prev := lambda
+		lambda = l + (1-c)*flattening*sinAlpha*(sigma+c*sinSigma*(cos2SigmaM+c*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+		if math.Abs(lambda-prev) > 1e-12 {
+			continue
+		}
+		// series of Vincenty with coefficients by Helmert expansion
+		uSq := cos2Alpha * (semiMajor*semiMajor - semiMinor*semiMinor) / (semiMinor * semiMinor)
+		k1 := (math.Sqrt(1+uSq) - 1) / (math.Sqrt(1+uSq) + 1)
+		bigA := (1 + k1*k1/4) / (1 - k1)
+		bigB := k1 * (1 - 3*k1*k1/8)
+		deltaSigma := bigB * sinSigma * (cos2SigmaM + bigB/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-
+			bigB/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+		return semiMinor * bigA * (sigma - deltaSigma)
+	}
+	return GreatCircle(a, b)
+}
+
+// GreatCircle returns distance in meters between points on sphere of mean Earth radius
+func GreatCircle(a, b Point) float64 {
+	dLat, dLon := radians(b.Lat-a.Lat), radians(b.Lon-a.Lon)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(radians(a.Lat))*math.Cos(radians(b.Lat))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Bearing returns initial bearing in degrees clockwise from north, 0..360, of great circle from a
+// to b. Bearing of coincident points is 0
+func Bearing(a, b Point) float64 {
+	sinLat1, cosLat1 := math.Sincos(radians(a.Lat))
+	sinLat2, cosLat2 := math.Sincos(radians(b.Lat))
+	sinDLon, cosDLon := math.Sincos(radians(b.Lon - a.Lon))
+	bearing := math.Atan2(sinDLon*cosLat2, cosLat1*sinLat2-sinLat1*cosLat2*cosDLon) * 180 / math.Pi
+	return math.Mod(bearing+360, 360)
+}
+
+// Area returns area of lon, lat ring on sphere in square meters, see
+// "Some Algorithms for Polygons on a Sphere" by Chamberlain and Duquette
+func Area(ring [][]float64) float64 {
+	var sum float64
+	for n := 0; n+1 < len(ring); n++ {
+		a, b := ring[n], ring[n+1]
+		sum += radians(wrap(b[0]-a[0])) * (2 + math.Sin(radians(a[1])) + math.Sin(radians(b[1])))
+	}
+	return math.Abs(sum * EarthRadius * EarthRadius / 2)
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+// wrap brings longitude difference into -180..180, so edges crossing the antimeridian are short
+func wrap(d float64) float64 {
+	switch {
+	case d > 180:
+		return d - 360
+	case d < -180:
+		return d + 360
+	}
+	return d
+}
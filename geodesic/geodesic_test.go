@@ -0,0 +1,55 @@
+package geodesic
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistance(t *testing.T) {
+	// Flinders Peak to Buninyong, the example of Vincenty's paper
+	assert.InDelta(t, 54972.271, Distance(Point{-37.95103342, 144.42486789}, Point{-37.65282114, 143.92649554}), 0.01)
+	assert.Zero(t, Distance(Point{42.87, 74.59}, Point{42.87, 74.59}))
+	// across the antimeridian is the short way
+	assert.InDelta(t, 22264, Distance(Point{0, 179.9}, Point{0, -179.9}), 1)
+	// antipodal points fall back to great circle
+	assert.InDelta(t, math.Pi*EarthRadius, Distance(Point{0, 0}, Point{0, 180}), 50000)
+}
+
+func TestBearing(t *testing.T) {
+	assert.InDelta(t, 0, Bearing(Point{42, 74}, Point{43, 74}), 1e-9)
+	assert.InDelta(t, 90, Bearing(Point{0, 74}, Point{0, 75}), 1e-9)
+	assert.InDelta(t, 180, Bearing(Point{43, 74}, Point{42, 74}), 1e-9)
+	// across the antimeridian heads west
+	assert.InDelta(t, 270, Bearing(Point{0, -179.9}, Point{0, 179.9}), 1e-9)
+	assert.Zero(t, Bearing(Point{42.87, 74.59}, Point{42.87, 74.59}))
+}
+
+func TestArea(t *testing.T) {
+	square := [][]float64{{74, 42}, {74.01, 42}, {74.01, 42.01}, {74, 42.01}, {74, 42}}
+	side := 0.01 * EarthRadius * math.Pi / 180
+	assert.InEpsilon(t, side*side*math.Cos(radians(42.005)), Area(square), 0.001)
+	crossing := [][]float64{{179.995, 0}, {-179.995, 0}, {-179.995, 0.01}, {179.995, 0.01}, {179.995, 0}}
+	assert.InEpsilon(t, side*side, Area(crossing), 0.001)
+}
+
+func TestPolygonContains(t *testing.T) {
+	square := NewPolygon([]Point{{0, 0}, {0, 2}, {2, 2}, {2, 0}})
+	assert.True(t, square.Contains(Point{1, 1}))
+	assert.False(t, square.Contains(Point{3, 1}))
+	assert.False(t, NewPolygon([]Point{{0, 0}, {1, 1}}).Contains(Point{0.5, 0.5}))
+
+	// Chukotka-like ring crossing the antimeridian
+	crossing := NewPolygon([]Point{{64, 170}, {64, -170}, {68, -170}, {68, 170}})
+	assert.True(t, crossing.Contains(Point{66, 179}))
+	assert.True(t, crossing.Contains(Point{66, -175}))
+	assert.False(t, crossing.Contains(Point{66, 0}))
+	assert.False(t, crossing.Contains(Point{66, 160}))
+
+	// Antarctica-like ring going around the south pole
+	antarctica := NewPolygon([]Point{{-70, -180}, {-70, -90}, {-70, 0}, {-70, 90}, {-70, 180}})
+	assert.True(t, antarctica.Contains(Point{-80, 45}))
+	assert.True(t, antarctica.Contains(Point{-89.9, -120}))
+	assert.False(t, antarctica.Contains(Point{-60, 45}))
+}
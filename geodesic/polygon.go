@@ -0,0 +1,83 @@
+package geodesic
+
+import "math"
+
+// Polygon is ring prepared for point in polygon tests. Longitudes of the ring are unwrapped,
+// so edges crossing the antimeridian stay short, and bounding box rejects far points without
+// walking the ring. Ring going around a pole is closed through the pole nearer to it.
+// Containment is planar: edges are straight lines in longitude, latitude rather than great
+// circle arcs, as they are drawn in OSM. Points within meters of long edges far from the
+// equator may be classified differently than by spherical test
+type Polygon struct {
+	points []Point
+	// ring is closed ring of unwrapped longitude, latitude pairs
+	ring [][2]float64
+	// bbox is min lat, min lon, max lat, max lon of ring
+	bbox [4]float64
+}
+
+// NewPolygon prepares ring of points, the last point forms edge with the first one.
+// Rings of less than three points contain nothing
+func NewPolygon(points []Point) *Polygon {
+	p := &Polygon{points: points, bbox: [4]float64{90, math.Inf(1), -90, math.Inf(-1)}}
+	if len(points) < 3 {
+		return p
+	}
+	lon := points[0].Lon
+	var sumLat float64
+	for n, point := range points {
+		if n > 0 {
+			lon += wrap(point.Lon - points[n-1].Lon)
+		}
+		p.ring = append(p.ring, [2]float64{lon, point.Lat})
+		sumLat += point.Lat
+	}
+	if winding := lon + wrap(points[0].Lon-points[len(points)-1].Lon) - points[0].Lon; math.Abs(winding) > 180 {
+		// ring goes around pole, e.g. Antarctica drawn along the antimeridian
+		pole := 90.0
+		if sumLat < 0 {
+			pole = -90
+		}
+		p.ring = append(p.ring, [2]float64{lon, pole}, [2]float64{points[0].Lon, pole})
+	}
+	for _, c := range p.ring {
+		p.bbox[0], p.bbox[1] = math.Min(p.bbox[0], c[1]), math.Min(p.bbox[1], c[0])
+		p.bbox[2], p.bbox[3] = math.Max(p.bbox[2], c[1]), math.Max(p.bbox[3], c[0])
+	}
+	return p
+}
+
+// Points returns points polygon was created of
+func (p *Polygon) Points() []Point {
+	return p.points
+}
+
+// Bounds returns min lat, min lon, max lat, max lon of polygon. Longitudes of polygon crossing
+// the antimeridian are out of -180..180 range
+func (p *Polygon) Bounds() [4]float64 {
+	return p.bbox
+}
+
+// Contains reports whether point is inside of polygon
+func (p *Polygon) Contains(point Point) bool {
+	if len(p.ring) == 0 || point.Lat < p.bbox[0] || point.Lat > p.bbox[2] {
+		return false
+	}
+	for _, lon := range []float64{point.Lon, point.Lon + 360, point.Lon - 360} {
+		if lon >= p.bbox[1] && lon <= p.bbox[3] && p.rayCast(lon, point.Lat) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Polygon) rayCast(x, y float64) bool {
+	inside := false
+	for n, m := 0, len(p.ring)-1; n < len(p.ring); m, n = n, n+1 {
+		a, b := p.ring[n], p.ring[m]
+		if (a[1] > y) != (b[1] > y) && x < (b[0]-a[0])*(y-a[1])/(b[1]-a[1])+a[0] {
+			inside = !inside
+		}
+	}
+	return inside
+}
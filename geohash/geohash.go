@@ -0,0 +1,87 @@
+// Package geohash encodes and decodes geohash strings
+package geohash
+
+import (
+	"errors"
+	"strings"
+)
+
+const alphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// ErrInvalid is returned for strings containing characters outside of geohash alphabet
+var ErrInvalid = errors.New("invalid geohash")
+
+// Encode returns geohash of point with given number of characters
+func Encode(lat, lon float64, precision int) string {
+	latRange := [2]float64{-90, 90}
+	lonRange := [2]float64{-180, 180}
+	var (
+		hash    strings.Builder
+		bit     uint
+		ch      int
+		evenBit = true
+	)
+	for hash.Len() < precision {
+		if evenBit {
+			mid := (lonRange[0] + lonRange[1]) / 2
+			if lon >= mid {
+				ch |= 1 << (4 - bit)
+				lonRange[0] = mid
+			} else {
+				lonRange[1] = mid
+			}
+		} else {
+			mid := (latRange[0] + latRange[1]) / 2
+			if lat >= mid {
+				ch |= 1 << (4 - bit)
+				latRange[0] = mid
+			} else {
+				latRange[1] = mid
+			}
+		}
+		evenBit = !evenBit
+		if bit < 4 {
+			bit++
+			continue
+		}
+		hash.WriteByte(alphabet[ch])
+		bit, ch = 0, 0
+	}
+	return hash.String()
+}
+
+// Decode returns center of geohash cell
+func Decode(hash string) (float64, float64, error) {
+	latRange := [2]float64{-90, 90}
+	lonRange := [2]float64{-180, 180}
+	evenBit := true
+	if hash == "" {
+		return 0, 0, ErrInvalid
+	}
+	for _, c := range strings.ToLower(hash) {
+		idx := strings.IndexRune(alphabet, c)
+		if idx < 0 {
+			return 0, 0, ErrInvalid
+		}
+		for n := 4; n >= 0; n-- {
+			bit := (idx >> uint(n)) & 1
+			if evenBit {
+				mid := (lonRange[0] + lonRange[1]) / 2
+				if bit == 1 {
+					lonRange[0] = mid
+				} else {
+					lonRange[1] = mid
+				}
+			} else {
+				mid := (latRange[0] + latRange[1]) / 2
+				if bit == 1 {
+					latRange[0] = mid
+				} else {
+					latRange[1] = mid
+				}
+			}
+			evenBit = !evenBit
+		}
+	}
+	return (latRange[0] + latRange[1]) / 2, (lonRange[0] + lonRange[1]) / 2, nil
+}
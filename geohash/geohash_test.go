@@ -0,0 +1,23 @@
+package geohash
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeohash(t *testing.T) {
+	assert.Equal(t, "ezs42", Encode(42.6, -5.6, 5))
+	lat, lon, err := Decode("ezs42")
+	require.NoError(t, err)
+	assert.InDelta(t, 42.605, lat, 0.01)
+	assert.InDelta(t, -5.603, lon, 0.01)
+	hash := Encode(42.874621, 74.569762, 9)
+	lat, lon, err = Decode(hash)
+	require.NoError(t, err)
+	assert.InDelta(t, 42.874621, lat, 0.0001)
+	assert.InDelta(t, 74.569762, lon, 0.0001)
+	_, _, err = Decode("abc")
+	assert.Equal(t, ErrInvalid, err)
+}
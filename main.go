@@ -1,31 +1,430 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
 	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/dataset"
+	"github.com/maddevsio/ariadna/diff"
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/evaluate"
+	"github.com/maddevsio/ariadna/memory"
+	"github.com/maddevsio/ariadna/offline"
 	"github.com/maddevsio/ariadna/osm"
 )
 
 func main() {
-
-	c, err := config.Get()
+	profile, args := splitProfile(os.Args[1:])
+	c, err := config.GetProfile(profile)
 	if err != nil {
 		log.Fatal(err)
 	}
-	i, err := osm.NewImporter(c)
+	command := ""
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+	if command == "evaluate" {
+		if err := runEvaluate(c, args); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if command == "stats" {
+		if err := runStats(interruptContext(), c, args); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if command == "snapshot" {
+		if err := runSnapshot(interruptContext(), c, args); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if command == "export" {
+		if err := runExport(interruptContext(), c, args); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if command == "history" {
+		if err := runHistory(interruptContext(), c, args); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if command == "diff" {
+		if err := runDiff(interruptContext(), c, args); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	ctx := interruptContext()
+	if command == "import" {
+		path, err := applyImportFlags(c, args)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if path != "" {
+			if err := loadDataset(ctx, c, path); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+	}
+	i, err := osm.NewImporter(ctx, c)
 	if err != nil {
 		log.Fatal(err)
 	}
-	if len(os.Args) > 1 && os.Args[1] == "web" {
-		i.StartWebServer()
+	i.WatchDiagnostics(ctx)
+	if command == "web" {
+		if err := i.StartWebServer(); err != nil {
+			log.Fatal(err)
+		}
 	}
-	if err := i.Start(); err != nil {
+	err = runImport(ctx, i)
+	// interrupted import is recorded too, so audit is written without ctx
+	i.WriteAudit(context.Background(), err)
+	if err != nil {
 		log.Fatal(err)
 	}
-	i.WaitStop()
-	if err := i.Done(); err != nil {
-		log.Fatal(err)
+}
+
+// runImport indexes documents of importer and switches the alias to them
+func runImport(ctx context.Context, i *osm.Importer) error {
+	if err := i.Start(ctx); err != nil {
+		return err
+	}
+	if err := i.WaitStop(); err != nil {
+		return err
+	}
+	return i.Done(ctx)
+}
+
+// splitProfile takes --profile=name or --profile name out of command line arguments,
+// so it can be given before or after command
+func splitProfile(args []string) (string, []string) {
+	var (
+		profile string
+		rest    = make([]string, 0, len(args))
+	)
+	for n := 0; n < len(args); n++ {
+		switch arg := args[n]; {
+		case arg == "--profile" || arg == "-profile":
+			if n+1 < len(args) {
+				profile = args[n+1]
+				n++
+			}
+		case strings.HasPrefix(arg, "--profile=") || strings.HasPrefix(arg, "-profile="):
+			profile = arg[strings.Index(arg, "=")+1:]
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return profile, rest
+}
+
+// applyImportFlags configures import by command line, --country selects built-in country preset,
+// --resume continues unfinished import and --layers rebuilds comma separated layers in the current
+// index, --max-memory bounds memory of import, e.g. 4GiB. Path of dataset given by --dataset is
+// returned, it is loaded instead of parsing extract
+func applyImportFlags(c *config.Ariadna, args []string) (string, error) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	country := flags.String("country", "", "ISO code of country preset: "+strings.Join(config.PresetCodes(), ", "))
+	path := flags.String("dataset", "", "dataset saved by export --format=dataset to index instead of extract")
+	resume := flags.Bool("resume", c.Resume, "continue in index of the latest unfinished import")
+	layers := flags.String("layers", strings.Join(c.ImportLayers, ","), "comma separated layers to rebuild in the current index, e.g. pois,addresses")
+	maxMemory := flags.String("max-memory", "", "memory budget of import, e.g. 4GiB, nodes are spilled to disk near it")
+	if err := flags.Parse(args); err != nil {
+		return "", err
+	}
+	if *maxMemory != "" {
+		budget, err := memory.ParseSize(*maxMemory)
+		if err != nil {
+			return "", fmt.Errorf("--max-memory: %w", err)
+		}
+		c.Memory.Max = budget
+	}
+	c.Resume = *resume
+	c.ImportLayers = nil
+	for _, layer := range strings.Split(*layers, ",") {
+		if layer = strings.TrimSpace(layer); layer != "" {
+			c.ImportLayers = append(c.ImportLayers, layer)
+		}
+	}
+	if *country == "" {
+		return *path, nil
+	}
+	preset, ok := config.PresetByCode(*country)
+	if !ok {
+		return "", fmt.Errorf("no preset for country %q, available: %s", *country, strings.Join(config.PresetCodes(), ", "))
+	}
+	c.ApplyPreset(preset)
+	return *path, nil
+}
+
+// loadDataset indexes documents of saved dataset into elasticsearch
+func loadDataset(ctx context.Context, c *config.Ariadna, path string) error {
+	e, err := elastic.New(c)
+	if err != nil {
+		return err
+	}
+	count, err := dataset.Load(ctx, path, e)
+	if err != nil {
+		return err
+	}
+	log.Printf("%d documents of %s indexed", count, path)
+	return nil
+}
+
+// interruptContext returns context cancelled on SIGINT or SIGTERM, so import stops cleanly
+func interruptContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+	}()
+	return ctx
+}
+
+// runDiff reports streets, POIs and admin areas which changed between two imports
+func runDiff(ctx context.Context, c *config.Ariadna, args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	oldIndex := flags.String("old", "", "index of previous import")
+	newIndex := flags.String("new", "", "index of next import")
+	output := flags.String("output", "", "file to save report to")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *oldIndex == "" || *newIndex == "" {
+		return fmt.Errorf("both --old and --new indices are required")
+	}
+	e, err := elastic.New(c)
+	if err != nil {
+		return err
+	}
+	report, err := diff.Run(ctx, e, *oldIndex, *newIndex)
+	if err != nil {
+		return err
+	}
+	report.Print(os.Stdout)
+	if *output == "" {
+		return nil
+	}
+	out, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	return json.NewEncoder(out).Encode(report)
+}
+
+// runHistory prints audit of the latest import runs with timings of their stages
+func runHistory(ctx context.Context, c *config.Ariadna, args []string) error {
+	flags := flag.NewFlagSet("history", flag.ExitOnError)
+	limit := flags.Int("limit", 10, "number of the latest import runs to print")
+	asJSON := flags.Bool("json", false, "print entries as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	e, err := elastic.New(c)
+	if err != nil {
+		return err
+	}
+	audits, err := e.Audits(ctx, *limit)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(audits)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, a := range audits {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d documents\t%s\t%s\n", a.ID, a.Status, a.FinishedAt.Sub(a.StartedAt).Round(time.Second), a.Documents, a.Index, a.Source)
+		for _, s := range a.Stages {
+			fmt.Fprintf(w, "  %s\t%s\t%v\t%d documents\t%s\t\n", s.Name, s.StartedAt.Format(time.RFC3339), time.Duration(s.DurationMS)*time.Millisecond, s.Documents, s.Error)
+		}
+		for _, message := range a.Errors {
+			fmt.Fprintf(w, "  error\t%s\t\t\t\t\n", message)
+		}
+	}
+	return w.Flush()
+}
+
+// runExport imports configured extract into offline database or dataset file instead of elasticsearch
+func runExport(ctx context.Context, c *config.Ariadna, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", "sqlite", "export format: sqlite or dataset")
+	output := flags.String("output", "ariadna.sqlite", "file to export to")
+	country := flags.String("country", "", "ISO code of country preset: "+strings.Join(config.PresetCodes(), ", "))
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *country != "" {
+		if _, err := applyImportFlags(c, []string{"--country", *country}); err != nil {
+			return err
+		}
+	}
+	var db interface {
+		osm.Storage
+		Close() error
+	}
+	switch *format {
+	case "sqlite":
+		d, err := offline.Create(*output)
+		if err != nil {
+			return err
+		}
+		db = d
+	case "dataset":
+		w, err := dataset.Create(*output)
+		if err != nil {
+			return err
+		}
+		db = w
+	default:
+		return fmt.Errorf("unsupported export format %q", *format)
+	}
+	defer db.Close()
+	i, err := osm.NewImporter(ctx, c, osm.WithStorage(db))
+	if err != nil {
+		return err
+	}
+	i.WatchDiagnostics(ctx)
+	if err := runImport(ctx, i); err != nil {
+		return err
+	}
+	return db.Close()
+}
+
+// runStats prints document counts per layer and category, index size and the latest import
+func runStats(ctx context.Context, c *config.Ariadna, args []string) error {
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	database := flags.String("database", "", "offline SQLite database to describe instead of elasticsearch index")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	var stats *elastic.Stats
+	if *database != "" {
+		db, err := offline.Open(*database)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		if stats, err = db.Stats(ctx); err != nil {
+			return err
+		}
+	} else {
+		e, err := elastic.New(c)
+		if err != nil {
+			return err
+		}
+		if stats, err = e.Stats(ctx); err != nil {
+			return err
+		}
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(stats)
+}
+
+// runSnapshot copies index to or from configured snapshot repository:
+// snapshot create [name], snapshot restore <name> or snapshot list
+func runSnapshot(ctx context.Context, c *config.Ariadna, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: snapshot create [name] | restore <name> | list")
+	}
+	e, err := elastic.New(c)
+	if err != nil {
+		return err
+	}
+	var result interface{}
+	switch args[0] {
+	case "create":
+		name := ""
+		if len(args) > 1 {
+			name = args[1]
+		}
+		result, err = e.CreateSnapshot(ctx, name)
+	case "restore":
+		if len(args) < 2 {
+			return fmt.Errorf("snapshot name is required")
+		}
+		result, err = e.RestoreSnapshot(ctx, args[1])
+	case "list":
+		result, err = e.Snapshots(ctx)
+	default:
+		return fmt.Errorf("unknown snapshot command %q", args[0])
+	}
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+// runEvaluate runs ground-truth test set through the live index
+func runEvaluate(c *config.Ariadna, args []string) error {
+	flags := flag.NewFlagSet("evaluate", flag.ExitOnError)
+	testset := flags.String("testset", "", "CSV file with query,lat,lon,id rows")
+	radius := flags.Float64("radius", 100, "max distance in meters for result to match expected point")
+	previous := flags.String("previous", "", "report of previous run to find regressions")
+	output := flags.String("output", "", "file to save report to")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	f, err := os.Open(*testset)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	cases, err := evaluate.ReadCases(f)
+	if err != nil {
+		return err
+	}
+	e, err := elastic.New(c)
+	if err != nil {
+		return err
+	}
+	report, err := evaluate.Run(context.Background(), e, cases, *radius)
+	if err != nil {
+		return err
+	}
+	if *previous != "" {
+		p, err := os.Open(*previous)
+		if err != nil {
+			return err
+		}
+		defer p.Close()
+		prev, err := evaluate.Load(p)
+		if err != nil {
+			return err
+		}
+		report.Compare(prev)
+	}
+	report.Print(os.Stdout)
+	if *output == "" {
+		return nil
+	}
+	out, err := os.Create(*output)
+	if err != nil {
+		return err
 	}
+	defer out.Close()
+	return json.NewEncoder(out).Encode(report)
 }
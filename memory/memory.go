@@ -0,0 +1,96 @@
+// Package memory keeps import within memory budget: it parses sizes like 4GiB, sets soft limit
+// of the runtime and reports when memory of the process approaches the budget
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// pressureRatio is share of budget above which memory is under pressure
+	pressureRatio = 0.8
+	// sampleInterval bounds how often memory statistics are read, reading them stops the world
+	sampleInterval = 100 * time.Millisecond
+)
+
+// ErrInvalidSize is returned for sizes which are neither bytes nor number with unit
+var ErrInvalidSize = errors.New("invalid size")
+
+var units = []struct {
+	suffix string
+	bytes  int64
+}{
+	// longer suffixes first, so GiB isn't read as B
+	{"kib", 1 << 10}, {"mib", 1 << 20}, {"gib", 1 << 30}, {"tib", 1 << 40},
+	{"kb", 1e3}, {"mb", 1e6}, {"gb", 1e9}, {"tb", 1e12},
+	{"k", 1 << 10}, {"m", 1 << 20}, {"g", 1 << 30}, {"t", 1 << 40},
+	{"b", 1},
+}
+
+var sample struct {
+	mu   sync.Mutex
+	used int64
+	at   time.Time
+}
+
+// ParseSize parses size in bytes with optional unit, e.g. 512MB, 4GiB or 4g. Sizes must be
+// finite and fit in int64
+func ParseSize(s string) (int64, error) {
+	value := strings.ToLower(strings.TrimSpace(s))
+	multiplier := int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(value, u.suffix) {
+			value, multiplier = strings.TrimSpace(strings.TrimSuffix(value, u.suffix)), u.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	size := n * float64(multiplier)
+	if err != nil || math.IsNaN(size) || size < 0 || size >= math.MaxInt64 {
+		return 0, fmt.Errorf("%w %q, expected bytes or number with unit like 4GiB", ErrInvalidSize, s)
+	}
+	return int64(size), nil
+}
+
+// Limit sets soft memory limit of the runtime to budget, so garbage is collected more often as it
+// is approached. Zero budget is unlimited
+func Limit(budget int64) {
+	if budget > 0 {
+		debug.SetMemoryLimit(budget)
+	}
+}
+
+// Used returns memory of the process obtained from the system and not returned to it yet
+func Used() int64 {
+	sample.mu.Lock()
+	defer sample.mu.Unlock()
+	if time.Since(sample.at) < sampleInterval {
+		return sample.used
+	}
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	sample.used = int64(m.Sys - m.HeapReleased)
+	sample.at = time.Now()
+	return sample.used
+}
+
+// Pressure reports whether memory of the process is above 80% of budget. Zero budget is unlimited
+func Pressure(budget int64) bool {
+	return budget > 0 && float64(Used()) > float64(budget)*pressureRatio
+}
+
+// Release returns freed memory to the system, it is called after large caches are dropped
+func Release() {
+	debug.FreeOSMemory()
+	sample.mu.Lock()
+	sample.at = time.Time{}
+	sample.mu.Unlock()
+}
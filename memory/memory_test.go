@@ -0,0 +1,34 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSize(t *testing.T) {
+	for s, expected := range map[string]int64{
+		"1024":    1024,
+		"512MB":   512e6,
+		"512 MiB": 512 << 20,
+		"4GiB":    4 << 30,
+		"4g":      4 << 30,
+		"1.5k":    1536,
+		"10b":     10,
+	} {
+		size, err := ParseSize(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, expected, size, s)
+	}
+	for _, s := range []string{"", "GiB", "-1GiB", "4 apples", "inf", "+Inf", "infGiB", "NaN", "1e30", "9e18k"} {
+		_, err := ParseSize(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestPressure(t *testing.T) {
+	assert.False(t, Pressure(0))
+	assert.True(t, Pressure(1))
+	assert.False(t, Pressure(Used()*100))
+}
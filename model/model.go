@@ -1,19 +1,104 @@
 package model
 
+import geojson "github.com/paulmach/go.geojson"
+
 type Address struct {
-	Country      string   `json:"country"`
-	City         string   `json:"city"`
-	Village      string   `json:"village"`
-	Town         string   `json:"town"`
-	District     string   `json:"district"`
-	Prefix       string   `json:"prefix"`
-	Street       string   `json:"street"`
-	HouseNumber  string   `json:"housenumber"`
-	Name         string   `json:"name"`
-	Intersection bool     `json:"intersection"`
-	Location     Location `json:"location"`
+	ID           string            `json:"id,omitempty"`
+	Country      string            `json:"country"`
+	City         string            `json:"city"`
+	Village      string            `json:"village"`
+	Town         string            `json:"town"`
+	District     string            `json:"district"`
+	Prefix       string            `json:"prefix"`
+	Street       string            `json:"street"`
+	HouseNumber  string            `json:"housenumber"`
+	Unit         string            `json:"unit,omitempty"`
+	Flats        string            `json:"flats,omitempty"`
+	Door         string            `json:"door,omitempty"`
+	Name         string            `json:"name"`
+	Names        map[string]string `json:"names,omitempty"`
+	Intersection bool              `json:"intersection"`
+	Location     Location          `json:"location"`
+	Entrances    []Entrance        `json:"entrances,omitempty"`
+	Layer        string            `json:"layer,omitempty"`
+	Category     string            `json:"category,omitempty"`
+	Routes       []string          `json:"routes,omitempty"`
+	WikidataID   string            `json:"wikidata_id,omitempty"`
+	Wikipedia    string            `json:"wikipedia,omitempty"`
+	Wikidata     *Wikidata         `json:"wikidata,omitempty"`
+	Elevation    *float64          `json:"elevation,omitempty"`
+	Timezone     string            `json:"timezone,omitempty"`
+	PlusCode     string            `json:"plus_code,omitempty"`
+	Geohash      string            `json:"geohash,omitempty"`
+	Geometry     *geojson.Geometry `json:"geometry,omitempty"`
+	Fields       Fields            `json:"fields,omitempty"`
+	Source       string            `json:"source,omitempty"`
+	Road         *Road             `json:"road,omitempty"`
+	Vertical     *Vertical         `json:"vertical,omitempty"`
+	AdminLevel   int               `json:"admin_level,omitempty"`
+	Area         float64           `json:"area,omitempty"`
+	Centroid     *Location         `json:"centroid,omitempty"`
+	LabelPoint   *Location         `json:"label_point,omitempty"`
+	Approximate  bool              `json:"approximate,omitempty"`
+	// Language of Name picked from Names by requested languages, empty for the default name
+	Language string `json:"language,omitempty"`
+	// LabelRomanized is name or street address in Latin script, set on request
+	LabelRomanized string `json:"label_romanized,omitempty"`
+	// Viewport is suggested map extent to show the feature
+	Viewport *BBox `json:"viewport,omitempty"`
+	// MatchType tells how result was found when it is not nearest feature, e.g. fallback_place
+	MatchType string `json:"match_type,omitempty"`
+
+	// normalized names are matched against normalized query, see package normalize
+	NameNormalized   string `json:"name_normalized,omitempty"`
+	StreetNormalized string `json:"street_normalized,omitempty"`
+}
+
+// Road holds attributes of road segment. MaxSpeed is in km/h, zero when unknown.
+// Oneway is forward or backward relative to way direction, empty for two-way roads
+type Road struct {
+	MaxSpeed int    `json:"maxspeed,omitempty"`
+	Surface  string `json:"surface,omitempty"`
+	Lanes    int    `json:"lanes,omitempty"`
+	Oneway   string `json:"oneway,omitempty"`
 }
+
+// Vertical places feature among stacked ones: Layer is OSM layer tag, negative under ground,
+// Levels are floors of building or station the feature is on
+type Vertical struct {
+	Layer  int       `json:"layer,omitempty"`
+	Bridge bool      `json:"bridge,omitempty"`
+	Tunnel bool      `json:"tunnel,omitempty"`
+	Levels []float64 `json:"levels,omitempty"`
+}
+
+// BBox is bounding box in WGS84 degrees
+type BBox struct {
+	MinLat float64 `json:"min_lat"`
+	MinLon float64 `json:"min_lon"`
+	MaxLat float64 `json:"max_lat"`
+	MaxLon float64 `json:"max_lon"`
+}
+
+// Fields holds values extracted from tags by configured rules
+type Fields map[string]interface{}
 type Location struct {
 	Lat float64 `json:"lat"`
 	Lon float64 `json:"lon"`
 }
+type Entrance struct {
+	Type     string   `json:"type"`
+	Ref      string   `json:"ref,omitempty"`
+	Location Location `json:"location"`
+}
+type Wikidata struct {
+	Labels     map[string]string `json:"labels,omitempty"`
+	Population int64             `json:"population,omitempty"`
+	Sitelinks  int               `json:"sitelinks,omitempty"`
+}
+
+// Facet is number of found documents having value of field
+type Facet struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
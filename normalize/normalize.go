@@ -0,0 +1,192 @@
+// Package normalize folds names into form compared at search time: NFKC, lowercase, no
+// diacritics and numbers instead of ordinal words, ordinal suffixes and roman numerals,
+// so "1-May Street" and "First of May Street" both become "1 may street"
+package normalize
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// combiningBreve is kept after Cyrillic letters, it tells й from и and ў from у
+const combiningBreve = '̆'
+
+var (
+	// stopWords are dropped, they differ between spellings of the same name
+	stopWords = map[string]bool{"of": true, "the": true}
+	// ordinalSuffixes follow numbers after hyphen like 1-й, 2-я, 3-го or without it like 4th
+	ordinalSuffixes = map[string]bool{
+		"st": true, "nd": true, "rd": true, "th": true,
+		"й": true, "я": true, "е": true, "ю": true, "го": true, "му": true, "м": true, "х": true,
+		"ая": true, "ое": true, "ой": true, "ый": true, "ий": true, "ого": true, "его": true, "ому": true,
+	}
+	ordinalWords = map[string]int{
+		"first": 1, "second": 2, "third": 3, "fourth": 4, "fifth": 5, "sixth": 6, "seventh": 7,
+		"eighth": 8, "ninth": 9, "tenth": 10, "eleventh": 11, "twelfth": 12, "thirteenth": 13,
+		"fourteenth": 14, "fifteenth": 15, "sixteenth": 16, "seventeenth": 17, "eighteenth": 18,
+		"nineteenth": 19, "twentieth": 20, "thirtieth": 30, "fortieth": 40, "fiftieth": 50,
+		"sixtieth": 60, "seventieth": 70, "eightieth": 80, "ninetieth": 90, "hundredth": 100,
+	}
+	// tensWords combine with following ordinal: twenty first, двадцать первый
+	tensWords = map[string]int{
+		"twenty": 20, "thirty": 30, "forty": 40, "fifty": 50, "sixty": 60, "seventy": 70, "eighty": 80, "ninety": 90,
+		"двадцать": 20, "тридцать": 30, "сорок": 40, "пятьдесят": 50, "шестьдесят": 60, "семьдесят": 70,
+		"восемьдесят": 80, "девяносто": 90,
+	}
+	// russianOrdinals are stems of ordinal adjectives declined with hardEndings
+	russianOrdinals = map[string]int{
+		"перв": 1, "втор": 2, "четверт": 4, "пят": 5, "шест": 6, "седьм": 7, "восьм": 8, "девят": 9,
+		"десят": 10, "одиннадцат": 11, "двенадцат": 12, "тринадцат": 13, "четырнадцат": 14,
+		"пятнадцат": 15, "шестнадцат": 16, "семнадцат": 17, "восемнадцат": 18, "девятнадцат": 19,
+		"двадцат": 20, "тридцат": 30, "сороков": 40, "пятидесят": 50, "шестидесят": 60,
+		"семидесят": 70, "восьмидесят": 80, "девяност": 90, "сот": 100,
+	}
+	hardEndings = []string{"ыми", "ого", "ому", "ый", "ой", "ая", "ое", "ом", "ую", "ые", "ых", "ым", "ою"}
+	// softEndings decline третий
+	softEndings = []string{"ьего", "ьему", "ьими", "ьей", "ьем", "ьих", "ьим", "ий", "ья", "ье", "ью", "ьи"}
+	romanValues = map[rune]int{'I': 1, 'V': 5, 'X': 10}
+)
+
+// Name returns normalized form of name, empty for names without letters and digits
+func Name(s string) string {
+	tokens := tokenize(fold(norm.NFKC.String(s)))
+	result := make([]string, 0, len(tokens))
+	for n := 0; n < len(tokens); n++ {
+		raw := tokens[n]
+		token := strings.ToLower(raw)
+		if stopWords[token] {
+			continue
+		}
+		if value, ok := roman(raw); ok && (len(raw) > 1 || len(result) > 0) {
+			token = strconv.Itoa(value)
+		} else if value, ok := ordinal(token); ok {
+			token = strconv.Itoa(value)
+		} else if tens, ok := tensWords[token]; ok && n+1 < len(tokens) {
+			if unit, ok := ordinal(strings.ToLower(tokens[n+1])); ok && unit < 10 {
+				token = strconv.Itoa(tens + unit)
+				n++
+			}
+		}
+		if isNumber(token) && n+1 < len(tokens) && ordinalSuffixes[strings.ToLower(tokens[n+1])] {
+			// suffix of 1-й and 2-я is split off by tokenize
+			n++
+		}
+		result = append(result, token)
+	}
+	return strings.Join(result, " ")
+}
+
+// fold drops diacritics except breve of Cyrillic letters
+func fold(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	var prev rune
+	for _, r := range norm.NFD.String(s) {
+		if unicode.Is(unicode.Mn, r) && !(r == combiningBreve && unicode.Is(unicode.Cyrillic, prev)) {
+			continue
+		}
+		b.WriteRune(r)
+		prev = r
+	}
+	return norm.NFC.String(b.String())
+}
+
+// tokenize splits s into runs of letters and runs of digits, digits followed by
+// letters like 4th stay single token
+func tokenize(s string) []string {
+	var (
+		tokens []string
+		start  = -1
+	)
+	for n, r := range s {
+		word := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case word && start < 0:
+			start = n
+		case !word && start >= 0:
+			tokens = append(tokens, s[start:n])
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, s[start:])
+	}
+	return tokens
+}
+
+// ordinal converts lowercase ordinal word or number with suffix like 4th to number
+func ordinal(token string) (int, bool) {
+	if value, ok := ordinalWords[token]; ok {
+		return value, true
+	}
+	if digits := strings.TrimRightFunc(token, unicode.IsLetter); digits != token && isNumber(digits) {
+		if ordinalSuffixes[token[len(digits):]] {
+			value, err := strconv.Atoi(digits)
+			return value, err == nil
+		}
+	}
+	for _, ending := range hardEndings {
+		if value, ok := russianOrdinals[strings.TrimSuffix(token, ending)]; ok && strings.HasSuffix(token, ending) {
+			return value, true
+		}
+	}
+	for _, ending := range softEndings {
+		if strings.TrimSuffix(token, ending) == "трет" && strings.HasSuffix(token, ending) {
+			return 3, true
+		}
+	}
+	return 0, false
+}
+
+// roman converts uppercase roman numeral up to XXXIX to number
+func roman(token string) (int, bool) {
+	if token == "" || len(token) > 6 {
+		return 0, false
+	}
+	total, prev := 0, 0
+	for n := len(token) - 1; n >= 0; n-- {
+		value, ok := romanValues[rune(token[n])]
+		if !ok {
+			return 0, false
+		}
+		if value < prev {
+			total -= value
+		} else {
+			total += value
+		}
+		if value > prev {
+			prev = value
+		}
+	}
+	// values are re-encoded to reject sequences like IIII or VX
+	return total, toRoman(total) == token
+}
+
+func toRoman(value int) string {
+	var b strings.Builder
+	for _, step := range []struct {
+		value   int
+		numeral string
+	}{{10, "X"}, {9, "IX"}, {5, "V"}, {4, "IV"}, {1, "I"}} {
+		for value >= step.value {
+			b.WriteString(step.numeral)
+			value -= step.value
+		}
+	}
+	return b.String()
+}
+
+func isNumber(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,33 @@
+package normalize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestName(t *testing.T) {
+	for _, c := range []struct {
+		name, normalized string
+	}{
+		{"1-May Street", "1 may street"},
+		{"First of May Street", "1 may street"},
+		{"улица 1-го Мая", "улица 1 мая"},
+		{"улица Первого Мая", "улица 1 мая"},
+		{"Twenty-First Avenue", "21 avenue"},
+		{"21st Avenue", "21 avenue"},
+		{"улица XXII Партсъезда", "улица 22 партсъезда"},
+		{"проспект Двадцать второго Партсъезда", "проспект 22 партсъезда"},
+		{"Третья линия", "3 линия"},
+		{"Пётр I", "петр 1"},
+		{"I Love Pizza", "i love pizza"},
+		{"Mix Café", "mix cafe"},
+		{"Ｃａｆé №１", "cafe no1"},
+		{"Чуйский проспект", "чуйский проспект"},
+		{"Пятница", "пятница"},
+		{"Ош-Базар", "ош базар"},
+		{"", ""},
+	} {
+		assert.Equal(t, c.normalized, Name(c.name), c.name)
+	}
+}
@@ -0,0 +1,554 @@
+// Package offline writes imported documents into a single SQLite file for offline geocoding
+// on mobile and embedded devices. Names are searchable with FTS5, bounding boxes of documents
+// are kept in R-tree, full documents are stored as JSON
+package offline
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/model"
+	_ "modernc.org/sqlite" // registers sqlite driver
+)
+
+const (
+	searchSize = 10
+	// reverseDistance matches reverse geocoding radius of elasticsearch storage
+	reverseDistance = "200m"
+	metersPerDegree = 111320.0
+)
+
+var schema = []string{
+	`CREATE TABLE metadata (name TEXT PRIMARY KEY, value TEXT)`,
+	`CREATE TABLE documents (
+		rowid INTEGER PRIMARY KEY,
+		id TEXT NOT NULL UNIQUE,
+		layer TEXT NOT NULL DEFAULT '',
+		lat REAL NOT NULL,
+		lon REAL NOT NULL,
+		data TEXT NOT NULL
+	)`,
+	`CREATE INDEX documents_layer ON documents (layer)`,
+	`CREATE VIRTUAL TABLE names USING fts5(name, street, housenumber, locality, country, tokenize = 'unicode61 remove_diacritics 2')`,
+	`CREATE VIRTUAL TABLE bounds USING rtree(rowid, min_lat, max_lat, min_lon, max_lon)`,
+}
+
+// Database is offline storage of documents, it implements storage of importer
+type Database struct {
+	db   *sql.DB
+	path string
+}
+
+// Create creates new database file at path, existing file is replaced
+func Create(path string) (*Database, error) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return Open(path)
+}
+
+// Open opens database file at path
+func Open(path string) (*Database, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// sqlite allows single writer, bulk writes of concurrent import stages are serialized
+	db.SetMaxOpenConns(1)
+	return &Database{db: db, path: path}, nil
+}
+
+// Close closes database file
+func (d *Database) Close() error {
+	return d.db.Close()
+}
+
+// UpdateIndex creates tables and records export metadata
+func (d *Database) UpdateIndex(ctx context.Context) error {
+	for _, stmt := range schema {
+		if _, err := d.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("create schema: %w", err)
+		}
+	}
+	metadata := map[string]string{
+		"format":  "ariadna",
+		"version": "1",
+		"created": time.Now().UTC().Format(time.RFC3339),
+	}
+	for name, value := range metadata {
+		if _, err := d.db.ExecContext(ctx, `INSERT INTO metadata (name, value) VALUES (?, ?)`, name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReindexLayers fails, offline database is always exported by full import
+func (d *Database) ReindexLayers(ctx context.Context, layers []string) error {
+	return errors.New("partial import into offline database is not supported")
+}
+
+// WriteImportInfo records import in metadata table
+func (d *Database) WriteImportInfo(ctx context.Context, info elastic.ImportInfo) error {
+	metadata := map[string]string{
+		"imported_at": info.ImportedAt.UTC().Format(time.RFC3339),
+		"source":      info.Source,
+	}
+	if info.ExtractTime != nil {
+		metadata["extract_time"] = info.ExtractTime.UTC().Format(time.RFC3339)
+	}
+	for name, value := range metadata {
+		if _, err := d.db.ExecContext(ctx, `INSERT OR REPLACE INTO metadata (name, value) VALUES (?, ?)`, name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stats counts documents per layer and category, reports file size and recorded import
+func (d *Database) Stats(ctx context.Context) (*elastic.Stats, error) {
+	rows, err := d.db.QueryContext(ctx, `SELECT layer, COALESCE(json_extract(data, '$.category'), ''), COUNT(*)
+		FROM documents GROUP BY 1, 2`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	stats := &elastic.Stats{Layers: make(map[string]elastic.LayerStats)}
+	for rows.Next() {
+		var (
+			layer, category string
+			count           int64
+		)
+		if err := rows.Scan(&layer, &category, &count); err != nil {
+			return nil, err
+		}
+		if layer == "" {
+			layer = elastic.AddressLayer
+		}
+		l := stats.Layers[layer]
+		l.Documents += count
+		if category != "" {
+			if l.Categories == nil {
+				l.Categories = make(map[string]int64)
+			}
+			l.Categories[category] = count
+		}
+		stats.Layers[layer] = l
+		stats.Documents += count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(d.path)
+	if err != nil {
+		return nil, err
+	}
+	stats.SizeBytes = info.Size()
+	stats.Import, err = d.importInfo(ctx)
+	return stats, err
+}
+
+func (d *Database) importInfo(ctx context.Context) (*elastic.ImportInfo, error) {
+	metadata := make(map[string]string)
+	rows, err := d.db.QueryContext(ctx, `SELECT name, value FROM metadata`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, err
+		}
+		metadata[name] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if metadata["imported_at"] == "" {
+		return nil, nil
+	}
+	importedAt, err := time.Parse(time.RFC3339, metadata["imported_at"])
+	if err != nil {
+		return nil, err
+	}
+	info := &elastic.ImportInfo{ImportedAt: importedAt, Source: metadata["source"]}
+	if s := metadata["extract_time"]; s != "" {
+		extractTime, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil, err
+		}
+		info.ExtractTime = &extractTime
+	}
+	return info, nil
+}
+
+// DeleteIndices optimizes full text index and compacts the file once import is done
+func (d *Database) DeleteIndices(ctx context.Context) error {
+	if _, err := d.db.ExecContext(ctx, `INSERT INTO names (names) VALUES ('optimize')`); err != nil {
+		return err
+	}
+	_, err := d.db.ExecContext(ctx, `VACUUM`)
+	return err
+}
+
+// IndexVersion returns creation time of database
+func (d *Database) IndexVersion(ctx context.Context) (string, error) {
+	var created string
+	err := d.db.QueryRowContext(ctx, `SELECT value FROM metadata WHERE name = 'created'`).Scan(&created)
+	return d.path + "@" + created, err
+}
+
+// BulkWrite stores documents of elasticsearch bulk request body
+func (d *Database) BulkWrite(ctx context.Context, buf bytes.Buffer) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	scanner := bufio.NewScanner(&buf)
+	scanner.Buffer(nil, buf.Len()+1)
+	for scanner.Scan() {
+		var meta map[string]struct {
+			ID string `json:"_id"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &meta); err != nil {
+			return err
+		}
+		if !scanner.Scan() {
+			return fmt.Errorf("bulk body: document expected after %s", scanner.Text())
+		}
+		var address model.Address
+		if err := json.Unmarshal(scanner.Bytes(), &address); err != nil {
+			return err
+		}
+		for _, action := range meta {
+			if err := insert(ctx, tx, action.ID, address, scanner.Bytes()); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insert(ctx context.Context, tx *sql.Tx, id string, address model.Address, data []byte) error {
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO documents (id, layer, lat, lon, data) VALUES (?, ?, ?, ?, ?) ON CONFLICT (id) DO NOTHING`,
+		id, address.Layer, address.Location.Lat, address.Location.Lon, string(data))
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+	rowid, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	locality := strings.Join(nonEmpty(address.City, address.Town, address.Village, address.District), " ")
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO names (rowid, name, street, housenumber, locality, country) VALUES (?, ?, ?, ?, ?, ?)`,
+		rowid, address.Name, strings.TrimSpace(address.Prefix+" "+address.Street), address.HouseNumber, locality, address.Country); err != nil {
+		return err
+	}
+	minLat, maxLat, minLon, maxLon := bbox(address)
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO bounds (rowid, min_lat, max_lat, min_lon, max_lon) VALUES (?, ?, ?, ?, ?)`,
+		rowid, minLat, maxLat, minLon, maxLon)
+	return err
+}
+
+// bbox returns bounding box of document geometry or its location
+func bbox(address model.Address) (minLat, maxLat, minLon, maxLon float64) {
+	minLat, maxLat = address.Location.Lat, address.Location.Lat
+	minLon, maxLon = address.Location.Lon, address.Location.Lon
+	if address.Geometry == nil {
+		return
+	}
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch c := v.(type) {
+		case []float64:
+			if len(c) < 2 {
+				return
+			}
+			minLon, maxLon = math.Min(minLon, c[0]), math.Max(maxLon, c[0])
+			minLat, maxLat = math.Min(minLat, c[1]), math.Max(maxLat, c[1])
+		case [][]float64:
+			for _, p := range c {
+				walk(p)
+			}
+		case [][][]float64:
+			for _, p := range c {
+				walk(p)
+			}
+		case [][][][]float64:
+			for _, p := range c {
+				walk(p)
+			}
+		}
+	}
+	g := address.Geometry
+	for _, coords := range []interface{}{g.Point, g.MultiPoint, g.LineString, g.MultiLineString, g.Polygon, g.MultiPolygon} {
+		walk(coords)
+	}
+	return
+}
+
+func nonEmpty(values ...string) []string {
+	result := values[:0]
+	for _, v := range values {
+		if v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// Search performs full text search of names, every query term has to match
+func (d *Database) Search(ctx context.Context, query string) (*elastic.Result, error) {
+	return d.SearchRanked(ctx, query, elastic.DefaultRanking)
+}
+
+// ExplainSearch fails, offline search is ranked by bm25 of sqlite
+func (d *Database) ExplainSearch(ctx context.Context, query string, profile config.RankingProfile) (*elastic.Explanation, error) {
+	return nil, fmt.Errorf("search explanation of offline database: %w", elastic.ErrNotSupported)
+}
+
+// Export fails, offline databases are exported whole by copying the file
+func (d *Database) Export(ctx context.Context, q elastic.ExportQuery, fn func(model.Address) error) error {
+	return fmt.Errorf("export of offline database: %w", elastic.ErrNotSupported)
+}
+
+// Slices fails, offline database holds single import
+func (d *Database) Slices(ctx context.Context) ([]elastic.Slice, error) {
+	return nil, fmt.Errorf("history of offline database: %w", elastic.ErrNotSupported)
+}
+
+// PutGeofence fails, offline databases are read only
+func (d *Database) PutGeofence(ctx context.Context, fence elastic.Geofence) (bool, error) {
+	return false, fmt.Errorf("geofences of offline database: %w", elastic.ErrNotSupported)
+}
+
+// DeleteGeofence fails, offline databases are read only
+func (d *Database) DeleteGeofence(ctx context.Context, name string) (bool, error) {
+	return false, fmt.Errorf("geofences of offline database: %w", elastic.ErrNotSupported)
+}
+
+// Geofences returns no fences, they can't be registered offline
+func (d *Database) Geofences(ctx context.Context) ([]elastic.Geofence, error) {
+	return nil, nil
+}
+
+// AddUsage fails, offline databases are read only
+func (d *Database) AddUsage(ctx context.Context, usage []elastic.Usage) error {
+	return fmt.Errorf("usage of offline database: %w", elastic.ErrNotSupported)
+}
+
+// Usage returns no counters, they can't be kept offline
+func (d *Database) Usage(ctx context.Context, periods []string) ([]elastic.Usage, error) {
+	return nil, nil
+}
+
+// WriteAudit fails, audit of imports is kept in elasticsearch only
+func (d *Database) WriteAudit(ctx context.Context, audit elastic.ImportAudit) error {
+	return fmt.Errorf("audit of offline database: %w", elastic.ErrNotSupported)
+}
+
+// Audits returns no entries, they aren't kept offline
+func (d *Database) Audits(ctx context.Context, limit int) ([]elastic.ImportAudit, error) {
+	return nil, nil
+}
+
+// WriteChanges fails, changes feed is kept in elasticsearch only
+func (d *Database) WriteChanges(ctx context.Context, changes []elastic.Change) error {
+	return fmt.Errorf("changes feed of offline database: %w", elastic.ErrNotSupported)
+}
+
+// Changes fails, changes feed is kept in elasticsearch only
+func (d *Database) Changes(ctx context.Context, since int64, limit int) (*elastic.ChangesPage, error) {
+	return nil, fmt.Errorf("changes feed of offline database: %w", elastic.ErrNotSupported)
+}
+
+// TrimChanges does nothing, there is no changes feed offline
+func (d *Database) TrimChanges(ctx context.Context) error {
+	return nil
+}
+
+// SearchRanked performs full text search ordered by bm25 rank, ranking profile is not supported offline
+func (d *Database) SearchRanked(ctx context.Context, query string, profile config.RankingProfile) (*elastic.Result, error) {
+	match := matchQuery(query)
+	if match == "" {
+		return &elastic.Result{Addresses: []model.Address{}}, nil
+	}
+	return d.query(ctx, `SELECT documents.id, documents.data FROM names
+		JOIN documents ON documents.rowid = names.rowid
+		WHERE names MATCH ? AND documents.layer != ? ORDER BY rank LIMIT ?`, match, elastic.BoundaryLayer, searchSize)
+}
+
+// matchQuery quotes query terms so punctuation is not read as FTS5 syntax
+func matchQuery(query string) string {
+	var terms []string
+	for _, term := range strings.Fields(query) {
+		term = strings.Trim(term, `.,;:!?()"'`)
+		if term == "" {
+			continue
+		}
+		terms = append(terms, `"`+strings.Replace(term, `"`, `""`, -1)+`"`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// Reverse returns documents nearest to given point
+func (d *Database) Reverse(ctx context.Context, lat, lon float64) (*elastic.Result, error) {
+	return d.Nearby(ctx, "", lat, lon, reverseDistance)
+}
+
+// ReverseBatch reverse geocodes points one by one, results follow order of points
+func (d *Database) ReverseBatch(ctx context.Context, points []model.Location) ([]*elastic.Result, error) {
+	results := make([]*elastic.Result, 0, len(points))
+	for _, p := range points {
+		result, err := d.Reverse(ctx, p.Lat, p.Lon)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Nearby returns documents of layer within distance from point sorted by distance.
+// Empty layer matches all documents except boundaries. Distance is given in m or km
+func (d *Database) Nearby(ctx context.Context, layer string, lat, lon float64, distance string) (*elastic.Result, error) {
+	meters, err := parseDistance(distance)
+	if err != nil {
+		return nil, err
+	}
+	dLat := meters / metersPerDegree
+	dLon := dLat / math.Max(math.Cos(lat*math.Pi/180), 0.01)
+	// distance is approximated on equirectangular projection, good enough within a few kilometers
+	scale := math.Pow(math.Cos(lat*math.Pi/180), 2)
+	return d.query(ctx, `SELECT documents.id, documents.data FROM bounds
+		JOIN documents ON documents.rowid = bounds.rowid
+		WHERE bounds.min_lat <= ? AND bounds.max_lat >= ? AND bounds.min_lon <= ? AND bounds.max_lon >= ?
+		AND ((? = '' AND documents.layer != ?) OR documents.layer = ?)
+		AND (documents.lat - ?) * (documents.lat - ?) + (documents.lon - ?) * (documents.lon - ?) * ? <= ? * ?
+		ORDER BY (documents.lat - ?) * (documents.lat - ?) + (documents.lon - ?) * (documents.lon - ?) * ?
+		LIMIT ?`,
+		lat+dLat, lat-dLat, lon+dLon, lon-dLon,
+		layer, elastic.BoundaryLayer, layer,
+		lat, lat, lon, lon, scale, dLat, dLat,
+		lat, lat, lon, lon, scale,
+		searchSize)
+}
+
+// Containing returns features of layer which bounding box contains given point.
+// Empty layer matches all documents
+func (d *Database) Containing(ctx context.Context, layer string, lat, lon float64) (*elastic.Result, error) {
+	return d.query(ctx, `SELECT documents.id, documents.data FROM bounds
+		JOIN documents ON documents.rowid = bounds.rowid
+		WHERE bounds.min_lat <= ? AND bounds.max_lat >= ? AND bounds.min_lon <= ? AND bounds.max_lon >= ?
+		AND (bounds.min_lat < bounds.max_lat OR bounds.min_lon < bounds.max_lon)
+		AND (? = '' OR documents.layer = ?)
+		ORDER BY (bounds.max_lat - bounds.min_lat) * (bounds.max_lon - bounds.min_lon)
+		LIMIT ?`, lat, lat, lon, lon, layer, layer, searchSize)
+}
+
+// Intersecting returns documents of layer which bounding box crosses square of radius meters around point
+func (d *Database) Intersecting(ctx context.Context, layer string, lat, lon, radius float64) (*elastic.Result, error) {
+	dLat := radius / metersPerDegree
+	dLon := dLat / math.Max(math.Cos(lat*math.Pi/180), 0.01)
+	return d.query(ctx, `SELECT documents.id, documents.data FROM bounds
+		JOIN documents ON documents.rowid = bounds.rowid
+		WHERE bounds.min_lat <= ? AND bounds.max_lat >= ? AND bounds.min_lon <= ? AND bounds.max_lon >= ?
+		AND documents.layer = ?
+		LIMIT ?`, lat+dLat, lat-dLat, lon+dLon, lon-dLon, layer, searchSize)
+}
+
+// Lookup returns documents with given ids
+func (d *Database) Lookup(ctx context.Context, ids []string) (*elastic.Result, error) {
+	if len(ids) == 0 {
+		return &elastic.Result{Addresses: []model.Address{}}, nil
+	}
+	args := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	return d.query(ctx, `SELECT id, data FROM documents WHERE id IN (`+placeholders+`)`, args...)
+}
+
+// Category returns all documents of layer in category
+func (d *Database) Category(ctx context.Context, layer, category string) (*elastic.Result, error) {
+	return d.query(ctx, `SELECT id, data FROM documents
+		WHERE layer = ? AND json_extract(data, '$.category') = ?`, layer, category)
+}
+
+// WriteDocuments does nothing, analytics are not kept offline
+func (d *Database) WriteDocuments(index string, docs []interface{}) error {
+	return nil
+}
+
+// DeleteDailyIndices does nothing, analytics are not kept offline
+func (d *Database) DeleteDailyIndices(prefix string, retention time.Duration) error {
+	return nil
+}
+
+// SetupLifecycle does nothing, analytics are not kept offline
+func (d *Database) SetupLifecycle(ctx context.Context, alias string, lifecycle config.Lifecycle, retention time.Duration) error {
+	return nil
+}
+
+func (d *Database) query(ctx context.Context, query string, args ...interface{}) (*elastic.Result, error) {
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	result := &elastic.Result{Addresses: []model.Address{}}
+	for rows.Next() {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+		var address model.Address
+		if err := json.Unmarshal([]byte(data), &address); err != nil {
+			return nil, err
+		}
+		address.ID = id
+		result.Addresses = append(result.Addresses, address)
+	}
+	return result, rows.Err()
+}
+
+// parseDistance parses distance in elasticsearch units m and km into meters
+func parseDistance(distance string) (float64, error) {
+	unit := 1.0
+	value := distance
+	switch {
+	case strings.HasSuffix(distance, "km"):
+		unit, value = 1000, strings.TrimSuffix(distance, "km")
+	case strings.HasSuffix(distance, "m"):
+		value = strings.TrimSuffix(distance, "m")
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid distance %q", distance)
+	}
+	return n * unit, nil
+}
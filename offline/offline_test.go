@@ -0,0 +1,60 @@
+package offline_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/offline"
+	"github.com/maddevsio/ariadna/osm"
+	"github.com/maddevsio/ariadna/osm/osmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExport(t *testing.T) {
+	data := osmtest.New().
+		Node(1, 42.87, 74.59, "addr:street", "Киевская улица", "addr:housenumber", "1").
+		Node(2, 42.88, 74.60, "highway", "bus_stop", "name", "Ала-Тоо").
+		Square(10, 100, 42.5, 74.5, 0.02, "natural", "water", "name", "Озеро")
+	db, err := offline.Create(filepath.Join(t.TempDir(), "ariadna.sqlite"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	i, err := osm.NewImporter(ctx, &config.Ariadna{}, osm.WithParser(data), osm.WithStorage(db))
+	require.NoError(t, err)
+	require.NoError(t, i.Start(ctx))
+	require.NoError(t, i.WaitStop())
+	require.NoError(t, i.Done(ctx))
+
+	result, err := db.Search(ctx, "киевская 1")
+	require.NoError(t, err)
+	require.Len(t, result.Addresses, 1)
+	assert.Equal(t, "1", result.Addresses[0].ID)
+
+	result, err = db.Reverse(ctx, 42.8801, 74.6001)
+	require.NoError(t, err)
+	require.NotEmpty(t, result.Addresses)
+	assert.Equal(t, "node/2", result.Addresses[0].ID)
+
+	result, err = db.Nearby(ctx, "transit", 42.87, 74.59, "2km")
+	require.NoError(t, err)
+	require.Len(t, result.Addresses, 1)
+
+	result, err = db.Containing(ctx, "", 42.505, 74.505)
+	require.NoError(t, err)
+	require.Len(t, result.Addresses, 1)
+	assert.Equal(t, "Озеро", result.Addresses[0].Name)
+
+	stats, err := db.Stats(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, stats.Documents)
+	assert.EqualValues(t, 1, stats.Layers[elastic.AddressLayer].Documents)
+	assert.EqualValues(t, 1, stats.Layers["natural"].Categories["water"])
+	assert.NotZero(t, stats.SizeBytes)
+	require.NotNil(t, stats.Import)
+	assert.False(t, stats.Import.ImportedAt.IsZero())
+}
@@ -0,0 +1,137 @@
+// Package olc implements Open Location Code (Plus Codes) encoding, decoding and short code recovery
+package olc
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+const (
+	alphabet   = "23456789CFGHJMPQRVWX"
+	separator  = '+'
+	sepPos     = 8
+	pairs      = 5
+	codeLength = pairs * 2
+	// gridSize is number of cells per degree at full precision
+	gridSize = 8000
+)
+
+// ErrInvalid is returned for strings which are not plus codes
+var ErrInvalid = errors.New("invalid plus code")
+
+// Encode returns 10 digit plus code of point, e.g. 8FVC9G8F+6W
+func Encode(lat, lon float64) string {
+	if lat > 90 {
+		lat = 90
+	}
+	if lat < -90 {
+		lat = -90
+	}
+	for lon >= 180 {
+		lon -= 360
+	}
+	for lon < -180 {
+		lon += 360
+	}
+	latInt := int64(math.Floor((lat + 90) * gridSize))
+	lonInt := int64(math.Floor((lon + 180) * gridSize))
+	if latInt >= 180*gridSize {
+		latInt = 180*gridSize - 1
+	}
+	code := make([]byte, codeLength)
+	for i := pairs - 1; i >= 0; i-- {
+		code[i*2] = alphabet[latInt%20]
+		code[i*2+1] = alphabet[lonInt%20]
+		latInt /= 20
+		lonInt /= 20
+	}
+	return string(code[:sepPos]) + string(separator) + string(code[sepPos:])
+}
+
+// IsFull reports whether code is a valid full plus code
+func IsFull(code string) bool {
+	code = strings.ToUpper(code)
+	idx := strings.IndexByte(code, separator)
+	return idx == sepPos && validDigits(code)
+}
+
+// IsShort reports whether code is a valid short plus code which needs reference location
+func IsShort(code string) bool {
+	code = strings.ToUpper(code)
+	idx := strings.IndexByte(code, separator)
+	return idx >= 2 && idx < sepPos && idx%2 == 0 && validDigits(code)
+}
+
+func validDigits(code string) bool {
+	idx := strings.IndexByte(code, separator)
+	if strings.Count(code, string(separator)) != 1 || len(code)-idx-1 == 1 {
+		return false
+	}
+	for i, c := range code {
+		if c == separator {
+			continue
+		}
+		if c == '0' && i < idx && i%2 == 0 && strings.Trim(code[i:idx], "0") == "" {
+			// padding after significant pairs
+			break
+		}
+		if !strings.ContainsRune(alphabet, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// Decode returns center of area described by full code
+func Decode(code string) (float64, float64, error) {
+	if !IsFull(code) {
+		return 0, 0, ErrInvalid
+	}
+	digits := strings.Replace(strings.ToUpper(code), string(separator), "", 1)
+	digits = strings.TrimRight(digits, "0")
+	if len(digits) > codeLength {
+		digits = digits[:codeLength]
+	}
+	var (
+		lat, lon   float64
+		resolution = 20.0
+	)
+	for i := 0; i+1 < len(digits); i += 2 {
+		lat += float64(strings.IndexByte(alphabet, digits[i])) * resolution
+		lon += float64(strings.IndexByte(alphabet, digits[i+1])) * resolution
+		resolution /= 20
+	}
+	// resolution now is one step below the last decoded pair
+	resolution *= 20
+	return lat - 90 + resolution/2, lon - 180 + resolution/2, nil
+}
+
+// RecoverNearest restores full code from short one using reference location
+// and returns center of its area
+func RecoverNearest(code string, refLat, refLon float64) (float64, float64, error) {
+	if !IsShort(code) {
+		return 0, 0, ErrInvalid
+	}
+	code = strings.ToUpper(code)
+	padding := sepPos - strings.IndexByte(code, separator)
+	resolution := math.Pow(20, 2-float64(padding/2))
+	half := resolution / 2
+	lat, lon, err := Decode(Encode(refLat, refLon)[:padding] + code)
+	if err != nil {
+		return 0, 0, err
+	}
+	switch {
+	case refLat+half < lat && lat-resolution >= -90:
+		lat -= resolution
+	case refLat-half > lat && lat+resolution <= 90:
+		lat += resolution
+	}
+	switch {
+	case refLon+half < lon:
+		lon -= resolution
+	case refLon-half > lon:
+		lon += resolution
+	}
+	return lat, lon, nil
+}
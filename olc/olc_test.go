@@ -0,0 +1,33 @@
+package olc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	assert.Equal(t, "7FG49QCJ+2V", Encode(20.3700625, 2.7821875))
+	lat, lon, err := Decode("7FG49QCJ+2V")
+	require.NoError(t, err)
+	assert.InDelta(t, 20.3700625, lat, 1e-9)
+	assert.InDelta(t, 2.7821875, lon, 1e-9)
+	lat, lon, err = Decode("7FG40000+")
+	require.NoError(t, err)
+	assert.InDelta(t, 20.5, lat, 1e-9)
+	assert.InDelta(t, 2.5, lon, 1e-9)
+	_, _, err = Decode("9G8F+6W")
+	assert.Equal(t, ErrInvalid, err)
+}
+
+func TestRecoverNearest(t *testing.T) {
+	code := Encode(42.874621, 74.569762)
+	lat, lon, err := RecoverNearest(code[4:], 42.87, 74.59)
+	require.NoError(t, err)
+	assert.InDelta(t, 42.874621, lat, 0.0002)
+	assert.InDelta(t, 74.569762, lon, 0.0002)
+	assert.True(t, IsShort("9G8F+6W"))
+	assert.False(t, IsShort("8FVC9G8F+6W"))
+	assert.False(t, IsFull("Bishkek"))
+}
@@ -0,0 +1,171 @@
+package osm
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/maddevsio/ariadna/config"
+)
+
+const (
+	defaultRealIPHeader = "X-Forwarded-For"
+	// privateNetworks and loopbackNetworks name ranges of RFC 1918 and RFC 4193, and loopback
+	privateNetworks  = "private"
+	loopbackNetworks = "loopback"
+)
+
+type (
+	// networks matches addresses of CIDRs and named ranges
+	networks struct {
+		nets     []*net.IPNet
+		private  bool
+		loopback bool
+	}
+	// accessControl decides which clients are served and finds real address of proxied ones
+	accessControl struct {
+		allow   *networks
+		deny    *networks
+		proxies *networks
+		header  string
+	}
+)
+
+// parseNetworks parses CIDRs, single addresses and names of ranges, nil for empty list
+func parseNetworks(list []string) (*networks, error) {
+	if len(list) == 0 {
+		return nil, nil
+	}
+	n := &networks{}
+	for _, s := range list {
+		switch s = strings.TrimSpace(s); {
+		case s == privateNetworks:
+			n.private = true
+		case s == loopbackNetworks:
+			n.loopback = true
+		case strings.Contains(s, "/"):
+			_, ipNet, err := net.ParseCIDR(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid network %q: %w", s, err)
+			}
+			n.nets = append(n.nets, ipNet)
+		default:
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid network %q: expected CIDR, address, %s or %s", s, privateNetworks, loopbackNetworks)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			n.nets = append(n.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		}
+	}
+	return n, nil
+}
+
+// contains reports whether ip belongs to networks, nil networks contain nothing
+func (n *networks) contains(ip net.IP) bool {
+	if n == nil || ip == nil {
+		return false
+	}
+	if (n.private && ip.IsPrivate()) || (n.loopback && ip.IsLoopback()) {
+		return true
+	}
+	for _, ipNet := range n.nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// newAccessControl parses networks of configuration, nil when access isn't restricted
+func newAccessControl(c config.Access) (*accessControl, error) {
+	if len(c.Allow) == 0 && len(c.Deny) == 0 && len(c.TrustedProxies) == 0 {
+		return nil, nil
+	}
+	a := &accessControl{header: c.RealIPHeader}
+	if a.header == "" {
+		a.header = defaultRealIPHeader
+	}
+	var err error
+	if a.allow, err = parseNetworks(c.Allow); err != nil {
+		return nil, fmt.Errorf("access allow: %w", err)
+	}
+	if a.deny, err = parseNetworks(c.Deny); err != nil {
+		return nil, fmt.Errorf("access deny: %w", err)
+	}
+	if a.proxies, err = parseNetworks(c.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("access trusted proxies: %w", err)
+	}
+	return a, nil
+}
+
+// unixPeer reports whether r came over Unix domain socket. Its peers have no address, they
+// are local reverse proxies or local clients
+func unixPeer(r *http.Request) bool {
+	addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	return ok && addr.Network() == "unix"
+}
+
+// clientIP returns address of client. Behind trusted proxies it is the last address of the
+// real IP header not belonging to a proxy, as earlier ones may be forged by the client. Unix
+// socket peers are trusted proxies, requests without the header are of loopback address
+func (a *accessControl) clientIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if unixPeer(r) {
+		ip = net.IPv4(127, 0, 0, 1)
+	} else if !a.proxies.contains(ip) {
+		return ip
+	}
+	var hops []string
+	for _, value := range r.Header.Values(a.header) {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+	for n := len(hops) - 1; n >= 0; n-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[n]))
+		if hop == nil {
+			break
+		}
+		ip = hop
+		if !a.proxies.contains(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+// allowed reports whether client of ip is served, deny list wins over allow list
+func (a *accessControl) allowed(ip net.IP) bool {
+	if a.deny.contains(ip) {
+		return false
+	}
+	return a.allow == nil || a.allow.contains(ip)
+}
+
+// withAccess rejects clients of denied networks or outside of allowed ones with 403. Requests
+// coming through trusted proxies or Unix sockets get address of the real client as RemoteAddr
+func (i *Importer) withAccess(next http.Handler) http.Handler {
+	a := i.access
+	if a == nil {
+		a = &accessControl{header: defaultRealIPHeader}
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := a.clientIP(r)
+		if !a.allowed(ip) {
+			i.writeFailure(w, r, http.StatusForbidden, BadRequest{Error: "client address is not allowed", Code: "forbidden"})
+			return
+		}
+		if host, _, _ := net.SplitHostPort(r.RemoteAddr); ip != nil && !ip.Equal(net.ParseIP(host)) {
+			r = r.Clone(r.Context())
+			r.RemoteAddr = net.JoinHostPort(ip.String(), "0")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,104 @@
+package osm
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccess(t *testing.T) {
+	conf := &config.Ariadna{API: config.API{Access: config.Access{
+		Allow:          []string{"private", "203.0.113.0/24"},
+		Deny:           []string{"10.0.0.13"},
+		TrustedProxies: []string{"loopback", "10.0.0.1"},
+	}}}
+	g, err := NewGeocoder(conf, WithStorage(&memoryStorage{}))
+	require.NoError(t, err)
+	var client string
+	handler := g.i.withAccess(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client = clientID(r)
+	}))
+	get := func(remote, forwarded string) int {
+		r := httptest.NewRequest(http.MethodGet, "/api/search/Бишкек", nil)
+		r.RemoteAddr = remote + ":41000"
+		if forwarded != "" {
+			r.Header.Set("X-Forwarded-For", forwarded)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, get("192.168.1.5", ""))
+	assert.Equal(t, http.StatusOK, get("203.0.113.7", ""))
+	assert.Equal(t, http.StatusForbidden, get("198.51.100.1", ""))
+	assert.Equal(t, http.StatusForbidden, get("10.0.0.13", ""))
+
+	// the client before the last proxy is served and identified, forged hops before it are ignored
+	assert.Equal(t, http.StatusOK, get("127.0.0.1", "198.51.100.1, 203.0.113.7, 10.0.0.1"))
+	assert.Equal(t, "203.0.113.7", client)
+	assert.Equal(t, http.StatusForbidden, get("127.0.0.1", "203.0.113.7, 198.51.100.1"))
+	// header of untrusted client is ignored
+	assert.Equal(t, http.StatusForbidden, get("198.51.100.1", "203.0.113.7"))
+
+	_, err = NewGeocoder(&config.Ariadna{API: config.API{Access: config.Access{Deny: []string{"10.0.0.0/33"}}}}, WithStorage(&memoryStorage{}))
+	assert.Error(t, err)
+}
+
+func TestAccessUnixSocket(t *testing.T) {
+	conf := &config.Ariadna{API: config.API{Access: config.Access{Allow: []string{"203.0.113.0/24"}}}}
+	g, err := NewGeocoder(conf, WithStorage(&memoryStorage{}))
+	require.NoError(t, err)
+	var client string
+	get := serveUnix(t, g.i.withAccess(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client = clientID(r)
+	})))
+
+	// the proxy on the socket is trusted without being listed
+	assert.Equal(t, http.StatusOK, get("198.51.100.1, 203.0.113.7"))
+	assert.Equal(t, "203.0.113.7", client)
+	assert.Equal(t, http.StatusForbidden, get("198.51.100.1"))
+	assert.Equal(t, http.StatusForbidden, get(""))
+
+	// without access control local clients are still told apart from proxied ones
+	g, err = NewGeocoder(&config.Ariadna{}, WithStorage(&memoryStorage{}))
+	require.NoError(t, err)
+	get = serveUnix(t, g.i.withAccess(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client, _ = g.i.quotaSubject(r)
+	})))
+	assert.Equal(t, http.StatusOK, get(""))
+	assert.Equal(t, "ip:127.0.0.1", client)
+	assert.Equal(t, http.StatusOK, get("198.51.100.1"))
+	assert.Equal(t, "ip:198.51.100.1", client)
+}
+
+// serveUnix serves handler on Unix socket, returned func sends request with real IP header
+func serveUnix(t *testing.T, handler http.Handler) func(forwarded string) int {
+	path := filepath.Join(t.TempDir(), "ariadna.sock")
+	l, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	server := &http.Server{Handler: handler}
+	go server.Serve(l)
+	t.Cleanup(func() { server.Close() })
+	c := &http.Client{Transport: &http.Transport{DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, "unix", path)
+	}}}
+	return func(forwarded string) int {
+		r, err := http.NewRequest(http.MethodGet, "http://ariadna/api/search/Бишкек", nil)
+		require.NoError(t, err)
+		if forwarded != "" {
+			r.Header.Set("X-Forwarded-For", forwarded)
+		}
+		res, err := c.Do(r)
+		require.NoError(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+}
@@ -2,54 +2,104 @@ package osm
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
+
+	"github.com/maddevsio/ariadna/model"
+	"github.com/maddevsio/ariadna/normalize"
 )
 
-func (i *Importer) waysToElastic() error {
+func (i *Importer) waysToElastic(ctx context.Context) error {
 	i.logger.Info("started to search ways")
 	buf, err := i.getWays()
 	if err != nil {
 		return err
 	}
 	i.logger.Info("ways found")
-	return i.e.BulkWrite(buf)
+	return i.bulkWrite(ctx, buf)
 }
 func (i *Importer) getWays() (bytes.Buffer, error) {
 	var buf bytes.Buffer
-	for wayID, node := range i.handler.Ways {
-		data, err := i.wayToJSON(node)
-		if err != nil {
-			return buf, err
+	ids := make([]int64, 0, len(i.handler.Ways))
+	for wayID := range i.handler.Ways {
+		if _, ok := i.handler.NaturalWays[wayID]; ok {
+			// indexed with geometry by natural layer
+			continue
 		}
-		meta := []byte(fmt.Sprintf(`{ "index": { "_id": "%d" } }%s`, wayID, "\n"))
-		data = append(data, "\n"...)
-		buf.Grow(len(meta) + len(data))
-		buf.Write(meta)
-		buf.Write(data)
+		ids = append(ids, wayID)
 	}
-	return buf, nil
+	err := i.buildParallel(&buf, len(ids), func(k int) (document, bool) {
+		way := i.handler.Ways[ids[k]]
+		center := i.wayCenter(way)
+		if !i.inClip(center.Lat, center.Lon) {
+			return document{}, false
+		}
+		return document{id: strconv.FormatInt(ids[k], 10), address: i.wayAddress(way)}, true
+	})
+	return buf, err
 }
-func (i *Importer) nodesToElastic() error {
+func (i *Importer) nodesToElastic(ctx context.Context) error {
 	i.logger.Info("started to search nodes")
 	buf, err := i.getNodes()
 	if err != nil {
 		return err
 	}
 	i.logger.Info("nodes searched")
-	return i.e.BulkWrite(buf)
+	return i.bulkWrite(ctx, buf)
 }
 func (i *Importer) getNodes() (bytes.Buffer, error) {
 	var buf bytes.Buffer
-	for nodeID, node := range i.handler.FilteredNodes {
-		data, err := i.nodeToJSON(node)
-		if err != nil {
-			return buf, err
+	ids := make([]int64, 0, len(i.handler.FilteredNodes))
+	for nodeID := range i.handler.FilteredNodes {
+		if _, ok := i.handler.TransitStops[nodeID]; ok {
+			// indexed with routes by transit layer
+			continue
+		}
+		ids = append(ids, nodeID)
+	}
+	err := i.buildParallel(&buf, len(ids), func(k int) (document, bool) {
+		node := i.handler.FilteredNodes[ids[k]]
+		if !i.inClip(node.Lat, node.Lon) {
+			return document{}, false
 		}
-		meta := []byte(fmt.Sprintf(`{ "index": { "_id": "%d" } }%s`, nodeID, "\n"))
-		data = append(data, "\n"...)
-		buf.Grow(len(meta) + len(data))
-		buf.Write(meta)
-		buf.Write(data)
+		return document{id: strconv.FormatInt(ids[k], 10), address: i.nodeAddress(node)}, true
+	})
+	return buf, err
+}
+
+// writeDocument appends address to bulk request body after registered processors,
+// document dropped by any of them is skipped. Geometry is normalized, so that index accepts it
+func (i *Importer) writeDocument(buf *bytes.Buffer, id string, address model.Address) error {
+	keep, err := i.process(id, &address)
+	if err != nil || !keep {
+		return err
+	}
+	if i.layers != nil && !i.layers[documentLayer(address)] {
+		// stage builds documents of other layers too, e.g. addresses along with POIs
+		return nil
+	}
+	address.NameNormalized = normalize.Name(address.Name)
+	address.StreetNormalized = normalize.Name(address.Street)
+	if address.Geometry != nil {
+		if address.Geometry = normalizeGeometry(address.Geometry); address.Geometry == nil {
+			i.logger.Warnf("document %s: invalid geometry is dropped", id)
+		}
+	}
+	data, err := json.Marshal(address)
+	if err != nil {
+		return err
+	}
+	if err := i.changes.upsert(id, data, address); err != nil {
+		return err
 	}
-	return buf, nil
+	// index action with id of OSM element creates or replaces document, so rerun of import
+	// into the same index overwrites documents instead of duplicating them
+	meta := []byte(fmt.Sprintf(`{ "index": { "_id": "%s" } }%s`, id, "\n"))
+	data = append(data, "\n"...)
+	buf.Grow(len(meta) + len(data))
+	buf.Write(meta)
+	buf.Write(data)
+	return nil
 }
@@ -0,0 +1,161 @@
+package osm
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/model"
+)
+
+const (
+	analyticsBuffer        = 10000
+	analyticsBatch         = 500
+	defaultAnalyticsFlush  = 10 * time.Second
+	analyticsCleanupPeriod = 24 * time.Hour
+)
+
+// secretParams are query parameters carrying credentials, they are not written to analytics
+var secretParams = map[string]bool{"api_key": true, "signature": true}
+
+// analyticsEvent describes single search request written to analytics index. Client is identified
+// by id of its API key, credentials of the request aren't written
+type analyticsEvent struct {
+	Timestamp   time.Time         `json:"@timestamp"`
+	Endpoint    string            `json:"endpoint"`
+	Query       string            `json:"query"`
+	Params      map[string]string `json:"params,omitempty"`
+	Results     int               `json:"results"`
+	TopResultID string            `json:"top_result_id,omitempty"`
+	LatencyMS   int64             `json:"latency_ms"`
+	ClientKey   string            `json:"client_key,omitempty"`
+	Profile     string            `json:"ranking_profile,omitempty"`
+}
+
+// observe accounts request in zero-result metrics and analytics
+func (i *Importer) observe(r *http.Request, endpoint, query, profile string, addresses []model.Address, start time.Time) {
+	i.metrics.record(endpoint, query, len(addresses))
+	if i.analytics == nil {
+		return
+	}
+	event := analyticsEvent{
+		Timestamp: start,
+		Endpoint:  endpoint,
+		Query:     query,
+		Results:   len(addresses),
+		LatencyMS: int64(time.Since(start) / time.Millisecond),
+		Profile:   profile,
+	}
+	if key := clientKey(r); key != "" {
+		event.ClientKey = keyID(key)
+	}
+	if len(addresses) > 0 {
+		event.TopResultID = addresses[0].ID
+	}
+	if params := r.URL.Query(); len(params) > 0 {
+		event.Params = make(map[string]string, len(params))
+		for k := range params {
+			if !secretParams[k] {
+				event.Params[k] = params.Get(k)
+			}
+		}
+	}
+	select {
+	case i.analytics <- event:
+	default:
+		i.logger.Warn("analytics buffer is full, event dropped")
+	}
+}
+
+// clientKey returns API key client identified itself with, or the one which signed URL
+func clientKey(r *http.Request) string {
+	if key, ok := r.Context().Value(signedKey{}).(string); ok {
+		return key
+	}
+	if key := r.Header.Get("X-Api-Key"); key != "" {
+		return key
+	}
+	return r.URL.Query().Get("api_key")
+}
+
+// keyID identifies API key in stored data without revealing the key
+func keyID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "key:" + hex.EncodeToString(sum[:8])
+}
+
+// keyAllowed checks API key of request against keys of feature, the feature is disabled without keys
+func keyAllowed(r *http.Request, keys []string, feature string) (int, BadRequest) {
+	if len(keys) == 0 {
+		return http.StatusForbidden, BadRequest{Error: feature + " is disabled", Code: "forbidden"}
+	}
+	key := clientKey(r)
+	if key == "" {
+		return http.StatusUnauthorized, BadRequest{Error: feature + " requires API key", Code: "unauthorized"}
+	}
+	for _, k := range keys {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			return http.StatusOK, BadRequest{}
+		}
+	}
+	return http.StatusForbidden, BadRequest{Error: "API key is not allowed for " + feature, Code: "forbidden"}
+}
+
+// runAnalytics writes buffered events to daily analytics indices and removes expired ones
+func (i *Importer) runAnalytics() {
+	c := i.config.Analytics
+	flush := c.FlushInterval
+	if flush <= 0 {
+		flush = defaultAnalyticsFlush
+	}
+	ticker := time.NewTicker(flush)
+	defer ticker.Stop()
+	cleanup := time.NewTicker(analyticsCleanupPeriod)
+	defer cleanup.Stop()
+	i.cleanupAnalytics()
+	index := func() string { return elastic.DailyIndex(c.Index, time.Now()) }
+	if c.Lifecycle.Enabled {
+		if err := i.e.SetupLifecycle(context.Background(), c.Index, c.Lifecycle, c.Retention); err != nil {
+			i.logger.Errorf("could not set up analytics lifecycle, daily indices are written: %v", err)
+		} else {
+			// rollover and retention are up to lifecycle policy, events are written through alias
+			index = func() string { return c.Index }
+		}
+	}
+	var batch []interface{}
+	write := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := i.e.WriteDocuments(index(), batch); err != nil {
+			i.logger.Errorf("could not write analytics: %v", err)
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case event := <-i.analytics:
+			batch = append(batch, event)
+			if len(batch) >= analyticsBatch {
+				write()
+			}
+		case <-ticker.C:
+			write()
+		case <-cleanup.C:
+			i.cleanupAnalytics()
+		}
+	}
+}
+
+func (i *Importer) cleanupAnalytics() {
+	if i.config.Analytics.Retention <= 0 {
+		return
+	}
+	if err := i.e.DeleteDailyIndices(i.config.Analytics.Index, i.config.Analytics.Retention); err != nil {
+		i.logger.Errorf("could not delete expired analytics: %v", err)
+	}
+}
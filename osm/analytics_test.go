@@ -0,0 +1,26 @@
+package osm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObserveHidesCredentials(t *testing.T) {
+	i := &Importer{config: &config.Ariadna{}, logger: logrus.New(), analytics: make(chan analyticsEvent, 1)}
+	i.metrics = newQueryMetrics(0, true)
+	r := httptest.NewRequest(http.MethodGet, "/api/search/bishkek?api_key=secret&signature=abc&expires=1&lang=ru", nil)
+	i.observe(r, "search", "bishkek", "", nil, time.Now())
+
+	require.Len(t, i.analytics, 1)
+	event := <-i.analytics
+	assert.Equal(t, keyID("secret"), event.ClientKey)
+	assert.NotContains(t, event.ClientKey, "secret")
+	assert.Equal(t, map[string]string{"expires": "1", "lang": "ru"}, event.Params)
+}
@@ -0,0 +1,201 @@
+package osm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/elastic"
+	v1 "github.com/maddevsio/ariadna/schema/v1"
+)
+
+// defaultAuditLimit is number of import runs returned by imports endpoint by default
+const defaultAuditLimit = 20
+
+type (
+	// importAudit collects audit entry of import run as its stages finish and tracks running ones
+	importAudit struct {
+		// inFlight counts documents sent to storage and not written yet
+		inFlight int64
+		mu       sync.Mutex
+		entry    elastic.ImportAudit
+		running  map[*stageCounter]bool
+	}
+	// stageCounter counts documents written by import stage
+	stageCounter struct {
+		documents int64
+		name      string
+		start     time.Time
+	}
+	// stageCounterKey is context key of stageCounter of running stage
+	stageCounterKey struct{}
+)
+
+func newImportAudit(source string, layers map[string]bool) *importAudit {
+	now := time.Now().UTC()
+	a := &importAudit{
+		entry:   elastic.ImportAudit{ID: now.Format("20060102T150405.000Z"), Source: source, StartedAt: now},
+		running: make(map[*stageCounter]bool),
+	}
+	for layer := range layers {
+		a.entry.Layers = append(a.entry.Layers, layer)
+	}
+	sort.Strings(a.entry.Layers)
+	return a
+}
+
+// begin tracks running stage of counter
+func (a *importAudit) begin(counter *stageCounter) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.running[counter] = true
+}
+
+// end records finished stage of counter
+func (a *importAudit) end(counter *stageCounter, stage elastic.StageAudit) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.running, counter)
+	a.entry.Stages = append(a.entry.Stages, stage)
+}
+
+// finish returns audit entry of run finished with err, *PartialError lists failed stages
+func (a *importAudit) finish(err error) elastic.ImportAudit {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	entry := a.entry
+	entry.FinishedAt = time.Now().UTC()
+	entry.Stages = append([]elastic.StageAudit(nil), a.entry.Stages...)
+	// concurrent stages are added as they finish
+	sort.SliceStable(entry.Stages, func(x, y int) bool { return entry.Stages[x].StartedAt.Before(entry.Stages[y].StartedAt) })
+	for _, s := range entry.Stages {
+		entry.Documents += s.Documents
+	}
+	var partial *PartialError
+	switch {
+	case err == nil:
+		entry.Status = elastic.ImportSucceeded
+	case errors.As(err, &partial):
+		entry.Status = elastic.ImportPartial
+		for _, f := range partial.Failed {
+			entry.Errors = append(entry.Errors, f.Error())
+		}
+	default:
+		entry.Status = elastic.ImportFailed
+		entry.Errors = []string{err.Error()}
+	}
+	return entry
+}
+
+// timed runs step of import recording its timing, documents it wrote and its failure in audit
+func (i *Importer) timed(ctx context.Context, name string, step func(ctx context.Context) error) error {
+	counter := &stageCounter{name: name, start: time.Now()}
+	if i.audit == nil {
+		return step(context.WithValue(ctx, stageCounterKey{}, counter))
+	}
+	i.audit.begin(counter)
+	err := step(context.WithValue(ctx, stageCounterKey{}, counter))
+	stage := elastic.StageAudit{
+		Name:       name,
+		StartedAt:  counter.start.UTC(),
+		DurationMS: int64(time.Since(counter.start) / time.Millisecond),
+		Documents:  atomic.LoadInt64(&counter.documents),
+	}
+	if err != nil {
+		stage.Error = err.Error()
+	}
+	i.audit.end(counter, stage)
+	return err
+}
+
+// bulkWrite writes bulk lines of documents to storage, they are counted for audit of stage of ctx
+func (i *Importer) bulkWrite(ctx context.Context, buf bytes.Buffer) error {
+	// every document is action line followed by source line
+	documents := int64(bytes.Count(buf.Bytes(), []byte("\n")) / 2)
+	if i.audit != nil {
+		atomic.AddInt64(&i.audit.inFlight, documents)
+		defer atomic.AddInt64(&i.audit.inFlight, -documents)
+	}
+	if err := i.e.BulkWrite(ctx, buf); err != nil {
+		return err
+	}
+	if counter, ok := ctx.Value(stageCounterKey{}).(*stageCounter); ok {
+		atomic.AddInt64(&counter.documents, documents)
+	}
+	return nil
+}
+
+// WriteAudit records audit entry of import run finished with err when audit is enabled. Failure
+// to record it is logged, so it doesn't hide result of import
+func (i *Importer) WriteAudit(ctx context.Context, err error) {
+	if !i.config.Audit.Enabled || i.audit == nil {
+		return
+	}
+	entry := i.audit.finish(err)
+	entry.ExtractTime = i.extractTime
+	if err := i.e.WriteAudit(ctx, entry); err != nil {
+		i.logger.Warnf("audit of import %s: %v", entry.ID, err)
+		return
+	}
+	i.logger.Infof("import %s %s, %d documents", entry.ID, entry.Status, entry.Documents)
+}
+
+// importsHandler returns audit entries of the latest ?limit= import runs, the latest first.
+// It needs admin API key
+func (i *Importer) importsHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	c := i.config.Audit
+	if !c.Enabled {
+		i.writeFailure(w, r, http.StatusBadRequest, BadRequest{Error: "audit is disabled", Code: "invalid_request"})
+		return
+	}
+	if status, e := keyAllowed(r, c.AdminKeys, "import audit"); status != http.StatusOK {
+		i.writeFailure(w, r, status, e)
+		return
+	}
+	limit := defaultAuditLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			i.writeFailure(w, r, http.StatusBadRequest, BadRequest{Error: "invalid limit, expected positive number", Code: "invalid_request"})
+			return
+		}
+		limit = n
+	}
+	audits, err := i.e.Audits(r.Context(), limit)
+	if err != nil {
+		i.writeError(w, r, err)
+		return
+	}
+	if schemaVersion(r) != v1.Version {
+		i.writeJSON(w, http.StatusOK, audits)
+		return
+	}
+	body := v1.Imports{SchemaVersion: v1.Version, Imports: make([]v1.ImportRun, 0, len(audits))}
+	for _, a := range audits {
+		run := v1.ImportRun{
+			ID:          a.ID,
+			Source:      a.Source,
+			ExtractTime: a.ExtractTime,
+			Index:       a.Index,
+			Layers:      a.Layers,
+			StartedAt:   a.StartedAt,
+			FinishedAt:  a.FinishedAt,
+			Status:      a.Status,
+			Documents:   a.Documents,
+			Stages:      make([]v1.ImportStage, 0, len(a.Stages)),
+			Errors:      a.Errors,
+		}
+		for _, s := range a.Stages {
+			run.Stages = append(run.Stages, v1.ImportStage{Name: s.Name, StartedAt: s.StartedAt, DurationMS: s.DurationMS, Documents: s.Documents, Error: s.Error})
+		}
+		body.Imports = append(body.Imports, run)
+	}
+	i.writeV1(w, http.StatusOK, body)
+}
@@ -0,0 +1,66 @@
+package osm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/maddevsio/ariadna/osm/osmtest"
+	v1 "github.com/maddevsio/ariadna/schema/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportAudit(t *testing.T) {
+	data := osmtest.New().
+		Node(1, 42.87, 74.59, "addr:street", "Киевская улица", "addr:housenumber", "1").
+		Node(2, 42.88, 74.60, "highway", "bus_stop", "name", "Ала-Тоо")
+	storage := &memoryStorage{docs: make(map[string]model.Address)}
+	c := &config.Ariadna{OSMURL: "https://example.com/kyrgyzstan.pbf", Audit: config.Audit{Enabled: true, AdminKeys: []string{"admin"}}}
+	ctx := context.Background()
+	i, err := NewImporter(ctx, c, WithParser(data), WithStorage(transitFailingStorage{storage}))
+	require.NoError(t, err)
+	require.NoError(t, i.Start(ctx))
+	i.WriteAudit(ctx, i.WaitStop())
+
+	require.Len(t, storage.audits, 1)
+	audit := storage.audits[0]
+	assert.Equal(t, elastic.ImportPartial, audit.Status)
+	assert.Equal(t, c.OSMURL, audit.Source)
+	assert.Equal(t, []string{"transit: bulk rejected"}, audit.Errors)
+	stages := make(map[string]elastic.StageAudit, len(audit.Stages))
+	for _, s := range audit.Stages {
+		stages[s.Name] = s
+	}
+	assert.Equal(t, "parse", audit.Stages[0].Name)
+	assert.Equal(t, int64(1), stages["nodes"].Documents)
+	assert.Equal(t, "bulk rejected", stages["transit"].Error)
+	assert.Equal(t, int64(0), stages["transit"].Documents)
+	assert.Equal(t, int64(len(storage.docs)), audit.Documents)
+
+	router := httprouter.New()
+	router.GET("/api/imports", i.importsHandler)
+	handler := i.withSchema(router)
+	get := func(key string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api/imports?limit=5", nil)
+		r.Header.Set("X-Api-Key", key)
+		r.Header.Set("Accept", v1.MediaType)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+	assert.Equal(t, http.StatusForbidden, get("client").Code)
+	w := get("admin")
+	require.Equal(t, http.StatusOK, w.Code)
+	var body v1.Imports
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Imports, 1)
+	assert.Equal(t, audit.ID, body.Imports[0].ID)
+	assert.Len(t, body.Imports[0].Stages, len(audit.Stages))
+}
@@ -0,0 +1,160 @@
+package osm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/config"
+	v1 "github.com/maddevsio/ariadna/schema/v1"
+)
+
+const (
+	endpointAutocomplete = "autocomplete"
+
+	defaultAutocompleteDebounce = 150 * time.Millisecond
+	defaultAutocompleteIdle     = time.Minute
+)
+
+// suggestion is result of autocomplete session query numbered in order of queries
+type suggestion struct {
+	seq  int
+	item batchResult
+}
+
+// autocompleteHandler serves WebSocket session where client sends query as it is typed and
+// gets suggestions, messages are plain text or JSON queries as lines of batch search. Queries
+// are geocoded once client pauses for debounce interval, newer query cancels the one in
+// progress, so only suggestions for the latest query are sent
+func (i *Importer) autocompleteHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	profileName, profile, err := i.searchProfile(r)
+	if err != nil {
+		i.writeFailure(w, r, http.StatusBadRequest, BadRequest{Error: err.Error(), Code: "invalid_request"})
+		return
+	}
+	key, err := websocketKey(r)
+	if err != nil {
+		w.Header().Set("Upgrade", "websocket")
+		i.writeFailure(w, r, http.StatusUpgradeRequired, BadRequest{Error: err.Error(), Code: "invalid_request"})
+		return
+	}
+	conn, err := acceptWebSocket(w, key)
+	if err != nil {
+		i.logger.Errorf("could not accept websocket: %v", err)
+		return
+	}
+	c := i.config.API.Autocomplete
+	debounce, idle := c.Debounce, c.IdleTimeout
+	if debounce <= 0 {
+		debounce = defaultAutocompleteDebounce
+	}
+	if idle <= 0 {
+		idle = defaultAutocompleteIdle
+	}
+	done := make(chan struct{})
+	defer close(done)
+	queries := make(chan batchQuery)
+	go func() {
+		defer close(queries)
+		for {
+			message, err := conn.read(idle)
+			if err != nil {
+				return
+			}
+			if message = bytes.TrimSpace(message); len(message) == 0 {
+				continue
+			}
+			q, err := parseBatchQuery(message, i.maxQueryLength())
+			if err != nil {
+				i.sendSuggestions(conn, batchResult{Error: &BadRequest{Error: err.Error(), Code: "invalid_request"}})
+				continue
+			}
+			select {
+			case queries <- q:
+			case <-done:
+				return
+			}
+		}
+	}()
+	var (
+		latest  batchQuery
+		seq     int
+		cancel  = func() {}
+		timer   = time.NewTimer(debounce)
+		results = make(chan suggestion)
+	)
+	timer.Stop()
+	defer func() {
+		cancel()
+		timer.Stop()
+		conn.close(wsCloseNormal, "")
+	}()
+	for {
+		select {
+		case q, ok := <-queries:
+			if !ok {
+				return
+			}
+			cancel()
+			latest = q
+			seq++
+			timer.Reset(debounce)
+		case <-timer.C:
+			var ctx context.Context
+			ctx, cancel = i.autocompleteContext()
+			go func(ctx context.Context, q batchQuery, seq int) {
+				select {
+				case results <- suggestion{seq: seq, item: i.suggest(ctx, r, q, profileName, profile)}:
+				case <-done:
+				}
+			}(ctx, latest, seq)
+		case s := <-results:
+			if s.seq != seq {
+				// client typed on while query was geocoded
+				continue
+			}
+			if err := i.sendSuggestions(conn, s.item); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// autocompleteContext bounds single query of session with request timeout
+func (i *Importer) autocompleteContext() (context.Context, context.CancelFunc) {
+	if timeout := i.config.API.RequestTimeout; timeout > 0 {
+		return context.WithTimeout(context.Background(), timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// suggest geocodes query of autocomplete session
+func (i *Importer) suggest(ctx context.Context, r *http.Request, q batchQuery, profileName string, profile config.RankingProfile) batchResult {
+	start := time.Now()
+	item := batchResult{ID: q.ID, Query: q.Query}
+	result, err := i.geocode(ctx, q.Query, q.Unit, profile)
+	if err != nil {
+		status, code := errorStatus(err)
+		if status == http.StatusInternalServerError && ctx.Err() == nil {
+			i.logger.Error(err)
+		}
+		item.Error = &BadRequest{Error: err.Error(), Code: code}
+		return item
+	}
+	i.observe(r, endpointAutocomplete, q.Query, profileName, result.Addresses, start)
+	item.Results = batchAddresses(r, withCodes(preferPoint(result.Addresses, r.URL.Query().Get("point_type"))))
+	item.Facets = v1.NewFacets(result.Facets)
+	item.TimedOut = result.TimedOut
+	return item
+}
+
+func (i *Importer) sendSuggestions(conn *wsConn, result batchResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return conn.write(wsText, data)
+}
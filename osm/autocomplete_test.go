@@ -0,0 +1,132 @@
+package osm
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutocomplete(t *testing.T) {
+	c := &config.Ariadna{API: config.API{Autocomplete: config.Autocomplete{Debounce: 50 * time.Millisecond}}}
+	g, err := NewGeocoder(c, WithStorage(&memoryStorage{docs: map[string]model.Address{}}))
+	require.NoError(t, err)
+	i := g.i
+	i.metrics = newQueryMetrics(0, true)
+	router := httprouter.New()
+	router.GET("/api/autocomplete", i.autocompleteHandler)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/autocomplete", nil))
+	assert.Equal(t, http.StatusUpgradeRequired, w.Code)
+
+	conn, rd, res := openAutocomplete(t, server)
+	defer conn.Close()
+	require.Equal(t, http.StatusSwitchingProtocols, res.StatusCode)
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", res.Header.Get("Sec-WebSocket-Accept"))
+
+	// keystrokes within debounce interval are answered once, for the latest query
+	for _, q := range []string{"Ки", "Киев", `{"id": "3", "query": "Киевская"}`} {
+		writeClientFrame(t, conn, wsText, []byte(q))
+	}
+	opcode, payload := readServerFrame(t, conn, rd)
+	require.Equal(t, byte(wsText), opcode)
+	var item batchResult
+	require.NoError(t, json.Unmarshal(payload, &item))
+	assert.Equal(t, "3", item.ID)
+	assert.Equal(t, "Киевская", item.Query)
+	assert.Nil(t, item.Error)
+
+	conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	_, err = rd.ReadByte()
+	assert.Error(t, err, "stale queries are not answered")
+	conn.SetReadDeadline(time.Time{})
+
+	writeClientFrame(t, conn, wsPing, []byte("ping"))
+	opcode, payload = readServerFrame(t, conn, rd)
+	assert.Equal(t, byte(wsPong), opcode)
+	assert.Equal(t, "ping", string(payload))
+
+	writeClientFrame(t, conn, wsClose, []byte{0x03, 0xe8})
+	opcode, _ = readServerFrame(t, conn, rd)
+	assert.Equal(t, byte(wsClose), opcode)
+}
+
+func TestAutocompleteSessionsNotLimited(t *testing.T) {
+	c := &config.Ariadna{API: config.API{MaxConcurrent: 1}}
+	g, err := NewGeocoder(c, WithStorage(&memoryStorage{docs: map[string]model.Address{}}))
+	require.NoError(t, err)
+	i := g.i
+	i.metrics = newQueryMetrics(0, true)
+	router := httprouter.New()
+	router.GET("/api/autocomplete", i.autocompleteHandler)
+	router.GET("/api/ping", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {})
+	server := httptest.NewServer(i.withLimit(router))
+	defer server.Close()
+
+	for n := 0; n < 3; n++ {
+		conn, _, res := openAutocomplete(t, server)
+		defer conn.Close()
+		require.Equal(t, http.StatusSwitchingProtocols, res.StatusCode)
+	}
+	res, err := http.Get(server.URL + "/api/ping")
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode, "open sessions don't hold slots")
+}
+
+// openAutocomplete sends websocket handshake of autocomplete session
+func openAutocomplete(t *testing.T, server *httptest.Server) (net.Conn, *bufio.Reader, *http.Response) {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	require.NoError(t, err)
+	io.WriteString(conn, "GET /api/autocomplete HTTP/1.1\r\nHost: test\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n"+
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n")
+	rd := bufio.NewReader(conn)
+	res, err := http.ReadResponse(rd, nil)
+	require.NoError(t, err)
+	return conn, rd, res
+}
+
+func writeClientFrame(t *testing.T, conn net.Conn, opcode byte, payload []byte) {
+	mask := [4]byte{1, 2, 3, 4}
+	frame := []byte{0x80 | opcode, 0x80 | byte(len(payload))}
+	frame = append(frame, mask[:]...)
+	for n, b := range payload {
+		frame = append(frame, b^mask[n%4])
+	}
+	_, err := conn.Write(frame)
+	require.NoError(t, err)
+}
+
+func readServerFrame(t *testing.T, conn net.Conn, rd *bufio.Reader) (byte, []byte) {
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+	var head [2]byte
+	_, err := io.ReadFull(rd, head[:])
+	require.NoError(t, err)
+	size := int(head[1] & 0x7f)
+	if size == 126 {
+		var ext [2]byte
+		_, err = io.ReadFull(rd, ext[:])
+		require.NoError(t, err)
+		size = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, size)
+	_, err = io.ReadFull(rd, payload)
+	require.NoError(t, err)
+	return head[0] & 0x0f, payload
+}
@@ -0,0 +1,139 @@
+package osm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/model"
+	v1 "github.com/maddevsio/ariadna/schema/v1"
+)
+
+const defaultMaxBatch = 10000
+
+type (
+	// batchQuery is line of batch request body. Plain text lines are queries without id
+	batchQuery struct {
+		ID    string `json:"id,omitempty"`
+		Query string `json:"query"`
+		Unit  string `json:"unit,omitempty"`
+	}
+	// batchResult is line of batch response, results follow negotiated schema
+	batchResult struct {
+		ID       string      `json:"id,omitempty"`
+		Query    string      `json:"query"`
+		Results  interface{} `json:"results,omitempty"`
+		TimedOut bool        `json:"timed_out,omitempty"`
+		Error    *BadRequest `json:"error,omitempty"`
+		// Facets count values of requested fields among all documents found by query
+		Facets map[string][]v1.Facet `json:"facets,omitempty"`
+	}
+)
+
+// batchSearchHandler geocodes newline delimited queries of request body and streams results
+// in the same order as they are found. Failed queries get error line and don't stop the batch
+func (i *Importer) batchSearchHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	profileName, profile, err := i.searchProfile(r)
+	if err != nil {
+		i.writeFailure(w, r, http.StatusBadRequest, BadRequest{Error: err.Error(), Code: "invalid_request"})
+		return
+	}
+	w.Header().Set("X-Ranking-Profile", profileName)
+	if !i.limitBody(w, r) {
+		return
+	}
+	limit, maxQuery := i.maxBatch(), i.maxQueryLength()
+	stream := newNDJSONStream(w, 1)
+	scanner := bufio.NewScanner(r.Body)
+	for n := 0; scanner.Scan(); {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var item batchResult
+		if n++; n > limit {
+			item.Error = &BadRequest{Error: fmt.Sprintf("batch is limited to %d queries", limit), Code: "batch_too_large"}
+			stream.write(item)
+			return
+		}
+		q, err := parseBatchQuery(line, maxQuery)
+		if err != nil {
+			item.Error = &BadRequest{Error: err.Error(), Code: "invalid_request"}
+		} else {
+			item = i.batchSearch(r, q, profileName, profile)
+		}
+		if err := stream.write(item); err != nil {
+			i.logger.Error(err)
+			return
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		e := bodyError(err)
+		stream.write(batchResult{Error: &e})
+	}
+}
+
+// parseBatchQuery parses query line of batch or autocomplete session, queries are limited to
+// maxLength characters
+func parseBatchQuery(line []byte, maxLength int) (batchQuery, error) {
+	q := batchQuery{Query: string(line)}
+	if line[0] == '{' {
+		if err := json.Unmarshal(line, &q); err != nil {
+			return q, fmt.Errorf("invalid query: %v", err)
+		}
+	}
+	return q, validQuery(q.Query, maxLength)
+}
+
+// batchSearch geocodes single query of batch within request timeout
+func (i *Importer) batchSearch(r *http.Request, q batchQuery, profileName string, profile config.RankingProfile) batchResult {
+	start := time.Now()
+	item := batchResult{ID: q.ID, Query: q.Query}
+	ctx := r.Context()
+	if timeout := i.config.API.RequestTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	result, err := i.geocode(ctx, q.Query, q.Unit, profile)
+	if err != nil {
+		status, code := errorStatus(err)
+		if status == http.StatusInternalServerError {
+			i.logger.Error(err)
+		}
+		item.Error = &BadRequest{Error: err.Error(), Code: code}
+		return item
+	}
+	i.observe(r, endpointSearch, q.Query, profileName, result.Addresses, start)
+	addresses := withCodes(preferPoint(result.Addresses, r.URL.Query().Get("point_type")))
+	item.Results = batchAddresses(r, addresses)
+	item.Facets = v1.NewFacets(result.Facets)
+	item.TimedOut = result.TimedOut
+	return item
+}
+
+// maxBatch returns number of queries or points batch requests are limited to
+func (i *Importer) maxBatch() int {
+	if i.config.API.MaxBatch > 0 {
+		return i.config.API.MaxBatch
+	}
+	return defaultMaxBatch
+}
+
+func batchAddresses(r *http.Request, addresses []model.Address) interface{} {
+	addresses = withViewports(labeled(r, addresses))
+	if schemaVersion(r) != v1.Version {
+		return addresses
+	}
+	results := make([]v1.Address, 0, len(addresses))
+	for _, a := range addresses {
+		results = append(results, v1.NewAddress(a))
+	}
+	return results
+}
@@ -0,0 +1,73 @@
+package osm
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// nameStorage finds documents by exact name
+type nameStorage struct {
+	memoryStorage
+}
+
+func (s *nameStorage) SearchRanked(ctx context.Context, query string, profile config.RankingProfile) (*elastic.Result, error) {
+	result := &elastic.Result{Addresses: []model.Address{}}
+	for id, doc := range s.docs {
+		if doc.Name == query {
+			doc.ID = id
+			result.Addresses = append(result.Addresses, doc)
+		}
+	}
+	return result, nil
+}
+
+func TestBatchSearch(t *testing.T) {
+	storage := &nameStorage{memoryStorage{docs: map[string]model.Address{
+		"node/1": {Name: "Ала-Тоо", Location: model.Location{Lat: 42.88, Lon: 74.6}},
+	}}}
+	g, err := NewGeocoder(&config.Ariadna{API: config.API{MaxBatch: 3}}, WithStorage(storage))
+	require.NoError(t, err)
+	i := g.i
+	i.metrics = newQueryMetrics(0, true)
+	router := httprouter.New()
+	router.POST("/api/batch/search", i.batchSearchHandler)
+
+	body := strings.Join([]string{
+		`{"id": "a", "query": "Ала-Тоо"}`,
+		`Дордой`,
+		`{"id": `,
+		``,
+		`Ош`,
+	}, "\n")
+	w := httptest.NewRecorder()
+	i.withSchema(router).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/batch/search", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, ndjsonMediaType, w.Header().Get("Content-Type"))
+
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+	require.Len(t, lines, 4)
+	assert.Equal(t, "a", lines[0]["id"])
+	require.Len(t, lines[0]["results"], 1)
+	assert.Equal(t, "Дордой", lines[1]["query"])
+	assert.Empty(t, lines[1]["results"])
+	assert.Equal(t, "invalid_request", lines[2]["error"].(map[string]interface{})["code"])
+	assert.Equal(t, "batch_too_large", lines[3]["error"].(map[string]interface{})["code"])
+}
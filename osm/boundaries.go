@@ -0,0 +1,215 @@
+package osm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/boundaries"
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/model"
+	v1 "github.com/maddevsio/ariadna/schema/v1"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+const (
+	layerBoundary      = elastic.BoundaryLayer
+	roleCountry        = "country"
+	endpointBoundaries = "boundaries"
+)
+
+func (i *Importer) boundariesToElastic(ctx context.Context) error {
+	i.logger.Info("started to search admin boundaries")
+	i.areas = nil
+	buf, err := i.getBoundaries()
+	if err != nil {
+		return err
+	}
+	i.logger.Info("admin boundaries found")
+	if path := i.config.BoundariesFile; path != "" {
+		if err := boundaries.New(i.areas).Save(path); err != nil {
+			return fmt.Errorf("could not save boundaries: %w", err)
+		}
+		i.logger.Infof("%d admin boundaries saved to %s", len(i.areas), path)
+	}
+	return i.bulkWrite(ctx, buf)
+}
+
+// getBoundaries indexes polygons of countries, cities and districts. Category holds
+// role of boundary: country or place type of city or district
+func (i *Importer) getBoundaries() (bytes.Buffer, error) {
+	var buf bytes.Buffer
+	for relationID, relation := range i.handler.Countries {
+		id := fmt.Sprintf("relation/%d", relationID)
+		geometry, approximate := i.boundaryGeometry(relation)
+		if err := i.writeAdminBoundary(&buf, id, relation.Tags, roleCountry, geometry, approximate); err != nil {
+			return buf, err
+		}
+	}
+	for relationID, relation := range i.handler.Areas {
+		id := fmt.Sprintf("relation/%d", relationID)
+		geometry, approximate := i.boundaryGeometry(relation)
+		if err := i.writeAdminBoundary(&buf, id, relation.Tags, relation.Tags["place"], geometry, approximate); err != nil {
+			return buf, err
+		}
+	}
+	for wayID, way := range i.handler.Districts {
+		id := fmt.Sprintf("way/%d", wayID)
+		if err := i.writeAdminBoundary(&buf, id, way.Tags, way.Tags["place"], i.wayGeometry(way), false); err != nil {
+			return buf, err
+		}
+	}
+	return buf, nil
+}
+
+// writeAdminBoundary writes boundary simplified within configured tolerance
+// unless its members don't make up a polygon. Approximate marks hull of broken boundary
+func (i *Importer) writeAdminBoundary(buf *bytes.Buffer, id string, tags map[string]string, role string, geometry *geojson.Geometry, approximate bool) error {
+	if geometry == nil || !(geometry.IsPolygon() || geometry.IsMultiPolygon()) {
+		return nil
+	}
+	geometry = simplifyGeometry(geometry, i.config.Simplify.Tolerance)
+	center := geometryCenter(geometry)
+	if !i.inClip(center.Lat, center.Lon) {
+		return nil
+	}
+	level, _ := strconv.Atoi(tags["admin_level"])
+	address := model.Address{
+		Name:        tags["name"],
+		Names:       nameVariants(tags),
+		Layer:       layerBoundary,
+		Category:    role,
+		AdminLevel:  level,
+		Approximate: approximate,
+		Location:    center,
+		Geometry:    geometry,
+		Fields:      i.extractFields(tags),
+	}
+	if setPolygonMetrics(&address, geometry) {
+		address.Location = *address.LabelPoint
+	}
+	if i.config.BoundariesFile != "" {
+		address.ID = id
+		i.areas = append(i.areas, boundaryArea(address))
+	}
+	return i.writeDocument(buf, id, address)
+}
+
+// boundaryArea converts document of admin boundary into area of boundaries package
+func boundaryArea(a model.Address) boundaries.Area {
+	return boundaries.Area{ID: a.ID, Name: a.Name, Names: a.Names, Role: a.Category, Level: a.AdminLevel, Geometry: a.Geometry}
+}
+
+// boundaries returns admin boundaries containing point, the outermost first
+func (i *Importer) boundaries(ctx context.Context, lat, lon float64) (*elastic.Result, error) {
+	result, err := i.e.Containing(ctx, layerBoundary, lat, lon)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(result.Addresses, func(a, b int) bool {
+		return bboxArea(result.Addresses[a].Geometry) > bboxArea(result.Addresses[b].Geometry)
+	})
+	return result, nil
+}
+
+// bboxArea returns area of bounding box of geometry in square degrees, enough to order nested boundaries
+func bboxArea(g *geojson.Geometry) float64 {
+	if g == nil {
+		return 0
+	}
+	var polygons [][][][]float64
+	switch {
+	case g.IsPolygon():
+		polygons = [][][][]float64{g.Polygon}
+	case g.IsMultiPolygon():
+		polygons = g.MultiPolygon
+	}
+	minLon, minLat, maxLon, maxLat := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, polygon := range polygons {
+		if len(polygon) == 0 {
+			continue
+		}
+		for _, c := range polygon[0] {
+			minLon, maxLon = math.Min(minLon, c[0]), math.Max(maxLon, c[0])
+			minLat, maxLat = math.Min(minLat, c[1]), math.Max(maxLat, c[1])
+		}
+	}
+	if minLon > maxLon {
+		return 0
+	}
+	return (maxLon - minLon) * (maxLat - minLat)
+}
+
+// boundary is admin polygon in legacy response of boundaries endpoint
+type boundary struct {
+	ID       string            `json:"id"`
+	Name     string            `json:"name"`
+	Role     string            `json:"role"`
+	Level    int               `json:"level,omitempty"`
+	Geometry *geojson.Geometry `json:"geometry,omitempty"`
+	// Approximate is set for concave hull of boundary which members don't close into rings
+	Approximate bool `json:"approximate,omitempty"`
+}
+
+// boundariesHandler lists admin boundaries containing point. Geometry is included
+// with ?geometry=true only, polygons of countries are large. ?simplify=<meters> simplifies
+// returned polygons further than indexed ones
+func (i *Importer) boundariesHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+	lat, lon, err := parsePoint(ps)
+	if err != nil {
+		i.writeFailure(w, r, http.StatusBadRequest, BadRequest{Error: err.Error(), Code: "invalid_request"})
+		return
+	}
+	withGeometry := false
+	if s := r.URL.Query().Get("geometry"); s != "" {
+		if withGeometry, err = strconv.ParseBool(s); err != nil {
+			i.writeFailure(w, r, http.StatusBadRequest, BadRequest{Error: "invalid geometry", Code: "invalid_request"})
+			return
+		}
+	}
+	var tolerance float64
+	if s := r.URL.Query().Get("simplify"); s != "" {
+		if tolerance, err = strconv.ParseFloat(s, 64); err != nil || tolerance < 0 {
+			i.writeFailure(w, r, http.StatusBadRequest, BadRequest{Error: "invalid simplify", Code: "invalid_request"})
+			return
+		}
+	}
+	if i.notModified(w, r, endpointBoundaries, strconv.FormatBool(withGeometry), strconv.FormatFloat(tolerance, 'f', -1, 64)) {
+		return
+	}
+	result, err := i.boundaries(r.Context(), lat, lon)
+	if err != nil {
+		i.writeError(w, r, err)
+		return
+	}
+	query := strconv.FormatFloat(lat, 'f', -1, 64) + "," + strconv.FormatFloat(lon, 'f', -1, 64)
+	i.observe(r, endpointBoundaries, query, "", result.Addresses, start)
+	if result.TimedOut {
+		w.Header().Set("X-Timed-Out", "true")
+		uncacheable(w)
+	}
+	list := make([]boundary, 0, len(result.Addresses))
+	for _, a := range result.Addresses {
+		b := boundary{ID: a.ID, Name: a.Name, Role: a.Category, Level: a.AdminLevel, Approximate: a.Approximate}
+		if withGeometry {
+			b.Geometry = simplifyGeometry(a.Geometry, tolerance)
+		}
+		list = append(list, b)
+	}
+	if schemaVersion(r) == v1.Version {
+		boundaries := make([]v1.Boundary, 0, len(list))
+		for _, b := range list {
+			boundaries = append(boundaries, v1.Boundary(b))
+		}
+		i.writeV1(w, http.StatusOK, v1.Boundaries{SchemaVersion: v1.Version, TimedOut: result.TimedOut, Boundaries: boundaries})
+		return
+	}
+	i.writeJSON(w, http.StatusOK, list)
+}
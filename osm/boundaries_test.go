@@ -0,0 +1,68 @@
+package osm
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/maddevsio/ariadna/boundaries"
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/maddevsio/ariadna/osm/osmtest"
+	"github.com/missinglink/gosmparse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoundaries(t *testing.T) {
+	data := osmtest.New().
+		Square(100, 1000, 41.5, 74.5, 4).
+		Relation(1, []gosmparse.RelationMember{osmtest.Way(100, "outer")}, "type", "boundary", "admin_level", "2", "name", "Кыргызстан").
+		Square(200, 2000, 42.87, 74.6, 0.2).
+		Relation(2, []gosmparse.RelationMember{osmtest.Way(200, "outer")}, "type", "boundary", "place", "city", "admin_level", "4", "name", "Бишкек")
+	storage := &memoryStorage{docs: make(map[string]model.Address)}
+	ctx := context.Background()
+	i, err := NewImporter(ctx, &config.Ariadna{}, WithParser(data), WithStorage(storage))
+	require.NoError(t, err)
+	require.NoError(t, i.Start(ctx))
+	require.NoError(t, i.WaitStop())
+
+	require.Contains(t, storage.docs, "relation/1")
+	country := storage.docs["relation/1"]
+	assert.Equal(t, layerBoundary, country.Layer)
+	assert.Equal(t, roleCountry, country.Category)
+	assert.Equal(t, 2, country.AdminLevel)
+	require.NotNil(t, country.Geometry)
+	assert.True(t, country.Geometry.IsMultiPolygon())
+	require.Contains(t, storage.docs, "relation/2")
+	assert.Equal(t, "city", storage.docs["relation/2"].Category)
+
+	result, err := i.Geocoder().Boundaries(ctx, 42.87, 74.6)
+	require.NoError(t, err)
+	require.Len(t, result.Addresses, 2)
+	assert.Equal(t, "Кыргызстан", result.Addresses[0].Name, "the outermost boundary goes first")
+	assert.Equal(t, "Бишкек", result.Addresses[1].Name)
+}
+
+func TestBoundariesFile(t *testing.T) {
+	data := osmtest.New().
+		Square(100, 1000, 41.5, 74.5, 4).
+		Relation(1, []gosmparse.RelationMember{osmtest.Way(100, "outer")}, "type", "boundary", "admin_level", "2", "name", "Кыргызстан").
+		Square(200, 2000, 42.87, 74.6, 0.2).
+		Relation(2, []gosmparse.RelationMember{osmtest.Way(200, "outer")}, "type", "boundary", "place", "city", "admin_level", "4", "name", "Бишкек")
+	path := filepath.Join(t.TempDir(), "boundaries.json.gz")
+	ctx := context.Background()
+	i, err := NewImporter(ctx, &config.Ariadna{BoundariesFile: path}, WithParser(data), WithStorage(&memoryStorage{docs: make(map[string]model.Address)}))
+	require.NoError(t, err)
+	require.NoError(t, i.Start(ctx))
+	require.NoError(t, i.WaitStop())
+
+	idx, err := boundaries.Open(path)
+	require.NoError(t, err)
+	areas := idx.Lookup(42.87, 74.6)
+	require.Len(t, areas, 2)
+	assert.Equal(t, "relation/1", areas[0].ID)
+	assert.Equal(t, roleCountry, areas[0].Role)
+	assert.Equal(t, "Бишкек", areas[1].Name)
+	assert.Equal(t, 4, areas[1].Level)
+}
@@ -0,0 +1,184 @@
+package osm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/model"
+)
+
+const (
+	defaultChangesLimit = 1000
+	maxChangesLimit     = 10000
+	changesFlushEvery   = 100
+	// changesBatch is number of changes written to storage at once
+	changesBatch = 1000
+
+	opUpsert = "upsert"
+	opDelete = "delete"
+)
+
+// changeLog records changes of indexed documents in changes feed of storage. Every import is
+// compared with the previous one by document hashes, so only changed documents get into the feed.
+// Sequence numbers continue the feed, so they grow across imports
+type changeLog struct {
+	mu      sync.Mutex
+	storage Storage
+	next    int64
+	pending []elastic.Change
+	hashes  map[string]uint64
+	seen    map[string]bool
+}
+
+func newChangeLog() *changeLog {
+	return &changeLog{hashes: make(map[string]uint64), seen: make(map[string]bool)}
+}
+
+// start makes changes of import go into feed of s after its latest change. Hashes of documents
+// of the previous import are loaded, without them every document is recorded as changed
+func (l *changeLog) start(ctx context.Context, s Storage) error {
+	page, err := s.Changes(ctx, 0, 0)
+	if err != nil {
+		return err
+	}
+	hashes := make(map[string]uint64)
+	err = s.Export(ctx, elastic.ExportQuery{}, func(address model.Address) error {
+		id := address.ID
+		address.ID = ""
+		data, err := json.Marshal(address)
+		if err != nil {
+			return err
+		}
+		hashes[id] = documentHash(data)
+		return nil
+	})
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.storage = s
+	l.next = page.Last
+	if err == nil {
+		l.hashes = hashes
+	}
+	return err
+}
+
+// documentHash identifies content of document marshaled to data
+func documentHash(data []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(data)
+	return h.Sum64()
+}
+
+// upsert records document written by import unless it is unchanged since previous import
+func (l *changeLog) upsert(id string, data []byte, address model.Address) error {
+	sum := documentHash(data)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.storage == nil {
+		return nil
+	}
+	l.seen[id] = true
+	if prev, ok := l.hashes[id]; ok && prev == sum {
+		return nil
+	}
+	l.hashes[id] = sum
+	return l.append(elastic.Change{Op: opUpsert, ID: id, Document: &address})
+}
+
+// finish writes pending changes, with deletes of documents missing in import when it is complete,
+// and trims the feed
+func (l *changeLog) finish(ctx context.Context, complete bool) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.storage == nil {
+		return nil
+	}
+	if complete {
+		for id := range l.hashes {
+			if !l.seen[id] {
+				delete(l.hashes, id)
+				l.next++
+				l.pending = append(l.pending, elastic.Change{Seq: l.next, Op: opDelete, ID: id})
+			}
+		}
+	}
+	l.seen = make(map[string]bool)
+	if err := l.flush(ctx); err != nil {
+		return err
+	}
+	return l.storage.TrimChanges(ctx)
+}
+
+// append numbers change and writes batch of pending changes, it is called with locked mu
+func (l *changeLog) append(c elastic.Change) error {
+	l.next++
+	c.Seq = l.next
+	l.pending = append(l.pending, c)
+	if len(l.pending) < changesBatch {
+		return nil
+	}
+	// changes are written in order of numbers, so readers don't skip ones written later
+	return l.flush(context.Background())
+}
+
+// flush writes pending changes, it is called with locked mu
+func (l *changeLog) flush(ctx context.Context) error {
+	pending := l.pending
+	l.pending = nil
+	for start := 0; start < len(pending); start += changesBatch {
+		end := start + changesBatch
+		if end > len(pending) {
+			end = len(pending)
+		}
+		if err := l.storage.WriteChanges(ctx, pending[start:end]); err != nil {
+			return fmt.Errorf("changes feed: %w", err)
+		}
+	}
+	return nil
+}
+
+// changesHandler streams changes after since as newline delimited JSON, ordered by seq. Changes
+// are read from storage, so every instance serves changes of all imports
+func (i *Importer) changesHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var since int64
+	if s := r.URL.Query().Get("since"); s != "" {
+		var err error
+		if since, err = strconv.ParseInt(s, 10, 64); err != nil {
+			i.writeFailure(w, r, http.StatusBadRequest, BadRequest{Error: "invalid since", Code: "invalid_request"})
+			return
+		}
+	}
+	limit := defaultChangesLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > maxChangesLimit {
+			i.writeFailure(w, r, http.StatusBadRequest, BadRequest{Error: "invalid limit", Code: "invalid_request"})
+			return
+		}
+		limit = n
+	}
+	page, err := i.e.Changes(r.Context(), since, limit)
+	if err != nil {
+		i.writeError(w, r, err)
+		return
+	}
+	// seq is unknown or changes after it are trimmed
+	if since > page.Last || since < page.First-1 {
+		i.writeFailure(w, r, http.StatusGone, BadRequest{Error: "changes are not available, full resync required", Code: "resync_required"})
+		return
+	}
+	stream := newNDJSONStream(w, changesFlushEvery)
+	for _, c := range page.Changes {
+		if err := stream.write(c); err != nil {
+			i.logger.Error(err)
+			return
+		}
+	}
+}
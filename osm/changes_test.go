@@ -0,0 +1,86 @@
+package osm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangeLog(t *testing.T) {
+	ctx := context.Background()
+	marshal := func(address model.Address) []byte {
+		data, err := json.Marshal(address)
+		require.NoError(t, err)
+		return data
+	}
+	// documents of the previous import
+	storage := &memoryStorage{docs: map[string]model.Address{
+		"1": {Name: "a"},
+		"2": {Name: "b"},
+		"4": {Name: "d"},
+	}}
+	l := newChangeLog()
+	require.NoError(t, l.start(ctx, storage))
+	// import changes 2, adds 3, keeps 4 and misses 1
+	require.NoError(t, l.upsert("2", marshal(model.Address{Name: "c"}), model.Address{Name: "c"}))
+	require.NoError(t, l.upsert("3", marshal(model.Address{Name: "e"}), model.Address{Name: "e"}))
+	require.NoError(t, l.upsert("4", marshal(model.Address{Name: "d"}), model.Address{Name: "d"}))
+	assert.Empty(t, storage.changes, "changes are written in batches")
+	require.NoError(t, l.finish(ctx, true))
+
+	require.Len(t, storage.changes, 3)
+	assert.Equal(t, elastic.Change{Seq: 1, Op: opUpsert, ID: "2", Document: &model.Address{Name: "c"}}, storage.changes[0])
+	assert.Equal(t, "3", storage.changes[1].ID)
+	assert.Equal(t, elastic.Change{Seq: 3, Op: opDelete, ID: "1"}, storage.changes[2])
+
+	// the next import continues numbering of the feed
+	l = newChangeLog()
+	require.NoError(t, l.start(ctx, storage))
+	require.NoError(t, l.upsert("5", marshal(model.Address{Name: "f"}), model.Address{Name: "f"}))
+	require.NoError(t, l.finish(ctx, false))
+	require.Len(t, storage.changes, 4)
+	assert.Equal(t, int64(4), storage.changes[3].Seq)
+}
+
+func TestChangesHandler(t *testing.T) {
+	storage := &memoryStorage{changes: []elastic.Change{
+		{Seq: 5, Op: opUpsert, ID: "1", Document: &model.Address{Name: "a"}},
+		{Seq: 6, Op: opDelete, ID: "2"},
+		{Seq: 7, Op: opDelete, ID: "3"},
+	}}
+	g, err := NewGeocoder(&config.Ariadna{}, WithStorage(storage))
+	require.NoError(t, err)
+	router := httprouter.New()
+	router.GET("/api/changes", g.i.changesHandler)
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/changes"+query, nil))
+		return w
+	}
+
+	w := get("?since=4&limit=2")
+	require.Equal(t, http.StatusOK, w.Code)
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"seq":5`)
+	assert.Contains(t, lines[1], `"op":"delete"`)
+	w = get("?since=7")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, strings.TrimSpace(w.Body.String()))
+
+	// changes before 5 are trimmed
+	assert.Equal(t, http.StatusGone, get("?since=0").Code)
+	assert.Equal(t, http.StatusGone, get("?since=3").Code)
+	assert.Equal(t, http.StatusGone, get("?since=8").Code, "unknown seq")
+	assert.Equal(t, http.StatusBadRequest, get("?since=x").Code)
+}
@@ -0,0 +1,118 @@
+package osm
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/maddevsio/ariadna/geodesic"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// loadClip reads GeoJSON file and returns outer rings of all polygons found in it
+func loadClip(path string) ([]*geodesic.Polygon, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var geometries []*geojson.Geometry
+	if fc, err := geojson.UnmarshalFeatureCollection(data); err == nil && len(fc.Features) > 0 {
+		for _, f := range fc.Features {
+			geometries = append(geometries, f.Geometry)
+		}
+	} else if f, err := geojson.UnmarshalFeature(data); err == nil && f.Geometry != nil {
+		geometries = append(geometries, f.Geometry)
+	} else {
+		g, err := geojson.UnmarshalGeometry(data)
+		if err != nil {
+			return nil, err
+		}
+		geometries = append(geometries, g)
+	}
+	var polygons []*geodesic.Polygon
+	for _, g := range geometries {
+		if g == nil {
+			continue
+		}
+		switch {
+		case g.IsPolygon():
+			polygons = append(polygons, ringToPolygon(g.Polygon[0]))
+		case g.IsMultiPolygon():
+			for _, p := range g.MultiPolygon {
+				polygons = append(polygons, ringToPolygon(p[0]))
+			}
+		}
+	}
+	if len(polygons) == 0 {
+		return nil, fmt.Errorf("no polygons found in %s", path)
+	}
+	return polygons, nil
+}
+
+func ringToPolygon(ring [][]float64) *geodesic.Polygon {
+	points := make([]geodesic.Point, 0, len(ring))
+	for _, coord := range ring {
+		points = append(points, geodesic.Point{Lat: coord[1], Lon: coord[0]})
+	}
+	return geodesic.NewPolygon(points)
+}
+
+// inClip reports whether point lies inside of configured clip area.
+// It returns true when no clip area configured
+func (i *Importer) inClip(lat, lon float64) bool {
+	if len(i.clip) == 0 {
+		return true
+	}
+	point := geodesic.Point{Lat: lat, Lon: lon}
+	for _, p := range i.clip {
+		if p.Contains(point) {
+			return true
+		}
+	}
+	return false
+}
+
+// touchesClip reports whether some polygon of area overlaps configured clip area.
+// It returns true when no clip area configured
+func (i *Importer) touchesClip(shape *areaShape) bool {
+	if len(i.clip) == 0 {
+		return true
+	}
+	for _, clip := range i.clip {
+		for _, rings := range shape.polygons {
+			if ringsOverlap(rings[0], clip) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ringsOverlap reports whether rings share some area: vertex of one lies inside of the other
+// or their edges cross
+func ringsOverlap(a, b *geodesic.Polygon) bool {
+	ba, bb := a.Bounds(), b.Bounds()
+	if ba[0] > bb[2] || bb[0] > ba[2] {
+		return false
+	}
+	for _, p := range b.Points() {
+		if a.Contains(p) {
+			return true
+		}
+	}
+	for _, p := range a.Points() {
+		if b.Contains(p) {
+			return true
+		}
+	}
+	pa, pb := a.Points(), b.Points()
+	for n := range pa {
+		c, d := pa[n], pa[(n+1)%len(pa)]
+		for k := range pb {
+			e, f := pb[k], pb[(k+1)%len(pb)]
+			if segmentsCross([2]float64{c.Lon, c.Lat}, [2]float64{d.Lon, d.Lat}, [2]float64{e.Lon, e.Lat}, [2]float64{f.Lon, f.Lat}) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package osm
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/geodesic"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/maddevsio/ariadna/osm/osmtest"
+	"github.com/missinglink/gosmparse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClipCountries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clip")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	// clip straddles border of the first two countries
+	clip := filepath.Join(dir, "clip.geojson")
+	require.NoError(t, ioutil.WriteFile(clip, []byte(`{"type": "Polygon", "coordinates": [[[74.5, 42.2], [75.5, 42.2], [75.5, 42.8], [74.5, 42.8], [74.5, 42.2]]]}`), 0644))
+	// boundary files are written into working directory
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+
+	data := osmtest.New().
+		Node(1, 42, 74).Node(2, 42, 75).Node(3, 43, 75).Node(4, 43, 74).
+		Way(10, []int64{1, 2, 3, 4, 1}).
+		Relation(100, []gosmparse.RelationMember{osmtest.Way(10, "outer")}, "type", "boundary", "admin_level", "2", "name", "Кыргызстан").
+		Node(5, 42, 76).Node(6, 43, 76).
+		Way(11, []int64{2, 5, 6, 3, 2}).
+		Relation(101, []gosmparse.RelationMember{osmtest.Way(11, "outer")}, "type", "boundary", "admin_level", "2", "name", "Казахстан").
+		Node(7, 40, 70).Node(8, 40, 71).Node(9, 41, 71).Node(20, 41, 70).
+		Way(12, []int64{7, 8, 9, 20, 7}).
+		Relation(102, []gosmparse.RelationMember{osmtest.Way(12, "outer")}, "type", "boundary", "admin_level", "2", "name", "Тоджикистон")
+	ctx := context.Background()
+	i, err := NewImporter(ctx, &config.Ariadna{ClipPolygon: clip}, WithParser(data),
+		WithStorage(&memoryStorage{docs: make(map[string]model.Address)}))
+	require.NoError(t, err)
+	require.NoError(t, i.Start(ctx))
+	require.NoError(t, i.WaitStop())
+
+	var names []string
+	for _, c := range i.countries {
+		names = append(names, c.name)
+	}
+	assert.ElementsMatch(t, []string{"Кыргызстан", "Казахстан"}, names)
+}
+
+func TestRingsOverlap(t *testing.T) {
+	square := func(lon, lat, size float64) *geodesic.Polygon {
+		return ringToPolygon([][]float64{{lon, lat}, {lon + size, lat}, {lon + size, lat + size}, {lon, lat + size}, {lon, lat}})
+	}
+	assert.True(t, ringsOverlap(square(0, 0, 10), square(2, 2, 1)), "inside")
+	assert.True(t, ringsOverlap(square(2, 2, 1), square(0, 0, 10)), "around")
+	assert.True(t, ringsOverlap(square(0, 0, 2), square(1, 1, 2)), "corners overlap")
+	// cross shape: no vertex of one is inside of the other
+	thin := ringToPolygon([][]float64{{-1, 1}, {3, 1}, {3, 2}, {-1, 2}, {-1, 1}})
+	tall := ringToPolygon([][]float64{{1, -1}, {2, -1}, {2, 3}, {1, 3}, {1, -1}})
+	assert.True(t, ringsOverlap(thin, tall), "edges cross")
+	assert.False(t, ringsOverlap(square(0, 0, 1), square(5, 0, 1)))
+}
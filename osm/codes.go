@@ -0,0 +1,57 @@
+package osm
+
+import (
+	"context"
+	"strings"
+
+	"github.com/maddevsio/ariadna/coordinates"
+	"github.com/maddevsio/ariadna/geohash"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/maddevsio/ariadna/olc"
+)
+
+const (
+	geohashPrecision = 9
+	geohashPrefix    = "geohash:"
+)
+
+// withCodes fills plus code and geohash of every address
+func withCodes(addresses []model.Address) []model.Address {
+	for idx := range addresses {
+		location := addresses[idx].Location
+		addresses[idx].PlusCode = olc.Encode(location.Lat, location.Lon)
+		addresses[idx].Geohash = geohash.Encode(location.Lat, location.Lon, geohashPrecision)
+	}
+	return addresses
+}
+
+// decodeCode recognizes coordinates, full plus codes, short plus codes followed by locality
+// like "9G8F+6W Bishkek" and "geohash:txm6..." queries and returns their location
+func (i *Importer) decodeCode(ctx context.Context, query string) (float64, float64, bool, error) {
+	query = strings.TrimSpace(query)
+	if lat, lon, ok := coordinates.Parse(query); ok {
+		return lat, lon, true, nil
+	}
+	if strings.HasPrefix(strings.ToLower(query), geohashPrefix) {
+		lat, lon, err := geohash.Decode(query[len(geohashPrefix):])
+		return lat, lon, err == nil, nil
+	}
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return 0, 0, false, nil
+	}
+	if olc.IsFull(fields[0]) {
+		lat, lon, err := olc.Decode(fields[0])
+		return lat, lon, err == nil, nil
+	}
+	if !olc.IsShort(fields[0]) || len(fields) == 1 {
+		return 0, 0, false, nil
+	}
+	localities, err := i.e.Search(ctx, strings.Join(fields[1:], " "))
+	if err != nil || len(localities.Addresses) == 0 {
+		return 0, 0, false, err
+	}
+	ref := localities.Addresses[0].Location
+	lat, lon, err := olc.RecoverNearest(fields[0], ref.Lat, ref.Lon)
+	return lat, lon, err == nil, nil
+}
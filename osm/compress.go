@@ -0,0 +1,182 @@
+package osm
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/maddevsio/ariadna/config"
+)
+
+const (
+	defaultCompressMinSize = 1024
+	encodingGzip           = "gzip"
+	encodingBrotli         = "br"
+)
+
+// encoder is streaming compressor
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// withCompression compresses responses with brotli or gzip negotiated by Accept-Encoding.
+// Responses smaller than configured minimum size are sent as is
+func (i *Importer) withCompression(next http.Handler) http.Handler {
+	c := i.config.API.Compression
+	if c.Disabled {
+		return next
+	}
+	minSize := c.MinSize
+	if minSize <= 0 {
+		minSize = defaultCompressMinSize
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := acceptedEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Header.Get("Range") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: minSize, config: c}
+		next.ServeHTTP(cw, r)
+		if err := cw.close(); err != nil {
+			i.logger.Debugf("could not write compressed response: %v", err)
+		}
+	})
+}
+
+// acceptedEncoding prefers brotli over gzip, empty result means no compression
+func acceptedEncoding(header string) string {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				q, _ = strconv.ParseFloat(param[2:], 64)
+			}
+		}
+		accepted[name] = q > 0
+	}
+	switch {
+	case accepted[encodingBrotli]:
+		return encodingBrotli
+	case accepted[encodingGzip], accepted["*"]:
+		return encodingGzip
+	}
+	return ""
+}
+
+// compressWriter buffers response until it reaches minimum size and then streams it through encoder
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+	config   config.Compression
+	status   int
+	buf      []byte
+	enc      encoder
+}
+
+func (c *compressWriter) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+}
+
+func (c *compressWriter) Write(p []byte) (int, error) {
+	if c.enc != nil {
+		return c.enc.Write(p)
+	}
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	c.buf = append(c.buf, p...)
+	if len(c.buf) < c.minSize {
+		return len(p), nil
+	}
+	if err := c.start(); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Unwrap returns underlying writer, so http.ResponseController reaches it
+func (c *compressWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// Flush sends buffered data to client, so streaming responses keep working
+func (c *compressWriter) Flush() {
+	if c.enc == nil && len(c.buf) > 0 {
+		if err := c.start(); err != nil {
+			return
+		}
+	}
+	if c.enc != nil {
+		c.enc.Flush()
+	}
+	if f, ok := c.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (c *compressWriter) start() error {
+	h := c.Header()
+	if h.Get("Content-Encoding") != "" || c.status == http.StatusNoContent || c.status == http.StatusNotModified {
+		// already encoded or bodyless, pass through
+		c.enc = nopEncoder{c.ResponseWriter}
+	} else {
+		h.Set("Content-Encoding", c.encoding)
+		h.Del("Content-Length")
+		c.enc = c.newEncoder()
+	}
+	c.ResponseWriter.WriteHeader(c.status)
+	_, err := c.enc.Write(c.buf)
+	c.buf = nil
+	return err
+}
+
+func (c *compressWriter) newEncoder() encoder {
+	if c.encoding == encodingBrotli {
+		level := c.config.BrotliLevel
+		if level <= 0 || level > brotli.BestCompression {
+			level = brotli.DefaultCompression
+		}
+		return brotli.NewWriterLevel(c.ResponseWriter, level)
+	}
+	level := c.config.GzipLevel
+	if level <= 0 || level > gzip.BestCompression {
+		level = gzip.DefaultCompression
+	}
+	w, _ := gzip.NewWriterLevel(c.ResponseWriter, level)
+	return w
+}
+
+// close flushes encoder or writes small response uncompressed
+func (c *compressWriter) close() error {
+	if c.enc != nil {
+		return c.enc.Close()
+	}
+	if c.status != 0 {
+		c.ResponseWriter.WriteHeader(c.status)
+	}
+	if len(c.buf) == 0 {
+		return nil
+	}
+	_, err := c.ResponseWriter.Write(c.buf)
+	return err
+}
+
+type nopEncoder struct {
+	io.Writer
+}
+
+func (nopEncoder) Close() error { return nil }
+func (nopEncoder) Flush() error { return nil }
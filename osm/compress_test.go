@@ -0,0 +1,63 @@
+package osm
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/maddevsio/ariadna/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcceptedEncoding(t *testing.T) {
+	assert.Equal(t, "br", acceptedEncoding("gzip, deflate, br"))
+	assert.Equal(t, "gzip", acceptedEncoding("gzip, br;q=0"))
+	assert.Equal(t, "gzip", acceptedEncoding("*"))
+	assert.Equal(t, "", acceptedEncoding("identity"))
+	assert.Equal(t, "", acceptedEncoding(""))
+}
+
+func TestWithCompression(t *testing.T) {
+	large := strings.Repeat("Бишкек ", 500)
+	i := &Importer{config: &config.Ariadna{}, logger: logrus.New()}
+	handler := i.withCompression(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/small" {
+			w.Write([]byte("ok"))
+			return
+		}
+		w.Write([]byte(large))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/small", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "ok", w.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, "/large", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	zr, err := gzip.NewReader(w.Body)
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, large, string(body))
+
+	req = httptest.NewRequest(http.MethodGet, "/large", nil)
+	req.Header.Set("Accept-Encoding", "br")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(t, "br", w.Header().Get("Content-Encoding"))
+	body, err = ioutil.ReadAll(brotli.NewReader(w.Body))
+	require.NoError(t, err)
+	assert.Equal(t, large, string(body))
+}
@@ -0,0 +1,98 @@
+package osm
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/boundaries"
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/model"
+)
+
+const endpointCountry = "country"
+
+// countryIndex keeps country boundaries of current index in memory, so country of point
+// is found without query to storage. It is reloaded when index version changes
+type countryIndex struct {
+	mu      sync.RWMutex
+	version string
+	index   *boundaries.Index
+	// addresses are documents of countries without geometry by id
+	addresses map[string]model.Address
+}
+
+// country returns country boundary containing point, result is empty outside of imported countries
+func (i *Importer) country(ctx context.Context, lat, lon float64) (*elastic.Result, error) {
+	version, err := i.indexVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c := &i.countryIndex
+	c.mu.RLock()
+	loaded := c.index != nil && c.version == version
+	c.mu.RUnlock()
+	if !loaded {
+		if err := i.loadCountries(ctx, version); err != nil {
+			return nil, err
+		}
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	result := &elastic.Result{Addresses: []model.Address{}}
+	// enclaves and disputed areas inside of larger countries win
+	if areas := c.index.Lookup(lat, lon); len(areas) > 0 {
+		result.Addresses = append(result.Addresses, c.addresses[areas[len(areas)-1].ID])
+	}
+	return result, nil
+}
+
+// loadCountries replaces country index by boundaries of index version
+func (i *Importer) loadCountries(ctx context.Context, version string) error {
+	c := &i.countryIndex
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.index != nil && c.version == version {
+		// loaded by concurrent request
+		return nil
+	}
+	result, err := i.e.Category(ctx, layerBoundary, roleCountry)
+	if err != nil {
+		return err
+	}
+	areas := make([]boundaries.Area, 0, len(result.Addresses))
+	c.addresses = make(map[string]model.Address, len(result.Addresses))
+	for _, a := range result.Addresses {
+		areas = append(areas, boundaryArea(a))
+		a.Geometry = nil
+		c.addresses[a.ID] = a
+	}
+	c.version, c.index = version, boundaries.New(areas)
+	i.logger.Infof("%d country boundaries of %s loaded", c.index.Len(), version)
+	return nil
+}
+
+// countryHandler returns country containing point by point in polygon test over boundaries
+// kept in memory, for country attribution at rates elasticsearch queries can't afford
+func (i *Importer) countryHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+	lat, lon, err := parsePoint(ps)
+	if err != nil {
+		i.writeFailure(w, r, http.StatusBadRequest, BadRequest{Error: err.Error(), Code: "invalid_request"})
+		return
+	}
+	if i.notModified(w, r, endpointCountry) {
+		return
+	}
+	result, err := i.country(r.Context(), lat, lon)
+	if err != nil {
+		i.writeError(w, r, err)
+		return
+	}
+	query := strconv.FormatFloat(lat, 'f', -1, 64) + "," + strconv.FormatFloat(lon, 'f', -1, 64)
+	i.observe(r, endpointCountry, query, "", result.Addresses, start)
+	i.writeResult(w, r, result, withCodes(result.Addresses))
+}
@@ -0,0 +1,83 @@
+package osm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/model"
+	geojson "github.com/paulmach/go.geojson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func square(minLon, minLat, maxLon, maxLat float64) [][]float64 {
+	return [][]float64{{minLon, minLat}, {maxLon, minLat}, {maxLon, maxLat}, {minLon, maxLat}, {minLon, minLat}}
+}
+
+func countryStorage() *memoryStorage {
+	return &memoryStorage{docs: map[string]model.Address{
+		"relation/1": {
+			Name: "Кыргызстан", Layer: layerBoundary, Category: roleCountry,
+			Geometry: geojson.NewPolygonGeometry([][][]float64{square(69, 39, 80, 43), square(70, 39.5, 71, 40)}),
+		},
+		"relation/2": {
+			Name: "Ўзбекистон", Layer: layerBoundary, Category: roleCountry,
+			Geometry: geojson.NewMultiPolygonGeometry([][][]float64{square(70.2, 39.6, 70.8, 39.9)}, [][][]float64{square(60, 37, 69, 43)}),
+		},
+		"relation/3": {Name: "Бишкек", Layer: layerBoundary, Category: "city", Geometry: geojson.NewPolygonGeometry([][][]float64{square(74.4, 42.7, 74.8, 43)})},
+	}}
+}
+
+func TestCountry(t *testing.T) {
+	g, err := NewGeocoder(&config.Ariadna{}, WithStorage(countryStorage()))
+	require.NoError(t, err)
+	ctx := context.Background()
+	for _, c := range []struct {
+		lat, lon float64
+		want     string
+	}{
+		{42.87, 74.59, "Кыргызстан"},
+		{39.7, 70.5, "Ўзбекистон"},
+		{41.3, 64.5, "Ўзбекистон"},
+		{39.55, 70.1, ""},
+		{50, 100, ""},
+	} {
+		result, err := g.i.country(ctx, c.lat, c.lon)
+		require.NoError(t, err)
+		if c.want == "" {
+			assert.Empty(t, result.Addresses, "%v,%v", c.lat, c.lon)
+			continue
+		}
+		require.Len(t, result.Addresses, 1, "%v,%v", c.lat, c.lon)
+		assert.Equal(t, c.want, result.Addresses[0].Name)
+		assert.Nil(t, result.Addresses[0].Geometry)
+	}
+	assert.Equal(t, "addresses-1", g.i.countryIndex.version)
+	assert.Equal(t, 2, g.i.countryIndex.index.Len())
+}
+
+func TestCountryHandler(t *testing.T) {
+	g, err := NewGeocoder(&config.Ariadna{}, WithStorage(countryStorage()))
+	require.NoError(t, err)
+	i := g.i
+	i.metrics = newQueryMetrics(0, true)
+	router := httprouter.New()
+	router.GET("/api/country/:lat/:lon", i.countryHandler)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/country/42.87/74.59", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var addresses []model.Address
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &addresses))
+	require.Len(t, addresses, 1)
+	assert.Equal(t, "relation/1", addresses[0].ID)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/country/91/74.59", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
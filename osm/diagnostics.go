@@ -0,0 +1,52 @@
+package osm
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// writeDiagnostics writes running import stages with documents they wrote so far, documents
+// being written to storage, memory statistics and stacks of all goroutines
+func (i *Importer) writeDiagnostics(w io.Writer) error {
+	if i.audit != nil {
+		type running struct {
+			name      string
+			start     time.Time
+			documents int64
+		}
+		i.audit.mu.Lock()
+		stages := make([]running, 0, len(i.audit.running))
+		for counter := range i.audit.running {
+			stages = append(stages, running{counter.name, counter.start, atomic.LoadInt64(&counter.documents)})
+		}
+		finished := len(i.audit.entry.Stages)
+		i.audit.mu.Unlock()
+		sort.Slice(stages, func(a, b int) bool { return stages[a].start.Before(stages[b].start) })
+		fmt.Fprintf(w, "import %s: %d stages finished, %d running\n", i.audit.entry.ID, finished, len(stages))
+		for _, s := range stages {
+			fmt.Fprintf(w, "  stage %s running for %v, %d documents written\n", s.name, time.Since(s.start).Round(time.Second), s.documents)
+		}
+		fmt.Fprintf(w, "documents in flight: %d\n", atomic.LoadInt64(&i.audit.inFlight))
+	}
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	fmt.Fprintf(w, "memory: heap %d MiB in use, %d MiB allocated, %d MiB from system, %d GC runs pausing %v\n",
+		m.HeapInuse>>20, m.HeapAlloc>>20, m.Sys>>20, m.NumGC, time.Duration(m.PauseTotalNs))
+	fmt.Fprintf(w, "goroutines: %d\n", runtime.NumGoroutine())
+	return pprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+// dumpDiagnostics logs diagnostics of importer, e.g. when import seems hung
+func (i *Importer) dumpDiagnostics() {
+	var buf bytes.Buffer
+	if err := i.writeDiagnostics(&buf); err != nil {
+		i.logger.Errorf("diagnostics: %v", err)
+	}
+	i.logger.Info("diagnostics:\n" + buf.String())
+}
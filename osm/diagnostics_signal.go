@@ -0,0 +1,29 @@
+//go:build !windows
+// +build !windows
+
+package osm
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// WatchDiagnostics logs diagnostics of importer on every SIGUSR1 until ctx is done: running
+// stages, documents being written, memory statistics and goroutine stacks
+func (i *Importer) WatchDiagnostics(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signals:
+				i.dumpDiagnostics()
+			}
+		}
+	}()
+}
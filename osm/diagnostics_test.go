@@ -0,0 +1,34 @@
+package osm
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/maddevsio/ariadna/osm/osmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnostics(t *testing.T) {
+	ctx := context.Background()
+	storage := &memoryStorage{docs: make(map[string]model.Address)}
+	i, err := NewImporter(ctx, &config.Ariadna{}, WithParser(osmtest.New()), WithStorage(storage))
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, i.timed(ctx, "nodes", func(ctx context.Context) error {
+		var docs bytes.Buffer
+		require.NoError(t, i.writeDocument(&docs, "1", model.Address{Street: "Киевская", HouseNumber: "1"}))
+		require.NoError(t, i.bulkWrite(ctx, docs))
+		return i.writeDiagnostics(&buf)
+	}))
+
+	report := buf.String()
+	assert.Contains(t, report, "0 stages finished, 1 running")
+	assert.Contains(t, report, "stage nodes running for 0s, 1 documents written")
+	assert.Contains(t, report, "documents in flight: 0")
+	assert.Contains(t, report, "memory: heap")
+	assert.Contains(t, report, "TestDiagnostics", "goroutine stacks are dumped")
+}
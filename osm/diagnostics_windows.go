@@ -0,0 +1,6 @@
+package osm
+
+import "context"
+
+// WatchDiagnostics does nothing, there is no SIGUSR1 on windows
+func (i *Importer) WatchDiagnostics(ctx context.Context) {}
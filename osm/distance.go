@@ -0,0 +1,150 @@
+package osm
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/coordinates"
+	"github.com/maddevsio/ariadna/geodesic"
+	"github.com/maddevsio/ariadna/model"
+	v1 "github.com/maddevsio/ariadna/schema/v1"
+)
+
+// destinationDistance is destination of distance response, see v1.Distance
+type destinationDistance struct {
+	ID       string       `json:"id,omitempty"`
+	Location *v1.Location `json:"location,omitempty"`
+	Distance float64      `json:"distance"`
+	Bearing  float64      `json:"bearing"`
+	Error    string       `json:"error,omitempty"`
+}
+
+// distanceHandler returns great circle distances and bearings from origin to destinations, so
+// clients can sort results without implementing haversine. Origin is lat,lon or OSM id,
+// destinations are lat,lon points separated by | and documents listed by ids, points go first
+func (i *Importer) distanceHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	params := r.URL.Query()
+	var points, refs []string
+	for _, p := range strings.Split(params.Get("destinations"), "|") {
+		if p = strings.TrimSpace(p); p != "" {
+			points = append(points, p)
+		}
+	}
+	for _, ref := range strings.Split(params.Get("ids"), ",") {
+		if ref = strings.TrimSpace(ref); ref != "" {
+			refs = append(refs, ref)
+		}
+	}
+	origin := strings.TrimSpace(params.Get("origin"))
+	if err := validateDistance(origin, points, refs, i.maxBatch()); err != nil {
+		i.writeFailure(w, r, http.StatusBadRequest, BadRequest{Error: err.Error(), Code: "invalid_request"})
+		return
+	}
+	ids := refs
+	if _, ok := documentIDs(origin); ok {
+		ids = append([]string{origin}, refs...)
+	}
+	located, err := i.locateRefs(r, ids)
+	if err != nil {
+		i.writeError(w, r, err)
+		return
+	}
+	from, ok := located[origin]
+	if lat, lon, isPoint := coordinates.Parse(origin); isPoint {
+		from, ok = model.Location{Lat: lat, Lon: lon}, true
+	}
+	if !ok {
+		i.writeFailure(w, r, http.StatusNotFound, BadRequest{Error: fmt.Sprintf("origin %s not found", origin), Code: "no_results"})
+		return
+	}
+	list := make([]destinationDistance, 0, len(points)+len(refs))
+	for _, p := range points {
+		lat, lon, _ := coordinates.Parse(p)
+		list = append(list, measure("", from, model.Location{Lat: lat, Lon: lon}))
+	}
+	for _, ref := range refs {
+		to, ok := located[ref]
+		if !ok {
+			list = append(list, destinationDistance{ID: ref, Error: "not found"})
+			continue
+		}
+		list = append(list, measure(ref, from, to))
+	}
+	if schemaVersion(r) == v1.Version {
+		destinations := make([]v1.Distance, 0, len(list))
+		for _, d := range list {
+			destinations = append(destinations, v1.Distance(d))
+		}
+		i.writeV1(w, http.StatusOK, v1.Distances{SchemaVersion: v1.Version, Origin: v1.Location(from), Destinations: destinations})
+		return
+	}
+	i.writeJSON(w, http.StatusOK, list)
+}
+
+// validateDistance checks origin and destinations of distance request
+func validateDistance(origin string, points, refs []string, limit int) error {
+	if _, _, ok := coordinates.Parse(origin); !ok {
+		if _, ok := documentIDs(origin); !ok {
+			return fmt.Errorf("invalid origin %q, expected lat,lon or OSM id", origin)
+		}
+	}
+	if len(points)+len(refs) == 0 {
+		return fmt.Errorf("destinations or ids are required")
+	}
+	if len(points) > limit {
+		return fmt.Errorf("too many destinations, max %d", limit)
+	}
+	if len(refs) > maxLookupIDs {
+		return fmt.Errorf("too many ids, max %d", maxLookupIDs)
+	}
+	for _, p := range points {
+		if _, _, ok := coordinates.Parse(p); !ok {
+			return fmt.Errorf("invalid destination %q, expected lat,lon", p)
+		}
+	}
+	for _, ref := range refs {
+		if _, ok := documentIDs(ref); !ok {
+			return fmt.Errorf("invalid id %q, expected node/<id>, way/<id> or relation/<id>", ref)
+		}
+	}
+	return nil
+}
+
+// locateRefs returns locations of documents of OSM objects by their refs
+func (i *Importer) locateRefs(r *http.Request, refs []string) (map[string]model.Location, error) {
+	located := make(map[string]model.Location, len(refs))
+	if len(refs) == 0 {
+		return located, nil
+	}
+	result, err := i.lookupIDs(r.Context(), refs)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]model.Location, len(result.Addresses))
+	for _, a := range result.Addresses {
+		byID[a.ID] = a.Location
+	}
+	for _, ref := range refs {
+		ids, _ := documentIDs(ref)
+		for _, id := range ids {
+			if location, ok := byID[id]; ok {
+				located[ref] = location
+				break
+			}
+		}
+	}
+	return located, nil
+}
+
+func measure(id string, from, to model.Location) destinationDistance {
+	a, b := geodesic.Point{Lat: from.Lat, Lon: from.Lon}, geodesic.Point{Lat: to.Lat, Lon: to.Lon}
+	return destinationDistance{
+		ID:       id,
+		Location: &v1.Location{Lat: to.Lat, Lon: to.Lon},
+		Distance: math.Round(geodesic.GreatCircle(a, b)*10) / 10,
+		Bearing:  math.Round(geodesic.Bearing(a, b)*10) / 10,
+	}
+}
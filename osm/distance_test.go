@@ -0,0 +1,58 @@
+package osm
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/model"
+	v1 "github.com/maddevsio/ariadna/schema/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDistance(t *testing.T) {
+	storage := &memoryStorage{docs: map[string]model.Address{
+		"node/1": {Name: "Ала-Тоо", Location: model.Location{Lat: 42.88, Lon: 74.6}},
+		"2":      {Street: "Киевская", HouseNumber: "1", Location: model.Location{Lat: 42.87, Lon: 74.59}},
+	}}
+	g, err := NewGeocoder(&config.Ariadna{}, WithStorage(storage))
+	require.NoError(t, err)
+	router := httprouter.New()
+	router.GET("/api/distance", g.i.distanceHandler)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/api/distance?origin=42.87,74.59&destinations=43.87,74.59|42.87,74.59&ids=node/1,way/2,way/3", nil)
+	r.Header.Set("Accept", v1.MediaType)
+	i := g.i
+	i.withSchema(router).ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	var body v1.Distances
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Destinations, 5)
+	assert.InDelta(t, 111195, body.Destinations[0].Distance, 1)
+	assert.Zero(t, body.Destinations[0].Bearing)
+	assert.Zero(t, body.Destinations[1].Distance)
+	assert.Equal(t, "node/1", body.Destinations[2].ID)
+	assert.InDelta(t, 45, body.Destinations[2].Bearing, 10)
+	assert.Equal(t, "way/2", body.Destinations[3].ID)
+	assert.Zero(t, body.Destinations[3].Distance)
+	assert.Equal(t, "not found", body.Destinations[4].Error)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/distance?origin=node/1&destinations=42.87,74.59", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var list []destinationDistance
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list, 1)
+	assert.InDelta(t, 225, list[0].Bearing, 10)
+
+	for _, query := range []string{"origin=north&ids=node/1", "origin=42,74", "origin=42,74&destinations=a,b", "origin=node/9&ids=node/1"} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/distance?"+query, nil))
+		assert.NotEqual(t, http.StatusOK, w.Code, query)
+	}
+}
@@ -0,0 +1,65 @@
+package osm
+
+import (
+	"github.com/maddevsio/ariadna/geodesic"
+	"github.com/missinglink/gosmparse"
+)
+
+// districtRadius is approximate extent in kilometers of districts mapped only as place nodes
+var districtRadius = map[string]float64{
+	"suburb":        1.5,
+	"neighbourhood": 0.7,
+}
+
+// district returns name of district containing point. Boundary polygons win,
+// otherwise the nearest district place node within its radius is taken,
+// which is a Voronoi partition of district nodes clipped by their extents
+func (c city) district(point geodesic.Point) string {
+	for _, d := range c.districts {
+		if d.geom != nil && d.geom.Contains(point) {
+			return d.name
+		}
+	}
+	var (
+		name    string
+		nearest float64
+	)
+	for _, d := range c.districts {
+		if d.center == nil {
+			continue
+		}
+		distance := geodesic.Distance(*d.center, point) / 1000
+		if distance > d.radius || (name != "" && distance >= nearest) {
+			continue
+		}
+		name = d.name
+		nearest = distance
+	}
+	return name
+}
+
+// nodeDistricts builds approximate districts of city from place nodes which have no boundary way
+func (i *Importer) nodeDistricts(c *city) {
+	mapped := make(map[string]bool, len(c.districts))
+	for _, d := range c.districts {
+		mapped[d.name] = true
+	}
+	for _, node := range i.handler.DistrictNodes {
+		if mapped[node.Tags["name"]] {
+			continue
+		}
+		center := geodesic.Point{Lat: node.Lat, Lon: node.Lon}
+		if !c.geom.Contains(center) {
+			continue
+		}
+		c.districts = append(c.districts, nodeDistrict(node, center))
+	}
+}
+
+func nodeDistrict(node gosmparse.Node, center geodesic.Point) district {
+	return district{
+		name:   node.Tags["name"],
+		center: &center,
+		radius: districtRadius[node.Tags["place"]],
+	}
+}
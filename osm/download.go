@@ -1,24 +1,304 @@
 package osm
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maddevsio/ariadna/config"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// gcsURL is JSON-less download endpoint of Google Cloud Storage objects
+	gcsURL = "https://storage.googleapis.com"
+	// maxRedirects is number of redirects followed by download, as by default HTTP client
+	maxRedirects = 10
 )
 
-func (i *Importer) download() error {
-	i.logger.Infof("downloading %s", i.config.OSMURL)
-	resp, err := http.Get(i.config.OSMURL)
+// httpDownloader fetches extracts with HTTP GET from http(s), s3 and gs URLs. Configured
+// headers and token are sent to host of osm_url only, other hosts get their own ones
+type httpDownloader struct {
+	source config.Source
+	// host is host of osm_url or its gs:// bucket
+	host string
+}
+
+func newHTTPDownloader(c *config.Ariadna) httpDownloader {
+	d := httpDownloader{source: c.Source}
+	if u, err := url.Parse(c.OSMURL); err == nil {
+		d.host = u.Host
+	}
+	return d
+}
+
+// Download saves content of source to path. Servers supporting range requests are asked
+// for parts of extract over configured number of connections
+func (d httpDownloader) Download(ctx context.Context, source, path string) error {
+	var limiter *rateLimiter
+	if d.source.Bandwidth > 0 {
+		limiter = newRateLimiter(float64(d.source.Bandwidth), int(d.source.Bandwidth))
+	}
+	probe := ""
+	if d.source.Connections > 1 {
+		probe = "bytes=0-0"
+	}
+	resp, err := d.get(ctx, source, probe, "")
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		err = d.save(ctx, limiter, resp.Body, path)
+	case http.StatusPartialContent:
+		var size int64
+		size, err = contentSize(resp.Header.Get("Content-Range"))
+		if err == nil {
+			err = d.saveRanges(ctx, limiter, source, path, size, resp.Header)
+		}
+	default:
+		return fmt.Errorf("%w: %s: %s", ErrDownloadFailed, source, resp.Status)
+	}
+	if err != nil {
+		return err
+	}
+	// modification time of extract tells its age in index statistics
+	if modified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		return os.Chtimes(path, modified, modified)
+	}
+	return nil
+}
+
+// get sends GET request of source, byteRange and ifRange set Range and If-Range headers
+// when not empty
+func (d httpDownloader) get(ctx context.Context, source, byteRange, ifRange string) (*http.Response, error) {
+	req, err := d.request(source)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDownloadFailed, err)
+	}
+	if byteRange != "" {
+		req.Header.Set("Range", byteRange)
+	}
+	if ifRange != "" {
+		req.Header.Set("If-Range", ifRange)
+	}
+	resp, err := d.client().Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDownloadFailed, err)
+	}
+	return resp, nil
+}
 
-	f, err := os.Create(i.config.OSMFilename)
+func (d httpDownloader) save(ctx context.Context, limiter *rateLimiter, body io.Reader, path string) error {
+	f, err := os.Create(path)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
-	_, err = io.Copy(f, resp.Body)
-	return err
+	if _, err := io.Copy(f, throttle(ctx, limiter, body)); err != nil {
+		return fmt.Errorf("%w: %v", ErrDownloadFailed, err)
+	}
+	return f.Close()
+}
+
+// saveRanges downloads size bytes of source in parallel parts written at their offsets. Parts
+// are asked for the version of the probe response by If-Range, a part of another version
+// fails the download instead of being stitched into a corrupt file
+func (d httpDownloader) saveRanges(ctx context.Context, limiter *rateLimiter, source, path string, size int64, probe http.Header) error {
+	etag := probe.Get("ETag")
+	ifRange := etag
+	if strings.HasPrefix(etag, "W/") || etag == "" {
+		// weak tags can't be used with ranges
+		ifRange = probe.Get("Last-Modified")
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := f.Truncate(size); err != nil {
+		return err
+	}
+	part := (size + int64(d.source.Connections) - 1) / int64(d.source.Connections)
+	eg, ctx := errgroup.WithContext(ctx)
+	for start := int64(0); start < size; start += part {
+		start, end := start, start+part
+		if end > size {
+			end = size
+		}
+		eg.Go(func() error {
+			resp, err := d.get(ctx, source, fmt.Sprintf("bytes=%d-%d", start, end-1), ifRange)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode == http.StatusOK || (etag != "" && resp.Header.Get("ETag") != etag) {
+				return fmt.Errorf("%w: %s changed during download", ErrDownloadFailed, source)
+			}
+			if resp.StatusCode != http.StatusPartialContent {
+				return fmt.Errorf("%w: %s: %s", ErrDownloadFailed, source, resp.Status)
+			}
+			n, err := io.Copy(&offsetWriter{f: f, offset: start}, throttle(ctx, limiter, resp.Body))
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrDownloadFailed, err)
+			}
+			if n != end-start {
+				return fmt.Errorf("%w: %s: got %d of %d bytes at %d", ErrDownloadFailed, source, n, end-start, start)
+			}
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// contentSize returns complete length from Content-Range header
+func contentSize(contentRange string) (int64, error) {
+	n := strings.LastIndex(contentRange, "/")
+	if n < 0 {
+		return 0, fmt.Errorf("%w: invalid Content-Range %q", ErrDownloadFailed, contentRange)
+	}
+	size, err := strconv.ParseInt(contentRange[n+1:], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid Content-Range %q", ErrDownloadFailed, contentRange)
+	}
+	return size, nil
+}
+
+type offsetWriter struct {
+	f      *os.File
+	offset int64
+}
+
+func (w *offsetWriter) Write(p []byte) (int, error) {
+	n, err := w.f.WriteAt(p, w.offset)
+	w.offset += int64(n)
+	return n, err
+}
+
+// throttledReader reads within bandwidth of limiter shared by all connections of download
+type throttledReader struct {
+	ctx     context.Context
+	limiter *rateLimiter
+	r       io.Reader
+}
+
+func throttle(ctx context.Context, limiter *rateLimiter, r io.Reader) io.Reader {
+	if limiter == nil {
+		return r
+	}
+	return &throttledReader{ctx: ctx, limiter: limiter, r: r}
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > int(t.limiter.burst) {
+		p = p[:int(t.limiter.burst)]
+	}
+	n, err := t.r.Read(p)
+	if n > 0 {
+		if werr := t.limiter.wait(t.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// request builds GET request of source. Objects of s3:// and gs:// URLs are requested
+// from HTTPS APIs of their storages
+func (d httpDownloader) request(source string) (*http.Request, error) {
+	u, err := url.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "s3":
+		return s3Request(d.source.S3, u.Host, u.Path, time.Now())
+	case "gs":
+		req, err := http.NewRequest(http.MethodGet, gcsURL+"/"+u.Host+u.EscapedPath(), nil)
+		if err != nil {
+			return nil, err
+		}
+		d.authorize(req, u.Host)
+		return req, nil
+	case "http", "https":
+		req, err := http.NewRequest(http.MethodGet, source, nil)
+		if err != nil {
+			return nil, err
+		}
+		d.authorize(req, u.Host)
+		return req, nil
+	}
+	return nil, fmt.Errorf("unsupported source %q", source)
+}
+
+// authorize adds headers and bearer token configured for host to request
+func (d httpDownloader) authorize(req *http.Request, host string) {
+	auth, ok := d.source.Auth[strings.ToLower(host)]
+	if !ok && strings.EqualFold(host, d.host) {
+		auth, ok = config.SourceAuth{Headers: d.source.Headers, Token: d.source.Token}, true
+	}
+	if !ok {
+		return
+	}
+	for name, value := range auth.Headers {
+		req.Header.Set(name, value)
+	}
+	if auth.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+auth.Token)
+	}
+}
+
+// client follows redirects without credentials when they lead to another host
+func (d httpDownloader) client() *http.Client {
+	return &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		if req.URL.Host == via[0].URL.Host {
+			return nil
+		}
+		req.Header.Del("Authorization")
+		for name := range d.source.Headers {
+			req.Header.Del(name)
+		}
+		for _, auth := range d.source.Auth {
+			for name := range auth.Headers {
+				req.Header.Del(name)
+			}
+		}
+		return nil
+	}}
+}
+
+// download fetches extract from osm_url, falling back to configured mirrors in order
+func (i *Importer) download(ctx context.Context) error {
+	sources := append([]string{i.config.OSMURL}, i.config.Source.Mirrors...)
+	var err error
+	for _, source := range sources {
+		i.logger.Infof("downloading %s", source)
+		if err = i.downloader.Download(ctx, source, i.config.OSMFilename); err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		i.logger.Warnf("download from %s failed: %v", source, err)
+	}
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(i.config.OSMFilename); err == nil {
+		modified := info.ModTime().UTC()
+		i.extractTime = &modified
+	}
+	return nil
 }
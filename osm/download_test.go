@@ -0,0 +1,201 @@
+package osm
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/maddevsio/ariadna/osm/osmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadSources(t *testing.T) {
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		w.Write([]byte("extract"))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "download")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "extract.osm.pbf")
+
+	d := newHTTPDownloader(&config.Ariadna{OSMURL: server.URL + "/asia/kyrgyzstan-latest.osm.pbf", Source: config.Source{
+		Headers: map[string]string{"x-mirror": "internal"},
+		Token:   "secret",
+		S3:      config.S3{Region: "eu-central-1", Endpoint: server.URL, AccessKey: "AKID", SecretKey: "key"},
+	}})
+	ctx := context.Background()
+	require.NoError(t, d.Download(ctx, server.URL+"/asia/kyrgyzstan-latest.osm.pbf", path))
+	require.NoError(t, d.Download(ctx, "s3://extracts/asia/kyrgyzstan-latest.osm.pbf", path))
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "extract", string(data))
+
+	require.Len(t, requests, 2)
+	assert.Equal(t, "Bearer secret", requests[0].Header.Get("Authorization"))
+	assert.Equal(t, "internal", requests[0].Header.Get("X-Mirror"))
+	assert.Equal(t, "/extracts/asia/kyrgyzstan-latest.osm.pbf", requests[1].URL.Path)
+	assert.True(t, strings.HasPrefix(requests[1].Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/"))
+
+	assert.Error(t, d.Download(ctx, "ftp://example.com/extract.osm.pbf", path))
+}
+
+func TestSourceRequests(t *testing.T) {
+	d := newHTTPDownloader(&config.Ariadna{OSMURL: "gs://extracts/asia/kyrgyzstan-latest.osm.pbf", Source: config.Source{Token: "token"}})
+	req, err := d.request("gs://extracts/asia/kyrgyzstan-latest.osm.pbf")
+	require.NoError(t, err)
+	assert.Equal(t, "https://storage.googleapis.com/extracts/asia/kyrgyzstan-latest.osm.pbf", req.URL.String())
+	assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+
+	req, err = d.request("gs://mirror/asia/kyrgyzstan-latest.osm.pbf")
+	require.NoError(t, err)
+	assert.Empty(t, req.Header.Get("Authorization"), "token of osm_url isn't sent to other buckets")
+
+	now := time.Date(2013, 5, 24, 0, 0, 0, 0, time.UTC)
+	req, err = s3Request(config.S3{Region: "us-east-1", AccessKey: "AKID", SecretKey: "key", SessionToken: "session"},
+		"extracts", "/kyrgyzstan-latest.osm.pbf", now)
+	require.NoError(t, err)
+	assert.Equal(t, "https://extracts.s3.us-east-1.amazonaws.com/kyrgyzstan-latest.osm.pbf", req.URL.String())
+	assert.Equal(t, "20130524T000000Z", req.Header.Get("X-Amz-Date"))
+	assert.Equal(t, "session", req.Header.Get("X-Amz-Security-Token"))
+	assert.Contains(t, req.Header.Get("Authorization"),
+		"Credential=AKID/20130524/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date;x-amz-security-token, Signature=")
+}
+
+func TestDownloadCredentialsScope(t *testing.T) {
+	var mu sync.Mutex
+	seen := make(map[string]http.Header)
+	record := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			seen[name] = r.Header.Clone()
+			mu.Unlock()
+			w.Write([]byte("extract"))
+		}
+	}
+	mirror := httptest.NewServer(record("mirror"))
+	defer mirror.Close()
+	cdn := httptest.NewServer(record("cdn"))
+	defer cdn.Close()
+	// primary redirects to another host, e.g. CDN of signed links
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen["primary"] = r.Header.Clone()
+		mu.Unlock()
+		http.Redirect(w, r, cdn.URL+r.URL.Path, http.StatusFound)
+	}))
+	defer primary.Close()
+	dir, err := ioutil.TempDir("", "download")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "extract.osm.pbf")
+
+	mirrorHost := strings.TrimPrefix(mirror.URL, "http://")
+	d := newHTTPDownloader(&config.Ariadna{OSMURL: primary.URL + "/extract.osm.pbf", Source: config.Source{
+		Headers: map[string]string{"x-api-key": "primary-key"},
+		Token:   "primary-token",
+		Auth:    map[string]config.SourceAuth{mirrorHost: {Token: "mirror-token"}},
+	}})
+	ctx := context.Background()
+	require.NoError(t, d.Download(ctx, primary.URL+"/extract.osm.pbf", path))
+	require.NoError(t, d.Download(ctx, mirror.URL+"/extract.osm.pbf", path))
+
+	assert.Equal(t, "Bearer primary-token", seen["primary"].Get("Authorization"))
+	assert.Equal(t, "primary-key", seen["primary"].Get("X-Api-Key"))
+	assert.Empty(t, seen["cdn"].Get("Authorization"), "credentials are dropped on redirect to another host")
+	assert.Empty(t, seen["cdn"].Get("X-Api-Key"))
+	assert.Equal(t, "Bearer mirror-token", seen["mirror"].Get("Authorization"))
+	assert.Empty(t, seen["mirror"].Get("X-Api-Key"), "headers of osm_url aren't sent to mirrors")
+}
+
+func TestDownloadRanges(t *testing.T) {
+	content := strings.Repeat("0123456789", 1000)
+	var ranges, ifRanges []string
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		ifRanges = append(ifRanges, r.Header.Get("If-Range"))
+		mu.Unlock()
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "extract.osm.pbf", time.Time{}, strings.NewReader(content))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "download")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "extract.osm.pbf")
+
+	d := httpDownloader{source: config.Source{Connections: 3, Bandwidth: 1 << 20}}
+	require.NoError(t, d.Download(context.Background(), server.URL, path))
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(data))
+	assert.ElementsMatch(t, []string{"bytes=0-0", "bytes=0-3333", "bytes=3334-6667", "bytes=6668-9999"}, ranges)
+	assert.ElementsMatch(t, []string{"", `"v1"`, `"v1"`, `"v1"`}, ifRanges)
+}
+
+func TestDownloadRangesChanged(t *testing.T) {
+	var mu sync.Mutex
+	version := "v1"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		v := version
+		// the extract is replaced right after the probe
+		version = "v2"
+		mu.Unlock()
+		w.Header().Set("ETag", `"`+v+`"`)
+		http.ServeContent(w, r, "extract.osm.pbf", time.Time{}, strings.NewReader(strings.Repeat(v, 1000)))
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "extract.osm.pbf")
+
+	d := httpDownloader{source: config.Source{Connections: 2}}
+	err := d.Download(context.Background(), server.URL, path)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrDownloadFailed))
+	assert.Contains(t, err.Error(), "changed during download")
+}
+
+type mirrorDownloader struct {
+	failing map[string]bool
+	tried   []string
+}
+
+func (d *mirrorDownloader) Download(ctx context.Context, source, path string) error {
+	d.tried = append(d.tried, source)
+	if d.failing[source] {
+		return ErrDownloadFailed
+	}
+	return nil
+}
+
+func TestDownloadMirrors(t *testing.T) {
+	d := &mirrorDownloader{failing: map[string]bool{"https://primary": true}}
+	c := &config.Ariadna{OSMURL: "https://primary", Source: config.Source{Mirrors: []string{"https://mirror", "https://spare"}}}
+	i, err := NewImporter(context.Background(), c, WithDownloader(d), WithParser(osmtest.New()),
+		WithStorage(&memoryStorage{docs: make(map[string]model.Address)}))
+	require.NoError(t, err)
+	require.NoError(t, i.download(context.Background()))
+	assert.Equal(t, []string{"https://primary", "https://mirror"}, d.tried)
+
+	d = &mirrorDownloader{failing: map[string]bool{"https://primary": true, "https://mirror": true, "https://spare": true}}
+	i, err = NewImporter(context.Background(), c, WithDownloader(d), WithParser(osmtest.New()),
+		WithStorage(&memoryStorage{docs: make(map[string]model.Address)}))
+	require.NoError(t, err)
+	assert.Error(t, i.download(context.Background()))
+	assert.Len(t, d.tried, 3)
+}
@@ -0,0 +1,71 @@
+package osm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/maddevsio/ariadna/elastic"
+)
+
+var (
+	// ErrDownloadFailed is reported when OSM extract could not be downloaded
+	ErrDownloadFailed = errors.New("download failed")
+	// ErrBoundaryNotFound is reported when configured country boundary is missing in extract
+	ErrBoundaryNotFound = errors.New("boundary not found")
+	// ErrNoResults is reported when nothing matches query or a place it refers to
+	ErrNoResults = errors.New("no results")
+	// ErrIndexUnavailable is reported when elasticsearch can't be reached or index is missing
+	ErrIndexUnavailable = elastic.ErrIndexUnavailable
+)
+
+// problemMediaType is media type of RFC 7807 problem details error responses are written as
+const problemMediaType = "application/problem+json"
+
+// problem is RFC 7807 problem details of failed request. Responses carry error and code of
+// their schema as extension members, so clients reading them keep working
+type problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail"`
+	Instance string `json:"instance,omitempty"`
+}
+
+// newProblem describes failure of request, its type is URN of error code
+func newProblem(r *http.Request, status int, e BadRequest) problem {
+	p := problem{Type: "about:blank", Title: http.StatusText(status), Status: status, Detail: e.Error}
+	if e.Code != "" {
+		p.Type = "urn:ariadna:error:" + e.Code
+	}
+	if r != nil && r.URL != nil {
+		p.Instance = r.URL.Path
+	}
+	return p
+}
+
+// errorStatus maps error to HTTP status and machine readable code of API error response
+func errorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, ErrNoResults):
+		return http.StatusNotFound, "no_results"
+	case errors.Is(err, ErrBoundaryNotFound):
+		return http.StatusNotFound, "boundary_not_found"
+	case errors.Is(err, ErrIndexUnavailable):
+		return http.StatusServiceUnavailable, "index_unavailable"
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, "timeout"
+	case errors.Is(err, elastic.ErrNotSupported):
+		return http.StatusNotImplemented, "not_supported"
+	}
+	return http.StatusInternalServerError, "internal"
+}
+
+// writeError writes error response with status matching the kind of error
+func (i *Importer) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status, code := errorStatus(err)
+	if status == http.StatusInternalServerError {
+		i.logger.Error(err)
+	}
+	i.writeFailure(w, r, status, BadRequest{Error: err.Error(), Code: code})
+}
@@ -0,0 +1,29 @@
+package osm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorStatus(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{fmt.Errorf("place %q: %w", "x", ErrNoResults), http.StatusNotFound, "no_results"},
+		{fmt.Errorf("%w: connection refused", ErrIndexUnavailable), http.StatusServiceUnavailable, "index_unavailable"},
+		{context.DeadlineExceeded, http.StatusGatewayTimeout, "timeout"},
+		{errors.New("boom"), http.StatusInternalServerError, "internal"},
+	}
+	for _, c := range cases {
+		status, code := errorStatus(c.err)
+		assert.Equal(t, c.status, status, c.err.Error())
+		assert.Equal(t, c.code, code, c.err.Error())
+	}
+}
@@ -0,0 +1,86 @@
+package osm
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maddevsio/ariadna/elastic"
+)
+
+// indexVersionTTL is how long index version is cached before asking elasticsearch again
+const indexVersionTTL = 30 * time.Second
+
+// indexVersion caches name of current index, so ETags don't cost a request to elasticsearch
+type indexVersion struct {
+	mu      sync.Mutex
+	name    string
+	expires time.Time
+}
+
+func (i *Importer) indexVersion(ctx context.Context) (string, error) {
+	if index, ok := elastic.ContextIndex(ctx); ok {
+		// slice of past import selected by ?as_of=
+		return index, nil
+	}
+	v := &i.version
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if time.Now().Before(v.expires) {
+		return v.name, nil
+	}
+	name, err := i.e.IndexVersion(ctx)
+	if err != nil {
+		return "", err
+	}
+	v.name, v.expires = name, time.Now().Add(indexVersionTTL)
+	return name, nil
+}
+
+// notModified sets ETag and Cache-Control of endpoint response and answers 304 when client has it cached.
+// ETag covers index version, request URI, negotiated schema and parts selected per client like ranking profile
+func (i *Importer) notModified(w http.ResponseWriter, r *http.Request, endpoint string, parts ...string) bool {
+	if cc := i.config.API.CacheControl[endpoint]; cc != "" {
+		w.Header().Set("Cache-Control", cc)
+	}
+	version, err := i.indexVersion(r.Context())
+	if err != nil {
+		i.logger.Debugf("no etag, index version unknown: %v", err)
+		return false
+	}
+	h := sha1.New()
+	for _, part := range append([]string{version, r.URL.RequestURI(), strconv.Itoa(schemaVersion(r)), strconv.FormatBool(wantsProtobuf(r))}, parts...) {
+		io.WriteString(h, part)
+		h.Write([]byte{0})
+	}
+	etag := `"` + hex.EncodeToString(h.Sum(nil)) + `"`
+	w.Header().Set("ETag", etag)
+	if !etagMatch(r.Header.Get("If-None-Match"), etag) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// uncacheable drops caching headers of responses which must not be reused, like errors and partial results
+func uncacheable(w http.ResponseWriter) {
+	w.Header().Del("ETag")
+	w.Header().Set("Cache-Control", "no-store")
+}
+
+// etagMatch reports whether If-None-Match header lists etag, weak comparison is used
+func etagMatch(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
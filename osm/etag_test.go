@@ -0,0 +1,53 @@
+package osm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEtagMatch(t *testing.T) {
+	assert.True(t, etagMatch(`"a"`, `"a"`))
+	assert.True(t, etagMatch(`"b", W/"a"`, `"a"`))
+	assert.True(t, etagMatch(`*`, `"a"`))
+	assert.False(t, etagMatch(``, `"a"`))
+	assert.False(t, etagMatch(`"b"`, `"a"`))
+}
+
+func TestReverseNotModified(t *testing.T) {
+	storage := &memoryStorage{docs: map[string]model.Address{"way/1": {Name: "Иссык-Куль"}}}
+	c := &config.Ariadna{API: config.API{CacheControl: map[string]string{endpointReverse: "public, max-age=60"}}}
+	g, err := NewGeocoder(c, WithStorage(storage))
+	require.NoError(t, err)
+	i := g.i
+	i.metrics = newQueryMetrics(0, true)
+	router := httprouter.New()
+	router.GET("/api/reverse/:lat/:lon", i.reverseGeoCodeHandler)
+	handler := i.withSchema(router)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/reverse/42.5/77.5", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	assert.Equal(t, "public, max-age=60", w.Header().Get("Cache-Control"))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/reverse/42.5/77.5", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/reverse/42.5/77.5", nil)
+	req.Header.Set("If-None-Match", etag)
+	req.Header.Set("Accept", "application/vnd.ariadna.v1+json")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
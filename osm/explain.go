@@ -0,0 +1,51 @@
+package osm
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/model"
+)
+
+// searchDebug is answer of search with debug=true
+type searchDebug struct {
+	Query   string `json:"query"`
+	Profile string `json:"profile"`
+	// Addresses are answer of the search, explanation is missing when query is resolved
+	// without full text search, e.g. coordinates or ids
+	Addresses []model.Address `json:"addresses"`
+	*elastic.Explanation
+}
+
+// explainSearch answers search for clients with debug key by the addresses found, the request
+// sent to index, tokens of analyzed query text and explanation of score of every hit. Query
+// goes the same way as search without debug, so coordinates, ids and unit are handled alike
+func (i *Importer) explainSearch(w http.ResponseWriter, r *http.Request, query, profileName string, profile config.RankingProfile) {
+	if status, e := keyAllowed(r, i.config.API.DebugKeys, "search debug"); status != http.StatusOK {
+		i.writeFailure(w, r, status, e)
+		return
+	}
+	debug := searchDebug{Query: query, Profile: profileName}
+	explain := func(ctx context.Context, text string, profile config.RankingProfile) (*elastic.Result, error) {
+		explanation, err := i.e.ExplainSearch(ctx, text, profile)
+		if err != nil {
+			return nil, err
+		}
+		debug.Query, debug.Explanation = text, explanation
+		result := &elastic.Result{Addresses: make([]model.Address, 0, len(explanation.Hits)), TimedOut: explanation.TimedOut}
+		for _, hit := range explanation.Hits {
+			result.Addresses = append(result.Addresses, hit.Address)
+		}
+		return result, nil
+	}
+	result, err := i.geocodeWith(r.Context(), query, r.URL.Query().Get("unit"), profile, explain)
+	if err != nil {
+		i.writeError(w, r, err)
+		return
+	}
+	debug.Addresses = withCodes(result.Addresses)
+	uncacheable(w)
+	i.writeJSON(w, http.StatusOK, debug)
+}
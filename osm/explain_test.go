@@ -0,0 +1,91 @@
+package osm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchDebug(t *testing.T) {
+	g, err := NewGeocoder(&config.Ariadna{API: config.API{DebugKeys: []string{"operator"}}}, WithStorage(&memoryStorage{}))
+	require.NoError(t, err)
+	router := httprouter.New()
+	router.GET("/api/search/:query", g.i.geoCodeHandler)
+	search := func(key string) *httptest.ResponseRecorder {
+		return getWithKey(router, "/api/search/Киевская%201%20кв%205?debug=true", key)
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, search("").Code)
+	assert.Equal(t, http.StatusForbidden, search("client").Code)
+	w := search("operator")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	var debug map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &debug))
+	assert.Equal(t, "Киевская 1", debug["query"], "unit is not searched")
+	assert.Equal(t, "default", debug["profile"])
+	assert.Contains(t, debug, "request")
+	assert.Contains(t, debug, "analysis")
+}
+
+// explainingStorage finds one house for any query and one place around any point
+type explainingStorage struct {
+	*memoryStorage
+}
+
+func (s explainingStorage) ExplainSearch(ctx context.Context, query string, profile config.RankingProfile) (*elastic.Explanation, error) {
+	return &elastic.Explanation{Request: []byte(`{}`), Hits: []elastic.ExplainedHit{
+		{Address: model.Address{Street: "Киевская", HouseNumber: "1"}, Score: 1},
+	}}, nil
+}
+
+func (s explainingStorage) Reverse(ctx context.Context, lat, lon float64) (*elastic.Result, error) {
+	return &elastic.Result{Addresses: []model.Address{{Name: "Ала-Тоо", Location: model.Location{Lat: lat, Lon: lon}}}}, nil
+}
+
+func TestSearchDebugFollowsSearch(t *testing.T) {
+	c := &config.Ariadna{API: config.API{DebugKeys: []string{"operator"}}}
+	g, err := NewGeocoder(c, WithStorage(explainingStorage{&memoryStorage{}}))
+	require.NoError(t, err)
+	router := httprouter.New()
+	router.GET("/api/search/:query", g.i.geoCodeHandler)
+	debug := func(path string) (map[string]interface{}, []model.Address) {
+		w := getWithKey(router, path, "operator")
+		require.Equal(t, http.StatusOK, w.Code)
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		var answer struct {
+			Addresses []model.Address `json:"addresses"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &answer))
+		return body, answer.Addresses
+	}
+
+	body, addresses := debug("/api/search/Киевская%201?debug=true&unit=7")
+	assert.Contains(t, body, "hits")
+	require.Len(t, addresses, 1)
+	assert.Equal(t, "7", addresses[0].Unit, "unit parameter applies")
+
+	body, addresses = debug("/api/search/42.87,74.59?debug=true")
+	assert.NotContains(t, body, "request", "coordinates are reverse geocoded without full text search")
+	assert.Equal(t, "42.87,74.59", body["query"])
+	require.Len(t, addresses, 1)
+	assert.Equal(t, "Ала-Тоо", addresses[0].Name)
+}
+
+func getWithKey(router http.Handler, path, key string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	r.Header.Set("X-Api-Key", key)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
@@ -0,0 +1,121 @@
+package osm
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/model"
+	v1 "github.com/maddevsio/ariadna/schema/v1"
+)
+
+const (
+	exportQuery      = "export"
+	exportFlushEvery = 100
+)
+
+// searchOrExportHandler serves export at /api/search/export, httprouter can't register static
+// path beside the query parameter of search
+func (i *Importer) searchOrExportHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if ps.ByName("query") == exportQuery {
+		i.exportHandler(w, r, ps)
+		return
+	}
+	i.geoCodeHandler(w, r, ps)
+}
+
+// exportHandler streams every document matching query as NDJSON for clients with export key.
+// Documents are selected by q text, repeated layer and category, filter of extracted fields and
+// bbox of min_lon,min_lat,max_lon,max_lat. Failure after the first document is written as
+// error line, since status is already sent
+func (i *Importer) exportHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if status, e := keyAllowed(r, i.config.API.ExportKeys, "export"); status != http.StatusOK {
+		i.writeFailure(w, r, status, e)
+		return
+	}
+	q, err := i.parseExportQuery(r)
+	if err != nil {
+		i.writeFailure(w, r, http.StatusBadRequest, BadRequest{Error: err.Error(), Code: "invalid_request"})
+		return
+	}
+	var stream *ndjsonStream
+	err = i.e.Export(r.Context(), q, func(a model.Address) error {
+		if stream == nil {
+			stream = newNDJSONStream(w, exportFlushEvery)
+		}
+		return stream.write(exportDocument(r, a))
+	})
+	if stream == nil {
+		if err != nil {
+			i.writeError(w, r, err)
+			return
+		}
+		// nothing matched, the stream is empty
+		newNDJSONStream(w, exportFlushEvery)
+		return
+	}
+	if err != nil && r.Context().Err() == nil {
+		status, code := errorStatus(err)
+		if status == http.StatusInternalServerError {
+			i.logger.Error(err)
+		}
+		stream.write(batchResult{Error: &BadRequest{Error: err.Error(), Code: code}})
+	}
+	stream.flush()
+}
+
+// exportDocument converts document to negotiated schema like search results
+func exportDocument(r *http.Request, a model.Address) interface{} {
+	a = withViewports(labeled(r, []model.Address{a}))[0]
+	if schemaVersion(r) == v1.Version {
+		return v1.NewAddress(a)
+	}
+	return a
+}
+
+func (i *Importer) parseExportQuery(r *http.Request) (elastic.ExportQuery, error) {
+	params := r.URL.Query()
+	q := elastic.ExportQuery{Text: strings.TrimSpace(params.Get("q")), Categories: params["category"]}
+	for _, name := range params["layer"] {
+		layer, ok := importLayers[strings.ToLower(name)]
+		if !ok {
+			return q, fmt.Errorf("unknown layer %q", name)
+		}
+		q.Layers = append(q.Layers, layer)
+	}
+	filters, err := parseFilters(params["filter"], i.config.Fields)
+	if err != nil {
+		return q, err
+	}
+	q.Filters = filters
+	if s := params.Get("bbox"); s != "" {
+		if q.BBox, err = parseBBox(s); err != nil {
+			return q, err
+		}
+	}
+	return q, nil
+}
+
+// parseBBox parses min_lon,min_lat,max_lon,max_lat
+func parseBBox(s string) (*model.BBox, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("bbox %q is not min_lon,min_lat,max_lon,max_lat", s)
+	}
+	var v [4]float64
+	for n, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("bbox %q is not min_lon,min_lat,max_lon,max_lat", s)
+		}
+		v[n] = f
+	}
+	b := &model.BBox{MinLon: v[0], MinLat: v[1], MaxLon: v[2], MaxLat: v[3]}
+	if b.MinLat > b.MaxLat || b.MinLon > b.MaxLon || b.MinLat < -90 || b.MaxLat > 90 || b.MinLon < -180 || b.MaxLon > 180 {
+		return nil, fmt.Errorf("bbox %q is out of range", s)
+	}
+	return b, nil
+}
@@ -0,0 +1,58 @@
+package osm
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExport(t *testing.T) {
+	storage := &memoryStorage{docs: map[string]model.Address{
+		"node/1": {Name: "Неман", Category: "pharmacy", Location: model.Location{Lat: 42.87, Lon: 74.59}},
+		"node/2": {Name: "Ала-Тоо", Layer: layerTransit},
+		"3":      {Street: "Киевская", HouseNumber: "1"},
+	}}
+	g, err := NewGeocoder(&config.Ariadna{API: config.API{ExportKeys: []string{"analyst"}}}, WithStorage(storage))
+	require.NoError(t, err)
+	router := httprouter.New()
+	router.GET("/api/search/:query", g.i.searchOrExportHandler)
+
+	for key, status := range map[string]int{"": http.StatusUnauthorized, "client": http.StatusForbidden} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/search/export?layer=poi&api_key="+key, nil))
+		assert.Equal(t, status, w.Code, key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/search/export?layer=pois&bbox=74,42,75,43", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/search/export?layer=pois&bbox=74,42,75,43", nil)
+	r.Header.Set("X-Api-Key", "analyst")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, ndjsonMediaType, w.Header().Get("Content-Type"))
+	var docs []model.Address
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var doc model.Address
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &doc))
+		docs = append(docs, doc)
+	}
+	require.Len(t, docs, 1)
+	assert.Equal(t, "node/1", docs[0].ID)
+
+	r = httptest.NewRequest(http.MethodGet, "/api/search/export?bbox=75,42,74,43", nil)
+	r.Header.Set("X-Api-Key", "analyst")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
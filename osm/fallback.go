@@ -0,0 +1,487 @@
+package osm
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	providerNominatim = "nominatim"
+	providerGoogle    = "google"
+
+	defaultNominatimURL    = "https://nominatim.openstreetmap.org"
+	defaultGoogleURL       = "https://maps.googleapis.com/maps/api/geocode/json"
+	defaultFallbackRate    = 1.0
+	defaultFallbackCache   = 10000
+	defaultFallbackTTL     = 24 * time.Hour
+	defaultFallbackTimeout = 2 * time.Second
+	fallbackLimit          = 5
+)
+
+// ExternalGeocoder answers queries own index has no results for
+type ExternalGeocoder interface {
+	Geocode(ctx context.Context, query string) ([]model.Address, error)
+}
+
+// fallback asks external geocoder within rate limit and caches its answers.
+// Found addresses are flagged with source
+type fallback struct {
+	geocoder ExternalGeocoder
+	source   string
+	limiter  *rateLimiter
+	cache    *addressCache
+	timeout  time.Duration
+	logger   logrus.FieldLogger
+	// refreshing holds queries of stale answers refreshed in background
+	refreshing sync.Map
+}
+
+// WithFallback makes importer and geocoder ask g when index has no results, addresses found
+// by g are flagged with source. Rate and cache settings are taken from fallback config
+func WithFallback(source string, g ExternalGeocoder) Option {
+	return func(i *Importer) {
+		i.fallback = newFallback(i.config.Fallback, source, g)
+	}
+}
+
+func newFallback(c config.Fallback, source string, g ExternalGeocoder) *fallback {
+	rate := c.Rate
+	if rate <= 0 {
+		rate = defaultFallbackRate
+	}
+	burst := c.Burst
+	if burst <= 0 {
+		burst = 1
+	}
+	size := c.CacheSize
+	if size <= 0 {
+		size = defaultFallbackCache
+	}
+	ttl := c.CacheTTL
+	if ttl <= 0 {
+		ttl = defaultFallbackTTL
+	}
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = defaultFallbackTimeout
+	}
+	return &fallback{
+		geocoder: g,
+		source:   source,
+		limiter:  newRateLimiter(rate, burst),
+		cache:    newAddressCache(size, ttl, c.StaleTTL),
+		timeout:  timeout,
+		logger:   logrus.StandardLogger(),
+	}
+}
+
+// configuredFallback creates fallback of configured provider, nil when none is configured
+func configuredFallback(c config.Fallback) (*fallback, error) {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = defaultFallbackTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+	switch c.Provider {
+	case "":
+		return nil, nil
+	case providerNominatim:
+		base := c.URL
+		if base == "" {
+			base = defaultNominatimURL
+		}
+		return newFallback(c, providerNominatim, &nominatim{client: client, url: base, userAgent: c.UserAgent}), nil
+	case providerGoogle:
+		base := c.URL
+		if base == "" {
+			base = defaultGoogleURL
+		}
+		return newFallback(c, providerGoogle, &google{client: client, url: base, key: c.Key}), nil
+	}
+	return nil, fmt.Errorf("unknown fallback provider %q", c.Provider)
+}
+
+// setupFallback creates configured fallback unless it is set by option
+func (i *Importer) setupFallback() error {
+	if i.fallback == nil {
+		f, err := configuredFallback(i.config.Fallback)
+		if err != nil || f == nil {
+			return err
+		}
+		i.fallback = f
+	}
+	i.fallback.logger = i.logger
+	return nil
+}
+
+// geocode returns cached or fresh answer of external geocoder. Stale answer is returned
+// at once and refreshed in background. False means the query was not sent because of rate limit
+func (f *fallback) geocode(ctx context.Context, query string) ([]model.Address, bool, error) {
+	key := strings.ToLower(strings.TrimSpace(query))
+	if addresses, fresh, ok := f.cache.getStale(key); ok {
+		if !fresh {
+			f.revalidate(key, query)
+		}
+		return addresses, true, nil
+	}
+	if !f.limiter.allow() {
+		return nil, false, nil
+	}
+	addresses, err := f.ask(ctx, key, query)
+	if err != nil {
+		return nil, true, err
+	}
+	return addresses, true, nil
+}
+
+// ask sends query to external geocoder and caches its answer
+func (f *fallback) ask(ctx context.Context, key, query string) ([]model.Address, error) {
+	addresses, err := f.geocoder.Geocode(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	for n := range addresses {
+		addresses[n].Source = f.source
+	}
+	// callers append to returned addresses, cache keeps own copy
+	f.cache.put(key, append([]model.Address(nil), addresses...))
+	return addresses, nil
+}
+
+// revalidate refreshes stale answer of query in background unless it is refreshed already or
+// rate limit is reached. Stale answer is kept when external geocoder fails
+func (f *fallback) revalidate(key, query string) {
+	if _, refreshing := f.refreshing.LoadOrStore(key, struct{}{}); refreshing {
+		return
+	}
+	if !f.limiter.allow() {
+		f.refreshing.Delete(key)
+		return
+	}
+	go func() {
+		defer f.refreshing.Delete(key)
+		ctx, cancel := context.WithTimeout(context.Background(), f.timeout)
+		defer cancel()
+		if _, err := f.ask(ctx, key, query); err != nil {
+			f.logger.Warnf("fallback %s: refresh %q: %v", f.source, query, err)
+		}
+	}()
+}
+
+// withFallback adds answer of external geocoder to empty result. Failures of external
+// geocoder are logged, own result is returned then
+func (i *Importer) withFallback(ctx context.Context, query string, result *elastic.Result) *elastic.Result {
+	if i.fallback == nil || len(result.Addresses) > 0 || result.TimedOut {
+		return result
+	}
+	addresses, asked, err := i.fallback.geocode(ctx, query)
+	if err != nil {
+		i.logger.Warnf("fallback %s: %v", i.fallback.source, err)
+		return result
+	}
+	if !asked {
+		i.logger.Debugf("fallback %s: rate limited", i.fallback.source)
+		return result
+	}
+	result.Addresses = append(result.Addresses, addresses...)
+	return result
+}
+
+// rateLimiter is token bucket allowing rate requests per second with bursts
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{rate: rate, burst: float64(burst), tokens: float64(burst), now: time.Now}
+}
+
+func (l *rateLimiter) allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill()
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// wait takes n tokens, sleeping until bucket is replenished when it falls short of them
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	l.refill()
+	l.tokens -= float64(n)
+	delay := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (l *rateLimiter) refill() {
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+}
+
+// addressCache keeps recent addresses by key for ttl, the least recently used are evicted.
+// Expired addresses are kept for stale period more to be served while they are refreshed
+type addressCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	stale   time.Duration
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type addressEntry struct {
+	key       string
+	addresses []model.Address
+	expires   time.Time
+}
+
+func newAddressCache(size int, ttl, stale time.Duration) *addressCache {
+	return &addressCache{size: size, ttl: ttl, stale: stale, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// get returns addresses of key unless they expired
+func (c *addressCache) get(key string) ([]model.Address, bool) {
+	addresses, fresh, ok := c.getStale(key)
+	return addresses, ok && fresh
+}
+
+// getStale returns addresses of key and whether they are fresh, expired addresses are returned
+// within stale period
+func (c *addressCache) getStale(key string) ([]model.Address, bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false, false
+	}
+	entry := e.Value.(*addressEntry)
+	now := time.Now()
+	if now.After(entry.expires.Add(c.stale)) {
+		c.order.Remove(e)
+		delete(c.entries, key)
+		return nil, false, false
+	}
+	c.order.MoveToFront(e)
+	return append([]model.Address(nil), entry.addresses...), !now.After(entry.expires), true
+}
+
+func (c *addressCache) put(key string, addresses []model.Address) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.order.Remove(e)
+	}
+	c.entries[key] = c.order.PushFront(&addressEntry{key: key, addresses: addresses, expires: time.Now().Add(c.ttl)})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*addressEntry).key)
+	}
+}
+
+// nominatim queries Nominatim search API
+type nominatim struct {
+	client    *http.Client
+	url       string
+	userAgent string
+}
+
+type nominatimPlace struct {
+	Lat     string `json:"lat"`
+	Lon     string `json:"lon"`
+	Name    string `json:"name"`
+	Address struct {
+		HouseNumber string `json:"house_number"`
+		Road        string `json:"road"`
+		Suburb      string `json:"suburb"`
+		City        string `json:"city"`
+		Town        string `json:"town"`
+		Village     string `json:"village"`
+		Country     string `json:"country"`
+	} `json:"address"`
+}
+
+func (n *nominatim) Geocode(ctx context.Context, query string) ([]model.Address, error) {
+	params := url.Values{
+		"q":              {query},
+		"format":         {"jsonv2"},
+		"addressdetails": {"1"},
+		"limit":          {strconv.Itoa(fallbackLimit)},
+	}
+	req, err := http.NewRequest(http.MethodGet, strings.TrimRight(n.url, "/")+"/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if n.userAgent != "" {
+		// usage policy of public Nominatim requires identifying user agent
+		req.Header.Set("User-Agent", n.userAgent)
+	}
+	var places []nominatimPlace
+	if err := getJSON(n.client, req.WithContext(ctx), &places); err != nil {
+		return nil, err
+	}
+	addresses := make([]model.Address, 0, len(places))
+	for _, p := range places {
+		lat, err := strconv.ParseFloat(p.Lat, 64)
+		if err != nil {
+			continue
+		}
+		lon, err := strconv.ParseFloat(p.Lon, 64)
+		if err != nil {
+			continue
+		}
+		addresses = append(addresses, model.Address{
+			Name:        p.Name,
+			Street:      p.Address.Road,
+			HouseNumber: p.Address.HouseNumber,
+			District:    p.Address.Suburb,
+			City:        p.Address.City,
+			Town:        p.Address.Town,
+			Village:     p.Address.Village,
+			Country:     p.Address.Country,
+			Location:    model.Location{Lat: lat, Lon: lon},
+		})
+	}
+	return addresses, nil
+}
+
+// google queries Google Geocoding API
+type google struct {
+	client *http.Client
+	url    string
+	key    string
+}
+
+type googleResponse struct {
+	Status       string `json:"status"`
+	ErrorMessage string `json:"error_message"`
+	Results      []struct {
+		FormattedAddress string `json:"formatted_address"`
+		Geometry         struct {
+			Location struct {
+				Lat float64 `json:"lat"`
+				Lng float64 `json:"lng"`
+			} `json:"location"`
+		} `json:"geometry"`
+		AddressComponents []struct {
+			LongName string   `json:"long_name"`
+			Types    []string `json:"types"`
+		} `json:"address_components"`
+	} `json:"results"`
+}
+
+func (g *google) Geocode(ctx context.Context, query string) ([]model.Address, error) {
+	params := url.Values{"address": {query}, "key": {g.key}}
+	req, err := http.NewRequest(http.MethodGet, g.url+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var res googleResponse
+	if err := getJSON(g.client, req.WithContext(ctx), &res); err != nil {
+		return nil, redactKey(err)
+	}
+	switch res.Status {
+	case "OK", "ZERO_RESULTS":
+	default:
+		return nil, fmt.Errorf("status %s: %s", res.Status, res.ErrorMessage)
+	}
+	addresses := make([]model.Address, 0, len(res.Results))
+	for n, r := range res.Results {
+		if n == fallbackLimit {
+			break
+		}
+		address := model.Address{
+			Name:     r.FormattedAddress,
+			Location: model.Location{Lat: r.Geometry.Location.Lat, Lon: r.Geometry.Location.Lng},
+		}
+		for _, c := range r.AddressComponents {
+			if len(c.Types) == 0 {
+				continue
+			}
+			switch c.Types[0] {
+			case "street_number":
+				address.HouseNumber = c.LongName
+			case "route":
+				address.Street = c.LongName
+			case "sublocality", "neighborhood":
+				address.District = c.LongName
+			case "locality":
+				address.City = c.LongName
+			case "country":
+				address.Country = c.LongName
+			}
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, nil
+}
+
+// redactKey hides API key in URL of failed request, errors of HTTP client include it
+func redactKey(err error) error {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return err
+	}
+	u, perr := url.Parse(urlErr.URL)
+	if perr != nil {
+		urlErr.URL = ""
+		return err
+	}
+	if q := u.Query(); q.Get("key") != "" {
+		q.Set("key", "REDACTED")
+		u.RawQuery = q.Encode()
+		urlErr.URL = u.String()
+	}
+	return err
+}
+
+func getJSON(client *http.Client, req *http.Request, v interface{}) error {
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: unexpected status %s", req.URL.Host, res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
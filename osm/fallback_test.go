@@ -0,0 +1,145 @@
+package osm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Now()
+	l := newRateLimiter(2, 2)
+	l.now = func() time.Time { return now }
+	assert.True(t, l.allow())
+	assert.True(t, l.allow())
+	assert.False(t, l.allow())
+	now = now.Add(500 * time.Millisecond)
+	assert.True(t, l.allow())
+	assert.False(t, l.allow())
+}
+
+func TestNominatimFallback(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "/search", r.URL.Path)
+		assert.Equal(t, "Ош базар", r.URL.Query().Get("q"))
+		assert.Equal(t, "ariadna-test", r.Header.Get("User-Agent"))
+		w.Write([]byte(`[{"lat": "42.87", "lon": "74.56", "name": "Ош базары", "address": {"city": "Бишкек", "country": "Кыргызстан"}}]`))
+	}))
+	defer server.Close()
+	c := &config.Ariadna{Fallback: config.Fallback{Provider: providerNominatim, URL: server.URL, UserAgent: "ariadna-test"}}
+	g, err := NewGeocoder(c, WithStorage(&memoryStorage{docs: map[string]model.Address{}}))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for n := 0; n < 2; n++ {
+		result, err := g.Search(ctx, "Ош базар")
+		require.NoError(t, err)
+		require.Len(t, result.Addresses, 1)
+		assert.Equal(t, providerNominatim, result.Addresses[0].Source)
+		assert.Equal(t, "Бишкек", result.Addresses[0].City)
+		assert.InDelta(t, 74.56, result.Addresses[0].Location.Lon, 1e-9)
+	}
+	assert.Equal(t, 1, requests, "second answer is cached")
+
+	// default rate of one request per second is spent
+	_, err = g.Search(ctx, "Дордой")
+	assert.True(t, errors.Is(err, ErrNoResults))
+	assert.Equal(t, 1, requests)
+}
+
+// countingGeocoder answers with name of its n-th call
+type countingGeocoder struct {
+	calls int32
+	fail  int32
+}
+
+func (g *countingGeocoder) Geocode(ctx context.Context, query string) ([]model.Address, error) {
+	n := atomic.AddInt32(&g.calls, 1)
+	if atomic.LoadInt32(&g.fail) == 1 {
+		return nil, errors.New("quota exceeded")
+	}
+	return []model.Address{{Name: query + " " + strconv.Itoa(int(n))}}, nil
+}
+
+// waitFor polls condition for a second
+func waitFor(condition func() bool) bool {
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if condition() {
+			return true
+		}
+	}
+	return condition()
+}
+
+func TestStaleFallback(t *testing.T) {
+	external := &countingGeocoder{}
+	c := &config.Ariadna{Fallback: config.Fallback{Rate: 100, Burst: 10, CacheTTL: time.Millisecond, StaleTTL: time.Hour}}
+	g, err := NewGeocoder(c, WithStorage(&memoryStorage{docs: map[string]model.Address{}}), WithFallback("test", external))
+	require.NoError(t, err)
+	ctx := context.Background()
+	name := func() string {
+		result, err := g.Search(ctx, "Ош базар")
+		require.NoError(t, err)
+		require.Len(t, result.Addresses, 1)
+		return result.Addresses[0].Name
+	}
+
+	assert.Equal(t, "Ош базар 1", name())
+	time.Sleep(2 * time.Millisecond)
+	assert.Equal(t, "Ош базар 1", name(), "stale answer is served at once")
+	assert.True(t, waitFor(func() bool {
+		addresses, _, _ := g.i.fallback.cache.getStale("ош базар")
+		return len(addresses) == 1 && addresses[0].Name == "Ош базар 2"
+	}), "answer is refreshed in background")
+
+	atomic.StoreInt32(&external.fail, 1)
+	time.Sleep(2 * time.Millisecond)
+	assert.Equal(t, "Ош базар 2", name())
+	assert.True(t, waitFor(func() bool {
+		_, refreshing := g.i.fallback.refreshing.Load("ош базар")
+		return atomic.LoadInt32(&external.calls) == 3 && !refreshing
+	}))
+	assert.Equal(t, "Ош базар 2", name(), "failed refresh keeps stale answer")
+}
+
+func TestGoogleFallbackHidesKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+	g := &google{client: &http.Client{}, url: server.URL, key: "AIzaSecret"}
+	_, err := g.Geocode(context.Background(), "Ош базар")
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "AIzaSecret")
+	assert.Contains(t, err.Error(), "key=REDACTED")
+}
+
+func TestFallbackCachesCopy(t *testing.T) {
+	f := newFallback(config.Fallback{Rate: 100, Burst: 10}, "test", &countingGeocoder{})
+	addresses, err := f.ask(context.Background(), "ош базар", "Ош базар")
+	require.NoError(t, err)
+	addresses[0].Name = "changed"
+	cached, ok := f.cache.get("ош базар")
+	require.True(t, ok)
+	assert.Equal(t, "Ош базар 1", cached[0].Name)
+}
+
+func TestAddressCacheStale(t *testing.T) {
+	c := newAddressCache(10, time.Millisecond, 0)
+	c.put("a", []model.Address{{Name: "a"}})
+	_, ok := c.get("a")
+	assert.True(t, ok)
+	time.Sleep(2 * time.Millisecond)
+	_, _, ok = c.getStale("a")
+	assert.False(t, ok, "entries without stale period expire at ttl")
+}
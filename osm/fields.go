@@ -0,0 +1,127 @@
+package osm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/model"
+)
+
+// validateFields checks extraction rules, empty type means string
+func validateFields(fields []config.Field) error {
+	for _, f := range fields {
+		if f.Name == "" || f.Tag == "" {
+			return fmt.Errorf("field rule %+v: name and tag are required", f)
+		}
+		switch f.Type {
+		case "", config.FieldString, config.FieldNumber, config.FieldBool, config.FieldList:
+		default:
+			return fmt.Errorf("field %s: unknown type %q", f.Name, f.Type)
+		}
+	}
+	return nil
+}
+
+// parseFilters parses filters like "cuisine:georgian" of extracted fields. Values are coerced
+// to field type and kept in canonical form, values of the same field are alternatives
+func parseFilters(filters []string, fields []config.Field) (map[string][]string, error) {
+	if len(filters) == 0 {
+		return nil, nil
+	}
+	result := make(map[string][]string)
+	for _, filter := range filters {
+		n := strings.Index(filter, ":")
+		if n < 0 {
+			return nil, fmt.Errorf("filter %q is not field:value", filter)
+		}
+		value, err := filterValue(filter[:n], filter[n+1:], fields)
+		if err != nil {
+			return nil, err
+		}
+		result[filter[:n]] = append(result[filter[:n]], value)
+	}
+	return result, nil
+}
+
+// filterValue returns canonical value of filter of field
+func filterValue(name, raw string, fields []config.Field) (string, error) {
+	for _, f := range fields {
+		if f.Name != name {
+			continue
+		}
+		value, ok := coerce(f.Type, raw)
+		if !ok {
+			return "", fmt.Errorf("filter value %q is not %s", raw, fieldType(f))
+		}
+		switch v := value.(type) {
+		case bool:
+			return strconv.FormatBool(v), nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		case []string:
+			if len(v) > 1 {
+				return "", fmt.Errorf("filter value %q is not single value of %s", raw, name)
+			}
+			return v[0], nil
+		}
+		return value.(string), nil
+	}
+	return "", fmt.Errorf("unknown filter field %q", name)
+}
+
+func fieldType(f config.Field) string {
+	if f.Type == "" {
+		return config.FieldString
+	}
+	return f.Type
+}
+
+// extractFields projects tags into fields by configured rules.
+// Values which can't be coerced to field type are skipped
+func (i *Importer) extractFields(tags map[string]string) model.Fields {
+	var fields model.Fields
+	for _, f := range i.config.Fields {
+		raw, ok := tags[f.Tag]
+		if !ok {
+			continue
+		}
+		value, ok := coerce(f.Type, raw)
+		if !ok {
+			continue
+		}
+		if fields == nil {
+			fields = make(model.Fields)
+		}
+		fields[f.Name] = value
+	}
+	return fields
+}
+
+// coerce converts tag value to field type. Lists are semicolon separated as usual in OSM
+func coerce(kind, raw string) (interface{}, bool) {
+	raw = strings.TrimSpace(raw)
+	switch kind {
+	case config.FieldNumber:
+		n, err := strconv.ParseFloat(raw, 64)
+		return n, err == nil
+	case config.FieldBool:
+		switch strings.ToLower(raw) {
+		case "yes", "true", "1":
+			return true, true
+		case "no", "false", "0":
+			return false, true
+		}
+		return nil, false
+	case config.FieldList:
+		var values []string
+		for _, v := range strings.Split(raw, ";") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		return values, len(values) > 0
+	}
+	return raw, raw != ""
+}
@@ -0,0 +1,61 @@
+package osm
+
+import (
+	"testing"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractFields(t *testing.T) {
+	i := &Importer{config: &config.Ariadna{Fields: []config.Field{
+		{Name: "cuisine", Tag: "cuisine", Type: config.FieldList},
+		{Name: "seats", Tag: "capacity", Type: config.FieldNumber},
+		{Name: "wheelchair", Tag: "wheelchair", Type: config.FieldBool},
+		{Name: "operator", Tag: "operator"},
+		{Name: "stars", Tag: "stars", Type: config.FieldNumber},
+	}}}
+	fields := i.extractFields(map[string]string{
+		"cuisine":    "kyrgyz; uzbek",
+		"capacity":   "40",
+		"wheelchair": "yes",
+		"operator":   "Фаиза",
+		"stars":      "many",
+	})
+	assert.Equal(t, model.Fields{
+		"cuisine":    []string{"kyrgyz", "uzbek"},
+		"seats":      40.0,
+		"wheelchair": true,
+		"operator":   "Фаиза",
+	}, fields)
+	assert.Nil(t, i.extractFields(map[string]string{"name": "Фаиза"}))
+}
+
+func TestValidateFields(t *testing.T) {
+	assert.NoError(t, validateFields([]config.Field{{Name: "cuisine", Tag: "cuisine"}}))
+	assert.Error(t, validateFields([]config.Field{{Name: "cuisine", Tag: "cuisine", Type: "date"}}))
+	assert.Error(t, validateFields([]config.Field{{Tag: "cuisine"}}))
+}
+
+func TestParseFilters(t *testing.T) {
+	fields := []config.Field{
+		{Name: "cuisine", Tag: "cuisine", Type: config.FieldList},
+		{Name: "seats", Tag: "capacity", Type: config.FieldNumber},
+		{Name: "wheelchair", Tag: "wheelchair", Type: config.FieldBool},
+		{Name: "operator", Tag: "operator"},
+	}
+	filters, err := parseFilters([]string{"cuisine:georgian", "cuisine:kyrgyz", "wheelchair:yes", "seats:40.0", "operator:Фаиза"}, fields)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"cuisine":    {"georgian", "kyrgyz"},
+		"wheelchair": {"true"},
+		"seats":      {"40"},
+		"operator":   {"Фаиза"},
+	}, filters)
+
+	for _, filter := range []string{"cuisine", "stars:5", "seats:many", "wheelchair:maybe", "cuisine:a;b"} {
+		_, err := parseFilters([]string{filter}, fields)
+		assert.Error(t, err, filter)
+	}
+}
@@ -0,0 +1,64 @@
+package osm
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/maddevsio/ariadna/model"
+)
+
+const formatCSV = "csv"
+
+// callbackRe limits JSONP callbacks to dotted javascript identifiers, so callback can't inject script
+var callbackRe = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*(\.[A-Za-z_$][A-Za-z0-9_$]*)*$`)
+
+var csvHeader = []string{
+	"id", "name", "country", "city", "town", "village", "district", "prefix", "street", "housenumber",
+	"unit", "lat", "lon", "layer", "category", "plus_code",
+}
+
+// writeCSV writes addresses as CSV with header row
+func (i *Importer) writeCSV(w http.ResponseWriter, addresses []model.Address) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="results.csv"`)
+	w.WriteHeader(http.StatusOK)
+	cw := csv.NewWriter(w)
+	cw.Write(csvHeader)
+	for _, a := range addresses {
+		cw.Write(csvRow(a))
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		i.logger.Error(err)
+	}
+}
+
+func csvRow(a model.Address) []string {
+	return []string{
+		a.ID, a.Name, a.Country, a.City, a.Town, a.Village, a.District, a.Prefix, a.Street, a.HouseNumber,
+		a.Unit,
+		strconv.FormatFloat(a.Location.Lat, 'f', -1, 64),
+		strconv.FormatFloat(a.Location.Lon, 'f', -1, 64),
+		a.Layer, a.Category, a.PlusCode,
+	}
+}
+
+// writeJSONP writes v as argument of callback call for legacy script tag embeds
+func (i *Importer) writeJSONP(w http.ResponseWriter, callback string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		i.logger.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	// comment guards against content sniffing attacks on the callback name
+	w.Write([]byte("/**/" + callback + "("))
+	w.Write(data)
+	w.Write([]byte(");\n"))
+}
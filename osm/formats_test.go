@@ -0,0 +1,16 @@
+package osm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCallbackRe(t *testing.T) {
+	for _, callback := range []string{"cb", "jQuery3210_15", "window.app.onResult", "$"} {
+		assert.True(t, callbackRe.MatchString(callback), callback)
+	}
+	for _, callback := range []string{"", "alert(1)", "a;b", "a..b", "1cb", "</script>"} {
+		assert.False(t, callbackRe.MatchString(callback), callback)
+	}
+}
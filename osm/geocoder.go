@@ -0,0 +1,173 @@
+package osm
+
+import (
+	"context"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/sirupsen/logrus"
+)
+
+// Geocoder answers search and reverse queries in-process, so Go applications can geocode
+// without HTTP. It shares query handling with the web API. It lives here rather than in the
+// root package, which is the ariadna command
+type Geocoder struct {
+	i *Importer
+}
+
+// NewGeocoder creates geocoder over already imported index. Unlike NewImporter
+// it neither downloads nor parses OSM data
+func NewGeocoder(c *config.Ariadna, opts ...Option) (*Geocoder, error) {
+	i := &Importer{config: c, logger: logrus.New()}
+	for _, opt := range opts {
+		opt(i)
+	}
+	if i.e == nil {
+		e, err := elastic.New(c)
+		if err != nil {
+			return nil, err
+		}
+		i.e = e
+	}
+	if err := i.setupFallback(); err != nil {
+		return nil, err
+	}
+	i.reverseCache = newReverseCache(c.API.ReverseCache)
+	access, err := newAccessControl(c.API.Access)
+	if err != nil {
+		return nil, err
+	}
+	i.access = access
+	if i.messages, err = loadCatalogs(c.API.Messages); err != nil {
+		return nil, err
+	}
+	return &Geocoder{i: i}, nil
+}
+
+// Geocoder returns geocoder sharing storage and resolvers with importer
+func (i *Importer) Geocoder() *Geocoder {
+	return &Geocoder{i: i}
+}
+
+// SnapToRoad returns the nearest named road with location moved to the closest point of the road
+func (g *Geocoder) SnapToRoad(ctx context.Context, lat, lon float64) (*elastic.Result, error) {
+	result, err := g.i.snapToRoad(ctx, lat, lon)
+	if err != nil {
+		return nil, err
+	}
+	return found(result)
+}
+
+// Boundaries returns admin boundaries containing point, the outermost first.
+// ErrNoResults is returned when point is outside of imported boundaries
+func (g *Geocoder) Boundaries(ctx context.Context, lat, lon float64) (*elastic.Result, error) {
+	result, err := g.i.boundaries(ctx, lat, lon)
+	if err != nil {
+		return nil, err
+	}
+	return found(result)
+}
+
+// Country returns country boundary containing point without geometry, see countryHandler.
+// ErrNoResults is returned when point is outside of imported countries
+func (g *Geocoder) Country(ctx context.Context, lat, lon float64) (*elastic.Result, error) {
+	result, err := g.i.country(ctx, lat, lon)
+	if err != nil {
+		return nil, err
+	}
+	return found(result)
+}
+
+// RegisterResolver adds resolver of special query syntax, see Importer.RegisterResolver
+func (g *Geocoder) RegisterResolver(r QueryResolver) {
+	g.i.RegisterResolver(r)
+}
+
+// Search finds addresses matching query ranked by control profile of configured experiment.
+// ErrNoResults is returned when nothing matches
+func (g *Geocoder) Search(ctx context.Context, query string) (*elastic.Result, error) {
+	_, profile := g.i.profileByName(g.i.config.Ranking.Experiment.Control)
+	result, err := g.i.geocode(ctx, query, "", profile)
+	if err != nil {
+		return nil, err
+	}
+	return found(result)
+}
+
+// Reverse finds addresses nearest to point or natural features containing it.
+// ErrNoResults is returned when nothing is around
+func (g *Geocoder) Reverse(ctx context.Context, lat, lon float64) (*elastic.Result, error) {
+	result, err := g.i.lookup(ctx, lat, lon)
+	if err != nil {
+		return nil, err
+	}
+	return found(result)
+}
+
+// found fills codes of result addresses or reports ErrNoResults for empty result
+func found(result *elastic.Result) (*elastic.Result, error) {
+	if len(result.Addresses) == 0 && !result.TimedOut {
+		return nil, ErrNoResults
+	}
+	withCodes(result.Addresses)
+	return result, nil
+}
+
+// searchFunc runs full text search of query text ranked by profile
+type searchFunc func(ctx context.Context, text string, profile config.RankingProfile) (*elastic.Result, error)
+
+// geocode answers search query. Coordinates and codes are reverse geocoded,
+// "X near Y" finds transit stops, unit overrides unit parsed from query
+func (i *Importer) geocode(ctx context.Context, query, unit string, profile config.RankingProfile) (*elastic.Result, error) {
+	return i.geocodeWith(ctx, query, unit, profile, i.e.SearchRanked)
+}
+
+// geocodeWith answers search query like geocode with full text search run by search
+func (i *Importer) geocodeWith(ctx context.Context, query, unit string, profile config.RankingProfile, search searchFunc) (*elastic.Result, error) {
+	if _, ok := documentIDs(query); ok {
+		return i.lookupIDs(ctx, []string{query})
+	}
+	lat, lon, ok, err := i.resolve(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return i.lookup(ctx, lat, lon)
+	}
+	if _, place, ok := splitNear(query); ok {
+		return i.searchTransitNear(ctx, place)
+	}
+	text, parsed := splitUnit(query)
+	if unit == "" {
+		unit = parsed
+	}
+	result, err := search(ctx, text, profile)
+	if err != nil {
+		return nil, err
+	}
+	result.Addresses = preferJunctions(withUnit(result.Addresses, unit))
+	return i.withFallback(ctx, query, result), nil
+}
+
+// lookup returns addresses around point, falling back to features containing it.
+// Results are cached by geohash of point when reverse cache is configured
+func (i *Importer) lookup(ctx context.Context, lat, lon float64) (*elastic.Result, error) {
+	key := i.reverseCacheKey(ctx, lat, lon)
+	if addresses, ok := i.reverseCache.get(key); ok {
+		return &elastic.Result{Addresses: addresses}, nil
+	}
+	result, err := i.e.Reverse(ctx, lat, lon)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Addresses) == 0 && !result.TimedOut {
+		// point may be over water or out of town without addresses around
+		if result, err = i.reverseFallback(ctx, lat, lon); err != nil {
+			return nil, err
+		}
+	}
+	if !result.TimedOut {
+		i.reverseCache.put(key, result.Addresses)
+	}
+	return result, nil
+}
@@ -0,0 +1,264 @@
+package osm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/boundaries"
+	"github.com/maddevsio/ariadna/elastic"
+	v1 "github.com/maddevsio/ariadna/schema/v1"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+const (
+	// defaultGeofenceRefresh is interval fences changed through other instances are reloaded in
+	defaultGeofenceRefresh = 30 * time.Second
+	// maxGeofenceBody caps size of registered GeoJSON in bytes
+	maxGeofenceBody = 10 << 20
+	maxGeofenceName = 128
+)
+
+// geofenceIndex keeps registered fences in memory, so points are checked without query to
+// storage. Fences are reloaded after refresh interval and after writes of this instance
+type geofenceIndex struct {
+	mu     sync.RWMutex
+	loaded time.Time
+	index  *boundaries.Index
+	// fences are registered fences without geometry by name
+	fences map[string]elastic.Geofence
+}
+
+// geofences returns index of fences and fences by name, loading them when they are stale
+func (i *Importer) geofences(r *http.Request) (*boundaries.Index, map[string]elastic.Geofence, error) {
+	refresh := i.config.Geofences.RefreshInterval
+	if refresh <= 0 {
+		refresh = defaultGeofenceRefresh
+	}
+	g := &i.geofenceIndex
+	g.mu.RLock()
+	index, fences := g.index, g.fences
+	fresh := index != nil && time.Since(g.loaded) < refresh
+	g.mu.RUnlock()
+	if fresh {
+		return index, fences, nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.index != nil && time.Since(g.loaded) < refresh {
+		// loaded by concurrent request
+		return g.index, g.fences, nil
+	}
+	list, err := i.e.Geofences(r.Context())
+	if err != nil {
+		return nil, nil, err
+	}
+	areas := make([]boundaries.Area, 0, len(list))
+	g.fences = make(map[string]elastic.Geofence, len(list))
+	for _, fence := range list {
+		areas = append(areas, boundaries.Area{ID: fence.Name, Name: fence.Name, Geometry: fence.Geometry})
+		fence.Geometry = nil
+		g.fences[fence.Name] = fence
+	}
+	g.index, g.loaded = boundaries.New(areas), time.Now()
+	return g.index, g.fences, nil
+}
+
+// invalidateGeofences makes the next check reload fences
+func (i *Importer) invalidateGeofences() {
+	g := &i.geofenceIndex
+	g.mu.Lock()
+	g.index = nil
+	g.mu.Unlock()
+}
+
+// geofencePutHandler registers fence of GeoJSON Feature or Polygon and MultiPolygon geometry
+// under name, fence of the same name is replaced. Changes require API key of geofences keys
+func (i *Importer) geofencePutHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if status, e := keyAllowed(r, i.config.Geofences.Keys, "geofence registration"); status != http.StatusOK {
+		i.writeFailure(w, r, status, e)
+		return
+	}
+	name := ps.ByName("name")
+	if err := validateGeofenceName(name); err != nil {
+		i.writeFailure(w, r, http.StatusBadRequest, BadRequest{Error: err.Error(), Code: "invalid_request"})
+		return
+	}
+	fence, err := parseGeofence(http.MaxBytesReader(w, r.Body, maxGeofenceBody))
+	if err != nil {
+		i.writeFailure(w, r, http.StatusBadRequest, BadRequest{Error: err.Error(), Code: "invalid_geofence"})
+		return
+	}
+	fence.Name, fence.UpdatedAt = name, time.Now().UTC()
+	created, err := i.e.PutGeofence(r.Context(), fence)
+	if err != nil {
+		i.writeError(w, r, err)
+		return
+	}
+	i.invalidateGeofences()
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	if schemaVersion(r) == v1.Version {
+		i.writeV1(w, status, v1.Geofences{SchemaVersion: v1.Version, Geofences: []v1.Geofence{newGeofence(fence)}})
+		return
+	}
+	i.writeJSON(w, status, newGeofence(fence))
+}
+
+// geofenceDeleteHandler removes fence by name
+func (i *Importer) geofenceDeleteHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if status, e := keyAllowed(r, i.config.Geofences.Keys, "geofence registration"); status != http.StatusOK {
+		i.writeFailure(w, r, status, e)
+		return
+	}
+	name := ps.ByName("name")
+	deleted, err := i.e.DeleteGeofence(r.Context(), name)
+	if err != nil {
+		i.writeError(w, r, err)
+		return
+	}
+	if !deleted {
+		i.writeFailure(w, r, http.StatusNotFound, BadRequest{Error: fmt.Sprintf("geofence %q not found", name), Code: "geofence_not_found"})
+		return
+	}
+	i.invalidateGeofences()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// geofencesHandler lists registered fences by name without geometry
+func (i *Importer) geofencesHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	_, fences, err := i.geofences(r)
+	if err != nil {
+		i.writeError(w, r, err)
+		return
+	}
+	names := make([]string, 0, len(fences))
+	for name := range fences {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	list := make([]v1.Geofence, 0, len(names))
+	for _, name := range names {
+		list = append(list, newGeofence(fences[name]))
+	}
+	i.writeGeofences(w, r, list)
+}
+
+// geofenceCheckHandler returns fences containing point, the outermost first
+func (i *Importer) geofenceCheckHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	lat, lon, err := parsePoint(ps)
+	if err != nil {
+		i.writeFailure(w, r, http.StatusBadRequest, BadRequest{Error: err.Error(), Code: "invalid_request"})
+		return
+	}
+	index, fences, err := i.geofences(r)
+	if err != nil {
+		i.writeError(w, r, err)
+		return
+	}
+	areas := index.Lookup(lat, lon)
+	list := make([]v1.Geofence, 0, len(areas))
+	for _, area := range areas {
+		list = append(list, newGeofence(fences[area.ID]))
+	}
+	i.writeGeofences(w, r, list)
+}
+
+// writeGeofences writes fences as array or response of negotiated schema
+func (i *Importer) writeGeofences(w http.ResponseWriter, r *http.Request, list []v1.Geofence) {
+	if schemaVersion(r) == v1.Version {
+		i.writeV1(w, http.StatusOK, v1.Geofences{SchemaVersion: v1.Version, Geofences: list})
+		return
+	}
+	i.writeJSON(w, http.StatusOK, list)
+}
+
+func newGeofence(fence elastic.Geofence) v1.Geofence {
+	return v1.Geofence{Name: fence.Name, Properties: fence.Properties, UpdatedAt: fence.UpdatedAt}
+}
+
+// validateGeofenceName allows letters, digits and -_.: in names, so they are safe in paths and ids
+func validateGeofenceName(name string) error {
+	if name == "" || len(name) > maxGeofenceName {
+		return fmt.Errorf("geofence name must be 1 to %d bytes long", maxGeofenceName)
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' && r != '.' && r != ':' {
+			return fmt.Errorf("invalid geofence name %q, only letters, digits and -_.: are allowed", name)
+		}
+	}
+	return nil
+}
+
+// parseGeofence reads fence of GeoJSON Feature with properties or bare geometry
+func parseGeofence(body io.Reader) (elastic.Geofence, error) {
+	var fence elastic.Geofence
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return fence, err
+	}
+	var feature struct {
+		Type       string                 `json:"type"`
+		Geometry   json.RawMessage        `json:"geometry"`
+		Properties map[string]interface{} `json:"properties"`
+	}
+	if err := json.Unmarshal(data, &feature); err != nil {
+		return fence, fmt.Errorf("invalid GeoJSON: %v", err)
+	}
+	if feature.Type == "Feature" {
+		data, fence.Properties = feature.Geometry, feature.Properties
+	}
+	g, err := geojson.UnmarshalGeometry(data)
+	if err != nil {
+		return fence, fmt.Errorf("invalid GeoJSON geometry: %v", err)
+	}
+	if err := validateFenceGeometry(g); err != nil {
+		return fence, err
+	}
+	fence.Geometry = g
+	return fence, nil
+}
+
+// validateFenceGeometry checks that geometry is polygon or multipolygon of closed rings
+func validateFenceGeometry(g *geojson.Geometry) error {
+	var polygons [][][][]float64
+	switch {
+	case g.IsPolygon():
+		polygons = [][][][]float64{g.Polygon}
+	case g.IsMultiPolygon():
+		polygons = g.MultiPolygon
+	default:
+		return fmt.Errorf("geofence geometry must be Polygon or MultiPolygon, got %s", g.Type)
+	}
+	if len(polygons) == 0 {
+		return fmt.Errorf("geofence geometry is empty")
+	}
+	for _, polygon := range polygons {
+		if len(polygon) == 0 {
+			return fmt.Errorf("geofence polygon has no rings")
+		}
+		for _, ring := range polygon {
+			if len(ring) < 4 {
+				return fmt.Errorf("geofence ring must have at least 4 positions")
+			}
+			for _, p := range ring {
+				if len(p) < 2 || p[0] < -180 || p[0] > 180 || p[1] < -90 || p[1] > 90 {
+					return fmt.Errorf("invalid geofence position %v", p)
+				}
+			}
+			first, last := ring[0], ring[len(ring)-1]
+			if first[0] != last[0] || first[1] != last[1] {
+				return fmt.Errorf("geofence ring is not closed")
+			}
+		}
+	}
+	return nil
+}
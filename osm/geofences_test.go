@@ -0,0 +1,81 @@
+package osm
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/config"
+	v1 "github.com/maddevsio/ariadna/schema/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeofences(t *testing.T) {
+	storage := &memoryStorage{}
+	g, err := NewGeocoder(&config.Ariadna{Geofences: config.Geofences{Keys: []string{"dispatch"}}}, WithStorage(storage))
+	require.NoError(t, err)
+	router := httprouter.New()
+	router.GET("/api/geofences", g.i.geofencesHandler)
+	router.PUT("/api/geofences/:name", g.i.geofencePutHandler)
+	router.DELETE("/api/geofences/:name", g.i.geofenceDeleteHandler)
+	router.GET("/api/geofence/check/:lat/:lon", g.i.geofenceCheckHandler)
+	do := func(method, path, key, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		if key != "" {
+			r.Header.Set("X-Api-Key", key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	city := `{"type":"Feature","properties":{"zone":"A"},"geometry":{"type":"Polygon","coordinates":[[[74.5,42.8],[74.7,42.8],[74.7,42.9],[74.5,42.9],[74.5,42.8]]]}}`
+	depot := `{"type":"MultiPolygon","coordinates":[[[[74.58,42.86],[74.6,42.86],[74.6,42.88],[74.58,42.88],[74.58,42.86]]]]}`
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodPut, "/api/geofences/city", "", city).Code)
+	assert.Equal(t, http.StatusForbidden, do(http.MethodPut, "/api/geofences/city", "client", city).Code)
+	assert.Equal(t, http.StatusCreated, do(http.MethodPut, "/api/geofences/city", "dispatch", city).Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodPut, "/api/geofences/city", "dispatch", city).Code)
+	assert.Equal(t, http.StatusCreated, do(http.MethodPut, "/api/geofences/depot-1", "dispatch", depot).Code)
+	for name, body := range map[string]string{
+		"point":   `{"type":"Point","coordinates":[74.5,42.8]}`,
+		"open":    `{"type":"Polygon","coordinates":[[[74.5,42.8],[74.7,42.8],[74.7,42.9],[74.5,42.9]]]}`,
+		"bad:lat": `{"type":"Polygon","coordinates":[[[74.5,92.8],[74.7,42.8],[74.7,42.9],[74.5,92.8]]]}`,
+		"json":    `{"type":`,
+	} {
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, "/api/geofences/"+name, "dispatch", body).Code, name)
+	}
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, "/api/geofences/a%20b", "dispatch", city).Code)
+
+	w := do(http.MethodGet, "/api/geofence/check/42.87/74.59", "", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var fences []v1.Geofence
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &fences))
+	require.Len(t, fences, 2)
+	assert.Equal(t, "city", fences[0].Name)
+	assert.Equal(t, "A", fences[0].Properties["zone"])
+	assert.Equal(t, "depot-1", fences[1].Name)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/geofence/check/42.85/74.55", nil)
+	r.Header.Set("Accept", v1.MediaType)
+	w = httptest.NewRecorder()
+	g.i.withSchema(router).ServeHTTP(w, r)
+	var response v1.Geofences
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, v1.Version, response.SchemaVersion)
+	require.Len(t, response.Geofences, 1)
+	assert.Equal(t, "city", response.Geofences[0].Name)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodGet, "/api/geofence/check/92/74.55", "", "").Code)
+
+	assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/api/geofences/city", "dispatch", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/api/geofences/city", "dispatch", "").Code)
+	w = do(http.MethodGet, "/api/geofences", "", "")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &fences))
+	require.Len(t, fences, 1)
+	assert.Equal(t, "depot-1", fences[0].Name)
+	w = do(http.MethodGet, "/api/geofence/check/42.85/74.55", "", "")
+	assert.JSONEq(t, `[]`, w.Body.String())
+}
@@ -7,6 +7,9 @@ import (
 	"github.com/missinglink/gosmparse"
 )
 
+// roleCountry is role of administrative boundaries of countries
+const roleCountry = "country"
+
 // Handler - Load all elements in to memory
 type Handler struct {
 	mu            *sync.Mutex
@@ -16,14 +19,31 @@ type Handler struct {
 	Ways          map[int64]gosmparse.Way
 	FullWays      map[int64]gosmparse.Way
 
-	WayNames     map[string]string
-	Areas        map[int64]gosmparse.Relation
-	Districts    map[int64]gosmparse.Way
-	Countries    map[int64]gosmparse.Relation
-	highWayTags  map[string]bool
-	areaTags     map[string]bool
-	districtTags map[string]bool
-	addressTags  map[string]string
+	WayNames      map[string]string
+	Areas         map[int64]gosmparse.Relation
+	Districts     map[int64]gosmparse.Way
+	Countries     map[int64]gosmparse.Relation
+	Entrances     map[int64]gosmparse.Node
+	DistrictNodes map[int64]gosmparse.Node
+	NaturalWays   map[int64]gosmparse.Way
+	NaturalAreas  map[int64]gosmparse.Relation
+	TransitStops  map[int64]gosmparse.Node
+	Routes        map[int64]gosmparse.Relation
+	Roads         map[int64]gosmparse.Way
+	Junctions     map[int64]gosmparse.Node
+	Roundabouts   map[int64]gosmparse.Way
+	Places        map[int64]gosmparse.Node
+	highWayTags   map[string]bool
+	areaTags      map[string]bool
+	districtTags  map[string]bool
+	routeTags     map[string]bool
+	addressTags   map[string]string
+	keepTags      map[string]bool
+	keepPrefixes  []string
+	adminLevels   map[string]string
+	tagMappings   map[string]map[string][][2]string
+	// spill moves node coordinates to disk near memory budget
+	spill *nodeSpill
 }
 
 // New creates new instance of Handler
@@ -38,7 +58,21 @@ func New() *Handler {
 		Areas:         make(map[int64]gosmparse.Relation),
 		Districts:     make(map[int64]gosmparse.Way),
 		Countries:     make(map[int64]gosmparse.Relation),
+		Entrances:     make(map[int64]gosmparse.Node),
+		DistrictNodes: make(map[int64]gosmparse.Node),
+		NaturalWays:   make(map[int64]gosmparse.Way),
+		NaturalAreas:  make(map[int64]gosmparse.Relation),
+		TransitStops:  make(map[int64]gosmparse.Node),
+		Routes:        make(map[int64]gosmparse.Relation),
+		Roads:         make(map[int64]gosmparse.Way),
+		Junctions:     make(map[int64]gosmparse.Node),
+		Roundabouts:   make(map[int64]gosmparse.Way),
+		Places:        make(map[int64]gosmparse.Node),
 		InvertedIndex: make(map[string][]string),
+		tagMappings:   make(map[string]map[string][][2]string),
+	}
+	for from, to := range defaultTagMappings {
+		h.MapTag(from, to...)
 	}
 	h.highWayTags = map[string]bool{
 		"motorway":    false,
@@ -56,10 +90,18 @@ func New() *Handler {
 		"village": false,
 		"hamlet":  false,
 	}
+	h.adminLevels = map[string]string{"2": roleCountry}
 	h.districtTags = map[string]bool{
 		"neighbourhood": false,
 		"suburb":        false,
 	}
+	h.routeTags = map[string]bool{
+		"bus":        false,
+		"trolleybus": false,
+		"tram":       false,
+		"minibus":    false,
+		"share_taxi": false,
+	}
 	h.addressTags = map[string]string{
 		"addr:street":      "addr:housenumber",
 		"amenity":          "name",
@@ -90,7 +132,23 @@ func New() *Handler {
 // ReadNode - called once per node
 func (h *Handler) ReadNode(item gosmparse.Node) {
 	h.mu.Lock()
+	item.Tags = h.filterTags(h.normalizeTags(item.Tags))
 	h.Nodes[item.ID] = item
+	if item.Tags["entrance"] != "" {
+		h.Entrances[item.ID] = item
+	}
+	if _, ok := h.districtTags[item.Tags["place"]]; ok && item.Tags["name"] != "" {
+		h.DistrictNodes[item.ID] = item
+	}
+	if isTransitStop(item.Tags) {
+		h.TransitStops[item.ID] = item
+	}
+	if isJunction(item.Tags) {
+		h.Junctions[item.ID] = item
+	}
+	if isPlace(item.Tags) {
+		h.Places[item.ID] = item
+	}
 	for k, v := range h.addressTags {
 		if item.Tags[k] != "" {
 			if v == "" {
@@ -101,6 +159,7 @@ func (h *Handler) ReadNode(item gosmparse.Node) {
 			}
 		}
 	}
+	h.checkSpill()
 	h.mu.Unlock()
 }
 
@@ -108,11 +167,18 @@ func (h *Handler) ReadNode(item gosmparse.Node) {
 func (h *Handler) ReadWay(item gosmparse.Way) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
+	item.Tags = h.filterTags(h.normalizeTags(item.Tags))
 
 	if _, ok := h.districtTags[item.Tags["place"]]; ok {
 		h.Districts[item.ID] = item
 	}
 	h.FullWays[item.ID] = item
+	if isNatural(item.Tags) {
+		h.NaturalWays[item.ID] = item
+	}
+	if IsRoundabout(item.Tags) && item.Tags["name"] != "" {
+		h.Roundabouts[item.ID] = item
+	}
 	for k, v := range h.addressTags {
 		if item.Tags[k] != "" {
 			if v == "" {
@@ -127,6 +193,9 @@ func (h *Handler) ReadWay(item gosmparse.Way) {
 	if _, ok := h.highWayTags[item.Tags["highway"]]; !ok {
 		return
 	}
+	if item.Tags["name"] != "" {
+		h.Roads[item.ID] = item
+	}
 	if item.Tags["addr:street"] != "" && item.Tags["addr:housenumber"] != "" {
 
 		h.Ways[item.ID] = item
@@ -151,11 +220,111 @@ func (h *Handler) ReadWay(item gosmparse.Way) {
 // ReadRelation - called once per relation
 func (h *Handler) ReadRelation(item gosmparse.Relation) {
 	h.mu.Lock()
-	if item.Tags["admin_level"] == "2" {
+	item.Tags = h.filterTags(h.normalizeTags(item.Tags))
+	role := h.adminLevels[item.Tags["admin_level"]]
+	if role == roleCountry {
 		h.Countries[item.ID] = item
 	}
+	if _, ok := h.areaTags[role]; ok && item.Tags["place"] == "" {
+		// settlement mapped only as administrative boundary
+		item.Tags["place"] = role
+	}
 	if _, ok := h.areaTags[item.Tags["place"]]; ok {
 		h.Areas[item.ID] = item
 	}
+	if isNatural(item.Tags) {
+		h.NaturalAreas[item.ID] = item
+	}
+	if _, ok := h.routeTags[item.Tags["route"]]; ok {
+		h.Routes[item.ID] = item
+	}
+	h.mu.Unlock()
+}
+
+// AdminLevels sets roles of administrative boundaries by admin_level: country, city, town or village
+func (h *Handler) AdminLevels(levels map[string]string) {
+	h.adminLevels = levels
+}
+
+// DeleteNode - called for nodes deleted by change file
+func (h *Handler) DeleteNode(id int64) {
+	h.mu.Lock()
+	delete(h.Nodes, id)
+	delete(h.FilteredNodes, id)
+	delete(h.Entrances, id)
+	delete(h.DistrictNodes, id)
+	delete(h.TransitStops, id)
+	delete(h.Junctions, id)
+	delete(h.Places, id)
+	if h.spill != nil && len(h.spill.runs) > 0 {
+		h.spill.deleted[id] = true
+	}
+	h.mu.Unlock()
+}
+
+// DeleteWay - called for ways deleted by change file
+func (h *Handler) DeleteWay(id int64) {
+	h.mu.Lock()
+	delete(h.Ways, id)
+	delete(h.FullWays, id)
+	delete(h.Districts, id)
+	delete(h.NaturalWays, id)
+	delete(h.Roads, id)
+	delete(h.Roundabouts, id)
+	delete(h.WayNames, strconv.FormatInt(id, 10))
 	h.mu.Unlock()
 }
+
+// DeleteRelation - called for relations deleted by change file
+func (h *Handler) DeleteRelation(id int64) {
+	h.mu.Lock()
+	delete(h.Areas, id)
+	delete(h.Countries, id)
+	delete(h.NaturalAreas, id)
+	delete(h.Routes, id)
+	h.mu.Unlock()
+}
+
+// isNatural reports whether element is named natural feature, waterway or island
+func isNatural(tags map[string]string) bool {
+	if tags["name"] == "" {
+		return false
+	}
+	return tags["natural"] != "" || tags["waterway"] != "" || tags["place"] == "island" || tags["place"] == "islet"
+}
+
+// IsRoundabout reports whether way is roundabout or other circular junction
+func IsRoundabout(tags map[string]string) bool {
+	return tags["junction"] == "roundabout" || tags["junction"] == "circular"
+}
+
+// isJunction reports whether node is named junction, like motorway exit or named crossing
+func isJunction(tags map[string]string) bool {
+	if tags["name"] == "" {
+		return false
+	}
+	return tags["junction"] != "" || tags["highway"] == "motorway_junction"
+}
+
+// isPlace reports whether node is named settlement or its part
+func isPlace(tags map[string]string) bool {
+	if tags["name"] == "" {
+		return false
+	}
+	switch tags["place"] {
+	case "city", "town", "village", "hamlet", "isolated_dwelling", "suburb", "quarter", "neighbourhood", "locality":
+		return true
+	}
+	return false
+}
+
+// isTransitStop reports whether node is public transport stop or station
+func isTransitStop(tags map[string]string) bool {
+	switch {
+	case tags["highway"] == "bus_stop":
+		return true
+	case tags["railway"] == "station" || tags["railway"] == "halt" || tags["railway"] == "tram_stop":
+		return true
+	}
+	return tags["public_transport"] == "station"
+}
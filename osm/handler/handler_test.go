@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/maddevsio/ariadna/osm/osmtest"
+	"github.com/maddevsio/ariadna/osm/parser"
+	"github.com/missinglink/gosmparse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlerClassifiesElements(t *testing.T) {
+	dir, err := ioutil.TempDir("", "handler")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path, err := osmtest.New().
+		Node(1, 42.87, 74.59, "addr:street", "Киевская улица", "addr:housenumber", "1", "fixme", "x").
+		Node(2, 42.88, 74.60, "highway", "bus_stop", "name", "Ала-Тоо").
+		Node(3, 42.89, 74.61, "place", "suburb", "name", "Джал").
+		Square(10, 100, 42.87, 74.59, 0.1, "place", "city", "name", "Бишкек").
+		Way(11, []int64{1, 2}, "highway", "residential", "name", "Киевская").
+		Square(12, 200, 42.5, 74.5, 0.01, "natural", "water", "name", "Озеро").
+		Relation(1000, []gosmparse.RelationMember{osmtest.Way(10, "outer")}, "admin_level", "2", "name", "Кыргызстан").
+		Relation(1001, []gosmparse.RelationMember{osmtest.Way(10, "outer")}, "place", "city", "name", "Бишкек").
+		WriteFile(dir)
+	require.NoError(t, err)
+
+	p, err := parser.NewParser(path)
+	require.NoError(t, err)
+	h := New()
+	h.KeepTags("addr:*")
+	require.NoError(t, p.Parse(context.Background(), h))
+
+	assert.Contains(t, h.FilteredNodes, int64(1))
+	assert.NotContains(t, h.FilteredNodes[1].Tags, "fixme")
+	assert.Contains(t, h.TransitStops, int64(2))
+	assert.Contains(t, h.DistrictNodes, int64(3))
+	assert.Contains(t, h.NaturalWays, int64(12))
+	assert.Equal(t, "Киевская", h.WayNames["11"])
+	assert.Equal(t, []string{"11"}, h.InvertedIndex["1"])
+	assert.Contains(t, h.Countries, int64(1000))
+	assert.Contains(t, h.Areas, int64(1001))
+}
+
+func TestHandlerAdminLevels(t *testing.T) {
+	h := New()
+	h.AdminLevels(map[string]string{"2": "country", "4": "city"})
+	osmtest.New().
+		Relation(1, nil, "admin_level", "2", "name", "Кыргызстан").
+		Relation(2, nil, "admin_level", "4", "name", "Бишкек").
+		Relation(3, nil, "admin_level", "6", "name", "Ленинский район").
+		Feed(h)
+
+	assert.Contains(t, h.Countries, int64(1))
+	require.Contains(t, h.Areas, int64(2))
+	assert.Equal(t, "city", h.Areas[2].Tags["place"])
+	assert.NotContains(t, h.Areas, int64(3))
+}
+
+func TestHandlerNormalizesTags(t *testing.T) {
+	h := New()
+	h.MapTag("amenity=gym", "amenity=gym")
+	h.MapTag("shop=kiosk", "shop=convenience", "kiosk")
+	h.ReadNode(gosmparse.Node{ID: 1, Tags: map[string]string{"amenity": "nursing_home", "name": "Дом"}})
+	h.ReadNode(gosmparse.Node{ID: 2, Tags: map[string]string{"highway": "ford"}})
+	h.ReadNode(gosmparse.Node{ID: 3, Tags: map[string]string{"amenity": "gym", "shop": "kiosk", "name": "Спорт"}})
+	h.ReadWay(gosmparse.Way{ID: 10, Tags: map[string]string{"natural": "marsh", "wetland": "bog", "name": "Болото"}})
+
+	assert.Equal(t, map[string]string{"amenity": "social_facility", "social_facility": "nursing_home", "name": "Дом"}, h.Nodes[1].Tags)
+	assert.Equal(t, map[string]string{"ford": "yes"}, h.Nodes[2].Tags)
+	assert.Equal(t, map[string]string{"amenity": "gym", "shop": "convenience", "kiosk": "yes", "name": "Спорт"}, h.Nodes[3].Tags)
+	assert.Equal(t, map[string]string{"natural": "wetland", "wetland": "bog", "name": "Болото"}, h.FullWays[10].Tags)
+	assert.Contains(t, h.NaturalWays, int64(10))
+}
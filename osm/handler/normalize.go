@@ -0,0 +1,78 @@
+package handler
+
+import "strings"
+
+// defaultTagMappings replace deprecated and regional tagging schemes by current ones,
+// so categories and searches behave the same across regions mapped in different eras
+var defaultTagMappings = map[string][]string{
+	"amenity=nursing_home":    {"amenity=social_facility", "social_facility=nursing_home"},
+	"amenity=youth_centre":    {"amenity=community_centre", "community_centre=youth_centre"},
+	"amenity=register_office": {"office=government", "government=register_office"},
+	"amenity=real_estate":     {"office=estate_agent"},
+	"amenity=emergency_phone": {"emergency=phone"},
+	"amenity=fire_hydrant":    {"emergency=fire_hydrant"},
+	"amenity=swimming_pool":   {"leisure=swimming_pool"},
+	"amenity=sauna":           {"leisure=sauna"},
+	"amenity=gym":             {"leisure=fitness_centre"},
+	"shop=real_estate":        {"office=estate_agent"},
+	"shop=fishmonger":         {"shop=seafood"},
+	"shop=perfume":            {"shop=perfumery"},
+	"shop=antique":            {"shop=antiques"},
+	"shop=tickets":            {"shop=ticket"},
+	"shop=vacuum_cleaner":     {"shop=appliance"},
+	"shop=gallery":            {"shop=art"},
+	"historic=museum":         {"tourism=museum"},
+	"highway=ford":            {"ford=yes"},
+	"highway=stile":           {"barrier=stile"},
+	"natural=marsh":           {"natural=wetland", "wetland=marsh"},
+	"waterway=riverbank":      {"natural=water", "water=river"},
+	"landuse=farm":            {"landuse=farmland"},
+	"power=station":           {"power=substation"},
+	"man_made=well":           {"man_made=water_well"},
+	"building=entrance":       {"entrance=yes"},
+}
+
+// MapTag replaces from tag by to tags at import, all given as key=value. It overrides
+// default mapping of the same tag: mapping tag to itself disables it, mapping to
+// nothing drops the tag
+func (h *Handler) MapTag(from string, to ...string) {
+	key, value := splitTag(from)
+	tags := make([][2]string, 0, len(to))
+	for _, tag := range to {
+		k, v := splitTag(tag)
+		tags = append(tags, [2]string{k, v})
+	}
+	if h.tagMappings[key] == nil {
+		h.tagMappings[key] = make(map[string][][2]string)
+	}
+	h.tagMappings[key][value] = tags
+}
+
+// normalizeTags applies tag mappings. Replacing tags don't overwrite more specific ones
+// mapped already, like wetland=bog of natural=marsh
+func (h *Handler) normalizeTags(tags map[string]string) map[string]string {
+	var matched [][][2]string
+	for key, value := range tags {
+		if to, ok := h.tagMappings[key][value]; ok {
+			matched = append(matched, to)
+			delete(tags, key)
+		}
+	}
+	for _, to := range matched {
+		for _, tag := range to {
+			if _, ok := tags[tag[0]]; !ok {
+				tags[tag[0]] = tag[1]
+			}
+		}
+	}
+	return tags
+}
+
+// splitTag splits key=value, tag without value is yes
+func splitTag(tag string) (string, string) {
+	n := strings.Index(tag, "=")
+	if n < 0 {
+		return strings.TrimSpace(tag), "yes"
+	}
+	return strings.TrimSpace(tag[:n]), strings.TrimSpace(tag[n+1:])
+}
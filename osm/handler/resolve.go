@@ -0,0 +1,82 @@
+package handler
+
+import "github.com/missinglink/gosmparse"
+
+// Resolver loads members of retained relations that were not kept during the first parse pass.
+// It is fed by additional parse passes until nothing is pending
+type Resolver struct {
+	h     *Handler
+	Ways  map[int64]bool
+	Nodes map[int64]bool
+}
+
+// NewResolver creates resolver for members missing in handler
+func (h *Handler) NewResolver() *Resolver {
+	r := &Resolver{h: h, Ways: make(map[int64]bool), Nodes: make(map[int64]bool)}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, relations := range []map[int64]gosmparse.Relation{h.Countries, h.Areas, h.NaturalAreas} {
+		for _, relation := range relations {
+			for _, member := range relation.Members {
+				switch member.Type {
+				case gosmparse.NodeType:
+					if _, ok := h.Node(member.ID); !ok {
+						r.Nodes[member.ID] = true
+					}
+				case gosmparse.WayType:
+					way, ok := h.FullWays[member.ID]
+					if !ok {
+						r.Ways[member.ID] = true
+						continue
+					}
+					r.addMissingNodes(way)
+				}
+			}
+		}
+	}
+	for _, way := range h.Districts {
+		r.addMissingNodes(way)
+	}
+	return r
+}
+
+func (r *Resolver) addMissingNodes(way gosmparse.Way) {
+	for _, nodeID := range way.NodeIDs {
+		if _, ok := r.h.Node(nodeID); !ok {
+			r.Nodes[nodeID] = true
+		}
+	}
+}
+
+// Pending reports whether there are members left to resolve
+func (r *Resolver) Pending() bool {
+	return len(r.Ways) > 0 || len(r.Nodes) > 0
+}
+
+// ReadNode - called once per node
+func (r *Resolver) ReadNode(item gosmparse.Node) {
+	r.h.mu.Lock()
+	defer r.h.mu.Unlock()
+	if !r.Nodes[item.ID] {
+		return
+	}
+	item.Tags = r.h.filterTags(r.h.normalizeTags(item.Tags))
+	r.h.Nodes[item.ID] = item
+	delete(r.Nodes, item.ID)
+}
+
+// ReadWay - called once per way
+func (r *Resolver) ReadWay(item gosmparse.Way) {
+	r.h.mu.Lock()
+	defer r.h.mu.Unlock()
+	if !r.Ways[item.ID] {
+		return
+	}
+	item.Tags = r.h.filterTags(r.h.normalizeTags(item.Tags))
+	r.h.FullWays[item.ID] = item
+	delete(r.Ways, item.ID)
+	r.addMissingNodes(item)
+}
+
+// ReadRelation - called once per relation
+func (r *Resolver) ReadRelation(item gosmparse.Relation) {}
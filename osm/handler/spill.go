@@ -0,0 +1,233 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/maddevsio/ariadna/memory"
+	"github.com/missinglink/gosmparse"
+)
+
+const (
+	// nodeRecord is size of node spilled to disk: id, lat and lon
+	nodeRecord = 24
+	// spillBlock is number of records read at once, first id of every block is kept in memory
+	spillBlock = 512
+	// spillCheckEvery is number of read nodes between checks of memory budget
+	spillCheckEvery = 100000
+	// minSpill is least number of nodes worth a run on disk
+	minSpill = 100000
+)
+
+type (
+	// nodeSpill keeps coordinates of nodes moved out of memory near memory budget. Every spill
+	// writes run of records sorted by id, lookups search the latest run first
+	nodeSpill struct {
+		root   string
+		budget int64
+		dir    string
+		runs   []*nodeRun
+		// deleted are spilled nodes deleted by change file afterwards
+		deleted map[int64]bool
+		read    int
+		blocks  sync.Pool
+
+		mu  sync.Mutex
+		err error
+	}
+	// nodeRun is file of spilled nodes sorted by id
+	nodeRun struct {
+		file   *os.File
+		count  int
+		firsts []int64
+	}
+)
+
+// SpillNodes makes handler move coordinates of read nodes to files in temporary directory under
+// dir when memory of the process approaches budget. Tags of spilled nodes are dropped, nodes kept
+// by layers retain them. Zero budget keeps all nodes in memory
+func (h *Handler) SpillNodes(dir string, budget int64) {
+	if budget <= 0 {
+		return
+	}
+	h.spill = &nodeSpill{root: dir, budget: budget, deleted: make(map[int64]bool)}
+	h.spill.blocks.New = func() interface{} {
+		block := make([]byte, spillBlock*nodeRecord)
+		return &block
+	}
+}
+
+// Spilled returns number of nodes on disk
+func (h *Handler) Spilled() int {
+	if h.spill == nil {
+		return 0
+	}
+	count := 0
+	for _, run := range h.spill.runs {
+		count += run.count
+	}
+	return count
+}
+
+// SpillErr returns the first failure to spill or read spilled nodes. Nodes stay in memory after
+// failed spill, failed read misses node
+func (h *Handler) SpillErr() error {
+	if h.spill == nil {
+		return nil
+	}
+	h.spill.mu.Lock()
+	defer h.spill.mu.Unlock()
+	return h.spill.err
+}
+
+// RemoveSpilled deletes files of spilled nodes, they can't be read afterwards
+func (h *Handler) RemoveSpilled() error {
+	if h.spill == nil || h.spill.dir == "" {
+		return nil
+	}
+	for _, run := range h.spill.runs {
+		run.file.Close()
+	}
+	h.spill.runs = nil
+	err := os.RemoveAll(h.spill.dir)
+	h.spill.dir = ""
+	return err
+}
+
+// Node returns node by id, nodes spilled to disk have only coordinates
+func (h *Handler) Node(id int64) (gosmparse.Node, bool) {
+	if node, ok := h.Nodes[id]; ok {
+		return node, true
+	}
+	if h.spill == nil || len(h.spill.runs) == 0 || h.spill.deleted[id] {
+		return gosmparse.Node{}, false
+	}
+	return h.spill.node(id)
+}
+
+// checkSpill spills nodes when memory approaches budget, it is called with locked mu
+func (h *Handler) checkSpill() {
+	s := h.spill
+	if s == nil {
+		return
+	}
+	if s.read++; s.read%spillCheckEvery != 0 || len(h.Nodes) < minSpill || !memory.Pressure(s.budget) {
+		return
+	}
+	if err := h.spillNodes(); err != nil {
+		s.fail(err)
+		// nodes stay in memory, spilling isn't tried again
+		s.budget = 0
+		return
+	}
+	memory.Release()
+}
+
+// spillNodes writes nodes held in memory to new run and drops them
+func (h *Handler) spillNodes() error {
+	s := h.spill
+	if s.dir == "" {
+		dir, err := ioutil.TempDir(s.root, "ariadna-nodes-")
+		if err != nil {
+			return fmt.Errorf("spill nodes: %w", err)
+		}
+		s.dir = dir
+	}
+	ids := make([]int64, 0, len(h.Nodes))
+	for id := range h.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(a, b int) bool { return ids[a] < ids[b] })
+	run, err := s.write(ids, h.Nodes)
+	if err != nil {
+		return fmt.Errorf("spill nodes: %w", err)
+	}
+	for _, id := range ids {
+		delete(s.deleted, id)
+	}
+	s.runs = append(s.runs, run)
+	h.Nodes = make(map[int64]gosmparse.Node)
+	return nil
+}
+
+func (s *nodeSpill) write(ids []int64, nodes map[int64]gosmparse.Node) (*nodeRun, error) {
+	f, err := ioutil.TempFile(s.dir, "run-")
+	if err != nil {
+		return nil, err
+	}
+	run := &nodeRun{file: f, count: len(ids), firsts: make([]int64, 0, len(ids)/spillBlock+1)}
+	w := bufio.NewWriterSize(f, 1<<20)
+	var record [nodeRecord]byte
+	for n, id := range ids {
+		if n%spillBlock == 0 {
+			run.firsts = append(run.firsts, id)
+		}
+		node := nodes[id]
+		binary.LittleEndian.PutUint64(record[0:], uint64(id))
+		binary.LittleEndian.PutUint64(record[8:], math.Float64bits(node.Lat))
+		binary.LittleEndian.PutUint64(record[16:], math.Float64bits(node.Lon))
+		if _, err := w.Write(record[:]); err != nil {
+			break
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return run, nil
+}
+
+func (s *nodeSpill) node(id int64) (gosmparse.Node, bool) {
+	block := s.blocks.Get().(*[]byte)
+	defer s.blocks.Put(block)
+	for n := len(s.runs) - 1; n >= 0; n-- {
+		lat, lon, ok, err := s.runs[n].find(id, *block)
+		if err != nil {
+			s.fail(fmt.Errorf("read spilled node %d: %w", id, err))
+			return gosmparse.Node{}, false
+		}
+		if ok {
+			return gosmparse.Node{ID: id, Lat: lat, Lon: lon}, true
+		}
+	}
+	return gosmparse.Node{}, false
+}
+
+func (s *nodeSpill) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// find reads block of run which may hold id and searches it
+func (r *nodeRun) find(id int64, block []byte) (lat, lon float64, ok bool, err error) {
+	b := sort.Search(len(r.firsts), func(n int) bool { return r.firsts[n] > id }) - 1
+	if b < 0 {
+		return 0, 0, false, nil
+	}
+	start := b * spillBlock
+	count := r.count - start
+	if count > spillBlock {
+		count = spillBlock
+	}
+	data := block[:count*nodeRecord]
+	if _, err := r.file.ReadAt(data, int64(start*nodeRecord)); err != nil {
+		return 0, 0, false, err
+	}
+	n := sort.Search(count, func(n int) bool { return int64(binary.LittleEndian.Uint64(data[n*nodeRecord:])) >= id })
+	if n == count || int64(binary.LittleEndian.Uint64(data[n*nodeRecord:])) != id {
+		return 0, 0, false, nil
+	}
+	lat = math.Float64frombits(binary.LittleEndian.Uint64(data[n*nodeRecord+8:]))
+	lon = math.Float64frombits(binary.LittleEndian.Uint64(data[n*nodeRecord+16:]))
+	return lat, lon, true, nil
+}
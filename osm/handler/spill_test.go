@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/missinglink/gosmparse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpillNodes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spill")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	h := New()
+	h.SpillNodes(dir, 1<<30)
+	// more nodes than a block, so lookups read different blocks
+	for id := int64(1); id <= 3*spillBlock; id++ {
+		h.ReadNode(gosmparse.Node{ID: id * 2, Lat: float64(id), Lon: -float64(id), Tags: map[string]string{"name": "x"}})
+	}
+	require.NoError(t, h.spillNodes())
+	assert.Empty(t, h.Nodes)
+	assert.Equal(t, 3*spillBlock, h.Spilled())
+
+	node, ok := h.Node(2 * 700)
+	require.True(t, ok)
+	assert.Equal(t, gosmparse.Node{ID: 1400, Lat: 700, Lon: -700}, node)
+	_, ok = h.Node(1401)
+	assert.False(t, ok)
+	_, ok = h.Node(0)
+	assert.False(t, ok)
+
+	h.DeleteNode(4)
+	_, ok = h.Node(4)
+	assert.False(t, ok)
+	// node read again after deletion is found in later run
+	h.ReadNode(gosmparse.Node{ID: 4, Lat: 1.5, Lon: 2.5})
+	h.ReadNode(gosmparse.Node{ID: 6, Lat: 3.5, Lon: 4.5})
+	require.NoError(t, h.spillNodes())
+	node, ok = h.Node(4)
+	require.True(t, ok)
+	assert.Equal(t, 1.5, node.Lat)
+	node, _ = h.Node(6)
+	assert.Equal(t, 4.5, node.Lon)
+	node, _ = h.Node(8)
+	assert.Equal(t, 4.0, node.Lat)
+	require.NoError(t, h.SpillErr())
+
+	require.NoError(t, h.RemoveSpilled())
+	entries, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestSpillNodesWithoutBudget(t *testing.T) {
+	h := New()
+	h.SpillNodes("", 0)
+	h.ReadNode(gosmparse.Node{ID: 1, Lat: 1, Lon: 2})
+	node, ok := h.Node(1)
+	require.True(t, ok)
+	assert.Equal(t, 2.0, node.Lon)
+	assert.Zero(t, h.Spilled())
+	assert.NoError(t, h.RemoveSpilled())
+}
@@ -0,0 +1,67 @@
+package handler
+
+import "strings"
+
+// keptTags are read by importer, they are kept whatever KeepTags patterns are
+var keptTags = []string{
+	"name", "place", "admin_level", "timezone", // places and admin boundaries
+	"highway", "junction", "ref", // streets, crossroads and route refs
+	"building", "entrance", // entrances of buildings
+	"addr:unit", "addr:flats", "addr:door", // address parts below house number
+	"natural", "waterway", // natural features
+	"route", "railway", "public_transport", // transit stops and routes
+	"wikidata", "wikipedia", // wikidata enrichment
+	"maxspeed", "surface", "lanes", "oneway", // road attributes
+	"layer", "bridge", "tunnel", "level", // vertical position
+}
+
+// KeepTags enables tag pre-filtering: only tags matching given patterns are retained
+// on parsed elements. Pattern is either exact key or prefix ending with "*".
+// Tags required by handler itself are always kept
+func (h *Handler) KeepTags(patterns ...string) {
+	h.keepTags = make(map[string]bool)
+	h.keepPrefixes = nil
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "*") {
+			h.keepPrefixes = append(h.keepPrefixes, strings.TrimSuffix(pattern, "*"))
+			continue
+		}
+		h.keepTags[pattern] = true
+	}
+	for _, key := range keptTags {
+		h.keepTags[key] = true
+	}
+	// name variants are picked by requested languages
+	h.keepPrefixes = append(h.keepPrefixes, "name:")
+	for k, v := range h.addressTags {
+		h.keepTags[k] = true
+		if v != "" {
+			h.keepTags[v] = true
+		}
+	}
+}
+
+// filterTags drops tags not needed for indexing
+func (h *Handler) filterTags(tags map[string]string) map[string]string {
+	if h.keepTags == nil || len(tags) == 0 {
+		return tags
+	}
+	for key := range tags {
+		if !h.keepTag(key) {
+			delete(tags, key)
+		}
+	}
+	return tags
+}
+
+func (h *Handler) keepTag(key string) bool {
+	if h.keepTags[key] {
+		return true
+	}
+	for _, prefix := range h.keepPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,21 +1,207 @@
 package osm
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/model"
+	v1 "github.com/maddevsio/ariadna/schema/v1"
 )
 
+// BadRequest is body of error responses, Code identifies kind of error
 type BadRequest struct {
 	Error string `json:"error"`
+	Code  string `json:"code,omitempty"`
 }
 
-func (i *Importer) geoCodeHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	w.Write([]byte{1})
+const (
+	pointTypeEntrance = "entrance"
+	endpointSearch    = "search"
+	endpointReverse   = "reverse"
+)
 
+func (i *Importer) geoCodeHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	start := time.Now()
+	if err := validQuery(ps.ByName("query"), i.maxQueryLength()); err != nil {
+		i.writeFailure(w, r, http.StatusBadRequest, BadRequest{Error: err.Error(), Code: "invalid_request"})
+		return
+	}
+	profileName, profile, err := i.searchProfile(r)
+	if err != nil {
+		i.writeFailure(w, r, http.StatusBadRequest, BadRequest{Error: err.Error(), Code: "invalid_request"})
+		return
+	}
+	w.Header().Set("X-Ranking-Profile", profileName)
+	if debug, _ := strconv.ParseBool(r.URL.Query().Get("debug")); debug {
+		i.explainSearch(w, r, ps.ByName("query"), profileName, profile)
+		return
+	}
+	if i.notModified(w, r, endpointSearch, profileName) {
+		return
+	}
+	result, err := i.geocode(r.Context(), ps.ByName("query"), r.URL.Query().Get("unit"), profile)
+	if err != nil {
+		i.writeError(w, r, err)
+		return
+	}
+	i.observe(r, endpointSearch, ps.ByName("query"), profileName, result.Addresses, start)
+	i.writeResult(w, r, result, withCodes(preferPoint(result.Addresses, r.URL.Query().Get("point_type"))))
 }
 
 func (i *Importer) reverseGeoCodeHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	w.Write([]byte{1})
+	start := time.Now()
+	lat, lon, err := parsePoint(ps)
+	if err != nil {
+		i.writeFailure(w, r, http.StatusBadRequest, BadRequest{Error: err.Error(), Code: "invalid_request"})
+		return
+	}
+	snap := r.URL.Query().Get("snap")
+	if snap != "" && snap != snapRoadParam {
+		i.writeFailure(w, r, http.StatusBadRequest, BadRequest{Error: "invalid snap, supported: road", Code: "invalid_request"})
+		return
+	}
+	var level float64
+	if s := r.URL.Query().Get("level"); s != "" {
+		if level, err = strconv.ParseFloat(s, 64); err != nil {
+			i.writeFailure(w, r, http.StatusBadRequest, BadRequest{Error: "invalid level", Code: "invalid_request"})
+			return
+		}
+	}
+	if i.notModified(w, r, endpointReverse) {
+		return
+	}
+	lookup := i.lookup
+	if snap == snapRoadParam {
+		lookup = i.snapToRoad
+	}
+	result, err := lookup(r.Context(), lat, lon)
+	if err != nil {
+		i.writeError(w, r, err)
+		return
+	}
+	if r.URL.Query().Get("level") != "" {
+		// stacked features share location, the one on requested level is meant
+		result.Addresses = preferLevel(result.Addresses, level)
+	}
+	query := strconv.FormatFloat(lat, 'f', -1, 64) + "," + strconv.FormatFloat(lon, 'f', -1, 64)
+	i.observe(r, endpointReverse, query, "", result.Addresses, start)
+	i.writeResult(w, r, result, withCodes(preferPoint(result.Addresses, r.URL.Query().Get("point_type"))))
+}
+
+// writeResult writes addresses flagging partial results cut by timeout with X-Timed-Out header.
+// ?format=csv selects CSV and ?callback= wraps JSON into JSONP call, see labeled for labels
+func (i *Importer) writeResult(w http.ResponseWriter, r *http.Request, result *elastic.Result, addresses []model.Address) {
+	addresses = withViewports(labeled(r, addresses))
+	if result.TimedOut {
+		w.Header().Set("X-Timed-Out", "true")
+		uncacheable(w)
+	}
+	if r.URL.Query().Get("format") == formatCSV {
+		i.writeCSV(w, addresses)
+		return
+	}
+	results := v1.NewResults(addresses, result.TimedOut)
+	results.Facets = v1.NewFacets(result.Facets)
+	var body interface{} = addresses
+	if schemaVersion(r) == v1.Version {
+		body = results
+	}
+	if callback := r.URL.Query().Get("callback"); callback != "" {
+		if !callbackRe.MatchString(callback) {
+			i.writeFailure(w, r, http.StatusBadRequest, BadRequest{Error: "invalid callback", Code: "invalid_request"})
+			return
+		}
+		i.writeJSONP(w, callback, body)
+		return
+	}
+	if wantsProtobuf(r) {
+		i.writeProtobuf(w, r, results)
+		return
+	}
+	if schemaVersion(r) == v1.Version {
+		i.writeV1(w, http.StatusOK, body)
+		return
+	}
+	i.writeJSON(w, http.StatusOK, body)
+}
+
+// writeFailure writes error body in negotiated schema
+func (i *Importer) writeFailure(w http.ResponseWriter, r *http.Request, status int, e BadRequest) {
+	uncacheable(w)
+	w.Header().Set("Content-Type", problemMediaType)
+	p := newProblem(r, status, e)
+	i.messages.localize(w, r, &p, e.Code)
+	if schemaVersion(r) == v1.Version {
+		i.writeJSON(w, status, struct {
+			problem
+			v1.Error
+		}{p, v1.NewError(e.Error, e.Code)})
+		return
+	}
+	i.writeJSON(w, status, struct {
+		problem
+		BadRequest
+	}{p, e})
+}
+
+func (i *Importer) writeV1(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", v1.MediaType)
+	i.writeJSON(w, status, v)
+}
+
+func (i *Importer) writeProtobuf(w http.ResponseWriter, r *http.Request, results v1.Results) {
+	data, err := results.MarshalProtobuf()
+	if err != nil {
+		i.writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", v1.ProtobufMediaType)
+	if _, err := w.Write(data); err != nil {
+		i.logger.Error(err)
+	}
+}
+
+func (i *Importer) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		i.logger.Error(err)
+	}
+}
+
+// withTimeout limits processing time of every request by configured request timeout
+func (i *Importer) withTimeout(next http.Handler) http.Handler {
+	if i.config.API.RequestTimeout <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isStream(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), i.config.API.RequestTimeout)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
 
+// preferPoint replaces location of addresses with their main entrance
+// when entrance point type was requested
+func preferPoint(addresses []model.Address, pointType string) []model.Address {
+	if pointType != pointTypeEntrance {
+		return addresses
+	}
+	for idx := range addresses {
+		if len(addresses[idx].Entrances) > 0 {
+			addresses[idx].Location = addresses[idx].Entrances[0].Location
+		}
+	}
+	return addresses
 }
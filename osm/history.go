@@ -0,0 +1,111 @@
+package osm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/elastic"
+	v1 "github.com/maddevsio/ariadna/schema/v1"
+)
+
+// asOfDate is layout of dates of ?as_of=, RFC 3339 times are accepted too
+const asOfDate = "2006-01-02"
+
+// historySlices caches slices of imports, so ?as_of= queries don't cost a request to elasticsearch
+type historySlices struct {
+	mu      sync.Mutex
+	slices  []elastic.Slice
+	expires time.Time
+}
+
+// slices returns imports of the index and history ordered by validity
+func (i *Importer) slices(ctx context.Context) ([]elastic.Slice, error) {
+	h := &i.history
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if time.Now().Before(h.expires) {
+		return h.slices, nil
+	}
+	slices, err := i.e.Slices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	h.slices, h.expires = slices, time.Now().Add(indexVersionTTL)
+	return slices, nil
+}
+
+// withHistory makes requests with ?as_of= read slice of import valid at that date instead of the
+// current index. Period of the slice is returned in X-Valid-From and X-Valid-To headers
+func (i *Importer) withHistory(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := r.URL.Query().Get("as_of")
+		if s == "" || !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !i.config.History.Enabled {
+			i.writeFailure(w, r, http.StatusBadRequest, BadRequest{Error: "history is disabled", Code: "invalid_request"})
+			return
+		}
+		asOf, err := parseAsOf(s)
+		if err != nil {
+			i.writeFailure(w, r, http.StatusBadRequest, BadRequest{Error: err.Error(), Code: "invalid_request"})
+			return
+		}
+		slices, err := i.slices(r.Context())
+		if err != nil {
+			i.writeError(w, r, err)
+			return
+		}
+		slice, ok := elastic.SliceAt(slices, asOf)
+		if !ok {
+			i.writeFailure(w, r, http.StatusNotFound, BadRequest{Error: fmt.Sprintf("no data as of %s", s), Code: "no_history"})
+			return
+		}
+		w.Header().Set("X-Valid-From", slice.ValidFrom.Format(time.RFC3339))
+		if slice.ValidTo != nil {
+			w.Header().Set("X-Valid-To", slice.ValidTo.Format(time.RFC3339))
+		}
+		ctx := elastic.WithIndex(r.Context(), strings.Join(slice.Indices, ","))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// parseAsOf parses date or time of ?as_of=, dates are midnight UTC
+func parseAsOf(s string) (time.Time, error) {
+	if t, err := time.Parse(asOfDate, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return t, fmt.Errorf("invalid as_of %q, expected date like 2022-01-01 or RFC 3339 time", s)
+	}
+	return t, nil
+}
+
+// historyHandler lists imports kept for ?as_of= queries with periods of their validity
+func (i *Importer) historyHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	slices, err := i.slices(r.Context())
+	if err != nil {
+		i.writeError(w, r, err)
+		return
+	}
+	if schemaVersion(r) != v1.Version {
+		i.writeJSON(w, http.StatusOK, slices)
+		return
+	}
+	body := v1.History{SchemaVersion: v1.Version, Slices: make([]v1.Slice, 0, len(slices))}
+	for _, s := range slices {
+		body.Slices = append(body.Slices, v1.Slice{
+			ValidFrom: s.ValidFrom,
+			ValidTo:   s.ValidTo,
+			Import:    v1.ImportInfo{ImportedAt: s.Import.ImportedAt, ExtractTime: s.Import.ExtractTime, Source: s.Import.Source},
+		})
+	}
+	i.writeV1(w, http.StatusOK, body)
+}
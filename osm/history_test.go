@@ -0,0 +1,82 @@
+package osm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/model"
+	v1 "github.com/maddevsio/ariadna/schema/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sliceStorage names streets after index searches read
+type sliceStorage struct {
+	memoryStorage
+}
+
+func (s *sliceStorage) Reverse(ctx context.Context, lat, lon float64) (*elastic.Result, error) {
+	index, ok := elastic.ContextIndex(ctx)
+	if !ok {
+		index = "current"
+	}
+	return &elastic.Result{Addresses: []model.Address{{Street: index, HouseNumber: "1"}}}, nil
+}
+
+func TestHistory(t *testing.T) {
+	validTo := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	storage := &sliceStorage{memoryStorage{slices: []elastic.Slice{
+		{Indices: []string{"addresses-1"}, ValidFrom: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), ValidTo: &validTo},
+		{Indices: []string{"addresses-2"}, ValidFrom: validTo},
+	}}}
+	g, err := NewGeocoder(&config.Ariadna{History: config.History{Enabled: true}}, WithStorage(storage))
+	require.NoError(t, err)
+	i := g.i
+	i.metrics = newQueryMetrics(0, true)
+	router := httprouter.New()
+	router.GET("/api/reverse/:lat/:lon", i.reverseGeoCodeHandler)
+	router.GET("/api/history", i.historyHandler)
+	handler := i.withSchema(i.withHistory(router))
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := get("/api/reverse/42.87/74.59?as_of=2022-01-01")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "addresses-1")
+	assert.Equal(t, "2021-01-01T00:00:00Z", w.Header().Get("X-Valid-From"))
+	assert.Equal(t, "2022-06-01T00:00:00Z", w.Header().Get("X-Valid-To"))
+
+	w = get("/api/reverse/42.87/74.59?as_of=2023-03-01T10:00:00Z")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "addresses-2")
+	assert.Empty(t, w.Header().Get("X-Valid-To"))
+
+	assert.Contains(t, get("/api/reverse/42.87/74.59").Body.String(), "current")
+	assert.Equal(t, http.StatusNotFound, get("/api/reverse/42.87/74.59?as_of=2020-01-01").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/api/reverse/42.87/74.59?as_of=yesterday").Code)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/history", nil)
+	r.Header.Set("Accept", v1.MediaType)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	var history v1.History
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &history))
+	require.Len(t, history.Slices, 2)
+	assert.Equal(t, validTo, *history.Slices[0].ValidTo)
+
+	disabled, err := NewGeocoder(&config.Ariadna{}, WithStorage(storage))
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	disabled.i.withHistory(router).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/reverse/42.87/74.59?as_of=2022-01-01", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
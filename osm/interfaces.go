@@ -0,0 +1,99 @@
+package osm
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/missinglink/gosmparse"
+)
+
+type (
+	// Parser reads OSM data. Every call of Parse feeds all elements to reader from the beginning,
+	// parsing stops with context error when ctx is cancelled
+	Parser interface {
+		Parse(ctx context.Context, reader gosmparse.OSMReader) error
+	}
+	// Storage keeps indexed documents and answers queries, elastic.Client is the default
+	Storage interface {
+		SearchStorage
+		ReverseStorage
+		AdminStorage
+		ImportStorage
+	}
+	// SearchStorage answers forward geocoding: full text search and lookups by id or category
+	SearchStorage interface {
+		Search(ctx context.Context, query string) (*elastic.Result, error)
+		SearchRanked(ctx context.Context, query string, profile config.RankingProfile) (*elastic.Result, error)
+		ExplainSearch(ctx context.Context, query string, profile config.RankingProfile) (*elastic.Explanation, error)
+		Lookup(ctx context.Context, ids []string) (*elastic.Result, error)
+		Category(ctx context.Context, layer, category string) (*elastic.Result, error)
+	}
+	// ReverseStorage answers reverse geocoding and spatial queries around point
+	ReverseStorage interface {
+		Reverse(ctx context.Context, lat, lon float64) (*elastic.Result, error)
+		ReverseBatch(ctx context.Context, points []model.Location) ([]*elastic.Result, error)
+		Nearby(ctx context.Context, layer string, lat, lon float64, distance string) (*elastic.Result, error)
+		Containing(ctx context.Context, layer string, lat, lon float64) (*elastic.Result, error)
+		Intersecting(ctx context.Context, layer string, lat, lon, radius float64) (*elastic.Result, error)
+	}
+	// AdminStorage serves operator endpoints: statistics, export, geofences, usage of API keys
+	// and analytics
+	AdminStorage interface {
+		IndexVersion(ctx context.Context) (string, error)
+		Stats(ctx context.Context) (*elastic.Stats, error)
+		Slices(ctx context.Context) ([]elastic.Slice, error)
+		Audits(ctx context.Context, limit int) ([]elastic.ImportAudit, error)
+		Export(ctx context.Context, q elastic.ExportQuery, fn func(model.Address) error) error
+		PutGeofence(ctx context.Context, fence elastic.Geofence) (bool, error)
+		DeleteGeofence(ctx context.Context, name string) (bool, error)
+		Geofences(ctx context.Context) ([]elastic.Geofence, error)
+		AddUsage(ctx context.Context, usage []elastic.Usage) error
+		Usage(ctx context.Context, periods []string) ([]elastic.Usage, error)
+		WriteDocuments(index string, docs []interface{}) error
+		DeleteDailyIndices(prefix string, retention time.Duration) error
+		SetupLifecycle(ctx context.Context, alias string, lifecycle config.Lifecycle, retention time.Duration) error
+	}
+	// ImportStorage receives documents of import along with its audit and changes feed
+	ImportStorage interface {
+		UpdateIndex(ctx context.Context) error
+		ReindexLayers(ctx context.Context, layers []string) error
+		DeleteIndices(ctx context.Context) error
+		BulkWrite(ctx context.Context, buf bytes.Buffer) error
+		WriteImportInfo(ctx context.Context, info elastic.ImportInfo) error
+		WriteAudit(ctx context.Context, audit elastic.ImportAudit) error
+		WriteChanges(ctx context.Context, changes []elastic.Change) error
+		Changes(ctx context.Context, since int64, limit int) (*elastic.ChangesPage, error)
+		TrimChanges(ctx context.Context) error
+	}
+	// Downloader fetches OSM extract from url into file at path
+	Downloader interface {
+		Download(ctx context.Context, url, path string) error
+	}
+	// Option customizes importer created by NewImporter
+	Option func(i *Importer)
+)
+
+// WithParser makes importer read data from p instead of downloading and opening configured file
+func WithParser(p Parser) Option {
+	return func(i *Importer) {
+		i.parser = p
+	}
+}
+
+// WithStorage makes importer write and search documents in s instead of elasticsearch
+func WithStorage(s Storage) Option {
+	return func(i *Importer) {
+		i.e = s
+	}
+}
+
+// WithDownloader replaces HTTP downloader of OSM extract
+func WithDownloader(d Downloader) Option {
+	return func(i *Importer) {
+		i.downloader = d
+	}
+}
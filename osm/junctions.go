@@ -0,0 +1,99 @@
+package osm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/maddevsio/ariadna/model"
+	"github.com/maddevsio/ariadna/osm/handler"
+)
+
+const (
+	layerJunction = "junction"
+
+	categoryRoundabout = "roundabout"
+	categoryJunction   = "junction"
+)
+
+func (i *Importer) junctionsToElastic(ctx context.Context) error {
+	i.logger.Info("started to search junctions")
+	buf, err := i.getJunctions()
+	if err != nil {
+		return err
+	}
+	i.logger.Info("junctions found")
+	return i.bulkWrite(ctx, buf)
+}
+
+// getJunctions indexes named roundabouts and junction nodes, like motorway exits, as features
+// searchable by their names
+func (i *Importer) getJunctions() (bytes.Buffer, error) {
+	var buf bytes.Buffer
+	for wayID, way := range i.handler.Roundabouts {
+		geometry := i.wayGeometry(way)
+		if geometry == nil {
+			continue
+		}
+		center := i.wayCenter(way)
+		if !i.inClip(center.Lat, center.Lon) {
+			continue
+		}
+		address := model.Address{
+			Name:     way.Tags["name"],
+			Names:    nameVariants(way.Tags),
+			Layer:    layerJunction,
+			Category: categoryRoundabout,
+			Location: center,
+			Geometry: geometry,
+			Fields:   i.extractFields(way.Tags),
+			Vertical: verticalOf(way.Tags),
+		}
+		i.locate(&address)
+		if err := i.writeDocument(&buf, fmt.Sprintf("way/%d", wayID), address); err != nil {
+			return buf, err
+		}
+	}
+	for nodeID, node := range i.handler.Junctions {
+		if !i.inClip(node.Lat, node.Lon) {
+			continue
+		}
+		category := categoryJunction
+		if handler.IsRoundabout(node.Tags) {
+			category = categoryRoundabout
+		}
+		address := model.Address{
+			Name:     node.Tags["name"],
+			Names:    nameVariants(node.Tags),
+			Layer:    layerJunction,
+			Category: category,
+			Location: model.Location{Lat: node.Lat, Lon: node.Lon},
+			Fields:   i.extractFields(node.Tags),
+			Vertical: verticalOf(node.Tags),
+		}
+		i.locate(&address)
+		if err := i.writeDocument(&buf, fmt.Sprintf("node/%d", nodeID), address); err != nil {
+			return buf, err
+		}
+	}
+	return buf, nil
+}
+
+// preferJunctions moves junctions found by search ahead of raw intersections, keeping
+// order of other results
+func preferJunctions(addresses []model.Address) []model.Address {
+	result := make([]model.Address, 0, len(addresses))
+	firstIntersection := -1
+	for _, a := range addresses {
+		if a.Layer == layerJunction && firstIntersection >= 0 {
+			result = append(result[:firstIntersection], append([]model.Address{a}, result[firstIntersection:]...)...)
+			firstIntersection++
+			continue
+		}
+		if a.Intersection && firstIntersection < 0 {
+			firstIntersection = len(result)
+		}
+		result = append(result, a)
+	}
+	return result
+}
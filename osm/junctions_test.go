@@ -0,0 +1,47 @@
+package osm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/maddevsio/ariadna/osm/osmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJunctions(t *testing.T) {
+	data := osmtest.New().
+		Node(1, 42.876, 74.600).Node(2, 42.877, 74.601).Node(3, 42.876, 74.602).Node(4, 42.875, 74.601).
+		Node(5, 42.870, 74.601).Node(6, 42.880, 74.700, "highway", "motorway_junction", "name", "Выезд на Кант").
+		Way(10, []int64{1, 2, 3, 4, 1}, "highway", "primary", "junction", "roundabout", "name", "Ала-Тоо").
+		Way(11, []int64{5, 4}, "highway", "primary", "name", "Советская")
+	storage := &memoryStorage{docs: make(map[string]model.Address)}
+	ctx := context.Background()
+	i, err := NewImporter(ctx, &config.Ariadna{}, WithParser(data), WithStorage(storage))
+	require.NoError(t, err)
+	require.NoError(t, i.Start(ctx))
+	require.NoError(t, i.WaitStop())
+
+	require.Contains(t, storage.docs, "way/10")
+	assert.Equal(t, layerJunction, storage.docs["way/10"].Layer)
+	assert.Equal(t, categoryRoundabout, storage.docs["way/10"].Category)
+	require.Contains(t, storage.docs, "node/6")
+	assert.Equal(t, categoryJunction, storage.docs["node/6"].Category)
+	assert.NotContains(t, storage.docs, "4", "street entering roundabout is not a crossroad")
+}
+
+func TestPreferJunctions(t *testing.T) {
+	addresses := preferJunctions([]model.Address{
+		{ID: "1", Name: "Ала-Тоо"},
+		{ID: "2", Intersection: true},
+		{ID: "3"},
+		{ID: "way/4", Layer: layerJunction},
+	})
+	var ids []string
+	for _, a := range addresses {
+		ids = append(ids, a.ID)
+	}
+	assert.Equal(t, []string{"1", "way/4", "2", "3"}, ids)
+}
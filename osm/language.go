@@ -0,0 +1,81 @@
+package osm
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/maddevsio/ariadna/model"
+	"github.com/maddevsio/ariadna/romanize"
+)
+
+// labeled picks names of addresses by ?lang= and adds their romanized labels on ?romanize=true
+func labeled(r *http.Request, addresses []model.Address) []model.Address {
+	addresses = localize(addresses, requestLanguages(r))
+	if ok, _ := strconv.ParseBool(r.URL.Query().Get("romanize")); ok {
+		for n := range addresses {
+			addresses[n].LabelRomanized = romanize.String(label(addresses[n]))
+		}
+	}
+	return addresses
+}
+
+// label returns name of feature or street address
+func label(a model.Address) string {
+	if a.Name != "" {
+		return a.Name
+	}
+	return strings.Join(strings.Fields(a.Prefix+" "+a.Street+" "+a.HouseNumber), " ")
+}
+
+// nameVariants collects name:<lang> tags by language
+func nameVariants(tags map[string]string) map[string]string {
+	var names map[string]string
+	for key, value := range tags {
+		if !strings.HasPrefix(key, "name:") || value == "" {
+			continue
+		}
+		if names == nil {
+			names = make(map[string]string)
+		}
+		names[strings.TrimPrefix(key, "name:")] = value
+	}
+	return names
+}
+
+// requestLanguages returns languages of ?lang=ky,ru,en in order of preference
+func requestLanguages(r *http.Request) []string {
+	var langs []string
+	for _, lang := range strings.Split(r.URL.Query().Get("lang"), ",") {
+		if lang = strings.ToLower(strings.TrimSpace(lang)); lang != "" {
+			langs = append(langs, lang)
+		}
+	}
+	return langs
+}
+
+// localize replaces names of addresses with variant in the first of langs they have,
+// name variants are looked up in name:<lang> tags and then in wikidata labels. Addresses
+// without any of langs keep the default name and empty language
+func localize(addresses []model.Address, langs []string) []model.Address {
+	if len(langs) == 0 {
+		return addresses
+	}
+	for n := range addresses {
+		a := &addresses[n]
+		if a.Name == "" {
+			continue
+		}
+		for _, lang := range langs {
+			name, ok := a.Names[lang]
+			if !ok && a.Wikidata != nil {
+				name, ok = a.Wikidata.Labels[lang]
+			}
+			if ok && name != "" {
+				a.Name, a.Language = name, lang
+				break
+			}
+		}
+	}
+	return addresses
+}
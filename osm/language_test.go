@@ -0,0 +1,47 @@
+package osm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maddevsio/ariadna/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalize(t *testing.T) {
+	names := nameVariants(map[string]string{"name": "Бишкек", "name:en": "Bishkek", "name:ky": "", "highway": "primary"})
+	assert.Equal(t, map[string]string{"en": "Bishkek"}, names)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/search/bishkek?lang=ky,%20RU,en", nil)
+	langs := requestLanguages(r)
+	assert.Equal(t, []string{"ky", "ru", "en"}, langs)
+
+	addresses := localize([]model.Address{
+		{Name: "Бишкек", Names: names},
+		{Name: "Ысык-Көл", Wikidata: &model.Wikidata{Labels: map[string]string{"ru": "Иссык-Куль"}}},
+		{Name: "Ош"},
+		{Street: "Киевская", HouseNumber: "1", Names: map[string]string{"en": "Kievskaya"}},
+	}, langs)
+	assert.Equal(t, "Bishkek", addresses[0].Name)
+	assert.Equal(t, "en", addresses[0].Language)
+	assert.Equal(t, "Иссык-Куль", addresses[1].Name)
+	assert.Equal(t, "ru", addresses[1].Language)
+	assert.Equal(t, "Ош", addresses[2].Name)
+	assert.Empty(t, addresses[2].Language)
+	assert.Empty(t, addresses[3].Name, "addresses without name are not labeled")
+}
+
+func TestLabeled(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/search/x?romanize=true", nil)
+	addresses := labeled(r, []model.Address{
+		{Name: "Ысык-Көл"},
+		{Prefix: "улица", Street: "Киевская", HouseNumber: "1"},
+	})
+	assert.Equal(t, "Ysyk-Köl", addresses[0].LabelRomanized)
+	assert.Equal(t, "Ысык-Көл", addresses[0].Name, "native label is kept")
+	assert.Equal(t, "ulitsa Kievskaya 1", addresses[1].LabelRomanized)
+
+	r = httptest.NewRequest(http.MethodGet, "/api/search/x", nil)
+	assert.Empty(t, labeled(r, []model.Address{{Name: "Ош"}})[0].LabelRomanized)
+}
@@ -0,0 +1,84 @@
+package osm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/model"
+)
+
+// importLayers are layers partial import can rebuild, plural names are accepted too
+var importLayers = map[string]string{
+	elastic.AddressLayer:   elastic.AddressLayer,
+	"addresses":            elastic.AddressLayer,
+	elastic.PoiLayer:       elastic.PoiLayer,
+	"pois":                 elastic.PoiLayer,
+	elastic.CrossroadLayer: elastic.CrossroadLayer,
+	"crossroads":           elastic.CrossroadLayer,
+	layerNatural:           layerNatural,
+	layerTransit:           layerTransit,
+	layerJunction:          layerJunction,
+	"junctions":            layerJunction,
+	layerPlace:             layerPlace,
+	"places":               layerPlace,
+	layerRoad:              layerRoad,
+	"roads":                layerRoad,
+	elastic.BoundaryLayer:  elastic.BoundaryLayer,
+	"boundaries":           elastic.BoundaryLayer,
+}
+
+// parseLayers validates layers of partial import, nil means full import
+func parseLayers(names []string) (map[string]bool, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	layers := make(map[string]bool, len(names))
+	for _, name := range names {
+		layer, ok := importLayers[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("unknown layer %q, available: %s", name, strings.Join(layerNames(), ", "))
+		}
+		layers[layer] = true
+	}
+	return layers, nil
+}
+
+func layerNames() []string {
+	var names []string
+	for name, layer := range importLayers {
+		if name == layer {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// documentLayer returns layer of document for partial import. Documents without layer are
+// addresses, named ones are POIs and intersections are crossroads
+func documentLayer(a model.Address) string {
+	switch {
+	case a.Layer != "":
+		return a.Layer
+	case a.Intersection:
+		return elastic.CrossroadLayer
+	case a.Name == "":
+		return elastic.AddressLayer
+	}
+	return elastic.PoiLayer
+}
+
+// selected reports whether stage builds documents of any layer of partial import
+func (s stage) selected(layers map[string]bool) bool {
+	if layers == nil {
+		return true
+	}
+	for _, layer := range s.layers {
+		if layers[layer] {
+			return true
+		}
+	}
+	return false
+}
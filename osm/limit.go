@@ -0,0 +1,85 @@
+package osm
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const defaultRetryAfter = time.Second
+
+// limiter bounds number of requests processed concurrently. Requests over the limit
+// wait in a bounded queue, the rest are shed with 503. Streaming sessions live until
+// client leaves, so they don't take slots
+type limiter struct {
+	slots      chan struct{}
+	queue      chan struct{}
+	wait       time.Duration
+	retryAfter time.Duration
+}
+
+func (i *Importer) withLimit(next http.Handler) http.Handler {
+	c := i.config.API
+	if c.MaxConcurrent <= 0 {
+		return next
+	}
+	l := &limiter{
+		slots:      make(chan struct{}, c.MaxConcurrent),
+		queue:      make(chan struct{}, c.MaxQueue),
+		wait:       c.QueueTimeout,
+		retryAfter: c.RetryAfter,
+	}
+	if l.retryAfter <= 0 {
+		l.retryAfter = defaultRetryAfter
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isStream(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !l.acquire(r) {
+			i.shed(w, r, l.retryAfter)
+			return
+		}
+		defer func() { <-l.slots }()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// acquire takes processing slot, waiting in queue if there is room there
+func (l *limiter) acquire(r *http.Request) bool {
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+	}
+	select {
+	case l.queue <- struct{}{}:
+	default:
+		return false
+	}
+	defer func() { <-l.queue }()
+	var timeout <-chan time.Time
+	if l.wait > 0 {
+		t := time.NewTimer(l.wait)
+		defer t.Stop()
+		timeout = t.C
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-timeout:
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+func (i *Importer) shed(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	seconds := int(retryAfter / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	i.writeFailure(w, r, http.StatusServiceUnavailable, BadRequest{Error: "server is overloaded", Code: "overloaded"})
+}
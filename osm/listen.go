@@ -0,0 +1,84 @@
+package osm
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	listenUnixPrefix = "unix:"
+	listenSystemd    = "systemd"
+	// systemdFirstFD is the first file descriptor passed by systemd socket activation
+	systemdFirstFD = 3
+)
+
+// listen opens listeners for addresses: host:port (IPv4 or IPv6 like [::1]:8080),
+// unix:/path/to.sock for Unix domain sockets and "systemd" for sockets passed by systemd
+func listen(addresses []string) ([]net.Listener, error) {
+	var listeners []net.Listener
+	closeAll := func() {
+		for _, l := range listeners {
+			l.Close()
+		}
+	}
+	for _, address := range addresses {
+		var opened []net.Listener
+		var err error
+		switch {
+		case address == listenSystemd:
+			opened, err = systemdListeners()
+		case strings.HasPrefix(address, listenUnixPrefix):
+			var l net.Listener
+			l, err = listenUnix(strings.TrimPrefix(address, listenUnixPrefix))
+			opened = []net.Listener{l}
+		default:
+			var l net.Listener
+			l, err = net.Listen("tcp", address)
+			opened = []net.Listener{l}
+		}
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("listen %s: %v", address, err)
+		}
+		listeners = append(listeners, opened...)
+	}
+	return listeners, nil
+}
+
+// listenUnix listens on Unix socket removing socket file left by previous run
+func listenUnix(path string) (net.Listener, error) {
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	return net.Listen("unix", path)
+}
+
+// systemdListeners returns sockets passed by systemd socket activation
+func systemdListeners() ([]net.Listener, error) {
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return nil, fmt.Errorf("no sockets passed by systemd")
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, fmt.Errorf("no sockets passed by systemd")
+	}
+	var listeners []net.Listener
+	for fd := systemdFirstFD; fd < systemdFirstFD+n; fd++ {
+		f := os.NewFile(uintptr(fd), "systemd-socket-"+strconv.Itoa(fd))
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			for _, opened := range listeners {
+				opened.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
@@ -0,0 +1,30 @@
+package osm
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "listen")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "ariadna.sock")
+
+	listeners, err := listen([]string{"127.0.0.1:0", "unix:" + socket})
+	require.NoError(t, err)
+	require.Len(t, listeners, 2)
+	assert.Equal(t, "tcp", listeners[0].Addr().Network())
+	assert.Equal(t, "unix", listeners[1].Addr().Network())
+	for _, l := range listeners {
+		l.Close()
+	}
+
+	_, err = listen([]string{"systemd"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,104 @@
+package osm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/model"
+)
+
+const (
+	endpointLookup = "lookup"
+	// maxLookupIDs limits objects fetched by single lookup request
+	maxLookupIDs = 100
+)
+
+var osmIDRe = regexp.MustCompile(`^(node|way|relation)/(\d+)$`)
+
+// documentIDs returns ids documents of OSM object like "node/123" are indexed under:
+// typed one and bare number used by addresses and crossroads of nodes and ways
+func documentIDs(ref string) ([]string, bool) {
+	m := osmIDRe.FindStringSubmatch(strings.TrimSpace(ref))
+	if m == nil {
+		return nil, false
+	}
+	if m[1] == "relation" {
+		return []string{m[0]}, true
+	}
+	return []string{m[0], m[2]}, true
+}
+
+// lookupIDs fetches documents of OSM objects keeping order of refs
+func (i *Importer) lookupIDs(ctx context.Context, refs []string) (*elastic.Result, error) {
+	var ids []string
+	for _, ref := range refs {
+		refIDs, ok := documentIDs(ref)
+		if !ok {
+			return nil, fmt.Errorf("invalid id %q", ref)
+		}
+		ids = append(ids, refIDs...)
+	}
+	found, err := i.e.Lookup(ctx, uniqString(ids))
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]model.Address, len(found.Addresses))
+	for _, a := range found.Addresses {
+		byID[a.ID] = a
+	}
+	result := &elastic.Result{Addresses: make([]model.Address, 0, len(found.Addresses)), TimedOut: found.TimedOut}
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if a, ok := byID[id]; ok && !seen[id] {
+			seen[id] = true
+			result.Addresses = append(result.Addresses, a)
+		}
+	}
+	return result, nil
+}
+
+// lookupHandler returns documents of OSM objects listed by ?ids=node/1,way/2,relation/3
+func (i *Importer) lookupHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	start := time.Now()
+	param := r.URL.Query().Get("ids")
+	var refs []string
+	for _, ref := range strings.Split(param, ",") {
+		if ref = strings.TrimSpace(ref); ref != "" {
+			refs = append(refs, ref)
+		}
+	}
+	if len(refs) == 0 {
+		i.writeFailure(w, r, http.StatusBadRequest, BadRequest{Error: "ids are required", Code: "invalid_request"})
+		return
+	}
+	if len(refs) > maxLookupIDs {
+		i.writeFailure(w, r, http.StatusBadRequest, BadRequest{
+			Error: fmt.Sprintf("too many ids, max %d", maxLookupIDs), Code: "invalid_request",
+		})
+		return
+	}
+	for _, ref := range refs {
+		if _, ok := documentIDs(ref); !ok {
+			i.writeFailure(w, r, http.StatusBadRequest, BadRequest{
+				Error: fmt.Sprintf("invalid id %q, expected node/<id>, way/<id> or relation/<id>", ref), Code: "invalid_request",
+			})
+			return
+		}
+	}
+	if i.notModified(w, r, endpointLookup) {
+		return
+	}
+	result, err := i.lookupIDs(r.Context(), refs)
+	if err != nil {
+		i.writeError(w, r, err)
+		return
+	}
+	i.observe(r, endpointLookup, param, "", result.Addresses, start)
+	i.writeResult(w, r, result, withCodes(result.Addresses))
+}
@@ -0,0 +1,48 @@
+package osm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookup(t *testing.T) {
+	storage := &memoryStorage{docs: map[string]model.Address{
+		"123":          {Street: "Киевская", HouseNumber: "1"},
+		"way/7":        {Name: "Чуй", Layer: layerRoad},
+		"relation/100": {Name: "Кыргызстан", Layer: layerBoundary},
+	}}
+	g, err := NewGeocoder(&config.Ariadna{}, WithStorage(storage))
+	require.NoError(t, err)
+	i := g.i
+	i.metrics = newQueryMetrics(0, true)
+	router := httprouter.New()
+	router.GET("/api/lookup", i.lookupHandler)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/lookup?ids=relation/100,node/123,way/7,node/5", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var addresses []model.Address
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&addresses))
+	require.Len(t, addresses, 3)
+	assert.Equal(t, "relation/100", addresses[0].ID)
+	assert.Equal(t, "123", addresses[1].ID)
+	assert.Equal(t, "way/7", addresses[2].ID)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/lookup?ids=node/1,street/2", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	result, err := i.geocode(context.Background(), "way/7", "", config.RankingProfile{})
+	require.NoError(t, err)
+	require.Len(t, result.Addresses, 1)
+	assert.Equal(t, "Чуй", result.Addresses[0].Name)
+}
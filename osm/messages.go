@@ -0,0 +1,181 @@
+package osm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// statusKey prefixes keys of catalogs holding titles of HTTP statuses, e.g. status.404
+const statusKey = "status."
+
+// builtinMessages are catalogs of error messages by language, keyed by error code and by status
+// key. English messages are the ones responses are written with
+var builtinMessages = map[string]map[string]string{
+	"ru": {
+		"invalid_request":    "Некорректный запрос",
+		"no_results":         "Ничего не найдено",
+		"boundary_not_found": "Граница не найдена",
+		"index_unavailable":  "Поиск временно недоступен",
+		"timeout":            "Время ожидания запроса истекло",
+		"not_supported":      "Возможность не поддерживается",
+		"internal":           "Внутренняя ошибка",
+		"not_acceptable":     "Запрошенный формат ответа не поддерживается",
+		"unauthorized":       "Требуется API ключ",
+		"forbidden":          "Доступ запрещён",
+		"expired":            "Срок действия ссылки истёк",
+		"quota_exceeded":     "Лимит запросов исчерпан",
+		"overloaded":         "Сервер перегружен, повторите позже",
+		"batch_too_large":    "Слишком много запросов в пакете",
+		"body_too_large":     "Слишком большой запрос",
+		"no_history":         "Нет данных на эту дату",
+		"geofence_not_found": "Геозона не найдена",
+		"invalid_geofence":   "Некорректная геозона",
+		"resync_required":    "Изменения недоступны, требуется полная синхронизация",
+		"status.400":         "Некорректный запрос",
+		"status.401":         "Требуется авторизация",
+		"status.403":         "Доступ запрещён",
+		"status.404":         "Не найдено",
+		"status.406":         "Неприемлемый формат",
+		"status.410":         "Больше недоступно",
+		"status.413":         "Слишком большой запрос",
+		"status.429":         "Слишком много запросов",
+		"status.500":         "Внутренняя ошибка сервера",
+		"status.501":         "Не реализовано",
+		"status.503":         "Сервис недоступен",
+		"status.504":         "Время ожидания истекло",
+	},
+	"ky": {
+		"invalid_request":    "Туура эмес суроо",
+		"no_results":         "Эч нерсе табылган жок",
+		"boundary_not_found": "Чек ара табылган жок",
+		"index_unavailable":  "Издөө убактылуу жеткиликсиз",
+		"timeout":            "Суроону күтүү убактысы бүттү",
+		"not_supported":      "Бул мүмкүнчүлүк колдоого алынбайт",
+		"internal":           "Ички ката",
+		"not_acceptable":     "Жооптун суралган форматы колдоого алынбайт",
+		"unauthorized":       "API ачкычы талап кылынат",
+		"forbidden":          "Кирүүгө тыюу салынган",
+		"expired":            "Шилтеменин мөөнөтү бүттү",
+		"quota_exceeded":     "Суроолордун лимити түгөндү",
+		"overloaded":         "Сервер ашыкча жүктөлгөн, кийинчерээк кайталаңыз",
+		"batch_too_large":    "Пакетте суроолор өтө көп",
+		"body_too_large":     "Суроо өтө чоң",
+		"no_history":         "Бул күнгө маалымат жок",
+		"geofence_not_found": "Геозона табылган жок",
+		"invalid_geofence":   "Геозона туура эмес",
+		"resync_required":    "Өзгөрүүлөр жеткиликсиз, толук синхрондоштуруу керек",
+		"status.400":         "Туура эмес суроо",
+		"status.401":         "Авторизация талап кылынат",
+		"status.403":         "Тыюу салынган",
+		"status.404":         "Табылган жок",
+		"status.406":         "Формат кабыл алынбайт",
+		"status.410":         "Мындан ары жеткиликсиз",
+		"status.413":         "Суроо өтө чоң",
+		"status.429":         "Суроолор өтө көп",
+		"status.500":         "Сервердин ички катасы",
+		"status.501":         "Ишке ашырылган эмес",
+		"status.503":         "Кызмат жеткиликсиз",
+		"status.504":         "Күтүү убактысы бүттү",
+	},
+}
+
+// catalogs are error messages by language and key
+type catalogs map[string]map[string]string
+
+// loadCatalogs returns built in catalogs extended by <lang>.json files of dir, messages of files
+// replace built in ones of the same key
+func loadCatalogs(dir string) (catalogs, error) {
+	c := make(catalogs, len(builtinMessages))
+	for lang, messages := range builtinMessages {
+		c[lang] = make(map[string]string, len(messages))
+		for key, message := range messages {
+			c[lang][key] = message
+		}
+	}
+	if dir == "" {
+		return c, nil
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return nil, fmt.Errorf("message catalog %s: %w", file, err)
+		}
+		lang := strings.ToLower(strings.TrimSuffix(filepath.Base(file), ".json"))
+		if c[lang] == nil {
+			c[lang] = make(map[string]string, len(messages))
+		}
+		for key, message := range messages {
+			c[lang][key] = message
+		}
+	}
+	return c, nil
+}
+
+// language picks the most preferred language of Accept-Language header having catalog. Regional
+// tags like ru-KG fall back to their language, empty result means English
+func (c catalogs) language(header string) string {
+	type tag struct {
+		lang string
+		q    float64
+	}
+	var tags []tag
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		t := tag{lang: strings.ToLower(strings.TrimSpace(fields[0])), q: 1}
+		for _, param := range fields[1:] {
+			if param = strings.TrimSpace(param); strings.HasPrefix(param, "q=") {
+				t.q, _ = strconv.ParseFloat(param[2:], 64)
+			}
+		}
+		if t.lang != "" && t.q > 0 {
+			tags = append(tags, t)
+		}
+	}
+	sort.SliceStable(tags, func(a, b int) bool { return tags[a].q > tags[b].q })
+	for _, t := range tags {
+		if t.lang == "en" || strings.HasPrefix(t.lang, "en-") {
+			return ""
+		}
+		for _, lang := range []string{t.lang, strings.SplitN(t.lang, "-", 2)[0]} {
+			if _, ok := c[lang]; ok {
+				return lang
+			}
+		}
+	}
+	return ""
+}
+
+// localize translates title and detail of problem to language of Accept-Language header.
+// Messages missing in catalog stay English
+func (c catalogs) localize(w http.ResponseWriter, r *http.Request, p *problem, code string) {
+	if r == nil {
+		return
+	}
+	w.Header().Add("Vary", "Accept-Language")
+	lang := c.language(r.Header.Get("Accept-Language"))
+	if lang == "" {
+		return
+	}
+	messages := c[lang]
+	if title, ok := messages[statusKey+strconv.Itoa(p.Status)]; ok {
+		p.Title = title
+	}
+	if detail, ok := messages[code]; ok {
+		p.Detail = detail
+	}
+	w.Header().Set("Content-Language", lang)
+}
@@ -0,0 +1,51 @@
+package osm
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalizedErrors(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "de.json"), []byte(`{"invalid_request": "Ungültige Anfrage", "status.400": "Ungültige Anfrage"}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ru.json"), []byte(`{"invalid_request": "Неверный запрос"}`), 0644))
+	g, err := NewGeocoder(&config.Ariadna{API: config.API{Messages: dir}}, WithStorage(&memoryStorage{}))
+	require.NoError(t, err)
+	router := httprouter.New()
+	router.GET("/api/reverse/:lat/:lon", g.i.reverseGeoCodeHandler)
+	get := func(language string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		r := httptest.NewRequest(http.MethodGet, "/api/reverse/91/74.59", nil)
+		r.Header.Set("Accept-Language", language)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w, body
+	}
+
+	w, body := get("ky-KG, ru;q=0.8")
+	assert.Equal(t, "ky", w.Header().Get("Content-Language"))
+	assert.Equal(t, "Туура эмес суроо", body["detail"])
+	assert.Contains(t, body["error"], "invalid lat", "error stays English for clients matching it")
+
+	_, body = get("fr, ru-RU;q=0.9, ky;q=0.5")
+	assert.Equal(t, "Неверный запрос", body["detail"], "catalog file replaces built in message")
+	assert.Equal(t, "Некорректный запрос", body["title"])
+
+	_, body = get("de")
+	assert.Equal(t, "Ungültige Anfrage", body["title"])
+
+	w, body = get("en-US, ru;q=0.5")
+	assert.Empty(t, w.Header().Get("Content-Language"))
+	assert.Equal(t, "Bad Request", body["title"])
+	assert.Contains(t, body["detail"], "invalid lat")
+}
@@ -0,0 +1,151 @@
+package osm
+
+import (
+	"math/rand"
+	"net/http"
+	"sync"
+
+	"github.com/julienschmidt/httprouter"
+	v1 "github.com/maddevsio/ariadna/schema/v1"
+)
+
+const defaultQueryLogSize = 1000
+
+type (
+	// queryMetrics counts requests and zero-result responses per endpoint and keeps
+	// uniform reservoir sample of queries which returned nothing
+	queryMetrics struct {
+		mu        sync.Mutex
+		endpoints map[string]*endpointStats
+		samples   []zeroQuery
+		seen      int64
+		size      int
+		disabled  bool
+	}
+	endpointStats struct {
+		Total       int64 `json:"total"`
+		ZeroResults int64 `json:"zero_results"`
+	}
+	zeroQuery struct {
+		Endpoint string `json:"endpoint"`
+		Query    string `json:"query"`
+	}
+	queryMetricsResponse struct {
+		Endpoints   map[string]endpointStats `json:"endpoints"`
+		ZeroQueries []zeroQuery              `json:"zero_queries"`
+		// Caches counts hits of enabled caches by name
+		Caches map[string]cacheStats `json:"caches,omitempty"`
+	}
+)
+
+func newQueryMetrics(size int, disableLog bool) *queryMetrics {
+	if size <= 0 {
+		size = defaultQueryLogSize
+	}
+	return &queryMetrics{
+		endpoints: make(map[string]*endpointStats),
+		size:      size,
+		disabled:  disableLog,
+	}
+}
+
+// record accounts request to endpoint which returned n results
+func (m *queryMetrics) record(endpoint, query string, n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats, ok := m.endpoints[endpoint]
+	if !ok {
+		stats = &endpointStats{}
+		m.endpoints[endpoint] = stats
+	}
+	stats.Total++
+	if n > 0 {
+		return
+	}
+	stats.ZeroResults++
+	if m.disabled {
+		return
+	}
+	m.seen++
+	q := zeroQuery{Endpoint: endpoint, Query: query}
+	if len(m.samples) < m.size {
+		m.samples = append(m.samples, q)
+		return
+	}
+	if idx := rand.Int63n(m.seen); idx < int64(m.size) {
+		m.samples[idx] = q
+	}
+}
+
+func (m *queryMetrics) snapshot() queryMetricsResponse {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r := queryMetricsResponse{
+		Endpoints:   make(map[string]endpointStats, len(m.endpoints)),
+		ZeroQueries: append([]zeroQuery{}, m.samples...),
+	}
+	for name, stats := range m.endpoints {
+		r.Endpoints[name] = *stats
+	}
+	return r
+}
+
+// indexStatsHandler reports documents per layer and category, index size and the latest import
+func (i *Importer) indexStatsHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	stats, err := i.e.Stats(r.Context())
+	if err != nil {
+		i.writeError(w, r, err)
+		return
+	}
+	if schemaVersion(r) != v1.Version {
+		i.writeJSON(w, http.StatusOK, stats)
+		return
+	}
+	body := v1.IndexStats{
+		SchemaVersion: v1.Version,
+		Documents:     stats.Documents,
+		SizeBytes:     stats.SizeBytes,
+		Layers:        make(map[string]v1.LayerStats, len(stats.Layers)),
+	}
+	for name, l := range stats.Layers {
+		body.Layers[name] = v1.LayerStats{Documents: l.Documents, Categories: l.Categories}
+	}
+	if stats.Import != nil {
+		body.Import = &v1.ImportInfo{ImportedAt: stats.Import.ImportedAt, ExtractTime: stats.Import.ExtractTime, Source: stats.Import.Source}
+	}
+	i.writeV1(w, http.StatusOK, body)
+}
+
+// queryMetricsHandler reports request counts of endpoints. Sampled query strings may hold
+// addresses of users, they are sent only to requests with a debug key
+func (i *Importer) queryMetricsHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	snapshot := i.metrics.snapshot()
+	if status, _ := keyAllowed(r, i.config.API.DebugKeys, "query log"); status != http.StatusOK {
+		snapshot.ZeroQueries = []zeroQuery{}
+	}
+	if i.reverseCache != nil {
+		snapshot.Caches = map[string]cacheStats{endpointReverse: i.reverseCache.stats()}
+	}
+	if schemaVersion(r) != v1.Version {
+		i.writeJSON(w, http.StatusOK, snapshot)
+		return
+	}
+	stats := v1.QueryStats{
+		SchemaVersion: v1.Version,
+		Endpoints:     make(map[string]v1.EndpointStats, len(snapshot.Endpoints)),
+		ZeroQueries:   make([]v1.ZeroQuery, 0, len(snapshot.ZeroQueries)),
+	}
+	for name, e := range snapshot.Endpoints {
+		stats.Endpoints[name] = v1.EndpointStats{Total: e.Total, ZeroResults: e.ZeroResults}
+	}
+	if len(snapshot.Caches) > 0 {
+		stats.Caches = make(map[string]v1.CacheStats, len(snapshot.Caches))
+		for name, c := range snapshot.Caches {
+			stats.Caches[name] = v1.CacheStats(c)
+		}
+	}
+	for _, q := range snapshot.ZeroQueries {
+		stats.ZeroQueries = append(stats.ZeroQueries, v1.ZeroQuery{Endpoint: q.Endpoint, Query: q.Query})
+	}
+	i.writeV1(w, http.StatusOK, stats)
+}
@@ -0,0 +1,40 @@
+package osm
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryMetricsRequireKey(t *testing.T) {
+	g, err := NewGeocoder(&config.Ariadna{API: config.API{DebugKeys: []string{"debug"}}}, WithStorage(&memoryStorage{docs: map[string]model.Address{}}))
+	require.NoError(t, err)
+	i := g.i
+	i.metrics = newQueryMetrics(0, false)
+	i.metrics.record(endpointSearch, "Киевская 1, кв 5", 0)
+	router := httprouter.New()
+	router.GET("/api/status/queries", i.queryMetricsHandler)
+	get := func(path string) queryMetricsResponse {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		var metrics queryMetricsResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &metrics))
+		return metrics
+	}
+
+	metrics := get("/api/status/queries")
+	assert.Equal(t, int64(1), metrics.Endpoints[endpointSearch].ZeroResults)
+	assert.Empty(t, metrics.ZeroQueries, "queries are not shown without key")
+	assert.Empty(t, get("/api/status/queries?api_key=other").ZeroQueries)
+	metrics = get("/api/status/queries?api_key=debug")
+	require.Len(t, metrics.ZeroQueries, 1)
+	assert.Equal(t, "Киевская 1, кв 5", metrics.ZeroQueries[0].Query)
+}
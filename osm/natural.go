@@ -0,0 +1,142 @@
+package osm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/maddevsio/ariadna/model"
+	"github.com/missinglink/gosmparse"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+const layerNatural = "natural"
+
+func (i *Importer) naturalToElastic(ctx context.Context) error {
+	i.logger.Info("started to search natural features")
+	buf, err := i.getNaturalFeatures()
+	if err != nil {
+		return err
+	}
+	i.logger.Info("natural features found")
+	return i.bulkWrite(ctx, buf)
+}
+
+func (i *Importer) getNaturalFeatures() (bytes.Buffer, error) {
+	var buf bytes.Buffer
+	for wayID, way := range i.handler.NaturalWays {
+		geometry := i.wayGeometry(way)
+		if geometry == nil {
+			continue
+		}
+		if err := i.writeNatural(&buf, fmt.Sprintf("way/%d", wayID), way.Tags, i.wayCenter(way), geometry); err != nil {
+			return buf, err
+		}
+	}
+	for relationID, relation := range i.handler.NaturalAreas {
+		geometry := i.relationGeometry(relation)
+		if geometry == nil {
+			continue
+		}
+		if err := i.writeNatural(&buf, fmt.Sprintf("relation/%d", relationID), relation.Tags, geometryCenter(geometry), geometry); err != nil {
+			return buf, err
+		}
+	}
+	return buf, nil
+}
+
+func (i *Importer) writeNatural(buf *bytes.Buffer, id string, tags map[string]string, center model.Location, geometry *geojson.Geometry) error {
+	if !i.inClip(center.Lat, center.Lon) {
+		return nil
+	}
+	address := model.Address{
+		Name:     tags["name"],
+		Names:    nameVariants(tags),
+		Layer:    layerNatural,
+		Category: naturalCategory(tags),
+		Location: center,
+		Geometry: geometry,
+		Fields:   i.extractFields(tags),
+	}
+	if setPolygonMetrics(&address, geometry) {
+		address.Location = *address.LabelPoint
+	}
+	i.locate(&address)
+	i.enrichWikidata(&address, tags)
+	return i.writeDocument(buf, id, address)
+}
+
+func naturalCategory(tags map[string]string) string {
+	switch {
+	case tags["place"] == "island" || tags["place"] == "islet":
+		return tags["place"]
+	case tags["natural"] != "":
+		return tags["natural"]
+	}
+	return tags["waterway"]
+}
+
+func (i *Importer) wayCoords(way gosmparse.Way) [][]float64 {
+	return i.nodeCoords(way.NodeIDs)
+}
+
+// wayGeometry returns polygon for closed ways and line string for open ones
+func (i *Importer) wayGeometry(way gosmparse.Way) *geojson.Geometry {
+	coords := i.wayCoords(way)
+	if len(coords) < 2 {
+		return nil
+	}
+	if isClosed(way) && len(coords) >= 4 {
+		return geojson.NewPolygonGeometry([][][]float64{coords})
+	}
+	return geojson.NewLineStringGeometry(coords)
+}
+
+// relationGeometry assembles outer ways of relation into rings with inner ways as holes,
+// see boundaryPolygons. Relations which ways don't close, like rivers, become multi line strings
+func (i *Importer) relationGeometry(relation gosmparse.Relation) *geojson.Geometry {
+	outer, inner := i.relationWays(relation)
+	rings, _ := assembleRings(outer)
+	if polygons, _ := i.ringPolygons(rings); len(polygons) > 0 {
+		return geojson.NewMultiPolygonGeometry(i.addHoles(polygons, inner)...)
+	}
+	var lines [][][]float64
+	for _, way := range outer {
+		if coords := i.nodeCoords(way); len(coords) >= 2 {
+			lines = append(lines, coords)
+		}
+	}
+	if len(lines) > 0 {
+		return geojson.NewMultiLineStringGeometry(lines...)
+	}
+	return nil
+}
+
+func isClosed(way gosmparse.Way) bool {
+	return len(way.NodeIDs) > 2 && way.NodeIDs[0] == way.NodeIDs[len(way.NodeIDs)-1]
+}
+
+// geometryCenter returns average of first ring or line vertices
+func geometryCenter(g *geojson.Geometry) model.Location {
+	var coords [][]float64
+	switch {
+	case g.IsPolygon():
+		coords = g.Polygon[0]
+	case g.IsMultiPolygon():
+		coords = g.MultiPolygon[0][0]
+	case g.IsLineString():
+		coords = g.LineString
+	case g.IsMultiLineString():
+		coords = g.MultiLineString[0]
+	}
+	var location model.Location
+	for _, c := range coords {
+		location.Lon += c[0]
+		location.Lat += c[1]
+	}
+	if len(coords) > 0 {
+		location.Lon /= float64(len(coords))
+		location.Lat /= float64(len(coords))
+	}
+	return location
+}
@@ -0,0 +1,46 @@
+package osm
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/maddevsio/ariadna/elastic"
+)
+
+const transitDistance = "1km"
+
+var (
+	nearRe       = regexp.MustCompile(`(?i)^(.+?)\s+(?:near|around|около|возле|рядом с)\s+(.+)$`)
+	transitWords = map[string]bool{
+		"bus stop":  true,
+		"stop":      true,
+		"station":   true,
+		"остановка": true,
+		"станция":   true,
+		"вокзал":    true,
+	}
+)
+
+// splitNear splits queries like "bus stop near Ala-Too" into transit part and place
+func splitNear(query string) (string, string, bool) {
+	m := nearRe.FindStringSubmatch(query)
+	if m == nil || !transitWords[strings.ToLower(strings.TrimSpace(m[1]))] {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
+// searchTransitNear finds place and returns transit stops around it
+func (i *Importer) searchTransitNear(ctx context.Context, place string) (*elastic.Result, error) {
+	places, err := i.e.Search(ctx, place)
+	if err != nil {
+		return nil, err
+	}
+	if len(places.Addresses) == 0 {
+		return nil, fmt.Errorf("place %q: %w", place, ErrNoResults)
+	}
+	location := places.Addresses[0].Location
+	return i.e.Nearby(ctx, layerTransit, location.Lat, location.Lon, transitDistance)
+}
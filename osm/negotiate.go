@@ -0,0 +1,87 @@
+package osm
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	v1 "github.com/maddevsio/ariadna/schema/v1"
+)
+
+// legacySchema is unversioned response body, kept for clients not sending versioned Accept
+const legacySchema = 0
+
+type schemaKey struct{}
+
+// negotiated is response schema selected by Accept header
+type negotiated struct {
+	version  int
+	protobuf bool
+}
+
+// withSchema negotiates response schema version by Accept header. Clients select versioned schema
+// with "application/vnd.ariadna.v1+json" or "application/json; version=1", or its binary encoding with
+// "application/vnd.ariadna.v1+protobuf". Unsupported versions get 406. Static files are served as is
+func (i *Importer) withSchema(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Accept")
+		schema, ok := acceptedSchema(r.Header.Get("Accept"))
+		if !ok {
+			i.writeFailure(w, r, http.StatusNotAcceptable, BadRequest{
+				Error: "supported media types: application/json, " + v1.MediaType + ", " + v1.ProtobufMediaType,
+				Code:  "not_acceptable",
+			})
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), schemaKey{}, schema)))
+	})
+}
+
+// schemaVersion returns negotiated schema version of request
+func schemaVersion(r *http.Request) int {
+	schema, _ := r.Context().Value(schemaKey{}).(negotiated)
+	return schema.version
+}
+
+// wantsProtobuf reports whether request negotiated protobuf encoding of results
+func wantsProtobuf(r *http.Request) bool {
+	schema, _ := r.Context().Value(schemaKey{}).(negotiated)
+	return schema.protobuf
+}
+
+// acceptedSchema picks the first supported schema of Accept header, false means none is supported
+func acceptedSchema(accept string) (negotiated, bool) {
+	legacy := negotiated{version: legacySchema}
+	if strings.TrimSpace(accept) == "" {
+		return legacy, true
+	}
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(mediaRange)
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case v1.MediaType:
+			return negotiated{version: v1.Version}, true
+		case v1.ProtobufMediaType:
+			return negotiated{version: v1.Version, protobuf: true}, true
+		case "application/json":
+			version, ok := params["version"]
+			if !ok {
+				return legacy, true
+			}
+			if n, err := strconv.Atoi(version); err == nil && n == v1.Version {
+				return negotiated{version: v1.Version}, true
+			}
+		case "application/*", "*/*":
+			return legacy, true
+		}
+	}
+	return legacy, false
+}
@@ -0,0 +1,62 @@
+package osm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcceptedSchema(t *testing.T) {
+	cases := []struct {
+		accept string
+		schema negotiated
+		ok     bool
+	}{
+		{"", negotiated{}, true},
+		{"application/json", negotiated{}, true},
+		{"text/html, */*;q=0.8", negotiated{}, true},
+		{"application/vnd.ariadna.v1+json", negotiated{version: 1}, true},
+		{"application/json; version=1", negotiated{version: 1}, true},
+		{"application/vnd.ariadna.v2+json, application/vnd.ariadna.v1+json", negotiated{version: 1}, true},
+		{"application/vnd.ariadna.v1+protobuf, application/vnd.ariadna.v1+json", negotiated{version: 1, protobuf: true}, true},
+		{"application/vnd.ariadna.v2+json", negotiated{}, false},
+		{"application/json; version=2", negotiated{}, false},
+		{"text/csv", negotiated{}, false},
+	}
+	for _, c := range cases {
+		schema, ok := acceptedSchema(c.accept)
+		assert.Equal(t, c.schema, schema, c.accept)
+		assert.Equal(t, c.ok, ok, c.accept)
+	}
+}
+
+func TestTimedOutFlag(t *testing.T) {
+	g, err := NewGeocoder(&config.Ariadna{}, WithStorage(&memoryStorage{}))
+	require.NoError(t, err)
+	i := g.i
+	handler := i.withSchema(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		addresses := []model.Address{{Name: "Киевская"}}
+		i.writeResult(w, r, &elastic.Result{Addresses: addresses, TimedOut: true}, addresses)
+	}))
+	get := func(accept string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api/search/Киевская", nil)
+		r.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	// the plain array of legacy responses has no room for the flag, the header is their contract
+	w := get("")
+	assert.Equal(t, "true", w.Header().Get("X-Timed-Out"))
+	assert.Equal(t, byte('['), w.Body.Bytes()[0])
+	w = get("application/vnd.ariadna.v1+json")
+	assert.Equal(t, "true", w.Header().Get("X-Timed-Out"))
+	assert.Contains(t, w.Body.String(), `"timed_out":true`)
+}
@@ -0,0 +1,198 @@
+package osm
+
+import (
+	"math"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// maxRingSplits limits repair of rings crossing themselves too many times
+const maxRingSplits = 100
+
+// normalizeGeometry prepares geometry for geo_shape field which rejects invalid shapes:
+// repeated vertices are dropped, rings are closed, self-intersecting rings are split into
+// simple ones and wound counterclockwise, holes clockwise, per RFC 7946.
+// Nil is returned when nothing valid is left
+func normalizeGeometry(g *geojson.Geometry) *geojson.Geometry {
+	if g == nil {
+		return nil
+	}
+	switch {
+	case g.IsPoint():
+		if !validCoord(g.Point) {
+			return nil
+		}
+		return g
+	case g.IsLineString():
+		line := cleanCoords(g.LineString)
+		if len(line) < 2 {
+			return nil
+		}
+		return geojson.NewLineStringGeometry(line)
+	case g.IsMultiLineString():
+		var lines [][][]float64
+		for _, line := range g.MultiLineString {
+			if line = cleanCoords(line); len(line) >= 2 {
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) == 0 {
+			return nil
+		}
+		return geojson.NewMultiLineStringGeometry(lines...)
+	case g.IsPolygon(), g.IsMultiPolygon():
+		polygons := normalizePolygons(geometryPolygons(g))
+		switch len(polygons) {
+		case 0:
+			return nil
+		case 1:
+			if g.IsPolygon() {
+				return geojson.NewPolygonGeometry(polygons[0])
+			}
+		}
+		return geojson.NewMultiPolygonGeometry(polygons...)
+	}
+	return g
+}
+
+func normalizePolygons(polygons [][][][]float64) [][][][]float64 {
+	var (
+		result [][][][]float64
+		holes  [][][]float64
+	)
+	for _, polygon := range polygons {
+		for n, ring := range polygon {
+			for _, simple := range simpleRings(ring) {
+				if n == 0 {
+					result = append(result, [][][]float64{windRing(simple, true)})
+					continue
+				}
+				holes = append(holes, windRing(simple, false))
+			}
+		}
+	}
+	// holes are attached to the first outer ring containing them, split outer rings may have moved
+	for _, hole := range holes {
+		for n, polygon := range result {
+			if ringContains(polygon[0], hole[0]) {
+				result[n] = append(result[n], hole)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// simpleRings closes ring and splits it at points where it crosses itself. Rings of
+// less than three distinct vertices are dropped
+func simpleRings(ring [][]float64) [][][]float64 {
+	ring = cleanCoords(ring)
+	if len(ring) > 1 && ring[0][0] == ring[len(ring)-1][0] && ring[0][1] == ring[len(ring)-1][1] {
+		ring = ring[:len(ring)-1]
+	}
+	pending := [][][]float64{ring}
+	var result [][][]float64
+	for splits := 0; len(pending) > 0; {
+		ring := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if len(ring) < 3 {
+			continue
+		}
+		if splits < maxRingSplits {
+			if first, second, ok := splitRing(ring); ok {
+				splits++
+				pending = append(pending, first, second)
+				continue
+			}
+		}
+		if signedArea(ring) == 0 {
+			continue
+		}
+		result = append(result, append(ring, ring[0]))
+	}
+	return result
+}
+
+// splitRing splits open ring at the first crossing of two of its edges into two loops
+// sharing crossing point
+func splitRing(ring [][]float64) ([][]float64, [][]float64, bool) {
+	n := len(ring)
+	for i := 0; i < n; i++ {
+		a, b := ring[i], ring[(i+1)%n]
+		for j := i + 2; j < n; j++ {
+			if i == 0 && j == n-1 {
+				// edges are adjacent through the closing vertex
+				continue
+			}
+			c, d := ring[j], ring[(j+1)%n]
+			x, ok := crossing(a, b, c, d)
+			if !ok {
+				continue
+			}
+			first := append(append(append([][]float64{}, ring[:i+1]...), x), ring[j+1:]...)
+			second := append([][]float64{x}, ring[i+1:j+1]...)
+			return first, second, true
+		}
+	}
+	return nil, nil, false
+}
+
+// crossing returns point where segments a-b and c-d properly cross
+func crossing(a, b, c, d []float64) ([]float64, bool) {
+	p := [2]float64{a[0], a[1]}
+	r := [2]float64{b[0] - a[0], b[1] - a[1]}
+	q := [2]float64{c[0], c[1]}
+	s := [2]float64{d[0] - c[0], d[1] - c[1]}
+	denom := r[0]*s[1] - r[1]*s[0]
+	if denom == 0 {
+		return nil, false
+	}
+	t := ((q[0]-p[0])*s[1] - (q[1]-p[1])*s[0]) / denom
+	u := ((q[0]-p[0])*r[1] - (q[1]-p[1])*r[0]) / denom
+	if t <= 0 || t >= 1 || u <= 0 || u >= 1 {
+		return nil, false
+	}
+	return []float64{p[0] + t*r[0], p[1] + t*r[1]}, true
+}
+
+// windRing returns closed ring wound counterclockwise or clockwise
+func windRing(ring [][]float64, counterclockwise bool) [][]float64 {
+	if (signedArea(ring) > 0) == counterclockwise {
+		return ring
+	}
+	reversed := make([][]float64, len(ring))
+	for n, c := range ring {
+		reversed[len(ring)-1-n] = c
+	}
+	return reversed
+}
+
+// signedArea returns doubled planar area of ring, positive for counterclockwise rings
+func signedArea(ring [][]float64) float64 {
+	var sum float64
+	for n := range ring {
+		a, b := ring[n], ring[(n+1)%len(ring)]
+		sum += a[0]*b[1] - b[0]*a[1]
+	}
+	return sum
+}
+
+// cleanCoords drops invalid and repeated consecutive coordinates
+func cleanCoords(coords [][]float64) [][]float64 {
+	result := make([][]float64, 0, len(coords))
+	for _, c := range coords {
+		if !validCoord(c) {
+			continue
+		}
+		if last := len(result) - 1; last >= 0 && result[last][0] == c[0] && result[last][1] == c[1] {
+			continue
+		}
+		result = append(result, c)
+	}
+	return result
+}
+
+func validCoord(c []float64) bool {
+	return len(c) >= 2 && !math.IsNaN(c[0]) && !math.IsNaN(c[1]) &&
+		c[0] >= -180 && c[0] <= 180 && c[1] >= -90 && c[1] <= 90
+}
@@ -0,0 +1,50 @@
+package osm
+
+import (
+	"testing"
+
+	geojson "github.com/paulmach/go.geojson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeGeometryWinding(t *testing.T) {
+	// clockwise outer ring which is not closed and has repeated vertex, counterclockwise hole
+	g := normalizeGeometry(geojson.NewPolygonGeometry([][][]float64{
+		{{0, 0}, {0, 4}, {0, 4}, {4, 4}, {4, 0}},
+		{{1, 1}, {2, 1}, {2, 2}, {1, 2}, {1, 1}},
+	}))
+	require.NotNil(t, g)
+	require.True(t, g.IsPolygon())
+	require.Len(t, g.Polygon, 2)
+	outer, hole := g.Polygon[0], g.Polygon[1]
+	assert.Len(t, outer, 5)
+	assert.Equal(t, outer[0], outer[len(outer)-1], "ring is closed")
+	assert.True(t, signedArea(outer) > 0, "outer ring is counterclockwise")
+	assert.True(t, signedArea(hole) < 0, "hole is clockwise")
+}
+
+func TestNormalizeGeometrySelfIntersection(t *testing.T) {
+	// bowtie crossing itself at (1, 1)
+	g := normalizeGeometry(geojson.NewPolygonGeometry([][][]float64{
+		{{0, 0}, {2, 2}, {2, 0}, {0, 2}, {0, 0}},
+	}))
+	require.NotNil(t, g)
+	require.True(t, g.IsMultiPolygon())
+	require.Len(t, g.MultiPolygon, 2)
+	for _, polygon := range g.MultiPolygon {
+		assert.Len(t, polygon[0], 4)
+		assert.True(t, signedArea(polygon[0]) > 0)
+		_, _, crosses := splitRing(polygon[0][:len(polygon[0])-1])
+		assert.False(t, crosses)
+	}
+}
+
+func TestNormalizeGeometryInvalid(t *testing.T) {
+	assert.Nil(t, normalizeGeometry(geojson.NewPolygonGeometry([][][]float64{{{0, 0}, {1, 1}, {0, 0}}})))
+	assert.Nil(t, normalizeGeometry(geojson.NewLineStringGeometry([][]float64{{1, 1}, {1, 1}})))
+	assert.Nil(t, normalizeGeometry(geojson.NewPointGeometry([]float64{200, 0})))
+	line := normalizeGeometry(geojson.NewLineStringGeometry([][]float64{{1, 1}, {1, 1}, {2, 2}}))
+	require.NotNil(t, line)
+	assert.Equal(t, [][]float64{{1, 1}, {2, 2}}, line.LineString)
+}
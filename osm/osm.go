@@ -1,15 +1,22 @@
 package osm
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"net"
 	"net/http"
 	"os"
+	"sort"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
-	geo "github.com/kellydunn/golang-geo"
+	"github.com/maddevsio/ariadna/boundaries"
 	"github.com/maddevsio/ariadna/config"
 	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/elevation"
+	"github.com/maddevsio/ariadna/geodesic"
+	"github.com/maddevsio/ariadna/memory"
+	"github.com/maddevsio/ariadna/model"
 	"github.com/maddevsio/ariadna/osm/handler"
 	"github.com/maddevsio/ariadna/osm/parser"
 	"github.com/missinglink/gosmparse"
@@ -17,82 +24,308 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
+const (
+	// maxResolvePasses is enough to load missing ways and then their nodes
+	maxResolvePasses   = 2
+	defaultListen      = ":8080"
+	writeTimeoutMargin = time.Second
+)
+
 // Importer struct represents needed values to import data to elasticsearch
 type (
 	Importer struct {
-		handler   *handler.Handler
-		parser    *parser.Parser
-		config    *config.Ariadna
-		e         *elastic.Client
-		eg        errgroup.Group
-		logger    *logrus.Logger
-		countries []country
+		handler    *handler.Handler
+		parser     Parser
+		config     *config.Ariadna
+		e          Storage
+		downloader Downloader
+		eg         *errgroup.Group
+		failures   failures
+		logger     *logrus.Logger
+		countries  []country
+		clip       []*geodesic.Polygon
+		wikidata   map[string]model.Wikidata
+		elevation  *elevation.Tiles
+		timezones  []timezone
+		resolvers  []QueryResolver
+		processors []DocumentProcessor
+		changes    *changeLog
+		fallback   *fallback
+		metrics    *queryMetrics
+		analytics  chan analyticsEvent
+		version    indexVersion
+		// areas collects admin boundaries saved to boundaries_file
+		areas []boundaries.Area
+		// countryIndex answers country endpoint from boundaries in memory
+		countryIndex countryIndex
+		// geofenceIndex answers geofence checks from registered fences in memory
+		geofenceIndex geofenceIndex
+		// reverseCache answers reverse geocoding of nearby points, nil when disabled
+		reverseCache *reverseCache
+		// history caches slices of imports answering ?as_of= queries
+		history historySlices
+		// usage counts requests of API keys against quotas
+		usage usageCounter
+		// access restricts client networks, nil when every client is served
+		access *accessControl
+		// messages translate error responses by Accept-Language
+		messages catalogs
+		// audit collects timings of stages of import run, nil for geocoder
+		audit *importAudit
+		// extractTime is modification time of downloaded extract
+		extractTime *time.Time
+		// layers limits partial import to documents of these layers, nil for full import
+		layers map[string]bool
 	}
 	country struct {
-		name  string
-		towns []city
-		geom  *geo.Polygon
+		name     string
+		towns    []city
+		geom     *areaShape
+		timezone string
 	}
 	city struct {
 		name      string
 		placeType string
-		geom      *geo.Polygon
+		geom      *areaShape
 		districts []district
+		timezone  string
 	}
 	district struct {
 		name string
-		geom *geo.Polygon
+		geom *geodesic.Polygon
+		// center and radius describe district mapped only as place node
+		center *geodesic.Point
+		radius float64
 	}
 )
 
-// NewImporter creates new instance of importer
-func NewImporter(c *config.Ariadna) (*Importer, error) {
-	i := &Importer{config: c, logger: logrus.New()}
-	if err := i.download(); err != nil {
-		return nil, err
+// NewImporter creates new instance of importer. By default it downloads configured extract,
+// parses it from disk and stores documents in elasticsearch, options replace any of these
+func NewImporter(ctx context.Context, c *config.Ariadna, opts ...Option) (*Importer, error) {
+	i := &Importer{config: c, logger: logrus.New(), downloader: newHTTPDownloader(c), eg: &errgroup.Group{}}
+	for _, opt := range opts {
+		opt(i)
 	}
-	p, err := parser.NewParser(c.OSMFilename)
+	i.metrics = newQueryMetrics(c.API.QueryLogSize, c.API.DisableLog)
+	i.changes = newChangeLog()
+	i.reverseCache = newReverseCache(c.API.ReverseCache)
+	access, err := newAccessControl(c.API.Access)
 	if err != nil {
 		return nil, err
 	}
-	i.parser = p
-	e, err := elastic.New(c)
+	i.access = access
+	if i.messages, err = loadCatalogs(c.API.Messages); err != nil {
+		return nil, err
+	}
+	if c.ClipPolygon != "" {
+		clip, err := loadClip(c.ClipPolygon)
+		if err != nil {
+			return nil, err
+		}
+		i.clip = clip
+		i.logger.Infof("import clipped by %s", c.ClipPolygon)
+	}
+	if c.Timezones != "" {
+		zones, err := loadTimezones(c.Timezones)
+		if err != nil {
+			return nil, err
+		}
+		i.timezones = zones
+	}
+	if c.ElevationDir != "" {
+		i.elevation = elevation.New(c.ElevationDir)
+	}
+	if err := validateFields(c.Fields); err != nil {
+		return nil, err
+	}
+	if err := validateProfiles(c.Ranking.Profiles, c.Fields); err != nil {
+		return nil, err
+	}
+	layers, err := parseLayers(c.ImportLayers)
 	if err != nil {
 		return nil, err
 	}
-	i.e = e
+	i.layers = layers
+	i.audit = newImportAudit(c.OSMURL, layers)
+	if err := i.setupFallback(); err != nil {
+		return nil, err
+	}
+	for _, path := range c.Plugins {
+		p, err := loadProcessor(path)
+		if err != nil {
+			return nil, err
+		}
+		i.RegisterProcessor(p)
+		i.logger.Infof("document processor loaded from %s", path)
+	}
+	if i.parser == nil {
+		if err := i.timed(ctx, "download", i.download); err != nil {
+			return nil, err
+		}
+		p, err := parser.NewParser(c.OSMFilename)
+		if err != nil {
+			return nil, err
+		}
+		i.parser = p
+	}
+	if i.e == nil {
+		e, err := elastic.New(c)
+		if err != nil {
+			return nil, err
+		}
+		i.e = e
+	}
 	i.handler = handler.New()
+	if c.Memory.Max > 0 {
+		memory.Limit(c.Memory.Max)
+		i.handler.SpillNodes(c.Memory.SpillDir, c.Memory.Max)
+		i.logger.Infof("import memory budget %d MiB", c.Memory.Max>>20)
+	}
+	if len(c.KeepTags) > 0 {
+		i.handler.KeepTags(c.KeepTags...)
+	}
+	if len(c.AdminLevels) > 0 {
+		i.handler.AdminLevels(c.AdminLevels)
+	}
+	for _, m := range c.TagMappings {
+		i.handler.MapTag(m.From, m.To...)
+	}
 	i.logger.Info("parser initialized")
 	return i, nil
 }
-func (i *Importer) parse() error {
-	return i.parser.Parse(i.handler)
+func (i *Importer) parse(ctx context.Context) error {
+	if err := i.parser.Parse(ctx, i.handler); err != nil {
+		return err
+	}
+	if n := i.handler.Spilled(); n > 0 {
+		i.logger.Infof("%d nodes spilled to disk near memory budget", n)
+	}
+	if err := i.handler.SpillErr(); err != nil {
+		return err
+	}
+	return i.resolveRelations(ctx)
 }
-func (i *Importer) updateIndices() error {
-	return i.e.UpdateIndex()
+
+// resolveRelations runs targeted parse passes loading members of retained relations
+// which were missing after the first pass
+func (i *Importer) resolveRelations(ctx context.Context) error {
+	r := i.handler.NewResolver()
+	for pass := 0; pass < maxResolvePasses && r.Pending(); pass++ {
+		i.logger.Infof("resolving %d ways and %d nodes of relations", len(r.Ways), len(r.Nodes))
+		if err := i.parser.Parse(ctx, r); err != nil {
+			return err
+		}
+	}
+	if r.Pending() {
+		i.logger.Warnf("unresolved relation members: %d ways, %d nodes", len(r.Ways), len(r.Nodes))
+		for id := range r.Ways {
+			i.logger.Debugf("unresolved way %d", id)
+		}
+		for id := range r.Nodes {
+			i.logger.Debugf("unresolved node %d", id)
+		}
+	}
+	return nil
+}
+
+// updateIndices creates index of import, partial import writes into the current one
+func (i *Importer) updateIndices(ctx context.Context) error {
+	if i.layers != nil {
+		layers := make([]string, 0, len(i.layers))
+		for layer := range i.layers {
+			layers = append(layers, layer)
+		}
+		sort.Strings(layers)
+		i.logger.Infof("partial import of layers %v", layers)
+		return i.e.ReindexLayers(ctx, layers)
+	}
+	return i.e.UpdateIndex(ctx)
 }
 
-// Start starts parsing
-func (i *Importer) Start() error {
-	if err := i.parse(); err != nil {
+// Start starts parsing. Cancelling ctx stops parsing and indexing. Errors of parsing and
+// index creation are returned, failures of indexing stages are reported by WaitStop
+func (i *Importer) Start(ctx context.Context) (err error) {
+	defer func() {
+		if err != nil {
+			i.removeSpilled()
+		}
+	}()
+	if err := i.timed(ctx, "parse", i.parse); err != nil {
 		return err
 	}
-	if err := i.updateIndices(); err != nil {
+	if err := i.timed(ctx, "indices", i.updateIndices); err != nil {
 		return err
 	}
-	i.areasToPolygons()
-	i.eg.Go(i.crossRoadsToElastic)
-	i.eg.Go(i.nodesToElastic)
-	i.eg.Go(i.waysToElastic)
+	if err := i.timed(ctx, "polygons", func(context.Context) error { return i.areasToPolygons() }); err != nil {
+		return err
+	}
+	if err := i.timed(ctx, "wikidata", i.fetchWikidata); err != nil {
+		return err
+	}
+	if err := i.changes.start(ctx, i.e); err != nil {
+		// import doesn't depend on the feed, storages without it don't record changes
+		i.logger.Warnf("changes feed: %v", err)
+	}
+	for _, s := range []stage{
+		{"crossroads", i.crossRoadsToElastic, []string{elastic.CrossroadLayer}},
+		{"nodes", i.nodesToElastic, []string{elastic.AddressLayer, elastic.PoiLayer}},
+		{"ways", i.waysToElastic, []string{elastic.AddressLayer, elastic.PoiLayer}},
+		{"natural", i.naturalToElastic, []string{layerNatural}},
+		{"transit", i.transitToElastic, []string{layerTransit}},
+		{"junctions", i.junctionsToElastic, []string{layerJunction}},
+		{"places", i.placesToElastic, []string{layerPlace}},
+		{"roads", i.roadsToElastic, []string{layerRoad}},
+		{"admin", i.boundariesToElastic, []string{elastic.BoundaryLayer}},
+	} {
+		if !s.selected(i.layers) {
+			continue
+		}
+		s := s
+		i.eg.Go(func() error {
+			if err := i.timed(ctx, s.name, s.run); err != nil {
+				i.logger.Errorf("%s stage failed: %v", s.name, err)
+				i.failures.add(s.name, err)
+			}
+			return nil
+		})
+	}
 	return nil
 }
 
-// WaitStop is wrapper around waitgroup
-func (i *Importer) WaitStop() {
+// WaitStop waits for indexing stages. A failed stage doesn't stop others,
+// *PartialError lists all failed stages
+func (i *Importer) WaitStop() error {
 	i.eg.Wait()
+	if err := i.handler.SpillErr(); err != nil {
+		// stages missed nodes which couldn't be read back
+		i.failures.add("spilled nodes", err)
+	}
+	i.removeSpilled()
+	// documents of failed or skipped stages are missing, so deletes are recorded only after
+	// complete import
+	complete := i.failures.err() == nil && i.layers == nil
+	if err := i.changes.finish(context.Background(), complete); err != nil {
+		i.failures.add("changes", err)
+	}
+	return i.failures.err()
 }
-func (i *Importer) Done() error {
-	return i.e.DeleteIndices()
+
+// removeSpilled deletes nodes spilled to disk once stages don't need them
+func (i *Importer) removeSpilled() {
+	if err := i.handler.RemoveSpilled(); err != nil {
+		i.logger.Warnf("remove spilled nodes: %v", err)
+	}
+}
+
+// Done records completed import and removes indices of previous imports
+func (i *Importer) Done(ctx context.Context) error {
+	return i.timed(ctx, "finish", func(ctx context.Context) error {
+		info := elastic.ImportInfo{ImportedAt: time.Now().UTC(), ExtractTime: i.extractTime, Source: i.config.OSMURL}
+		if err := i.e.WriteImportInfo(ctx, info); err != nil {
+			return err
+		}
+		return i.e.DeleteIndices(ctx)
+	})
 }
 func uniqString(list []string) []string {
 	uniqueSet := make(map[string]bool)
@@ -105,82 +338,156 @@ func uniqString(list []string) []string {
 	}
 	return result
 }
-func (i *Importer) areasToPolygons() {
+
+// areasToPolygons builds index of countries with their settlements and districts which documents
+// are located by. Polygons are assembled once and prepared with bounding boxes
+func (i *Importer) areasToPolygons() error {
 	i.logger.Info("started to build country index")
+	districts := make([]district, 0, len(i.handler.Districts))
+	for _, dist := range i.handler.Districts {
+		districts = append(districts, district{name: dist.Tags["name"], geom: i.wayToPolygon(dist)})
+	}
+	cities := make([]city, 0, len(i.handler.Areas))
+	for _, area := range i.handler.Areas {
+		areaPolygon := i.relationShape(area)
+		city := city{
+			name:      area.Tags["name"],
+			geom:      areaPolygon,
+			placeType: area.Tags["place"],
+			timezone:  area.Tags["timezone"],
+		}
+		for _, d := range districts {
+			if inside, ok := interiorPoint(d.geom); ok && areaPolygon.Contains(inside) {
+				city.districts = append(city.districts, d)
+			}
+		}
+		i.nodeDistricts(&city)
+		cities = append(cities, city)
+	}
 	for _, cn := range i.handler.Countries {
-		if cn.Tags["name"] != i.config.ImportCountry {
+		if i.config.ImportCountry != "" && cn.Tags["name"] != i.config.ImportCountry {
 			continue
 		}
-		countryPolygon := i.relationToPolygon(cn)
-
-		f, err := os.Create(cn.Tags["name"])
-		if err != nil {
-			log.Fatal(err)
+		if i.config.ImportCountry == "" && len(i.clip) == 0 {
+			continue
+		}
+		countryPolygon := i.relationShape(cn)
+		if i.config.ImportCountry == "" && !i.touchesClip(countryPolygon) {
+			continue
 		}
-		for _, point := range countryPolygon.Points() {
-			f.Write([]byte(fmt.Sprintf("%v,%v\n", point.Lng(), point.Lat())))
+		if err := writeBoundary(cn.Tags["name"], countryPolygon.largest); err != nil {
+			// boundary dump is informational, country is still indexed
+			i.failures.add("boundaries", err)
 		}
-		f.Close()
 		c := country{
-			name: cn.Tags["name"],
-			geom: countryPolygon,
-		}
-		for _, area := range i.handler.Areas {
-			areaPolygon := i.relationToPolygon(area)
-			city := city{
-				name:      area.Tags["name"],
-				geom:      areaPolygon,
-				placeType: area.Tags["place"],
-			}
-			for _, dist := range i.handler.Districts {
-				districtPolygon := i.wayToPolygon(dist)
-				if areaPolygon.Contains(districtPolygon.Points()[1]) {
-					d := district{name: dist.Tags["name"], geom: districtPolygon}
-					city.districts = append(city.districts, d)
-				}
-			}
-			if countryPolygon.Contains(areaPolygon.Points()[1]) {
+			name:     cn.Tags["name"],
+			geom:     countryPolygon,
+			timezone: cn.Tags["timezone"],
+		}
+		for _, city := range cities {
+			if inside, ok := interiorPoint(city.geom.largest); ok && countryPolygon.Contains(inside) {
 				c.towns = append(c.towns, city)
 			}
-
 		}
 		i.countries = append(i.countries, c)
 
 	}
+	if i.config.ImportCountry != "" && len(i.countries) == 0 {
+		return fmt.Errorf("country %q: %w", i.config.ImportCountry, ErrBoundaryNotFound)
+	}
 	i.logger.Info("finished to build country index")
+	return nil
 }
-func (i *Importer) relationToPolygon(area gosmparse.Relation) *geo.Polygon {
-	var points []*geo.Point
-	for _, member := range area.Members {
-		node, ok := i.handler.Nodes[member.ID]
-		if ok {
-			points = append(points, geo.NewPoint(node.Lat, node.Lon))
-		}
-		if !ok {
-			way := i.handler.FullWays[member.ID]
-			for _, nodeID := range way.NodeIDs {
-				node := i.handler.Nodes[nodeID]
-				points = append(points, geo.NewPoint(node.Lat, node.Lon))
-			}
+
+// writeBoundary dumps boundary points as "lon,lat" lines into file named after country
+func writeBoundary(name string, polygon *geodesic.Polygon) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	for _, point := range polygon.Points() {
+		if _, err := fmt.Fprintf(f, "%v,%v\n", point.Lon, point.Lat); err != nil {
+			f.Close()
+			return err
 		}
+	}
+	return f.Close()
+}
 
+// relationShape returns polygons of boundary prepared for point in polygon tests, see boundaryPolygons
+func (i *Importer) relationShape(area gosmparse.Relation) *areaShape {
+	polygons, approximate := i.boundaryPolygons(area)
+	if approximate {
+		i.logger.Warnf("boundary %q of relation %d doesn't close, approximated by hull", area.Tags["name"], area.ID)
 	}
-	return geo.NewPolygon(points)
+	return newAreaShape(polygons)
 }
-func (i *Importer) wayToPolygon(way gosmparse.Way) *geo.Polygon {
-	var points []*geo.Point
+func (i *Importer) wayToPolygon(way gosmparse.Way) *geodesic.Polygon {
+	points := make([]geodesic.Point, 0, len(way.NodeIDs))
 	for _, nodeID := range way.NodeIDs {
-		node := i.handler.Nodes[nodeID]
-		points = append(points, geo.NewPoint(node.Lat, node.Lon))
+		node, _ := i.handler.Node(nodeID)
+		points = append(points, geodesic.Point{Lat: node.Lat, Lon: node.Lon})
 	}
-	return geo.NewPolygon(points)
+	return geodesic.NewPolygon(points)
 }
 
 func (i *Importer) StartWebServer() error {
+	if i.config.Analytics.Enabled {
+		i.analytics = make(chan analyticsEvent, analyticsBuffer)
+		go i.runAnalytics()
+	}
+	if i.config.Quotas.Enabled {
+		go i.runQuotas()
+	}
 	router := httprouter.New()
-	router.GET("/api/search/:query", i.geoCodeHandler)
+	router.GET("/api/search/:query", i.searchOrExportHandler)
 	router.GET("/api/reverse/:lat/:lon", i.reverseGeoCodeHandler)
+	router.GET("/api/lookup", i.lookupHandler)
+	router.GET("/api/distance", i.distanceHandler)
+	router.GET("/api/autocomplete", i.autocompleteHandler)
+	router.GET("/api/status/queries", i.queryMetricsHandler)
+	router.GET("/api/status/index", i.indexStatsHandler)
+	router.GET("/api/boundaries/:lat/:lon", i.boundariesHandler)
+	router.GET("/api/country/:lat/:lon", i.countryHandler)
+	router.GET("/api/changes", i.changesHandler)
+	router.GET("/api/history", i.historyHandler)
+	router.GET("/api/usage", i.usageHandler)
+	router.GET("/api/imports", i.importsHandler)
+	router.GET("/api/geofences", i.geofencesHandler)
+	router.PUT("/api/geofences/:name", i.geofencePutHandler)
+	router.DELETE("/api/geofences/:name", i.geofenceDeleteHandler)
+	router.GET("/api/geofence/check/:lat/:lon", i.geofenceCheckHandler)
+	router.POST("/api/batch/search", i.batchSearchHandler)
+	router.POST("/api/reverse/batch", i.batchReverseHandler)
 	router.NotFound = http.FileServer(http.Dir("public"))
-	http.ListenAndServe(":8080", router)
-	return nil
+	server := &http.Server{
+		Handler: i.withAccess(i.withCompression(i.withSchema(i.withSignature(i.withQuota(i.withTimeout(i.withLimit(i.withHistory(router)))))))),
+	}
+	if timeout := i.config.API.RequestTimeout; timeout > 0 {
+		server.ReadTimeout = timeout
+		server.WriteTimeout = timeout + writeTimeoutMargin
+	}
+	addresses := i.config.API.Listeners
+	if len(addresses) == 0 {
+		listen := i.config.API.Listen
+		if listen == "" {
+			listen = defaultListen
+		}
+		addresses = []string{listen}
+	}
+	listeners, err := listen(addresses)
+	if err != nil {
+		return err
+	}
+	errs := make(chan error, len(listeners))
+	for _, l := range listeners {
+		i.logger.Infof("serving API on %s %s", l.Addr().Network(), l.Addr())
+		go func(l net.Listener) {
+			errs <- server.Serve(l)
+		}(l)
+	}
+	// the first failed listener stops the others
+	err = <-errs
+	server.Close()
+	return err
 }
@@ -0,0 +1,487 @@
+package osm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/maddevsio/ariadna/osm/osmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStorage keeps bulk written documents in memory
+type memoryStorage struct {
+	mu   sync.Mutex
+	docs map[string]model.Address
+	info *elastic.ImportInfo
+	// fences are registered geofences by name
+	fences map[string]elastic.Geofence
+	slices []elastic.Slice
+	usage  map[string]elastic.Usage
+	audits []elastic.ImportAudit
+	// failUsage are keys which counts fail to be added
+	failUsage map[string]bool
+	changes   []elastic.Change
+}
+
+func (s *memoryStorage) UpdateIndex(ctx context.Context) error   { return nil }
+func (s *memoryStorage) DeleteIndices(ctx context.Context) error { return nil }
+func (s *memoryStorage) Export(ctx context.Context, q elastic.ExportQuery, fn func(model.Address) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]string, 0, len(s.docs))
+	for id := range s.docs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		doc := s.docs[id]
+		if len(q.Layers) > 0 && documentLayer(doc) != q.Layers[0] {
+			continue
+		}
+		doc.ID = id
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func (s *memoryStorage) PutGeofence(ctx context.Context, fence elastic.Geofence) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fences == nil {
+		s.fences = make(map[string]elastic.Geofence)
+	}
+	_, exists := s.fences[fence.Name]
+	s.fences[fence.Name] = fence
+	return !exists, nil
+}
+func (s *memoryStorage) DeleteGeofence(ctx context.Context, name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, exists := s.fences[name]
+	delete(s.fences, name)
+	return exists, nil
+}
+func (s *memoryStorage) Geofences(ctx context.Context) ([]elastic.Geofence, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fences := make([]elastic.Geofence, 0, len(s.fences))
+	for _, fence := range s.fences {
+		fences = append(fences, fence)
+	}
+	sort.Slice(fences, func(a, b int) bool { return fences[a].Name < fences[b].Name })
+	return fences, nil
+}
+func (s *memoryStorage) AddUsage(ctx context.Context, usage []elastic.Usage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.usage == nil {
+		s.usage = make(map[string]elastic.Usage)
+	}
+	failed := &elastic.UsageError{}
+	for _, u := range usage {
+		if s.failUsage[u.Key] {
+			failed.Failed = append(failed.Failed, u)
+			failed.Errors = append(failed.Errors, u.Key)
+			continue
+		}
+		id := u.Key + "/" + u.Period
+		u.Count += s.usage[id].Count
+		s.usage[id] = u
+	}
+	if len(failed.Failed) > 0 {
+		return failed
+	}
+	return nil
+}
+func (s *memoryStorage) WriteChanges(ctx context.Context, changes []elastic.Change) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.changes = append(s.changes, changes...)
+	return nil
+}
+func (s *memoryStorage) Changes(ctx context.Context, since int64, limit int) (*elastic.ChangesPage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	page := &elastic.ChangesPage{Changes: []elastic.Change{}}
+	if len(s.changes) > 0 {
+		page.First, page.Last = s.changes[0].Seq, s.changes[len(s.changes)-1].Seq
+	}
+	for _, c := range s.changes {
+		if c.Seq > since && len(page.Changes) < limit {
+			page.Changes = append(page.Changes, c)
+		}
+	}
+	return page, nil
+}
+func (s *memoryStorage) TrimChanges(ctx context.Context) error { return nil }
+func (s *memoryStorage) WriteAudit(ctx context.Context, audit elastic.ImportAudit) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.audits = append([]elastic.ImportAudit{audit}, s.audits...)
+	return nil
+}
+func (s *memoryStorage) Audits(ctx context.Context, limit int) ([]elastic.ImportAudit, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if limit > len(s.audits) {
+		limit = len(s.audits)
+	}
+	return append([]elastic.ImportAudit(nil), s.audits[:limit]...), nil
+}
+func (s *memoryStorage) Usage(ctx context.Context, periods []string) ([]elastic.Usage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var usage []elastic.Usage
+	for _, u := range s.usage {
+		for _, period := range periods {
+			if u.Period == period {
+				usage = append(usage, u)
+			}
+		}
+	}
+	sort.Slice(usage, func(a, b int) bool { return usage[a].Key+usage[a].Period < usage[b].Key+usage[b].Period })
+	return usage, nil
+}
+func (s *memoryStorage) ReindexLayers(ctx context.Context, layers []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, doc := range s.docs {
+		for _, layer := range layers {
+			if documentLayer(doc) == layer {
+				delete(s.docs, id)
+			}
+		}
+	}
+	return nil
+}
+func (s *memoryStorage) IndexVersion(ctx context.Context) (string, error) {
+	return "addresses-1", nil
+}
+func (s *memoryStorage) BulkWrite(ctx context.Context, buf bytes.Buffer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	for n := 0; n+1 < len(lines); n += 2 {
+		var meta struct {
+			Index struct {
+				ID string `json:"_id"`
+			} `json:"index"`
+		}
+		var address model.Address
+		if err := json.Unmarshal([]byte(lines[n]), &meta); err != nil {
+			return err
+		}
+		if err := json.Unmarshal([]byte(lines[n+1]), &address); err != nil {
+			return err
+		}
+		s.docs[meta.Index.ID] = address
+	}
+	return nil
+}
+func (s *memoryStorage) Search(ctx context.Context, query string) (*elastic.Result, error) {
+	return &elastic.Result{}, nil
+}
+func (s *memoryStorage) SearchRanked(ctx context.Context, query string, profile config.RankingProfile) (*elastic.Result, error) {
+	return &elastic.Result{}, nil
+}
+func (s *memoryStorage) ExplainSearch(ctx context.Context, query string, profile config.RankingProfile) (*elastic.Explanation, error) {
+	result, err := s.SearchRanked(ctx, query, profile)
+	if err != nil {
+		return nil, err
+	}
+	explanation := &elastic.Explanation{Request: []byte(`{}`), Analysis: map[string][]elastic.Token{}}
+	for _, address := range result.Addresses {
+		explanation.Hits = append(explanation.Hits, elastic.ExplainedHit{Address: address, Score: 1})
+	}
+	return explanation, nil
+}
+func (s *memoryStorage) Reverse(ctx context.Context, lat, lon float64) (*elastic.Result, error) {
+	return &elastic.Result{}, nil
+}
+func (s *memoryStorage) ReverseBatch(ctx context.Context, points []model.Location) ([]*elastic.Result, error) {
+	results := make([]*elastic.Result, 0, len(points))
+	for _, p := range points {
+		result, err := s.Reverse(ctx, p.Lat, p.Lon)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+func (s *memoryStorage) Nearby(ctx context.Context, layer string, lat, lon float64, distance string) (*elastic.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := &elastic.Result{}
+	for id, doc := range s.docs {
+		if layer != "" && doc.Layer == layer {
+			doc.ID = id
+			result.Addresses = append(result.Addresses, doc)
+		}
+	}
+	return result, nil
+}
+func (s *memoryStorage) Containing(ctx context.Context, layer string, lat, lon float64) (*elastic.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := &elastic.Result{}
+	for id, address := range s.docs {
+		if layer == "" || address.Layer == layer {
+			address.ID = id
+			result.Addresses = append(result.Addresses, address)
+		}
+	}
+	return result, nil
+}
+func (s *memoryStorage) Category(ctx context.Context, layer, category string) (*elastic.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := &elastic.Result{}
+	for id, doc := range s.docs {
+		if doc.Layer == layer && doc.Category == category {
+			doc.ID = id
+			result.Addresses = append(result.Addresses, doc)
+		}
+	}
+	return result, nil
+}
+func (s *memoryStorage) Intersecting(ctx context.Context, layer string, lat, lon, radius float64) (*elastic.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := &elastic.Result{Addresses: []model.Address{}}
+	for id, doc := range s.docs {
+		if doc.Layer == layer {
+			doc.ID = id
+			result.Addresses = append(result.Addresses, doc)
+		}
+	}
+	return result, nil
+}
+func (s *memoryStorage) Lookup(ctx context.Context, ids []string) (*elastic.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := &elastic.Result{Addresses: []model.Address{}}
+	for _, id := range ids {
+		if a, ok := s.docs[id]; ok {
+			a.ID = id
+			result.Addresses = append(result.Addresses, a)
+		}
+	}
+	return result, nil
+}
+func (s *memoryStorage) WriteDocuments(index string, docs []interface{}) error { return nil }
+func (s *memoryStorage) DeleteDailyIndices(prefix string, retention time.Duration) error {
+	return nil
+}
+func (s *memoryStorage) SetupLifecycle(ctx context.Context, alias string, lifecycle config.Lifecycle, retention time.Duration) error {
+	return nil
+}
+func (s *memoryStorage) WriteImportInfo(ctx context.Context, info elastic.ImportInfo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.info = &info
+	return nil
+}
+func (s *memoryStorage) Slices(ctx context.Context) ([]elastic.Slice, error) {
+	return s.slices, nil
+}
+func (s *memoryStorage) Stats(ctx context.Context) (*elastic.Stats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := &elastic.Stats{Layers: make(map[string]elastic.LayerStats), Import: s.info}
+	for _, doc := range s.docs {
+		layer := doc.Layer
+		if layer == "" {
+			layer = elastic.AddressLayer
+		}
+		l := stats.Layers[layer]
+		l.Documents++
+		stats.Layers[layer] = l
+		stats.Documents++
+	}
+	return stats, nil
+}
+
+func TestImportWithoutNetwork(t *testing.T) {
+	data := osmtest.New().
+		Node(1, 42.87, 74.59, "addr:street", "Киевская улица", "addr:housenumber", "1").
+		Node(2, 42.88, 74.60, "highway", "bus_stop", "name", "Ала-Тоо").
+		Square(10, 100, 42.5, 74.5, 0.01, "building", "yes", "addr:street", "Чуй проспект", "addr:housenumber", "2")
+	storage := &memoryStorage{docs: make(map[string]model.Address)}
+
+	ctx := context.Background()
+	i, err := NewImporter(ctx, &config.Ariadna{}, WithParser(data), WithStorage(storage))
+	require.NoError(t, err)
+	require.NoError(t, i.Start(ctx))
+	require.NoError(t, i.WaitStop())
+
+	require.Contains(t, storage.docs, "1")
+	assert.Equal(t, "Киевская", storage.docs["1"].Street)
+	assert.Equal(t, "улица", storage.docs["1"].Prefix)
+	require.Contains(t, storage.docs, "10")
+	assert.Equal(t, "проспект", storage.docs["10"].Prefix)
+	assert.InDelta(t, 42.5, storage.docs["10"].Location.Lat, 0.01)
+	require.Contains(t, storage.docs, "node/2")
+	assert.Equal(t, layerTransit, storage.docs["node/2"].Layer)
+}
+
+func TestGeocoderReverseFallsBackToContaining(t *testing.T) {
+	storage := &memoryStorage{docs: map[string]model.Address{
+		"way/1": {Name: "Иссык-Куль", Layer: layerNatural, Location: model.Location{Lat: 42.4, Lon: 77.3}},
+	}}
+	g, err := NewGeocoder(&config.Ariadna{}, WithStorage(storage))
+	require.NoError(t, err)
+
+	result, err := g.Reverse(context.Background(), 42.5, 77.5)
+	require.NoError(t, err)
+	require.Len(t, result.Addresses, 1)
+	assert.Equal(t, "Иссык-Куль", result.Addresses[0].Name)
+	assert.NotEmpty(t, result.Addresses[0].PlusCode)
+}
+
+func TestImportCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	storage := &memoryStorage{docs: make(map[string]model.Address)}
+	i, err := NewImporter(ctx, &config.Ariadna{}, WithParser(osmtest.New()), WithStorage(storage))
+	require.NoError(t, err)
+	assert.Equal(t, context.Canceled, i.Start(ctx))
+}
+
+func TestImportCountryNotFound(t *testing.T) {
+	ctx := context.Background()
+	storage := &memoryStorage{docs: make(map[string]model.Address)}
+	i, err := NewImporter(ctx, &config.Ariadna{ImportCountry: "Кыргызстан"}, WithParser(osmtest.New()), WithStorage(storage))
+	require.NoError(t, err)
+	assert.True(t, errors.Is(i.Start(ctx), ErrBoundaryNotFound))
+}
+
+func TestGeocoderNoResults(t *testing.T) {
+	g, err := NewGeocoder(&config.Ariadna{}, WithStorage(&memoryStorage{docs: make(map[string]model.Address)}))
+	require.NoError(t, err)
+	_, err = g.Search(context.Background(), "Чуй 1")
+	assert.Equal(t, ErrNoResults, err)
+}
+
+// transitFailingStorage fails bulk writes of transit layer
+type transitFailingStorage struct {
+	*memoryStorage
+}
+
+func (s transitFailingStorage) BulkWrite(ctx context.Context, buf bytes.Buffer) error {
+	if strings.Contains(buf.String(), `"layer":"transit"`) {
+		return errors.New("bulk rejected")
+	}
+	return s.memoryStorage.BulkWrite(ctx, buf)
+}
+
+func TestImportPartialFailure(t *testing.T) {
+	data := osmtest.New().
+		Node(1, 42.87, 74.59, "addr:street", "Киевская улица", "addr:housenumber", "1").
+		Node(2, 42.88, 74.60, "highway", "bus_stop", "name", "Ала-Тоо")
+	storage := &memoryStorage{docs: make(map[string]model.Address)}
+	ctx := context.Background()
+	i, err := NewImporter(ctx, &config.Ariadna{}, WithParser(data), WithStorage(transitFailingStorage{storage}))
+	require.NoError(t, err)
+	require.NoError(t, i.Start(ctx))
+
+	err = i.WaitStop()
+	var partial *PartialError
+	require.True(t, errors.As(err, &partial))
+	require.Len(t, partial.Failed, 1)
+	assert.Equal(t, "transit", partial.Failed[0].Stage)
+	assert.True(t, errors.Is(err, partial.Failed[0]))
+	assert.Contains(t, storage.docs, "1")
+}
+
+func TestImportEntrancesKeptTags(t *testing.T) {
+	data := osmtest.New().
+		Node(1, 42.870, 74.590).
+		Node(2, 42.870, 74.591, "entrance", "main", "ref", "1").
+		Node(3, 42.871, 74.591).
+		Way(10, []int64{1, 2, 3, 1}, "building", "yes", "addr:street", "Киевская улица", "addr:housenumber", "10")
+	storage := &memoryStorage{docs: make(map[string]model.Address)}
+	ctx := context.Background()
+	c := &config.Ariadna{KeepTags: []string{"name*", "addr:*", "amenity"}}
+	i, err := NewImporter(ctx, c, WithParser(data), WithStorage(storage))
+	require.NoError(t, err)
+	require.NoError(t, i.Start(ctx))
+	require.NoError(t, i.WaitStop())
+
+	var entrances []model.Entrance
+	for _, doc := range storage.docs {
+		if doc.HouseNumber == "10" {
+			entrances = doc.Entrances
+		}
+	}
+	require.Len(t, entrances, 1)
+	assert.Equal(t, "main", entrances[0].Type)
+}
+
+func TestPartialErrorIs(t *testing.T) {
+	errBulk := errors.New("bulk rejected")
+	stage := &StageError{Stage: "transit", Err: errBulk}
+	err := error(&PartialError{Failed: []*StageError{{Stage: "roads", Err: errors.New("timeout")}, stage}})
+	assert.True(t, errors.Is(err, stage))
+	assert.True(t, errors.Is(err, errBulk))
+	assert.False(t, errors.Is(err, ErrNoResults))
+}
+
+func TestImportProcessors(t *testing.T) {
+	data := osmtest.New().
+		Node(1, 42.87, 74.59, "addr:street", "Киевская улица", "addr:housenumber", "1").
+		Node(2, 42.88, 74.60, "highway", "bus_stop", "name", "Ала-Тоо")
+	storage := &memoryStorage{docs: make(map[string]model.Address)}
+	enrich := DocumentProcessorFunc(func(id string, address *model.Address) (bool, error) {
+		address.Category = "enriched"
+		return true, nil
+	})
+	dropTransit := DocumentProcessorFunc(func(id string, address *model.Address) (bool, error) {
+		return address.Layer != layerTransit, nil
+	})
+
+	ctx := context.Background()
+	i, err := NewImporter(ctx, &config.Ariadna{}, WithParser(data), WithStorage(storage), WithProcessor(enrich), WithProcessor(dropTransit))
+	require.NoError(t, err)
+	require.NoError(t, i.Start(ctx))
+	require.NoError(t, i.WaitStop())
+
+	require.Contains(t, storage.docs, "1")
+	assert.Equal(t, "enriched", storage.docs["1"].Category)
+	assert.NotContains(t, storage.docs, "node/2")
+}
+
+func TestImportLayers(t *testing.T) {
+	data := osmtest.New().
+		Node(1, 42.87, 74.59, "addr:street", "Киевская улица", "addr:housenumber", "1").
+		Node(2, 42.88, 74.60, "highway", "bus_stop", "name", "Ала-Тоо")
+	storage := &memoryStorage{docs: map[string]model.Address{
+		"node/3": {Name: "Бишкек-1", Layer: layerTransit},
+		"node/4": {Name: "Ошский базар", Layer: layerPlace},
+	}}
+	ctx := context.Background()
+	i, err := NewImporter(ctx, &config.Ariadna{ImportLayers: []string{"transit"}}, WithParser(data), WithStorage(storage))
+	require.NoError(t, err)
+	require.NoError(t, i.Start(ctx))
+	require.NoError(t, i.WaitStop())
+
+	assert.Contains(t, storage.docs, "node/2")
+	assert.NotContains(t, storage.docs, "node/3")
+	assert.Contains(t, storage.docs, "node/4")
+	assert.NotContains(t, storage.docs, "1")
+
+	_, err = NewImporter(ctx, &config.Ariadna{ImportLayers: []string{"buildings"}}, WithParser(data), WithStorage(storage))
+	assert.Error(t, err)
+}
@@ -0,0 +1,267 @@
+// Package osmtest builds tiny OSM datasets in memory and writes them as PBF extracts,
+// so importer and handler behaviour can be tested without real downloads
+package osmtest
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/missinglink/gosmparse"
+	"github.com/missinglink/gosmparse/OSMPBF"
+)
+
+// granularity of coordinates in nanodegrees, same as the PBF default
+const granularity = 100
+
+// Dataset is an ordered set of OSM elements
+type Dataset struct {
+	Nodes     []gosmparse.Node
+	Ways      []gosmparse.Way
+	Relations []gosmparse.Relation
+}
+
+// New creates empty dataset
+func New() *Dataset {
+	return &Dataset{}
+}
+
+// Node adds node with tags given as key, value pairs
+func (d *Dataset) Node(id int64, lat, lon float64, tags ...string) *Dataset {
+	d.Nodes = append(d.Nodes, gosmparse.Node{ID: id, Lat: lat, Lon: lon, Tags: pairs(tags)})
+	return d
+}
+
+// Way adds way referencing nodes with tags given as key, value pairs
+func (d *Dataset) Way(id int64, nodeIDs []int64, tags ...string) *Dataset {
+	d.Ways = append(d.Ways, gosmparse.Way{ID: id, NodeIDs: nodeIDs, Tags: pairs(tags)})
+	return d
+}
+
+// Relation adds relation with members and tags given as key, value pairs
+func (d *Dataset) Relation(id int64, members []gosmparse.RelationMember, tags ...string) *Dataset {
+	d.Relations = append(d.Relations, gosmparse.Relation{ID: id, Members: members, Tags: pairs(tags)})
+	return d
+}
+
+// Square adds closed way of four new untagged nodes around center.
+// Node ids start from firstNodeID, size is side length in degrees
+func (d *Dataset) Square(id, firstNodeID int64, lat, lon, size float64, tags ...string) *Dataset {
+	half := size / 2
+	corners := [][2]float64{{lat - half, lon - half}, {lat - half, lon + half}, {lat + half, lon + half}, {lat + half, lon - half}}
+	nodeIDs := make([]int64, 0, len(corners)+1)
+	for n, corner := range corners {
+		nodeID := firstNodeID + int64(n)
+		d.Node(nodeID, corner[0], corner[1])
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	return d.Way(id, append(nodeIDs, firstNodeID), tags...)
+}
+
+// Way returns relation member referencing way
+func Way(id int64, role string) gosmparse.RelationMember {
+	return gosmparse.RelationMember{ID: id, Type: gosmparse.WayType, Role: role}
+}
+
+// Node returns relation member referencing node
+func Node(id int64, role string) gosmparse.RelationMember {
+	return gosmparse.RelationMember{ID: id, Type: gosmparse.NodeType, Role: role}
+}
+
+// Feed passes all elements to reader in file order: nodes, ways, relations
+func (d *Dataset) Feed(r gosmparse.OSMReader) {
+	for _, node := range d.Nodes {
+		r.ReadNode(node)
+	}
+	for _, way := range d.Ways {
+		r.ReadWay(way)
+	}
+	for _, relation := range d.Relations {
+		r.ReadRelation(relation)
+	}
+}
+
+// Parse feeds dataset to reader, so dataset can replace parser of real extract
+func (d *Dataset) Parse(ctx context.Context, r gosmparse.OSMReader) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	d.Feed(r)
+	return nil
+}
+
+// WriteFile writes dataset as PBF file into dir and returns its path
+func (d *Dataset) WriteFile(dir string) (string, error) {
+	f, err := ioutil.TempFile(dir, "osmtest-*.osm.pbf")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if err := d.WritePBF(f); err != nil {
+		return "", err
+	}
+	return f.Name(), f.Close()
+}
+
+// WritePBF writes dataset in PBF format
+func (d *Dataset) WritePBF(w io.Writer) error {
+	header := &OSMPBF.HeaderBlock{
+		RequiredFeatures: []string{"OsmSchema-V0.6", "DenseNodes"},
+		Writingprogram:   proto.String("ariadna-osmtest"),
+	}
+	if err := writeBlob(w, "OSMHeader", header); err != nil {
+		return err
+	}
+	var strings stringTable
+	block := &OSMPBF.PrimitiveBlock{Granularity: proto.Int32(granularity)}
+	if len(d.Nodes) > 0 {
+		block.Primitivegroup = append(block.Primitivegroup, &OSMPBF.PrimitiveGroup{Dense: strings.dense(d.Nodes)})
+	}
+	if len(d.Ways) > 0 {
+		block.Primitivegroup = append(block.Primitivegroup, &OSMPBF.PrimitiveGroup{Ways: strings.ways(d.Ways)})
+	}
+	if len(d.Relations) > 0 {
+		block.Primitivegroup = append(block.Primitivegroup, &OSMPBF.PrimitiveGroup{Relations: strings.relations(d.Relations)})
+	}
+	if len(block.Primitivegroup) == 0 {
+		return nil
+	}
+	block.Stringtable = &OSMPBF.StringTable{S: strings.s}
+	return writeBlob(w, "OSMData", block)
+}
+
+func writeBlob(w io.Writer, kind string, msg proto.Message) error {
+	raw, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write(raw); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	blob, err := proto.Marshal(&OSMPBF.Blob{RawSize: proto.Int32(int32(len(raw))), ZlibData: compressed.Bytes()})
+	if err != nil {
+		return err
+	}
+	header, err := proto.Marshal(&OSMPBF.BlobHeader{Type: proto.String(kind), Datasize: proto.Int32(int32(len(blob)))})
+	if err != nil {
+		return err
+	}
+	size := make([]byte, 4)
+	binary.BigEndian.PutUint32(size, uint32(len(header)))
+	for _, part := range [][]byte{size, header, blob} {
+		if _, err := w.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stringTable collects strings of a block, index 0 is reserved as delimiter
+type stringTable struct {
+	s     []string
+	index map[string]uint32
+}
+
+func (t *stringTable) id(s string) uint32 {
+	if t.index == nil {
+		t.s = []string{""}
+		t.index = map[string]uint32{}
+	}
+	if id, ok := t.index[s]; ok {
+		return id
+	}
+	id := uint32(len(t.s))
+	t.s = append(t.s, s)
+	t.index[s] = id
+	return id
+}
+
+// tags returns key and value string ids sorted by key for stable output
+func (t *stringTable) tags(tags map[string]string) ([]uint32, []uint32) {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var ks, vs []uint32
+	for _, k := range keys {
+		ks = append(ks, t.id(k))
+		vs = append(vs, t.id(tags[k]))
+	}
+	return ks, vs
+}
+
+func (t *stringTable) dense(nodes []gosmparse.Node) *OSMPBF.DenseNodes {
+	dense := &OSMPBF.DenseNodes{}
+	var id, lat, lon int64
+	for _, node := range nodes {
+		nodeLat := int64(math.Round(node.Lat * 1e9 / granularity))
+		nodeLon := int64(math.Round(node.Lon * 1e9 / granularity))
+		dense.Id = append(dense.Id, node.ID-id)
+		dense.Lat = append(dense.Lat, nodeLat-lat)
+		dense.Lon = append(dense.Lon, nodeLon-lon)
+		id, lat, lon = node.ID, nodeLat, nodeLon
+		ks, vs := t.tags(node.Tags)
+		for n := range ks {
+			dense.KeysVals = append(dense.KeysVals, int32(ks[n]), int32(vs[n]))
+		}
+		dense.KeysVals = append(dense.KeysVals, 0)
+	}
+	return dense
+}
+
+func (t *stringTable) ways(ways []gosmparse.Way) []*OSMPBF.Way {
+	result := make([]*OSMPBF.Way, 0, len(ways))
+	for _, way := range ways {
+		w := &OSMPBF.Way{Id: proto.Int64(way.ID)}
+		w.Keys, w.Vals = t.tags(way.Tags)
+		var prev int64
+		for _, ref := range way.NodeIDs {
+			w.Refs = append(w.Refs, ref-prev)
+			prev = ref
+		}
+		result = append(result, w)
+	}
+	return result
+}
+
+func (t *stringTable) relations(relations []gosmparse.Relation) []*OSMPBF.Relation {
+	types := map[gosmparse.MemberType]OSMPBF.Relation_MemberType{
+		gosmparse.NodeType:     OSMPBF.Relation_NODE,
+		gosmparse.WayType:      OSMPBF.Relation_WAY,
+		gosmparse.RelationType: OSMPBF.Relation_RELATION,
+	}
+	result := make([]*OSMPBF.Relation, 0, len(relations))
+	for _, relation := range relations {
+		r := &OSMPBF.Relation{Id: proto.Int64(relation.ID)}
+		r.Keys, r.Vals = t.tags(relation.Tags)
+		var prev int64
+		for _, member := range relation.Members {
+			r.Memids = append(r.Memids, member.ID-prev)
+			r.Types = append(r.Types, types[member.Type])
+			r.RolesSid = append(r.RolesSid, int32(t.id(member.Role)))
+			prev = member.ID
+		}
+		result = append(result, r)
+	}
+	return result
+}
+
+func pairs(tags []string) map[string]string {
+	m := make(map[string]string, len(tags)/2)
+	for n := 0; n+1 < len(tags); n += 2 {
+		m[tags[n]] = tags[n+1]
+	}
+	return m
+}
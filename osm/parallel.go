@@ -0,0 +1,61 @@
+package osm
+
+import (
+	"bytes"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/maddevsio/ariadna/model"
+)
+
+// document is built document waiting to be written to bulk request body
+type document struct {
+	id      string
+	address model.Address
+}
+
+// buildParallel builds documents of n elements on all cores and writes them to buf from the
+// calling goroutine, so processors and change log get documents one by one. Build returns
+// false for elements which are not indexed, it must only read importer state
+func (i *Importer) buildParallel(buf *bytes.Buffer, n int, build func(k int) (document, bool)) error {
+	workers := runtime.GOMAXPROCS(0)
+	var (
+		wg   sync.WaitGroup
+		next = int64(-1)
+		docs = make(chan document, workers*4)
+		done = make(chan struct{})
+	)
+	defer close(done)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				k := int(atomic.AddInt64(&next, 1))
+				if k >= n {
+					return
+				}
+				doc, ok := build(k)
+				if !ok {
+					continue
+				}
+				select {
+				case docs <- doc:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(docs)
+	}()
+	for doc := range docs {
+		if err := i.writeDocument(buf, doc.id, doc.address); err != nil {
+			return err
+		}
+	}
+	return nil
+}
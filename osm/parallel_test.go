@@ -0,0 +1,43 @@
+package osm
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/geodesic"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildParallel(t *testing.T) {
+	i := &Importer{config: &config.Ariadna{}, logger: logrus.New(), changes: newChangeLog()}
+	var buf bytes.Buffer
+	err := i.buildParallel(&buf, 1000, func(k int) (document, bool) {
+		return document{id: strconv.Itoa(k), address: model.Address{Name: strconv.Itoa(k)}}, k%2 == 0
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1000, strings.Count(buf.String(), "\n"), "index and document lines of every even element")
+
+	failing := errors.New("processor failed")
+	i.RegisterProcessor(DocumentProcessorFunc(func(id string, a *model.Address) (bool, error) {
+		return false, failing
+	}))
+	err = i.buildParallel(&bytes.Buffer{}, 1000, func(k int) (document, bool) {
+		return document{id: strconv.Itoa(k)}, true
+	})
+	assert.True(t, errors.Is(err, failing))
+}
+
+func TestPreparedPolygon(t *testing.T) {
+	p := geodesic.NewPolygon([]geodesic.Point{{Lat: 0, Lon: 0}, {Lat: 0, Lon: 2}, {Lat: 2, Lon: 2}, {Lat: 2, Lon: 0}, {Lat: 0, Lon: 0}})
+	assert.Equal(t, [4]float64{0, 0, 2, 2}, p.Bounds())
+	assert.True(t, p.Contains(geodesic.Point{Lat: 1, Lon: 1}))
+	assert.False(t, p.Contains(geodesic.Point{Lat: 3, Lon: 1}))
+	assert.False(t, geodesic.NewPolygon(nil).Contains(geodesic.Point{Lat: 1, Lon: 1}))
+}
@@ -0,0 +1,368 @@
+package parser
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/missinglink/gosmparse"
+)
+
+// Deleter may be implemented by handler to receive deletions from o5c change files
+type Deleter interface {
+	DeleteNode(id int64)
+	DeleteWay(id int64)
+	DeleteRelation(id int64)
+}
+
+const (
+	o5mNode      = 0x10
+	o5mWay       = 0x11
+	o5mRelation  = 0x12
+	o5mHeader    = 0xe0
+	o5mEOF       = 0xfe
+	o5mReset     = 0xff
+	o5mTableSize = 15000
+	o5mMaxPair   = 250
+)
+
+var errO5MMalformed = errors.New("malformed o5m data")
+
+// o5mDecoder keeps delta coding state and string table of o5m stream
+type o5mDecoder struct {
+	r         *bufio.Reader
+	handler   gosmparse.OSMReader
+	table     [][]string
+	tablePos  int
+	nodeID    int64
+	wayID     int64
+	relID     int64
+	lat, lon  int64
+	wayRef    int64
+	memberRef [3]int64
+	timestamp int64
+	changeset int64
+}
+
+// parseO5M streams o5m or o5c data from r into handler
+func parseO5M(r io.Reader, handler gosmparse.OSMReader) error {
+	d := &o5mDecoder{r: bufio.NewReader(r), handler: handler}
+	d.reset()
+	for {
+		kind, err := d.r.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch kind {
+		case o5mReset:
+			d.reset()
+			continue
+		case o5mEOF:
+			return nil
+		}
+		if kind >= 0xf0 {
+			continue
+		}
+		length, err := readUvarint(d.r)
+		if err != nil {
+			return err
+		}
+		data := make([]byte, length)
+		if _, err := io.ReadFull(d.r, data); err != nil {
+			return err
+		}
+		ds := &dataset{data: data}
+		switch kind {
+		case o5mNode:
+			err = d.node(ds)
+		case o5mWay:
+			err = d.way(ds)
+		case o5mRelation:
+			err = d.relation(ds)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (d *o5mDecoder) reset() {
+	d.table = make([][]string, o5mTableSize)
+	d.tablePos = 0
+	d.nodeID, d.wayID, d.relID = 0, 0, 0
+	d.lat, d.lon, d.wayRef = 0, 0, 0
+	d.memberRef = [3]int64{}
+	d.timestamp, d.changeset = 0, 0
+}
+
+// dataset is a cursor over single o5m dataset payload
+type dataset struct {
+	data []byte
+	pos  int
+}
+
+func (ds *dataset) done() bool {
+	return ds.pos >= len(ds.data)
+}
+
+func (ds *dataset) uvarint() (uint64, error) {
+	var (
+		value uint64
+		shift uint
+	)
+	for {
+		if ds.done() {
+			return 0, errO5MMalformed
+		}
+		b := ds.data[ds.pos]
+		ds.pos++
+		value |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return value, nil
+		}
+		shift += 7
+	}
+}
+
+func (ds *dataset) varint() (int64, error) {
+	u, err := ds.uvarint()
+	if err != nil {
+		return 0, err
+	}
+	if u&1 == 1 {
+		return -int64(u>>1) - 1, nil
+	}
+	return int64(u >> 1), nil
+}
+
+func (ds *dataset) cstring() (string, error) {
+	for i := ds.pos; i < len(ds.data); i++ {
+		if ds.data[i] == 0 {
+			s := string(ds.data[ds.pos:i])
+			ds.pos = i + 1
+			return s, nil
+		}
+	}
+	return "", errO5MMalformed
+}
+
+// strings reads either inline or referenced string group of n strings
+func (d *o5mDecoder) strings(ds *dataset, n int) ([]string, error) {
+	ref, err := ds.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	if ref != 0 {
+		if ref > o5mTableSize {
+			return nil, errO5MMalformed
+		}
+		s := d.table[(d.tablePos-int(ref)+o5mTableSize)%o5mTableSize]
+		if len(s) != n {
+			return nil, errO5MMalformed
+		}
+		return s, nil
+	}
+	result := make([]string, n)
+	size := 0
+	for i := range result {
+		if result[i], err = ds.cstring(); err != nil {
+			return nil, err
+		}
+		size += len(result[i])
+	}
+	if size <= o5mMaxPair {
+		d.table[d.tablePos] = result
+		d.tablePos = (d.tablePos + 1) % o5mTableSize
+	}
+	return result, nil
+}
+
+// version skips version section and reports whether object is deleted
+func (d *o5mDecoder) version(ds *dataset) (bool, error) {
+	version, err := ds.uvarint()
+	if err != nil {
+		return false, err
+	}
+	if version != 0 {
+		delta, err := ds.varint()
+		if err != nil {
+			return false, err
+		}
+		d.timestamp += delta
+		if d.timestamp != 0 {
+			delta, err := ds.varint()
+			if err != nil {
+				return false, err
+			}
+			d.changeset += delta
+			if _, err := d.strings(ds, 2); err != nil {
+				return false, err
+			}
+		}
+	}
+	return ds.done(), nil
+}
+
+func (d *o5mDecoder) tags(ds *dataset) (map[string]string, error) {
+	tags := make(map[string]string)
+	for !ds.done() {
+		pair, err := d.strings(ds, 2)
+		if err != nil {
+			return nil, err
+		}
+		tags[pair[0]] = pair[1]
+	}
+	return tags, nil
+}
+
+func (d *o5mDecoder) node(ds *dataset) error {
+	delta, err := ds.varint()
+	if err != nil {
+		return err
+	}
+	d.nodeID += delta
+	deleted, err := d.version(ds)
+	if err != nil {
+		return err
+	}
+	if deleted {
+		if deleter, ok := d.handler.(Deleter); ok {
+			deleter.DeleteNode(d.nodeID)
+		}
+		return nil
+	}
+	lon, err := ds.varint()
+	if err != nil {
+		return err
+	}
+	lat, err := ds.varint()
+	if err != nil {
+		return err
+	}
+	d.lon += lon
+	d.lat += lat
+	tags, err := d.tags(ds)
+	if err != nil {
+		return err
+	}
+	d.handler.ReadNode(gosmparse.Node{ID: d.nodeID, Lat: float64(d.lat) / 1e7, Lon: float64(d.lon) / 1e7, Tags: tags})
+	return nil
+}
+
+func (d *o5mDecoder) way(ds *dataset) error {
+	delta, err := ds.varint()
+	if err != nil {
+		return err
+	}
+	d.wayID += delta
+	deleted, err := d.version(ds)
+	if err != nil {
+		return err
+	}
+	if deleted {
+		if deleter, ok := d.handler.(Deleter); ok {
+			deleter.DeleteWay(d.wayID)
+		}
+		return nil
+	}
+	length, err := ds.uvarint()
+	if err != nil {
+		return err
+	}
+	end := ds.pos + int(length)
+	if end > len(ds.data) {
+		return errO5MMalformed
+	}
+	way := gosmparse.Way{ID: d.wayID}
+	for ds.pos < end {
+		delta, err := ds.varint()
+		if err != nil {
+			return err
+		}
+		d.wayRef += delta
+		way.NodeIDs = append(way.NodeIDs, d.wayRef)
+	}
+	if way.Tags, err = d.tags(ds); err != nil {
+		return err
+	}
+	d.handler.ReadWay(way)
+	return nil
+}
+
+func (d *o5mDecoder) relation(ds *dataset) error {
+	delta, err := ds.varint()
+	if err != nil {
+		return err
+	}
+	d.relID += delta
+	deleted, err := d.version(ds)
+	if err != nil {
+		return err
+	}
+	if deleted {
+		if deleter, ok := d.handler.(Deleter); ok {
+			deleter.DeleteRelation(d.relID)
+		}
+		return nil
+	}
+	length, err := ds.uvarint()
+	if err != nil {
+		return err
+	}
+	end := ds.pos + int(length)
+	if end > len(ds.data) {
+		return errO5MMalformed
+	}
+	relation := gosmparse.Relation{ID: d.relID}
+	for ds.pos < end {
+		delta, err := ds.varint()
+		if err != nil {
+			return err
+		}
+		role, err := d.strings(ds, 1)
+		if err != nil {
+			return err
+		}
+		if len(role[0]) == 0 {
+			return errO5MMalformed
+		}
+		memberType, err := strconv.Atoi(role[0][:1])
+		if err != nil || memberType > 2 {
+			return fmt.Errorf("unknown o5m member type %q", role[0][:1])
+		}
+		d.memberRef[memberType] += delta
+		relation.Members = append(relation.Members, gosmparse.RelationMember{
+			ID:   d.memberRef[memberType],
+			Type: gosmparse.MemberType(memberType),
+			Role: role[0][1:],
+		})
+	}
+	if relation.Tags, err = d.tags(ds); err != nil {
+		return err
+	}
+	d.handler.ReadRelation(relation)
+	return nil
+}
+
+func readUvarint(r io.ByteReader) (uint64, error) {
+	var (
+		value uint64
+		shift uint
+	)
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		value |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return value, nil
+		}
+		shift += 7
+	}
+}
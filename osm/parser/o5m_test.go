@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/missinglink/gosmparse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type deletingCollector struct {
+	collector
+	deletedNodes []int64
+}
+
+func (c *deletingCollector) DeleteNode(id int64)     { c.deletedNodes = append(c.deletedNodes, id) }
+func (c *deletingCollector) DeleteWay(id int64)      {}
+func (c *deletingCollector) DeleteRelation(id int64) {}
+
+func uvarint(v uint64) []byte {
+	var b []byte
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+func varint(v int64) []byte {
+	if v < 0 {
+		return uvarint(uint64(-v-1)<<1 | 1)
+	}
+	return uvarint(uint64(v) << 1)
+}
+
+func o5mDataset(kind byte, parts ...[]byte) []byte {
+	payload := bytes.Join(parts, nil)
+	return append(append([]byte{kind}, uvarint(uint64(len(payload)))...), payload...)
+}
+
+func pair(k, v string) []byte {
+	return []byte("\x00" + k + "\x00" + v + "\x00")
+}
+
+func TestParseO5M(t *testing.T) {
+	var buf bytes.Buffer
+	buf.Write([]byte{o5mReset})
+	buf.Write(o5mDataset(o5mHeader, []byte("o5m2")))
+	buf.Write(o5mDataset(o5mNode, varint(1), uvarint(0), varint(745900000), varint(428700000), pair("name", "Ош")))
+	buf.Write(o5mDataset(o5mNode, varint(1), uvarint(0), varint(100), varint(-100), uvarint(1)))
+	refs := append(varint(1), varint(1)...)
+	buf.Write(o5mDataset(o5mWay, varint(10), uvarint(0), uvarint(uint64(len(refs))), refs, pair("highway", "primary")))
+	member := append(varint(10), []byte("\x001outer\x00")...)
+	buf.Write(o5mDataset(o5mRelation, varint(100), uvarint(0), uvarint(uint64(len(member))), member, pair("type", "multipolygon")))
+	buf.Write(o5mDataset(o5mNode, varint(1), uvarint(0)))
+	buf.Write([]byte{o5mEOF})
+
+	var c deletingCollector
+	require.NoError(t, parseO5M(&buf, &c))
+	require.Len(t, c.nodes, 2)
+	assert.Equal(t, int64(1), c.nodes[0].ID)
+	assert.InDelta(t, 42.87, c.nodes[0].Lat, 1e-7)
+	assert.InDelta(t, 74.59, c.nodes[0].Lon, 1e-7)
+	assert.Equal(t, "Ош", c.nodes[1].Tags["name"])
+	require.Len(t, c.ways, 1)
+	assert.Equal(t, []int64{1, 2}, c.ways[0].NodeIDs)
+	require.Len(t, c.relations, 1)
+	assert.Equal(t, gosmparse.RelationMember{ID: 10, Type: gosmparse.WayType, Role: "outer"}, c.relations[0].Members[0])
+	assert.Equal(t, []int64{3}, c.deletedNodes)
+}
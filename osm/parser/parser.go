@@ -1,15 +1,29 @@
 package parser
 
 import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"context"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/missinglink/gosmparse"
 	"github.com/sirupsen/logrus"
 )
 
+const (
+	formatPBF = iota
+	formatXML
+	formatXMLBzip2
+	formatO5M
+)
+
 // Parser - PBF Parser
 type Parser struct {
 	file    *os.File
+	format  int
 	decoder *gosmparse.Decoder
 	logger  *logrus.Logger
 }
@@ -21,14 +35,67 @@ func (p *Parser) open(path string) error {
 		return err
 	}
 	p.file = file
-	p.decoder = gosmparse.NewDecoder(file)
+	format, err := detectFormat(path, file)
+	if err != nil {
+		return err
+	}
+	p.format = format
 	return nil
 }
 
-// Parse - execute parser
-func (p *Parser) Parse(handler gosmparse.OSMReader) error {
+// detectFormat - guess file format by its magic bytes and extension
+func detectFormat(path string, file *os.File) (int, error) {
+	head := make([]byte, 5)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return 0, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	head = head[:n]
+	switch {
+	case bytes.HasPrefix(head, []byte{o5mReset, o5mHeader}) || strings.HasSuffix(path, ".o5m") || strings.HasSuffix(path, ".o5c"):
+		return formatO5M, nil
+	case bytes.HasPrefix(head, []byte("BZh")) || strings.HasSuffix(path, ".bz2"):
+		return formatXMLBzip2, nil
+	case bytes.HasPrefix(head, []byte("<")) || strings.HasSuffix(path, ".osm"):
+		return formatXML, nil
+	}
+	return formatPBF, nil
+}
+
+// Parse - execute parser. It could be called several times, every call reads file from the beginning.
+// Cancelling ctx closes the file to interrupt decoding, so parser can't be used after that
+func (p *Parser) Parse(ctx context.Context, handler gosmparse.OSMReader) error {
 	p.logger.Info("parsing started")
-	err := p.decoder.Parse(handler, false)
+	if _, err := p.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			p.file.Close()
+		case <-done:
+		}
+	}()
+	var err error
+	switch p.format {
+	case formatXML:
+		err = parseXML(bufio.NewReader(p.file), handler)
+	case formatXMLBzip2:
+		err = parseXML(bzip2.NewReader(bufio.NewReader(p.file)), handler)
+	case formatO5M:
+		err = parseO5M(p.file, handler)
+	default:
+		p.decoder = gosmparse.NewDecoder(p.file)
+		err = p.decoder.Parse(handler, false)
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
 	if err != nil {
 		return err
 	}
@@ -36,7 +103,8 @@ func (p *Parser) Parse(handler gosmparse.OSMReader) error {
 	return nil
 }
 
-// NewParser - Create a new parser for file at path
+// NewParser - Create a new parser for file at path.
+// PBF, o5m/o5c, OSM XML and bzip2 compressed OSM XML files are supported
 func NewParser(path string) (*Parser, error) {
 	p := &Parser{logger: logrus.New()}
 	err := p.open(path)
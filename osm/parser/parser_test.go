@@ -0,0 +1,59 @@
+package parser
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/maddevsio/ariadna/osm/osmtest"
+	"github.com/missinglink/gosmparse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePBF(t *testing.T) {
+	dir, err := ioutil.TempDir("", "parser")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path, err := osmtest.New().
+		Node(1, 42.87, 74.59, "name", "Ала-Тоо").
+		Node(2, 42.88, 74.60).
+		Way(10, []int64{1, 2}, "highway", "residential").
+		Relation(100, []gosmparse.RelationMember{osmtest.Way(10, "outer")}, "admin_level", "2").
+		WriteFile(dir)
+	require.NoError(t, err)
+
+	p, err := NewParser(path)
+	require.NoError(t, err)
+	var c collector
+	require.NoError(t, p.Parse(context.Background(), &c))
+
+	require.Len(t, c.nodes, 2)
+	assert.Equal(t, "Ала-Тоо", c.nodes[0].Tags["name"])
+	assert.InDelta(t, 42.87, c.nodes[0].Lat, 1e-7)
+	assert.InDelta(t, 74.60, c.nodes[1].Lon, 1e-7)
+	require.Len(t, c.ways, 1)
+	assert.Equal(t, []int64{1, 2}, c.ways[0].NodeIDs)
+	assert.Equal(t, "residential", c.ways[0].Tags["highway"])
+	require.Len(t, c.relations, 1)
+	assert.Equal(t, gosmparse.WayType, c.relations[0].Members[0].Type)
+	assert.Equal(t, int64(10), c.relations[0].Members[0].ID)
+	assert.Equal(t, "outer", c.relations[0].Members[0].Role)
+}
+
+func TestParseCancelled(t *testing.T) {
+	dir, err := ioutil.TempDir("", "parser")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path, err := osmtest.New().Node(1, 42.87, 74.59).WriteFile(dir)
+	require.NoError(t, err)
+
+	p, err := NewParser(path)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var c collector
+	assert.Equal(t, context.Canceled, p.Parse(ctx, &c))
+}
@@ -0,0 +1,99 @@
+package parser
+
+import (
+	"encoding/xml"
+	"io"
+
+	"github.com/missinglink/gosmparse"
+)
+
+type (
+	xmlTag struct {
+		Key   string `xml:"k,attr"`
+		Value string `xml:"v,attr"`
+	}
+	xmlNode struct {
+		ID   int64    `xml:"id,attr"`
+		Lat  float64  `xml:"lat,attr"`
+		Lon  float64  `xml:"lon,attr"`
+		Tags []xmlTag `xml:"tag"`
+	}
+	xmlWay struct {
+		ID    int64    `xml:"id,attr"`
+		Nodes []xmlRef `xml:"nd"`
+		Tags  []xmlTag `xml:"tag"`
+	}
+	xmlRef struct {
+		Ref int64 `xml:"ref,attr"`
+	}
+	xmlRelation struct {
+		ID      int64       `xml:"id,attr"`
+		Members []xmlMember `xml:"member"`
+		Tags    []xmlTag    `xml:"tag"`
+	}
+	xmlMember struct {
+		Type string `xml:"type,attr"`
+		Ref  int64  `xml:"ref,attr"`
+		Role string `xml:"role,attr"`
+	}
+)
+
+var memberTypes = map[string]gosmparse.MemberType{
+	"node":     gosmparse.NodeType,
+	"way":      gosmparse.WayType,
+	"relation": gosmparse.RelationType,
+}
+
+func tagsToMap(tags []xmlTag) map[string]string {
+	m := make(map[string]string, len(tags))
+	for _, t := range tags {
+		m[t.Key] = t.Value
+	}
+	return m
+}
+
+// parseXML streams OSM XML document from r into handler
+func parseXML(r io.Reader, handler gosmparse.OSMReader) error {
+	d := xml.NewDecoder(r)
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "node":
+			var n xmlNode
+			if err := d.DecodeElement(&n, &start); err != nil {
+				return err
+			}
+			handler.ReadNode(gosmparse.Node{ID: n.ID, Lat: n.Lat, Lon: n.Lon, Tags: tagsToMap(n.Tags)})
+		case "way":
+			var w xmlWay
+			if err := d.DecodeElement(&w, &start); err != nil {
+				return err
+			}
+			way := gosmparse.Way{ID: w.ID, Tags: tagsToMap(w.Tags), NodeIDs: make([]int64, len(w.Nodes))}
+			for idx, nd := range w.Nodes {
+				way.NodeIDs[idx] = nd.Ref
+			}
+			handler.ReadWay(way)
+		case "relation":
+			var rel xmlRelation
+			if err := d.DecodeElement(&rel, &start); err != nil {
+				return err
+			}
+			relation := gosmparse.Relation{ID: rel.ID, Tags: tagsToMap(rel.Tags), Members: make([]gosmparse.RelationMember, len(rel.Members))}
+			for idx, m := range rel.Members {
+				relation.Members[idx] = gosmparse.RelationMember{ID: m.Ref, Type: memberTypes[m.Type], Role: m.Role}
+			}
+			handler.ReadRelation(relation)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/missinglink/gosmparse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type collector struct {
+	nodes     []gosmparse.Node
+	ways      []gosmparse.Way
+	relations []gosmparse.Relation
+}
+
+func (c *collector) ReadNode(n gosmparse.Node)         { c.nodes = append(c.nodes, n) }
+func (c *collector) ReadWay(w gosmparse.Way)           { c.ways = append(c.ways, w) }
+func (c *collector) ReadRelation(r gosmparse.Relation) { c.relations = append(c.relations, r) }
+
+func TestParseXML(t *testing.T) {
+	data := `<?xml version="1.0" encoding="UTF-8"?>
+<osm version="0.6">
+ <node id="1" lat="42.87" lon="74.59"><tag k="name" v="Ала-Тоо"/></node>
+ <node id="2" lat="42.88" lon="74.60"/>
+ <way id="10"><nd ref="1"/><nd ref="2"/><tag k="highway" v="residential"/></way>
+ <relation id="100"><member type="way" ref="10" role="outer"/><tag k="admin_level" v="2"/></relation>
+</osm>`
+	var c collector
+	require.NoError(t, parseXML(strings.NewReader(data), &c))
+	require.Len(t, c.nodes, 2)
+	assert.Equal(t, "Ала-Тоо", c.nodes[0].Tags["name"])
+	assert.Equal(t, 42.87, c.nodes[0].Lat)
+	require.Len(t, c.ways, 1)
+	assert.Equal(t, []int64{1, 2}, c.ways[0].NodeIDs)
+	require.Len(t, c.relations, 1)
+	assert.Equal(t, gosmparse.WayType, c.relations[0].Members[0].Type)
+	assert.Equal(t, "outer", c.relations[0].Members[0].Role)
+}
@@ -0,0 +1,122 @@
+package osm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/geodesic"
+	"github.com/maddevsio/ariadna/model"
+)
+
+const (
+	layerPlace = "place"
+
+	// matchFallbackPlace marks place returned by reverse geocoding when no address is near
+	matchFallbackPlace = "fallback_place"
+)
+
+// defaultPlaceRadius is distance in kilometers by place type reverse geocoding falls back to
+// the nearest place node within, api.place_radius overrides it
+var defaultPlaceRadius = map[string]float64{
+	"city":              10,
+	"town":              5,
+	"village":           2,
+	"hamlet":            1,
+	"isolated_dwelling": 0.5,
+	"suburb":            1.5,
+	"quarter":           1,
+	"neighbourhood":     0.7,
+	"locality":          1,
+}
+
+func (i *Importer) placesToElastic(ctx context.Context) error {
+	i.logger.Info("started to search places")
+	buf, err := i.getPlaces()
+	if err != nil {
+		return err
+	}
+	i.logger.Info("places found")
+	return i.bulkWrite(ctx, buf)
+}
+
+// getPlaces indexes named place nodes of settlements and their parts, which reverse geocoding
+// falls back to away from addresses
+func (i *Importer) getPlaces() (bytes.Buffer, error) {
+	var buf bytes.Buffer
+	for nodeID, node := range i.handler.Places {
+		if !i.inClip(node.Lat, node.Lon) {
+			continue
+		}
+		address := model.Address{
+			Name:     node.Tags["name"],
+			Names:    nameVariants(node.Tags),
+			Layer:    layerPlace,
+			Category: node.Tags["place"],
+			Location: model.Location{Lat: node.Lat, Lon: node.Lon},
+			Fields:   i.extractFields(node.Tags),
+		}
+		i.locate(&address)
+		i.enrichWikidata(&address, node.Tags)
+		if err := i.writeDocument(&buf, fmt.Sprintf("node/%d", nodeID), address); err != nil {
+			return buf, err
+		}
+	}
+	return buf, nil
+}
+
+// placeRadius returns fallback radius by place type, configured radii override default ones
+func (i *Importer) placeRadius() map[string]float64 {
+	if len(i.config.API.PlaceRadius) == 0 {
+		return defaultPlaceRadius
+	}
+	radius := make(map[string]float64, len(defaultPlaceRadius)+len(i.config.API.PlaceRadius))
+	for place, r := range defaultPlaceRadius {
+		radius[place] = r
+	}
+	for place, r := range i.config.API.PlaceRadius {
+		radius[place] = r
+	}
+	return radius
+}
+
+// reverseFallback answers reverse geocoding of point without addresses around: natural feature
+// containing point, e.g. lake, or the nearest place within radius of its type
+func (i *Importer) reverseFallback(ctx context.Context, lat, lon float64) (*elastic.Result, error) {
+	result, err := i.e.Containing(ctx, layerNatural, lat, lon)
+	if err != nil || len(result.Addresses) > 0 || result.TimedOut {
+		return result, err
+	}
+	return i.fallbackPlace(ctx, lat, lon)
+}
+
+// fallbackPlace returns the nearest place which radius covers point, marked as fallback_place
+func (i *Importer) fallbackPlace(ctx context.Context, lat, lon float64) (*elastic.Result, error) {
+	radius := i.placeRadius()
+	var farthest float64
+	for _, r := range radius {
+		farthest = math.Max(farthest, r)
+	}
+	if farthest <= 0 {
+		return &elastic.Result{}, nil
+	}
+	found, err := i.e.Nearby(ctx, layerPlace, lat, lon, fmt.Sprintf("%gkm", farthest))
+	if err != nil {
+		return nil, err
+	}
+	result := &elastic.Result{TimedOut: found.TimedOut}
+	point := geodesic.Point{Lat: lat, Lon: lon}
+	var nearest float64
+	for _, a := range found.Addresses {
+		distance := geodesic.Distance(point, geodesic.Point{Lat: a.Location.Lat, Lon: a.Location.Lon}) / 1000
+		if distance > radius[a.Category] || (len(result.Addresses) > 0 && distance >= nearest) {
+			continue
+		}
+		a.MatchType = matchFallbackPlace
+		result.Addresses = []model.Address{a}
+		nearest = distance
+	}
+	return result, nil
+}
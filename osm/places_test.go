@@ -0,0 +1,80 @@
+package osm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/maddevsio/ariadna/osm/osmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlaces(t *testing.T) {
+	data := osmtest.New().
+		Node(1, 42.87, 74.59, "place", "city", "name", "Бишкек").
+		Node(2, 42.70, 74.59, "place", "village", "name", "Көк-Жар").
+		Node(3, 42.80, 74.59, "place", "village")
+	storage := &memoryStorage{docs: make(map[string]model.Address)}
+	ctx := context.Background()
+	i, err := NewImporter(ctx, &config.Ariadna{}, WithParser(data), WithStorage(storage))
+	require.NoError(t, err)
+	require.NoError(t, i.Start(ctx))
+	require.NoError(t, i.WaitStop())
+
+	require.Contains(t, storage.docs, "node/1")
+	assert.Equal(t, layerPlace, storage.docs["node/1"].Layer)
+	assert.Equal(t, "city", storage.docs["node/1"].Category)
+	assert.NotContains(t, storage.docs, "node/3", "place without name is not indexed")
+
+	for _, c := range []struct {
+		lat  float64
+		want string
+	}{
+		{42.90, "Бишкек"},
+		{42.71, "Көк-Жар"},
+		{42.72, ""},
+	} {
+		result, err := i.fallbackPlace(ctx, c.lat, 74.59)
+		require.NoError(t, err)
+		if c.want == "" {
+			assert.Empty(t, result.Addresses, "%v is out of radius of places", c.lat)
+			continue
+		}
+		require.Len(t, result.Addresses, 1)
+		assert.Equal(t, c.want, result.Addresses[0].Name)
+		assert.Equal(t, matchFallbackPlace, result.Addresses[0].MatchType)
+	}
+
+	i.config.API.PlaceRadius = map[string]float64{"village": 3}
+	result, err := i.fallbackPlace(ctx, 42.72, 74.59)
+	require.NoError(t, err)
+	require.Len(t, result.Addresses, 1)
+	assert.Equal(t, "Көк-Жар", result.Addresses[0].Name)
+}
+
+func TestReverseFallbackPlace(t *testing.T) {
+	storage := &memoryStorage{docs: map[string]model.Address{
+		"node/1": {Name: "Бишкек", Layer: layerPlace, Category: "city", Location: model.Location{Lat: 42.87, Lon: 74.59}},
+	}}
+	g, err := NewGeocoder(&config.Ariadna{}, WithStorage(storage))
+	require.NoError(t, err)
+	i := g.i
+	i.metrics = newQueryMetrics(0, true)
+	router := httprouter.New()
+	router.GET("/api/reverse/:lat/:lon", i.reverseGeoCodeHandler)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/reverse/42.9/74.59", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var addresses []model.Address
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &addresses))
+	require.Len(t, addresses, 1)
+	assert.Equal(t, "Бишкек", addresses[0].Name)
+	assert.Equal(t, matchFallbackPlace, addresses[0].MatchType)
+}
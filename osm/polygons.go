@@ -0,0 +1,209 @@
+package osm
+
+import (
+	"container/heap"
+	"math"
+
+	"github.com/maddevsio/ariadna/geodesic"
+	"github.com/maddevsio/ariadna/model"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// labelPrecision is fraction of polygon size the label point search stops at
+const labelPrecision = 0.001
+
+// setPolygonMetrics fills area, centroid and label point of polygonal geometry.
+// It reports false for other geometries and degenerate polygons
+func setPolygonMetrics(address *model.Address, g *geojson.Geometry) bool {
+	polygons := geometryPolygons(g)
+	var (
+		area, largest float64
+		cx, cy        float64
+		label         [][][]float64
+	)
+	for _, polygon := range polygons {
+		if len(polygon) == 0 || len(polygon[0]) < 4 {
+			continue
+		}
+		a := polygonArea(polygon)
+		if a <= 0 {
+			continue
+		}
+		x, y := ringCentroid(polygon[0])
+		cx, cy = cx+x*a, cy+y*a
+		area += a
+		if a > largest {
+			largest, label = a, polygon
+		}
+	}
+	if area == 0 {
+		return false
+	}
+	lon, lat := labelPoint(label)
+	address.Area = math.Round(area)
+	address.Centroid = &model.Location{Lat: cy / area, Lon: cx / area}
+	address.LabelPoint = &model.Location{Lat: lat, Lon: lon}
+	return true
+}
+
+func geometryPolygons(g *geojson.Geometry) [][][][]float64 {
+	switch {
+	case g == nil:
+		return nil
+	case g.IsPolygon():
+		return [][][][]float64{g.Polygon}
+	case g.IsMultiPolygon():
+		return g.MultiPolygon
+	}
+	return nil
+}
+
+// polygonArea returns area of outer ring minus holes on sphere in square meters
+func polygonArea(polygon [][][]float64) float64 {
+	area := geodesic.Area(polygon[0])
+	for _, hole := range polygon[1:] {
+		area -= geodesic.Area(hole)
+	}
+	return math.Max(area, 0)
+}
+
+// ringCentroid returns centroid of ring area as lon, lat
+func ringCentroid(ring [][]float64) (float64, float64) {
+	// longitudes are scaled, so the centroid is computed on equirectangular projection
+	scale := math.Cos(radians(ring[0][1]))
+	var area, cx, cy float64
+	for n := 0; n+1 < len(ring); n++ {
+		x0, y0 := (ring[n][0]-ring[0][0])*scale, ring[n][1]-ring[0][1]
+		x1, y1 := (ring[n+1][0]-ring[0][0])*scale, ring[n+1][1]-ring[0][1]
+		cross := x0*y1 - x1*y0
+		area += cross
+		cx += (x0 + x1) * cross
+		cy += (y0 + y1) * cross
+	}
+	if area == 0 {
+		return ring[0][0], ring[0][1]
+	}
+	return ring[0][0] + cx/(3*area)/scale, ring[0][1] + cy/(3*area)
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+// labelPoint returns pole of inaccessibility of polygon as lon, lat: the interior point
+// farthest from its edges, found by quadtree search of polylabel
+func labelPoint(polygon [][][]float64) (float64, float64) {
+	outer := polygon[0]
+	scale := math.Cos(radians(outer[0][1]))
+	rings := make([][][2]float64, 0, len(polygon))
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for n, ring := range polygon {
+		projected := make([][2]float64, 0, len(ring))
+		for _, c := range ring {
+			x, y := c[0]*scale, c[1]
+			projected = append(projected, [2]float64{x, y})
+			if n == 0 {
+				minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+				minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+			}
+		}
+		rings = append(rings, projected)
+	}
+	width := math.Max(maxX-minX, maxY-minY)
+	if width == 0 {
+		return outer[0][0], outer[0][1]
+	}
+	// slivers would need too many initial cells of their width
+	size := math.Max(math.Min(maxX-minX, maxY-minY), width/100)
+	precision := width * labelPrecision
+	cells := &cellQueue{}
+	for x := minX; x < maxX; x += size {
+		for y := minY; y < maxY; y += size {
+			heap.Push(cells, newCell(x+size/2, y+size/2, size/2, rings))
+		}
+	}
+	// centroid is often a good start and makes the answer stable for simple shapes
+	cx, cy := ringCentroid(outer)
+	best := newCell(cx*scale, cy, 0, rings)
+	if bbox := newCell(minX+(maxX-minX)/2, minY+(maxY-minY)/2, 0, rings); bbox.distance > best.distance {
+		best = bbox
+	}
+	for cells.Len() > 0 {
+		c := heap.Pop(cells).(cell)
+		if c.distance > best.distance {
+			best = c
+		}
+		if c.max-best.distance <= precision {
+			continue
+		}
+		half := c.half / 2
+		heap.Push(cells, newCell(c.x-half, c.y-half, half, rings))
+		heap.Push(cells, newCell(c.x+half, c.y-half, half, rings))
+		heap.Push(cells, newCell(c.x-half, c.y+half, half, rings))
+		heap.Push(cells, newCell(c.x+half, c.y+half, half, rings))
+	}
+	return best.x / scale, best.y
+}
+
+// cell is square of label point search. Distance is signed distance from its center
+// to polygon edges, negative outside, max is the best distance reachable inside the cell
+type cell struct {
+	x, y, half float64
+	distance   float64
+	max        float64
+}
+
+func newCell(x, y, half float64, rings [][][2]float64) cell {
+	d := pointToPolygon(x, y, rings)
+	return cell{x: x, y: y, half: half, distance: d, max: d + half*math.Sqrt2}
+}
+
+// pointToPolygon returns distance from point to the nearest edge, negative when point is outside
+func pointToPolygon(x, y float64, rings [][][2]float64) float64 {
+	inside := false
+	best := math.Inf(1)
+	for _, ring := range rings {
+		for n, m := 0, len(ring)-1; n < len(ring); m, n = n, n+1 {
+			a, b := ring[n], ring[m]
+			if (a[1] > y) != (b[1] > y) && x < (b[0]-a[0])*(y-a[1])/(b[1]-a[1])+a[0] {
+				inside = !inside
+			}
+			px, py := projectToSegment(a[0]-x, a[1]-y, b[0]-x, b[1]-y)
+			best = math.Min(best, px*px+py*py)
+		}
+	}
+	if inside {
+		return math.Sqrt(best)
+	}
+	return -math.Sqrt(best)
+}
+
+// cellQueue pops cells with the best reachable distance first
+type cellQueue []cell
+
+func (q cellQueue) Len() int            { return len(q) }
+func (q cellQueue) Less(a, b int) bool  { return q[a].max > q[b].max }
+func (q cellQueue) Swap(a, b int)       { q[a], q[b] = q[b], q[a] }
+func (q *cellQueue) Push(x interface{}) { *q = append(*q, x.(cell)) }
+func (q *cellQueue) Pop() interface{} {
+	old := *q
+	c := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return c
+}
+
+// interiorPoint returns label point of polygon, which unlike its vertices is inside it.
+// False is returned for polygons of less than three points
+func interiorPoint(p *geodesic.Polygon) (geodesic.Point, bool) {
+	points := p.Points()
+	if len(points) < 3 {
+		return geodesic.Point{}, false
+	}
+	ring := make([][]float64, 0, len(points)+1)
+	for _, point := range points {
+		ring = append(ring, []float64{point.Lon, point.Lat})
+	}
+	ring = append(ring, ring[0])
+	lon, lat := labelPoint([][][]float64{ring})
+	return geodesic.Point{Lat: lat, Lon: lon}, true
+}
@@ -0,0 +1,54 @@
+package osm
+
+import (
+	"math"
+	"testing"
+
+	"github.com/maddevsio/ariadna/geodesic"
+	"github.com/maddevsio/ariadna/model"
+	geojson "github.com/paulmach/go.geojson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolygonMetrics(t *testing.T) {
+	square := [][]float64{{74, 42}, {74.01, 42}, {74.01, 42.01}, {74, 42.01}, {74, 42}}
+	var address model.Address
+	require.True(t, setPolygonMetrics(&address, geojson.NewPolygonGeometry([][][]float64{square})))
+	side := 0.01 * earthRadiusM * math.Pi / 180
+	want := side * side * math.Cos(radians(42.005))
+	assert.InEpsilon(t, want, address.Area, 0.001)
+	assert.InDelta(t, 42.005, address.Centroid.Lat, 1e-6)
+	assert.InDelta(t, 74.005, address.Centroid.Lon, 1e-6)
+	assert.InDelta(t, 42.005, address.LabelPoint.Lat, 1e-4)
+	assert.InDelta(t, 74.005, address.LabelPoint.Lon, 1e-4)
+
+	var line model.Address
+	assert.False(t, setPolygonMetrics(&line, geojson.NewLineStringGeometry(square)))
+	assert.Zero(t, line.Area)
+}
+
+func TestLabelPointInsideConcavePolygon(t *testing.T) {
+	// U shape opening to the north, its centroid lies in the empty middle
+	u := [][]float64{
+		{0, 0}, {3, 0}, {3, 3}, {2, 3}, {2, 1}, {1, 1}, {1, 3}, {0, 3}, {0, 0},
+	}
+	cx, cy := ringCentroid(u)
+	rings := [][][2]float64{{}}
+	for _, c := range u {
+		rings[0] = append(rings[0], [2]float64{c[0], c[1]})
+	}
+	assert.True(t, pointToPolygon(cx, cy, rings) < 0, "centroid is outside")
+	x, y := labelPoint([][][]float64{u})
+	assert.True(t, pointToPolygon(x, y, rings) > 0.4, "label point is deep inside")
+
+	p := geodesic.NewPolygon([]geodesic.Point{
+		{Lat: 0, Lon: 0}, {Lat: 0, Lon: 3}, {Lat: 3, Lon: 3}, {Lat: 3, Lon: 2},
+		{Lat: 1, Lon: 2}, {Lat: 1, Lon: 1}, {Lat: 3, Lon: 1}, {Lat: 3, Lon: 0},
+	})
+	inside, ok := interiorPoint(p)
+	require.True(t, ok)
+	assert.True(t, p.Contains(inside))
+	_, ok = interiorPoint(geodesic.NewPolygon([]geodesic.Point{{Lat: 0, Lon: 0}, {Lat: 1, Lon: 1}}))
+	assert.False(t, ok)
+}
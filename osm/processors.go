@@ -0,0 +1,73 @@
+package osm
+
+import (
+	"fmt"
+	"plugin"
+
+	"github.com/maddevsio/ariadna/model"
+)
+
+// processorSymbol is name of symbol looked up in Go plugins listed in config
+const processorSymbol = "Processor"
+
+// DocumentProcessor changes documents before indexing, e.g. enriches them with data of
+// internal systems. Document is dropped when any processor returns false
+type DocumentProcessor interface {
+	Process(id string, address *model.Address) (keep bool, err error)
+}
+
+// DocumentProcessorFunc is an adapter to use ordinary functions as document processors
+type DocumentProcessorFunc func(id string, address *model.Address) (bool, error)
+
+// Process calls f(id, address)
+func (f DocumentProcessorFunc) Process(id string, address *model.Address) (bool, error) {
+	return f(id, address)
+}
+
+// RegisterProcessor adds processor of documents. Processors run in order of registration
+func (i *Importer) RegisterProcessor(p DocumentProcessor) {
+	i.processors = append(i.processors, p)
+}
+
+// WithProcessor registers document processor, see Importer.RegisterProcessor
+func WithProcessor(p DocumentProcessor) Option {
+	return func(i *Importer) {
+		i.RegisterProcessor(p)
+	}
+}
+
+// process runs registered processors on document
+func (i *Importer) process(id string, address *model.Address) (bool, error) {
+	for _, p := range i.processors {
+		keep, err := p.Process(id, address)
+		if err != nil {
+			return false, fmt.Errorf("process document %s: %w", id, err)
+		}
+		if !keep {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// loadProcessor opens Go plugin exporting Processor as DocumentProcessor
+// or as func(id string, address *model.Address) (bool, error)
+func loadProcessor(path string) (DocumentProcessor, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	sym, err := p.Lookup(processorSymbol)
+	if err != nil {
+		return nil, err
+	}
+	switch processor := sym.(type) {
+	case *DocumentProcessor:
+		return *processor, nil
+	case DocumentProcessor:
+		return processor, nil
+	case func(string, *model.Address) (bool, error):
+		return DocumentProcessorFunc(processor), nil
+	}
+	return nil, fmt.Errorf("plugin %s: %s is %T, not a document processor", path, processorSymbol, sym)
+}
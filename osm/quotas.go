@@ -0,0 +1,336 @@
+package osm
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/elastic"
+	v1 "github.com/maddevsio/ariadna/schema/v1"
+)
+
+const (
+	defaultQuotaFlush = 10 * time.Second
+	// dayPeriod and monthPeriod are layouts of UTC periods requests are counted in
+	dayPeriod   = "2006-01-02"
+	monthPeriod = "2006-01"
+)
+
+type (
+	// usageCounter counts requests of API keys per period. Stored counts are read from storage
+	// and include requests served by other instances, pending ones are not written yet
+	usageCounter struct {
+		mu      sync.Mutex
+		stored  map[usageKey]int64
+		pending map[usageKey]int64
+	}
+	usageKey struct {
+		key    string
+		period string
+	}
+	// periodQuota is limit of requests in period which resets at reset
+	periodQuota struct {
+		name   string
+		period string
+		limit  int64
+		reset  time.Time
+	}
+)
+
+// count returns requests of key in period known to this instance
+func (u *usageCounter) count(k usageKey) int64 {
+	return u.stored[k] + u.pending[k]
+}
+
+// use counts request of key in periods of quotas unless one of them is exhausted. It returns
+// the quota with the fewest requests left and how many are left after this request
+func (u *usageCounter) use(key string, quotas []periodQuota) (periodQuota, int64, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	var (
+		tightest  periodQuota
+		remaining int64 = -1
+	)
+	for _, q := range quotas {
+		if q.limit <= 0 {
+			continue
+		}
+		left := q.limit - u.count(usageKey{key, q.period})
+		if left <= 0 {
+			return q, 0, false
+		}
+		if remaining < 0 || left-1 < remaining {
+			tightest, remaining = q, left-1
+		}
+	}
+	if u.pending == nil {
+		u.pending = make(map[usageKey]int64)
+	}
+	for _, q := range quotas {
+		u.pending[usageKey{key, q.period}]++
+	}
+	return tightest, remaining, true
+}
+
+// take returns pending counts to be written and accounts them as stored
+func (u *usageCounter) take() []elastic.Usage {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	usage := make([]elastic.Usage, 0, len(u.pending))
+	if u.stored == nil {
+		u.stored = make(map[usageKey]int64)
+	}
+	for k, n := range u.pending {
+		usage = append(usage, elastic.Usage{Key: k.key, Period: k.period, Count: n})
+		u.stored[k] += n
+	}
+	u.pending = nil
+	return usage
+}
+
+// restore returns counts which failed to be written to pending
+func (u *usageCounter) restore(usage []elastic.Usage) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.pending == nil {
+		u.pending = make(map[usageKey]int64)
+	}
+	for _, c := range usage {
+		k := usageKey{c.Key, c.Period}
+		u.stored[k] -= c.Count
+		u.pending[k] += c.Count
+	}
+}
+
+// load replaces stored counts by ones read from storage, counts of past periods are dropped
+func (u *usageCounter) load(usage []elastic.Usage) {
+	stored := make(map[usageKey]int64, len(usage))
+	for _, c := range usage {
+		stored[usageKey{c.Key, c.Period}] = c.Count
+	}
+	u.mu.Lock()
+	u.stored = stored
+	u.mu.Unlock()
+}
+
+// pendingOf returns counts of periods not written yet
+func (u *usageCounter) pendingOf(periods []string) map[usageKey]int64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	pending := make(map[usageKey]int64)
+	for k, n := range u.pending {
+		for _, period := range periods {
+			if k.period == period {
+				pending[k] = n
+			}
+		}
+	}
+	return pending
+}
+
+// currentPeriods returns UTC day and month of t
+func currentPeriods(t time.Time) (string, string) {
+	t = t.UTC()
+	return t.Format(dayPeriod), t.Format(monthPeriod)
+}
+
+// limits returns daily and monthly limit of subject counted in usage, id of API key or address of
+// client without key. Zero is unlimited
+func (i *Importer) limits(subject string) (int64, int64) {
+	c := i.config.Quotas
+	for _, client := range c.Clients {
+		if keyID(client.Key) == subject {
+			return client.Daily, client.Monthly
+		}
+	}
+	return c.Daily, c.Monthly
+}
+
+// quotaSubject returns what requests of r are counted as: id of API key or address of client
+// without key. Keys which are not configured are rejected, so a made-up key doesn't get quota
+// of its own
+func (i *Importer) quotaSubject(r *http.Request) (string, bool) {
+	key := clientKey(r)
+	if key == "" {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		return "ip:" + host, true
+	}
+	c := i.config.Quotas
+	keys := append(append(append([]string(nil), c.Keys...), c.AdminKeys...), i.signingKeys()...)
+	for _, client := range c.Clients {
+		keys = append(keys, client.Key)
+	}
+	for _, k := range keys {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			return keyID(key), true
+		}
+	}
+	return "", false
+}
+
+// quotas returns daily and monthly quotas of subject at t
+func (i *Importer) quotas(subject string, t time.Time) []periodQuota {
+	daily, monthly := i.limits(subject)
+	t = t.UTC()
+	day, month := currentPeriods(t)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return []periodQuota{
+		{name: "daily", period: day, limit: daily, reset: midnight.AddDate(0, 0, 1)},
+		{name: "monthly", period: month, limit: monthly, reset: midnight.AddDate(0, 1, 1-t.Day())},
+	}
+}
+
+// withQuota counts API requests by API key, or by client address without key, and rejects them
+// with 429 once daily or monthly quota is exhausted. Requests with unknown key are rejected with
+// 401. The quota closest to exhaustion is reported in X-Quota-Limit, X-Quota-Remaining and
+// X-Quota-Reset headers
+func (i *Importer) withQuota(next http.Handler) http.Handler {
+	if !i.config.Quotas.Enabled {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") || r.URL.Path == "/api/usage" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		subject, ok := i.quotaSubject(r)
+		if !ok {
+			i.writeFailure(w, r, http.StatusUnauthorized, BadRequest{Error: "unknown API key", Code: "unauthorized"})
+			return
+		}
+		now := time.Now()
+		q, remaining, ok := i.usage.use(subject, i.quotas(subject, now))
+		if q.limit > 0 {
+			w.Header().Set("X-Quota-Limit", strconv.FormatInt(q.limit, 10))
+			w.Header().Set("X-Quota-Remaining", strconv.FormatInt(remaining, 10))
+			w.Header().Set("X-Quota-Reset", q.reset.Format(time.RFC3339))
+		}
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(q.reset.Sub(now)/time.Second)+1))
+			i.writeFailure(w, r, http.StatusTooManyRequests, BadRequest{
+				Error: fmt.Sprintf("%s quota of %d requests is exhausted until %s", q.name, q.limit, q.reset.Format(time.RFC3339)),
+				Code:  "quota_exceeded",
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// runQuotas writes counted requests to storage and reads counts of all instances back
+func (i *Importer) runQuotas() {
+	flush := i.config.Quotas.FlushInterval
+	if flush <= 0 {
+		flush = defaultQuotaFlush
+	}
+	ticker := time.NewTicker(flush)
+	defer ticker.Stop()
+	for {
+		i.flushUsage(context.Background())
+		<-ticker.C
+	}
+}
+
+// flushUsage writes pending counts and reloads counts of current periods, failed counts are
+// written with the next flush
+func (i *Importer) flushUsage(ctx context.Context) {
+	usage := i.usage.take()
+	if err := i.e.AddUsage(ctx, usage); err != nil {
+		var partial *elastic.UsageError
+		if errors.As(err, &partial) {
+			// other counts are added, writing them again would count them twice
+			usage = partial.Failed
+		}
+		i.usage.restore(usage)
+		i.logger.Errorf("could not write usage: %v", err)
+		return
+	}
+	day, month := currentPeriods(time.Now())
+	stored, err := i.e.Usage(ctx, []string{day, month})
+	if err != nil {
+		i.logger.Errorf("could not read usage: %v", err)
+		return
+	}
+	i.usage.load(stored)
+}
+
+// usageHandler returns request counts of API keys in periods of ?period=, days like 2022-01-15
+// or months like 2022-01, the current day and month by default. Keys are reported by their ids,
+// ?key= selects one by key, its id or ip:<address> of clients without key. It needs admin API key
+func (i *Importer) usageHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	c := i.config.Quotas
+	if !c.Enabled {
+		i.writeFailure(w, r, http.StatusBadRequest, BadRequest{Error: "quotas are disabled", Code: "invalid_request"})
+		return
+	}
+	if status, e := keyAllowed(r, c.AdminKeys, "usage"); status != http.StatusOK {
+		i.writeFailure(w, r, status, e)
+		return
+	}
+	periods := r.URL.Query()["period"]
+	for _, p := range periods {
+		if _, err := time.Parse(dayPeriod, p); err == nil {
+			continue
+		}
+		if _, err := time.Parse(monthPeriod, p); err != nil {
+			i.writeFailure(w, r, http.StatusBadRequest, BadRequest{Error: fmt.Sprintf("invalid period %q, expected day like 2022-01-15 or month like 2022-01", p), Code: "invalid_request"})
+			return
+		}
+	}
+	if len(periods) == 0 {
+		day, month := currentPeriods(time.Now())
+		periods = []string{day, month}
+	}
+	stored, err := i.e.Usage(r.Context(), periods)
+	if err != nil {
+		i.writeError(w, r, err)
+		return
+	}
+	counts := i.usage.pendingOf(periods)
+	for _, u := range stored {
+		counts[usageKey{u.Key, u.Period}] += u.Count
+	}
+	key := r.URL.Query().Get("key")
+	usage := make([]elastic.Usage, 0, len(counts))
+	for k, n := range counts {
+		if key == "" || k.key == key || k.key == keyID(key) {
+			usage = append(usage, elastic.Usage{Key: k.key, Period: k.period, Count: n})
+		}
+	}
+	sort.Slice(usage, func(a, b int) bool {
+		if usage[a].Key != usage[b].Key {
+			return usage[a].Key < usage[b].Key
+		}
+		return usage[a].Period < usage[b].Period
+	})
+	if schemaVersion(r) != v1.Version {
+		i.writeJSON(w, http.StatusOK, usage)
+		return
+	}
+	body := v1.Usage{SchemaVersion: v1.Version, Usage: make([]v1.KeyUsage, 0, len(usage))}
+	for _, u := range usage {
+		body.Usage = append(body.Usage, v1.KeyUsage{Key: u.Key, Period: u.Period, Count: u.Count, Limit: i.quotaLimit(u.Key, u.Period)})
+	}
+	i.writeV1(w, http.StatusOK, body)
+}
+
+// quotaLimit returns limit of subject in day or month period, zero when unlimited
+func (i *Importer) quotaLimit(subject, period string) int64 {
+	daily, monthly := i.limits(subject)
+	if len(period) == len(dayPeriod) {
+		return daily
+	}
+	return monthly
+}
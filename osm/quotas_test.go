@@ -0,0 +1,105 @@
+package osm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/elastic"
+	v1 "github.com/maddevsio/ariadna/schema/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuotas(t *testing.T) {
+	storage := &memoryStorage{}
+	conf := &config.Ariadna{Quotas: config.Quotas{
+		Enabled:   true,
+		Daily:     2,
+		Keys:      []string{"client"},
+		Clients:   []config.ClientQuota{{Key: "partner", Monthly: 100}},
+		AdminKeys: []string{"admin"},
+	}}
+	g, err := NewGeocoder(conf, WithStorage(storage))
+	require.NoError(t, err)
+	i := g.i
+	router := httprouter.New()
+	router.GET("/api/reverse/:lat/:lon", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w.WriteHeader(http.StatusOK)
+	})
+	router.GET("/api/usage", i.usageHandler)
+	handler := i.withSchema(i.withQuota(router))
+	get := func(path, key string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		r.Header.Set("X-Api-Key", key)
+		r.Header.Set("Accept", v1.MediaType)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w := get("/api/reverse/42.87/74.59", "client")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("X-Quota-Limit"))
+	assert.Equal(t, "1", w.Header().Get("X-Quota-Remaining"))
+	// counts of other instances arrive with flush
+	require.NoError(t, storage.AddUsage(context.Background(), []elastic.Usage{{Key: keyID("client"), Period: time.Now().UTC().Format(dayPeriod), Count: 1}}))
+	i.flushUsage(context.Background())
+
+	w = get("/api/reverse/42.87/74.59", "client")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), "quota_exceeded")
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	for n := 0; n < 3; n++ {
+		assert.Equal(t, http.StatusOK, get("/api/reverse/42.87/74.59", "partner").Code)
+	}
+	// unknown key doesn't get quota of its own
+	w = get("/api/reverse/42.87/74.59", "made-up")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	// requests without key are counted by address
+	assert.Equal(t, http.StatusOK, get("/api/reverse/42.87/74.59", "").Code)
+	assert.Equal(t, http.StatusOK, get("/api/reverse/42.87/74.59", "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, get("/api/reverse/42.87/74.59", "").Code)
+
+	assert.Equal(t, http.StatusForbidden, get("/api/usage", "client").Code)
+	w = get("/api/usage?key=partner", "admin")
+	require.Equal(t, http.StatusOK, w.Code)
+	var usage v1.Usage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &usage))
+	require.Len(t, usage.Usage, 2)
+	// month sorts before its days
+	assert.Equal(t, int64(100), usage.Usage[0].Limit)
+	assert.Equal(t, int64(3), usage.Usage[1].Count)
+	assert.Zero(t, usage.Usage[1].Limit)
+	assert.Equal(t, keyID("partner"), usage.Usage[0].Key)
+	assert.NotContains(t, w.Body.String(), `"partner"`)
+	w = get("/api/usage?key=ip:192.0.2.1", "admin")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &usage))
+	require.Len(t, usage.Usage, 2)
+	assert.Equal(t, int64(2), usage.Usage[1].Count)
+	assert.Equal(t, http.StatusBadRequest, get("/api/usage?period=yesterday", "admin").Code)
+}
+
+func TestFlushUsageRestoresFailedCounts(t *testing.T) {
+	storage := &memoryStorage{failUsage: map[string]bool{"bad": true}}
+	g, err := NewGeocoder(&config.Ariadna{Quotas: config.Quotas{Enabled: true}}, WithStorage(storage))
+	require.NoError(t, err)
+	i := g.i
+	day := time.Now().UTC().Format(dayPeriod)
+	quotas := []periodQuota{{period: day}}
+	i.usage.use("good", quotas)
+	i.usage.use("bad", quotas)
+
+	i.flushUsage(context.Background())
+	i.flushUsage(context.Background())
+	// written count isn't added again with failed one
+	assert.Equal(t, int64(1), storage.usage["good/"+day].Count)
+	assert.Equal(t, map[usageKey]int64{{"bad", day}: 1}, i.usage.pendingOf([]string{day}))
+}
@@ -0,0 +1,252 @@
+package osm
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/elastic"
+)
+
+const defaultProfile = "default"
+
+// rankingProfile picks ranking profile for request. API keys listed in experiment are always
+// served by candidate, other clients get it with configured percentage. Split is sticky per client
+func (i *Importer) rankingProfile(r *http.Request) (string, config.RankingProfile) {
+	ranking := i.config.Ranking
+	exp := ranking.Experiment
+	name := exp.Control
+	if exp.Candidate != "" {
+		key := clientKey(r)
+		for _, k := range exp.Keys {
+			if key != "" && k == key {
+				name = exp.Candidate
+			}
+		}
+		if name != exp.Candidate && exp.Percentage > 0 && bucket(clientID(r)) < exp.Percentage {
+			name = exp.Candidate
+		}
+	}
+	return i.profileByName(name)
+}
+
+// searchProfile returns ranking profile for request with layer and category boosts of query
+// parameters like layers=address^2,poi^1 applied over boosts of the profile. Profile given by
+// profile parameter is used as query preset instead of the one of experiment
+func (i *Importer) searchProfile(r *http.Request) (string, config.RankingProfile, error) {
+	name, profile := i.rankingProfile(r)
+	if preset := r.URL.Query().Get("profile"); preset != "" {
+		var ok bool
+		if profile, ok = i.config.Ranking.Profiles[preset]; !ok {
+			return name, profile, fmt.Errorf("unknown profile %q, available: %s", preset, strings.Join(profileNames(i.config.Ranking.Profiles), ", "))
+		}
+		name = preset
+	}
+	layers, err := parseBoosts(r.URL.Query().Get("layers"), func(name string) (string, bool) {
+		layer, ok := importLayers[strings.ToLower(name)]
+		return layer, ok
+	})
+	if err != nil {
+		return name, profile, fmt.Errorf("invalid layers: %v", err)
+	}
+	categories, err := parseBoosts(r.URL.Query().Get("categories"), func(name string) (string, bool) {
+		return name, true
+	})
+	if err != nil {
+		return name, profile, fmt.Errorf("invalid categories: %v", err)
+	}
+	filters, err := parseFilters(r.URL.Query()["filter"], i.config.Fields)
+	if err != nil {
+		return name, profile, err
+	}
+	profile.LayerBoosts = mergeBoosts(profile.LayerBoosts, layers)
+	profile.CategoryBoosts = mergeBoosts(profile.CategoryBoosts, categories)
+	profile.Filters = mergeFilters(profile.Filters, filters)
+	if s := r.URL.Query().Get("facets"); s != "" {
+		if profile.Facets, err = parseFacets(strings.Split(s, ","), i.config.Fields); err != nil {
+			return name, profile, err
+		}
+	}
+	return name, profile, nil
+}
+
+// facetFields are fields of documents facets can count besides extracted ones
+var facetFields = []string{"layer", "category", "country", "city", "town", "village", "district", "street"}
+
+// parseFacets validates facets, they are fields of documents or extracted fields
+func parseFacets(facets []string, fields []config.Field) ([]string, error) {
+	result := make([]string, 0, len(facets))
+next:
+	for _, facet := range facets {
+		facet = strings.TrimSpace(facet)
+		for _, name := range facetFields {
+			if facet == name {
+				result = append(result, facet)
+				continue next
+			}
+		}
+		for _, f := range fields {
+			if facet == f.Name {
+				result = append(result, facet)
+				continue next
+			}
+		}
+		return nil, fmt.Errorf("unknown facet %q", facet)
+	}
+	return result, nil
+}
+
+// parseBoosts parses comma separated names with optional positive weights like "poi^1.5",
+// names are resolved by known
+func parseBoosts(s string, known func(name string) (string, bool)) (map[string]float64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	boosts := make(map[string]float64)
+	for _, item := range strings.Split(s, ",") {
+		name, weight := strings.TrimSpace(item), 1.0
+		if n := strings.Index(name, "^"); n >= 0 {
+			w, err := strconv.ParseFloat(name[n+1:], 64)
+			if err != nil || w <= 0 {
+				return nil, fmt.Errorf("boost of %q is not positive number", item)
+			}
+			name, weight = name[:n], w
+		}
+		resolved, ok := known(name)
+		if name == "" || !ok {
+			return nil, fmt.Errorf("unknown name %q", name)
+		}
+		boosts[resolved] = weight
+	}
+	return boosts, nil
+}
+
+// mergeBoosts returns boosts of profile overridden by boosts of request, profile is not modified
+func mergeBoosts(profile, request map[string]float64) map[string]float64 {
+	if len(request) == 0 {
+		return profile
+	}
+	merged := make(map[string]float64, len(profile)+len(request))
+	for name, weight := range profile {
+		merged[name] = weight
+	}
+	for name, weight := range request {
+		merged[name] = weight
+	}
+	return merged
+}
+
+// mergeFilters returns filters of profile with fields filtered by request replaced
+func mergeFilters(profile, request map[string][]string) map[string][]string {
+	if len(request) == 0 {
+		return profile
+	}
+	merged := make(map[string][]string, len(profile)+len(request))
+	for field, values := range profile {
+		merged[field] = values
+	}
+	for field, values := range request {
+		merged[field] = values
+	}
+	return merged
+}
+
+// validateProfiles checks layers, boosts, filters and size of ranking profiles, plural names of
+// layers are replaced by layers they stand for and filter values are coerced to field types
+func validateProfiles(profiles map[string]config.RankingProfile, fields []config.Field) error {
+	for name, profile := range profiles {
+		if profile.Size < 0 {
+			return fmt.Errorf("ranking profile %s: negative size", name)
+		}
+		layers := make([]string, 0, len(profile.Layers))
+		for _, l := range profile.Layers {
+			layer, ok := importLayers[strings.ToLower(l)]
+			if !ok {
+				return fmt.Errorf("ranking profile %s: unknown layer %q", name, l)
+			}
+			layers = append(layers, layer)
+		}
+		boosts := make(map[string]float64, len(profile.LayerBoosts))
+		for l, weight := range profile.LayerBoosts {
+			layer, ok := importLayers[strings.ToLower(l)]
+			if !ok {
+				return fmt.Errorf("ranking profile %s: unknown layer %q", name, l)
+			}
+			if weight <= 0 {
+				return fmt.Errorf("ranking profile %s: boost of %s is not positive", name, l)
+			}
+			boosts[layer] = weight
+		}
+		for category, weight := range profile.CategoryBoosts {
+			if weight <= 0 {
+				return fmt.Errorf("ranking profile %s: boost of %s is not positive", name, category)
+			}
+		}
+		filters := make(map[string][]string, len(profile.Filters))
+		for field, values := range profile.Filters {
+			for _, raw := range values {
+				value, err := filterValue(field, raw, fields)
+				if err != nil {
+					return fmt.Errorf("ranking profile %s: %v", name, err)
+				}
+				filters[field] = append(filters[field], value)
+			}
+		}
+		if _, err := parseFacets(profile.Facets, fields); err != nil {
+			return fmt.Errorf("ranking profile %s: %v", name, err)
+		}
+		if len(profile.Layers) > 0 {
+			profile.Layers = layers
+		}
+		if len(profile.Filters) > 0 {
+			profile.Filters = filters
+		}
+		if len(profile.LayerBoosts) > 0 {
+			profile.LayerBoosts = boosts
+		}
+		profiles[name] = profile
+	}
+	return nil
+}
+
+func profileNames(profiles map[string]config.RankingProfile) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// profileByName returns configured ranking profile falling back to the default one
+func (i *Importer) profileByName(name string) (string, config.RankingProfile) {
+	profile, ok := i.config.Ranking.Profiles[name]
+	if !ok {
+		return defaultProfile, elastic.DefaultRanking
+	}
+	return name, profile
+}
+
+// clientID identifies client by API key or address
+func clientID(r *http.Request) string {
+	if key := clientKey(r); key != "" {
+		return key
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// bucket maps id to stable number in [0, 100)
+func bucket(id string) int {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return int(h.Sum32() % 100)
+}
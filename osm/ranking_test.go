@@ -0,0 +1,58 @@
+package osm
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchProfileBoosts(t *testing.T) {
+	profiles := map[string]config.RankingProfile{
+		defaultProfile: {LayerBoosts: map[string]float64{"address": 3, "transit": 0.5}},
+	}
+	i := &Importer{config: &config.Ariadna{Ranking: config.Ranking{Profiles: profiles, Experiment: config.Experiment{Control: defaultProfile}}}}
+
+	_, profile, err := i.searchProfile(httptest.NewRequest("GET", "/search?layers=addresses^2,poi^1.5&categories=cafe^3", nil))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"address": 2, "poi": 1.5, "transit": 0.5}, profile.LayerBoosts)
+	assert.Equal(t, map[string]float64{"cafe": 3}, profile.CategoryBoosts)
+	assert.Equal(t, 3.0, profiles[defaultProfile].LayerBoosts["address"])
+
+	for _, query := range []string{"layers=buildings^2", "layers=poi^0", "layers=poi^x", "categories=^2"} {
+		_, _, err := i.searchProfile(httptest.NewRequest("GET", "/search?"+query, nil))
+		assert.Error(t, err, query)
+	}
+}
+
+func TestSearchProfilePreset(t *testing.T) {
+	profiles := map[string]config.RankingProfile{
+		"delivery": {Layers: []string{"addresses", "pois"}, LayerBoosts: map[string]float64{"addresses": 2}, Size: 5},
+	}
+	require.NoError(t, validateProfiles(profiles, nil))
+	assert.Equal(t, []string{"address", "poi"}, profiles["delivery"].Layers)
+	assert.Equal(t, map[string]float64{"address": 2}, profiles["delivery"].LayerBoosts)
+
+	i := &Importer{config: &config.Ariadna{Ranking: config.Ranking{Profiles: profiles}}}
+	name, profile, err := i.searchProfile(httptest.NewRequest("GET", "/api/search/Чуй?profile=delivery", nil))
+	require.NoError(t, err)
+	assert.Equal(t, "delivery", name)
+	assert.Equal(t, 5, profile.Size)
+
+	_, _, err = i.searchProfile(httptest.NewRequest("GET", "/api/search/Чуй?profile=tourism", nil))
+	assert.Error(t, err)
+	assert.Error(t, validateProfiles(map[string]config.RankingProfile{"a": {Layers: []string{"buildings"}}}, nil))
+	assert.Error(t, validateProfiles(map[string]config.RankingProfile{"a": {CategoryBoosts: map[string]float64{"cafe": -1}}}, nil))
+}
+
+func TestSearchProfileFacets(t *testing.T) {
+	i := &Importer{config: &config.Ariadna{Fields: []config.Field{{Name: "cuisine", Tag: "cuisine"}}}}
+	_, profile, err := i.searchProfile(httptest.NewRequest("GET", "/api/search/Чуй?facets=category,city,cuisine", nil))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"category", "city", "cuisine"}, profile.Facets)
+
+	_, _, err = i.searchProfile(httptest.NewRequest("GET", "/api/search/Чуй?facets=housenumber", nil))
+	assert.Error(t, err)
+}
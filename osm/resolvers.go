@@ -0,0 +1,35 @@
+package osm
+
+import "context"
+
+// QueryResolver translates special query syntax, e.g. internal location codes or
+// warehouse ids, into coordinates. Resolved queries are answered by reverse geocoding
+type QueryResolver interface {
+	// Resolve reports false when query is not handled by resolver
+	Resolve(query string) (lat, lon float64, ok bool, err error)
+}
+
+// QueryResolverFunc is an adapter to use ordinary functions as query resolvers
+type QueryResolverFunc func(query string) (float64, float64, bool, error)
+
+// Resolve calls f(query)
+func (f QueryResolverFunc) Resolve(query string) (float64, float64, bool, error) {
+	return f(query)
+}
+
+// RegisterResolver adds resolver consulted before built-in coordinates and plus codes detection.
+// Resolvers are tried in order of registration
+func (i *Importer) RegisterResolver(r QueryResolver) {
+	i.resolvers = append(i.resolvers, r)
+}
+
+// resolve runs registered resolvers and then built-in ones
+func (i *Importer) resolve(ctx context.Context, query string) (float64, float64, bool, error) {
+	for _, r := range i.resolvers {
+		lat, lon, ok, err := r.Resolve(query)
+		if err != nil || ok {
+			return lat, lon, ok, err
+		}
+	}
+	return i.decodeCode(ctx, query)
+}
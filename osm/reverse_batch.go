@@ -0,0 +1,140 @@
+package osm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/model"
+)
+
+// reverseBatchChunk is number of points sent to storage in one multi search request
+const reverseBatchChunk = 100
+
+type (
+	// reversePoint is element of batch reverse request body
+	reversePoint struct {
+		ID  string  `json:"id,omitempty"`
+		Lat float64 `json:"lat"`
+		Lon float64 `json:"lon"`
+	}
+	// reverseBatchResult is line of batch reverse response, results follow negotiated schema
+	reverseBatchResult struct {
+		ID       string      `json:"id,omitempty"`
+		Lat      float64     `json:"lat"`
+		Lon      float64     `json:"lon"`
+		Results  interface{} `json:"results,omitempty"`
+		TimedOut bool        `json:"timed_out,omitempty"`
+		Error    *BadRequest `json:"error,omitempty"`
+	}
+)
+
+// batchReverseHandler reverse geocodes JSON array of points and streams results in order
+// of points. Points are looked up in chunks of multi search requests, failed chunk gets
+// error lines and doesn't stop the batch
+func (i *Importer) batchReverseHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if !i.limitBody(w, r) {
+		return
+	}
+	var points []reversePoint
+	if err := json.NewDecoder(r.Body).Decode(&points); err != nil {
+		if e := bodyError(err); e.Code == "body_too_large" {
+			i.writeFailure(w, r, http.StatusRequestEntityTooLarge, e)
+			return
+		}
+		i.writeFailure(w, r, http.StatusBadRequest, BadRequest{Error: fmt.Sprintf("invalid points: %v", err), Code: "invalid_request"})
+		return
+	}
+	limit := i.maxBatch()
+	if len(points) > limit {
+		i.writeFailure(w, r, http.StatusBadRequest, BadRequest{Error: fmt.Sprintf("batch is limited to %d points", limit), Code: "batch_too_large"})
+		return
+	}
+	stream := newNDJSONStream(w, 1)
+	for start := 0; start < len(points); start += reverseBatchChunk {
+		end := start + reverseBatchChunk
+		if end > len(points) {
+			end = len(points)
+		}
+		for _, item := range i.reverseChunk(r, points[start:end]) {
+			if err := stream.write(item); err != nil {
+				i.logger.Error(err)
+				return
+			}
+		}
+	}
+}
+
+// reverseChunk reverse geocodes points of single multi search request within request timeout.
+// Points found in reverse cache are answered without the request
+func (i *Importer) reverseChunk(r *http.Request, points []reversePoint) []reverseBatchResult {
+	start := time.Now()
+	ctx := r.Context()
+	if timeout := i.config.API.RequestTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	items := make([]reverseBatchResult, len(points))
+	answer := func(n int, result *elastic.Result) {
+		query := strconv.FormatFloat(points[n].Lat, 'f', -1, 64) + "," + strconv.FormatFloat(points[n].Lon, 'f', -1, 64)
+		i.observe(r, endpointReverse, query, "", result.Addresses, start)
+		items[n].Results = batchAddresses(r, withCodes(preferPoint(result.Addresses, r.URL.Query().Get("point_type"))))
+		items[n].TimedOut = result.TimedOut
+	}
+	locations := make([]model.Location, 0, len(points))
+	valid := make([]int, 0, len(points))
+	keys := make([]string, len(points))
+	for n, p := range points {
+		items[n] = reverseBatchResult{ID: p.ID, Lat: p.Lat, Lon: p.Lon}
+		if err := validPoint(p.Lat, p.Lon); err != nil {
+			items[n].Error = &BadRequest{Error: err.Error(), Code: "invalid_request"}
+			continue
+		}
+		keys[n] = i.reverseCacheKey(ctx, p.Lat, p.Lon)
+		if addresses, ok := i.reverseCache.get(keys[n]); ok {
+			answer(n, &elastic.Result{Addresses: addresses})
+			continue
+		}
+		locations = append(locations, model.Location{Lat: p.Lat, Lon: p.Lon})
+		valid = append(valid, n)
+	}
+	if len(locations) == 0 {
+		return items
+	}
+	results, err := i.e.ReverseBatch(ctx, locations)
+	if err == nil && len(results) != len(locations) {
+		err = fmt.Errorf("got %d results for %d points", len(results), len(locations))
+	}
+	if err != nil {
+		status, code := errorStatus(err)
+		if status == http.StatusInternalServerError {
+			i.logger.Error(err)
+		}
+		for _, n := range valid {
+			items[n].Error = &BadRequest{Error: err.Error(), Code: code}
+		}
+		return items
+	}
+	for k, n := range valid {
+		result := results[k]
+		if len(result.Addresses) == 0 && !result.TimedOut {
+			// point may be over water or out of town without addresses around
+			if result, err = i.reverseFallback(ctx, points[n].Lat, points[n].Lon); err != nil {
+				_, code := errorStatus(err)
+				items[n].Error = &BadRequest{Error: err.Error(), Code: code}
+				continue
+			}
+		}
+		if !result.TimedOut {
+			i.reverseCache.put(keys[n], result.Addresses)
+		}
+		answer(n, result)
+	}
+	return items
+}
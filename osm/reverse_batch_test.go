@@ -0,0 +1,57 @@
+package osm
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchReverse(t *testing.T) {
+	storage := &memoryStorage{docs: map[string]model.Address{
+		"relation/5": {Name: "Иссык-Куль", Layer: layerNatural},
+	}}
+	g, err := NewGeocoder(&config.Ariadna{API: config.API{MaxBatch: 3}}, WithStorage(storage))
+	require.NoError(t, err)
+	i := g.i
+	i.metrics = newQueryMetrics(0, true)
+	router := httprouter.New()
+	router.POST("/api/reverse/batch", i.batchReverseHandler)
+
+	w := httptest.NewRecorder()
+	body := `[{"id": "a", "lat": 42.4, "lon": 77.2}, {"id": "b", "lat": 100, "lon": 0}]`
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/reverse/batch", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, w.Code)
+	var items []reverseBatchResult
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var item reverseBatchResult
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &item))
+		items = append(items, item)
+	}
+	require.Len(t, items, 2)
+	assert.Equal(t, "a", items[0].ID)
+	assert.Nil(t, items[0].Error)
+	assert.Len(t, items[0].Results, 1, "natural feature is returned without addresses around")
+	assert.Equal(t, "b", items[1].ID)
+	require.NotNil(t, items[1].Error)
+	assert.Equal(t, "invalid_request", items[1].Error.Code)
+
+	w = httptest.NewRecorder()
+	body = `[{"lat": 1, "lon": 1}, {"lat": 2, "lon": 2}, {"lat": 3, "lon": 3}, {"lat": 4, "lon": 4}]`
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/reverse/batch", strings.NewReader(body)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "batch_too_large")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/reverse/batch", strings.NewReader("42,74")))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,100 @@
+package osm
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/geohash"
+	"github.com/maddevsio/ariadna/model"
+)
+
+const (
+	defaultReverseCacheSize = 100000
+	defaultReverseCacheTTL  = 10 * time.Minute
+	// maxReverseCachePrecision is geohash cell of about a meter
+	maxReverseCachePrecision = 10
+)
+
+// reverseCache keeps reverse geocoding results by geohash cell of point, so dense streams of
+// points like vehicles parked in one spot are answered without storage queries. Points of one
+// cell share result of the first of them. Keys include index version, new index starts empty
+type reverseCache struct {
+	precision int
+	cache     *addressCache
+	hits      int64
+	misses    int64
+}
+
+// cacheStats counts lookups of cache
+type cacheStats struct {
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+}
+
+// newReverseCache returns cache of config, nil when it is disabled
+func newReverseCache(c config.ReverseCache) *reverseCache {
+	if c.Precision <= 0 {
+		return nil
+	}
+	precision := c.Precision
+	if precision > maxReverseCachePrecision {
+		precision = maxReverseCachePrecision
+	}
+	size := c.Size
+	if size <= 0 {
+		size = defaultReverseCacheSize
+	}
+	ttl := c.TTL
+	if ttl <= 0 {
+		ttl = defaultReverseCacheTTL
+	}
+	return &reverseCache{precision: precision, cache: newAddressCache(size, ttl, 0)}
+}
+
+// reverseCacheKey returns key of point in reverse cache, empty when cache is disabled or index
+// version is unknown
+func (i *Importer) reverseCacheKey(ctx context.Context, lat, lon float64) string {
+	c := i.reverseCache
+	if c == nil {
+		return ""
+	}
+	version, err := i.indexVersion(ctx)
+	if err != nil {
+		i.logger.Debugf("reverse cache skipped, index version unknown: %v", err)
+		return ""
+	}
+	return version + "/" + geohash.Encode(lat, lon, c.precision)
+}
+
+// get returns cached addresses of key and accounts hit or miss
+func (c *reverseCache) get(key string) ([]model.Address, bool) {
+	if c == nil || key == "" {
+		return nil, false
+	}
+	addresses, ok := c.cache.get(key)
+	if ok {
+		atomic.AddInt64(&c.hits, 1)
+	} else {
+		atomic.AddInt64(&c.misses, 1)
+	}
+	return addresses, ok
+}
+
+// put caches copy of addresses, callers go on changing their results
+func (c *reverseCache) put(key string, addresses []model.Address) {
+	if c == nil || key == "" {
+		return
+	}
+	c.cache.put(key, append([]model.Address(nil), addresses...))
+}
+
+func (c *reverseCache) stats() cacheStats {
+	s := cacheStats{Hits: atomic.LoadInt64(&c.hits), Misses: atomic.LoadInt64(&c.misses)}
+	if total := s.Hits + s.Misses; total > 0 {
+		s.HitRate = float64(s.Hits) / float64(total)
+	}
+	return s
+}
@@ -0,0 +1,80 @@
+package osm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingStorage finds the same address around every point and counts reverse queries
+type countingStorage struct {
+	memoryStorage
+	reverses int
+}
+
+func (s *countingStorage) Reverse(ctx context.Context, lat, lon float64) (*elastic.Result, error) {
+	s.reverses++
+	return &elastic.Result{Addresses: []model.Address{{Street: "Киевская", HouseNumber: "1", Location: model.Location{Lat: lat, Lon: lon}}}}, nil
+}
+
+func (s *countingStorage) ReverseBatch(ctx context.Context, points []model.Location) ([]*elastic.Result, error) {
+	results := make([]*elastic.Result, 0, len(points))
+	for _, p := range points {
+		result, _ := s.Reverse(ctx, p.Lat, p.Lon)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func TestReverseCache(t *testing.T) {
+	storage := &countingStorage{}
+	g, err := NewGeocoder(&config.Ariadna{API: config.API{ReverseCache: config.ReverseCache{Precision: 7}}}, WithStorage(storage))
+	require.NoError(t, err)
+	i := g.i
+	i.metrics = newQueryMetrics(0, true)
+	router := httprouter.New()
+	router.GET("/api/reverse/:lat/:lon", i.reverseGeoCodeHandler)
+	router.POST("/api/reverse/batch", i.batchReverseHandler)
+	router.GET("/api/status/queries", i.queryMetricsHandler)
+
+	for _, path := range []string{"/api/reverse/42.87401/74.59001", "/api/reverse/42.87402/74.59002", "/api/reverse/42.9/74.7"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "Киевская")
+	}
+	assert.Equal(t, 2, storage.reverses, "points of one geohash cell share result")
+
+	w := httptest.NewRecorder()
+	body := `[{"lat": 42.87403, "lon": 74.59003}, {"lat": 42.95, "lon": 74.75}]`
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/reverse/batch", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, strings.Count(w.Body.String(), "Киевская"))
+	assert.Equal(t, 3, storage.reverses, "cached point is not sent in batch")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/status/queries", nil))
+	var metrics queryMetricsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &metrics))
+	stats := metrics.Caches[endpointReverse]
+	assert.Equal(t, int64(2), stats.Hits)
+	assert.Equal(t, int64(3), stats.Misses)
+	assert.InDelta(t, 0.4, stats.HitRate, 1e-9)
+
+	uncached, err := NewGeocoder(&config.Ariadna{}, WithStorage(storage))
+	require.NoError(t, err)
+	assert.Nil(t, uncached.i.reverseCache)
+	_, err = uncached.Reverse(context.Background(), 42.87401, 74.59001)
+	require.NoError(t, err)
+	assert.Equal(t, 4, storage.reverses)
+}
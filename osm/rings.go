@@ -0,0 +1,339 @@
+package osm
+
+import (
+	"math"
+	"sort"
+
+	"github.com/maddevsio/ariadna/geodesic"
+	"github.com/missinglink/gosmparse"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+const (
+	// hullConcavity is ratio of hull edge length to distance of point digging it in,
+	// lower values follow the points closer
+	hullConcavity = 2.0
+	// maxHullPoints limits points hull is built of, member nodes are sampled evenly above it
+	maxHullPoints = 2000
+)
+
+// assembleRings joins ways into closed rings by their end nodes. Chains of ways which
+// don't close into a ring are returned as broken
+func assembleRings(ways [][]int64) (rings, broken [][]int64) {
+	left := make([][]int64, 0, len(ways))
+	for _, way := range ways {
+		if len(way) < 2 {
+			continue
+		}
+		left = append(left, way)
+	}
+	for len(left) > 0 {
+		ring := append([]int64(nil), left[0]...)
+		left = left[1:]
+		for ring[0] != ring[len(ring)-1] {
+			joined := false
+			for n, way := range left {
+				end := ring[len(ring)-1]
+				switch end {
+				case way[0]:
+					ring = append(ring, way[1:]...)
+				case way[len(way)-1]:
+					for k := len(way) - 2; k >= 0; k-- {
+						ring = append(ring, way[k])
+					}
+				default:
+					continue
+				}
+				left = append(left[:n], left[n+1:]...)
+				joined = true
+				break
+			}
+			if !joined {
+				break
+			}
+		}
+		if ring[0] != ring[len(ring)-1] || len(ring) < 4 {
+			broken = append(broken, ring)
+			continue
+		}
+		rings = append(rings, ring)
+	}
+	return rings, broken
+}
+
+// boundaryPolygons assembles outer and inner ways of boundary relation into polygons.
+// Outer ways which don't close into rings get concave hull of their nodes alongside
+// assembled rings, approximate is true then
+func (i *Importer) boundaryPolygons(relation gosmparse.Relation) (polygons [][][][]float64, approximate bool) {
+	outer, inner := i.relationWays(relation)
+	rings, broken := assembleRings(outer)
+	polygons, missing := i.ringPolygons(rings)
+	if broken = append(broken, missing...); len(broken) > 0 {
+		approximate = true
+		var points [][]float64
+		for _, chain := range broken {
+			points = append(points, i.nodeCoords(chain)...)
+		}
+		if hull := concaveHull(points); hull != nil {
+			polygons = append(polygons, [][][]float64{hull})
+		}
+	}
+	return i.addHoles(polygons, inner), approximate
+}
+
+// relationWays returns node ids of outer and inner way members of relation, members
+// without role are outer
+func (i *Importer) relationWays(relation gosmparse.Relation) (outer, inner [][]int64) {
+	for _, member := range relation.Members {
+		if member.Type != gosmparse.WayType {
+			continue
+		}
+		way, ok := i.handler.FullWays[member.ID]
+		if !ok {
+			continue
+		}
+		if member.Role == "inner" {
+			inner = append(inner, way.NodeIDs)
+			continue
+		}
+		outer = append(outer, way.NodeIDs)
+	}
+	return outer, inner
+}
+
+// ringPolygons makes polygon of every ring, rings missing nodes are returned apart
+func (i *Importer) ringPolygons(rings [][]int64) (polygons [][][][]float64, missing [][]int64) {
+	for _, ring := range rings {
+		coords := i.nodeCoords(ring)
+		if len(coords) < 4 {
+			missing = append(missing, ring)
+			continue
+		}
+		polygons = append(polygons, [][][]float64{coords})
+	}
+	return polygons, missing
+}
+
+// addHoles assembles inner ways into rings and adds them to polygons containing them.
+// Holes which don't close are dropped, polygon stays usable without them
+func (i *Importer) addHoles(polygons [][][][]float64, inner [][]int64) [][][][]float64 {
+	innerRings, _ := assembleRings(inner)
+	for _, ring := range innerRings {
+		coords := i.nodeCoords(ring)
+		if len(coords) < 4 {
+			continue
+		}
+		for n, polygon := range polygons {
+			if ringContains(polygon[0], coords[0]) {
+				polygons[n] = append(polygons[n], coords)
+				break
+			}
+		}
+	}
+	return polygons
+}
+
+// boundaryGeometry returns multipolygon of boundary relation, see boundaryPolygons
+func (i *Importer) boundaryGeometry(relation gosmparse.Relation) (*geojson.Geometry, bool) {
+	polygons, approximate := i.boundaryPolygons(relation)
+	if len(polygons) == 0 {
+		return nil, approximate
+	}
+	return geojson.NewMultiPolygonGeometry(polygons...), approximate
+}
+
+func (i *Importer) nodeCoords(nodeIDs []int64) [][]float64 {
+	coords := make([][]float64, 0, len(nodeIDs))
+	for _, nodeID := range nodeIDs {
+		node, ok := i.handler.Node(nodeID)
+		if !ok {
+			continue
+		}
+		coords = append(coords, []float64{node.Lon, node.Lat})
+	}
+	return coords
+}
+
+// ringContains reports whether point lies inside of ring by ray casting
+func ringContains(ring [][]float64, point []float64) bool {
+	inside := false
+	for n, m := 0, len(ring)-1; n < len(ring); m, n = n, n+1 {
+		a, b := ring[n], ring[m]
+		if (a[1] > point[1]) != (b[1] > point[1]) && point[0] < (b[0]-a[0])*(point[1]-a[1])/(b[1]-a[1])+a[0] {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// areaShape is multipolygon of area prepared for point in polygon tests
+type areaShape struct {
+	// polygons are outer rings followed by their holes
+	polygons [][]*geodesic.Polygon
+	// largest is outer ring of the largest polygon
+	largest *geodesic.Polygon
+}
+
+func newAreaShape(polygons [][][][]float64) *areaShape {
+	var (
+		b    = &areaShape{polygons: make([][]*geodesic.Polygon, 0, len(polygons))}
+		best [][]float64
+		max  float64
+	)
+	for _, polygon := range polygons {
+		rings := make([]*geodesic.Polygon, 0, len(polygon))
+		for _, ring := range polygon {
+			rings = append(rings, ringToPolygon(ring))
+		}
+		b.polygons = append(b.polygons, rings)
+		if area := geodesic.Area(polygon[0]); area > max {
+			best, max = polygon[0], area
+		}
+	}
+	b.largest = ringToPolygon(best)
+	return b
+}
+
+// Contains reports whether point is inside of outer ring of any polygon and out of its holes
+func (b *areaShape) Contains(point geodesic.Point) bool {
+	for _, rings := range b.polygons {
+		if !rings[0].Contains(point) {
+			continue
+		}
+		inHole := false
+		for _, hole := range rings[1:] {
+			if hole.Contains(point) {
+				inHole = true
+				break
+			}
+		}
+		if !inHole {
+			return true
+		}
+	}
+	return false
+}
+
+// concaveHull returns closed ring enclosing points which follows their outline closer
+// than convex hull: edges of convex hull are dug in towards the nearest points while
+// the ring doesn't cross itself. Nil is returned for less than three distinct points
+func concaveHull(points [][]float64) [][]float64 {
+	if len(points) == 0 {
+		return nil
+	}
+	// equirectangular projection around the first point keeps edge lengths comparable
+	scale := math.Cos(radians(points[0][1]))
+	seen := make(map[[2]float64]bool, len(points))
+	var projected [][2]float64
+	for _, p := range points {
+		key := [2]float64{p[0] * scale, p[1]}
+		if !seen[key] {
+			seen[key] = true
+			projected = append(projected, key)
+		}
+	}
+	if step := len(projected) / maxHullPoints; step > 1 {
+		sampled := make([][2]float64, 0, maxHullPoints+1)
+		for n := 0; n < len(projected); n += step {
+			sampled = append(sampled, projected[n])
+		}
+		projected = sampled
+	}
+	hull := convexHull(projected)
+	if len(hull) < 3 {
+		return nil
+	}
+	inHull := make(map[int]bool, len(hull))
+	for _, n := range hull {
+		inHull[n] = true
+	}
+	for k := 0; k < len(hull); {
+		a, b := projected[hull[k]], projected[hull[(k+1)%len(hull)]]
+		length := distance(a, b)
+		candidate, nearest := -1, math.Inf(1)
+		for n, p := range projected {
+			if inHull[n] {
+				continue
+			}
+			px, py := projectToSegment(a[0]-p[0], a[1]-p[1], b[0]-p[0], b[1]-p[1])
+			if d := math.Hypot(px, py); d < nearest {
+				candidate, nearest = n, d
+			}
+		}
+		if candidate >= 0 {
+			p := projected[candidate]
+			dig := math.Min(distance(a, p), distance(p, b))
+			if dig > 0 && length/dig > hullConcavity && !crossesHull(projected, hull, k, p) {
+				hull = append(hull[:k+1], append([]int{candidate}, hull[k+1:]...)...)
+				inHull[candidate] = true
+				// the new edge from a is checked again
+				continue
+			}
+		}
+		k++
+	}
+	ring := make([][]float64, 0, len(hull)+1)
+	for _, n := range hull {
+		ring = append(ring, []float64{projected[n][0] / scale, projected[n][1]})
+	}
+	return append(ring, ring[0])
+}
+
+// convexHull returns indices of convex hull vertices in counterclockwise order
+func convexHull(points [][2]float64) []int {
+	order := make([]int, len(points))
+	for n := range order {
+		order[n] = n
+	}
+	sort.Slice(order, func(a, b int) bool {
+		pa, pb := points[order[a]], points[order[b]]
+		return pa[0] < pb[0] || (pa[0] == pb[0] && pa[1] < pb[1])
+	})
+	cross := func(o, a, b [2]float64) float64 {
+		return (a[0]-o[0])*(b[1]-o[1]) - (a[1]-o[1])*(b[0]-o[0])
+	}
+	hull := make([]int, 0, 2*len(order))
+	for _, n := range order {
+		for len(hull) >= 2 && cross(points[hull[len(hull)-2]], points[hull[len(hull)-1]], points[n]) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, n)
+	}
+	lower := len(hull) + 1
+	for k := len(order) - 2; k >= 0; k-- {
+		n := order[k]
+		for len(hull) >= lower && cross(points[hull[len(hull)-2]], points[hull[len(hull)-1]], points[n]) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, n)
+	}
+	return hull[:len(hull)-1]
+}
+
+// crossesHull reports whether edges from the ends of hull edge k to p cross other hull edges
+func crossesHull(points [][2]float64, hull []int, k int, p [2]float64) bool {
+	a, b := points[hull[k]], points[hull[(k+1)%len(hull)]]
+	for n := range hull {
+		if n == k {
+			continue
+		}
+		c, d := points[hull[n]], points[hull[(n+1)%len(hull)]]
+		if (c != a && d != a && segmentsCross(a, p, c, d)) || (c != b && d != b && segmentsCross(p, b, c, d)) {
+			return true
+		}
+	}
+	return false
+}
+
+func segmentsCross(a, b, c, d [2]float64) bool {
+	side := func(o, p, q [2]float64) float64 {
+		return (p[0]-o[0])*(q[1]-o[1]) - (p[1]-o[1])*(q[0]-o[0])
+	}
+	d1, d2 := side(c, d, a), side(c, d, b)
+	d3, d4 := side(a, b, c), side(a, b, d)
+	return ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0))
+}
+
+func distance(a, b [2]float64) float64 {
+	return math.Hypot(a[0]-b[0], a[1]-b[1])
+}
@@ -0,0 +1,149 @@
+package osm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/geodesic"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/maddevsio/ariadna/osm/osmtest"
+	"github.com/missinglink/gosmparse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssembleRings(t *testing.T) {
+	rings, broken := assembleRings([][]int64{{1, 2, 3}, {5, 4, 3}, {5, 6, 1}, {10, 11, 12, 10}})
+	assert.Empty(t, broken)
+	assert.Equal(t, [][]int64{{1, 2, 3, 4, 5, 6, 1}, {10, 11, 12, 10}}, rings)
+
+	rings, broken = assembleRings([][]int64{{1, 2, 3}, {3, 4}, {10, 11, 12, 10}})
+	assert.Equal(t, [][]int64{{1, 2, 3, 4}}, broken)
+	assert.Equal(t, [][]int64{{10, 11, 12, 10}}, rings)
+}
+
+func TestConcaveHull(t *testing.T) {
+	// points of L shape, convex hull would cover the empty upper right quarter
+	var points [][]float64
+	for x := 0.0; x <= 2; x += 0.25 {
+		for y := 0.0; y <= 2; y += 0.25 {
+			if x <= 1 || y <= 1 {
+				points = append(points, []float64{x, y})
+			}
+		}
+	}
+	hull := concaveHull(points)
+	require.NotNil(t, hull)
+	assert.Equal(t, hull[0], hull[len(hull)-1])
+	assert.False(t, ringContains(hull, []float64{1.75, 1.75}), "empty corner is dug out")
+	assert.True(t, ringContains(hull, []float64{0.5, 1.5}))
+	assert.True(t, ringContains(hull, []float64{1.5, 0.5}))
+
+	assert.Nil(t, concaveHull([][]float64{{0, 0}, {1, 1}, {0, 0}}))
+}
+
+func TestBoundaryOfOpenWays(t *testing.T) {
+	data := osmtest.New().
+		Node(1, 42, 74).Node(2, 42, 75).Node(3, 43, 75).Node(4, 43, 74).
+		Way(10, []int64{1, 2, 3}).
+		Way(11, []int64{1, 4, 3}).
+		Relation(100, []gosmparse.RelationMember{osmtest.Way(10, "outer"), osmtest.Way(11, "outer")},
+			"type", "boundary", "admin_level", "2", "name", "Кыргызстан").
+		Node(5, 40, 70).Node(6, 40, 71).Node(7, 41, 71).Node(8, 40.5, 70.5).
+		Way(12, []int64{5, 6, 7}).
+		Way(13, []int64{8, 5}).
+		Relation(101, []gosmparse.RelationMember{osmtest.Way(12, "outer"), osmtest.Way(13, "outer")},
+			"type", "boundary", "admin_level", "2", "name", "Тоджикистон")
+	storage := &memoryStorage{docs: make(map[string]model.Address)}
+	ctx := context.Background()
+	i, err := NewImporter(ctx, &config.Ariadna{}, WithParser(data), WithStorage(storage))
+	require.NoError(t, err)
+	require.NoError(t, i.Start(ctx))
+	require.NoError(t, i.WaitStop())
+
+	require.Contains(t, storage.docs, "relation/100")
+	assembled := storage.docs["relation/100"]
+	assert.False(t, assembled.Approximate)
+	require.True(t, assembled.Geometry.IsMultiPolygon())
+	assert.Len(t, assembled.Geometry.MultiPolygon[0][0], 5)
+
+	require.Contains(t, storage.docs, "relation/101")
+	broken := storage.docs["relation/101"]
+	assert.True(t, broken.Approximate)
+	require.True(t, broken.Geometry.IsMultiPolygon())
+	assert.True(t, ringContains(broken.Geometry.MultiPolygon[0][0], []float64{70.7, 40.3}))
+}
+
+func TestBoundaryOfBrokenWay(t *testing.T) {
+	data := osmtest.New().
+		// closed mainland ring and exclave which way doesn't close
+		Node(1, 42, 74).Node(2, 42, 75).Node(3, 43, 75).Node(4, 43, 74).
+		Way(10, []int64{1, 2, 3, 4, 1}).
+		Node(5, 40, 70).Node(6, 40, 71).Node(7, 41, 71).Node(8, 41, 70).
+		Way(11, []int64{5, 6, 7, 8}).
+		Relation(100, []gosmparse.RelationMember{osmtest.Way(10, "outer"), osmtest.Way(11, "outer")},
+			"type", "boundary", "admin_level", "2", "name", "Кыргызстан")
+	ctx := context.Background()
+	i, err := NewImporter(ctx, &config.Ariadna{}, WithParser(data), WithStorage(&memoryStorage{docs: make(map[string]model.Address)}))
+	require.NoError(t, err)
+	require.NoError(t, i.Start(ctx))
+	require.NoError(t, i.WaitStop())
+
+	relation := i.handler.Countries[100]
+	polygons, approximate := i.boundaryPolygons(relation)
+	assert.True(t, approximate)
+	require.Len(t, polygons, 2, "closed ring is kept beside hull of broken way")
+	assert.Len(t, polygons[0][0], 5)
+	assert.True(t, ringContains(polygons[1][0], []float64{70.5, 40.5}))
+
+	shape := i.relationShape(relation)
+	assert.True(t, shape.Contains(geodesic.Point{Lat: 42.5, Lon: 74.5}))
+	assert.True(t, shape.Contains(geodesic.Point{Lat: 40.5, Lon: 70.5}), "smaller polygon is checked too")
+	assert.False(t, shape.Contains(geodesic.Point{Lat: 41.5, Lon: 72}))
+}
+
+func TestAreaShapeHoles(t *testing.T) {
+	square := func(min, max float64) [][]float64 {
+		return [][]float64{{min, min}, {max, min}, {max, max}, {min, max}, {min, min}}
+	}
+	shape := newAreaShape([][][][]float64{{square(0, 10), square(4, 6)}, {square(20, 21)}})
+	assert.True(t, shape.Contains(geodesic.Point{Lat: 2, Lon: 2}))
+	assert.False(t, shape.Contains(geodesic.Point{Lat: 5, Lon: 5}), "point in hole")
+	assert.True(t, shape.Contains(geodesic.Point{Lat: 20.5, Lon: 20.5}))
+	assert.Len(t, shape.largest.Points(), 5)
+}
+
+func TestNaturalMultipolygon(t *testing.T) {
+	data := osmtest.New().
+		Node(1, 42, 77).Node(2, 42, 78).Node(3, 43, 78).Node(4, 43, 77).
+		Way(10, []int64{1, 2, 3}).
+		Way(11, []int64{3, 4, 1}).
+		Node(5, 42.4, 77.4).Node(6, 42.4, 77.6).Node(7, 42.6, 77.6).Node(8, 42.6, 77.4).
+		Way(12, []int64{5, 6, 7, 8, 5}).
+		Relation(100, []gosmparse.RelationMember{osmtest.Way(10, "outer"), osmtest.Way(11, "outer"), osmtest.Way(12, "inner")},
+			"type", "multipolygon", "natural", "water", "name", "Иссык-Куль").
+		Node(20, 41, 75).Node(21, 41.1, 75.2).Node(22, 41.2, 75.4).
+		Way(30, []int64{20, 21}).
+		Way(31, []int64{21, 22}).
+		Relation(101, []gosmparse.RelationMember{osmtest.Way(30, "main_stream"), osmtest.Way(31, "main_stream")},
+			"type", "waterway", "waterway", "river", "name", "Нарын")
+	storage := &memoryStorage{docs: make(map[string]model.Address)}
+	ctx := context.Background()
+	i, err := NewImporter(ctx, &config.Ariadna{}, WithParser(data), WithStorage(storage))
+	require.NoError(t, err)
+	require.NoError(t, i.Start(ctx))
+	require.NoError(t, i.WaitStop())
+
+	require.Contains(t, storage.docs, "relation/100")
+	lake := storage.docs["relation/100"].Geometry
+	require.True(t, lake.IsMultiPolygon())
+	require.Len(t, lake.MultiPolygon, 1)
+	require.Len(t, lake.MultiPolygon[0], 2, "island is a hole")
+	assert.Len(t, lake.MultiPolygon[0][0], 5)
+
+	require.Contains(t, storage.docs, "relation/101")
+	river := storage.docs["relation/101"].Geometry
+	require.True(t, river.IsMultiLineString())
+	assert.Len(t, river.MultiLineString, 2)
+}
@@ -0,0 +1,205 @@
+package osm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/maddevsio/ariadna/elastic"
+	"github.com/maddevsio/ariadna/model"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+const (
+	layerRoad = "road"
+	// snapRadius is max distance in meters from point to road it is snapped to
+	snapRadius    = 100.0
+	earthRadiusM  = 6371000.0
+	snapRoadParam = "road"
+	kmhPerMph     = 1.609344
+)
+
+// implicitSpeeds are km/h of zone maxspeed values like RU:urban, same in countries of presets
+var implicitSpeeds = map[string]int{"urban": 60, "rural": 90, "motorway": 110, "living_street": 20}
+
+func (i *Importer) roadsToElastic(ctx context.Context) error {
+	i.logger.Info("started to search roads")
+	buf, err := i.getRoads()
+	if err != nil {
+		return err
+	}
+	i.logger.Info("roads found")
+	return i.bulkWrite(ctx, buf)
+}
+
+// getRoads indexes named roads with their line strings for snapping
+func (i *Importer) getRoads() (bytes.Buffer, error) {
+	var buf bytes.Buffer
+	for wayID, way := range i.handler.Roads {
+		if _, ok := i.handler.NaturalWays[wayID]; ok {
+			continue
+		}
+		// named roundabouts are indexed as junctions
+		if _, ok := i.handler.Roundabouts[wayID]; ok {
+			continue
+		}
+		coords := i.wayCoords(way)
+		if len(coords) < 2 {
+			continue
+		}
+		middle := coords[len(coords)/2]
+		if !i.inClip(middle[1], middle[0]) {
+			continue
+		}
+		address := model.Address{
+			Name:     way.Tags["name"],
+			Names:    nameVariants(way.Tags),
+			Street:   way.Tags["name"],
+			Layer:    layerRoad,
+			Category: way.Tags["highway"],
+			Location: model.Location{Lat: middle[1], Lon: middle[0]},
+			Geometry: geojson.NewLineStringGeometry(coords),
+			Fields:   i.extractFields(way.Tags),
+			Road:     roadAttributes(way.Tags),
+			Vertical: verticalOf(way.Tags),
+		}
+		i.locate(&address)
+		if err := i.writeDocument(&buf, fmt.Sprintf("way/%d", wayID), address); err != nil {
+			return buf, err
+		}
+	}
+	return buf, nil
+}
+
+// snapToRoad returns the nearest named road within snap radius located at the point
+// of the road closest to given one
+func (i *Importer) snapToRoad(ctx context.Context, lat, lon float64) (*elastic.Result, error) {
+	result, err := i.e.Intersecting(ctx, layerRoad, lat, lon, snapRadius)
+	if err != nil {
+		return nil, err
+	}
+	best := -1
+	var (
+		bestDistance = math.Inf(1)
+		bestPoint    model.Location
+	)
+	for n, road := range result.Addresses {
+		point, distance, ok := nearestPoint(road.Geometry, lat, lon)
+		if ok && distance < bestDistance {
+			best, bestDistance, bestPoint = n, distance, point
+		}
+	}
+	if best < 0 || bestDistance > snapRadius {
+		return &elastic.Result{Addresses: []model.Address{}, TimedOut: result.TimedOut}, nil
+	}
+	road := result.Addresses[best]
+	road.Location = bestPoint
+	road.Geometry = nil
+	return &elastic.Result{Addresses: []model.Address{road}, TimedOut: result.TimedOut}, nil
+}
+
+// nearestPoint returns perpendicular projection of point to the closest segment of line
+// and distance to it in meters
+func nearestPoint(g *geojson.Geometry, lat, lon float64) (model.Location, float64, bool) {
+	if g == nil {
+		return model.Location{}, 0, false
+	}
+	var lines [][][]float64
+	switch {
+	case g.IsLineString():
+		lines = [][][]float64{g.LineString}
+	case g.IsMultiLineString():
+		lines = g.MultiLineString
+	default:
+		return model.Location{}, 0, false
+	}
+	// equirectangular projection around the point is precise enough within snap radius
+	scale := math.Cos(lat * math.Pi / 180)
+	toXY := func(c []float64) (float64, float64) {
+		return (c[0] - lon) * scale, c[1] - lat
+	}
+	found := false
+	best := math.Inf(1)
+	var bx, by float64
+	for _, line := range lines {
+		for n := 1; n < len(line); n++ {
+			if len(line[n-1]) < 2 || len(line[n]) < 2 {
+				continue
+			}
+			ax, ay := toXY(line[n-1])
+			cx, cy := toXY(line[n])
+			px, py := projectToSegment(ax, ay, cx, cy)
+			if d := px*px + py*py; d < best {
+				best, bx, by, found = d, px, py, true
+			}
+		}
+	}
+	if !found {
+		return model.Location{}, 0, false
+	}
+	point := model.Location{Lat: lat + by, Lon: lon + bx/scale}
+	return point, math.Sqrt(best) * math.Pi / 180 * earthRadiusM, true
+}
+
+// projectToSegment returns point of segment a-c closest to origin
+func projectToSegment(ax, ay, cx, cy float64) (float64, float64) {
+	dx, dy := cx-ax, cy-ay
+	length := dx*dx + dy*dy
+	if length == 0 {
+		return ax, ay
+	}
+	t := -(ax*dx + ay*dy) / length
+	t = math.Max(0, math.Min(1, t))
+	return ax + t*dx, ay + t*dy
+}
+
+// roadAttributes parses speed limit, surface, lanes and direction of highway
+func roadAttributes(tags map[string]string) *model.Road {
+	road := &model.Road{
+		MaxSpeed: parseMaxSpeed(tags["maxspeed"]),
+		Surface:  tags["surface"],
+	}
+	if lanes, err := strconv.Atoi(tags["lanes"]); err == nil && lanes > 0 {
+		road.Lanes = lanes
+	}
+	switch tags["oneway"] {
+	case "yes", "true", "1":
+		road.Oneway = "forward"
+	case "-1", "reverse":
+		road.Oneway = "backward"
+	case "no", "false", "0":
+	default:
+		// roundabouts and motorways are one way unless tagged otherwise
+		if tags["junction"] == "roundabout" || tags["highway"] == "motorway" {
+			road.Oneway = "forward"
+		}
+	}
+	if *road == (model.Road{}) {
+		return nil
+	}
+	return road
+}
+
+// parseMaxSpeed converts maxspeed tag to km/h: "60", "30 mph" or zone like "RU:urban".
+// Unknown values give zero
+func parseMaxSpeed(value string) int {
+	value = strings.TrimSpace(value)
+	if n := strings.Index(value, ":"); n >= 0 {
+		return implicitSpeeds[value[n+1:]]
+	}
+	if strings.HasSuffix(value, "mph") {
+		mph, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(value, "mph")), 64)
+		if err != nil {
+			return 0
+		}
+		return int(math.Round(mph * kmhPerMph))
+	}
+	kmh, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(value, "km/h")), 64)
+	if err != nil {
+		return 0
+	}
+	return int(math.Round(kmh))
+}
@@ -0,0 +1,68 @@
+package osm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/maddevsio/ariadna/osm/handler"
+	"github.com/maddevsio/ariadna/osm/osmtest"
+	"github.com/missinglink/gosmparse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapToRoad(t *testing.T) {
+	data := osmtest.New().
+		Node(1, 42.870, 74.590).
+		Node(2, 42.870, 74.600).
+		Node(3, 42.880, 74.600).
+		Way(10, []int64{1, 2, 3}, "highway", "primary", "name", "Чуй проспект", "maxspeed", "60").
+		Node(4, 42.871, 74.590).
+		Node(5, 42.871, 74.600).
+		Way(11, []int64{4, 5}, "highway", "service")
+	storage := &memoryStorage{docs: make(map[string]model.Address)}
+	ctx := context.Background()
+	i, err := NewImporter(ctx, &config.Ariadna{}, WithParser(data), WithStorage(storage))
+	require.NoError(t, err)
+	require.NoError(t, i.Start(ctx))
+	require.NoError(t, i.WaitStop())
+	require.Contains(t, storage.docs, "way/10")
+	assert.NotContains(t, storage.docs, "way/11", "unnamed roads are not indexed")
+
+	result, err := i.Geocoder().SnapToRoad(ctx, 42.8703, 74.595)
+	require.NoError(t, err)
+	require.Len(t, result.Addresses, 1)
+	road := result.Addresses[0]
+	assert.Equal(t, "Чуй проспект", road.Name)
+	assert.InDelta(t, 42.870, road.Location.Lat, 1e-6)
+	assert.InDelta(t, 74.595, road.Location.Lon, 1e-6)
+	assert.Nil(t, road.Geometry)
+	require.NotNil(t, road.Road)
+	assert.Equal(t, 60, road.Road.MaxSpeed)
+
+	_, err = i.Geocoder().SnapToRoad(ctx, 42.9, 74.595)
+	assert.Error(t, err, "road is too far")
+}
+
+func TestRoadAttributes(t *testing.T) {
+	assert.Nil(t, roadAttributes(map[string]string{"highway": "residential"}))
+	assert.Equal(t, &model.Road{MaxSpeed: 60, Surface: "asphalt", Lanes: 4, Oneway: "forward"},
+		roadAttributes(map[string]string{"maxspeed": "60", "surface": "asphalt", "lanes": "4", "oneway": "yes"}))
+	assert.Equal(t, &model.Road{MaxSpeed: 48, Oneway: "backward"}, roadAttributes(map[string]string{"maxspeed": "30 mph", "oneway": "-1"}))
+	assert.Equal(t, &model.Road{MaxSpeed: 90, Oneway: "forward"}, roadAttributes(map[string]string{"maxspeed": "KG:rural", "junction": "roundabout"}))
+	assert.Nil(t, roadAttributes(map[string]string{"maxspeed": "signals", "lanes": "two", "highway": "motorway", "oneway": "no"}))
+}
+
+func TestRoadAttributesKeptTags(t *testing.T) {
+	h := handler.New()
+	h.KeepTags("cuisine")
+	h.ReadWay(gosmparse.Way{ID: 10, NodeIDs: []int64{1, 2}, Tags: map[string]string{
+		"highway": "residential", "name": "Киевская", "maxspeed": "40", "surface": "asphalt",
+		"lanes": "2", "oneway": "yes", "smoothness": "good",
+	}})
+	require.Contains(t, h.Roads, int64(10))
+	assert.Equal(t, &model.Road{MaxSpeed: 40, Surface: "asphalt", Lanes: 2, Oneway: "forward"}, roadAttributes(h.Roads[10].Tags))
+	assert.NotContains(t, h.Roads[10].Tags, "smoothness")
+}
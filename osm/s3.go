@@ -0,0 +1,111 @@
+package osm
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/maddevsio/ariadna/config"
+)
+
+const (
+	defaultS3Region = "us-east-1"
+	// unsignedPayload is accepted by S3 as content hash of requests without body
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	amzDateFormat   = "20060102T150405Z"
+)
+
+// s3Request builds GET request of object key in bucket, signed with AWS Signature Version 4
+// when credentials are known
+func s3Request(c config.S3, bucket, key string, now time.Time) (*http.Request, error) {
+	c = s3Credentials(c)
+	u := &url.URL{Scheme: "https", Host: bucket + ".s3." + c.Region + ".amazonaws.com", Path: key}
+	if c.Endpoint != "" {
+		endpoint, err := url.Parse(c.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+		u = &url.URL{Scheme: endpoint.Scheme, Host: endpoint.Host, Path: "/" + bucket + key}
+	}
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.AccessKey == "" || c.SecretKey == "" {
+		return req, nil
+	}
+	signS3(req, c, now)
+	return req, nil
+}
+
+// s3Credentials fills missing region and credentials from environment
+func s3Credentials(c config.S3) config.S3 {
+	env := func(value *string, names ...string) {
+		for _, name := range names {
+			if *value == "" {
+				*value = os.Getenv(name)
+			}
+		}
+	}
+	env(&c.Region, "AWS_REGION", "AWS_DEFAULT_REGION")
+	if c.AccessKey == "" {
+		env(&c.AccessKey, "AWS_ACCESS_KEY_ID")
+		env(&c.SecretKey, "AWS_SECRET_ACCESS_KEY")
+		env(&c.SessionToken, "AWS_SESSION_TOKEN")
+	}
+	if c.Region == "" {
+		c.Region = defaultS3Region
+	}
+	return c
+}
+
+// signS3 adds Authorization header of AWS Signature Version 4 to bodiless request
+func signS3(req *http.Request, c config.S3, now time.Time) {
+	date := now.UTC().Format(amzDateFormat)
+	req.Header.Set("X-Amz-Date", date)
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+	headers := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	values := map[string]string{"host": req.URL.Host, "x-amz-content-sha256": unsignedPayload, "x-amz-date": date}
+	if c.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.SessionToken)
+		headers = append(headers, "x-amz-security-token")
+		values["x-amz-security-token"] = c.SessionToken
+	}
+	var canonicalHeaders strings.Builder
+	for _, name := range headers {
+		canonicalHeaders.WriteString(name + ":" + values[name] + "\n")
+	}
+	signedHeaders := strings.Join(headers, ";")
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		unsignedPayload,
+	}, "\n")
+	scope := date[:8] + "/" + c.Region + "/s3/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + date + "\n" + scope + "\n" + hexSHA256(canonical)
+	key := []byte("AWS4" + c.SecretKey)
+	for _, part := range []string{date[:8], c.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+c.AccessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+hex.EncodeToString(hmacSHA256(key, toSign)))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func hexSHA256(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,105 @@
+package osm
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultSignedURLTTL = 24 * time.Hour
+
+type signedKey struct{}
+
+// SignURL signs request URL with API key until expires, so the URL can be handed to clients
+// instead of the key. Query parameters can't be changed after signing
+func SignURL(method string, u *url.URL, key string, expires time.Time) {
+	q := u.Query()
+	q.Del("signature")
+	q.Set("expires", strconv.FormatInt(expires.Unix(), 10))
+	u.RawQuery = q.Encode()
+	q.Set("signature", hex.EncodeToString(urlSignature(method, u.Path, q, key)))
+	u.RawQuery = q.Encode()
+}
+
+// urlSignature is HMAC-SHA256 by key of method, path and sorted query parameters except signature
+func urlSignature(method, path string, q url.Values, key string) []byte {
+	params := make(url.Values, len(q))
+	for k, v := range q {
+		if k != "signature" {
+			params[k] = v
+		}
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(strings.ToUpper(method) + "\n" + path + "\n" + params.Encode()))
+	return mac.Sum(nil)
+}
+
+// withSignature authenticates requests with signature parameter by API key which signed URL.
+// Such requests are served as if they passed the key, invalid and expired URLs get 401
+func (i *Importer) withSignature(next http.Handler) http.Handler {
+	c := i.config.API.SignedURLs
+	if !c.Enabled {
+		return next
+	}
+	ttl := c.MaxTTL
+	if ttl <= 0 {
+		ttl = defaultSignedURLTTL
+	}
+	keys := i.signingKeys()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		s := q.Get("signature")
+		if s == "" || !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		expires, err := strconv.ParseInt(q.Get("expires"), 10, 64)
+		if err != nil {
+			i.writeFailure(w, r, http.StatusUnauthorized, BadRequest{Error: "signed URL requires expires as unix time", Code: "unauthorized"})
+			return
+		}
+		now, deadline := time.Now(), time.Unix(expires, 0)
+		if !deadline.After(now) {
+			i.writeFailure(w, r, http.StatusUnauthorized, BadRequest{Error: "signed URL has expired", Code: "expired"})
+			return
+		}
+		if deadline.After(now.Add(ttl)) {
+			i.writeFailure(w, r, http.StatusUnauthorized, BadRequest{Error: "signed URL expires later than allowed " + ttl.String(), Code: "unauthorized"})
+			return
+		}
+		signature, err := hex.DecodeString(s)
+		if err != nil {
+			i.writeFailure(w, r, http.StatusUnauthorized, BadRequest{Error: "invalid signature", Code: "unauthorized"})
+			return
+		}
+		for _, key := range keys {
+			if hmac.Equal(signature, urlSignature(r.Method, r.URL.Path, q, key)) {
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), signedKey{}, key)))
+				return
+			}
+		}
+		i.writeFailure(w, r, http.StatusUnauthorized, BadRequest{Error: "invalid signature", Code: "unauthorized"})
+	})
+}
+
+// signingKeys returns API keys URLs can be signed by
+func (i *Importer) signingKeys() []string {
+	c := i.config
+	seen := make(map[string]bool)
+	var keys []string
+	for _, list := range [][]string{c.API.SignedURLs.Keys, c.API.ExportKeys, c.API.DebugKeys, c.Geofences.Keys, c.Ranking.Experiment.Keys} {
+		for _, key := range list {
+			if key != "" && !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
@@ -0,0 +1,55 @@
+package osm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignedURL(t *testing.T) {
+	storage := &memoryStorage{docs: map[string]model.Address{
+		"node/1": {Name: "Неман", Category: "pharmacy", Location: model.Location{Lat: 42.87, Lon: 74.59}},
+	}}
+	conf := &config.Ariadna{API: config.API{
+		ExportKeys: []string{"analyst"},
+		SignedURLs: config.SignedURLs{Enabled: true, Keys: []string{"mobile"}, MaxTTL: time.Hour},
+	}}
+	g, err := NewGeocoder(conf, WithStorage(storage))
+	require.NoError(t, err)
+	router := httprouter.New()
+	router.GET("/api/search/:query", g.i.searchOrExportHandler)
+	handler := g.i.withSignature(router)
+	get := func(u *url.URL) int {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, u.String(), nil))
+		return w.Code
+	}
+	signed := func(key string, expires time.Time) *url.URL {
+		u := &url.URL{Path: "/api/search/export", RawQuery: "layer=poi"}
+		SignURL(http.MethodGet, u, key, expires)
+		return u
+	}
+
+	u := signed("analyst", time.Now().Add(time.Minute))
+	assert.NotContains(t, u.String(), "analyst")
+	assert.Equal(t, http.StatusOK, get(u))
+
+	assert.Equal(t, http.StatusForbidden, get(signed("mobile", time.Now().Add(time.Minute))), "signing key allows no feature")
+	assert.Equal(t, http.StatusUnauthorized, get(signed("stolen", time.Now().Add(time.Minute))))
+	assert.Equal(t, http.StatusUnauthorized, get(signed("analyst", time.Now().Add(-time.Minute))))
+	assert.Equal(t, http.StatusUnauthorized, get(signed("analyst", time.Now().Add(2*time.Hour))))
+
+	tampered := signed("analyst", time.Now().Add(time.Minute))
+	q := tampered.Query()
+	q.Set("layer", "address")
+	tampered.RawQuery = q.Encode()
+	assert.Equal(t, http.StatusUnauthorized, get(tampered))
+}
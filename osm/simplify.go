@@ -0,0 +1,93 @@
+package osm
+
+import (
+	"math"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// simplifyGeometry drops vertices of polygons deviating from simplified outline by less than
+// tolerance meters (Douglas-Peucker), so containment answers change only within tolerance.
+// Other geometries and non-positive tolerance return g unchanged
+func simplifyGeometry(g *geojson.Geometry, tolerance float64) *geojson.Geometry {
+	if g == nil || tolerance <= 0 {
+		return g
+	}
+	switch {
+	case g.IsPolygon():
+		return geojson.NewPolygonGeometry(simplifyPolygon(g.Polygon, tolerance))
+	case g.IsMultiPolygon():
+		polygons := make([][][][]float64, 0, len(g.MultiPolygon))
+		for _, polygon := range g.MultiPolygon {
+			polygons = append(polygons, simplifyPolygon(polygon, tolerance))
+		}
+		return geojson.NewMultiPolygonGeometry(polygons...)
+	}
+	return g
+}
+
+func simplifyPolygon(polygon [][][]float64, tolerance float64) [][][]float64 {
+	rings := make([][][]float64, 0, len(polygon))
+	for _, ring := range polygon {
+		rings = append(rings, simplifyRing(ring, tolerance))
+	}
+	return rings
+}
+
+// simplifyRing simplifies closed ring keeping its first vertex. Rings which would
+// collapse below a triangle are kept as is
+func simplifyRing(ring [][]float64, tolerance float64) [][]float64 {
+	if len(ring) <= 4 {
+		return ring
+	}
+	// equirectangular projection around the first vertex, tolerance is converted to degrees of latitude
+	scale := math.Cos(ring[0][1] * math.Pi / 180)
+	limit := tolerance / earthRadiusM * 180 / math.Pi
+	keep := make([]bool, len(ring))
+	keep[0], keep[len(ring)-1] = true, true
+	// the first and the last vertices of closed ring coincide, so ring is split at the farthest vertex
+	far, farthest := 0, -1.0
+	for n := 1; n < len(ring)-1; n++ {
+		dx, dy := (ring[n][0]-ring[0][0])*scale, ring[n][1]-ring[0][1]
+		if d := dx*dx + dy*dy; d > farthest {
+			far, farthest = n, d
+		}
+	}
+	keep[far] = true
+	douglasPeucker(ring, 0, far, scale, limit, keep)
+	douglasPeucker(ring, far, len(ring)-1, scale, limit, keep)
+	simplified := make([][]float64, 0, len(ring))
+	for n, c := range ring {
+		if keep[n] {
+			simplified = append(simplified, c)
+		}
+	}
+	if len(simplified) < 4 {
+		return ring
+	}
+	return simplified
+}
+
+// douglasPeucker marks vertices between first and last which deviate from segment
+// first-last by more than limit
+func douglasPeucker(coords [][]float64, first, last int, scale, limit float64, keep []bool) {
+	if last-first < 2 {
+		return
+	}
+	max, index := 0.0, 0
+	for n := first + 1; n < last; n++ {
+		// segment is moved so that vertex n is at origin
+		ax, ay := (coords[first][0]-coords[n][0])*scale, coords[first][1]-coords[n][1]
+		cx, cy := (coords[last][0]-coords[n][0])*scale, coords[last][1]-coords[n][1]
+		px, py := projectToSegment(ax, ay, cx, cy)
+		if d := math.Sqrt(px*px + py*py); d > max {
+			max, index = d, n
+		}
+	}
+	if max <= limit {
+		return
+	}
+	keep[index] = true
+	douglasPeucker(coords, first, index, scale, limit, keep)
+	douglasPeucker(coords, index, last, scale, limit, keep)
+}
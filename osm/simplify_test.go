@@ -0,0 +1,33 @@
+package osm
+
+import (
+	"testing"
+
+	geojson "github.com/paulmach/go.geojson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimplifyRing(t *testing.T) {
+	// square of ~1.1 km with a 1 m bump and a 100 m spike on its southern side
+	ring := [][]float64{
+		{74.00, 42.00}, {74.003, 42.00001}, {74.005, 42.00}, {74.007, 42.0009}, {74.01, 42.00},
+		{74.01, 42.01}, {74.00, 42.01}, {74.00, 42.00},
+	}
+	simplified := simplifyRing(ring, 10)
+	assert.Equal(t, [][]float64{
+		{74.00, 42.00}, {74.005, 42.00}, {74.007, 42.0009}, {74.01, 42.00}, {74.01, 42.01}, {74.00, 42.01}, {74.00, 42.00},
+	}, simplified, "the bump is dropped, the spike is kept")
+	assert.Equal(t, ring, simplifyRing(ring, 1000), "ring doesn't collapse below a triangle")
+	assert.Equal(t, ring, simplifyRing(ring, 0.5))
+}
+
+func TestSimplifyGeometry(t *testing.T) {
+	line := geojson.NewLineStringGeometry([][]float64{{74, 42}, {74.001, 42}, {74.002, 42}})
+	assert.Equal(t, line, simplifyGeometry(line, 10), "only polygons are simplified")
+
+	square := [][]float64{{74, 42}, {74.005, 42}, {74.01, 42}, {74.01, 42.01}, {74, 42.01}, {74, 42}}
+	g := simplifyGeometry(geojson.NewMultiPolygonGeometry([][][]float64{square}), 10)
+	require.True(t, g.IsMultiPolygon())
+	assert.Len(t, g.MultiPolygon[0][0], 5)
+}
@@ -0,0 +1,72 @@
+package osm
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+type (
+	// StageError is failure of a single import stage
+	StageError struct {
+		Stage string
+		Err   error
+	}
+	// PartialError lists failed stages of import, data of other stages is indexed
+	PartialError struct {
+		Failed []*StageError
+	}
+	// stage is named step of import run concurrently with others, building documents of layers
+	stage struct {
+		name   string
+		run    func(ctx context.Context) error
+		layers []string
+	}
+	// failures collects errors of stages which don't stop import
+	failures struct {
+		mu     sync.Mutex
+		failed []*StageError
+	}
+)
+
+func (e *StageError) Error() string {
+	return e.Stage + ": " + e.Err.Error()
+}
+
+// Unwrap returns error of stage
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+func (e *PartialError) Error() string {
+	msgs := make([]string, 0, len(e.Failed))
+	for _, f := range e.Failed {
+		msgs = append(msgs, f.Error())
+	}
+	return "import partially failed: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap returns errors of failed stages, so errors.Is matches any of them
+func (e *PartialError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failed))
+	for _, f := range e.Failed {
+		errs = append(errs, f)
+	}
+	return errs
+}
+
+func (f *failures) add(stage string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failed = append(f.failed, &StageError{Stage: stage, Err: err})
+}
+
+// err returns PartialError when any stage failed
+func (f *failures) err() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.failed) == 0 {
+		return nil
+	}
+	return &PartialError{Failed: append([]*StageError(nil), f.failed...)}
+}
@@ -0,0 +1,62 @@
+package osm
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const ndjsonMediaType = "application/x-ndjson"
+
+// ndjsonStream writes values as newline delimited JSON as soon as they are ready,
+// so long responses don't have to be buffered in memory
+type ndjsonStream struct {
+	enc        *json.Encoder
+	flusher    http.Flusher
+	flushEvery int
+	pending    int
+}
+
+// newNDJSONStream starts streamed response flushed every flushEvery values. Write deadline of
+// the server is lifted, stream length is limited by its producer instead
+func newNDJSONStream(w http.ResponseWriter, flushEvery int) *ndjsonStream {
+	uncacheable(w)
+	w.Header().Set("Content-Type", ndjsonMediaType)
+	// not every writer supports deadlines, e.g. in tests
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	flusher, _ := w.(http.Flusher)
+	if flushEvery <= 0 {
+		flushEvery = 1
+	}
+	return &ndjsonStream{enc: json.NewEncoder(w), flusher: flusher, flushEvery: flushEvery}
+}
+
+func (s *ndjsonStream) write(v interface{}) error {
+	if err := s.enc.Encode(v); err != nil {
+		return err
+	}
+	s.pending++
+	if s.pending >= s.flushEvery {
+		s.flush()
+	}
+	return nil
+}
+
+func (s *ndjsonStream) flush() {
+	if s.flusher != nil && s.pending > 0 {
+		s.flusher.Flush()
+	}
+	s.pending = 0
+}
+
+// isStream reports whether request is served by streaming endpoint, such endpoints
+// apply request timeout to every item instead of the whole response and don't take
+// slots of concurrency limit
+func isStream(r *http.Request) bool {
+	switch r.URL.Path {
+	case "/api/changes", "/api/reverse/batch", "/api/autocomplete", "/api/search/export":
+		return true
+	}
+	return strings.HasPrefix(r.URL.Path, "/api/batch/")
+}
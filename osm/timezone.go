@@ -0,0 +1,64 @@
+package osm
+
+import (
+	"io/ioutil"
+
+	"github.com/maddevsio/ariadna/geodesic"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// timezone is IANA time zone area loaded from timezone-boundary-builder GeoJSON. The data is
+// read from configured file, it is too large to be embedded
+type timezone struct {
+	name     string
+	polygons []*geodesic.Polygon
+}
+
+// loadTimezones reads feature collection with "tzid" property on every feature
+func loadTimezones(path string) ([]timezone, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fc, err := geojson.UnmarshalFeatureCollection(data)
+	if err != nil {
+		return nil, err
+	}
+	var zones []timezone
+	for _, f := range fc.Features {
+		name, err := f.PropertyString("tzid")
+		if err != nil || f.Geometry == nil {
+			continue
+		}
+		tz := timezone{name: name}
+		switch {
+		case f.Geometry.IsPolygon():
+			tz.polygons = append(tz.polygons, ringToPolygon(f.Geometry.Polygon[0]))
+		case f.Geometry.IsMultiPolygon():
+			for _, p := range f.Geometry.MultiPolygon {
+				tz.polygons = append(tz.polygons, ringToPolygon(p[0]))
+			}
+		}
+		zones = append(zones, tz)
+	}
+	return zones, nil
+}
+
+func (tz timezone) contains(point geodesic.Point) bool {
+	for _, p := range tz.polygons {
+		if p.Contains(point) {
+			return true
+		}
+	}
+	return false
+}
+
+// timezoneAt returns time zone of point from boundary data
+func (i *Importer) timezoneAt(point geodesic.Point) string {
+	for _, tz := range i.timezones {
+		if tz.contains(point) {
+			return tz.name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,98 @@
+package osm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/maddevsio/ariadna/model"
+	"github.com/missinglink/gosmparse"
+)
+
+const layerTransit = "transit"
+
+func (i *Importer) transitToElastic(ctx context.Context) error {
+	i.logger.Info("started to search transit stops")
+	buf, err := i.getTransitStops()
+	if err != nil {
+		return err
+	}
+	i.logger.Info("transit stops found")
+	return i.bulkWrite(ctx, buf)
+}
+
+func (i *Importer) getTransitStops() (bytes.Buffer, error) {
+	var buf bytes.Buffer
+	routes := i.stopRoutes()
+	for nodeID, node := range i.handler.TransitStops {
+		if !i.inClip(node.Lat, node.Lon) {
+			continue
+		}
+		address := model.Address{
+			Name:     node.Tags["name"],
+			Names:    nameVariants(node.Tags),
+			Layer:    layerTransit,
+			Category: transitCategory(node.Tags),
+			Routes:   routes[nodeID],
+			Location: model.Location{Lat: node.Lat, Lon: node.Lon},
+			Fields:   i.extractFields(node.Tags),
+			Vertical: verticalOf(node.Tags),
+		}
+		i.locate(&address)
+		i.enrichWikidata(&address, node.Tags)
+		if err := i.writeDocument(&buf, fmt.Sprintf("node/%d", nodeID), address); err != nil {
+			return buf, err
+		}
+	}
+	return buf, nil
+}
+
+// stopRoutes returns sorted refs of routes serving every stop
+func (i *Importer) stopRoutes() map[int64][]string {
+	refs := make(map[int64]map[string]bool)
+	for _, route := range i.handler.Routes {
+		ref := routeRef(route)
+		if ref == "" {
+			continue
+		}
+		for _, member := range route.Members {
+			if member.Type != gosmparse.NodeType {
+				continue
+			}
+			if refs[member.ID] == nil {
+				refs[member.ID] = make(map[string]bool)
+			}
+			refs[member.ID][ref] = true
+		}
+	}
+	result := make(map[int64][]string, len(refs))
+	for nodeID, set := range refs {
+		for ref := range set {
+			result[nodeID] = append(result[nodeID], ref)
+		}
+		sort.Strings(result[nodeID])
+	}
+	return result
+}
+
+func routeRef(route gosmparse.Relation) string {
+	ref := route.Tags["ref"]
+	if ref == "" {
+		ref = route.Tags["name"]
+	}
+	if ref == "" {
+		return ""
+	}
+	return route.Tags["route"] + " " + ref
+}
+
+func transitCategory(tags map[string]string) string {
+	switch {
+	case tags["highway"] == "bus_stop":
+		return "bus_stop"
+	case tags["railway"] != "":
+		return tags["railway"]
+	}
+	return tags["public_transport"]
+}
@@ -0,0 +1,62 @@
+package osm
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/maddevsio/ariadna/model"
+)
+
+var unitRe = regexp.MustCompile(`(?i)[\s,]+(?:apt|apartment|unit|flat|office|кв|квартира|оф|офис)\.?\s*([\p{L}\d/-]+)\s*$`)
+
+// splitUnit extracts unit designator from the end of query, e.g. "Toktogula 125 apt 4"
+func splitUnit(query string) (string, string) {
+	m := unitRe.FindStringSubmatchIndex(query)
+	if m == nil {
+		return query, ""
+	}
+	return strings.TrimSpace(query[:m[0]]), query[m[2]:m[3]]
+}
+
+// flatsContain reports whether addr:flats value like "1-40;45" contains unit
+func flatsContain(flats, unit string) bool {
+	n, err := strconv.Atoi(unit)
+	for _, part := range strings.FieldsFunc(flats, func(r rune) bool { return r == ';' || r == ',' }) {
+		part = strings.TrimSpace(part)
+		if part == unit {
+			return true
+		}
+		bounds := strings.SplitN(part, "-", 2)
+		if err != nil || len(bounds) != 2 {
+			continue
+		}
+		from, errFrom := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		to, errTo := strconv.Atoi(strings.TrimSpace(bounds[1]))
+		if errFrom == nil && errTo == nil && from <= n && n <= to {
+			return true
+		}
+	}
+	return false
+}
+
+// withUnit keeps addresses that could contain unit and echoes unit back on them
+func withUnit(addresses []model.Address, unit string) []model.Address {
+	if unit == "" {
+		return addresses
+	}
+	result := make([]model.Address, 0, len(addresses))
+	for _, address := range addresses {
+		switch {
+		case address.Unit == unit:
+		case address.Unit != "":
+			continue
+		case address.Flats != "" && !flatsContain(address.Flats, unit):
+			continue
+		default:
+			address.Unit = unit
+		}
+		result = append(result, address)
+	}
+	return result
+}
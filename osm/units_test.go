@@ -0,0 +1,33 @@
+package osm
+
+import (
+	"testing"
+
+	"github.com/maddevsio/ariadna/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitUnit(t *testing.T) {
+	q, unit := splitUnit("Toktogula 125 apt 4")
+	assert.Equal(t, "Toktogula 125", q)
+	assert.Equal(t, "4", unit)
+	q, unit = splitUnit("Токтогула 125, кв. 12а")
+	assert.Equal(t, "Токтогула 125", q)
+	assert.Equal(t, "12а", unit)
+	q, unit = splitUnit("Toktogula 125")
+	assert.Equal(t, "Toktogula 125", q)
+	assert.Equal(t, "", unit)
+}
+
+func TestWithUnit(t *testing.T) {
+	addresses := []model.Address{
+		{Street: "Toktogula", HouseNumber: "125", Flats: "1-40;45"},
+		{Street: "Toktogula", HouseNumber: "127", Flats: "1-3"},
+		{Street: "Toktogula", HouseNumber: "125", Unit: "5"},
+	}
+	result := withUnit(addresses, "4")
+	assert.Len(t, result, 1)
+	assert.Equal(t, "4", result[0].Unit)
+	assert.True(t, flatsContain("1-40;45", "45"))
+	assert.False(t, flatsContain("1-40;45", "41"))
+}
@@ -1,18 +1,52 @@
 package osm
 
 import (
-	"encoding/json"
 	"strings"
 
-	geo "github.com/kellydunn/golang-geo"
+	"github.com/maddevsio/ariadna/geodesic"
 	"github.com/maddevsio/ariadna/model"
 	"github.com/missinglink/gosmparse"
+	geojson "github.com/paulmach/go.geojson"
 )
 
-func (i *Importer) wayToJSON(way gosmparse.Way) ([]byte, error) {
+func (i *Importer) wayAddress(way gosmparse.Way) model.Address {
+	address := i.tagsToAddress(way.Tags, i.wayCenter(way))
+	address.Entrances = i.wayEntrances(way)
+	if isClosed(way) {
+		setPolygonMetrics(&address, geojson.NewPolygonGeometry([][][]float64{i.wayCoords(way)}))
+	}
+	return address
+}
+
+// wayEntrances returns entrances of building, main entrance goes first
+func (i *Importer) wayEntrances(way gosmparse.Way) []model.Entrance {
+	if way.Tags["building"] == "" {
+		return nil
+	}
+	var entrances []model.Entrance
+	for _, nodeID := range way.NodeIDs {
+		node, ok := i.handler.Entrances[nodeID]
+		if !ok {
+			continue
+		}
+		entrance := model.Entrance{
+			Type:     node.Tags["entrance"],
+			Ref:      node.Tags["ref"],
+			Location: model.Location{Lat: node.Lat, Lon: node.Lon},
+		}
+		if entrance.Type == "main" {
+			entrances = append([]model.Entrance{entrance}, entrances...)
+			continue
+		}
+		entrances = append(entrances, entrance)
+	}
+	return entrances
+}
+
+func (i *Importer) wayCenter(way gosmparse.Way) model.Location {
 	var coords [][]float64
 	for _, nodeID := range way.NodeIDs {
-		node := i.handler.Nodes[nodeID]
+		node, _ := i.handler.Node(nodeID)
 		coords = append(coords, []float64{node.Lon, node.Lat})
 	}
 	x := 0.0
@@ -22,22 +56,28 @@ func (i *Importer) wayToJSON(way gosmparse.Way) ([]byte, error) {
 		x += point[0]
 		y += point[1]
 	}
-	return i.marshalJSON(way.Tags, model.Location{Lat: y / numPoints, Lon: x / numPoints})
+	return model.Location{Lat: y / numPoints, Lon: x / numPoints}
 }
 
-func (i *Importer) nodeToJSON(node gosmparse.Node) ([]byte, error) {
-	return i.marshalJSON(node.Tags, model.Location{Lat: node.Lat, Lon: node.Lon})
+func (i *Importer) nodeAddress(node gosmparse.Node) model.Address {
+	return i.tagsToAddress(node.Tags, model.Location{Lat: node.Lat, Lon: node.Lon})
 }
 
-func (i *Importer) marshalJSON(tags map[string]string, location model.Location) ([]byte, error) {
+func (i *Importer) tagsToAddress(tags map[string]string, location model.Location) model.Address {
 	var street = tags["addr:street"]
 	var name = tags["name"]
 	var houseNumber = tags["addr:housenumber"]
 	var address = model.Address{
 		Street:      street,
 		Name:        name,
+		Names:       nameVariants(tags),
 		Location:    location,
 		HouseNumber: houseNumber,
+		Unit:        tags["addr:unit"],
+		Flats:       tags["addr:flats"],
+		Door:        tags["addr:door"],
+		Fields:      i.extractFields(tags),
+		Vertical:    verticalOf(tags),
 	}
 	if address.Street != "" {
 		if strings.Contains(address.Street, "улица") {
@@ -57,35 +97,53 @@ func (i *Importer) marshalJSON(tags map[string]string, location model.Location)
 			address.Street = strings.TrimSpace(strings.Replace(address.Street, "переулок", "", -1))
 		}
 	}
+	i.locate(&address)
+	i.enrichWikidata(&address, tags)
+
+	return address
+}
+
+// locate fills country, settlement, district, time zone and elevation of address by its location.
+// Time zone boundaries win over timezone tags of settlements and countries
+func (i *Importer) locate(address *model.Address) {
+	if i.elevation != nil {
+		if h, ok := i.elevation.Elevation(address.Location.Lat, address.Location.Lon); ok {
+			address.Elevation = &h
+		}
+	}
+	point := geodesic.Point{Lat: address.Location.Lat, Lon: address.Location.Lon}
 	for countryID := range i.countries {
 		country := i.countries[countryID]
-		point := geo.NewPoint(address.Location.Lat, address.Location.Lon)
 		if country.geom.Contains(point) {
 			address.Country = country.name
+			if address.Timezone == "" {
+				address.Timezone = country.timezone
+			}
 		}
 		for townID := range country.towns {
 			town := country.towns[townID]
-			if town.geom.Contains(point) {
-				switch town.placeType {
-				case "city":
-					address.City = town.name
-				case "town":
-					address.Town = town.name
-				case "hamlet":
-					address.Village = town.name
-				case "village":
-					address.Village = town.name
-				}
+			if !town.geom.Contains(point) {
+				continue
 			}
-			for districtID := range town.districts {
-				district := town.districts[districtID]
-				if district.geom.Contains(point) {
-					address.District = district.name
-				}
+			switch town.placeType {
+			case "city":
+				address.City = town.name
+			case "town":
+				address.Town = town.name
+			case "hamlet":
+				address.Village = town.name
+			case "village":
+				address.Village = town.name
+			}
+			if town.timezone != "" {
+				address.Timezone = town.timezone
+			}
+			if name := town.district(point); name != "" {
+				address.District = name
 			}
 		}
-
 	}
-
-	return json.Marshal(address)
+	if tz := i.timezoneAt(point); tz != "" {
+		address.Timezone = tz
+	}
 }
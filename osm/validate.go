@@ -0,0 +1,92 @@
+package osm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	defaultMaxQueryLength = 256
+	defaultMaxBody        = 10 << 20
+)
+
+// parseCoordinate parses finite number of parameter within [-limit, limit]
+func parseCoordinate(name, s string, limit float64) (float64, error) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || v < -limit || v > limit {
+		return 0, fmt.Errorf("invalid %s %q, expected number within [%v, %v]", name, s, -limit, limit)
+	}
+	return v, nil
+}
+
+// parsePoint parses lat and lon parameters of path
+func parsePoint(ps httprouter.Params) (float64, float64, error) {
+	lat, err := parseCoordinate("lat", ps.ByName("lat"), 90)
+	if err != nil {
+		return 0, 0, err
+	}
+	lon, err := parseCoordinate("lon", ps.ByName("lon"), 180)
+	if err != nil {
+		return 0, 0, err
+	}
+	return lat, lon, nil
+}
+
+// validPoint checks point coordinates of request body
+func validPoint(lat, lon float64) error {
+	if math.IsNaN(lat) || lat < -90 || lat > 90 {
+		return fmt.Errorf("invalid lat %v, expected number within [-90, 90]", lat)
+	}
+	if math.IsNaN(lon) || lon < -180 || lon > 180 {
+		return fmt.Errorf("invalid lon %v, expected number within [-180, 180]", lon)
+	}
+	return nil
+}
+
+// validQuery checks query is not longer than max characters
+func validQuery(query string, max int) error {
+	if n := utf8.RuneCountInString(query); n > max {
+		return fmt.Errorf("query of %d characters is longer than %d", n, max)
+	}
+	return nil
+}
+
+// maxQueryLength returns number of characters queries are limited to
+func (i *Importer) maxQueryLength() int {
+	if i.config.API.MaxQueryLength > 0 {
+		return i.config.API.MaxQueryLength
+	}
+	return defaultMaxQueryLength
+}
+
+// limitBody rejects request with body larger than configured limit with 413 and limits reading
+// of body with unknown length, reads past the limit fail with *http.MaxBytesError
+func (i *Importer) limitBody(w http.ResponseWriter, r *http.Request) bool {
+	limit := i.config.API.MaxBodySize
+	if limit <= 0 {
+		limit = defaultMaxBody
+	}
+	if r.ContentLength > limit {
+		i.writeFailure(w, r, http.StatusRequestEntityTooLarge, BadRequest{
+			Error: fmt.Sprintf("request body of %d bytes is larger than %d", r.ContentLength, limit), Code: "body_too_large",
+		})
+		return false
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+	return true
+}
+
+// bodyError returns error of reading request body limited by limitBody with its code
+func bodyError(err error) BadRequest {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return BadRequest{Error: fmt.Sprintf("request body is larger than %d bytes", tooLarge.Limit), Code: "body_too_large"}
+	}
+	return BadRequest{Error: err.Error(), Code: "invalid_request"}
+}
@@ -0,0 +1,73 @@
+package osm
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/maddevsio/ariadna/config"
+	v1 "github.com/maddevsio/ariadna/schema/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidation(t *testing.T) {
+	g, err := NewGeocoder(&config.Ariadna{API: config.API{MaxQueryLength: 10, MaxBodySize: 64}}, WithStorage(&memoryStorage{}))
+	require.NoError(t, err)
+	i := g.i
+	i.metrics = newQueryMetrics(0, true)
+	router := httprouter.New()
+	router.GET("/api/search/:query", i.geoCodeHandler)
+	router.GET("/api/reverse/:lat/:lon", i.reverseGeoCodeHandler)
+	router.POST("/api/batch/search", i.batchSearchHandler)
+	handler := i.withSchema(router)
+	serve := func(r *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve(httptest.NewRequest(http.MethodGet, "/api/reverse/91/74.59", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, problemMediaType, w.Header().Get("Content-Type"))
+	var p struct {
+		problem
+		BadRequest
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	assert.Equal(t, "urn:ariadna:error:invalid_request", p.Type)
+	assert.Equal(t, http.StatusBadRequest, p.Status)
+	assert.Equal(t, "/api/reverse/91/74.59", p.Instance)
+	assert.Contains(t, p.Detail, "[-90, 90]")
+	assert.Equal(t, p.Detail, p.Error, "legacy error field is kept")
+	assert.Equal(t, http.StatusBadRequest, serve(httptest.NewRequest(http.MethodGet, "/api/reverse/42.87/NaN", nil)).Code)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/search/"+strings.Repeat("ж", 11), nil)
+	r.Header.Set("Accept", v1.MediaType)
+	w = serve(r)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	var v1Problem struct {
+		problem
+		v1.Error
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &v1Problem))
+	assert.Equal(t, v1.Version, v1Problem.SchemaVersion)
+	assert.Equal(t, "Bad Request", v1Problem.Title)
+
+	body := strings.Repeat("Киевская 1\n", 10)
+	w = serve(httptest.NewRequest(http.MethodPost, "/api/batch/search", strings.NewReader(body)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), "body_too_large")
+
+	// body of unknown length is cut at the limit
+	r = httptest.NewRequest(http.MethodPost, "/api/batch/search", io.MultiReader(strings.NewReader(body)))
+	r.ContentLength = -1
+	w = serve(r)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "body_too_large")
+	assert.Contains(t, serve(httptest.NewRequest(http.MethodPost, "/api/batch/search", strings.NewReader("Киевская, 1 Бишкек\n"))).Body.String(), "longer than 10")
+}
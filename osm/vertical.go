@@ -0,0 +1,89 @@
+package osm
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/maddevsio/ariadna/model"
+)
+
+// verticalOf reads layer, bridge, tunnel and level tags. Nil is returned for features
+// on the ground which have none of them
+func verticalOf(tags map[string]string) *model.Vertical {
+	v := model.Vertical{
+		Bridge: tags["bridge"] != "" && tags["bridge"] != "no",
+		Tunnel: tags["tunnel"] != "" && tags["tunnel"] != "no",
+		Levels: parseLevels(tags["level"]),
+	}
+	v.Layer, _ = strconv.Atoi(strings.TrimSpace(tags["layer"]))
+	if v.Layer == 0 && !v.Bridge && !v.Tunnel && len(v.Levels) == 0 {
+		return nil
+	}
+	return &v
+}
+
+// parseLevels parses level tag: semicolon separated levels and ranges of whole levels like 0-2
+func parseLevels(s string) []float64 {
+	var levels []float64
+	for _, part := range strings.Split(s, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if level, err := strconv.ParseFloat(part, 64); err == nil {
+			levels = append(levels, level)
+			continue
+		}
+		// range separator follows optional sign of the first level
+		sep := strings.Index(part[1:], "-") + 1
+		if sep <= 0 {
+			continue
+		}
+		from, err := strconv.Atoi(part[:sep])
+		if err != nil {
+			continue
+		}
+		to, err := strconv.Atoi(part[sep+1:])
+		if err != nil || to < from || to-from > maxLevelRange {
+			continue
+		}
+		for level := from; level <= to; level++ {
+			levels = append(levels, float64(level))
+		}
+	}
+	return levels
+}
+
+// maxLevelRange limits levels expanded from range, taller buildings are mistagged
+const maxLevelRange = 200
+
+// onLevel reports whether feature is on level: levels of the feature if tagged, otherwise
+// its layer, features without them are on the ground level 0
+func onLevel(a model.Address, level float64) bool {
+	if a.Vertical == nil {
+		return level == 0
+	}
+	if len(a.Vertical.Levels) > 0 {
+		for _, l := range a.Vertical.Levels {
+			if l == level {
+				return true
+			}
+		}
+		return false
+	}
+	return float64(a.Vertical.Layer) == level
+}
+
+// preferLevel moves features on level ahead of others, keeping their order by distance
+func preferLevel(addresses []model.Address, level float64) []model.Address {
+	result := make([]model.Address, 0, len(addresses))
+	var others []model.Address
+	for _, a := range addresses {
+		if onLevel(a, level) {
+			result = append(result, a)
+			continue
+		}
+		others = append(others, a)
+	}
+	return append(result, others...)
+}
@@ -0,0 +1,62 @@
+package osm
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/maddevsio/ariadna/model"
+)
+
+func TestParseLevels(t *testing.T) {
+	for s, want := range map[string][]float64{
+		"":       nil,
+		"1":      {1},
+		"-1;0":   {-1, 0},
+		"0-2":    {0, 1, 2},
+		"-2--1":  {-2, -1},
+		"0.5; 3": {0.5, 3},
+		"roof":   nil,
+	} {
+		if got := parseLevels(s); !reflect.DeepEqual(got, want) {
+			t.Errorf("parseLevels(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestVerticalOf(t *testing.T) {
+	if v := verticalOf(map[string]string{"bridge": "no", "layer": "0"}); v != nil {
+		t.Errorf("expected no vertical for ground feature, got %+v", v)
+	}
+	v := verticalOf(map[string]string{"bridge": "viaduct", "layer": "1"})
+	if v == nil || !v.Bridge || v.Tunnel || v.Layer != 1 {
+		t.Errorf("unexpected vertical of bridge: %+v", v)
+	}
+	v = verticalOf(map[string]string{"tunnel": "yes", "layer": "-1", "level": "-1"})
+	if v == nil || !v.Tunnel || v.Layer != -1 || !reflect.DeepEqual(v.Levels, []float64{-1}) {
+		t.Errorf("unexpected vertical of tunnel: %+v", v)
+	}
+}
+
+func TestPreferLevel(t *testing.T) {
+	addresses := []model.Address{
+		{Name: "street"},
+		{Name: "flyover", Vertical: &model.Vertical{Layer: 1, Bridge: true}},
+		{Name: "parking", Vertical: &model.Vertical{Layer: -1, Levels: []float64{-2, -1}}},
+	}
+	names := func(addresses []model.Address) []string {
+		var result []string
+		for _, a := range addresses {
+			result = append(result, a.Name)
+		}
+		return result
+	}
+	for level, want := range map[float64][]string{
+		0:  {"street", "flyover", "parking"},
+		1:  {"flyover", "street", "parking"},
+		-2: {"parking", "street", "flyover"},
+	} {
+		if got := names(preferLevel(addresses, level)); !reflect.DeepEqual(got, want) {
+			t.Errorf("level %v: got %v, want %v", level, got, want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package osm
+
+import (
+	"math"
+
+	"github.com/maddevsio/ariadna/model"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+const (
+	// viewportPadding widens bounding box of geometry by this share on every side,
+	// so feature doesn't touch edges of the map
+	viewportPadding = 0.1
+	// defaultViewportRadius is half size of viewport of buildings and other small features in meters
+	defaultViewportRadius = 150.0
+)
+
+// viewportRadius is half size of viewport in meters by category of places without geometry,
+// then by layer
+var viewportRadius = map[string]float64{
+	"country":       500000,
+	"state":         200000,
+	"region":        200000,
+	"county":        50000,
+	"city":          10000,
+	"town":          5000,
+	"district":      3000,
+	"village":       2000,
+	"suburb":        2000,
+	"hamlet":        1000,
+	"neighbourhood": 1000,
+	"quarter":       1000,
+	layerNatural:    2000,
+	layerRoad:       500,
+	layerTransit:    300,
+	layerJunction:   200,
+}
+
+// withViewports sets suggested map viewport of addresses: bounding box of their geometry
+// or box of radius of their place type around location
+func withViewports(addresses []model.Address) []model.Address {
+	for n := range addresses {
+		addresses[n].Viewport = viewport(addresses[n])
+	}
+	return addresses
+}
+
+func viewport(a model.Address) *model.BBox {
+	if box, ok := geometryBounds(a.Geometry); ok {
+		dLat, dLon := (box.MaxLat-box.MinLat)*viewportPadding, (box.MaxLon-box.MinLon)*viewportPadding
+		box.MinLat, box.MaxLat = box.MinLat-dLat, box.MaxLat+dLat
+		box.MinLon, box.MaxLon = box.MinLon-dLon, box.MaxLon+dLon
+		// small features like buildings get at least default viewport around them
+		return extend(box, aroundPoint(geometryMiddle(box), defaultViewportRadius))
+	}
+	radius, ok := viewportRadius[a.Category]
+	if !ok {
+		if radius, ok = viewportRadius[a.Layer]; !ok {
+			radius = defaultViewportRadius
+		}
+	}
+	return aroundPoint(a.Location, radius)
+}
+
+// aroundPoint returns box of radius meters around location
+func aroundPoint(l model.Location, radius float64) *model.BBox {
+	dLat := radius / earthRadiusM * 180 / math.Pi
+	dLon := dLat
+	if c := math.Cos(radians(l.Lat)); c > 0.01 {
+		dLon = dLat / c
+	}
+	return &model.BBox{
+		MinLat: math.Max(l.Lat-dLat, -90),
+		MinLon: math.Max(l.Lon-dLon, -180),
+		MaxLat: math.Min(l.Lat+dLat, 90),
+		MaxLon: math.Min(l.Lon+dLon, 180),
+	}
+}
+
+func extend(a, b *model.BBox) *model.BBox {
+	return &model.BBox{
+		MinLat: math.Min(a.MinLat, b.MinLat),
+		MinLon: math.Min(a.MinLon, b.MinLon),
+		MaxLat: math.Max(a.MaxLat, b.MaxLat),
+		MaxLon: math.Max(a.MaxLon, b.MaxLon),
+	}
+}
+
+func geometryMiddle(box *model.BBox) model.Location {
+	return model.Location{Lat: (box.MinLat + box.MaxLat) / 2, Lon: (box.MinLon + box.MaxLon) / 2}
+}
+
+// geometryBounds returns bounding box of line or polygon geometry, points have no extent
+func geometryBounds(g *geojson.Geometry) (*model.BBox, bool) {
+	if g == nil {
+		return nil, false
+	}
+	var lines [][][]float64
+	switch {
+	case g.IsLineString():
+		lines = [][][]float64{g.LineString}
+	case g.IsMultiLineString():
+		lines = g.MultiLineString
+	case g.IsPolygon(), g.IsMultiPolygon():
+		for _, polygon := range geometryPolygons(g) {
+			// holes are inside of outer ring
+			lines = append(lines, polygon[0])
+		}
+	}
+	var box *model.BBox
+	for _, line := range lines {
+		for _, c := range line {
+			if box == nil {
+				box = &model.BBox{MinLat: c[1], MinLon: c[0], MaxLat: c[1], MaxLon: c[0]}
+				continue
+			}
+			box.MinLat, box.MaxLat = math.Min(box.MinLat, c[1]), math.Max(box.MaxLat, c[1])
+			box.MinLon, box.MaxLon = math.Min(box.MinLon, c[0]), math.Max(box.MaxLon, c[0])
+		}
+	}
+	return box, box != nil
+}
@@ -0,0 +1,34 @@
+package osm
+
+import (
+	"testing"
+
+	"github.com/maddevsio/ariadna/model"
+	geojson "github.com/paulmach/go.geojson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestViewport(t *testing.T) {
+	city := viewport(model.Address{
+		Layer:    layerBoundary,
+		Category: "city",
+		Geometry: geojson.NewPolygonGeometry([][][]float64{{{74.5, 42.8}, {74.7, 42.8}, {74.7, 42.9}, {74.5, 42.9}, {74.5, 42.8}}}),
+	})
+	require.NotNil(t, city)
+	assert.InDelta(t, 74.48, city.MinLon, 1e-9)
+	assert.InDelta(t, 74.72, city.MaxLon, 1e-9)
+	assert.InDelta(t, 42.79, city.MinLat, 1e-9)
+	assert.InDelta(t, 42.91, city.MaxLat, 1e-9)
+
+	building := viewport(model.Address{
+		Geometry: geojson.NewPolygonGeometry([][][]float64{{{74.6, 42.87}, {74.6001, 42.87}, {74.6001, 42.8701}, {74.6, 42.87}}}),
+	})
+	assert.True(t, building.MaxLat-building.MinLat > 0.002, "small features get default viewport")
+
+	village := viewport(model.Address{Category: "village", Location: model.Location{Lat: 42, Lon: 75}})
+	address := viewport(model.Address{Location: model.Location{Lat: 42, Lon: 75}})
+	assert.True(t, village.MaxLat-village.MinLat > 10*(address.MaxLat-address.MinLat))
+	assert.True(t, address.MaxLon-address.MinLon > address.MaxLat-address.MinLat, "box is wider in degrees of longitude away from equator")
+	assert.InDelta(t, 42, (address.MinLat+address.MaxLat)/2, 1e-9)
+}
@@ -0,0 +1,222 @@
+package osm
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// websocketGUID is appended to client key to compute accept key, see RFC 6455 section 1.3
+	websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	// maxWebSocketMessage limits size of client messages, autocomplete queries are short
+	maxWebSocketMessage = 4096
+
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xa
+
+	wsCloseNormal   = 1000
+	wsCloseProtocol = 1002
+	wsCloseTooBig   = 1009
+)
+
+var errWebSocketClosed = errors.New("websocket is closed")
+
+// wsConn is server side of WebSocket connection. Only text and binary messages are
+// returned by read, control frames are answered while reading
+type wsConn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+	mu   sync.Mutex
+}
+
+// websocketKey validates upgrade request and returns key of client
+func websocketKey(r *http.Request) (string, error) {
+	if r.Method != http.MethodGet {
+		return "", errors.New("websocket upgrade requires GET")
+	}
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		return "", errors.New("websocket upgrade is required")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return "", errors.New("unsupported websocket version, supported: 13")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return "", errors.New("invalid Sec-WebSocket-Key")
+	}
+	return key, nil
+}
+
+func headerContains(h http.Header, name, token string) bool {
+	for _, value := range h[name] {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// acceptWebSocket takes over connection of validated upgrade request and completes handshake.
+// Deadlines of the server are lifted, connection owner sets its own ones
+func acceptWebSocket(w http.ResponseWriter, key string) (*wsConn, error) {
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+		base64.StdEncoding.EncodeToString(sum[:]))
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, rw: rw}, nil
+}
+
+// read returns the next data message. Reading stops with errWebSocketClosed when client
+// closes connection, no message arrives before idle timeout or protocol is violated
+func (c *wsConn) read(idle time.Duration) ([]byte, error) {
+	var message []byte
+	for {
+		if idle > 0 {
+			if err := c.conn.SetReadDeadline(time.Now().Add(idle)); err != nil {
+				return nil, err
+			}
+		}
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch opcode {
+		case wsPing:
+			if err := c.write(wsPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case wsPong:
+			continue
+		case wsClose:
+			c.write(wsClose, payload)
+			return nil, errWebSocketClosed
+		case wsText, wsBinary:
+			if message != nil {
+				return nil, c.fail(wsCloseProtocol, "message is not finished")
+			}
+			message = payload
+		case wsContinuation:
+			if message == nil {
+				return nil, c.fail(wsCloseProtocol, "unexpected continuation")
+			}
+			message = append(message, payload...)
+		default:
+			return nil, c.fail(wsCloseProtocol, "unknown opcode")
+		}
+		if len(message) > maxWebSocketMessage {
+			return nil, c.fail(wsCloseTooBig, "message is too big")
+		}
+		if fin {
+			return message, nil
+		}
+	}
+}
+
+func (c *wsConn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err = io.ReadFull(c.rw, head[:]); err != nil {
+		return
+	}
+	fin, opcode = head[0]&0x80 != 0, head[0]&0x0f
+	if head[1]&0x80 == 0 {
+		// client frames are always masked
+		err = c.fail(wsCloseProtocol, "frame is not masked")
+		return
+	}
+	size := uint64(head[1] & 0x7f)
+	switch size {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.rw, ext[:]); err != nil {
+			return
+		}
+		size = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.rw, ext[:]); err != nil {
+			return
+		}
+		size = binary.BigEndian.Uint64(ext[:])
+	}
+	if size > maxWebSocketMessage {
+		err = c.fail(wsCloseTooBig, "message is too big")
+		return
+	}
+	var mask [4]byte
+	if _, err = io.ReadFull(c.rw, mask[:]); err != nil {
+		return
+	}
+	payload = make([]byte, size)
+	if _, err = io.ReadFull(c.rw, payload); err != nil {
+		return
+	}
+	for n := range payload {
+		payload[n] ^= mask[n%4]
+	}
+	return
+}
+
+// write sends single unmasked frame, it is safe for concurrent use
+func (c *wsConn) write(opcode byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	head := []byte{0x80 | opcode}
+	switch size := len(payload); {
+	case size < 126:
+		head = append(head, byte(size))
+	case size <= 0xffff:
+		head = append(head, 126, byte(size>>8), byte(size))
+	default:
+		head = append(head, 127)
+		head = append(head, make([]byte, 8)...)
+		binary.BigEndian.PutUint64(head[2:], uint64(size))
+	}
+	if _, err := c.rw.Write(head); err != nil {
+		return err
+	}
+	if _, err := c.rw.Write(payload); err != nil {
+		return err
+	}
+	return c.rw.Flush()
+}
+
+// close sends close frame with status code and reason and closes connection
+func (c *wsConn) close(code int, reason string) error {
+	payload := append([]byte{byte(code >> 8), byte(code)}, reason...)
+	c.write(wsClose, payload)
+	return c.conn.Close()
+}
+
+// fail closes connection violating protocol and returns error describing violation
+func (c *wsConn) fail(code int, reason string) error {
+	c.close(code, reason)
+	return fmt.Errorf("websocket: %s", reason)
+}
@@ -0,0 +1,144 @@
+package osm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maddevsio/ariadna/model"
+)
+
+const (
+	wikidataURL        = "https://www.wikidata.org/w/api.php"
+	wikidataBatch      = 50
+	wikidataPopulation = "P1082"
+	wikidataTimeout    = 30 * time.Second
+)
+
+type (
+	wikidataResponse struct {
+		Entities map[string]wikidataEntity `json:"entities"`
+	}
+	wikidataEntity struct {
+		Labels map[string]struct {
+			Value string `json:"value"`
+		} `json:"labels"`
+		Sitelinks map[string]json.RawMessage `json:"sitelinks"`
+		Claims    map[string][]struct {
+			Mainsnak struct {
+				Datavalue struct {
+					Value struct {
+						Amount string `json:"amount"`
+					} `json:"value"`
+				} `json:"datavalue"`
+			} `json:"mainsnak"`
+		} `json:"claims"`
+	}
+)
+
+// fetchWikidata loads labels, population and sitelinks count of all entities referenced by wikidata tags
+func (i *Importer) fetchWikidata(ctx context.Context) error {
+	if !i.config.Wikidata.Fetch {
+		return nil
+	}
+	ids := i.wikidataIDs()
+	i.logger.Infof("fetching %d wikidata entities", len(ids))
+	i.wikidata = make(map[string]model.Wikidata, len(ids))
+	timeout := i.config.Wikidata.Timeout
+	if timeout <= 0 {
+		timeout = wikidataTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+	for start := 0; start < len(ids); start += wikidataBatch {
+		end := start + wikidataBatch
+		if end > len(ids) {
+			end = len(ids)
+		}
+		if err := i.fetchWikidataBatch(ctx, client, ids[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (i *Importer) wikidataIDs() []string {
+	var ids []string
+	add := func(tags map[string]string) {
+		if id := tags["wikidata"]; id != "" {
+			ids = append(ids, id)
+		}
+	}
+	for _, node := range i.handler.FilteredNodes {
+		add(node.Tags)
+	}
+	for _, way := range i.handler.Ways {
+		add(way.Tags)
+	}
+	for _, area := range i.handler.Areas {
+		add(area.Tags)
+	}
+	for _, area := range i.handler.NaturalAreas {
+		add(area.Tags)
+	}
+	return uniqString(ids)
+}
+
+func (i *Importer) fetchWikidataBatch(ctx context.Context, client *http.Client, ids []string) error {
+	endpoint := i.config.Wikidata.URL
+	if endpoint == "" {
+		endpoint = wikidataURL
+	}
+	params := url.Values{}
+	params.Set("action", "wbgetentities")
+	params.Set("format", "json")
+	params.Set("props", "labels|claims|sitelinks")
+	params.Set("ids", strings.Join(ids, "|"))
+	if len(i.config.Wikidata.Languages) > 0 {
+		params.Set("languages", strings.Join(i.config.Wikidata.Languages, "|"))
+	}
+	req, err := http.NewRequest(http.MethodGet, endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("could not fetch wikidata entities: %s", resp.Status)
+	}
+	var r wikidataResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return err
+	}
+	for id, entity := range r.Entities {
+		data := model.Wikidata{Sitelinks: len(entity.Sitelinks)}
+		for lang, label := range entity.Labels {
+			if data.Labels == nil {
+				data.Labels = make(map[string]string)
+			}
+			data.Labels[lang] = label.Value
+		}
+		if claims := entity.Claims[wikidataPopulation]; len(claims) > 0 {
+			amount := strings.TrimPrefix(claims[0].Mainsnak.Datavalue.Value.Amount, "+")
+			data.Population, _ = strconv.ParseInt(amount, 10, 64)
+		}
+		i.wikidata[id] = data
+	}
+	return nil
+}
+
+// enrichWikidata copies wikidata and wikipedia references and fetched metadata onto address
+func (i *Importer) enrichWikidata(address *model.Address, tags map[string]string) {
+	address.WikidataID = tags["wikidata"]
+	address.Wikipedia = tags["wikipedia"]
+	if data, ok := i.wikidata[address.WikidataID]; ok {
+		address.Wikidata = &data
+	}
+}
@@ -0,0 +1,41 @@
+package osm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/maddevsio/ariadna/osm/osmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchWikidataTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx := context.Background()
+	c := &config.Ariadna{Wikidata: config.Wikidata{Fetch: true, URL: server.URL, Timeout: 50 * time.Millisecond}}
+	data := osmtest.New().Node(1, 42.87, 74.59, "amenity", "theatre", "name", "Опера", "wikidata", "Q4214186")
+	i, err := NewImporter(ctx, c, WithParser(data), WithStorage(&memoryStorage{docs: map[string]model.Address{}}))
+	require.NoError(t, err)
+	data.Feed(i.handler)
+	require.NotEmpty(t, i.wikidataIDs())
+
+	done := make(chan error, 1)
+	go func() { done <- i.fetchWikidata(ctx) }()
+	select {
+	case err := <-done:
+		assert.Error(t, err, "stalled response fails fetch")
+	case <-time.After(5 * time.Second):
+		t.Fatal("fetch is not limited by timeout")
+	}
+}
@@ -2,24 +2,46 @@ package osm
 
 import (
 	"bytes"
-	"encoding/json"
-	"fmt"
+	"context"
 	"sort"
 	"strconv"
 	"strings"
 
-	geo "github.com/kellydunn/golang-geo"
+	"github.com/maddevsio/ariadna/geodesic"
 	"github.com/maddevsio/ariadna/model"
 )
 
-func (i *Importer) crossRoadsToElastic() error {
+// crossroadMergeDistance is distance in meters within which nodes where the same streets
+// meet are one crossroad
+const crossroadMergeDistance = 100.0
+
+func (i *Importer) crossRoadsToElastic(ctx context.Context) error {
 	i.logger.Info("started to search crossroads")
 	buf, err := i.searchCrossRoads()
 	if err != nil {
 		return err
 	}
 	i.logger.Info("crossroads found")
-	return i.e.BulkWrite(buf)
+	return i.bulkWrite(ctx, buf)
+}
+
+// crossroad is crossing of streets merged from nodes where the same streets meet,
+// id is the lowest of node ids, location is average of nodes
+type crossroad struct {
+	id       int64
+	names    []string
+	lat, lon float64
+	nodes    int
+}
+
+func (c *crossroad) add(id int64, lat, lon float64) {
+	if id < c.id {
+		c.id = id
+	}
+	n := float64(c.nodes)
+	c.lat = (c.lat*n + lat) / (n + 1)
+	c.lon = (c.lon*n + lon) / (n + 1)
+	c.nodes++
 }
 
 func (i *Importer) searchCrossRoads() (bytes.Buffer, error) {
@@ -30,70 +52,84 @@ func (i *Importer) searchCrossRoads() (bytes.Buffer, error) {
 		"бульвар", "",
 		"проспект", "",
 	)
-	for nodeid, wayids := range i.handler.InvertedIndex {
-		uniqueWayIds := uniqString(wayids)
-		if len(uniqueWayIds) > 1 {
-			var names []string
-			sort.Strings(uniqueWayIds)
-			for _, wayid := range uniqueWayIds {
-				names = append(names, i.handler.WayNames[wayid])
+	for _, c := range i.crossroads() {
+		// country is filled by locate as for addresses, so both land in one shard
+		address := model.Address{
+			Name:         replacer.Replace(strings.Join(c.names, " ")),
+			Location:     model.Location{Lat: c.lat, Lon: c.lon},
+			Intersection: true,
+		}
+		i.locate(&address)
+
+		if err := i.writeDocument(&buf, strconv.FormatInt(c.id, 10), address); err != nil {
+			return buf, err
+		}
+	}
+	return buf, nil
+}
+
+// crossroads finds nodes shared by ways of different named streets. Streets split into several
+// ways and carriageways of divided roads meet at several nodes close to each other, nodes where
+// the same streets meet within crossroadMergeDistance are merged into one crossroad
+func (i *Importer) crossroads() []*crossroad {
+	var nodeIDs []int64
+	for nodeid := range i.handler.InvertedIndex {
+		id, err := strconv.ParseInt(nodeid, 10, 64)
+		if err != nil {
+			continue
+		}
+		nodeIDs = append(nodeIDs, id)
+	}
+	// nodes are visited in order of ids, so merged crossroads don't depend on map order
+	sort.Slice(nodeIDs, func(a, b int) bool { return nodeIDs[a] < nodeIDs[b] })
+	var (
+		result []*crossroad
+		byKey  = make(map[string][]*crossroad)
+	)
+	for _, id := range nodeIDs {
+		names := i.crossingNames(i.handler.InvertedIndex[strconv.FormatInt(id, 10)])
+		if len(names) < 2 {
+			continue
+		}
+		node, _ := i.handler.Node(id)
+		if !i.inClip(node.Lat, node.Lon) {
+			continue
+		}
+		key := strings.Join(names, "\x00")
+		merged := false
+		for _, c := range byKey[key] {
+			if geodesic.Distance(geodesic.Point{Lat: c.lat, Lon: c.lon}, geodesic.Point{Lat: node.Lat, Lon: node.Lon}) <= crossroadMergeDistance {
+				c.add(id, node.Lat, node.Lon)
+				merged = true
+				break
 			}
-			var uniqueNames = uniqString(names)
-			sort.Strings(uniqueNames)
-			if len(uniqueNames) > 1 {
-				id, err := strconv.Atoi(nodeid)
-				if err != nil {
-					return buf, err
-				}
-				node := i.handler.Nodes[int64(id)]
-				address := model.Address{
-					Country:      "KG",
-					Name:         replacer.Replace(strings.Join(uniqueNames, " ")),
-					Location:     model.Location{Lat: node.Lat, Lon: node.Lon},
-					Intersection: true,
-				}
-				for countryID := range i.countries {
-					country := i.countries[countryID]
-					lat := address.Location.Lat
-					lon := address.Location.Lon
-					point := geo.NewPoint(lat, lon)
-					if country.geom.Contains(point) {
-						address.Country = country.name
-					}
-					for townID := range country.towns {
-						town := country.towns[townID]
-						if town.geom.Contains(point) {
-							switch town.placeType {
-							case "city":
-								address.City = town.name
-							case "town":
-								address.Town = town.name
-							case "hamlet":
-								address.Village = town.name
-							case "village":
-								address.Village = town.name
-							}
-						}
-						for districtID := range town.districts {
-							district := town.districts[districtID]
-							if district.geom.Contains(point) {
-								address.District = district.name
-							}
-						}
-					}
-				}
+		}
+		if merged {
+			continue
+		}
+		c := &crossroad{id: id, names: names, lat: node.Lat, lon: node.Lon, nodes: 1}
+		byKey[key] = append(byKey[key], c)
+		result = append(result, c)
+	}
+	return result
+}
 
-				data, err := json.Marshal(address)
-				if err != nil {
-					return buf, err
-				}
-				meta := []byte(fmt.Sprintf(`{ "index" : { "_id" : "%s" } }%s`, nodeid, "\n"))
-				data = append(data, "\n"...)
-				buf.Grow(len(meta) + len(data))
-				buf.Write(meta)
-				buf.Write(data)
+// crossingNames returns sorted distinct names of named ways, unnamed ways like driveways
+// don't make crossroads. Named roundabouts are indexed as junctions instead of crossroads
+// with every street entering them
+func (i *Importer) crossingNames(wayIDs []string) []string {
+	var names []string
+	for _, wayID := range uniqString(wayIDs) {
+		if id, err := strconv.ParseInt(wayID, 10, 64); err == nil {
+			if _, ok := i.handler.Roundabouts[id]; ok {
+				continue
 			}
 		}
+		if name := i.handler.WayNames[wayID]; name != "" {
+			names = append(names, name)
+		}
 	}
-	return buf, nil
+	names = uniqString(names)
+	sort.Strings(names)
+	return names
 }
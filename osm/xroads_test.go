@@ -0,0 +1,70 @@
+package osm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/maddevsio/ariadna/config"
+	"github.com/maddevsio/ariadna/model"
+	"github.com/maddevsio/ariadna/osm/osmtest"
+	"github.com/missinglink/gosmparse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCrossroadsDeduplicated(t *testing.T) {
+	// carriageways of divided Чуй cross Советская at nodes 2 and 5 about 30 meters apart,
+	// Советская is split at node 5, far away it crosses Чуй again at node 8
+	data := osmtest.New().
+		Node(1, 42.8760, 74.6000).Node(2, 42.8760, 74.6100).Node(3, 42.8760, 74.6200).
+		Node(4, 42.8763, 74.6000).Node(5, 42.8763, 74.6100).Node(6, 42.8763, 74.6200).
+		Node(7, 42.8700, 74.6100).Node(8, 42.8900, 74.6100).Node(9, 42.8910, 74.6100).
+		Way(10, []int64{1, 2, 3}, "highway", "primary", "name", "Чуй").
+		Way(11, []int64{6, 5, 4}, "highway", "primary", "name", "Чуй").
+		Way(12, []int64{7, 2, 5}, "highway", "residential", "name", "Советская").
+		Way(13, []int64{5, 8, 9}, "highway", "residential", "name", "Советская").
+		Way(14, []int64{8, 3}, "highway", "residential", "name", "Чуй").
+		Way(15, []int64{2, 7}, "highway", "service")
+	storage := &memoryStorage{docs: make(map[string]model.Address)}
+	ctx := context.Background()
+	i, err := NewImporter(ctx, &config.Ariadna{}, WithParser(data), WithStorage(storage))
+	require.NoError(t, err)
+	require.NoError(t, i.Start(ctx))
+	require.NoError(t, i.WaitStop())
+
+	var crossroads []string
+	for id, doc := range storage.docs {
+		if doc.Intersection {
+			crossroads = append(crossroads, id)
+		}
+	}
+	assert.ElementsMatch(t, []string{"2", "8"}, crossroads)
+	assert.InDelta(t, 42.87615, storage.docs["2"].Location.Lat, 1e-9, "merged crossroad is between carriageways")
+}
+
+func TestCrossroadsCountry(t *testing.T) {
+	// with sharding by country crossroads go to index of the country of addresses around them
+	data := osmtest.New().
+		Square(100, 1000, 41.5, 74.5, 4).
+		Relation(1, []gosmparse.RelationMember{osmtest.Way(100, "outer")}, "type", "boundary", "admin_level", "2", "name", "Кыргызстан").
+		Node(1, 42.8760, 74.6000).Node(2, 42.8760, 74.6100).Node(3, 42.8700, 74.6100).Node(4, 42.8800, 74.6100).
+		Node(5, 42.8765, 74.6105, "addr:street", "Чуй", "addr:housenumber", "1").
+		Node(6, 44.0000, 79.0000).Node(7, 44.0000, 79.0100).Node(8, 43.9900, 79.0100).Node(9, 44.0100, 79.0100).
+		Way(10, []int64{1, 2}, "highway", "primary", "name", "Чуй").
+		Way(11, []int64{3, 2, 4}, "highway", "residential", "name", "Советская").
+		Way(12, []int64{6, 7}, "highway", "primary", "name", "Первая").
+		Way(13, []int64{8, 7, 9}, "highway", "residential", "name", "Вторая")
+	storage := &memoryStorage{docs: make(map[string]model.Address)}
+	ctx := context.Background()
+	i, err := NewImporter(ctx, &config.Ariadna{ImportCountry: "Кыргызстан"}, WithParser(data), WithStorage(storage))
+	require.NoError(t, err)
+	require.NoError(t, i.Start(ctx))
+	require.NoError(t, i.WaitStop())
+
+	require.Contains(t, storage.docs, "5")
+	require.Contains(t, storage.docs, "2")
+	assert.Equal(t, "Кыргызстан", storage.docs["5"].Country)
+	assert.Equal(t, storage.docs["5"].Country, storage.docs["2"].Country)
+	require.Contains(t, storage.docs, "7")
+	assert.Empty(t, storage.docs["7"].Country, "crossroad outside of countries stays in the main index")
+}
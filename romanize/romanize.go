@@ -0,0 +1,90 @@
+// Package romanize transliterates names into Latin script for clients which can't render
+// the native one. Cyrillic follows BGN/PCGN with letters of Central Asian languages, Greek,
+// Georgian, Armenian and Arabic are transliterated by simplified national systems. Latin
+// letters lose diacritics, scripts without table are kept as is
+package romanize
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var table = map[rune]string{
+	// Cyrillic
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo", 'ж': "zh", 'з': "z",
+	'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r",
+	'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh",
+	'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	// Kyrgyz, Kazakh, Tajik, Uzbek and Ukrainian letters
+	'ө': "ö", 'ү': "ü", 'ң': "ng", 'ә': "ä", 'ғ': "gh", 'қ': "q", 'ұ': "u", 'һ': "h", 'і': "i",
+	'ӣ': "i", 'ӯ': "u", 'ҳ': "h", 'ҷ': "j", 'ў': "o'", 'ї': "yi", 'є': "ye", 'ґ': "g",
+	// Greek
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th", 'ι': "i",
+	'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s",
+	'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o",
+	// Georgian
+	'ა': "a", 'ბ': "b", 'გ': "g", 'დ': "d", 'ე': "e", 'ვ': "v", 'ზ': "z", 'თ': "t", 'ი': "i",
+	'კ': "k'", 'ლ': "l", 'მ': "m", 'ნ': "n", 'ო': "o", 'პ': "p'", 'ჟ': "zh", 'რ': "r", 'ს': "s",
+	'ტ': "t'", 'უ': "u", 'ფ': "p", 'ქ': "k", 'ღ': "gh", 'ყ': "q'", 'შ': "sh", 'ჩ': "ch", 'ც': "ts",
+	'ძ': "dz", 'წ': "ts'", 'ჭ': "ch'", 'ხ': "kh", 'ჯ': "j", 'ჰ': "h",
+	// Armenian
+	'ա': "a", 'բ': "b", 'գ': "g", 'դ': "d", 'ե': "e", 'զ': "z", 'է': "e", 'ը': "y", 'թ': "t",
+	'ժ': "zh", 'ի': "i", 'լ': "l", 'խ': "kh", 'ծ': "ts", 'կ': "k", 'հ': "h", 'ձ': "dz", 'ղ': "gh",
+	'ճ': "ch", 'մ': "m", 'յ': "y", 'ն': "n", 'շ': "sh", 'ո': "o", 'չ': "ch", 'պ': "p", 'ջ': "j",
+	'ռ': "r", 'ս': "s", 'վ': "v", 'տ': "t", 'ր': "r", 'ց': "ts", 'ւ': "v", 'փ': "p", 'ք': "k",
+	'և': "ev", 'օ': "o", 'ֆ': "f",
+	// Arabic and Persian, short vowels are not written and are not restored
+	'ا': "a", 'أ': "a", 'إ': "i", 'آ': "a", 'ب': "b", 'پ': "p", 'ت': "t", 'ث': "th", 'ج': "j",
+	'چ': "ch", 'ح': "h", 'خ': "kh", 'د': "d", 'ذ': "dh", 'ر': "r", 'ز': "z", 'ژ': "zh", 'س': "s",
+	'ش': "sh", 'ص': "s", 'ض': "d", 'ط': "t", 'ظ': "z", 'ع': "'", 'غ': "gh", 'ف': "f", 'ق': "q",
+	'ک': "k", 'ك': "k", 'گ': "g", 'ل': "l", 'م': "m", 'ن': "n", 'ه': "h", 'ة': "a", 'و': "w",
+	'ي': "y", 'ی': "y", 'ى': "a", 'ء': "'", 'ئ': "'", 'ؤ': "'",
+	'٠': "0", '١': "1", '٢': "2", '٣': "3", '٤': "4", '٥': "5", '٦': "6", '٧': "7", '٨': "8", '٩': "9",
+	'۰': "0", '۱': "1", '۲': "2", '۳': "3", '۴': "4", '۵': "5", '۶': "6", '۷': "7", '۸': "8", '۹': "9",
+	'،': ",",
+}
+
+// String returns s transliterated into Latin script. Capital letters give capitalized
+// transliteration, so "Щорс" becomes "Shchors"
+func String(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFC.String(s) {
+		lower := unicode.ToLower(r)
+		latin, ok := table[lower]
+		if !ok {
+			// accented letters like Greek ή are looked up by base letter
+			latin, ok = table[[]rune(norm.NFD.String(string(lower)))[0]]
+		}
+		if !ok {
+			b.WriteString(foldLatin(r))
+			continue
+		}
+		if lower != r && latin != "" {
+			first := []rune(latin)
+			first[0] = unicode.ToUpper(first[0])
+			latin = string(first)
+		}
+		b.WriteString(latin)
+	}
+	return norm.NFC.String(b.String())
+}
+
+// foldLatin drops diacritics of Latin letters, other runes are kept
+func foldLatin(r rune) string {
+	if !unicode.Is(unicode.Latin, r) || r < unicode.MaxASCII {
+		if unicode.Is(unicode.Mn, r) {
+			// combining marks left of decomposed input
+			return ""
+		}
+		return string(r)
+	}
+	var b strings.Builder
+	for _, d := range norm.NFD.String(string(r)) {
+		if !unicode.Is(unicode.Mn, d) {
+			b.WriteRune(d)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,28 @@
+package romanize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestString(t *testing.T) {
+	for _, c := range []struct {
+		native, romanized string
+	}{
+		{"Чуйский проспект", "Chuyskiy prospekt"},
+		{"улица Щорса", "ulitsa Shchorsa"},
+		{"Ысык-Көл", "Ysyk-Köl"},
+		{"Қазақстан", "Qazaqstan"},
+		{"Тоҷикистон", "Tojikiston"},
+		{"Αθήνα", "Athina"},
+		{"თბილისი", "tbilisi"},
+		{"Երևան", "Erevan"},
+		{"دمشق", "dmshq"},
+		{"Mix Café", "Mix Cafe"},
+		{"東京", "東京"},
+		{"", ""},
+	} {
+		assert.Equal(t, c.romanized, String(c.native), c.native)
+	}
+}
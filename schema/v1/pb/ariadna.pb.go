@@ -0,0 +1,994 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: ariadna.proto
+
+package pb
+
+import (
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type Results struct {
+	SchemaVersion        int32              `protobuf:"varint,1,opt,name=schema_version,json=schemaVersion,proto3" json:"schema_version,omitempty"`
+	TimedOut             bool               `protobuf:"varint,2,opt,name=timed_out,json=timedOut,proto3" json:"timed_out,omitempty"`
+	Results              []*Address         `protobuf:"bytes,3,rep,name=results,proto3" json:"results,omitempty"`
+	Facets               map[string]*Facets `protobuf:"bytes,4,rep,name=facets,proto3" json:"facets,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *Results) Reset()         { *m = Results{} }
+func (m *Results) String() string { return proto.CompactTextString(m) }
+func (*Results) ProtoMessage()    {}
+func (*Results) Descriptor() ([]byte, []int) {
+	return fileDescriptor_524d2da9c792bce3, []int{0}
+}
+
+func (m *Results) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Results.Unmarshal(m, b)
+}
+func (m *Results) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Results.Marshal(b, m, deterministic)
+}
+func (m *Results) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Results.Merge(m, src)
+}
+func (m *Results) XXX_Size() int {
+	return xxx_messageInfo_Results.Size(m)
+}
+func (m *Results) XXX_DiscardUnknown() {
+	xxx_messageInfo_Results.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Results proto.InternalMessageInfo
+
+func (m *Results) GetSchemaVersion() int32 {
+	if m != nil {
+		return m.SchemaVersion
+	}
+	return 0
+}
+
+func (m *Results) GetTimedOut() bool {
+	if m != nil {
+		return m.TimedOut
+	}
+	return false
+}
+
+func (m *Results) GetResults() []*Address {
+	if m != nil {
+		return m.Results
+	}
+	return nil
+}
+
+func (m *Results) GetFacets() map[string]*Facets {
+	if m != nil {
+		return m.Facets
+	}
+	return nil
+}
+
+// Facets are buckets of field values, the most frequent go first
+type Facets struct {
+	Buckets              []*Facet `protobuf:"bytes,1,rep,name=buckets,proto3" json:"buckets,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Facets) Reset()         { *m = Facets{} }
+func (m *Facets) String() string { return proto.CompactTextString(m) }
+func (*Facets) ProtoMessage()    {}
+func (*Facets) Descriptor() ([]byte, []int) {
+	return fileDescriptor_524d2da9c792bce3, []int{1}
+}
+
+func (m *Facets) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Facets.Unmarshal(m, b)
+}
+func (m *Facets) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Facets.Marshal(b, m, deterministic)
+}
+func (m *Facets) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Facets.Merge(m, src)
+}
+func (m *Facets) XXX_Size() int {
+	return xxx_messageInfo_Facets.Size(m)
+}
+func (m *Facets) XXX_DiscardUnknown() {
+	xxx_messageInfo_Facets.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Facets proto.InternalMessageInfo
+
+func (m *Facets) GetBuckets() []*Facet {
+	if m != nil {
+		return m.Buckets
+	}
+	return nil
+}
+
+type Facet struct {
+	Value                string   `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	Count                int64    `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Facet) Reset()         { *m = Facet{} }
+func (m *Facet) String() string { return proto.CompactTextString(m) }
+func (*Facet) ProtoMessage()    {}
+func (*Facet) Descriptor() ([]byte, []int) {
+	return fileDescriptor_524d2da9c792bce3, []int{2}
+}
+
+func (m *Facet) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Facet.Unmarshal(m, b)
+}
+func (m *Facet) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Facet.Marshal(b, m, deterministic)
+}
+func (m *Facet) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Facet.Merge(m, src)
+}
+func (m *Facet) XXX_Size() int {
+	return xxx_messageInfo_Facet.Size(m)
+}
+func (m *Facet) XXX_DiscardUnknown() {
+	xxx_messageInfo_Facet.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Facet proto.InternalMessageInfo
+
+func (m *Facet) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
+func (m *Facet) GetCount() int64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+type Address struct {
+	Id           string      `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name         string      `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Country      string      `protobuf:"bytes,3,opt,name=country,proto3" json:"country,omitempty"`
+	City         string      `protobuf:"bytes,4,opt,name=city,proto3" json:"city,omitempty"`
+	Town         string      `protobuf:"bytes,5,opt,name=town,proto3" json:"town,omitempty"`
+	Village      string      `protobuf:"bytes,6,opt,name=village,proto3" json:"village,omitempty"`
+	District     string      `protobuf:"bytes,7,opt,name=district,proto3" json:"district,omitempty"`
+	StreetPrefix string      `protobuf:"bytes,8,opt,name=street_prefix,json=streetPrefix,proto3" json:"street_prefix,omitempty"`
+	Street       string      `protobuf:"bytes,9,opt,name=street,proto3" json:"street,omitempty"`
+	HouseNumber  string      `protobuf:"bytes,10,opt,name=house_number,json=houseNumber,proto3" json:"house_number,omitempty"`
+	Unit         string      `protobuf:"bytes,11,opt,name=unit,proto3" json:"unit,omitempty"`
+	Flats        string      `protobuf:"bytes,12,opt,name=flats,proto3" json:"flats,omitempty"`
+	Door         string      `protobuf:"bytes,13,opt,name=door,proto3" json:"door,omitempty"`
+	Intersection bool        `protobuf:"varint,14,opt,name=intersection,proto3" json:"intersection,omitempty"`
+	Location     *Location   `protobuf:"bytes,15,opt,name=location,proto3" json:"location,omitempty"`
+	Entrances    []*Entrance `protobuf:"bytes,16,rep,name=entrances,proto3" json:"entrances,omitempty"`
+	Layer        string      `protobuf:"bytes,17,opt,name=layer,proto3" json:"layer,omitempty"`
+	Category     string      `protobuf:"bytes,18,opt,name=category,proto3" json:"category,omitempty"`
+	Routes       []string    `protobuf:"bytes,19,rep,name=routes,proto3" json:"routes,omitempty"`
+	Wikidata     *Wikidata   `protobuf:"bytes,20,opt,name=wikidata,proto3" json:"wikidata,omitempty"`
+	Wikipedia    string      `protobuf:"bytes,21,opt,name=wikipedia,proto3" json:"wikipedia,omitempty"`
+	// meters, oneof tells missing elevation from zero one
+	//
+	// Types that are valid to be assigned to ElevationValue:
+	//	*Address_Elevation
+	ElevationValue isAddress_ElevationValue `protobuf_oneof:"elevation_value"`
+	Timezone       string                   `protobuf:"bytes,23,opt,name=timezone,proto3" json:"timezone,omitempty"`
+	PlusCode       string                   `protobuf:"bytes,24,opt,name=plus_code,json=plusCode,proto3" json:"plus_code,omitempty"`
+	Geohash        string                   `protobuf:"bytes,25,opt,name=geohash,proto3" json:"geohash,omitempty"`
+	// GeoJSON geometry object
+	Geometry string `protobuf:"bytes,26,opt,name=geometry,proto3" json:"geometry,omitempty"`
+	// JSON object of fields extracted from tags
+	Fields string `protobuf:"bytes,27,opt,name=fields,proto3" json:"fields,omitempty"`
+	// external geocoder which found address, empty for own index
+	Source string `protobuf:"bytes,28,opt,name=source,proto3" json:"source,omitempty"`
+	Road   *Road  `protobuf:"bytes,29,opt,name=road,proto3" json:"road,omitempty"`
+	// square meters, polygons only
+	Area     float64   `protobuf:"fixed64,30,opt,name=area,proto3" json:"area,omitempty"`
+	Centroid *Location `protobuf:"bytes,31,opt,name=centroid,proto3" json:"centroid,omitempty"`
+	// pole of inaccessibility, good marker position inside polygon
+	LabelPoint *Location `protobuf:"bytes,32,opt,name=label_point,json=labelPoint,proto3" json:"label_point,omitempty"`
+	// geometry is concave hull of boundary which members don't close into rings
+	Approximate bool `protobuf:"varint,33,opt,name=approximate,proto3" json:"approximate,omitempty"`
+	// language of name picked by lang parameter, empty for the default name
+	Language string `protobuf:"bytes,34,opt,name=language,proto3" json:"language,omitempty"`
+	// name or street address in Latin script, set by romanize parameter
+	LabelRomanized string `protobuf:"bytes,35,opt,name=label_romanized,json=labelRomanized,proto3" json:"label_romanized,omitempty"`
+	// suggested map extent to show the feature
+	Viewport *BBox     `protobuf:"bytes,36,opt,name=viewport,proto3" json:"viewport,omitempty"`
+	Vertical *Vertical `protobuf:"bytes,37,opt,name=vertical,proto3" json:"vertical,omitempty"`
+	// how result was found when it is not nearest feature, e.g. fallback_place
+	MatchType            string   `protobuf:"bytes,38,opt,name=match_type,json=matchType,proto3" json:"match_type,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Address) Reset()         { *m = Address{} }
+func (m *Address) String() string { return proto.CompactTextString(m) }
+func (*Address) ProtoMessage()    {}
+func (*Address) Descriptor() ([]byte, []int) {
+	return fileDescriptor_524d2da9c792bce3, []int{3}
+}
+
+func (m *Address) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Address.Unmarshal(m, b)
+}
+func (m *Address) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Address.Marshal(b, m, deterministic)
+}
+func (m *Address) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Address.Merge(m, src)
+}
+func (m *Address) XXX_Size() int {
+	return xxx_messageInfo_Address.Size(m)
+}
+func (m *Address) XXX_DiscardUnknown() {
+	xxx_messageInfo_Address.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Address proto.InternalMessageInfo
+
+func (m *Address) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Address) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Address) GetCountry() string {
+	if m != nil {
+		return m.Country
+	}
+	return ""
+}
+
+func (m *Address) GetCity() string {
+	if m != nil {
+		return m.City
+	}
+	return ""
+}
+
+func (m *Address) GetTown() string {
+	if m != nil {
+		return m.Town
+	}
+	return ""
+}
+
+func (m *Address) GetVillage() string {
+	if m != nil {
+		return m.Village
+	}
+	return ""
+}
+
+func (m *Address) GetDistrict() string {
+	if m != nil {
+		return m.District
+	}
+	return ""
+}
+
+func (m *Address) GetStreetPrefix() string {
+	if m != nil {
+		return m.StreetPrefix
+	}
+	return ""
+}
+
+func (m *Address) GetStreet() string {
+	if m != nil {
+		return m.Street
+	}
+	return ""
+}
+
+func (m *Address) GetHouseNumber() string {
+	if m != nil {
+		return m.HouseNumber
+	}
+	return ""
+}
+
+func (m *Address) GetUnit() string {
+	if m != nil {
+		return m.Unit
+	}
+	return ""
+}
+
+func (m *Address) GetFlats() string {
+	if m != nil {
+		return m.Flats
+	}
+	return ""
+}
+
+func (m *Address) GetDoor() string {
+	if m != nil {
+		return m.Door
+	}
+	return ""
+}
+
+func (m *Address) GetIntersection() bool {
+	if m != nil {
+		return m.Intersection
+	}
+	return false
+}
+
+func (m *Address) GetLocation() *Location {
+	if m != nil {
+		return m.Location
+	}
+	return nil
+}
+
+func (m *Address) GetEntrances() []*Entrance {
+	if m != nil {
+		return m.Entrances
+	}
+	return nil
+}
+
+func (m *Address) GetLayer() string {
+	if m != nil {
+		return m.Layer
+	}
+	return ""
+}
+
+func (m *Address) GetCategory() string {
+	if m != nil {
+		return m.Category
+	}
+	return ""
+}
+
+func (m *Address) GetRoutes() []string {
+	if m != nil {
+		return m.Routes
+	}
+	return nil
+}
+
+func (m *Address) GetWikidata() *Wikidata {
+	if m != nil {
+		return m.Wikidata
+	}
+	return nil
+}
+
+func (m *Address) GetWikipedia() string {
+	if m != nil {
+		return m.Wikipedia
+	}
+	return ""
+}
+
+type isAddress_ElevationValue interface {
+	isAddress_ElevationValue()
+}
+
+type Address_Elevation struct {
+	Elevation float64 `protobuf:"fixed64,22,opt,name=elevation,proto3,oneof"`
+}
+
+func (*Address_Elevation) isAddress_ElevationValue() {}
+
+func (m *Address) GetElevationValue() isAddress_ElevationValue {
+	if m != nil {
+		return m.ElevationValue
+	}
+	return nil
+}
+
+func (m *Address) GetElevation() float64 {
+	if x, ok := m.GetElevationValue().(*Address_Elevation); ok {
+		return x.Elevation
+	}
+	return 0
+}
+
+func (m *Address) GetTimezone() string {
+	if m != nil {
+		return m.Timezone
+	}
+	return ""
+}
+
+func (m *Address) GetPlusCode() string {
+	if m != nil {
+		return m.PlusCode
+	}
+	return ""
+}
+
+func (m *Address) GetGeohash() string {
+	if m != nil {
+		return m.Geohash
+	}
+	return ""
+}
+
+func (m *Address) GetGeometry() string {
+	if m != nil {
+		return m.Geometry
+	}
+	return ""
+}
+
+func (m *Address) GetFields() string {
+	if m != nil {
+		return m.Fields
+	}
+	return ""
+}
+
+func (m *Address) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+func (m *Address) GetRoad() *Road {
+	if m != nil {
+		return m.Road
+	}
+	return nil
+}
+
+func (m *Address) GetArea() float64 {
+	if m != nil {
+		return m.Area
+	}
+	return 0
+}
+
+func (m *Address) GetCentroid() *Location {
+	if m != nil {
+		return m.Centroid
+	}
+	return nil
+}
+
+func (m *Address) GetLabelPoint() *Location {
+	if m != nil {
+		return m.LabelPoint
+	}
+	return nil
+}
+
+func (m *Address) GetApproximate() bool {
+	if m != nil {
+		return m.Approximate
+	}
+	return false
+}
+
+func (m *Address) GetLanguage() string {
+	if m != nil {
+		return m.Language
+	}
+	return ""
+}
+
+func (m *Address) GetLabelRomanized() string {
+	if m != nil {
+		return m.LabelRomanized
+	}
+	return ""
+}
+
+func (m *Address) GetViewport() *BBox {
+	if m != nil {
+		return m.Viewport
+	}
+	return nil
+}
+
+func (m *Address) GetVertical() *Vertical {
+	if m != nil {
+		return m.Vertical
+	}
+	return nil
+}
+
+func (m *Address) GetMatchType() string {
+	if m != nil {
+		return m.MatchType
+	}
+	return ""
+}
+
+// XXX_OneofWrappers is for the internal use of the proto package.
+func (*Address) XXX_OneofWrappers() []interface{} {
+	return []interface{}{
+		(*Address_Elevation)(nil),
+	}
+}
+
+// position among stacked features
+type Vertical struct {
+	// OSM layer tag, negative under ground
+	Layer  int32 `protobuf:"varint,1,opt,name=layer,proto3" json:"layer,omitempty"`
+	Bridge bool  `protobuf:"varint,2,opt,name=bridge,proto3" json:"bridge,omitempty"`
+	Tunnel bool  `protobuf:"varint,3,opt,name=tunnel,proto3" json:"tunnel,omitempty"`
+	// floors of building or station
+	Levels               []float64 `protobuf:"fixed64,4,rep,packed,name=levels,proto3" json:"levels,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *Vertical) Reset()         { *m = Vertical{} }
+func (m *Vertical) String() string { return proto.CompactTextString(m) }
+func (*Vertical) ProtoMessage()    {}
+func (*Vertical) Descriptor() ([]byte, []int) {
+	return fileDescriptor_524d2da9c792bce3, []int{4}
+}
+
+func (m *Vertical) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Vertical.Unmarshal(m, b)
+}
+func (m *Vertical) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Vertical.Marshal(b, m, deterministic)
+}
+func (m *Vertical) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Vertical.Merge(m, src)
+}
+func (m *Vertical) XXX_Size() int {
+	return xxx_messageInfo_Vertical.Size(m)
+}
+func (m *Vertical) XXX_DiscardUnknown() {
+	xxx_messageInfo_Vertical.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Vertical proto.InternalMessageInfo
+
+func (m *Vertical) GetLayer() int32 {
+	if m != nil {
+		return m.Layer
+	}
+	return 0
+}
+
+func (m *Vertical) GetBridge() bool {
+	if m != nil {
+		return m.Bridge
+	}
+	return false
+}
+
+func (m *Vertical) GetTunnel() bool {
+	if m != nil {
+		return m.Tunnel
+	}
+	return false
+}
+
+func (m *Vertical) GetLevels() []float64 {
+	if m != nil {
+		return m.Levels
+	}
+	return nil
+}
+
+type Road struct {
+	// km/h
+	Maxspeed int32  `protobuf:"varint,1,opt,name=maxspeed,proto3" json:"maxspeed,omitempty"`
+	Surface  string `protobuf:"bytes,2,opt,name=surface,proto3" json:"surface,omitempty"`
+	Lanes    int32  `protobuf:"varint,3,opt,name=lanes,proto3" json:"lanes,omitempty"`
+	// forward or backward relative to way direction, empty for two-way roads
+	Oneway               string   `protobuf:"bytes,4,opt,name=oneway,proto3" json:"oneway,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Road) Reset()         { *m = Road{} }
+func (m *Road) String() string { return proto.CompactTextString(m) }
+func (*Road) ProtoMessage()    {}
+func (*Road) Descriptor() ([]byte, []int) {
+	return fileDescriptor_524d2da9c792bce3, []int{5}
+}
+
+func (m *Road) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Road.Unmarshal(m, b)
+}
+func (m *Road) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Road.Marshal(b, m, deterministic)
+}
+func (m *Road) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Road.Merge(m, src)
+}
+func (m *Road) XXX_Size() int {
+	return xxx_messageInfo_Road.Size(m)
+}
+func (m *Road) XXX_DiscardUnknown() {
+	xxx_messageInfo_Road.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Road proto.InternalMessageInfo
+
+func (m *Road) GetMaxspeed() int32 {
+	if m != nil {
+		return m.Maxspeed
+	}
+	return 0
+}
+
+func (m *Road) GetSurface() string {
+	if m != nil {
+		return m.Surface
+	}
+	return ""
+}
+
+func (m *Road) GetLanes() int32 {
+	if m != nil {
+		return m.Lanes
+	}
+	return 0
+}
+
+func (m *Road) GetOneway() string {
+	if m != nil {
+		return m.Oneway
+	}
+	return ""
+}
+
+// bounding box in WGS84 degrees
+type BBox struct {
+	MinLat               float64  `protobuf:"fixed64,1,opt,name=min_lat,json=minLat,proto3" json:"min_lat,omitempty"`
+	MinLon               float64  `protobuf:"fixed64,2,opt,name=min_lon,json=minLon,proto3" json:"min_lon,omitempty"`
+	MaxLat               float64  `protobuf:"fixed64,3,opt,name=max_lat,json=maxLat,proto3" json:"max_lat,omitempty"`
+	MaxLon               float64  `protobuf:"fixed64,4,opt,name=max_lon,json=maxLon,proto3" json:"max_lon,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BBox) Reset()         { *m = BBox{} }
+func (m *BBox) String() string { return proto.CompactTextString(m) }
+func (*BBox) ProtoMessage()    {}
+func (*BBox) Descriptor() ([]byte, []int) {
+	return fileDescriptor_524d2da9c792bce3, []int{6}
+}
+
+func (m *BBox) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BBox.Unmarshal(m, b)
+}
+func (m *BBox) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BBox.Marshal(b, m, deterministic)
+}
+func (m *BBox) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BBox.Merge(m, src)
+}
+func (m *BBox) XXX_Size() int {
+	return xxx_messageInfo_BBox.Size(m)
+}
+func (m *BBox) XXX_DiscardUnknown() {
+	xxx_messageInfo_BBox.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BBox proto.InternalMessageInfo
+
+func (m *BBox) GetMinLat() float64 {
+	if m != nil {
+		return m.MinLat
+	}
+	return 0
+}
+
+func (m *BBox) GetMinLon() float64 {
+	if m != nil {
+		return m.MinLon
+	}
+	return 0
+}
+
+func (m *BBox) GetMaxLat() float64 {
+	if m != nil {
+		return m.MaxLat
+	}
+	return 0
+}
+
+func (m *BBox) GetMaxLon() float64 {
+	if m != nil {
+		return m.MaxLon
+	}
+	return 0
+}
+
+type Location struct {
+	Lat                  float64  `protobuf:"fixed64,1,opt,name=lat,proto3" json:"lat,omitempty"`
+	Lon                  float64  `protobuf:"fixed64,2,opt,name=lon,proto3" json:"lon,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Location) Reset()         { *m = Location{} }
+func (m *Location) String() string { return proto.CompactTextString(m) }
+func (*Location) ProtoMessage()    {}
+func (*Location) Descriptor() ([]byte, []int) {
+	return fileDescriptor_524d2da9c792bce3, []int{7}
+}
+
+func (m *Location) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Location.Unmarshal(m, b)
+}
+func (m *Location) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Location.Marshal(b, m, deterministic)
+}
+func (m *Location) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Location.Merge(m, src)
+}
+func (m *Location) XXX_Size() int {
+	return xxx_messageInfo_Location.Size(m)
+}
+func (m *Location) XXX_DiscardUnknown() {
+	xxx_messageInfo_Location.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Location proto.InternalMessageInfo
+
+func (m *Location) GetLat() float64 {
+	if m != nil {
+		return m.Lat
+	}
+	return 0
+}
+
+func (m *Location) GetLon() float64 {
+	if m != nil {
+		return m.Lon
+	}
+	return 0
+}
+
+type Entrance struct {
+	Type                 string    `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Ref                  string    `protobuf:"bytes,2,opt,name=ref,proto3" json:"ref,omitempty"`
+	Location             *Location `protobuf:"bytes,3,opt,name=location,proto3" json:"location,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *Entrance) Reset()         { *m = Entrance{} }
+func (m *Entrance) String() string { return proto.CompactTextString(m) }
+func (*Entrance) ProtoMessage()    {}
+func (*Entrance) Descriptor() ([]byte, []int) {
+	return fileDescriptor_524d2da9c792bce3, []int{8}
+}
+
+func (m *Entrance) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Entrance.Unmarshal(m, b)
+}
+func (m *Entrance) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Entrance.Marshal(b, m, deterministic)
+}
+func (m *Entrance) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Entrance.Merge(m, src)
+}
+func (m *Entrance) XXX_Size() int {
+	return xxx_messageInfo_Entrance.Size(m)
+}
+func (m *Entrance) XXX_DiscardUnknown() {
+	xxx_messageInfo_Entrance.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Entrance proto.InternalMessageInfo
+
+func (m *Entrance) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *Entrance) GetRef() string {
+	if m != nil {
+		return m.Ref
+	}
+	return ""
+}
+
+func (m *Entrance) GetLocation() *Location {
+	if m != nil {
+		return m.Location
+	}
+	return nil
+}
+
+type Wikidata struct {
+	Id                   string            `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Labels               map[string]string `protobuf:"bytes,2,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Population           int64             `protobuf:"varint,3,opt,name=population,proto3" json:"population,omitempty"`
+	Sitelinks            int32             `protobuf:"varint,4,opt,name=sitelinks,proto3" json:"sitelinks,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *Wikidata) Reset()         { *m = Wikidata{} }
+func (m *Wikidata) String() string { return proto.CompactTextString(m) }
+func (*Wikidata) ProtoMessage()    {}
+func (*Wikidata) Descriptor() ([]byte, []int) {
+	return fileDescriptor_524d2da9c792bce3, []int{9}
+}
+
+func (m *Wikidata) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Wikidata.Unmarshal(m, b)
+}
+func (m *Wikidata) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Wikidata.Marshal(b, m, deterministic)
+}
+func (m *Wikidata) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Wikidata.Merge(m, src)
+}
+func (m *Wikidata) XXX_Size() int {
+	return xxx_messageInfo_Wikidata.Size(m)
+}
+func (m *Wikidata) XXX_DiscardUnknown() {
+	xxx_messageInfo_Wikidata.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Wikidata proto.InternalMessageInfo
+
+func (m *Wikidata) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Wikidata) GetLabels() map[string]string {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
+func (m *Wikidata) GetPopulation() int64 {
+	if m != nil {
+		return m.Population
+	}
+	return 0
+}
+
+func (m *Wikidata) GetSitelinks() int32 {
+	if m != nil {
+		return m.Sitelinks
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterType((*Results)(nil), "ariadna.v1.Results")
+	proto.RegisterMapType((map[string]*Facets)(nil), "ariadna.v1.Results.FacetsEntry")
+	proto.RegisterType((*Facets)(nil), "ariadna.v1.Facets")
+	proto.RegisterType((*Facet)(nil), "ariadna.v1.Facet")
+	proto.RegisterType((*Address)(nil), "ariadna.v1.Address")
+	proto.RegisterType((*Vertical)(nil), "ariadna.v1.Vertical")
+	proto.RegisterType((*Road)(nil), "ariadna.v1.Road")
+	proto.RegisterType((*BBox)(nil), "ariadna.v1.BBox")
+	proto.RegisterType((*Location)(nil), "ariadna.v1.Location")
+	proto.RegisterType((*Entrance)(nil), "ariadna.v1.Entrance")
+	proto.RegisterType((*Wikidata)(nil), "ariadna.v1.Wikidata")
+	proto.RegisterMapType((map[string]string)(nil), "ariadna.v1.Wikidata.LabelsEntry")
+}
+
+func init() { proto.RegisterFile("ariadna.proto", fileDescriptor_524d2da9c792bce3) }
+
+var fileDescriptor_524d2da9c792bce3 = []byte{
+	// 1103 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x56, 0x4d, 0x6f, 0x1b, 0x37,
+	0x13, 0x7e, 0xf5, 0x61, 0x7d, 0x8c, 0x6c, 0x27, 0x61, 0xfc, 0x26, 0xac, 0xf3, 0xa5, 0x28, 0x49,
+	0xeb, 0x22, 0xad, 0xdc, 0x38, 0x08, 0x9a, 0xf6, 0x56, 0x17, 0x29, 0x7a, 0x70, 0xdb, 0x80, 0x28,
+	0x52, 0xa0, 0x17, 0x81, 0xda, 0x1d, 0x4b, 0xac, 0x77, 0xc9, 0x05, 0xc9, 0x95, 0xa5, 0x9c, 0xfb,
+	0xcb, 0x7a, 0xef, 0x7f, 0x2a, 0x86, 0xdc, 0x95, 0xe4, 0x3a, 0x08, 0x7a, 0x9b, 0xe7, 0x99, 0x87,
+	0xe4, 0x70, 0x38, 0x3b, 0xb3, 0xb0, 0x27, 0xad, 0x92, 0xa9, 0x96, 0xe3, 0xc2, 0x1a, 0x6f, 0x18,
+	0xd4, 0x70, 0xf1, 0x62, 0xf4, 0x67, 0x13, 0xba, 0x02, 0x5d, 0x99, 0x79, 0xc7, 0x9e, 0xc1, 0xbe,
+	0x4b, 0xe6, 0x98, 0xcb, 0xc9, 0x02, 0xad, 0x53, 0x46, 0xf3, 0xc6, 0xb0, 0x71, 0xb4, 0x23, 0xf6,
+	0x22, 0xfb, 0x2e, 0x92, 0xec, 0x1e, 0xf4, 0xbd, 0xca, 0x31, 0x9d, 0x98, 0xd2, 0xf3, 0xe6, 0xb0,
+	0x71, 0xd4, 0x13, 0xbd, 0x40, 0xfc, 0x52, 0x7a, 0xf6, 0x25, 0x74, 0x6d, 0xdc, 0x8e, 0xb7, 0x86,
+	0xad, 0xa3, 0xc1, 0xc9, 0xed, 0xf1, 0xe6, 0xb4, 0xf1, 0x77, 0x69, 0x6a, 0xd1, 0x39, 0x51, 0x6b,
+	0xd8, 0xd7, 0xd0, 0x39, 0x97, 0x09, 0x7a, 0xc7, 0xdb, 0x41, 0xfd, 0x68, 0x5b, 0x5d, 0xc5, 0x35,
+	0xfe, 0x21, 0x28, 0xde, 0x68, 0x6f, 0x57, 0xa2, 0x92, 0x1f, 0xfe, 0x04, 0x83, 0x2d, 0x9a, 0xdd,
+	0x84, 0xd6, 0x05, 0xae, 0x42, 0xbc, 0x7d, 0x41, 0x26, 0x3b, 0x82, 0x9d, 0x85, 0xcc, 0x4a, 0x0c,
+	0x11, 0x0e, 0x4e, 0xd8, 0xf6, 0xc6, 0x71, 0xa5, 0x88, 0x82, 0x6f, 0x9b, 0xaf, 0x1b, 0xa3, 0x57,
+	0xd0, 0x89, 0x24, 0x7b, 0x0e, 0xdd, 0x69, 0x99, 0x5c, 0x50, 0x48, 0x8d, 0x10, 0xd2, 0xad, 0x6b,
+	0x2b, 0x45, 0xad, 0x18, 0xbd, 0x84, 0x9d, 0xc0, 0xb0, 0x83, 0xfa, 0xb4, 0x18, 0x41, 0x04, 0xc4,
+	0x26, 0xa6, 0xd4, 0x31, 0x4b, 0x2d, 0x11, 0xc1, 0xe8, 0xaf, 0x3e, 0x74, 0xab, 0x44, 0xb0, 0x7d,
+	0x68, 0xaa, 0xb4, 0x5a, 0xd4, 0x54, 0x29, 0x63, 0xd0, 0xd6, 0x32, 0x8f, 0x41, 0xf7, 0x45, 0xb0,
+	0x19, 0x87, 0x6e, 0x58, 0x68, 0x57, 0xbc, 0x15, 0xe8, 0x1a, 0x92, 0x3a, 0x51, 0x7e, 0xc5, 0xdb,
+	0x51, 0x4d, 0x36, 0x71, 0xde, 0x5c, 0x6a, 0xbe, 0x13, 0x39, 0xb2, 0x69, 0x87, 0x85, 0xca, 0x32,
+	0x39, 0x43, 0xde, 0x89, 0x3b, 0x54, 0x90, 0x1d, 0x42, 0x2f, 0x55, 0xce, 0x5b, 0x95, 0x78, 0xde,
+	0x0d, 0xae, 0x35, 0x66, 0x4f, 0x60, 0xcf, 0x79, 0x8b, 0xe8, 0x27, 0x85, 0xc5, 0x73, 0xb5, 0xe4,
+	0xbd, 0x20, 0xd8, 0x8d, 0xe4, 0xdb, 0xc0, 0xb1, 0x3b, 0xd0, 0x89, 0x98, 0xf7, 0x83, 0xb7, 0x42,
+	0xec, 0x31, 0xec, 0xce, 0x4d, 0xe9, 0x70, 0xa2, 0xcb, 0x7c, 0x8a, 0x96, 0x43, 0xf0, 0x0e, 0x02,
+	0xf7, 0x73, 0xa0, 0x28, 0xd2, 0x52, 0x2b, 0xcf, 0x07, 0x31, 0x52, 0xb2, 0x29, 0x63, 0xe7, 0x99,
+	0xf4, 0x8e, 0xef, 0xc6, 0x3c, 0x06, 0x40, 0xca, 0xd4, 0x18, 0xcb, 0xf7, 0xa2, 0x92, 0x6c, 0x36,
+	0x82, 0x5d, 0xa5, 0x3d, 0x5a, 0x87, 0x89, 0xa7, 0x52, 0xdd, 0x0f, 0x85, 0x78, 0x85, 0x63, 0x5f,
+	0x41, 0x2f, 0x33, 0x89, 0x0c, 0xfe, 0x1b, 0xa1, 0x0c, 0x0e, 0xb6, 0x1f, 0xf3, 0xac, 0xf2, 0x89,
+	0xb5, 0x8a, 0x9d, 0x40, 0x1f, 0xb5, 0xb7, 0x52, 0x27, 0xe8, 0xf8, 0xcd, 0x61, 0xeb, 0xdf, 0x4b,
+	0xde, 0x54, 0x4e, 0xb1, 0x91, 0x51, 0xcc, 0x99, 0x5c, 0xa1, 0xe5, 0xb7, 0x62, 0xcc, 0x01, 0x50,
+	0x66, 0x13, 0xe9, 0x71, 0x66, 0xec, 0x8a, 0xb3, 0x98, 0xd9, 0x1a, 0x53, 0xd2, 0xac, 0x29, 0x3d,
+	0x3a, 0x7e, 0x7b, 0xd8, 0xa2, 0xa4, 0x45, 0x44, 0xf1, 0x5e, 0xaa, 0x0b, 0x95, 0x4a, 0x2f, 0xf9,
+	0xc1, 0xf5, 0x78, 0x7f, 0xab, 0x7c, 0x62, 0xad, 0x62, 0xf7, 0xa1, 0x4f, 0x76, 0x81, 0xa9, 0x92,
+	0xfc, 0xff, 0xe1, 0x98, 0x0d, 0xc1, 0x1e, 0x42, 0x1f, 0x33, 0x5c, 0xc4, 0x04, 0xdc, 0x19, 0x36,
+	0x8e, 0x1a, 0x3f, 0xfe, 0x4f, 0x6c, 0x28, 0x8a, 0x91, 0x3e, 0xdc, 0xf7, 0x46, 0x23, 0xbf, 0x1b,
+	0x63, 0xac, 0x31, 0x7d, 0xe5, 0x45, 0x56, 0xba, 0x49, 0x62, 0x52, 0xe4, 0x3c, 0x3a, 0x89, 0xf8,
+	0xde, 0xa4, 0xa1, 0x24, 0x67, 0x68, 0xe6, 0xd2, 0xcd, 0xf9, 0x27, 0xb1, 0xa0, 0x2a, 0x48, 0x5b,
+	0xce, 0xd0, 0xe4, 0x48, 0xd5, 0x7a, 0x18, 0x57, 0xd5, 0x98, 0xae, 0x7d, 0xae, 0x30, 0x4b, 0x1d,
+	0xbf, 0x17, 0x6b, 0x25, 0x22, 0xe2, 0x9d, 0x29, 0x6d, 0x82, 0xfc, 0x7e, 0xe4, 0x23, 0x62, 0x4f,
+	0xa1, 0x6d, 0x8d, 0x4c, 0xf9, 0x83, 0x90, 0x8a, 0x9b, 0x57, 0x5a, 0x83, 0x91, 0xa9, 0x08, 0x5e,
+	0x2a, 0x0e, 0x69, 0x51, 0xf2, 0x87, 0x74, 0x3f, 0x11, 0x6c, 0x4a, 0x64, 0x42, 0x0f, 0x64, 0x54,
+	0xca, 0x1f, 0x7d, 0xec, 0xe1, 0x6b, 0x15, 0x7b, 0x05, 0x83, 0x4c, 0x4e, 0x31, 0x9b, 0x14, 0x46,
+	0x69, 0xcf, 0x87, 0x1f, 0x59, 0x04, 0x41, 0xf8, 0x96, 0x74, 0x6c, 0x08, 0x03, 0x59, 0x14, 0xd6,
+	0x2c, 0x55, 0x2e, 0x3d, 0xf2, 0xc7, 0xa1, 0x08, 0xb7, 0x29, 0x4a, 0x48, 0x26, 0xf5, 0xac, 0xa4,
+	0x8f, 0x6f, 0x14, 0x13, 0x52, 0x63, 0xf6, 0x19, 0xdc, 0x88, 0x87, 0x5a, 0x93, 0x4b, 0xad, 0xde,
+	0x63, 0xca, 0x9f, 0x04, 0xc9, 0x7e, 0xa0, 0x45, 0xcd, 0xb2, 0x2f, 0xa0, 0xb7, 0x50, 0x78, 0x59,
+	0x18, 0xeb, 0xf9, 0xd3, 0xeb, 0xd9, 0x38, 0x3d, 0x35, 0x4b, 0xb1, 0x56, 0xd0, 0xed, 0x17, 0x68,
+	0xbd, 0x4a, 0x64, 0xc6, 0x9f, 0x5d, 0xbf, 0xc8, 0xbb, 0xca, 0x27, 0xd6, 0x2a, 0xf6, 0x00, 0x20,
+	0x97, 0x3e, 0x99, 0x4f, 0xfc, 0xaa, 0x40, 0xfe, 0x69, 0xac, 0xa3, 0xc0, 0xfc, 0xba, 0x2a, 0xf0,
+	0xf4, 0x16, 0xdc, 0x58, 0x17, 0xcd, 0x24, 0xb4, 0xb6, 0xd1, 0x1c, 0x7a, 0xf5, 0x3e, 0x9b, 0x0f,
+	0x20, 0x8e, 0x8b, 0x08, 0xe8, 0x55, 0xa7, 0x56, 0xa5, 0x33, 0xac, 0x66, 0x44, 0x85, 0x88, 0xf7,
+	0xa5, 0xd6, 0x98, 0x85, 0x6e, 0xd6, 0x13, 0x15, 0x22, 0x3e, 0xc3, 0x05, 0x66, 0x71, 0x14, 0x34,
+	0x44, 0x85, 0x46, 0x7f, 0x40, 0x9b, 0x5e, 0x9b, 0x12, 0x99, 0xcb, 0xa5, 0x2b, 0x10, 0xd3, 0xea,
+	0xa0, 0x35, 0xa6, 0x7a, 0x74, 0xa5, 0xa5, 0xd1, 0x50, 0x75, 0xce, 0x1a, 0xc6, 0xd8, 0x34, 0x3a,
+	0xde, 0xaa, 0x63, 0xd3, 0x18, 0x2a, 0xce, 0x68, 0xbc, 0x94, 0x75, 0xeb, 0xac, 0x10, 0x9d, 0x45,
+	0xb9, 0x64, 0x77, 0xa1, 0x9b, 0x2b, 0x3d, 0xc9, 0xa4, 0x0f, 0x47, 0x35, 0x44, 0x27, 0x57, 0xfa,
+	0x4c, 0xfa, 0xb5, 0xc3, 0x68, 0xde, 0xdc, 0x38, 0x8c, 0x0e, 0x0e, 0xb9, 0x0c, 0x2b, 0x5a, 0x95,
+	0x43, 0x2e, 0xeb, 0x15, 0xe4, 0x30, 0x9a, 0xb7, 0x37, 0x0e, 0xa3, 0x47, 0x63, 0xe8, 0xd5, 0x25,
+	0x45, 0xe3, 0x6b, 0x73, 0x16, 0x99, 0x81, 0x59, 0x1f, 0x42, 0xe6, 0x68, 0x0a, 0xbd, 0xba, 0xfb,
+	0x84, 0x26, 0x4f, 0x2f, 0xd5, 0xa8, 0x9a, 0xfc, 0xaa, 0x40, 0x5a, 0x61, 0xf1, 0xbc, 0xba, 0x3f,
+	0x99, 0x57, 0xda, 0x5f, 0xeb, 0xbf, 0xb4, 0xbf, 0xd1, 0xdf, 0x0d, 0xe8, 0xd5, 0x5d, 0xe6, 0xda,
+	0x6c, 0x7a, 0x0d, 0x9d, 0x50, 0x96, 0x8e, 0x37, 0x43, 0x63, 0x1c, 0x7e, 0xa8, 0x37, 0x8d, 0xcf,
+	0x82, 0xa4, 0x1a, 0xd6, 0x51, 0xcf, 0x1e, 0x02, 0x14, 0xa6, 0x28, 0xb3, 0x4d, 0x28, 0x2d, 0xb1,
+	0xc5, 0x50, 0x17, 0x73, 0xca, 0x63, 0xa6, 0xf4, 0x85, 0x0b, 0x59, 0xda, 0x11, 0x1b, 0xe2, 0xf0,
+	0x1b, 0x18, 0x6c, 0x6d, 0xfa, 0x81, 0x51, 0x7f, 0xb0, 0x3d, 0xea, 0xfb, 0x5b, 0x63, 0xfd, 0xf4,
+	0xf9, 0xef, 0x9f, 0xcf, 0x94, 0x9f, 0x97, 0xd3, 0x71, 0x62, 0xf2, 0xe3, 0x5c, 0xa6, 0x29, 0x2e,
+	0x9c, 0x32, 0xc7, 0x55, 0xe0, 0xc7, 0xf1, 0xc7, 0xe6, 0x78, 0xf1, 0xe2, 0xb8, 0x98, 0x4e, 0x3b,
+	0xe1, 0xef, 0xe8, 0xe5, 0x3f, 0x03, 0x00, 0x0b, 0x1c, 0xde, 0xc9, 0x2e, 0x09, 0x00, 0x00,
+}
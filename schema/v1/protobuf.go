@@ -0,0 +1,115 @@
+package v1
+
+import (
+	"encoding/json"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/maddevsio/ariadna/schema/v1/pb"
+)
+
+//go:generate protoc --go_out=paths=source_relative:pb ariadna.proto
+
+// MarshalProtobuf encodes results as Results message of ariadna.proto. Map entries are
+// ordered by key, so equal results get equal bytes
+func (r Results) MarshalProtobuf() ([]byte, error) {
+	m := &pb.Results{SchemaVersion: int32(r.SchemaVersion), TimedOut: r.TimedOut}
+	for _, a := range r.Results {
+		address, err := a.proto()
+		if err != nil {
+			return nil, err
+		}
+		m.Results = append(m.Results, address)
+	}
+	if len(r.Facets) > 0 {
+		m.Facets = make(map[string]*pb.Facets, len(r.Facets))
+		for field, buckets := range r.Facets {
+			facets := &pb.Facets{}
+			for _, b := range buckets {
+				facets.Buckets = append(facets.Buckets, &pb.Facet{Value: b.Value, Count: b.Count})
+			}
+			m.Facets[field] = facets
+		}
+	}
+	var buf proto.Buffer
+	buf.SetDeterministic(true)
+	if err := buf.Marshal(m); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (a Address) proto() (*pb.Address, error) {
+	m := &pb.Address{
+		Id:             a.ID,
+		Name:           a.Name,
+		Country:        a.Country,
+		City:           a.City,
+		Town:           a.Town,
+		Village:        a.Village,
+		District:       a.District,
+		StreetPrefix:   a.Prefix,
+		Street:         a.Street,
+		HouseNumber:    a.HouseNumber,
+		Unit:           a.Unit,
+		Flats:          a.Flats,
+		Door:           a.Door,
+		Intersection:   a.Intersection,
+		Location:       a.Location.proto(),
+		Layer:          a.Layer,
+		Category:       a.Category,
+		Routes:         a.Routes,
+		Wikipedia:      a.Wikipedia,
+		Timezone:       a.Timezone,
+		PlusCode:       a.PlusCode,
+		Geohash:        a.Geohash,
+		Source:         a.Source,
+		Area:           a.Area,
+		Approximate:    a.Approximate,
+		Language:       a.Language,
+		LabelRomanized: a.LabelRomanized,
+		MatchType:      a.MatchType,
+	}
+	for _, e := range a.Entrances {
+		m.Entrances = append(m.Entrances, &pb.Entrance{Type: e.Type, Ref: e.Ref, Location: e.Location.proto()})
+	}
+	if w := a.Wikidata; w != nil {
+		m.Wikidata = &pb.Wikidata{Id: w.ID, Labels: w.Labels, Population: w.Population, Sitelinks: int32(w.Sitelinks)}
+	}
+	if a.Elevation != nil {
+		m.ElevationValue = &pb.Address_Elevation{Elevation: *a.Elevation}
+	}
+	if a.Geometry != nil {
+		data, err := json.Marshal(a.Geometry)
+		if err != nil {
+			return nil, err
+		}
+		m.Geometry = string(data)
+	}
+	if len(a.Fields) > 0 {
+		data, err := json.Marshal(a.Fields)
+		if err != nil {
+			return nil, err
+		}
+		m.Fields = string(data)
+	}
+	if r := a.Road; r != nil {
+		m.Road = &pb.Road{Maxspeed: int32(r.MaxSpeed), Surface: r.Surface, Lanes: int32(r.Lanes), Oneway: r.Oneway}
+	}
+	if a.Centroid != nil {
+		m.Centroid = a.Centroid.proto()
+	}
+	if a.LabelPoint != nil {
+		m.LabelPoint = a.LabelPoint.proto()
+	}
+	if b := a.Viewport; b != nil {
+		m.Viewport = &pb.BBox{MinLat: b.MinLat, MinLon: b.MinLon, MaxLat: b.MaxLat, MaxLon: b.MaxLon}
+	}
+	if v := a.Vertical; v != nil {
+		m.Vertical = &pb.Vertical{Layer: int32(v.Layer), Bridge: v.Bridge, Tunnel: v.Tunnel, Levels: v.Levels}
+	}
+	return m, nil
+}
+
+func (l Location) proto() *pb.Location {
+	return &pb.Location{Lat: l.Lat, Lon: l.Lon}
+}
@@ -0,0 +1,70 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/maddevsio/ariadna/schema/v1/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalProtobuf(t *testing.T) {
+	r := Results{SchemaVersion: Version, Results: []Address{{Name: "a", Location: Location{Lat: 1, Lon: 2}}}}
+	got, err := r.MarshalProtobuf()
+	require.NoError(t, err)
+	want := []byte{
+		0x08, 0x01, // schema_version
+		0x1a, 0x17, // results
+		0x12, 0x01, 'a', // name
+		0x7a, 0x12, // location
+		0x09, 0, 0, 0, 0, 0, 0, 0xf0, 0x3f, // lat
+		0x11, 0, 0, 0, 0, 0, 0, 0x00, 0x40, // lon
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestMarshalProtobufFacets(t *testing.T) {
+	r := Results{Facets: map[string][]Facet{"layer": {{Value: "poi", Count: 3}}}}
+	got, err := r.MarshalProtobuf()
+	require.NoError(t, err)
+	want := []byte{
+		0x22, 0x12, // facets entry
+		0x0a, 0x05, 'l', 'a', 'y', 'e', 'r', // key
+		0x12, 0x09, // value
+		0x0a, 0x07, // buckets
+		0x0a, 0x03, 'p', 'o', 'i', // value
+		0x10, 0x03, // count
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestMarshalProtobufRoundTrip(t *testing.T) {
+	zero := 0.0
+	r := Results{SchemaVersion: Version, Results: []Address{{
+		ID:        "way/1",
+		Elevation: &zero,
+		Wikidata:  &Wikidata{ID: "Q9361", Labels: map[string]string{"ky": "Бишкек", "en": "Bishkek"}, Sitelinks: 3},
+		Vertical:  &Vertical{Layer: -1, Tunnel: true, Levels: []float64{-1, -2}},
+		Road:      &Road{MaxSpeed: 60, Oneway: "forward"},
+		Fields:    map[string]interface{}{"cuisine": "kyrgyz"},
+	}}}
+	data, err := r.MarshalProtobuf()
+	require.NoError(t, err)
+	var m pb.Results
+	require.NoError(t, proto.Unmarshal(data, &m))
+	require.Len(t, m.Results, 1)
+	a := m.Results[0]
+	assert.Equal(t, "way/1", a.Id)
+	_, ok := a.ElevationValue.(*pb.Address_Elevation)
+	assert.True(t, ok, "zero elevation is sent")
+	assert.Equal(t, map[string]string{"ky": "Бишкек", "en": "Bishkek"}, a.Wikidata.Labels)
+	assert.Equal(t, int32(-1), a.Vertical.Layer)
+	assert.Equal(t, []float64{-1, -2}, a.Vertical.Levels)
+	assert.Equal(t, int32(60), a.Road.Maxspeed)
+	assert.JSONEq(t, `{"cuisine": "kyrgyz"}`, a.Fields)
+
+	again, err := r.MarshalProtobuf()
+	require.NoError(t, err)
+	assert.Equal(t, data, again, "map entries are ordered")
+}
@@ -0,0 +1,361 @@
+// Package v1 defines version 1 of API response schema. Field names here are stable:
+// changes of index documents must not change them, incompatible changes go to a new version
+package v1
+
+import (
+	"time"
+
+	"github.com/maddevsio/ariadna/model"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+const (
+	// Version is value of schema_version field of every response
+	Version = 1
+	// MediaType selects this schema in Accept header
+	MediaType = "application/vnd.ariadna.v1+json"
+	// ProtobufMediaType selects binary encoding of this schema described by ariadna.proto
+	ProtobufMediaType = "application/vnd.ariadna.v1+protobuf"
+)
+
+type (
+	// Results is response of search and reverse endpoints
+	Results struct {
+		SchemaVersion int       `json:"schema_version"`
+		TimedOut      bool      `json:"timed_out"`
+		Results       []Address `json:"results"`
+		// Facets count values of requested fields among all found documents
+		Facets map[string][]Facet `json:"facets,omitempty"`
+	}
+	// Facet is number of found documents having value of field
+	Facet struct {
+		Value string `json:"value"`
+		Count int64  `json:"count"`
+	}
+	// Boundaries is response of boundaries endpoint, the outermost boundary goes first
+	Boundaries struct {
+		SchemaVersion int        `json:"schema_version"`
+		TimedOut      bool       `json:"timed_out"`
+		Boundaries    []Boundary `json:"boundaries"`
+	}
+	// Distances is response of distance endpoint, destinations follow order of request
+	Distances struct {
+		SchemaVersion int        `json:"schema_version"`
+		Origin        Location   `json:"origin"`
+		Destinations  []Distance `json:"destinations"`
+	}
+	// Distance is great circle distance in meters and initial bearing in degrees from origin to
+	// destination point or document, Error describes destination which couldn't be located
+	Distance struct {
+		ID       string    `json:"id,omitempty"`
+		Location *Location `json:"location,omitempty"`
+		Distance float64   `json:"distance"`
+		Bearing  float64   `json:"bearing"`
+		Error    string    `json:"error,omitempty"`
+	}
+	// Geofences is response of geofence list and point check endpoints
+	Geofences struct {
+		SchemaVersion int        `json:"schema_version"`
+		Geofences     []Geofence `json:"geofences"`
+	}
+	// Geofence is named area registered by client, Properties are ones given at registration
+	Geofence struct {
+		Name       string                 `json:"name"`
+		Properties map[string]interface{} `json:"properties,omitempty"`
+		UpdatedAt  time.Time              `json:"updated_at"`
+	}
+	// Boundary is admin polygon containing requested point. Role is country or place type
+	Boundary struct {
+		ID       string            `json:"id"`
+		Name     string            `json:"name"`
+		Role     string            `json:"role"`
+		Level    int               `json:"level,omitempty"`
+		Geometry *geojson.Geometry `json:"geometry,omitempty"`
+		// Approximate is set for concave hull of boundary which members don't close into rings
+		Approximate bool `json:"approximate,omitempty"`
+	}
+	// Error is response of failed request
+	Error struct {
+		SchemaVersion int    `json:"schema_version"`
+		Error         string `json:"error"`
+		Code          string `json:"code,omitempty"`
+	}
+	// QueryStats is response of query statistics endpoint
+	QueryStats struct {
+		SchemaVersion int                      `json:"schema_version"`
+		Endpoints     map[string]EndpointStats `json:"endpoints"`
+		ZeroQueries   []ZeroQuery              `json:"zero_queries"`
+		Caches        map[string]CacheStats    `json:"caches,omitempty"`
+	}
+	// CacheStats counts hits and misses of cache, HitRate is share of hits in lookups
+	CacheStats struct {
+		Hits    int64   `json:"hits"`
+		Misses  int64   `json:"misses"`
+		HitRate float64 `json:"hit_rate"`
+	}
+	// IndexStats is response of index statistics endpoint
+	IndexStats struct {
+		SchemaVersion int                   `json:"schema_version"`
+		Documents     int64                 `json:"documents"`
+		SizeBytes     int64                 `json:"size_bytes"`
+		Layers        map[string]LayerStats `json:"layers"`
+		Import        *ImportInfo           `json:"import,omitempty"`
+	}
+	// LayerStats counts documents of layer per category, documents without layer are counted as address
+	LayerStats struct {
+		Documents  int64            `json:"documents"`
+		Categories map[string]int64 `json:"categories,omitempty"`
+	}
+	// ImportInfo describes the latest import, extract_time is modification time of the source extract
+	ImportInfo struct {
+		ImportedAt  time.Time  `json:"imported_at"`
+		ExtractTime *time.Time `json:"extract_time,omitempty"`
+		Source      string     `json:"source,omitempty"`
+	}
+	// History is response of history endpoint, slices are ordered by validity
+	History struct {
+		SchemaVersion int     `json:"schema_version"`
+		Slices        []Slice `json:"slices"`
+	}
+	// Slice is data of one import answering ?as_of= dates from ValidFrom until ValidTo,
+	// ValidTo of the current import is missing
+	Slice struct {
+		ValidFrom time.Time  `json:"valid_from"`
+		ValidTo   *time.Time `json:"valid_to,omitempty"`
+		Import    ImportInfo `json:"import"`
+	}
+	// Usage is response of usage endpoint, counters are ordered by key and period
+	Usage struct {
+		SchemaVersion int        `json:"schema_version"`
+		Usage         []KeyUsage `json:"usage"`
+	}
+	// KeyUsage is count of requests of API key in day like 2022-01-15 or month like 2022-01,
+	// Limit is quota of the period, missing when unlimited. Key is id of API key, or ip:<address>
+	// of client without key
+	KeyUsage struct {
+		Key    string `json:"key"`
+		Period string `json:"period"`
+		Count  int64  `json:"count"`
+		Limit  int64  `json:"limit,omitempty"`
+	}
+	// Imports is response of imports endpoint, the latest import run goes first
+	Imports struct {
+		SchemaVersion int         `json:"schema_version"`
+		Imports       []ImportRun `json:"imports"`
+	}
+	// ImportRun is audit of import run: source, index receiving documents, stages and errors.
+	// Status is succeeded, partial when some stages failed, or failed
+	ImportRun struct {
+		ID          string        `json:"id"`
+		Source      string        `json:"source,omitempty"`
+		ExtractTime *time.Time    `json:"extract_time,omitempty"`
+		Index       string        `json:"index,omitempty"`
+		Layers      []string      `json:"layers,omitempty"`
+		StartedAt   time.Time     `json:"started_at"`
+		FinishedAt  time.Time     `json:"finished_at"`
+		Status      string        `json:"status"`
+		Documents   int64         `json:"documents"`
+		Stages      []ImportStage `json:"stages"`
+		Errors      []string      `json:"errors,omitempty"`
+	}
+	// ImportStage is timing of stage of import run and number of documents it wrote
+	ImportStage struct {
+		Name       string    `json:"name"`
+		StartedAt  time.Time `json:"started_at"`
+		DurationMS int64     `json:"duration_ms"`
+		Documents  int64     `json:"documents"`
+		Error      string    `json:"error,omitempty"`
+	}
+	// EndpointStats counts requests of endpoint
+	EndpointStats struct {
+		Total       int64 `json:"total"`
+		ZeroResults int64 `json:"zero_results"`
+	}
+	// ZeroQuery is query which found nothing
+	ZeroQuery struct {
+		Endpoint string `json:"endpoint"`
+		Query    string `json:"query"`
+	}
+	// Address is found address or feature
+	Address struct {
+		ID           string            `json:"id,omitempty"`
+		Name         string            `json:"name,omitempty"`
+		Country      string            `json:"country,omitempty"`
+		City         string            `json:"city,omitempty"`
+		Town         string            `json:"town,omitempty"`
+		Village      string            `json:"village,omitempty"`
+		District     string            `json:"district,omitempty"`
+		Prefix       string            `json:"street_prefix,omitempty"`
+		Street       string            `json:"street,omitempty"`
+		HouseNumber  string            `json:"house_number,omitempty"`
+		Unit         string            `json:"unit,omitempty"`
+		Flats        string            `json:"flats,omitempty"`
+		Door         string            `json:"door,omitempty"`
+		Intersection bool              `json:"intersection,omitempty"`
+		Location     Location          `json:"location"`
+		Entrances    []Entrance        `json:"entrances,omitempty"`
+		Layer        string            `json:"layer,omitempty"`
+		Category     string            `json:"category,omitempty"`
+		Routes       []string          `json:"routes,omitempty"`
+		Wikidata     *Wikidata         `json:"wikidata,omitempty"`
+		Wikipedia    string            `json:"wikipedia,omitempty"`
+		Elevation    *float64          `json:"elevation,omitempty"`
+		Timezone     string            `json:"timezone,omitempty"`
+		PlusCode     string            `json:"plus_code,omitempty"`
+		Geohash      string            `json:"geohash,omitempty"`
+		Geometry     *geojson.Geometry `json:"geometry,omitempty"`
+		Fields       model.Fields      `json:"fields,omitempty"`
+		Source       string            `json:"source,omitempty"`
+		Road         *Road             `json:"road,omitempty"`
+		Vertical     *Vertical         `json:"vertical,omitempty"`
+		Area         float64           `json:"area,omitempty"`
+		Centroid     *Location         `json:"centroid,omitempty"`
+		LabelPoint   *Location         `json:"label_point,omitempty"`
+		Approximate  bool              `json:"approximate,omitempty"`
+		// Language of name picked by lang parameter, empty for the default name
+		Language string `json:"language,omitempty"`
+		// LabelRomanized is name or street address in Latin script, set by romanize parameter
+		LabelRomanized string `json:"label_romanized,omitempty"`
+		// Viewport is suggested map extent to show the feature
+		Viewport *BBox `json:"viewport,omitempty"`
+		// MatchType tells how result was found when it is not nearest feature, e.g. fallback_place
+		MatchType string `json:"match_type,omitempty"`
+	}
+	// Road holds attributes of road segment, maxspeed is in km/h
+	Road struct {
+		MaxSpeed int    `json:"maxspeed,omitempty"`
+		Surface  string `json:"surface,omitempty"`
+		Lanes    int    `json:"lanes,omitempty"`
+		Oneway   string `json:"oneway,omitempty"`
+	}
+	// Vertical places feature among stacked ones, layer is negative under ground, levels are floors
+	Vertical struct {
+		Layer  int       `json:"layer,omitempty"`
+		Bridge bool      `json:"bridge,omitempty"`
+		Tunnel bool      `json:"tunnel,omitempty"`
+		Levels []float64 `json:"levels,omitempty"`
+	}
+	// BBox is bounding box in WGS84 degrees
+	BBox struct {
+		MinLat float64 `json:"min_lat"`
+		MinLon float64 `json:"min_lon"`
+		MaxLat float64 `json:"max_lat"`
+		MaxLon float64 `json:"max_lon"`
+	}
+	// Location is WGS84 point
+	Location struct {
+		Lat float64 `json:"lat"`
+		Lon float64 `json:"lon"`
+	}
+	// Entrance is building entrance
+	Entrance struct {
+		Type     string   `json:"type"`
+		Ref      string   `json:"ref,omitempty"`
+		Location Location `json:"location"`
+	}
+	// Wikidata describes wikidata entity of feature
+	Wikidata struct {
+		ID         string            `json:"id"`
+		Labels     map[string]string `json:"labels,omitempty"`
+		Population int64             `json:"population,omitempty"`
+		Sitelinks  int               `json:"sitelinks,omitempty"`
+	}
+)
+
+// NewResults converts found addresses
+func NewResults(addresses []model.Address, timedOut bool) Results {
+	r := Results{SchemaVersion: Version, TimedOut: timedOut, Results: make([]Address, 0, len(addresses))}
+	for _, a := range addresses {
+		r.Results = append(r.Results, NewAddress(a))
+	}
+	return r
+}
+
+// NewFacets converts facets of search result, nil is returned without facets
+func NewFacets(facets map[string][]model.Facet) map[string][]Facet {
+	if len(facets) == 0 {
+		return nil
+	}
+	result := make(map[string][]Facet, len(facets))
+	for field, buckets := range facets {
+		converted := make([]Facet, 0, len(buckets))
+		for _, b := range buckets {
+			converted = append(converted, Facet{Value: b.Value, Count: b.Count})
+		}
+		result[field] = converted
+	}
+	return result
+}
+
+// NewError creates error response
+func NewError(msg, code string) Error {
+	return Error{SchemaVersion: Version, Error: msg, Code: code}
+}
+
+// NewAddress converts index document to response address
+func NewAddress(a model.Address) Address {
+	address := Address{
+		ID:           a.ID,
+		Name:         a.Name,
+		Country:      a.Country,
+		City:         a.City,
+		Town:         a.Town,
+		Village:      a.Village,
+		District:     a.District,
+		Prefix:       a.Prefix,
+		Street:       a.Street,
+		HouseNumber:  a.HouseNumber,
+		Unit:         a.Unit,
+		Flats:        a.Flats,
+		Door:         a.Door,
+		Intersection: a.Intersection,
+		Location:     Location{Lat: a.Location.Lat, Lon: a.Location.Lon},
+		Layer:        a.Layer,
+		Category:     a.Category,
+		Routes:       a.Routes,
+		Wikipedia:    a.Wikipedia,
+		Elevation:    a.Elevation,
+		Timezone:     a.Timezone,
+		PlusCode:     a.PlusCode,
+		Geohash:      a.Geohash,
+		Geometry:     a.Geometry,
+		Fields:       a.Fields,
+		Source:       a.Source,
+		Area:         a.Area,
+		Approximate:  a.Approximate,
+		Language:     a.Language,
+	}
+	address.LabelRomanized = a.LabelRomanized
+	address.MatchType = a.MatchType
+	if a.Vertical != nil {
+		address.Vertical = &Vertical{Layer: a.Vertical.Layer, Bridge: a.Vertical.Bridge, Tunnel: a.Vertical.Tunnel, Levels: a.Vertical.Levels}
+	}
+	if a.Viewport != nil {
+		address.Viewport = &BBox{MinLat: a.Viewport.MinLat, MinLon: a.Viewport.MinLon, MaxLat: a.Viewport.MaxLat, MaxLon: a.Viewport.MaxLon}
+	}
+	if a.Centroid != nil {
+		address.Centroid = &Location{Lat: a.Centroid.Lat, Lon: a.Centroid.Lon}
+	}
+	if a.LabelPoint != nil {
+		address.LabelPoint = &Location{Lat: a.LabelPoint.Lat, Lon: a.LabelPoint.Lon}
+	}
+	if a.Road != nil {
+		address.Road = &Road{MaxSpeed: a.Road.MaxSpeed, Surface: a.Road.Surface, Lanes: a.Road.Lanes, Oneway: a.Road.Oneway}
+	}
+	for _, e := range a.Entrances {
+		address.Entrances = append(address.Entrances, Entrance{
+			Type:     e.Type,
+			Ref:      e.Ref,
+			Location: Location{Lat: e.Location.Lat, Lon: e.Location.Lon},
+		})
+	}
+	if a.WikidataID != "" {
+		address.Wikidata = &Wikidata{ID: a.WikidataID}
+		if a.Wikidata != nil {
+			address.Wikidata.Labels = a.Wikidata.Labels
+			address.Wikidata.Population = a.Wikidata.Population
+			address.Wikidata.Sitelinks = a.Wikidata.Sitelinks
+		}
+	}
+	return address
+}